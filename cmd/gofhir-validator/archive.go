@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// archiveSeparator joins an archive name and a member name in results,
+// e.g., "examples.zip!/patient.json".
+const archiveSeparator = "!/"
+
+// isArchive reports whether a file is a ZIP archive or a tarball, by its
+// extension.
+func isArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveInput is a resource read from an archive member: the whole member,
+// or one line of an NDJSON member.
+type archiveInput struct {
+	name string // archive!/member, with :line for NDJSON
+	data []byte
+	err  error
+}
+
+// readArchive streams the resources of an archive to fn without extracting
+// it. Members are selected like files in directories, by -include and
+// -exclude; .ndjson members are also read, one resource per non-empty line.
+// Hidden members and directories are skipped, as are XML members, which
+// cannot be validated and are counted instead. An error opening or reading
+// the archive is passed to fn with the archive name.
+func readArchive(name string, config *Config, fn func(archiveInput)) (skippedXML int) {
+	member := func(entry string, r io.Reader) {
+		base := path.Base(entry)
+		if hiddenMember(entry) || matchAny(config.Exclude, base, entry) {
+			return
+		}
+		ndjson := strings.HasSuffix(strings.ToLower(base), ".ndjson")
+		if !ndjson && !matchAny(config.Include, base, entry) {
+			if strings.HasSuffix(strings.ToLower(base), ".xml") {
+				skippedXML++
+			}
+			return
+		}
+		input := name + archiveSeparator + entry
+		if !ndjson {
+			data, err := io.ReadAll(r)
+			fn(archiveInput{name: input, data: data, err: err})
+			return
+		}
+		if err := readNDJSON(r, func(line int, data []byte) {
+			fn(archiveInput{name: input + ":" + strconv.Itoa(line), data: data})
+		}); err != nil {
+			fn(archiveInput{name: input, err: err})
+		}
+	}
+
+	var err error
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		err = readZip(name, member)
+	} else {
+		err = readTar(name, member)
+	}
+	if err != nil {
+		fn(archiveInput{name: name, err: err})
+	}
+	return skippedXML
+}
+
+// readZip calls member for each file of a ZIP archive, in archive order.
+func readZip(name string, member func(string, io.Reader)) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		member(f.Name, rc)
+		rc.Close()
+	}
+	return nil
+}
+
+// readTar calls member for each regular file of a tarball, gzip-compressed
+// or not.
+func readTar(name string, member func(string, io.Reader)) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			member(strings.TrimPrefix(hdr.Name, "./"), tr)
+		}
+	}
+}
+
+// readNDJSON calls fn with each non-empty line of r and its 1-based number.
+func readNDJSON(r io.Reader, fn func(line int, data []byte)) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 {
+			fn(line, trimmed)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// hiddenMember reports whether a member or one of its directories is hidden,
+// including the resource forks macOS adds to ZIP archives.
+func hiddenMember(entry string) bool {
+	for _, part := range strings.Split(entry, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/codegen"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+const codegenUsage = `gofhir-validator codegen - Go models from profiles
+
+Usage:
+  gofhir-validator codegen [options] -profile <url>[,<url>...]
+
+Writes a Go source file with a struct for each profile, and for the backbone
+elements and datatypes it uses, to stdout. Fields carry json tags and validate
+tags (github.com/go-playground/validator) for the profile's cardinalities,
+fixed values, maxLength and required bindings to small ValueSets.
+
+Examples:
+  gofhir-validator codegen -profile http://hl7.org/fhir/StructureDefinition/bodyweight > models.go
+  gofhir-validator codegen -package hl7.fhir.us.core#6.1.0 -go-package uscore \
+    -profile http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient,http://hl7.org/fhir/us/core/StructureDefinition/us-core-encounter
+
+Options:
+`
+
+// runCodegen implements the codegen subcommand.
+// Exit code is 1 when the models cannot be generated, 2 on usage or load errors.
+func runCodegen(args []string) int {
+	fs := flag.NewFlagSet("codegen", flag.ExitOnError)
+	var fhirVersion, profiles, goPackage, packages, packageFiles string
+	fs.StringVar(&fhirVersion, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0 or R4, R4B, R5)")
+	fs.StringVar(&profiles, "profile", "", "Canonical URL(s) of the profiles to generate models for (comma-separated)")
+	fs.StringVar(&goPackage, "go-package", "models", "Name of the generated Go package")
+	fs.StringVar(&packages, "package", "", "Additional FHIR package(s) to load (e.g., hl7.fhir.us.core#6.1.0)")
+	fs.StringVar(&packageFiles, "package-file", "", "Local .tgz package file(s) to load (comma-separated)")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, codegenUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if profiles == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := []validator.Option{validator.WithVersion(fhirVersion)}
+	if packages != "" {
+		for _, pkg := range strings.Split(packages, ",") {
+			if parts := strings.SplitN(strings.TrimSpace(pkg), "#", 2); len(parts) == 2 {
+				opts = append(opts, validator.WithPackage(parts[0], parts[1]))
+			}
+		}
+	}
+	if packageFiles != "" {
+		for _, p := range strings.Split(packageFiles, ",") {
+			opts = append(opts, validator.WithPackageTgz(strings.TrimSpace(p)))
+		}
+	}
+	v, err := validator.New(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	var urls []string
+	for _, url := range strings.Split(profiles, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	src, err := codegen.New(v.Registry(), v.Terminology()).Go(goPackage, urls...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Print(string(src))
+	return 0
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/profilecompare"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+const compareUsage = `gofhir-validator compare-profiles - Profile compatibility report
+
+Usage:
+  gofhir-validator compare-profiles [options] <old> <new>
+
+Each profile is either a path to a StructureDefinition JSON file or a canonical
+URL resolved from the loaded packages.
+
+Examples:
+  gofhir-validator compare-profiles old/StructureDefinition-my-patient.json new/StructureDefinition-my-patient.json
+  gofhir-validator compare-profiles -package-file us-core-6.1.0.tgz my-patient-v1.json \
+    http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient
+
+Options:
+`
+
+// CompareOutput represents the JSON output of compare-profiles.
+type CompareOutput struct {
+	Old      string         `json:"old"`
+	New      string         `json:"new"`
+	Breaking bool           `json:"breaking"`
+	Changes  []ChangeOutput `json:"changes"`
+}
+
+// ChangeOutput represents a single profile change in JSON output.
+type ChangeOutput struct {
+	Kind     string `json:"kind"`
+	Element  string `json:"element"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	Breaking bool   `json:"breaking"`
+}
+
+// runCompareProfiles implements the compare-profiles subcommand.
+// Exit code is 1 when breaking changes are found, 2 on usage or load errors.
+func runCompareProfiles(args []string) int {
+	fs := flag.NewFlagSet("compare-profiles", flag.ExitOnError)
+	var fhirVersion, packageFiles, output string
+	fs.StringVar(&fhirVersion, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0 or R4, R4B, R5)")
+	fs.StringVar(&packageFiles, "package-file", "", "Local .tgz package file(s) to resolve canonical URLs (comma-separated)")
+	fs.StringVar(&output, "output", "text", "Output format: text, json")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, compareUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	// Registry is only built when a profile is given as a canonical URL.
+	var reg *registry.Registry
+	resolve := func(ref string) (*registry.StructureDefinition, error) {
+		if data, err := os.ReadFile(ref); err == nil {
+			var sd registry.StructureDefinition
+			if err := json.Unmarshal(data, &sd); err != nil {
+				return nil, fmt.Errorf("parse %s: %w", ref, err)
+			}
+			return &sd, nil
+		}
+
+		if reg == nil {
+			opts := []validator.Option{validator.WithVersion(fhirVersion)}
+			if packageFiles != "" {
+				for _, p := range strings.Split(packageFiles, ",") {
+					opts = append(opts, validator.WithPackageTgz(strings.TrimSpace(p)))
+				}
+			}
+			v, err := validator.New(opts...)
+			if err != nil {
+				return nil, err
+			}
+			reg = v.Registry()
+		}
+
+		sd := reg.GetByURL(ref)
+		if sd == nil {
+			return nil, fmt.Errorf("profile %s is neither a readable file nor a loaded canonical URL", ref)
+		}
+		return sd, nil
+	}
+
+	oldSD, err := resolve(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	newSD, err := resolve(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	report := profilecompare.Compare(oldSD, newSD)
+
+	if strings.EqualFold(output, "json") {
+		out := CompareOutput{
+			Old:      fs.Arg(0),
+			New:      fs.Arg(1),
+			Breaking: report.HasBreakingChanges(),
+			Changes:  make([]ChangeOutput, 0, len(report.Changes)),
+		}
+		for _, c := range report.Changes {
+			out.Changes = append(out.Changes, ChangeOutput{
+				Kind:     string(c.Kind),
+				Element:  c.ElementID,
+				Old:      c.Old,
+				New:      c.New,
+				Breaking: c.Breaking(),
+			})
+		}
+		jsonOutput, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(jsonOutput))
+	} else {
+		printCompareReport(fs.Arg(0), fs.Arg(1), report)
+	}
+
+	if report.HasBreakingChanges() {
+		return 1
+	}
+	return 0
+}
+
+func printCompareReport(oldName, newName string, report *profilecompare.Report) {
+	fmt.Printf("== %s -> %s ==\n", oldName, newName)
+	fmt.Printf("Changes: %d\n", len(report.Changes))
+
+	if len(report.Changes) > 0 {
+		fmt.Println()
+		for _, c := range report.Changes {
+			marker := "     "
+			if c.Breaking() {
+				marker = "BREAK"
+			}
+			detail := ""
+			switch {
+			case c.Old != "" && c.New != "":
+				detail = fmt.Sprintf(": %s -> %s", c.Old, c.New)
+			case c.New != "":
+				detail = fmt.Sprintf(": %s", c.New)
+			case c.Old != "":
+				detail = fmt.Sprintf(": was %s", c.Old)
+			}
+			fmt.Printf("  %s [%s] %s%s\n", marker, c.Kind, c.ElementID, detail)
+		}
+	}
+
+	fmt.Println()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// writeCSV writes the -output csv/tsv rows for all validated files. Files
+// that could not be validated get a single exception row.
+func writeCSV(w io.Writer, outputs []ValidationOutput, format OutputFormat) error {
+	comma := ','
+	if format == OutputTSV {
+		comma = '\t'
+	}
+
+	cw := issue.NewCSVWriter(w, comma)
+	for _, out := range outputs {
+		if err := cw.Write(out.Resource, out.issueResult()); err != nil {
+			return err
+		}
+	}
+	return cw.Flush()
+}
+
+// issueResult returns the validation result of an output, or for files that
+// could not be validated a result holding their exception issue.
+func (out ValidationOutput) issueResult() *issue.Result {
+	if out.result != nil {
+		return out.result
+	}
+	result := issue.NewResult()
+	for _, iss := range out.Issues {
+		result.AddIssue(issue.Issue{
+			Severity:    issue.Severity(iss.Severity),
+			Code:        issue.Code(iss.Code),
+			Diagnostics: iss.Diagnostics,
+			Expression:  iss.Expression,
+			Profile:     iss.Profile,
+		})
+	}
+	return result
+}
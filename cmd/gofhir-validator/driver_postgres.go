@@ -0,0 +1,6 @@
+//go:build postgres
+
+package main
+
+// PostgreSQL driver for -report-db, in builds with -tags postgres.
+import _ "github.com/jackc/pgx/v5/stdlib"
@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package main
+
+// SQLite driver for -report-db, in builds with -tags sqlite (needs cgo).
+import _ "github.com/mattn/go-sqlite3"
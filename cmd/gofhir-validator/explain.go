@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+const explainUsage = `gofhir-validator explain - How an element of a resource is validated
+
+Usage:
+  gofhir-validator explain [options] <file> -path <element path>
+
+Prints, for each profile the resource is validated against, the effective
+ElementDefinition at the path, the slices the resource's items match on the
+way, the bindings of the element and its children, and the checks that apply
+with their phases, followed by the issues reported at or below the path.
+Paths use JSON names and indices, e.g. Patient.identifier[0].type or
+Observation.valueQuantity. The configuration file applies as for validation.
+
+Examples:
+  gofhir-validator explain patient.json -path Patient.identifier[0].type
+  gofhir-validator explain -package hl7.fhir.us.core#6.1.0 -verbose obs.json -path Observation.category[1]
+
+Options:
+`
+
+// runExplain implements the explain subcommand.
+// Exit code is 1 when the element cannot be explained, 2 on usage or load errors.
+func runExplain(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	config := &Config{}
+	var path, profiles, packages, packageFiles, output string
+	fs.StringVar(&path, "path", "", "Element path to explain (e.g., Patient.identifier[0].type)")
+	fs.StringVar(&config.Version, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0 or R4, R4B, R5)")
+	fs.StringVar(&profiles, "ig", "", "Profile URL(s) to validate against (comma-separated)")
+	fs.StringVar(&packages, "package", "", "Additional FHIR package(s) to load (e.g., hl7.fhir.us.core#6.1.0)")
+	fs.StringVar(&packageFiles, "package-file", "", "Local .tgz package file(s) to load (comma-separated)")
+	fs.StringVar(&config.ConfigFile, "config", "", "Configuration file (default: ./"+validator.DefaultConfigFile+" if present)")
+	fs.StringVar(&output, "output", "text", "Output format: text, json")
+	fs.BoolVar(&config.Verbose, "verbose", false, "Also print the ElementDefinitions as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, explainUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	// Accept options after the file too, as in "explain <file> -path ..."
+	var file string
+	if fs.NArg() > 0 {
+		file = fs.Arg(0)
+		_ = fs.Parse(fs.Args()[1:])
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "version" {
+			config.VersionSet = true
+		}
+	})
+
+	if file == "" || path == "" || fs.NArg() != 0 || (output != "text" && output != "json") {
+		fs.Usage()
+		return 2
+	}
+
+	resource, err := readInput(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	config.Quiet = true
+	opts, err := configFileOptions(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	opts = append(opts, validator.WithVersion(config.Version))
+	for _, profile := range splitList(profiles) {
+		opts = append(opts, validator.WithProfile(profile))
+	}
+	for _, pkg := range splitList(packages) {
+		if parts := strings.SplitN(pkg, "#", 2); len(parts) == 2 {
+			opts = append(opts, validator.WithPackage(parts[0], parts[1]))
+		}
+	}
+	for _, p := range splitList(packageFiles) {
+		opts = append(opts, validator.WithPackageTgz(p))
+	}
+	v, err := validator.New(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	exp, err := v.Explain(context.Background(), resource, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if output == "json" {
+		data, err := json.MarshalIndent(explainOutput(exp), "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+	printExplanation(os.Stdout, exp, config.Verbose)
+	return 0
+}
+
+// explainJSON is the -output json form of an Explanation, with the raw
+// ElementDefinitions so that fixed and pattern values are kept.
+type explainJSON struct {
+	Path     string               `json:"path"`
+	Value    any                  `json:"value,omitempty"`
+	Profiles []explainProfileJSON `json:"profiles"`
+	Issues   []IssueOutput        `json:"issues,omitempty"`
+}
+
+type explainProfileJSON struct {
+	Profile  string                  `json:"profile"`
+	Source   string                  `json:"source"`
+	Type     string                  `json:"type,omitempty"`
+	Element  json.RawMessage         `json:"element"`
+	Slices   []validator.SliceMatch  `json:"slices,omitempty"`
+	Bindings []validator.BindingInfo `json:"bindings,omitempty"`
+	Checks   []validator.Check       `json:"checks"`
+}
+
+func explainOutput(exp *validator.Explanation) explainJSON {
+	out := explainJSON{Path: exp.Path, Value: exp.Value}
+	for _, pe := range exp.Profiles {
+		out.Profiles = append(out.Profiles, explainProfileJSON{
+			Profile:  pe.Profile,
+			Source:   pe.Source,
+			Type:     pe.Type,
+			Element:  elementJSON(pe.Element),
+			Slices:   pe.Slices,
+			Bindings: pe.Bindings,
+			Checks:   pe.Checks,
+		})
+	}
+	for _, iss := range exp.Issues {
+		out.Issues = append(out.Issues, IssueOutput{
+			Severity:    string(iss.Severity),
+			Code:        string(iss.Code),
+			Diagnostics: iss.Diagnostics,
+			Expression:  iss.Expression,
+			Profile:     iss.Profile,
+			Source:      iss.Source,
+		})
+	}
+	return out
+}
+
+// elementJSON returns the ElementDefinition as loaded, or as decoded when
+// the raw JSON was not kept.
+func elementJSON(def *registry.ElementDefinition) json.RawMessage {
+	if raw := def.Raw(); len(raw) > 0 {
+		return raw
+	}
+	data, _ := json.Marshal(def)
+	return data
+}
+
+// printExplanation writes the text form of an Explanation.
+func printExplanation(w io.Writer, exp *validator.Explanation, verbose bool) {
+	fmt.Fprintf(w, "Path: %s\n", exp.Path)
+	if exp.Value == nil {
+		fmt.Fprintln(w, "Value: (not present)")
+	} else if data, err := json.Marshal(exp.Value); err == nil {
+		value := string(data)
+		if len(value) > 120 && !verbose {
+			value = value[:117] + "..."
+		}
+		fmt.Fprintf(w, "Value: %s\n", value)
+	}
+
+	for _, pe := range exp.Profiles {
+		fmt.Fprintf(w, "\nProfile: %s\n", pe.Profile)
+		fmt.Fprintf(w, "  Element: %s", pe.Element.ID)
+		if pe.Source != pe.Profile {
+			fmt.Fprintf(w, " (from %s)", pe.Source)
+		}
+		fmt.Fprintln(w)
+		if pe.Type != "" {
+			fmt.Fprintf(w, "  Type: %s\n", pe.Type)
+		}
+		if short := pe.Element.GetShort(); short != "" {
+			fmt.Fprintf(w, "  Short: %s\n", short)
+		}
+		if pe.Element.MustSupport {
+			fmt.Fprintln(w, "  Must support: yes")
+		}
+
+		if len(pe.Slices) > 0 {
+			fmt.Fprintln(w, "  Slicing:")
+			for _, s := range pe.Slices {
+				var discriminators []string
+				for _, d := range s.Discriminators {
+					discriminators = append(discriminators, d.Type+":"+d.Path)
+				}
+				match := "matches no slice"
+				if s.Slice != "" {
+					match = "matches slice " + s.Slice
+				}
+				fmt.Fprintf(w, "    %s %s of %s [%s] by %s (%s)\n", s.Path, match, s.ElementID,
+					strings.Join(s.Slices, ", "), strings.Join(discriminators, ", "), s.Rules)
+			}
+		}
+
+		if len(pe.Bindings) > 0 {
+			fmt.Fprintln(w, "  Bindings:")
+			for _, b := range pe.Bindings {
+				loaded := ""
+				if !b.Loaded {
+					loaded = " (ValueSet not loaded: codes are not checked)"
+				}
+				fmt.Fprintf(w, "    %s: %s %s%s\n", b.ElementID, b.Strength, b.ValueSet, loaded)
+			}
+		}
+
+		fmt.Fprintln(w, "  Checks:")
+		for _, c := range pe.Checks {
+			disabled := ""
+			if !c.Enabled {
+				disabled = " (phase disabled)"
+			}
+			fmt.Fprintf(w, "    [%s] %s%s\n", c.Phase, c.Description, disabled)
+		}
+
+		if verbose {
+			var def bytes.Buffer
+			if json.Indent(&def, elementJSON(pe.Element), "    ", "  ") == nil {
+				fmt.Fprintf(w, "  Definition:\n    %s\n", def.String())
+			}
+		}
+	}
+
+	if len(exp.Issues) == 0 {
+		fmt.Fprintln(w, "\nNo issues at this path.")
+		return
+	}
+	fmt.Fprintln(w, "\nIssues:")
+	for _, iss := range exp.Issues {
+		fmt.Fprintf(w, "  %s [%s] %s @ %s\n", getSeverityIcon(iss.Severity), iss.Code, iss.Diagnostics, strings.Join(iss.Expression, ", "))
+		if iss.Source != nil && iss.Source.ElementID != "" {
+			fmt.Fprintf(w, "        rule %s\n", iss.Source)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/generate"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+const generateUsage = `gofhir-validator generate - Instance skeleton from a profile
+
+Usage:
+  gofhir-validator generate [options] -profile <url>
+
+Writes a minimal instance of the profile (required elements, fixed and pattern
+values, required slices) to stdout. With -example, mustSupport elements are
+populated too.
+
+Examples:
+  gofhir-validator generate -profile http://hl7.org/fhir/StructureDefinition/bodyweight
+  gofhir-validator generate -package hl7.fhir.us.core#6.1.0 -example \
+    -profile http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient > patient.json
+
+Options:
+`
+
+// runGenerate implements the generate subcommand.
+// Exit code is 1 when the instance cannot be generated, 2 on usage or load errors.
+func runGenerate(args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	var fhirVersion, profile, packages, packageFiles string
+	var example bool
+	fs.StringVar(&fhirVersion, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0 or R4, R4B, R5)")
+	fs.StringVar(&profile, "profile", "", "Canonical URL of the profile to generate an instance of")
+	fs.StringVar(&packages, "package", "", "Additional FHIR package(s) to load (e.g., hl7.fhir.us.core#6.1.0)")
+	fs.StringVar(&packageFiles, "package-file", "", "Local .tgz package file(s) to load (comma-separated)")
+	fs.BoolVar(&example, "example", false, "Also populate mustSupport elements")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, generateUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if profile == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := []validator.Option{validator.WithVersion(fhirVersion)}
+	if packages != "" {
+		for _, pkg := range strings.Split(packages, ",") {
+			if parts := strings.SplitN(strings.TrimSpace(pkg), "#", 2); len(parts) == 2 {
+				opts = append(opts, validator.WithPackage(parts[0], parts[1]))
+			}
+		}
+	}
+	if packageFiles != "" {
+		for _, p := range strings.Split(packageFiles, ",") {
+			opts = append(opts, validator.WithPackageTgz(strings.TrimSpace(p)))
+		}
+	}
+	v, err := validator.New(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	mode := generate.Minimal
+	if example {
+		mode = generate.Example
+	}
+	data, err := generate.New(v.Registry(), v.Terminology()).Generate(profile, mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println(out.String())
+	return 0
+}
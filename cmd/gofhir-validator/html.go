@@ -0,0 +1,25 @@
+package main
+
+import (
+	"io"
+
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/report"
+)
+
+// writeHTMLReport writes the -output html report for all validated files.
+func writeHTMLReport(w io.Writer, outputs []ValidationOutput, config *Config) error {
+	files := make([]report.File, 0, len(outputs))
+	for _, out := range outputs {
+		files = append(files, report.File{
+			Name:     out.Resource,
+			Result:   out.result,
+			Err:      out.err,
+			Duration: out.elapsed,
+		})
+	}
+	return report.WriteHTML(w, files, report.Options{
+		FHIRVersion: loader.NormalizeVersion(config.Version),
+		Quiet:       config.Quiet,
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stdinName is the input name used for "-".
+const stdinName = "stdin"
+
+// collectInputs expands the command-line arguments into the list of inputs to
+// validate: "-" for stdin, shell-style globs, and, with -recursive,
+// directories. Each input is listed once, in argument order. Problems with
+// individual arguments are returned as errors without stopping the expansion.
+func collectInputs(args []string, config *Config) ([]string, []error) {
+	var inputs []string
+	var errs []error
+	seen := make(map[string]bool)
+
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			inputs = append(inputs, name)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == "-" {
+			add(stdinName)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error with pattern '%s': %w", arg, err))
+			continue
+		}
+		if len(matches) == 0 {
+			errs = append(errs, fmt.Errorf("no files match pattern: %s", arg))
+			continue
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.IsDir() {
+				// Unreadable files are reported when they are validated
+				add(match)
+				continue
+			}
+			if !config.Recursive {
+				errs = append(errs, fmt.Errorf("%s is a directory (use -recursive to validate its files)", match))
+				continue
+			}
+			files, err := walkDir(match, config.Include, config.Exclude)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if len(files) == 0 && err == nil {
+				errs = append(errs, fmt.Errorf("no files to validate in directory: %s", match))
+			}
+			for _, f := range files {
+				add(f)
+			}
+		}
+	}
+	return inputs, errs
+}
+
+// walkDir lists the files under root that match an include pattern and no
+// exclude pattern, in lexical order. Patterns use filepath.Match syntax and
+// are matched against both the file name and the slash-separated path
+// relative to root; a directory matching an exclude pattern is skipped
+// entirely. Hidden directories (e.g., .git) are always skipped.
+func walkDir(root string, include, exclude []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return relErr
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || matchAny(exclude, d.Name(), rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchAny(include, d.Name(), rel) && !matchAny(exclude, d.Name(), rel) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return files, fmt.Errorf("error reading directory %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// matchAny reports whether the name or relative path matches any pattern.
+func matchAny(patterns []string, name, rel string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readInput reads a file, or stdin for stdinName.
+func readInput(name string) ([]byte, error) {
+	if name == stdinName {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
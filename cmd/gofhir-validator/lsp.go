@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/lsp"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+const lspUsage = `gofhir-validator lsp - Language server for FHIR JSON files
+
+Usage:
+  gofhir-validator lsp [options]
+
+Speaks the Language Server Protocol on stdin and stdout, for editors:
+diagnostics from validation as files are edited, hover documentation of
+elements and completion of member names and codes. Configure the editor to
+start it for JSON files; files without a resourceType are left alone. Logs
+are written to stderr. The configuration file applies as for validation.
+
+Examples:
+  gofhir-validator lsp
+  gofhir-validator lsp -package hl7.fhir.us.core#6.1.0
+
+Options:
+`
+
+// runLSP implements the lsp subcommand.
+// Exit code is 1 when the session fails, 2 on usage or load errors.
+func runLSP(args []string) int {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	config := &Config{}
+	var profiles, packages, packageFiles string
+	fs.StringVar(&config.Version, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0 or R4, R4B, R5)")
+	fs.StringVar(&profiles, "ig", "", "Profile URL(s) to validate against (comma-separated)")
+	fs.StringVar(&packages, "package", "", "Additional FHIR package(s) to load (e.g., hl7.fhir.us.core#6.1.0)")
+	fs.StringVar(&packageFiles, "package-file", "", "Local .tgz package file(s) to load (comma-separated)")
+	fs.StringVar(&config.ConfigFile, "config", "", "Configuration file (default: ./"+validator.DefaultConfigFile+" if present)")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, lspUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "version" {
+			config.VersionSet = true
+		}
+	})
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	config.Quiet = true
+	opts, err := configFileOptions(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	opts = append(opts, validator.WithVersion(config.Version))
+	for _, profile := range splitList(profiles) {
+		opts = append(opts, validator.WithProfile(profile))
+	}
+	for _, pkg := range splitList(packages) {
+		if parts := strings.SplitN(pkg, "#", 2); len(parts) == 2 {
+			opts = append(opts, validator.WithPackage(parts[0], parts[1]))
+		}
+	}
+	for _, p := range splitList(packageFiles) {
+		opts = append(opts, validator.WithPackageTgz(p))
+	}
+	v, err := validator.New(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := lsp.New(v).Serve(ctx, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -9,12 +9,15 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/reportstore"
 	"github.com/gofhir/validator/pkg/validator"
+	"github.com/gofhir/validator/pkg/worker"
 )
 
 const (
@@ -25,14 +28,30 @@ Usage:
   gofhir-validator [options] <file>...
   gofhir-validator [options] -           (read from stdin)
   cat resource.json | gofhir-validator - (pipe input)
+  gofhir-validator codegen [options] -profile <url>[,<url>...]
+  gofhir-validator compare-profiles [options] <old> <new>
+  gofhir-validator explain [options] <file> -path <element path>
+  gofhir-validator export-schema [options] -profile <url>
+  gofhir-validator generate [options] -profile <url>
+  gofhir-validator lsp [options]
+  gofhir-validator terminology-preflight [options]
 
 Examples:
   gofhir-validator patient.json
   gofhir-validator -version r4 patient.json
   gofhir-validator -ig http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient patient.json
+  gofhir-validator -ig hl7.fhir.us.core#6.1.0 patient.json
   gofhir-validator -output json patient.json
+  gofhir-validator -output html examples/*.json > report.html
+  gofhir-validator -output csv examples/*.json > issues.csv
+  gofhir-validator -quiet -output review examples/*.json > validation.txt
   gofhir-validator -tx n/a patient.json
+  gofhir-validator -config ci/gofhir-validator.yaml examples/*.json
+  gofhir-validator -max-warnings 10 examples/*.json
   gofhir-validator *.json
+  gofhir-validator -recursive -exclude 'draft*' -jobs 8 examples/
+  gofhir-validator -recursive -quiet -sink results.ndjson corpus/
+  gofhir-validator examples.zip synthea.tar.gz
   cat patient.json | gofhir-validator -
 
 Options:
@@ -46,22 +65,56 @@ type OutputFormat string
 const (
 	OutputText OutputFormat = "text"
 	OutputJSON OutputFormat = "json"
+	// OutputReview is a stable, sorted plain-text format for committing to
+	// version control (no timestamps or durations).
+	OutputReview OutputFormat = "review"
+	// OutputHTML is a self-contained HTML report of all files.
+	OutputHTML OutputFormat = "html"
+	// OutputCSV and OutputTSV emit one row per issue for spreadsheets.
+	OutputCSV OutputFormat = "csv"
+	OutputTSV OutputFormat = "tsv"
 )
 
+// aggregated reports whether the format is written once for all files
+// rather than printed per file as validation progresses.
+func (f OutputFormat) aggregated() bool {
+	return f == OutputJSON || f == OutputHTML || f == OutputCSV || f == OutputTSV
+}
+
 // Config holds CLI configuration
 type Config struct {
 	Version       string
+	SourceVersion string // FHIR version of the input, converted to Version
 	Profiles      []string
 	Packages      []string
+
+	// ProfileSelection, if set, overrides the configuration file's setting
+	ProfileSelection *validator.ProfileSelection
+
 	PackageFiles  []string
 	PackageURLs   []string
+	Offline       bool
 	Output        OutputFormat
 	Strict        bool
+	FailOn        issue.Severity
+	MaxWarnings   int
+	RawIssues     bool
+	Locale        string
 	NoTerminology bool
 	Quiet         bool
 	Verbose       bool
 	ShowVersion   bool
+	VersionFull   bool
 	Help          bool
+	ConfigFile    string
+	VersionSet    bool // -version was given explicitly
+	Recursive     bool
+	Include       []string
+	Exclude       []string
+	Jobs          int
+	Sink          string
+	Summary       string
+	ReportDB      string
 	Files         []string
 }
 
@@ -74,17 +127,55 @@ type ValidationOutput struct {
 	Info     int           `json:"info"`
 	Issues   []IssueOutput `json:"issues,omitempty"`
 	Duration string        `json:"duration"`
+
+	// Profiles validated against, and declared or requested profiles that
+	// could not be resolved
+	Profiles           []string `json:"profiles,omitempty"`
+	UnresolvedProfiles []string `json:"unresolvedProfiles,omitempty"`
+
+	// Outcome of each profile validated against
+	ProfilesEvaluated []issue.ProfileOutcome `json:"profilesEvaluated,omitempty"`
+
+	// For the HTML report
+	result  *issue.Result
+	elapsed time.Duration
+	err     error
 }
 
 // IssueOutput represents a single issue in JSON output
 type IssueOutput struct {
-	Severity    string   `json:"severity"`
-	Code        string   `json:"code"`
-	Diagnostics string   `json:"diagnostics"`
-	Expression  []string `json:"expression,omitempty"`
+	Severity    string        `json:"severity"`
+	Code        string        `json:"code"`
+	Diagnostics string        `json:"diagnostics"`
+	Expression  []string      `json:"expression,omitempty"`
+	Profile     string        `json:"profile,omitempty"`
+	CodeSystem  string        `json:"codeSystem,omitempty"`
+	Source      *issue.Source `json:"source,omitempty"`
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "compare-profiles" {
+		os.Exit(runCompareProfiles(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "codegen" {
+		os.Exit(runCodegen(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "explain" {
+		os.Exit(runExplain(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export-schema" {
+		os.Exit(runExportSchema(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		os.Exit(runGenerate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		os.Exit(runLSP(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "terminology-preflight" {
+		os.Exit(runPreflight(os.Args[2:]))
+	}
+
 	config := parseFlags()
 
 	if config.ShowVersion {
@@ -92,6 +183,10 @@ func main() {
 		os.Exit(0)
 	}
 
+	if config.VersionFull {
+		os.Exit(printVersionFull(os.Stdout))
+	}
+
 	if config.Help || len(config.Files) == 0 {
 		flag.Usage()
 		os.Exit(0)
@@ -110,18 +205,36 @@ func parseFlags() *Config {
 	// Define flags compatible with HL7 validator
 	var profiles, packages, packageFiles, packageURLs string
 	var output string
+	var include, exclude string
+	var failOn, profileSelection string
 
-	flag.StringVar(&config.Version, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0)")
-	flag.StringVar(&profiles, "ig", "", "Profile URL(s) to validate against (comma-separated)")
+	flag.StringVar(&config.Version, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0 or R4, R4B, R5)")
+	flag.StringVar(&config.SourceVersion, "source-version", "", "FHIR version of the input when it differs from -version (R4, R4B or R5); resources are converted with the cross-version mappings and unmapped elements reported")
+	flag.StringVar(&profiles, "ig", "", "Profile URL(s) to validate against, or IG package(s) as name#version whose global profiles apply (comma-separated)")
+	flag.StringVar(&profileSelection, "profile-selection", "", "Profiles to validate against: union (all that apply, default) or precedence (only -ig profiles when given, else meta.profile, else IG global profiles)")
 	flag.StringVar(&packages, "package", "", "Additional FHIR package(s) to load (e.g., hl7.fhir.us.core#6.1.0)")
 	flag.StringVar(&packageFiles, "package-file", "", "Local .tgz package file(s) to load (comma-separated)")
 	flag.StringVar(&packageURLs, "package-url", "", "Remote .tgz package URL(s) to load (comma-separated)")
-	flag.StringVar(&output, "output", "text", "Output format: text, json")
+	flag.BoolVar(&config.Offline, "offline", false, "Load -package-url packages from the download cache only, without network calls")
+	flag.StringVar(&output, "output", "text", "Output format: text, json, review, html, csv, tsv")
 	flag.BoolVar(&config.Strict, "strict", false, "Treat warnings as errors")
+	flag.StringVar(&failOn, "fail-on", "error", "Least severe issue that makes the exit code non-zero: error, warning, info")
+	flag.IntVar(&config.MaxWarnings, "max-warnings", -1, "Exit non-zero when the total warnings exceed N (-1: no limit)")
+	flag.StringVar(&config.Locale, "locale", "", "Language of issue messages (e.g., es); default English")
+	flag.BoolVar(&config.RawIssues, "raw-issues", false, "Report issues in phase order without merging duplicates (debugging)")
 	flag.BoolVar(&config.NoTerminology, "tx", false, "Disable terminology validation (use '-tx n/a')")
 	flag.BoolVar(&config.Quiet, "quiet", false, "Only show errors and warnings")
 	flag.BoolVar(&config.Verbose, "verbose", false, "Show detailed output")
 	flag.BoolVar(&config.ShowVersion, "v", false, "Show version")
+	flag.BoolVar(&config.VersionFull, "version-full", false, "Show version with the embedded package manifest and verify its checksums")
+	flag.StringVar(&config.ConfigFile, "config", "", "Configuration file (default: ./"+validator.DefaultConfigFile+" if present)")
+	flag.BoolVar(&config.Recursive, "recursive", false, "Validate the files in directory arguments and their subdirectories")
+	flag.StringVar(&include, "include", "*.json", "File patterns to validate in directories and archives (comma-separated)")
+	flag.StringVar(&exclude, "exclude", "", "File or directory patterns to skip in directories and archives (comma-separated)")
+	flag.IntVar(&config.Jobs, "jobs", runtime.NumCPU(), "Number of files to validate in parallel")
+	flag.StringVar(&config.Sink, "sink", "", "Stream results to a file as files complete (.ndjson, .jsonl, .csv or .tsv), keeping only counts in memory")
+	flag.StringVar(&config.Summary, "summary", "", "Write data quality totals of the run (issue counts, coverage, quality scores) to a JSON file")
+	flag.StringVar(&config.ReportDB, "report-db", "", "Record the run in a database for trending: a postgres:// URL, or a SQLite file in builds with -tags sqlite")
 	flag.BoolVar(&config.Help, "help", false, "Show help")
 
 	flag.Usage = func() {
@@ -130,12 +243,26 @@ func parseFlags() *Config {
 	}
 
 	flag.Parse()
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "version" {
+			config.VersionSet = true
+		}
+	})
 
 	// Parse profiles
 	if profiles != "" {
 		config.Profiles = strings.Split(profiles, ",")
 	}
 
+	if profileSelection != "" {
+		selection, ok := validator.ParseProfileSelection(profileSelection)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: -profile-selection %q: expected union or precedence\n", profileSelection)
+			os.Exit(2)
+		}
+		config.ProfileSelection = &selection
+	}
+
 	// Parse packages
 	if packages != "" {
 		config.Packages = strings.Split(packages, ",")
@@ -151,14 +278,39 @@ func parseFlags() *Config {
 		config.PackageURLs = strings.Split(packageURLs, ",")
 	}
 
+	// Parse exit policy
+	severity, err := issue.ParseSeverity(failOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -fail-on: %v\n", err)
+		os.Exit(2)
+	}
+	config.FailOn = severity
+
+	// Parse directory patterns
+	config.Include = splitList(include)
+	config.Exclude = splitList(exclude)
+
 	// Parse output format
 	switch strings.ToLower(output) {
 	case "json":
 		config.Output = OutputJSON
+	case "review":
+		config.Output = OutputReview
+	case "html":
+		config.Output = OutputHTML
+	case "csv":
+		config.Output = OutputCSV
+	case "tsv":
+		config.Output = OutputTSV
 	default:
 		config.Output = OutputText
 	}
 
+	if config.Sink != "" && config.Output.aggregated() {
+		fmt.Fprintf(os.Stderr, "Error: -sink cannot be combined with -output %s\n", config.Output)
+		os.Exit(2)
+	}
+
 	// Handle -tx n/a style flag
 	for _, arg := range os.Args {
 		if arg == "n/a" {
@@ -172,14 +324,76 @@ func parseFlags() *Config {
 	return config
 }
 
+// configFileOptions loads the -config file, or DefaultConfigFile from the
+// working directory if it exists. The file's FHIR version replaces the
+// default unless -version was given.
+func configFileOptions(config *Config) ([]validator.Option, error) {
+	path := config.ConfigFile
+	if path == "" {
+		if _, err := os.Stat(validator.DefaultConfigFile); err != nil {
+			return nil, nil
+		}
+		path = validator.DefaultConfigFile
+	}
+
+	fc, err := validator.LoadConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	opts, err := fc.Options()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+	if fc.FHIRVersion != "" && !config.VersionSet {
+		config.Version = fc.FHIRVersion
+	}
+	if !config.Quiet {
+		fmt.Fprintf(os.Stderr, "Using configuration %s\n", path)
+	}
+	return opts, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func run(config *Config) int {
+	// Settings from a configuration file come first so that flags override them
+	opts, err := configFileOptions(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Score results for the summary and report database, unless the
+	// configuration file already sets the scoring
+	if config.Summary != "" || config.ReportDB != "" {
+		opts = append([]validator.Option{validator.WithQualityScoring(validator.DefaultQualityScoring())}, opts...)
+	}
+
 	// Build validator options
-	opts := []validator.Option{
-		validator.WithVersion(config.Version),
+	opts = append(opts, validator.WithVersion(config.Version))
+	if config.SourceVersion != "" {
+		opts = append(opts, validator.WithSourceVersion(config.SourceVersion))
 	}
 
 	for _, profile := range config.Profiles {
-		opts = append(opts, validator.WithProfile(strings.TrimSpace(profile)))
+		profile = strings.TrimSpace(profile)
+		if strings.Contains(profile, "#") && !strings.Contains(profile, "://") {
+			opts = append(opts, validator.WithIG(profile))
+			continue
+		}
+		opts = append(opts, validator.WithProfile(profile))
+	}
+	if config.ProfileSelection != nil {
+		opts = append(opts, validator.WithProfileSelection(*config.ProfileSelection))
 	}
 
 	for _, pkg := range config.Packages {
@@ -199,11 +413,31 @@ func run(config *Config) int {
 	for _, url := range config.PackageURLs {
 		opts = append(opts, validator.WithPackageURL(strings.TrimSpace(url)))
 	}
+	if config.Offline {
+		opts = append(opts, validator.WithOffline(true))
+	}
 
 	if config.Strict {
 		opts = append(opts, validator.WithStrictMode(true))
 	}
 
+	if config.RawIssues {
+		opts = append(opts, validator.WithRawIssues())
+	}
+
+	if config.Locale != "" {
+		opts = append(opts, validator.WithLocale(config.Locale))
+	}
+
+	// Expand arguments before loading packages, so bad paths fail fast
+	inputs, inputErrs := collectInputs(config.Files, config)
+	for _, err := range inputErrs {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	if len(inputs) == 0 {
+		return 1
+	}
+
 	// Create validator
 	if !config.Quiet {
 		fmt.Fprintf(os.Stderr, "Initializing FHIR Validator (version %s)...\n", config.Version)
@@ -216,55 +450,114 @@ func run(config *Config) int {
 	}
 
 	if !config.Quiet {
-		fmt.Fprintf(os.Stderr, "Validator ready. Processing %d file(s)...\n\n", len(config.Files))
+		fmt.Fprintf(os.Stderr, "Validator ready. Processing %d file(s)...\n\n", len(inputs))
 	}
 
-	// Process files
-	hasErrors := false
-	outputs := make([]ValidationOutput, 0, len(config.Files))
+	var sink worker.ResultSink
+	var closeSink func() error
+	if config.Sink != "" {
+		if sink, closeSink, err = openSink(config.Sink); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
 
-	for _, file := range config.Files {
-		var data []byte
-		var name string
+	var report *reportstore.Run
+	var closeReport func() error
+	if config.ReportDB != "" {
+		if report, closeReport, err = openReportDB(context.Background(), config.ReportDB, config.Version); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
 
-		if file == "-" {
-			// Read from stdin
-			name = "stdin"
-			data, err = io.ReadAll(os.Stdin)
+	// Process files concurrently; results come back in input order
+	// Inputs that could not be read or validated fail regardless of policy
+	hasErrors := len(inputErrs) > 0
+	var tally issue.Tally
+	var summary issue.Summary
+	outputs := make([]ValidationOutput, 0, len(inputs))
+
+	// Archives expand to one job per resource, so read errors are kept by
+	// job sequence number
+	var readErrsMu sync.Mutex
+	readErrs := make(map[int]error)
+	jobs := make(chan worker.Job)
+	go func() {
+		defer close(jobs)
+		seq := 0
+		submit := func(name string, data []byte, err error) {
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
-				hasErrors = true
+				readErrsMu.Lock()
+				readErrs[seq] = err
+				readErrsMu.Unlock()
+			}
+			seq++
+			jobs <- worker.Job{ID: name, Data: data}
+		}
+		for _, name := range inputs {
+			if name == stdinName || !isArchive(name) {
+				data, err := readInput(name)
+				submit(name, data, err)
 				continue
 			}
-		} else {
-			// Handle glob patterns
-			matches, globErr := filepath.Glob(file)
-			if globErr != nil {
-				fmt.Fprintf(os.Stderr, "Error with pattern '%s': %v\n", file, globErr)
+			skipped := readArchive(name, config, func(in archiveInput) {
+				submit(in.name, in.data, in.err)
+			})
+			if skipped > 0 && !config.Quiet {
+				fmt.Fprintf(os.Stderr, "Warning: skipped %d XML member(s) of %s: only JSON resources can be validated\n", skipped, name)
+			}
+		}
+	}()
+
+	pool := worker.New(v, worker.WithWorkers(config.Jobs), worker.WithOrdered(0))
+	for res := range pool.Run(context.Background(), jobs) {
+		readErrsMu.Lock()
+		readErr := readErrs[res.Seq]
+		readErrsMu.Unlock()
+		output := newOutput(res, readErr, config)
+		tally.Add(output.result)
+		if config.Summary != "" {
+			summary.Add(output.result)
+		}
+		if output.err != nil {
+			hasErrors = true
+		}
+		if report != nil {
+			if err := report.WriteResult(output.Resource, output.issueResult()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing to -report-db: %v\n", err)
 				hasErrors = true
-				continue
+				report = nil
 			}
-
-			if len(matches) == 0 {
-				fmt.Fprintf(os.Stderr, "No files match pattern: %s\n", file)
+		}
+		if sink != nil {
+			if err := sink.WriteResult(output.Resource, output.issueResult()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", config.Sink, err)
 				hasErrors = true
-				continue
+				sink = nil
 			}
+			// Streamed: only the counts are kept for the summary
+			output.Issues, output.result = nil, nil
+		}
+		outputs = append(outputs, output)
+	}
+	if closeSink != nil {
+		if err := closeSink(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", config.Sink, err)
+			hasErrors = true
+		}
+	}
 
-			for _, match := range matches {
-				output, fileHasErrors := validateFile(v, match, config)
-				outputs = append(outputs, output)
-				if fileHasErrors {
-					hasErrors = true
-				}
-			}
-			continue
+	if closeReport != nil {
+		if err := closeReport(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing to -report-db: %v\n", err)
+			hasErrors = true
 		}
+	}
 
-		// Validate stdin data
-		output, fileHasErrors := validateData(v, data, name, config)
-		outputs = append(outputs, output)
-		if fileHasErrors {
+	if config.Summary != "" {
+		if err := writeSummary(config.Summary, &summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", config.Summary, err)
 			hasErrors = true
 		}
 	}
@@ -275,43 +568,70 @@ func run(config *Config) int {
 		fmt.Println(string(jsonOutput))
 	}
 
-	if hasErrors {
+	if config.Output == OutputCSV || config.Output == OutputTSV {
+		if err := writeCSV(os.Stdout, outputs, config.Output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", config.Output, err)
+			return 1
+		}
+	}
+
+	if config.Output == OutputHTML {
+		if err := writeHTMLReport(os.Stdout, outputs, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing HTML report: %v\n", err)
+			return 1
+		}
+	}
+
+	if len(outputs) > 1 {
+		if config.Output == OutputText {
+			printSummary(os.Stdout, outputs)
+		} else if !config.Quiet {
+			printSummary(os.Stderr, outputs)
+		}
+	}
+
+	policy := issue.ResultPolicy{FailOn: config.FailOn, MaxWarnings: config.MaxWarnings}
+	if config.Strict && (policy.FailOn == issue.SeverityError || policy.FailOn == issue.SeverityFatal) {
+		policy.FailOn = issue.SeverityWarning
+	}
+	verdict := policy.EvaluateTally(tally)
+	if !verdict.Passed && !config.Quiet {
+		fmt.Fprintf(os.Stderr, "Validation failed: %s\n", verdict.Reason)
+	}
+
+	if hasErrors || !verdict.Passed {
 		return 1
 	}
 	return 0
 }
 
-func validateFile(v *validator.Validator, path string, config *Config) (ValidationOutput, bool) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		output := ValidationOutput{
-			Resource: path,
+// newOutput builds the output for one validated input and, for per-file
+// formats, prints it.
+func newOutput(res worker.Result, readErr error, config *Config) ValidationOutput {
+	name := res.JobID
+	if readErr != nil {
+		if !config.Output.aggregated() {
+			fmt.Printf("Error reading %s: %v\n", name, readErr)
+		}
+		return ValidationOutput{
+			Resource: name,
 			Valid:    false,
 			Errors:   1,
 			Issues: []IssueOutput{{
 				Severity:    "error",
 				Code:        "exception",
-				Diagnostics: fmt.Sprintf("Failed to read file: %v", err),
+				Diagnostics: fmt.Sprintf("Failed to read file: %v", readErr),
 			}},
+			err: fmt.Errorf("failed to read file: %w", readErr),
 		}
-		if config.Output == OutputText {
-			fmt.Printf("Error reading %s: %v\n", path, err)
-		}
-		return output, true
 	}
 
-	return validateData(v, data, path, config)
-}
-
-func validateData(v *validator.Validator, data []byte, name string, config *Config) (ValidationOutput, bool) {
-	ctx := context.Background()
-	startTime := time.Now()
-
-	result, err := v.Validate(ctx, data)
-	duration := time.Since(startTime)
-
-	if err != nil {
-		output := ValidationOutput{
+	duration := res.Duration()
+	if res.Err != nil {
+		if !config.Output.aggregated() {
+			fmt.Printf("Error validating %s: %v\n", name, res.Err)
+		}
+		return ValidationOutput{
 			Resource: name,
 			Valid:    false,
 			Errors:   1,
@@ -319,16 +639,15 @@ func validateData(v *validator.Validator, data []byte, name string, config *Conf
 			Issues: []IssueOutput{{
 				Severity:    "error",
 				Code:        "exception",
-				Diagnostics: fmt.Sprintf("Validation failed: %v", err),
+				Diagnostics: fmt.Sprintf("Validation failed: %v", res.Err),
 			}},
+			elapsed: duration,
+			err:     fmt.Errorf("validation failed: %w", res.Err),
 		}
-		if config.Output == OutputText {
-			fmt.Printf("Error validating %s: %v\n", name, err)
-		}
-		return output, true
 	}
 
 	// Build output
+	result := res.Result
 	output := ValidationOutput{
 		Resource: name,
 		Valid:    !result.HasErrors(),
@@ -336,6 +655,12 @@ func validateData(v *validator.Validator, data []byte, name string, config *Conf
 		Warnings: result.WarningCount(),
 		Info:     result.InfoCount(),
 		Duration: duration.Round(time.Microsecond).String(),
+		result:   result,
+		elapsed:  duration,
+	}
+	if result.Stats != nil {
+		output.Profiles, output.UnresolvedProfiles = result.Stats.AppliedProfiles, result.Stats.UnresolvedProfiles
+		output.ProfilesEvaluated = result.Stats.ProfilesEvaluated
 	}
 
 	// Convert issues
@@ -345,15 +670,45 @@ func validateData(v *validator.Validator, data []byte, name string, config *Conf
 			Code:        string(iss.Code),
 			Diagnostics: iss.Diagnostics,
 			Expression:  iss.Expression,
+			Profile:     iss.Profile,
+			CodeSystem:  iss.SystemVersion,
+			Source:      iss.Source,
 		})
 	}
 
-	// Text output
-	if config.Output == OutputText {
+	switch config.Output {
+	case OutputText:
 		printTextResult(name, result, duration, config)
+	case OutputReview:
+		printReviewResult(name, result, config)
 	}
 
-	return output, result.HasErrors()
+	return output
+}
+
+// writeSummary writes the data quality totals of a run as indented JSON.
+func writeSummary(path string, summary *issue.Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// printSummary writes the totals for a multi-file run.
+func printSummary(w io.Writer, outputs []ValidationOutput) {
+	var passed, errors, warnings, info int
+	for _, o := range outputs {
+		if o.Valid {
+			passed++
+		}
+		errors += o.Errors
+		warnings += o.Warnings
+		info += o.Info
+	}
+	fmt.Fprintf(w, "== Summary ==\n")
+	fmt.Fprintf(w, "Files: %d, Passed: %d, Failed: %d\n", len(outputs), passed, len(outputs)-passed)
+	fmt.Fprintf(w, "Errors: %d, Warnings: %d, Info: %d\n", errors, warnings, info)
 }
 
 func printTextResult(name string, result *issue.Result, duration time.Duration, config *Config) {
@@ -368,7 +723,28 @@ func printTextResult(name string, result *issue.Result, duration time.Duration,
 	fmt.Printf("Errors: %d, Warnings: %d, Info: %d\n", result.ErrorCount(), result.WarningCount(), result.InfoCount())
 
 	if result.Stats != nil {
-		fmt.Printf("Profile: %s\n", result.Stats.ProfileURL)
+		profiles := result.Stats.AppliedProfiles
+		if len(profiles) == 0 {
+			profiles = []string{result.Stats.ProfileURL}
+		}
+		fmt.Printf("Profile: %s\n", strings.Join(profiles, ", "))
+		if len(result.Stats.UnresolvedProfiles) > 0 {
+			fmt.Printf("Unresolved: %s\n", strings.Join(result.Stats.UnresolvedProfiles, ", "))
+		}
+		if config.Verbose && len(result.Stats.ProfilesEvaluated) > 1 {
+			for _, p := range result.Stats.ProfilesEvaluated {
+				outcome := "pass"
+				if !p.Valid {
+					outcome = fmt.Sprintf("fail (%d errors)", p.Errors)
+				}
+				fmt.Printf("  %s [%s]: %s\n", p.URL, p.Origin, outcome)
+			}
+		}
+		if config.Verbose && result.Stats.Scored {
+			fmt.Printf("Quality score: %.1f (must-support %d/%d, terminology %d/%d)\n", result.Stats.Score,
+				result.Stats.MustSupport.Covered, result.Stats.MustSupport.Total,
+				result.Stats.Terminology.Covered, result.Stats.Terminology.Total)
+		}
 		fmt.Printf("Duration: %s\n", duration.Round(time.Microsecond))
 	}
 
@@ -388,6 +764,17 @@ func printTextResult(name string, result *issue.Result, duration time.Duration,
 			}
 
 			fmt.Printf("  %s [%s] %s%s\n", severityIcon, iss.Code, iss.Diagnostics, location)
+			// Name the ancestor profile that defined an inherited rule
+			if config.Verbose && iss.Profile != "" && result.Stats != nil && iss.Profile != result.Stats.ProfileURL {
+				fmt.Printf("        from %s\n", iss.Profile)
+			}
+			if config.Verbose && iss.SystemVersion != "" {
+				fmt.Printf("        checked against %s\n", iss.SystemVersion)
+			}
+			// Name the element definition, invariant or ValueSet behind the issue
+			if config.Verbose && iss.Source != nil && iss.Source.ElementID != "" {
+				fmt.Printf("        rule %s\n", iss.Source)
+			}
 		}
 	}
 
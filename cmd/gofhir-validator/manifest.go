@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/gofhir/validator/pkg/specs"
+)
+
+// printVersionFull writes the version, build kind and embedded package
+// manifest, then verifies the bundled packages against their checksums.
+// Returns the process exit code.
+func printVersionFull(w io.Writer) int {
+	build := "standard"
+	if specs.IsBundle() {
+		build = "bundle"
+	}
+	fmt.Fprintf(w, "gofhir-validator v%s (%s build, %s %s/%s)\n\n", version, build, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	infos, err := specs.Manifest()
+	if err != nil {
+		fmt.Fprintf(w, "Manifest: %v\n", err)
+		return 1
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tFHIR\tSOURCE\tSIZE\tSHA256")
+	for _, info := range infos {
+		source := "core"
+		if info.Bundled {
+			source = "bundled"
+		}
+		fmt.Fprintf(tw, "%s#%s\t%s\t%s\t%d\t%s\n", info.Name, info.Version, info.FHIRVersion, source, info.Size, info.SHA256)
+	}
+	tw.Flush()
+
+	if err := specs.Verify(); err != nil {
+		fmt.Fprintf(w, "\nIntegrity: FAILED\n%v\n", err)
+		return 1
+	}
+	fmt.Fprintln(w, "\nIntegrity: OK")
+	return 0
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/preflight"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+const preflightUsage = `gofhir-validator terminology-preflight - Terminology readiness report
+
+Usage:
+  gofhir-validator terminology-preflight [options]
+
+Scans the required and extensible bindings of the profiles in scope and reports
+which ValueSets and CodeSystems can be checked locally, which need a terminology
+server, and which are missing from the loaded packages.
+
+The scope is, in order: the -profile URLs, the -ig packages, the -package
+packages, or every loaded StructureDefinition.
+
+Examples:
+  gofhir-validator terminology-preflight -package hl7.fhir.us.core#6.1.0
+  gofhir-validator terminology-preflight -package-file my-ig.tgz -ig my.ig -output json
+
+Options:
+`
+
+// PreflightOutput represents the JSON output of terminology-preflight.
+type PreflightOutput struct {
+	Summary     PreflightSummary      `json:"summary"`
+	ValueSets   []PreflightValueSet   `json:"valueSets"`
+	CodeSystems []PreflightCodeSystem `json:"codeSystems"`
+}
+
+// PreflightSummary counts bound ValueSets by status.
+type PreflightSummary struct {
+	Local   int `json:"local"`
+	Server  int `json:"server"`
+	Missing int `json:"missing"`
+}
+
+// PreflightValueSet represents a bound ValueSet in JSON output.
+type PreflightValueSet struct {
+	URL         string   `json:"url"`
+	Status      string   `json:"status"`
+	CodeSystems []string `json:"codeSystems,omitempty"`
+	Bindings    []string `json:"bindings"`
+}
+
+// PreflightCodeSystem represents a needed CodeSystem in JSON output.
+type PreflightCodeSystem struct {
+	URL       string   `json:"url"`
+	Status    string   `json:"status"`
+	Content   string   `json:"content,omitempty"`
+	ValueSets []string `json:"valueSets"`
+}
+
+// runPreflight implements the terminology-preflight subcommand.
+// Exit code is 1 when a bound ValueSet is missing, 2 on usage or load errors.
+func runPreflight(args []string) int {
+	fs := flag.NewFlagSet("terminology-preflight", flag.ExitOnError)
+	var fhirVersion, packages, packageFiles, igs, profiles, output string
+	fs.StringVar(&fhirVersion, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0 or R4, R4B, R5)")
+	fs.StringVar(&packages, "package", "", "Additional FHIR package(s) to load (e.g., hl7.fhir.us.core#6.1.0)")
+	fs.StringVar(&packageFiles, "package-file", "", "Local .tgz package file(s) to load (comma-separated)")
+	fs.StringVar(&igs, "ig", "", "Package name(s) whose profiles to scan (comma-separated)")
+	fs.StringVar(&profiles, "profile", "", "Profile URL(s) to scan (comma-separated)")
+	fs.StringVar(&output, "output", "text", "Output format: text, json")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, preflightUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := []validator.Option{validator.WithVersion(fhirVersion)}
+	var packageNames []string
+	if packages != "" {
+		for _, pkg := range strings.Split(packages, ",") {
+			if parts := strings.SplitN(strings.TrimSpace(pkg), "#", 2); len(parts) == 2 {
+				opts = append(opts, validator.WithPackage(parts[0], parts[1]))
+				packageNames = append(packageNames, parts[0])
+			}
+		}
+	}
+	if packageFiles != "" {
+		for _, p := range strings.Split(packageFiles, ",") {
+			opts = append(opts, validator.WithPackageTgz(strings.TrimSpace(p)))
+		}
+	}
+	v, err := validator.New(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	var scope []string
+	switch {
+	case profiles != "":
+		for _, p := range strings.Split(profiles, ",") {
+			scope = append(scope, strings.TrimSpace(p))
+		}
+	case igs != "":
+		var names []string
+		for _, name := range strings.Split(igs, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+		scope = preflight.ProfilesInPackages(v.Registry(), names...)
+	case len(packageNames) > 0:
+		scope = preflight.ProfilesInPackages(v.Registry(), packageNames...)
+	default:
+		scope = v.Registry().AllURLs()
+	}
+	if len(scope) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no StructureDefinitions in scope")
+		return 2
+	}
+
+	report := preflight.Check(v.Registry(), v.Terminology(), scope)
+
+	if strings.EqualFold(output, "json") {
+		out := PreflightOutput{
+			Summary: PreflightSummary{
+				Local:   report.Count(preflight.StatusLocal),
+				Server:  report.Count(preflight.StatusServer),
+				Missing: report.Count(preflight.StatusMissing),
+			},
+			ValueSets:   make([]PreflightValueSet, 0, len(report.ValueSets)),
+			CodeSystems: make([]PreflightCodeSystem, 0, len(report.CodeSystems)),
+		}
+		for _, vs := range report.ValueSets {
+			bindings := make([]string, 0, len(vs.Bindings))
+			for _, b := range vs.Bindings {
+				bindings = append(bindings, b.Profile+"#"+b.ElementID)
+			}
+			out.ValueSets = append(out.ValueSets, PreflightValueSet{
+				URL:         vs.URL,
+				Status:      string(vs.Status),
+				CodeSystems: vs.CodeSystems,
+				Bindings:    bindings,
+			})
+		}
+		for _, cs := range report.CodeSystems {
+			out.CodeSystems = append(out.CodeSystems, PreflightCodeSystem{
+				URL:       cs.URL,
+				Status:    string(cs.Status),
+				Content:   cs.Content,
+				ValueSets: cs.ValueSets,
+			})
+		}
+		jsonOutput, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(jsonOutput))
+	} else {
+		printPreflightReport(len(scope), report)
+	}
+
+	if report.Count(preflight.StatusMissing) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printPreflightReport(profiles int, report *preflight.Report) {
+	fmt.Printf("== Terminology preflight (%d StructureDefinitions) ==\n", profiles)
+	fmt.Printf("ValueSets: %d local, %d need a terminology server, %d missing\n",
+		report.Count(preflight.StatusLocal), report.Count(preflight.StatusServer), report.Count(preflight.StatusMissing))
+
+	for _, status := range []preflight.Status{preflight.StatusMissing, preflight.StatusServer} {
+		var lines []string
+		for _, vs := range report.ValueSets {
+			if vs.Status == status {
+				lines = append(lines, fmt.Sprintf("  %s (%d bindings)", vs.URL, len(vs.Bindings)))
+			}
+		}
+		for _, cs := range report.CodeSystems {
+			if cs.Status == status {
+				detail := ""
+				if cs.Content != "" {
+					detail = ", content " + cs.Content
+				}
+				lines = append(lines, fmt.Sprintf("  CodeSystem %s (%d ValueSets%s)", cs.URL, len(cs.ValueSets), detail))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Println()
+		if status == preflight.StatusMissing {
+			fmt.Println("Missing:")
+		} else {
+			fmt.Println("Terminology server required:")
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	}
+
+	fmt.Println()
+}
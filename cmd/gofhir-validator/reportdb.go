@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/reportstore"
+)
+
+// reportDrivers are the database/sql driver names tried for each dialect,
+// as registered by the common drivers (pgx and lib/pq; modernc.org/sqlite
+// and mattn/go-sqlite3). Builds with -tags postgres link pgx, and builds
+// with -tags sqlite link mattn/go-sqlite3.
+var reportDrivers = map[reportstore.Dialect][]string{
+	reportstore.Postgres: {"pgx", "postgres"},
+	reportstore.SQLite:   {"sqlite", "sqlite3"},
+}
+
+// openReportDB opens the -report-db database and starts a run in it: a
+// postgres:// or postgresql:// URL, or else a SQLite database file,
+// optionally prefixed with "sqlite:". The driver is linked with a build tag
+// (see docs/USAGE.md).
+func openReportDB(ctx context.Context, dsn, fhirVersion string) (*reportstore.Run, func() error, error) {
+	dialect := reportstore.SQLite
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		dialect = reportstore.Postgres
+	} else {
+		dsn = strings.TrimPrefix(dsn, "sqlite:")
+	}
+
+	drivers := sql.Drivers()
+	i := slices.IndexFunc(reportDrivers[dialect], func(name string) bool {
+		return slices.Contains(drivers, name)
+	})
+	if i < 0 {
+		return nil, nil, fmt.Errorf("-report-db: no %s database driver is linked into this build (build with -tags %s)", dialect, dialect)
+	}
+
+	db, err := sql.Open(reportDrivers[dialect][i], dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("-report-db: %w", err)
+	}
+	store, err := reportstore.Open(ctx, db, dialect)
+	if err == nil {
+		var run *reportstore.Run
+		if run, err = store.StartRun(ctx, reportstore.RunInfo{FHIRVersion: fhirVersion}); err == nil {
+			return run, func() error {
+				err := run.Finish(ctx)
+				if closeErr := db.Close(); err == nil {
+					err = closeErr
+				}
+				return err
+			}, nil
+		}
+	}
+	db.Close()
+	return nil, nil, fmt.Errorf("-report-db: %w", err)
+}
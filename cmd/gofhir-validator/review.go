@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// reviewNoPath groups issues that carry no FHIRPath expression.
+const reviewNoPath = "(resource)"
+
+// printReviewResult prints a stable, diff-friendly report for committing
+// alongside example resources. It omits timestamps and durations, normalizes
+// the resource path and groups issues by element path in sorted order, so the
+// output only changes when the validation outcome changes.
+func printReviewResult(name string, result *issue.Result, config *Config) {
+	status := "valid"
+	if result.HasErrors() {
+		status = "invalid"
+	}
+
+	fmt.Printf("# %s\n", normalizeReviewName(name))
+	fmt.Printf("status: %s (errors: %d, warnings: %d, info: %d)\n",
+		status, result.ErrorCount(), result.WarningCount(), result.InfoCount())
+
+	groups := make(map[string][]issue.Issue)
+	for _, iss := range result.Issues {
+		if config.Quiet && iss.Severity == issue.SeverityInformation {
+			continue
+		}
+		path := reviewNoPath
+		if len(iss.Expression) > 0 {
+			path = strings.TrimSpace(iss.Expression[0])
+		}
+		groups[path] = append(groups[path], iss)
+	}
+
+	paths := make([]string, 0, len(groups))
+	for p := range groups {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		issues := groups[p]
+		sort.SliceStable(issues, func(i, j int) bool {
+			a, b := issues[i], issues[j]
+			if ra, rb := severityRank(a.Severity), severityRank(b.Severity); ra != rb {
+				return ra < rb
+			}
+			if a.Code != b.Code {
+				return a.Code < b.Code
+			}
+			return a.Diagnostics < b.Diagnostics
+		})
+
+		fmt.Printf("\n%s\n", p)
+		for _, iss := range issues {
+			fmt.Printf("  %s [%s] %s\n", iss.Severity, iss.Code, iss.Diagnostics)
+		}
+	}
+
+	fmt.Println()
+}
+
+// normalizeReviewName makes file names stable across platforms and invocations.
+func normalizeReviewName(name string) string {
+	if name == "stdin" {
+		return name
+	}
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+// severityRank orders severities from most to least severe.
+func severityRank(s issue.Severity) int {
+	switch s {
+	case issue.SeverityFatal:
+		return 0
+	case issue.SeverityError:
+		return 1
+	case issue.SeverityWarning:
+		return 2
+	case issue.SeverityInformation:
+		return 3
+	default:
+		return 4
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/jsonschema"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+const exportSchemaUsage = `gofhir-validator export-schema - JSON Schema from a profile
+
+Usage:
+  gofhir-validator export-schema [options] -profile <url>
+
+Writes a JSON Schema (draft 2020-12) of the profile to stdout: its elements,
+cardinalities, primitive regexes, fixed and pattern values, required slices
+and enums for required bindings to small ValueSets. Invariants and other
+bindings are not exported, so validate with gofhir-validator for full
+conformance.
+
+Examples:
+  gofhir-validator export-schema -profile http://hl7.org/fhir/StructureDefinition/bodyweight
+  gofhir-validator export-schema -package hl7.fhir.us.core#6.1.0 \
+    -profile http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient > us-core-patient.schema.json
+
+Options:
+`
+
+// runExportSchema implements the export-schema subcommand.
+// Exit code is 1 when the schema cannot be exported, 2 on usage or load errors.
+func runExportSchema(args []string) int {
+	fs := flag.NewFlagSet("export-schema", flag.ExitOnError)
+	var fhirVersion, profile, packages, packageFiles string
+	fs.StringVar(&fhirVersion, "version", "4.0.1", "FHIR version (4.0.1, 4.3.0, 5.0.0 or R4, R4B, R5)")
+	fs.StringVar(&profile, "profile", "", "Canonical URL of the profile to export")
+	fs.StringVar(&packages, "package", "", "Additional FHIR package(s) to load (e.g., hl7.fhir.us.core#6.1.0)")
+	fs.StringVar(&packageFiles, "package-file", "", "Local .tgz package file(s) to load (comma-separated)")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, exportSchemaUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if profile == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := []validator.Option{validator.WithVersion(fhirVersion)}
+	if packages != "" {
+		for _, pkg := range strings.Split(packages, ",") {
+			if parts := strings.SplitN(strings.TrimSpace(pkg), "#", 2); len(parts) == 2 {
+				opts = append(opts, validator.WithPackage(parts[0], parts[1]))
+			}
+		}
+	}
+	if packageFiles != "" {
+		for _, p := range strings.Split(packageFiles, ",") {
+			opts = append(opts, validator.WithPackageTgz(strings.TrimSpace(p)))
+		}
+	}
+	v, err := validator.New(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	data, err := jsonschema.New(v.Registry(), v.Terminology()).Export(profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println(out.String())
+	return 0
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/worker"
+)
+
+// openSink creates the -sink file and a sink for its format, chosen by the
+// extension: .ndjson or .jsonl for JSON lines, .csv or .tsv for one row per
+// issue. The returned function flushes and closes the file.
+func openSink(path string) (worker.ResultSink, func() error, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".ndjson", ".jsonl", ".csv", ".tsv":
+	default:
+		return nil, nil, fmt.Errorf("-sink %s: unknown format (use .ndjson, .jsonl, .csv or .tsv)", path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("-sink: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	closeSink := func() error {
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	switch ext {
+	case ".csv":
+		return worker.NewCSVSink(w, ','), closeSink, nil
+	case ".tsv":
+		return worker.NewCSVSink(w, '\t'), closeSink, nil
+	default:
+		return worker.NewNDJSONSink(w), closeSink, nil
+	}
+}
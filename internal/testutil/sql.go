@@ -0,0 +1,71 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// SQLRecorder is a database/sql driver that records the statements executed
+// through it, each with its whitespace collapsed and followed by its
+// arguments as JSON. It answers no queries.
+type SQLRecorder struct {
+	mu    sync.Mutex
+	execs []string
+}
+
+// OpenSQLRecorder returns a database recording its statements in the
+// returned SQLRecorder. The database is closed when the test ends.
+func OpenSQLRecorder(t testing.TB) (*sql.DB, *SQLRecorder) {
+	t.Helper()
+	r := &SQLRecorder{}
+	db := sql.OpenDB(r)
+	t.Cleanup(func() { db.Close() })
+	return db, r
+}
+
+// Take returns the recorded statements and forgets them.
+func (r *SQLRecorder) Take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	execs := r.execs
+	r.execs = nil
+	return execs
+}
+
+// Connect, Driver and Open implement driver.Connector and driver.Driver.
+func (r *SQLRecorder) Connect(context.Context) (driver.Conn, error) { return recordingConn{r}, nil }
+func (r *SQLRecorder) Driver() driver.Driver                        { return r }
+func (r *SQLRecorder) Open(string) (driver.Conn, error)             { return recordingConn{r}, nil }
+
+type recordingConn struct{ r *SQLRecorder }
+type recordingStmt struct {
+	r     *SQLRecorder
+	query string
+}
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.r, query}, nil
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return c, nil }
+func (c recordingConn) Commit() error             { return nil }
+func (c recordingConn) Rollback() error           { return nil }
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	data, _ := json.Marshal(args)
+	s.r.execs = append(s.r.execs, strings.Join(strings.Fields(s.query), " ")+" "+string(data))
+	return driver.RowsAffected(1), nil
+}
+func (s recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
@@ -0,0 +1,53 @@
+// Package testutil provides the fixtures shared by the package tests.
+package testutil
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/specs"
+)
+
+var (
+	mu         sync.Mutex
+	registries = map[string]*registry.Registry{}
+)
+
+// Packages loads the embedded packages of a FHIR version.
+func Packages(t testing.TB, version string) []*loader.Package {
+	t.Helper()
+	packages, err := loader.NewLoader("").LoadFromEmbeddedData(specs.GetPackages(version))
+	if err != nil {
+		t.Fatalf("Failed to load packages: %v", err)
+	}
+	return packages
+}
+
+// Registry returns a registry of the embedded packages of a FHIR version.
+// It is loaded once per test binary and shared, so tests must not add to it.
+func Registry(t testing.TB, version string) *registry.Registry {
+	t.Helper()
+	mu.Lock()
+	defer mu.Unlock()
+	if reg, ok := registries[version]; ok {
+		return reg
+	}
+	reg := registry.New()
+	if err := reg.LoadFromPackages(Packages(t, version)); err != nil {
+		t.Fatalf("Failed to load registry: %v", err)
+	}
+	registries[version] = reg
+	return reg
+}
+
+// IssueIDs returns the MessageIDs of the issues in a result.
+func IssueIDs(result *issue.Result) []string {
+	ids := make([]string, 0, len(result.Issues))
+	for _, iss := range result.Issues {
+		ids = append(ids, iss.MessageID)
+	}
+	return ids
+}
@@ -0,0 +1,216 @@
+// Package audit provides an opt-in rule pack for the integrity conventions of
+// Provenance and AuditEvent resources, beyond what their base structure enforces:
+//
+//   - Provenance.target references resolve to an entry of the enclosing Bundle
+//   - Provenance.agent.who identifies the agent (reference or identifier, not only display)
+//   - Signature data is accompanied by sigFormat, and sigFormat/targetFormat are MIME types
+//   - AuditEvent agent and entity codings carry both system and code, and come
+//     from the ValueSet bound to their element even where the binding is only
+//     extensible, preferred or an example
+package audit
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/binding"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/reference"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// mimeTypeRegex matches a MIME type with optional parameters (e.g., "application/jose;charset=utf-8").
+var mimeTypeRegex = regexp.MustCompile(`^[A-Za-z0-9][\w.+-]*/[A-Za-z0-9][\w.+-]*(\s*;.*)?$`)
+
+// auditCodedElements are the AuditEvent agent/entity elements whose codings are checked.
+// Both Coding (R4 entity.type/role) and CodeableConcept (agent.type/role, R5 entity.role)
+// shapes are accepted.
+var auditCodedElements = map[string][]string{
+	"agent":  {"type", "role"},
+	"entity": {"type", "role"},
+}
+
+// Validator applies the Provenance and AuditEvent rule pack.
+type Validator struct {
+	registry *registry.Registry
+	walker   *walker.Walker
+	binding  *binding.Validator
+}
+
+// New creates a new audit rule Validator. AuditEvent codings are checked
+// against their ValueSets with bind.
+func New(reg *registry.Registry, bind *binding.Validator) *Validator {
+	return &Validator{registry: reg, walker: walker.New(reg), binding: bind}
+}
+
+// ValidateData applies the rule pack to a pre-parsed resource, including
+// contained resources and Bundle entries.
+func (v *Validator) ValidateData(ctx context.Context, resource map[string]any, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+
+	var bundleCtx *reference.BundleContext
+	if resourceType == "Bundle" {
+		bundleCtx = reference.NewBundleContext(resource)
+	}
+
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
+		switch rc.ResourceType {
+		case "Provenance":
+			var bc *reference.BundleContext
+			if rc.IsBundleEntry {
+				bc = bundleCtx
+			}
+			v.validateProvenance(rc.Data, rc.FHIRPath, bc, result)
+		case "AuditEvent":
+			v.validateAuditEvent(ctx, rc.Data, rc.FHIRPath, result)
+		}
+		return true
+	})
+}
+
+// validateProvenance checks target resolution, agent identity and signatures.
+func (v *Validator) validateProvenance(data map[string]any, fhirPath string, bundleCtx *reference.BundleContext, result *issue.Result) {
+	if bundleCtx != nil {
+		targets, _ := data["target"].([]any)
+		for i, t := range targets {
+			target, ok := t.(map[string]any)
+			if !ok {
+				continue
+			}
+			ref, _ := target["reference"].(string)
+			if ref == "" || strings.HasPrefix(ref, "#") || bundleCtx.Resolves(ref) {
+				continue
+			}
+			result.AddErrorWithID(
+				issue.DiagProvenanceTargetNotResolved,
+				issue.Params{issue.String("reference", ref)},
+				fmt.Sprintf("%s.target[%d].reference", fhirPath, i),
+			)
+		}
+	}
+
+	agents, _ := data["agent"].([]any)
+	for i, a := range agents {
+		agent, ok := a.(map[string]any)
+		if !ok {
+			continue
+		}
+		agentPath := fmt.Sprintf("%s.agent[%d]", fhirPath, i)
+		who, _ := agent["who"].(map[string]any)
+		if who == nil {
+			// Missing who is reported by cardinality validation
+			continue
+		}
+		if _, hasRef := who["reference"]; hasRef {
+			continue
+		}
+		if _, hasID := who["identifier"]; hasID {
+			continue
+		}
+		result.AddErrorWithID(issue.DiagProvenanceAgentNoIdentity, nil, agentPath+".who")
+	}
+
+	signatures, _ := data["signature"].([]any)
+	for i, s := range signatures {
+		if sig, ok := s.(map[string]any); ok {
+			validateSignature(sig, fmt.Sprintf("%s.signature[%d]", fhirPath, i), result)
+		}
+	}
+}
+
+// validateSignature checks the format metadata of a Signature.
+func validateSignature(sig map[string]any, fhirPath string, result *issue.Result) {
+	if _, hasData := sig["data"]; hasData {
+		if _, hasSigFormat := sig["sigFormat"].(string); !hasSigFormat {
+			result.AddErrorWithID(issue.DiagSignatureNoFormat, nil, fhirPath)
+		}
+	}
+
+	for _, element := range []string{"sigFormat", "targetFormat"} {
+		value, _ := sig[element].(string)
+		if value != "" && !mimeTypeRegex.MatchString(value) {
+			result.AddErrorWithID(
+				issue.DiagSignatureInvalidFormat,
+				issue.Params{issue.String("element", element), issue.String("value", value)},
+				fhirPath+"."+element,
+			)
+		}
+	}
+}
+
+// validateAuditEvent checks that agent and entity codings are complete and
+// in the ValueSet bound to their element.
+func (v *Validator) validateAuditEvent(ctx context.Context, data map[string]any, fhirPath string, result *issue.Result) {
+	resourceType, _ := data["resourceType"].(string)
+	for backbone, elements := range auditCodedElements {
+		items, _ := data[backbone].([]any)
+		for i, item := range items {
+			itemMap, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			for _, element := range elements {
+				valueSet := v.valueSet(resourceType + "." + backbone + "." + element)
+				elementPath := fmt.Sprintf("%s.%s[%d].%s", fhirPath, backbone, i, element)
+				switch val := itemMap[element].(type) {
+				case map[string]any:
+					v.checkCoded(ctx, val, valueSet, elementPath, result)
+				case []any:
+					for j, c := range val {
+						if coded, ok := c.(map[string]any); ok {
+							v.checkCoded(ctx, coded, valueSet, fmt.Sprintf("%s[%d]", elementPath, j), result)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// valueSet returns the ValueSet bound to an element, or "" when it has none
+// or a required binding, which the binding phase already enforces.
+func (v *Validator) valueSet(path string) string {
+	elem := v.registry.GetElementDefinition(path)
+	if elem == nil || elem.Binding == nil || elem.Binding.Strength == "required" {
+		return ""
+	}
+	return elem.Binding.ValueSet
+}
+
+// checkCoded checks a Coding or the codings of a CodeableConcept, then their
+// membership in valueSet once they are complete.
+func (v *Validator) checkCoded(ctx context.Context, coded map[string]any, valueSet, fhirPath string, result *issue.Result) {
+	before := len(result.Issues)
+	codings, isConcept := coded["coding"].([]any)
+	switch {
+	case !isConcept && coded["text"] != nil:
+		// CodeableConcept with text only
+		result.AddErrorWithID(issue.DiagAuditCodingIncomplete, nil, fhirPath)
+	case !isConcept:
+		checkCoding(coded, fhirPath, result)
+	default:
+		for i, c := range codings {
+			if coding, ok := c.(map[string]any); ok {
+				checkCoding(coding, fmt.Sprintf("%s.coding[%d]", fhirPath, i), result)
+			}
+		}
+	}
+	if len(result.Issues) == before && valueSet != "" && v.binding != nil {
+		v.binding.ValidateCoded(ctx, coded, valueSet, fhirPath, result)
+	}
+}
+
+// checkCoding reports a Coding missing its system or code.
+func checkCoding(coding map[string]any, fhirPath string, result *issue.Result) {
+	system, _ := coding["system"].(string)
+	code, _ := coding["code"].(string)
+	if system == "" || code == "" {
+		result.AddErrorWithID(issue.DiagAuditCodingIncomplete, nil, fhirPath)
+	}
+}
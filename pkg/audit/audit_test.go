@@ -0,0 +1,208 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/binding"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/terminology"
+)
+
+// provenanceBundle wraps a Provenance in a transaction Bundle with one Patient entry.
+func provenanceBundle(prov map[string]any) map[string]any {
+	prov["resourceType"] = "Provenance"
+	return map[string]any{
+		"resourceType": "Bundle",
+		"type":         "transaction",
+		"entry": []any{
+			map[string]any{
+				"fullUrl":  "urn:uuid:61ebe359-bfdc-4613-8bf2-c5e300945f0a",
+				"resource": map[string]any{"resourceType": "Patient"},
+			},
+			map[string]any{
+				"fullUrl":  "urn:uuid:88f151c0-a954-468a-88dd-6f9b2ba1f5a3",
+				"resource": prov,
+			},
+		},
+	}
+}
+
+// deviceAgent is a Provenance agent identified by reference.
+var deviceAgent = map[string]any{"who": map[string]any{"reference": "Device/d1"}}
+
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource map[string]any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "target resolves in bundle",
+			resource: provenanceBundle(map[string]any{
+				"target": []any{map[string]any{"reference": "urn:uuid:61ebe359-bfdc-4613-8bf2-c5e300945f0a"}},
+				"agent":  []any{deviceAgent},
+			}),
+		},
+		{
+			name: "target not in bundle",
+			resource: provenanceBundle(map[string]any{
+				"target": []any{map[string]any{"reference": "urn:uuid:00000000-0000-0000-0000-000000000000"}},
+				"agent":  []any{deviceAgent},
+			}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagProvenanceTargetNotResolved},
+			wantPath: "Bundle.entry[1].resource.target[0].reference",
+		},
+		{
+			name: "standalone provenance target not checked",
+			resource: map[string]any{
+				"resourceType": "Provenance",
+				"target":       []any{map[string]any{"reference": "Patient/unknown"}},
+				"agent":        []any{deviceAgent},
+			},
+		},
+		{
+			name: "agent identified by identifier",
+			resource: map[string]any{
+				"resourceType": "Provenance",
+				"agent": []any{map[string]any{
+					"who": map[string]any{"identifier": map[string]any{"system": "urn:oid:1.2.3", "value": "42"}},
+				}},
+			},
+		},
+		{
+			name: "agent with display only",
+			resource: map[string]any{
+				"resourceType": "Provenance",
+				"agent":        []any{map[string]any{"who": map[string]any{"display": "Dr. Smith"}}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagProvenanceAgentNoIdentity},
+			wantPath: "Provenance.agent[0].who",
+		},
+		{
+			name: "valid signature",
+			resource: map[string]any{
+				"resourceType": "Provenance",
+				"agent":        []any{deviceAgent},
+				"signature": []any{map[string]any{
+					"sigFormat":    "application/jose",
+					"targetFormat": "application/fhir+json; fhirVersion=4.0",
+					"data":         "ZXlKaGJHY2lPaUpGVXpJMU5pSjk=",
+				}},
+			},
+		},
+		{
+			name: "signature data without sigFormat",
+			resource: map[string]any{
+				"resourceType": "Provenance",
+				"agent":        []any{deviceAgent},
+				"signature":    []any{map[string]any{"data": "ZXlKaGJHY2lPaUpGVXpJMU5pSjk="}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSignatureNoFormat},
+			wantPath: "Provenance.signature[0]",
+		},
+		{
+			name: "signature invalid targetFormat",
+			resource: map[string]any{
+				"resourceType": "Provenance",
+				"agent":        []any{deviceAgent},
+				"signature":    []any{map[string]any{"sigFormat": "application/jose", "targetFormat": "json"}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSignatureInvalidFormat},
+			wantPath: "Provenance.signature[0].targetFormat",
+		},
+		{
+			name: "complete audit event codings",
+			resource: map[string]any{
+				"resourceType": "AuditEvent",
+				"agent": []any{map[string]any{
+					"type": map[string]any{"coding": []any{map[string]any{
+						"system": "http://terminology.hl7.org/CodeSystem/extra-security-role-type", "code": "humanuser",
+					}}},
+				}},
+				"entity": []any{map[string]any{
+					"type": map[string]any{"system": "http://terminology.hl7.org/CodeSystem/audit-entity-type", "code": "2"},
+					"role": map[string]any{"system": "http://terminology.hl7.org/CodeSystem/object-role", "code": "4"},
+				}},
+			},
+		},
+		{
+			name: "entity coding without system",
+			resource: map[string]any{
+				"resourceType": "AuditEvent",
+				"entity":       []any{map[string]any{"role": map[string]any{"code": "4"}}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagAuditCodingIncomplete},
+			wantPath: "AuditEvent.entity[0].role",
+		},
+		{
+			name: "agent role with text only",
+			resource: map[string]any{
+				"resourceType": "AuditEvent",
+				"agent":        []any{map[string]any{"role": []any{map[string]any{"text": "admin"}}}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagAuditCodingIncomplete},
+			wantPath: "AuditEvent.agent[0].role[0]",
+		},
+		{
+			name: "agent type coding without code",
+			resource: map[string]any{
+				"resourceType": "AuditEvent",
+				"agent": []any{map[string]any{
+					"type": map[string]any{"coding": []any{map[string]any{"system": "http://dicom.nema.org/resources/ontology/DCM"}}},
+				}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagAuditCodingIncomplete},
+			wantPath: "AuditEvent.agent[0].type.coding[0]",
+		},
+		{
+			name: "entity role outside its extensible value set",
+			resource: map[string]any{
+				"resourceType": "AuditEvent",
+				"entity": []any{map[string]any{
+					"role": map[string]any{"system": "http://terminology.hl7.org/CodeSystem/object-role", "code": "99"},
+				}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeNotInCodeSystem},
+			wantPath: "AuditEvent.entity[0].role",
+		},
+		{
+			name: "agent role outside its example value set",
+			resource: map[string]any{
+				"resourceType": "AuditEvent",
+				"agent": []any{map[string]any{"role": []any{map[string]any{"coding": []any{map[string]any{
+					"system": "http://example.org/roles", "code": "admin",
+				}}}}}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagBindingRequired},
+			wantPath: "AuditEvent.agent[0].role[0].coding[0]",
+		},
+	}
+
+	reg := testutil.Registry(t, "4.0.1")
+	termReg := terminology.NewRegistry()
+	if err := termReg.LoadFromPackages(testutil.Packages(t, "4.0.1")); err != nil {
+		t.Fatalf("Failed to load terminology: %v", err)
+	}
+	v := New(reg, binding.New(reg, termReg))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(context.Background(), tt.resource, result)
+
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", testutil.IssueIDs(result), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
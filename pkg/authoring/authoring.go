@@ -0,0 +1,71 @@
+// Package authoring checks conformance resources the way IG authoring tools
+// do, beyond the structure and invariants every resource is validated
+// against:
+//
+//   - StructureDefinitions: element order and hierarchy in the snapshot and
+//     differential, types and target profiles that restrict those of the
+//     base, slicing declared before slices, and bindings that are no weaker
+//     than those of the base
+//   - SearchParameters: expressions (see searchparam.Validator)
+//   - ValueSets: included systems that are not loaded, filters their
+//     CodeSystem does not support, and concepts listed twice
+//   - CodeSystems: duplicate codes, concepts without a display, and content,
+//     count and hierarchyMeaning that do not match the concepts
+package authoring
+
+import (
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/searchparam"
+	"github.com/gofhir/validator/pkg/terminology"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Validator checks conformance resources for IG authors.
+type Validator struct {
+	registry     *registry.Registry
+	termRegistry *terminology.Registry
+	searchParams *searchparam.Validator
+	walker       *walker.Walker
+}
+
+// New creates a new authoring Validator. ValueSet systems and filters are
+// resolved against termReg.
+func New(reg *registry.Registry, termReg *terminology.Registry) *Validator {
+	return &Validator{
+		registry:     reg,
+		termRegistry: termReg,
+		searchParams: searchparam.NewValidator(reg),
+		walker:       walker.New(reg),
+	}
+}
+
+// ValidateData checks the conformance resources of a pre-parsed resource:
+// the resource itself, contained resources and Bundle entries.
+func (v *Validator) ValidateData(resource map[string]any, result *issue.Result) {
+	v.searchParams.ValidateData(resource, result)
+
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+	v.validate(resource, resourceType, resourceType, result)
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
+		if rc.FHIRPath != resourceType {
+			v.validate(rc.Data, rc.ResourceType, rc.FHIRPath, result)
+		}
+		return true
+	})
+}
+
+// validate checks one conformance resource.
+func (v *Validator) validate(data map[string]any, resourceType, fhirPath string, result *issue.Result) {
+	switch resourceType {
+	case "StructureDefinition":
+		v.validateStructureDefinition(data, fhirPath, result)
+	case "ValueSet":
+		v.validateValueSet(data, fhirPath, result)
+	case "CodeSystem":
+		v.validateCodeSystem(data, fhirPath, result)
+	}
+}
@@ -0,0 +1,500 @@
+package authoring
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/terminology"
+)
+
+var (
+	testRegistry     *registry.Registry
+	testTermRegistry *terminology.Registry
+	testRegistryErr  error
+	testRegistryOnce sync.Once
+)
+
+// testCodeSystem is loaded with the embedded R4 packages for filter checks.
+const testCodeSystem = `{"resourceType": "CodeSystem", "id": "colors", "url": "http://example.org/CodeSystem/colors",
+	"status": "draft", "content": "complete", "hierarchyMeaning": "is-a",
+	"filter": [{"code": "hue", "operator": ["=", "in"], "value": "a hue"}],
+	"property": [{"code": "shade", "type": "code"}],
+	"concept": [{"code": "red", "display": "Red", "concept": [{"code": "crimson", "display": "Crimson"}]}]}`
+
+// getTestRegistry loads the embedded R4 packages once for all tests.
+func getTestRegistry(t *testing.T) *registry.Registry {
+	t.Helper()
+	testRegistryOnce.Do(func() {
+		packages := testutil.Packages(t, "4.0.1")
+		pkg, err := loader.NewLoader("").LoadFromResources([][]byte{[]byte(testCodeSystem)})
+		if err != nil {
+			testRegistryErr = err
+			return
+		}
+		packages = append(packages, pkg)
+		testRegistry = registry.New()
+		if testRegistryErr = testRegistry.LoadFromPackages(packages); testRegistryErr != nil {
+			return
+		}
+		testTermRegistry = terminology.NewRegistry()
+		testRegistryErr = testTermRegistry.LoadFromPackages(packages)
+	})
+	if testRegistryErr != nil {
+		t.Fatalf("Failed to load registry: %v", testRegistryErr)
+	}
+	return testRegistry
+}
+
+// newTestValidator creates a Validator over the test registries.
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+	reg := getTestRegistry(t)
+	return New(reg, testTermRegistry)
+}
+
+// element builds an ElementDefinition.
+func element(path string, fields ...any) map[string]any {
+	ed := map[string]any{"id": path, "path": path}
+	for i := 0; i+1 < len(fields); i += 2 {
+		ed[fields[i].(string)] = fields[i+1]
+	}
+	return ed
+}
+
+// profile builds an Observation profile with a differential.
+func profile(elements ...any) map[string]any {
+	return map[string]any{
+		"resourceType":   "StructureDefinition",
+		"url":            "http://example.org/StructureDefinition/my-observation",
+		"name":           "MyObservation",
+		"status":         "draft",
+		"kind":           "resource",
+		"abstract":       false,
+		"type":           "Observation",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Observation",
+		"derivation":     "constraint",
+		"differential":   map[string]any{"element": elements},
+	}
+}
+
+func typeList(codes ...string) []any {
+	types := make([]any, 0, len(codes))
+	for _, code := range codes {
+		types = append(types, map[string]any{"code": code})
+	}
+	return types
+}
+
+func TestValidateStructureDefinition(t *testing.T) {
+	componentSlicing := map[string]any{
+		"discriminator": []any{map[string]any{"type": "pattern", "path": "code"}},
+		"rules":         "open",
+	}
+
+	tests := []struct {
+		name     string
+		resource map[string]any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "valid profile",
+			resource: profile(
+				element("Observation"),
+				element("Observation.status", "binding", map[string]any{"strength": "required", "valueSet": "http://example.org/ValueSet/status"}),
+				element("Observation.code", "binding", map[string]any{"strength": "required", "valueSet": "http://example.org/ValueSet/codes"}),
+				element("Observation.subject", "type", []any{map[string]any{
+					"code": "Reference", "targetProfile": []any{"http://hl7.org/fhir/StructureDefinition/Patient"},
+				}}),
+				element("Observation.effective[x]", "type", typeList("dateTime", "Period")),
+				element("Observation.valueQuantity", "type", typeList("Quantity")),
+				element("Observation.valueQuantity.value", "min", 1),
+				element("Observation.component", "slicing", componentSlicing),
+				element("Observation.component", "sliceName", "systolic"),
+				element("Observation.component.code"),
+				element("Observation.component.referenceRange"),
+				element("Observation.component", "sliceName", "diastolic"),
+				element("Observation.component.value[x]", "type", typeList("Quantity")),
+			),
+		},
+		{
+			name: "element before its predecessor in the base",
+			resource: profile(
+				element("Observation"),
+				element("Observation.code"),
+				element("Observation.status"),
+			),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSDElementOrder},
+			wantPath: "StructureDefinition.differential.element[2]",
+		},
+		{
+			name: "datatype children out of order",
+			resource: profile(
+				element("Observation"),
+				element("Observation.code.text"),
+				element("Observation.code.coding"),
+			),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSDElementOrder},
+			wantPath: "StructureDefinition.differential.element[2]",
+		},
+		{
+			name: "slice without slicing",
+			resource: profile(
+				element("Observation"),
+				element("Observation.component", "sliceName", "systolic"),
+			),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSDSlicingMissing},
+			wantPath: "StructureDefinition.differential.element[1]",
+		},
+		{
+			name: "slicing declared after the slice",
+			resource: profile(
+				element("Observation"),
+				element("Observation.component", "sliceName", "systolic"),
+				element("Observation.component", "slicing", componentSlicing),
+			),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSDSlicingMissing},
+			wantPath: "StructureDefinition.differential.element[1]",
+		},
+		{
+			name: "extension slices need no slicing in the differential",
+			resource: profile(
+				element("Observation"),
+				element("Observation.extension", "sliceName", "note", "type", []any{map[string]any{
+					"code": "Extension", "profile": []any{"http://example.org/StructureDefinition/note"},
+				}}),
+			),
+		},
+		{
+			name: "type not allowed by the base",
+			resource: profile(
+				element("Observation"),
+				element("Observation.effective[x]", "type", typeList("dateTime", "string")),
+			),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSDTypeNotInBase},
+			wantPath: "StructureDefinition.differential.element[1].type[1]",
+		},
+		{
+			name: "restricted choice types",
+			resource: profile(
+				element("Observation"),
+				element("Observation.value[x]", "type", typeList("Quantity")),
+				element("Observation.component.value[x]", "type", typeList("Quantity")),
+			),
+		},
+		{
+			name: "target profile not allowed by the base",
+			resource: profile(
+				element("Observation"),
+				element("Observation.subject", "type", []any{map[string]any{
+					"code": "Reference", "targetProfile": []any{"http://hl7.org/fhir/StructureDefinition/Practitioner"},
+				}}),
+			),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSDTargetNotInBase},
+			wantPath: "StructureDefinition.differential.element[1].type[0].targetProfile[0]",
+		},
+		{
+			name: "binding weaker than the base",
+			resource: profile(
+				element("Observation"),
+				element("Observation.status", "binding", map[string]any{"strength": "extensible", "valueSet": "http://example.org/ValueSet/status"}),
+			),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSDBindingWeaker},
+			wantPath: "StructureDefinition.differential.element[1].binding.strength",
+		},
+		{
+			name: "snapshot element without parent",
+			resource: map[string]any{
+				"resourceType": "StructureDefinition", "url": "http://example.org/StructureDefinition/Model",
+				"name": "Model", "status": "draft", "kind": "logical", "abstract": false, "type": "Model",
+				"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Element", "derivation": "specialization",
+				"snapshot": map[string]any{"element": []any{
+					element("Model"),
+					element("Model.part.name"),
+					element("Model.part"),
+				}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSDElementParent},
+			wantPath: "StructureDefinition.snapshot.element[1]",
+		},
+	}
+
+	v := newTestValidator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, result)
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", testutil.IssueIDs(result), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestValidateDataSearchParameter(t *testing.T) {
+	v := newTestValidator(t)
+	result := issue.NewResult()
+	v.ValidateData(map[string]any{
+		"resourceType": "SearchParameter", "type": "token", "expression": "Patient.gendr", "base": []any{"Patient"},
+	}, result)
+	if len(result.Issues) != 1 || result.Issues[0].MessageID != string(issue.DiagSearchParamExpressionElement) {
+		t.Errorf("issues = %v", testutil.IssueIDs(result))
+	}
+}
+
+// valueSet builds a ValueSet with a compose include.
+func valueSet(include map[string]any) map[string]any {
+	return map[string]any{
+		"resourceType": "ValueSet", "url": "http://example.org/ValueSet/colors", "status": "draft",
+		"compose": map[string]any{"include": []any{include}},
+	}
+}
+
+// filter builds a compose include filter.
+func filter(property, op, value string) []any {
+	return []any{map[string]any{"property": property, "op": op, "value": value}}
+}
+
+func TestValidateValueSet(t *testing.T) {
+	const colors = "http://example.org/CodeSystem/colors"
+	tests := []struct {
+		name     string
+		resource map[string]any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name:     "loaded system",
+			resource: valueSet(map[string]any{"system": colors, "concept": []any{map[string]any{"code": "red"}}}),
+		},
+		{
+			name:     "external system",
+			resource: valueSet(map[string]any{"system": "http://loinc.org", "concept": []any{map[string]any{"code": "1234-5"}}}),
+		},
+		{
+			name:     "unresolved system",
+			resource: valueSet(map[string]any{"system": "http://example.org/CodeSystem/unknown"}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueSetSystemUnresolved},
+			wantPath: "ValueSet.compose.include[0].system",
+		},
+		{
+			name: "duplicate concept",
+			resource: valueSet(map[string]any{"system": colors, "concept": []any{
+				map[string]any{"code": "red"}, map[string]any{"code": "crimson"}, map[string]any{"code": "red"},
+			}}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueSetConceptDuplicate},
+			wantPath: "ValueSet.compose.include[0].concept[2]",
+		},
+		{
+			name:     "declared filter",
+			resource: valueSet(map[string]any{"system": colors, "filter": filter("hue", "in", "warm,cool")}),
+		},
+		{
+			name:     "declared filter with undeclared operator",
+			resource: valueSet(map[string]any{"system": colors, "filter": filter("hue", "is-a", "warm")}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueSetFilterOperatorInvalid},
+			wantPath: "ValueSet.compose.include[0].filter[0].op",
+		},
+		{
+			name:     "concept hierarchy filter",
+			resource: valueSet(map[string]any{"system": colors, "filter": filter("concept", "is-a", "red")}),
+		},
+		{
+			name:     "property filter",
+			resource: valueSet(map[string]any{"system": colors, "filter": filter("shade", "=", "dark")}),
+		},
+		{
+			name:     "hierarchy operator on property",
+			resource: valueSet(map[string]any{"system": colors, "filter": filter("shade", "descendent-of", "dark")}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueSetFilterOperatorInvalid},
+			wantPath: "ValueSet.compose.include[0].filter[0].op",
+		},
+		{
+			name:     "unknown filter property",
+			resource: valueSet(map[string]any{"system": colors, "filter": filter("texture", "=", "matte")}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueSetFilterPropertyUnknown},
+			wantPath: "ValueSet.compose.include[0].filter[0].property",
+		},
+		{
+			name:     "filter on external system",
+			resource: valueSet(map[string]any{"system": "http://snomed.info/sct", "filter": filter("constraint", "=", "<< 404684003")}),
+		},
+		{
+			name: "contained",
+			resource: map[string]any{
+				"resourceType": "Questionnaire", "status": "draft",
+				"contained": []any{valueSet(map[string]any{"system": colors, "filter": filter("texture", "=", "matte")})},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueSetFilterPropertyUnknown},
+			wantPath: "Questionnaire.contained[0].compose.include[0].filter[0].property",
+		},
+	}
+
+	v := newTestValidator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, result)
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", testutil.IssueIDs(result), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
+
+// codeSystem builds a CodeSystem with content and concepts.
+func codeSystem(content string, concepts ...any) map[string]any {
+	cs := map[string]any{
+		"resourceType": "CodeSystem", "url": "http://example.org/CodeSystem/sizes", "status": "draft", "content": content,
+	}
+	if len(concepts) > 0 {
+		cs["concept"] = concepts
+	}
+	return cs
+}
+
+// concept builds a CodeSystem concept with a display and child concepts.
+func concept(code string, children ...any) map[string]any {
+	c := map[string]any{"code": code, "display": code}
+	if len(children) > 0 {
+		c["concept"] = children
+	}
+	return c
+}
+
+// with sets fields of a resource.
+func with(resource map[string]any, fields ...any) map[string]any {
+	for i := 0; i+1 < len(fields); i += 2 {
+		resource[fields[i].(string)] = fields[i+1]
+	}
+	return resource
+}
+
+func TestValidateCodeSystem(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource map[string]any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name:     "valid",
+			resource: with(codeSystem("complete", concept("small"), concept("large")), "count", 2),
+		},
+		{
+			name:     "duplicate nested code",
+			resource: codeSystem("complete", concept("small"), concept("large", concept("small"))),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemConceptDuplicate, issue.DiagCodeSystemHierarchyMeaningMissing},
+			wantPath: "CodeSystem.concept[1].concept[0]",
+		},
+		{
+			name:     "missing display",
+			resource: codeSystem("complete", map[string]any{"code": "small"}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemDisplayMissing},
+			wantPath: "CodeSystem.concept[0]",
+		},
+		{
+			name:     "supplement without display",
+			resource: codeSystem("supplement", map[string]any{"code": "small"}),
+		},
+		{
+			name:     "not-present with concepts",
+			resource: codeSystem("not-present", concept("small")),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemContentMismatch},
+			wantPath: "CodeSystem.content",
+		},
+		{
+			name:     "complete without concepts",
+			resource: codeSystem("complete"),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemContentMismatch},
+			wantPath: "CodeSystem.content",
+		},
+		{
+			name:     "not-present without concepts",
+			resource: with(codeSystem("not-present"), "count", 5000),
+		},
+		{
+			name:     "complete count mismatch",
+			resource: with(codeSystem("complete", concept("small"), concept("large", concept("huge"))), "count", 2, "hierarchyMeaning", "is-a"),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemCountMismatch},
+			wantPath: "CodeSystem.count",
+		},
+		{
+			name:     "fragment counts the whole system",
+			resource: with(codeSystem("fragment", concept("small")), "count", 30),
+		},
+		{
+			name:     "fragment count below concepts",
+			resource: with(codeSystem("fragment", concept("small"), concept("large")), "count", 1),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemCountMismatch},
+			wantPath: "CodeSystem.count",
+		},
+		{
+			name:     "nested without hierarchyMeaning",
+			resource: codeSystem("complete", concept("large", concept("huge"))),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemHierarchyMeaningMissing},
+			wantPath: "CodeSystem",
+		},
+		{
+			name: "parent property without hierarchyMeaning",
+			resource: codeSystem("complete", concept("large"), with(concept("huge"), "property", []any{
+				map[string]any{"code": "parent", "valueCode": "large"},
+			})),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemHierarchyMeaningMissing},
+			wantPath: "CodeSystem",
+		},
+		{
+			name: "parent property with unknown code",
+			resource: with(codeSystem("complete", with(concept("huge"), "property", []any{
+				map[string]any{"code": "subsumedBy", "valueCode": "large"},
+			})), "hierarchyMeaning", "is-a"),
+			wantIDs:  []issue.DiagnosticID{issue.DiagCodeSystemHierarchyCodeUnknown},
+			wantPath: "CodeSystem.concept[0].property[0]",
+		},
+		{
+			name: "fragment parent defined elsewhere",
+			resource: with(codeSystem("fragment", with(concept("huge"), "property", []any{
+				map[string]any{"code": "parent", "valueCode": "large"},
+			})), "hierarchyMeaning", "is-a"),
+		},
+	}
+
+	v := newTestValidator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, result)
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", testutil.IssueIDs(result), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
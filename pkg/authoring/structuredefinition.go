@@ -0,0 +1,352 @@
+package authoring
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// bindingStrength ranks binding strengths from weakest to strongest.
+var bindingStrength = map[string]int{
+	"example":    1,
+	"preferred":  2,
+	"extensible": 3,
+	"required":   4,
+}
+
+// maxDerivationDepth bounds baseDefinition chains, which may be cyclic in
+// broken packages.
+const maxDerivationDepth = 32
+
+// elementList is the snapshot or differential of a StructureDefinition.
+type elementList struct {
+	name     string // snapshot | differential
+	elements []registry.ElementDefinition
+}
+
+// validateStructureDefinition checks the snapshot and differential of a
+// StructureDefinition.
+func (v *Validator) validateStructureDefinition(data map[string]any, fhirPath string, result *issue.Result) {
+	var sd registry.StructureDefinition
+	if !remarshal(data, &sd) {
+		return
+	}
+
+	// Base-dependent checks need the base snapshot; specializations add
+	// elements that are not in their base
+	var base *registry.StructureDefinition
+	if sd.Derivation == "constraint" {
+		if b := v.registry.GetByURL(sd.BaseDefinition); b != nil && b.Snapshot != nil && len(b.Snapshot.Element) > 0 {
+			base = b
+		}
+	}
+
+	var lists []elementList
+	if sd.Snapshot != nil {
+		lists = append(lists, elementList{"snapshot", sd.Snapshot.Element})
+	}
+	if sd.Differential != nil {
+		lists = append(lists, elementList{"differential", sd.Differential.Element})
+	}
+	for _, list := range lists {
+		listPath := fhirPath + "." + list.name + ".element"
+		if list.name == "snapshot" {
+			v.checkHierarchy(list.elements, listPath, result)
+		}
+		if base != nil {
+			v.checkOrder(base, list.elements, listPath, result)
+		}
+		v.checkSlicing(base, list, listPath, result)
+	}
+
+	// Types and bindings are checked once, where the author changed them
+	if base != nil && len(lists) > 0 {
+		list := lists[len(lists)-1]
+		v.checkConstraints(base, list.elements, fhirPath+"."+list.name+".element", result)
+	}
+}
+
+// checkHierarchy checks that every snapshot element follows its parent.
+func (v *Validator) checkHierarchy(elements []registry.ElementDefinition, listPath string, result *issue.Result) {
+	seen := make(map[string]bool, len(elements))
+	for i := range elements {
+		path := elements[i].Path
+		if i > 0 {
+			parent := path[:max(strings.LastIndex(path, "."), 0)]
+			if !seen[parent] {
+				result.AddErrorWithID(
+					issue.DiagSDElementParent,
+					issue.Params{issue.String("path", path), issue.String("parent", parent)},
+					fmt.Sprintf("%s[%d]", listPath, i),
+				)
+			}
+		}
+		seen[path] = true
+	}
+}
+
+// checkOrder checks that elements are in the order of the base: each
+// element after the previous one, except that a slice may return to the
+// element it slices after the previous slice's descendants.
+func (v *Validator) checkOrder(base *registry.StructureDefinition, elements []registry.ElementDefinition, listPath string, result *issue.Result) {
+	var prev []int
+	prevPath := ""
+	for i := range elements {
+		ed := &elements[i]
+		_, key := v.resolve(base.Snapshot.Element, ed.Path, "")
+		if key == nil {
+			continue // Not in the base: checked by its own rules
+		}
+		reentry := ed.SliceName != nil && (prevPath == ed.Path || strings.HasPrefix(prevPath, ed.Path+"."))
+		if prev != nil && slices.Compare(key, prev) < 0 && !reentry {
+			result.AddErrorWithID(
+				issue.DiagSDElementOrder,
+				issue.Params{issue.String("path", ed.Path), issue.String("previous", prevPath)},
+				fmt.Sprintf("%s[%d]", listPath, i),
+			)
+		}
+		prev, prevPath = key, ed.Path
+	}
+}
+
+// checkSlicing checks that each slice is preceded by the element it slices,
+// declaring slicing. Choice elements are implicitly sliced by type, and
+// differentials may rely on slicing declared by the base and on the implicit
+// slicing of extensions.
+func (v *Validator) checkSlicing(base *registry.StructureDefinition, list elementList, listPath string, result *issue.Result) {
+	for i := range list.elements {
+		ed := &list.elements[i]
+		if ed.SliceName == nil || strings.Contains(*ed.SliceName, "/") || strings.HasSuffix(ed.Path, "[x]") {
+			continue // Re-slices are declared on their slice, choices sliced by type
+		}
+		var sliced *registry.ElementDefinition
+		for j := i - 1; j >= 0; j-- {
+			if e := &list.elements[j]; e.Path == ed.Path && e.SliceName == nil {
+				sliced = e
+				break
+			}
+		}
+		if sliced != nil && sliced.Slicing != nil {
+			continue
+		}
+		if list.name == "differential" {
+			if strings.HasSuffix(ed.Path, ".extension") || strings.HasSuffix(ed.Path, ".modifierExtension") {
+				continue
+			}
+			if base != nil {
+				if b, _ := v.resolve(base.Snapshot.Element, ed.Path, ""); b != nil && b.Slicing != nil {
+					continue
+				}
+			}
+		}
+		result.AddErrorWithID(
+			issue.DiagSDSlicingMissing,
+			issue.Params{issue.String("slice", *ed.SliceName), issue.String("path", ed.Path)},
+			fmt.Sprintf("%s[%d]", listPath, i),
+		)
+	}
+}
+
+// checkConstraints checks that types, target profiles and bindings restrict
+// those of the base element.
+func (v *Validator) checkConstraints(base *registry.StructureDefinition, elements []registry.ElementDefinition, listPath string, result *issue.Result) {
+	for i := range elements {
+		ed := &elements[i]
+		sliceName := ""
+		if ed.SliceName != nil {
+			sliceName = *ed.SliceName
+		}
+		baseED, _ := v.resolve(base.Snapshot.Element, ed.Path, sliceName)
+		if baseED == nil {
+			continue
+		}
+		elemPath := fmt.Sprintf("%s[%d]", listPath, i)
+
+		if len(baseED.Type) > 0 {
+			for j, t := range ed.Type {
+				bt := v.baseType(baseED, t.Code)
+				if bt == nil {
+					result.AddErrorWithID(
+						issue.DiagSDTypeNotInBase,
+						issue.Params{issue.String("type", t.Code), issue.String("path", ed.Path), issue.String("base", typeCodes(baseED))},
+						fmt.Sprintf("%s.type[%d]", elemPath, j),
+					)
+					continue
+				}
+				v.checkTargets(t, bt, ed.Path, fmt.Sprintf("%s.type[%d]", elemPath, j), result)
+			}
+		}
+
+		if ed.Binding != nil && baseED.Binding != nil {
+			strength, baseStrength := bindingStrength[ed.Binding.Strength], bindingStrength[baseED.Binding.Strength]
+			if strength > 0 && strength < baseStrength {
+				result.AddErrorWithID(
+					issue.DiagSDBindingWeaker,
+					issue.Params{issue.String("path", ed.Path), issue.String("strength", ed.Binding.Strength), issue.String("base", baseED.Binding.Strength)},
+					elemPath+".binding.strength",
+				)
+			}
+		}
+	}
+}
+
+// checkTargets checks that the target profiles of a reference type are
+// allowed by the target profiles of the base type.
+func (v *Validator) checkTargets(t registry.Type, bt *registry.Type, path, typePath string, result *issue.Result) {
+	if len(bt.TargetProfile) == 0 {
+		return
+	}
+	for k, target := range t.TargetProfile {
+		if slices.Contains(bt.TargetProfile, target) {
+			continue
+		}
+		targetSD := v.registry.GetByURL(target)
+		if targetSD == nil {
+			continue // Unresolved profiles are reported elsewhere
+		}
+		if !slices.ContainsFunc(bt.TargetProfile, func(baseTarget string) bool {
+			baseSD := v.registry.GetByURL(baseTarget)
+			return baseSD == nil || v.derivesFrom(targetSD.Type, baseSD.Type)
+		}) {
+			result.AddErrorWithID(
+				issue.DiagSDTargetNotInBase,
+				issue.Params{issue.String("target", target), issue.String("path", path), issue.String("base", strings.Join(bt.TargetProfile, ", "))},
+				fmt.Sprintf("%s.targetProfile[%d]", typePath, k),
+			)
+		}
+	}
+}
+
+// baseType returns the type of a base element that a type code restricts,
+// or nil if the code is not a restriction of any of them.
+func (v *Validator) baseType(baseED *registry.ElementDefinition, code string) *registry.Type {
+	for i := range baseED.Type {
+		if bt := &baseED.Type[i]; v.derivesFrom(code, bt.Code) {
+			return bt
+		}
+	}
+	return nil
+}
+
+// derivesFrom reports whether a type is, or specializes, a base type (e.g.,
+// Age from Quantity, Patient from Resource). FHIRPath system types, such as
+// that of Extension.url, admit the FHIR primitive types.
+func (v *Validator) derivesFrom(typeName, baseType string) bool {
+	if typeName == baseType {
+		return true
+	}
+	if strings.HasPrefix(baseType, "http://hl7.org/fhirpath/System.") {
+		return v.registry.IsPrimitiveType(typeName)
+	}
+	sd := v.registry.GetByType(typeName)
+	for depth := 0; sd != nil && depth < maxDerivationDepth; depth++ {
+		if sd.Type == baseType {
+			return true
+		}
+		sd = v.registry.GetByURL(sd.BaseDefinition)
+	}
+	return false
+}
+
+// resolve finds the base element of a path in the snapshot elements of a
+// StructureDefinition, following choice type names (valueQuantity),
+// content references and the datatypes of elements. It also returns the
+// element's position: its index in elements, followed by its position in the
+// datatype definitions descended into.
+func (v *Validator) resolve(elements []registry.ElementDefinition, path, sliceName string) (*registry.ElementDefinition, []int) {
+	segments := strings.Split(path, ".")
+	for k := len(segments); k >= 1; k-- {
+		prefix := strings.Join(segments[:k], ".")
+		name := ""
+		if k == len(segments) {
+			name = sliceName
+		}
+		idx, typeName := findElement(elements, prefix, name)
+		if idx < 0 {
+			continue
+		}
+		ed := &elements[idx]
+		if k == len(segments) {
+			return ed, []int{idx}
+		}
+
+		rest := strings.Join(segments[k:], ".")
+		if ed.ContentReference != nil {
+			_, ref, _ := strings.Cut(*ed.ContentReference, "#")
+			if ref == "" || ref == prefix {
+				return nil, nil
+			}
+			sub, key := v.resolve(elements, ref+"."+rest, sliceName)
+			if sub == nil {
+				return nil, nil
+			}
+			return sub, append([]int{idx}, key...)
+		}
+		if typeName == "" && len(ed.Type) == 1 {
+			typeName = ed.Type[0].Code
+		}
+		typeSD := v.registry.GetByType(typeName)
+		if typeSD == nil || typeSD.Snapshot == nil {
+			return nil, nil
+		}
+		sub, key := v.resolve(typeSD.Snapshot.Element, typeName+"."+rest, sliceName)
+		if sub == nil {
+			return nil, nil
+		}
+		return sub, append([]int{idx}, key...)
+	}
+	return nil, nil
+}
+
+// findElement returns the index of the element with a path, preferring the
+// slice with sliceName and otherwise the unsliced element. A type-specific
+// choice name (Observation.valueQuantity) matches the choice element, and
+// its type is returned.
+func findElement(elements []registry.ElementDefinition, path, sliceName string) (int, string) {
+	found := -1
+	for i := range elements {
+		e := &elements[i]
+		if e.Path != path {
+			continue
+		}
+		if sliceName != "" && e.SliceName != nil && *e.SliceName == sliceName {
+			return i, ""
+		}
+		if e.SliceName == nil && found < 0 {
+			found = i
+		}
+	}
+	if found >= 0 {
+		return found, ""
+	}
+
+	for i := range elements {
+		e := &elements[i]
+		choice, ok := strings.CutSuffix(e.Path, "[x]")
+		if !ok || e.SliceName != nil {
+			continue
+		}
+		suffix, ok := strings.CutPrefix(path, choice)
+		if !ok || suffix == "" || suffix[0] < 'A' || suffix[0] > 'Z' || strings.Contains(suffix, ".") {
+			continue
+		}
+		for _, t := range e.Type {
+			if strings.EqualFold(t.Code, suffix) {
+				return i, t.Code
+			}
+		}
+	}
+	return -1, ""
+}
+
+// typeCodes lists the type codes of an element for messages.
+func typeCodes(ed *registry.ElementDefinition) string {
+	codes := make([]string, 0, len(ed.Type))
+	for _, t := range ed.Type {
+		codes = append(codes, t.Code)
+	}
+	return strings.Join(codes, ", ")
+}
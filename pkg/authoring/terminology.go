@@ -0,0 +1,226 @@
+package authoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/terminology"
+)
+
+// conceptFilterOperators are the operators of the implicit filters on the
+// concept (or code) itself, which every CodeSystem supports.
+var conceptFilterOperators = []string{"=", "is-a", "descendent-of", "is-not-a", "regex", "in", "not-in", "generalizes"}
+
+// propertyFilterOperators are the operators of filters on a concept
+// property that the CodeSystem does not declare as a filter.
+var propertyFilterOperators = []string{"=", "in", "not-in", "regex", "exists"}
+
+// standardProperties are the concept properties defined by FHIR for every
+// CodeSystem (http://hl7.org/fhir/concept-properties).
+var standardProperties = map[string]bool{
+	"inactive":      true,
+	"deprecated":    true,
+	"notSelectable": true,
+	"parent":        true,
+	"child":         true,
+	"status":        true,
+}
+
+// hierarchyProperties are the concept properties that place a concept in a
+// hierarchy, besides nesting.
+var hierarchyProperties = map[string]bool{
+	"parent":     true,
+	"child":      true,
+	"subsumedBy": true,
+}
+
+// validateValueSet checks the systems, filters and concepts of a ValueSet's
+// compose.
+func (v *Validator) validateValueSet(data map[string]any, fhirPath string, result *issue.Result) {
+	var vs terminology.ValueSet
+	if !remarshal(data, &vs) {
+		return
+	}
+	for _, part := range []struct {
+		name     string
+		includes []terminology.Include
+	}{{"include", vs.Compose.Include}, {"exclude", vs.Compose.Exclude}} {
+		for i := range part.includes {
+			v.checkInclude(&part.includes[i], fmt.Sprintf("%s.compose.%s[%d]", fhirPath, part.name, i), result)
+		}
+	}
+}
+
+// checkInclude checks one include or exclude of a ValueSet.
+func (v *Validator) checkInclude(inc *terminology.Include, incPath string, result *issue.Result) {
+	if inc.System == "" {
+		return // Only ValueSets are included
+	}
+
+	var cs *terminology.CodeSystem
+	if v.termRegistry != nil {
+		url := inc.System
+		if inc.Version != "" {
+			url += "|" + inc.Version
+		}
+		cs = v.termRegistry.GetCodeSystem(url)
+		if cs == nil && !v.termRegistry.IsExternalSystem(inc.System) && !v.termRegistry.IsImplicitSystem(inc.System) {
+			result.AddWarningWithID(
+				issue.DiagValueSetSystemUnresolved,
+				issue.Params{issue.String("system", url)},
+				incPath+".system",
+			)
+		}
+	}
+
+	seen := make(map[string]bool, len(inc.Concept))
+	for j, c := range inc.Concept {
+		if seen[c.Code] {
+			result.AddWarningWithID(
+				issue.DiagValueSetConceptDuplicate,
+				issue.Params{issue.String("code", c.Code), issue.String("system", inc.System)},
+				fmt.Sprintf("%s.concept[%d]", incPath, j),
+			)
+		}
+		seen[c.Code] = true
+	}
+
+	if cs == nil || cs.Content == "not-present" {
+		return // Filters can only be checked against the definition of the system
+	}
+	for j, f := range inc.Filter {
+		v.checkFilter(cs, f, fmt.Sprintf("%s.filter[%d]", incPath, j), result)
+	}
+}
+
+// checkFilter checks that a CodeSystem supports a filter's property and
+// operator: filters it declares with their operators, the concept itself,
+// and its properties with the operators that compare values.
+func (v *Validator) checkFilter(cs *terminology.CodeSystem, f terminology.Filter, filterPath string, result *issue.Result) {
+	var allowed []string
+	switch {
+	case slices.ContainsFunc(cs.Filter, func(d terminology.CodeSystemFilter) bool { return d.Code == f.Property }):
+		for _, d := range cs.Filter {
+			if d.Code == f.Property {
+				allowed = append(allowed, d.Operator...)
+			}
+		}
+	case f.Property == "concept" || f.Property == "code":
+		allowed = conceptFilterOperators
+	case standardProperties[f.Property] || slices.ContainsFunc(cs.Property, func(d terminology.CodeSystemPropertyDefinition) bool { return d.Code == f.Property }):
+		allowed = propertyFilterOperators
+	default:
+		result.AddErrorWithID(
+			issue.DiagValueSetFilterPropertyUnknown,
+			issue.Params{issue.String("property", f.Property), issue.String("system", cs.URL)},
+			filterPath+".property",
+		)
+		return
+	}
+	if f.Op != "" && !slices.Contains(allowed, f.Op) {
+		result.AddErrorWithID(
+			issue.DiagValueSetFilterOperatorInvalid,
+			issue.Params{issue.String("op", f.Op), issue.String("property", f.Property), issue.String("system", cs.URL), issue.String("allowed", strings.Join(allowed, ", "))},
+			filterPath+".op",
+		)
+	}
+}
+
+// validateCodeSystem checks the concepts of a CodeSystem against each other
+// and against its content, count and hierarchyMeaning.
+func (v *Validator) validateCodeSystem(data map[string]any, fhirPath string, result *issue.Result) {
+	var cs terminology.CodeSystem
+	if !remarshal(data, &cs) {
+		return
+	}
+
+	seen := make(map[string]bool)
+	total, hierarchy := 0, false
+	type link struct{ property, code, path string }
+	var links []link
+	var walk func(concepts []terminology.CodeSystemCode, listPath string)
+	walk = func(concepts []terminology.CodeSystemCode, listPath string) {
+		for i := range concepts {
+			c := &concepts[i]
+			conceptPath := fmt.Sprintf("%s[%d]", listPath, i)
+			total++
+			if seen[c.Code] {
+				result.AddErrorWithID(
+					issue.DiagCodeSystemConceptDuplicate,
+					issue.Params{issue.String("code", c.Code)},
+					conceptPath,
+				)
+			}
+			seen[c.Code] = true
+			// Supplements add designations and properties to concepts defined elsewhere
+			if c.Display == "" && cs.Content != "supplement" {
+				result.AddInfoWithID(
+					issue.DiagCodeSystemDisplayMissing,
+					issue.Params{issue.String("code", c.Code)},
+					conceptPath,
+				)
+			}
+			if len(c.Concept) > 0 {
+				hierarchy = true
+			}
+			for j, p := range c.Property {
+				if hierarchyProperties[p.Code] && p.ValueCode != "" {
+					hierarchy = true
+					links = append(links, link{p.Code, p.ValueCode, fmt.Sprintf("%s.property[%d]", conceptPath, j)})
+				}
+			}
+			walk(c.Concept, conceptPath+".concept")
+		}
+	}
+	walk(cs.Concept, fhirPath+".concept")
+
+	// Links are checked once every code is known; supplements and fragments
+	// may link to concepts defined elsewhere
+	if cs.Content == "complete" {
+		for _, l := range links {
+			if !seen[l.code] {
+				result.AddErrorWithID(
+					issue.DiagCodeSystemHierarchyCodeUnknown,
+					issue.Params{issue.String("property", l.property), issue.String("code", l.code)},
+					l.path,
+				)
+			}
+		}
+	}
+
+	if (cs.Content == "not-present" && total > 0) || (cs.Content == "complete" && total == 0) {
+		result.AddWarningWithID(
+			issue.DiagCodeSystemContentMismatch,
+			issue.Params{issue.String("content", cs.Content), issue.Int("concepts", total)},
+			fhirPath+".content",
+		)
+	}
+
+	// A fragment or example counts the concepts of the whole system
+	if cs.Count != nil && cs.Content != "supplement" && cs.Content != "not-present" &&
+		(*cs.Count < total || (cs.Content == "complete" && *cs.Count != total)) {
+		result.AddWarningWithID(
+			issue.DiagCodeSystemCountMismatch,
+			issue.Params{issue.Int("count", *cs.Count), issue.Int("concepts", total)},
+			fhirPath+".count",
+		)
+	}
+
+	if hierarchy && cs.HierarchyMeaning == "" {
+		result.AddWarningWithID(issue.DiagCodeSystemHierarchyMeaningMissing, nil, fhirPath)
+	}
+}
+
+// remarshal decodes a pre-parsed resource into a typed value, reporting
+// whether it could. Resources that cannot be decoded are reported by the
+// structural phase.
+func remarshal(data map[string]any, v any) bool {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
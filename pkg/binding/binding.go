@@ -2,16 +2,26 @@
 package binding
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/gofhir/validator/pkg/issue"
 	"github.com/gofhir/validator/pkg/registry"
 	"github.com/gofhir/validator/pkg/terminology"
+	"github.com/gofhir/validator/pkg/ucum"
 	"github.com/gofhir/validator/pkg/walker"
 )
 
+// SystemVersionExtension is the URL of the binding extension that pins the
+// version of a code system the binding draws codes from, as "system|version"
+// (valueCanonical, valueUri or valueString), like the system-version
+// parameter of $expand. It may repeat for several systems.
+const SystemVersionExtension = "http://hl7.org/fhir/StructureDefinition/elementdefinition-bindingSystemVersion"
+
 // Binding strength constants.
 const (
 	strengthRequired   = "required"
@@ -23,6 +33,12 @@ type Validator struct {
 	sdRegistry   *registry.Registry
 	termRegistry *terminology.Registry
 	walker       *walker.Walker
+
+	// ucum validates Quantity units; checkUnits also checks them against the
+	// units declared by the profile (see SetUnitConsistency).
+	ucum            ucum.Service
+	checkUnits      bool
+	quantityIndexes sync.Map // *registry.StructureDefinition -> *quantityIndex
 }
 
 // New creates a new binding Validator.
@@ -31,6 +47,7 @@ func New(sdRegistry *registry.Registry, termRegistry *terminology.Registry) *Val
 		sdRegistry:   sdRegistry,
 		termRegistry: termRegistry,
 		walker:       walker.New(sdRegistry),
+		ucum:         ucum.Engine(),
 	}
 }
 
@@ -52,6 +69,13 @@ func (v *Validator) Validate(resourceData json.RawMessage, sd *registry.Structur
 // ValidateData validates bindings for a pre-parsed FHIR resource.
 // This is the preferred method when JSON has already been parsed to avoid redundant parsing.
 func (v *Validator) ValidateData(resource map[string]any, sd *registry.StructureDefinition, result *issue.Result) {
+	v.ValidateDataContext(context.Background(), resource, sd, result)
+}
+
+// ValidateDataContext is ValidateData with a context that is passed to the
+// external terminology provider. Validation stops early once ctx is done;
+// callers should treat the result as incomplete when ctx.Err() is non-nil.
+func (v *Validator) ValidateDataContext(ctx context.Context, resource map[string]any, sd *registry.StructureDefinition, result *issue.Result) {
 	if sd == nil || sd.Snapshot == nil {
 		return
 	}
@@ -61,38 +85,54 @@ func (v *Validator) ValidateData(resource map[string]any, sd *registry.Structure
 		return
 	}
 
-	// Validate root resource bindings
-	v.validateElement(resource, sd, resourceType, result)
+	// Validate root resource bindings and Quantity units
+	v.validateElement(ctx, resource, sd, resourceType, result)
+	v.validateQuantities(resource, sd, resourceType, resourceType, result)
 
 	// Walk all nested resources (contained + Bundle entries) using the generic walker.
 	// This replaces the duplicated validateContainedBindings, validateBundleEntryBindings,
 	// and validateContainedBindingsInEntry methods.
-	v.walker.Walk(resource, resourceType, resourceType, func(ctx *walker.ResourceContext) bool {
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
 		// Skip root resource (already validated above)
-		if ctx.FHIRPath == resourceType {
+		if rc.FHIRPath == resourceType {
 			return true
 		}
 
 		// Validate bindings in the nested resource
-		v.validateElementWithPaths(ctx.Data, ctx.SD, ctx.ResourceType, ctx.FHIRPath, result)
-		return true
+		v.validateElementWithPaths(ctx, rc.Data, rc.SD, rc.ResourceType, rc.FHIRPath, result)
+		v.validateQuantities(rc.Data, rc.SD, rc.ResourceType, rc.FHIRPath, result)
+		return ctx.Err() == nil
+	})
+}
+
+// Prefetch checks the codes of a resource that need the external terminology
+// provider ahead of ValidateDataContext, with at most concurrency requests in
+// flight, and returns a context to validate with that carries the answers.
+func (v *Validator) Prefetch(ctx context.Context, resource map[string]any, sd *registry.StructureDefinition, concurrency int) context.Context {
+	return v.termRegistry.Prefetch(ctx, concurrency, func(ctx context.Context) {
+		scratch := issue.GetPooledResult()
+		v.ValidateDataContext(ctx, resource, sd, scratch)
+		issue.ReleaseResult(scratch)
 	})
 }
 
 // validateElement recursively validates bindings for an element.
 // This is a convenience wrapper where sdPath and fhirPath are the same.
-func (v *Validator) validateElement(data map[string]any, sd *registry.StructureDefinition, basePath string, result *issue.Result) {
-	v.validateElementWithPaths(data, sd, basePath, basePath, result)
+func (v *Validator) validateElement(ctx context.Context, data map[string]any, sd *registry.StructureDefinition, basePath string, result *issue.Result) {
+	v.validateElementWithPaths(ctx, data, sd, basePath, basePath, result)
 }
 
 // ValidateElementWithPaths validates bindings with separate paths for SD lookup and error reporting.
 // SdPath is used to look up ElementDefinitions in the StructureDefinition.
 // FhirPath is used for error reporting (e.g., "Patient.contained[0].telecom").
-func (v *Validator) validateElementWithPaths(data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, result *issue.Result) {
+func (v *Validator) validateElementWithPaths(ctx context.Context, data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, result *issue.Result) {
 	for key, value := range data {
 		if key == "resourceType" {
 			continue
 		}
+		if ctx.Err() != nil {
+			return
+		}
 
 		elementSDPath := fmt.Sprintf("%s.%s", sdPath, key)
 		elementFhirPath := fmt.Sprintf("%s.%s", fhirPath, key)
@@ -103,31 +143,63 @@ func (v *Validator) validateElementWithPaths(data map[string]any, sd *registry.S
 			continue
 		}
 
-		// Check if this element has a binding
-		if elemDef.Binding != nil && elemDef.Binding.ValueSet != "" {
-			v.validateBinding(value, elemDef, elementFhirPath, result)
-		}
+		before := len(result.Issues)
+		v.validateValue(ctx, value, elemDef, elementFhirPath, result)
+		result.Attribute(before, issue.Source{StructureDefinition: sd.URL})
+	}
+}
 
-		// Recurse into complex types
-		switch val := value.(type) {
-		case map[string]any:
-			v.validateComplexElement(val, elemDef, elementFhirPath, result)
-		case []any:
-			for i, item := range val {
-				itemPath := fmt.Sprintf("%s[%d]", elementFhirPath, i)
-				if mapItem, ok := item.(map[string]any); ok {
-					v.validateComplexElement(mapItem, elemDef, itemPath, result)
-				} else if elemDef.Binding != nil {
-					// Array of primitives with binding (e.g., array of codes)
-					v.validatePrimitiveBinding(item, elemDef, itemPath, result)
-				}
+// ValidateElement validates the bindings of a single element value and its
+// descendants. ParentPath is the SD path of the element's parent (e.g.,
+// "Patient" or "HumanName"), name the element name and fhirPath the path of
+// value for error reporting (e.g., "Patient.contact[0].relationship").
+func (v *Validator) ValidateElement(ctx context.Context, value any, sd *registry.StructureDefinition, parentPath, name, fhirPath string, result *issue.Result) {
+	elemDef := v.findElementDef(sd, parentPath+"."+name)
+	if elemDef == nil {
+		return
+	}
+	before := len(result.Issues)
+	v.validateValue(ctx, value, elemDef, fhirPath, result)
+	result.Attribute(before, issue.Source{StructureDefinition: sd.URL})
+}
+
+// ValidateCoded checks a Coding or CodeableConcept against a ValueSet as a
+// required binding, whatever the strength its element declares. Rule packs
+// use it to hold elements to their value sets.
+func (v *Validator) ValidateCoded(ctx context.Context, value map[string]any, valueSet, fhirPath string, result *issue.Result) {
+	binding := &registry.Binding{Strength: strengthRequired, ValueSet: valueSet}
+	before := len(result.Issues)
+	v.validateMapBinding(ctx, value, binding, fhirPath, result)
+	result.Attribute(before, issue.Source{ValueSet: valueSet})
+}
+
+// validateValue validates the binding of an element value and recurses into
+// complex values.
+func (v *Validator) validateValue(ctx context.Context, value any, elemDef *registry.ElementDefinition, fhirPath string, result *issue.Result) {
+	// Check if this element has a binding
+	if elemDef.Binding != nil && elemDef.Binding.ValueSet != "" {
+		v.validateBinding(ctx, value, elemDef, fhirPath, result)
+	}
+
+	// Recurse into complex types
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateComplexElement(ctx, val, elemDef, fhirPath, result)
+	case []any:
+		for i, item := range val {
+			itemPath := fmt.Sprintf("%s[%d]", fhirPath, i)
+			if mapItem, ok := item.(map[string]any); ok {
+				v.validateComplexElement(ctx, mapItem, elemDef, itemPath, result)
+			} else if elemDef.Binding != nil {
+				// Array of primitives with binding (e.g., array of codes)
+				v.validatePrimitiveBinding(ctx, item, elemDef, itemPath, result)
 			}
 		}
 	}
 }
 
 // validateComplexElement validates bindings within a complex element.
-func (v *Validator) validateComplexElement(data map[string]any, parentDef *registry.ElementDefinition, basePath string, result *issue.Result) {
+func (v *Validator) validateComplexElement(ctx context.Context, data map[string]any, parentDef *registry.ElementDefinition, basePath string, result *issue.Result) {
 	// Get the type's StructureDefinition
 	if len(parentDef.Type) == 0 {
 		return
@@ -138,6 +210,8 @@ func (v *Validator) validateComplexElement(data map[string]any, parentDef *regis
 	if typeSD == nil || typeSD.Snapshot == nil {
 		return
 	}
+	before := len(result.Issues)
+	defer func() { result.Attribute(before, issue.Source{StructureDefinition: typeSD.URL}) }()
 
 	// Validate each field in the complex type
 	for key, value := range data {
@@ -159,18 +233,18 @@ func (v *Validator) validateComplexElement(data map[string]any, parentDef *regis
 
 		// Check binding on this element
 		if elemDef.Binding != nil && elemDef.Binding.ValueSet != "" {
-			v.validateBinding(value, elemDef, elementPath, result)
+			v.validateBinding(ctx, value, elemDef, elementPath, result)
 		}
 
 		// Recurse
 		switch val := value.(type) {
 		case map[string]any:
-			v.validateComplexElement(val, elemDef, elementPath, result)
+			v.validateComplexElement(ctx, val, elemDef, elementPath, result)
 		case []any:
 			for i, item := range val {
 				itemPath := fmt.Sprintf("%s[%d]", elementPath, i)
 				if mapItem, ok := item.(map[string]any); ok {
-					v.validateComplexElement(mapItem, elemDef, itemPath, result)
+					v.validateComplexElement(ctx, mapItem, elemDef, itemPath, result)
 				}
 			}
 		}
@@ -178,7 +252,7 @@ func (v *Validator) validateComplexElement(data map[string]any, parentDef *regis
 }
 
 // validateBinding validates a value against its binding.
-func (v *Validator) validateBinding(value any, elemDef *registry.ElementDefinition, fhirPath string, result *issue.Result) {
+func (v *Validator) validateBinding(ctx context.Context, value any, elemDef *registry.ElementDefinition, fhirPath string, result *issue.Result) {
 	binding := elemDef.Binding
 	if binding == nil {
 		return
@@ -189,28 +263,30 @@ func (v *Validator) validateBinding(value any, elemDef *registry.ElementDefiniti
 	if binding.Strength != strengthRequired && binding.Strength != strengthExtensible {
 		return
 	}
+	before := len(result.Issues)
+	defer func() { result.Attribute(before, issue.Source{ElementID: elemDef.ID, ValueSet: binding.ValueSet}) }()
 
 	// Handle different value types
 	switch val := value.(type) {
 	case string:
-		v.validateCodeBinding(val, "", binding, fhirPath, result)
+		v.validateCodeBinding(ctx, val, "", binding, fhirPath, result)
 
 	case map[string]any:
-		v.validateMapBinding(val, binding, fhirPath, result)
+		v.validateMapBinding(ctx, val, binding, fhirPath, result)
 
 	case []any:
 		for i, item := range val {
 			itemPath := fmt.Sprintf("%s[%d]", fhirPath, i)
-			v.validateBinding(item, elemDef, itemPath, result)
+			v.validateBinding(ctx, item, elemDef, itemPath, result)
 		}
 	}
 }
 
 // validateMapBinding validates a map value (Coding or CodeableConcept) against a binding.
-func (v *Validator) validateMapBinding(val map[string]any, binding *registry.Binding, fhirPath string, result *issue.Result) {
+func (v *Validator) validateMapBinding(ctx context.Context, val map[string]any, binding *registry.Binding, fhirPath string, result *issue.Result) {
 	// Check if it's a CodeableConcept with coding array
 	if coding, ok := val["coding"]; ok {
-		v.validateCodeableConceptWithCoding(val, coding, binding, fhirPath, result)
+		v.validateCodeableConceptWithCoding(ctx, val, coding, binding, fhirPath, result)
 		return
 	}
 
@@ -222,20 +298,20 @@ func (v *Validator) validateMapBinding(val map[string]any, binding *registry.Bin
 
 	// Looks like a Coding with system
 	if _, ok := val["system"]; ok {
-		v.validateCodingBinding(val, binding, fhirPath, result)
+		v.validateCodingBinding(ctx, val, binding, fhirPath, result)
 		return
 	}
 
 	// Coding with just code
 	if code, ok := val["code"]; ok {
 		if codeStr, ok := code.(string); ok {
-			v.validateCodeBinding(codeStr, "", binding, fhirPath, result)
+			v.validateCodeBinding(ctx, codeStr, "", binding, fhirPath, result)
 		}
 	}
 }
 
 // validateCodeableConceptWithCoding validates a CodeableConcept that has a coding array.
-func (v *Validator) validateCodeableConceptWithCoding(val map[string]any, coding any, binding *registry.Binding, fhirPath string, result *issue.Result) {
+func (v *Validator) validateCodeableConceptWithCoding(ctx context.Context, val map[string]any, coding any, binding *registry.Binding, fhirPath string, result *issue.Result) {
 	codings, isList := coding.([]any)
 	hasText := val["text"] != nil && val["text"] != ""
 
@@ -251,7 +327,7 @@ func (v *Validator) validateCodeableConceptWithCoding(val map[string]any, coding
 		for i, c := range codings {
 			if codingMap, ok := c.(map[string]any); ok {
 				codingPath := fmt.Sprintf("%s.coding[%d]", fhirPath, i)
-				v.validateCodingBinding(codingMap, binding, codingPath, result)
+				v.validateCodingBinding(ctx, codingMap, binding, codingPath, result)
 			}
 		}
 	}
@@ -261,35 +337,43 @@ func (v *Validator) validateCodeableConceptWithCoding(val map[string]any, coding
 func (v *Validator) emitTextOnlyWarning(valueSet, fhirPath string, result *issue.Result) {
 	result.AddWarningWithID(
 		issue.DiagBindingTextOnlyWarning,
-		map[string]any{
-			"valueSet": valueSet,
+		issue.Params{
+			issue.String("valueSet", valueSet),
 		},
 		fhirPath,
 	)
 }
 
 // validatePrimitiveBinding validates a primitive value against a binding.
-func (v *Validator) validatePrimitiveBinding(value any, elemDef *registry.ElementDefinition, fhirPath string, result *issue.Result) {
+func (v *Validator) validatePrimitiveBinding(ctx context.Context, value any, elemDef *registry.ElementDefinition, fhirPath string, result *issue.Result) {
 	if elemDef.Binding == nil {
 		return
 	}
 
 	if str, ok := value.(string); ok {
-		v.validateCodeBinding(str, "", elemDef.Binding, fhirPath, result)
+		v.validateCodeBinding(ctx, str, "", elemDef.Binding, fhirPath, result)
 	}
 }
 
 // validateCodeBinding validates a code against a ValueSet.
-func (v *Validator) validateCodeBinding(code, system string, binding *registry.Binding, fhirPath string, result *issue.Result) {
+func (v *Validator) validateCodeBinding(ctx context.Context, code, system string, binding *registry.Binding, fhirPath string, result *issue.Result) {
 	if code == "" {
 		return // Empty code is handled by cardinality validation
 	}
 
-	valid, found := v.termRegistry.ValidateCode(binding.ValueSet, system, code)
+	versions := systemVersions(binding)
+	defer v.recordSystemVersion(binding, system, versions, result, len(result.Issues))
+	valid, found := v.termRegistry.ValidateCodeVersions(ctx, binding.ValueSet, system, code, versions)
 
 	if !found {
 		// ValueSet not found - can't validate
 		// This is a warning, not an error
+		countCoverage(ctx, false)
+		return
+	}
+	countCoverage(ctx, system == "" || v.termRegistry.IsCheckable(system))
+
+	if v.reportConceptStatus(system, code, valid, binding, fhirPath, result) {
 		return
 	}
 
@@ -297,18 +381,18 @@ func (v *Validator) validateCodeBinding(code, system string, binding *registry.B
 		if binding.Strength == strengthRequired {
 			result.AddErrorWithID(
 				issue.DiagBindingRequired,
-				map[string]any{
-					"code":     code,
-					"valueSet": binding.ValueSet,
+				issue.Params{
+					issue.String("code", code),
+					issue.String("valueSet", binding.ValueSet),
 				},
 				fhirPath,
 			)
 		} else if binding.Strength == strengthExtensible {
 			result.AddWarningWithID(
 				issue.DiagBindingExtensible,
-				map[string]any{
-					"code":     code,
-					"valueSet": binding.ValueSet,
+				issue.Params{
+					issue.String("code", code),
+					issue.String("valueSet", binding.ValueSet),
 				},
 				fhirPath,
 			)
@@ -317,7 +401,7 @@ func (v *Validator) validateCodeBinding(code, system string, binding *registry.B
 }
 
 // validateCodingBinding validates a Coding against a ValueSet and its CodeSystem.
-func (v *Validator) validateCodingBinding(coding map[string]any, binding *registry.Binding, fhirPath string, result *issue.Result) {
+func (v *Validator) validateCodingBinding(ctx context.Context, coding map[string]any, binding *registry.Binding, fhirPath string, result *issue.Result) {
 	system, _ := coding["system"].(string)
 	code, _ := coding["code"].(string)
 	providedDisplay, _ := coding["display"].(string)
@@ -326,17 +410,44 @@ func (v *Validator) validateCodingBinding(coding map[string]any, binding *regist
 		return // Empty code is handled elsewhere
 	}
 
+	if system != "" && v.termRegistry.IsUnsupportedSystem(system) {
+		result.AddInfoWithID(
+			issue.DiagBindingSystemUnsupported,
+			issue.Params{
+				issue.String("code", code),
+				issue.String("system", system),
+			},
+			fhirPath,
+		)
+		countCoverage(ctx, false)
+		return // Accept code the terminology server cannot check
+	}
+
+	versions := systemVersions(binding)
+	defer v.recordSystemVersion(binding, system, versions, result, len(result.Issues))
+	csRef := system
+	if version := versions[system]; version != "" {
+		csRef += "|" + version
+	}
+
 	// Validate code exists in CodeSystem and check display
-	codeValidInCS, shouldReturn := v.validateCodeInCodeSystem(system, code, providedDisplay, fhirPath, result)
+	codeValidInCS, shouldReturn := v.validateCodeInCodeSystem(ctx, system, csRef, code, providedDisplay, fhirPath, result)
 	if shouldReturn {
+		countCoverage(ctx, true)
 		return
 	}
 
 	// Validate against the ValueSet binding
-	valid, found := v.termRegistry.ValidateCode(binding.ValueSet, system, code)
+	valid, found := v.termRegistry.ValidateCodeVersions(ctx, binding.ValueSet, system, code, versions)
 	if !found {
+		countCoverage(ctx, false)
 		return // ValueSet not found
 	}
+	countCoverage(ctx, system == "" || v.termRegistry.IsCheckable(system))
+
+	if v.reportConceptStatus(system, code, valid, binding, fhirPath, result) {
+		return
+	}
 
 	if !valid {
 		v.reportBindingViolation(system, code, binding, fhirPath, result)
@@ -345,18 +456,54 @@ func (v *Validator) validateCodingBinding(coding map[string]any, binding *regist
 
 	// Validate display if not already validated via CodeSystem
 	if !codeValidInCS && providedDisplay != "" && system != "" {
-		v.validateDisplayMismatch(system, code, providedDisplay, fhirPath, result)
+		v.validateDisplayMismatch(csRef, code, providedDisplay, fhirPath, result)
+	}
+}
+
+// systemVersions returns the code system versions a binding pins with
+// SystemVersionExtension (system URL -> version), or nil.
+func systemVersions(binding *registry.Binding) map[string]string {
+	var versions map[string]string
+	for _, ext := range binding.Extension {
+		if ext.URL != SystemVersionExtension {
+			continue
+		}
+		value := cmp.Or(ext.ValueCanonical, ext.ValueURI, ext.ValueString)
+		if system, version, ok := strings.Cut(value, "|"); ok && system != "" && version != "" {
+			if versions == nil {
+				versions = make(map[string]string)
+			}
+			versions[system] = version
+		}
+	}
+	return versions
+}
+
+// recordSystemVersion records on the issues reported since the first one
+// the code system version the code was checked against.
+func (v *Validator) recordSystemVersion(binding *registry.Binding, system string, versions map[string]string, result *issue.Result, first int) {
+	if len(result.Issues) == first {
+		return
+	}
+	systemVersion := v.termRegistry.CodeSystemVersion(binding.ValueSet, system, versions)
+	if systemVersion == "" {
+		return
+	}
+	for i := first; i < len(result.Issues); i++ {
+		result.Issues[i].SystemVersion = systemVersion
 	}
 }
 
 // validateCodeInCodeSystem validates a code exists in its CodeSystem and checks display.
-// Returns (codeValidInCS, shouldReturn) where shouldReturn indicates validation should stop.
-func (v *Validator) validateCodeInCodeSystem(system, code, providedDisplay, fhirPath string, result *issue.Result) (codeValidInCS, shouldReturn bool) {
+// The CodeSystem is looked up by csRef, the system with the version the binding
+// pins, if any. Returns (codeValidInCS, shouldReturn) where shouldReturn
+// indicates validation should stop.
+func (v *Validator) validateCodeInCodeSystem(ctx context.Context, system, csRef, code, providedDisplay, fhirPath string, result *issue.Result) (codeValidInCS, shouldReturn bool) {
 	if system == "" {
 		return false, false
 	}
 
-	codeValid, csFound := v.termRegistry.ValidateCodeInCodeSystem(system, code)
+	codeValid, csFound := v.termRegistry.ValidateCodeInCodeSystemContext(ctx, csRef, code)
 	if !csFound {
 		return false, false
 	}
@@ -364,7 +511,7 @@ func (v *Validator) validateCodeInCodeSystem(system, code, providedDisplay, fhir
 	if !codeValid {
 		result.AddErrorWithID(
 			issue.DiagCodeNotInCodeSystem,
-			map[string]any{"code": code, "system": system},
+			issue.Params{issue.String("code", code), issue.String("system", system)},
 			fhirPath,
 		)
 		return false, true // Stop validation - code invalid in CodeSystem
@@ -372,7 +519,7 @@ func (v *Validator) validateCodeInCodeSystem(system, code, providedDisplay, fhir
 
 	// Validate display if provided (HL7 is case-insensitive)
 	if providedDisplay != "" {
-		v.validateDisplayMismatch(system, code, providedDisplay, fhirPath, result)
+		v.validateDisplayMismatch(csRef, code, providedDisplay, fhirPath, result)
 	}
 
 	return true, false
@@ -384,16 +531,73 @@ func (v *Validator) validateDisplayMismatch(system, code, providedDisplay, fhirP
 	if displayFound && expectedDisplay != "" && !strings.EqualFold(providedDisplay, expectedDisplay) {
 		result.AddErrorWithID(
 			issue.DiagBindingDisplayMismatch,
-			map[string]any{
-				"code":     code,
-				"provided": providedDisplay,
-				"expected": expectedDisplay,
+			issue.Params{
+				issue.String("code", code),
+				issue.String("provided", providedDisplay),
+				issue.String("expected", expectedDisplay),
 			},
 			fhirPath+".display",
 		)
 	}
 }
 
+// reportConceptStatus reports codes whose CodeSystem status explains or
+// qualifies the binding result: inactive codes left out of a ValueSet that
+// excludes them, abstract codes that cannot be selected, and inactive or
+// deprecated codes the ValueSet still contains. Returns true when it reported
+// the binding violation itself.
+func (v *Validator) reportConceptStatus(system, code string, valid bool, binding *registry.Binding, fhirPath string, result *issue.Result) bool {
+	status := v.termRegistry.CodeConceptStatus(binding.ValueSet, system, code)
+	codeDisplay := code
+	if system != "" {
+		codeDisplay = fmt.Sprintf("%s#%s", system, code)
+	}
+
+	if valid {
+		switch {
+		case status.Inactive:
+			label := status.Status
+			if label == "" {
+				label = "inactive"
+			}
+			result.AddWarningWithID(
+				issue.DiagCodeInactive,
+				issue.Params{issue.String("code", codeDisplay), issue.String("status", label)},
+				fhirPath,
+			)
+		case status.Deprecated:
+			result.AddWarningWithID(
+				issue.DiagCodeDeprecated,
+				issue.Params{issue.String("code", codeDisplay)},
+				fhirPath,
+			)
+		}
+		return false
+	}
+
+	// Codes from systems outside the ValueSet fail for that reason alone
+	if system != "" && !v.termRegistry.IsSystemInValueSet(binding.ValueSet, system) {
+		return false
+	}
+	var id issue.DiagnosticID
+	switch {
+	case status.Inactive && v.termRegistry.ExcludesInactive(binding.ValueSet):
+		id = issue.DiagBindingCodeInactive
+	case status.Abstract:
+		id = issue.DiagBindingCodeAbstract
+	default:
+		return false
+	}
+	params := issue.Params{issue.String("code", codeDisplay), issue.String("valueSet", binding.ValueSet)}
+	switch binding.Strength {
+	case strengthRequired:
+		result.AddErrorWithID(id, params, fhirPath)
+	case strengthExtensible:
+		result.AddWarningWithID(id, params, fhirPath)
+	}
+	return true
+}
+
 // reportBindingViolation reports a binding violation based on binding strength.
 func (v *Validator) reportBindingViolation(system, code string, binding *registry.Binding, fhirPath string, result *issue.Result) {
 	codeDisplay := code
@@ -405,7 +609,7 @@ func (v *Validator) reportBindingViolation(system, code string, binding *registr
 	case strengthRequired:
 		result.AddErrorWithID(
 			issue.DiagBindingRequired,
-			map[string]any{"code": codeDisplay, "valueSet": binding.ValueSet},
+			issue.Params{issue.String("code", codeDisplay), issue.String("valueSet", binding.ValueSet)},
 			fhirPath,
 		)
 	case strengthExtensible:
@@ -413,7 +617,7 @@ func (v *Validator) reportBindingViolation(system, code string, binding *registr
 		if system == "" || v.termRegistry.IsSystemInValueSet(binding.ValueSet, system) {
 			result.AddWarningWithID(
 				issue.DiagBindingExtensible,
-				map[string]any{"code": codeDisplay, "valueSet": binding.ValueSet},
+				issue.Params{issue.String("code", codeDisplay), issue.String("valueSet", binding.ValueSet)},
 				fhirPath,
 			)
 		}
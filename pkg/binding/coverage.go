@@ -0,0 +1,35 @@
+package binding
+
+import "context"
+
+// Coverage counts the coded values checked against their bindings:
+// Validated were looked up, Skipped were accepted without a check because
+// the ValueSet is unknown or the code system needs a terminology server
+// that is not configured or does not support it.
+type Coverage struct {
+	Validated int
+	Skipped   int
+}
+
+type coverageKey struct{}
+
+// WithCoverage returns a context that makes ValidateDataContext count the
+// coded values it checks into c. c must not be shared between concurrent
+// validations.
+func WithCoverage(ctx context.Context, c *Coverage) context.Context {
+	return context.WithValue(ctx, coverageKey{}, c)
+}
+
+// countCoverage records one coded value on the Coverage carried by ctx, if
+// any.
+func countCoverage(ctx context.Context, checked bool) {
+	c, _ := ctx.Value(coverageKey{}).(*Coverage)
+	if c == nil {
+		return
+	}
+	if checked {
+		c.Validated++
+	} else {
+		c.Skipped++
+	}
+}
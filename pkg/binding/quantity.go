@@ -0,0 +1,195 @@
+package binding
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/ucum"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// ConstraintKeys lists the Quantity invariants enforced by this package.
+// The constraint phase skips them to avoid reporting the same violation twice.
+var ConstraintKeys = []string{"qty-3"}
+
+// quantityTypes are the data types whose code is a UCUM unit when the system
+// is UCUM. SimpleQuantity and MoneyQuantity are profiles of Quantity.
+var quantityTypes = map[string]bool{
+	"Quantity": true,
+	"Age":      true,
+	"Count":    true,
+	"Distance": true,
+	"Duration": true,
+}
+
+// quantityIndex indexes the snapshot elements of a StructureDefinition by ID
+// to find the units a profile declares.
+type quantityIndex struct {
+	byID map[string]*registry.ElementDefinition
+}
+
+// SetUCUMService sets the service used to validate UCUM units (default
+// ucum.Engine).
+func (v *Validator) SetUCUMService(s ucum.Service) {
+	v.ucum = s
+}
+
+// SetUnitConsistency enables checking Quantity units against the units a
+// profile declares with a fixed or pattern code or a required ValueSet.
+func (v *Validator) SetUnitConsistency(enabled bool) {
+	v.checkUnits = enabled
+}
+
+// validateQuantities checks the Quantity values of an element and its
+// descendants: a code needs a system (qty-3), a UCUM code must be a valid
+// unit and, with unit consistency enabled, the unit declared by the profile.
+func (v *Validator) validateQuantities(data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, result *issue.Result) {
+	v.walker.WalkElements(data, sd, sdPath, fhirPath, func(ec *walker.ElementContext) bool {
+		if !quantityTypes[ec.Type] {
+			return true
+		}
+		v.validateQuantity(ec.Data, v.quantityIndex(ec.SD), ec.Element, ec.Name, ec.FHIRPath, result)
+		return false
+	})
+}
+
+// validateQuantity checks a single Quantity value.
+func (v *Validator) validateQuantity(quantity map[string]any, idx *quantityIndex, elemDef *registry.ElementDefinition, key, fhirPath string, result *issue.Result) {
+	code, _ := quantity["code"].(string)
+	system, _ := quantity["system"].(string)
+	if code == "" {
+		return
+	}
+	if system == "" {
+		result.AddErrorWithID(issue.DiagQuantityCodeNoSystem, issue.Params{issue.String("code", code)}, fhirPath)
+		return
+	}
+	if system != ucum.System || v.ucum == nil {
+		return
+	}
+	if err := v.ucum.Validate(code); err != nil {
+		result.AddErrorWithID(issue.DiagUCUMInvalidUnit, issue.Params{issue.String("code", code), issue.String("error", err.Error())}, fhirPath+".code")
+		return
+	}
+	if v.checkUnits {
+		v.validateUnit(code, v.declaredUnits(idx, elemDef, key), fhirPath+".code", result)
+	}
+}
+
+// validateUnit reports a unit that is not one of the declared units,
+// telling apart units that can be converted from units that cannot.
+func (v *Validator) validateUnit(code string, declared []string, fhirPath string, result *issue.Result) {
+	if len(declared) == 0 || slices.Contains(declared, code) {
+		return
+	}
+	params := issue.Params{issue.String("code", code), issue.String("expected", strings.Join(declared, ", "))}
+	for _, unit := range declared {
+		if ok, err := v.ucum.Commensurable(code, unit); err == nil && ok {
+			result.AddErrorWithID(issue.DiagUCUMUnitMismatch, params, fhirPath)
+			return
+		}
+	}
+	result.AddErrorWithID(issue.DiagUCUMUnitIncompatible, params, fhirPath)
+}
+
+// declaredUnits returns the UCUM units a profile allows for a Quantity
+// element: a fixed or pattern Quantity code, a fixed or pattern code on the
+// code child, or the UCUM codes listed by a required binding on the code
+// child. A choice element's type slice (e.g., value[x]:valueQuantity) takes
+// precedence over the choice element itself.
+func (v *Validator) declaredUnits(idx *quantityIndex, elemDef *registry.ElementDefinition, key string) []string {
+	ids := []string{elemDef.ID}
+	if strings.HasSuffix(elemDef.ID, "[x]") {
+		ids = []string{elemDef.ID + ":" + key, elemDef.ID}
+	}
+
+	for _, id := range ids {
+		if quantity := idx.byID[id]; quantity != nil {
+			if unit := quantityCode(quantity); unit != "" {
+				return []string{unit}
+			}
+		}
+		codeDef := idx.byID[id+".code"]
+		if codeDef == nil {
+			continue
+		}
+		if unit := fixedCode(codeDef); unit != "" {
+			return []string{unit}
+		}
+		if b := codeDef.Binding; b != nil && b.Strength == strengthRequired && b.ValueSet != "" {
+			if units := v.valueSetUnits(b.ValueSet); len(units) > 0 {
+				return units
+			}
+		}
+	}
+	return nil
+}
+
+// valueSetUnits returns the UCUM codes a ValueSet lists explicitly.
+// ValueSets defined by filters (e.g., all UCUM units) declare no units.
+func (v *Validator) valueSetUnits(url string) []string {
+	vs := v.termRegistry.GetValueSet(url)
+	if vs == nil {
+		return nil
+	}
+	var units []string
+	for _, inc := range vs.Compose.Include {
+		if inc.System != ucum.System || len(inc.Filter) > 0 || len(inc.ValueSet) > 0 {
+			continue
+		}
+		for _, c := range inc.Concept {
+			units = append(units, c.Code)
+		}
+	}
+	return units
+}
+
+// quantityCode returns the code of a fixed or pattern Quantity.
+func quantityCode(elemDef *registry.ElementDefinition) string {
+	value, _, ok := elemDef.GetFixed()
+	if !ok {
+		value, _, ok = elemDef.GetPattern()
+	}
+	if !ok {
+		return ""
+	}
+	var quantity struct {
+		Code string `json:"code"`
+	}
+	if json.Unmarshal(value, &quantity) != nil {
+		return ""
+	}
+	return quantity.Code
+}
+
+// fixedCode returns the fixed or pattern value of a code element.
+func fixedCode(elemDef *registry.ElementDefinition) string {
+	value, _, ok := elemDef.GetFixed()
+	if !ok {
+		value, _, ok = elemDef.GetPattern()
+	}
+	if !ok {
+		return ""
+	}
+	var code string
+	if json.Unmarshal(value, &code) != nil {
+		return ""
+	}
+	return code
+}
+
+// quantityIndex returns the cached element index of a StructureDefinition.
+func (v *Validator) quantityIndex(sd *registry.StructureDefinition) *quantityIndex {
+	if cached, ok := v.quantityIndexes.Load(sd); ok {
+		return cached.(*quantityIndex)
+	}
+	idx := &quantityIndex{byID: make(map[string]*registry.ElementDefinition, len(sd.Snapshot.Element))}
+	for i := range sd.Snapshot.Element {
+		idx.byID[sd.Snapshot.Element[i].ID] = &sd.Snapshot.Element[i]
+	}
+	v.quantityIndexes.Store(sd, idx)
+	return idx
+}
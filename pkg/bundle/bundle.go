@@ -0,0 +1,182 @@
+// Package bundle detects duplicated content inside Bundles:
+//
+//   - entries with the same fullUrl, or with resources of the same type and id
+//     (entries of history Bundles, and versions of a resource distinguished by
+//     meta.versionId, are allowed)
+//   - Composition sections that list the same entry more than once
+//   - CodeableConcepts that repeat an identical coding (informational)
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// ConstraintKeys lists the Bundle invariants enforced by this package.
+// The constraint phase skips them to avoid reporting the same violation twice.
+var ConstraintKeys = []string{"bdl-7"}
+
+// Validator detects duplicates inside Bundles.
+type Validator struct{}
+
+// New creates a new Bundle duplicate Validator.
+func New() *Validator {
+	return &Validator{}
+}
+
+// ValidateData checks a pre-parsed Bundle for duplicates. Other resources are
+// ignored.
+func (v *Validator) ValidateData(bundle map[string]any, result *issue.Result) {
+	if resourceType, _ := bundle["resourceType"].(string); resourceType != "Bundle" {
+		return
+	}
+	entries, ok := bundle["entry"].([]any)
+	if !ok {
+		return
+	}
+
+	if bundleType, _ := bundle["type"].(string); bundleType != "history" {
+		v.validateEntries(entries, result)
+	}
+
+	for i, entry := range entries {
+		entryMap, _ := entry.(map[string]any)
+		resource, ok := entryMap["resource"].(map[string]any)
+		if !ok {
+			continue
+		}
+		path := fmt.Sprintf("Bundle.entry[%d].resource", i)
+		if resourceType, _ := resource["resourceType"].(string); resourceType == "Composition" {
+			v.validateSections(resource["section"], path+".section", result)
+		}
+		v.validateCodings(resource, path, result)
+	}
+}
+
+// validateEntries reports entries that repeat the fullUrl, or the resource
+// type and id, of an earlier entry with the same meta.versionId.
+func (v *Validator) validateEntries(entries []any, result *issue.Result) {
+	fullURLs := make(map[string]int, len(entries))
+	resources := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		entryMap, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		resource, _ := entryMap["resource"].(map[string]any)
+		version := versionID(resource)
+
+		if fullURL, _ := entryMap["fullUrl"].(string); fullURL != "" {
+			key := fullURL + "|" + version
+			if first, dup := fullURLs[key]; dup {
+				result.AddErrorWithID(
+					issue.DiagBundleFullURLDuplicate,
+					issue.Params{issue.String("fullUrl", fullURL), issue.Int("entry", first)},
+					fmt.Sprintf("Bundle.entry[%d].fullUrl", i),
+				)
+				continue // Same resource; do not report it twice
+			}
+			fullURLs[key] = i
+		}
+
+		resourceType, _ := resource["resourceType"].(string)
+		id, _ := resource["id"].(string)
+		if resourceType == "" || id == "" {
+			continue
+		}
+		name := resourceType + "/" + id
+		if first, dup := resources[name+"|"+version]; dup {
+			result.AddErrorWithID(
+				issue.DiagBundleResourceDuplicate,
+				issue.Params{issue.String("resource", name), issue.Int("entry", first)},
+				fmt.Sprintf("Bundle.entry[%d].resource", i),
+			)
+			continue
+		}
+		resources[name+"|"+version] = i
+	}
+}
+
+// versionID returns a resource's meta.versionId, or "".
+func versionID(resource map[string]any) string {
+	meta, _ := resource["meta"].(map[string]any)
+	version, _ := meta["versionId"].(string)
+	return version
+}
+
+// validateSections reports references listed more than once in the entries
+// of a Composition section, recursing into nested sections.
+func (v *Validator) validateSections(sections any, path string, result *issue.Result) {
+	list, _ := sections.([]any)
+	for i, s := range list {
+		section, ok := s.(map[string]any)
+		if !ok {
+			continue
+		}
+		sectionPath := fmt.Sprintf("%s[%d]", path, i)
+
+		entries, _ := section["entry"].([]any)
+		seen := make(map[string]int, len(entries))
+		for j, e := range entries {
+			entry, _ := e.(map[string]any)
+			ref, _ := entry["reference"].(string)
+			if ref == "" {
+				continue
+			}
+			if first, dup := seen[ref]; dup {
+				result.AddWarningWithID(
+					issue.DiagCompositionSectionDuplicate,
+					issue.Params{issue.String("reference", ref), issue.Int("index", first)},
+					fmt.Sprintf("%s.entry[%d]", sectionPath, j),
+				)
+				continue
+			}
+			seen[ref] = j
+		}
+
+		v.validateSections(section["section"], sectionPath+".section", result)
+	}
+}
+
+// validateCodings reports codings that repeat the system, version and code
+// of an earlier coding in the same CodeableConcept, anywhere in data.
+func (v *Validator) validateCodings(data any, path string, result *issue.Result) {
+	switch node := data.(type) {
+	case map[string]any:
+		if codings, ok := node["coding"].([]any); ok {
+			seen := make(map[[3]string]int, len(codings))
+			for i, c := range codings {
+				coding, ok := c.(map[string]any)
+				if !ok {
+					continue
+				}
+				system, _ := coding["system"].(string)
+				version, _ := coding["version"].(string)
+				code, _ := coding["code"].(string)
+				if code == "" {
+					continue
+				}
+				key := [3]string{system, version, code}
+				if first, dup := seen[key]; dup {
+					result.AddInfoWithID(
+						issue.DiagCodingDuplicate,
+						issue.Params{issue.String("system", system), issue.String("code", code), issue.Int("index", first)},
+						fmt.Sprintf("%s.coding[%d]", path, i),
+					)
+					continue
+				}
+				seen[key] = i
+			}
+		}
+		for key, value := range node {
+			if key != "coding" {
+				v.validateCodings(value, path+"."+key, result)
+			}
+		}
+	case []any:
+		for i, item := range node {
+			v.validateCodings(item, fmt.Sprintf("%s[%d]", path, i), result)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package bundle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+const testBundle = `{
+  "resourceType": "Bundle",
+  "type": "document",
+  "entry": [
+    {"fullUrl": "urn:uuid:1", "resource": {
+      "resourceType": "Composition",
+      "section": [
+        {"entry": [{"reference": "urn:uuid:2"}, {"reference": "urn:uuid:3"}, {"reference": "urn:uuid:2"}]},
+        {"entry": [{"reference": "urn:uuid:2"}],
+         "section": [{"entry": [{"reference": "urn:uuid:3"}, {"reference": "urn:uuid:3"}]}]}
+      ]}},
+    {"fullUrl": "urn:uuid:2", "resource": {"resourceType": "Observation", "id": "a",
+      "code": {"coding": [
+        {"system": "http://loinc.org", "code": "1-1"},
+        {"system": "http://loinc.org", "code": "1-1", "display": "Same"},
+        {"system": "http://snomed.info/sct", "code": "1-1"}
+      ]}}},
+    {"fullUrl": "urn:uuid:3", "resource": {"resourceType": "Observation", "id": "a"}},
+    {"fullUrl": "urn:uuid:3", "resource": {"resourceType": "Observation", "id": "b"}},
+    {"fullUrl": "urn:uuid:4", "resource": {"resourceType": "Observation", "id": "a", "meta": {"versionId": "2"}}}
+  ]
+}`
+
+func TestValidateData(t *testing.T) {
+	var bundle map[string]any
+	if err := json.Unmarshal([]byte(testBundle), &bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	result := issue.NewResult()
+	New().ValidateData(bundle, result)
+
+	want := map[string]issue.DiagnosticID{
+		"Bundle.entry[0].resource.section[0].entry[2]":            issue.DiagCompositionSectionDuplicate,
+		"Bundle.entry[0].resource.section[1].section[0].entry[1]": issue.DiagCompositionSectionDuplicate,
+		"Bundle.entry[1].resource.code.coding[1]":                 issue.DiagCodingDuplicate,
+		"Bundle.entry[2].resource":                                issue.DiagBundleResourceDuplicate,
+		"Bundle.entry[3].fullUrl":                                 issue.DiagBundleFullURLDuplicate,
+	}
+	got := make(map[string]issue.DiagnosticID)
+	for _, iss := range result.Issues {
+		got[iss.Expression[0]] = issue.DiagnosticID(iss.MessageID)
+	}
+	for path, id := range want {
+		if got[path] != id {
+			t.Errorf("%s: got %q, want %s", path, got[path], id)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected issues: %v", result.Issues)
+	}
+}
+
+func TestValidateDataHistory(t *testing.T) {
+	var bundle map[string]any
+	if err := json.Unmarshal([]byte(testBundle), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	bundle["type"] = "history"
+
+	result := issue.NewResult()
+	New().ValidateData(bundle, result)
+	for _, iss := range result.Issues {
+		switch issue.DiagnosticID(iss.MessageID) {
+		case issue.DiagBundleResourceDuplicate, issue.DiagBundleFullURLDuplicate:
+			t.Errorf("history Bundle: unexpected %s at %v", iss.MessageID, iss.Expression)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// checkInterval is the minimum time between two heap checks of a Budget.
+const checkInterval = 250 * time.Millisecond
+
+// minCacheShare is the fraction of a budget left to caches when the
+// baseline (loaded definitions) already takes most of it.
+const minCacheShare = 0.1
+
+// pressureTrim is the fraction of their cost that caches keep when the heap
+// exceeds the budget.
+const pressureTrim = 0.5
+
+// Cache is a cache whose size a Budget manages.
+type Cache interface {
+	SetBudget(budget int64)
+	Trim(fraction float64)
+	Stats() Stats
+}
+
+// share is a cache with its weight in a Budget.
+type share struct {
+	name   string
+	cache  Cache
+	weight float64
+}
+
+// Budget divides a memory budget between caches in proportion to their
+// weights, and trims them when the heap grows beyond the budget. It is safe
+// for concurrent use once its caches are added.
+type Budget struct {
+	total  int64
+	shares []share
+	heap   func() uint64
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	trims     uint64
+}
+
+// NewBudget creates a Budget of total bytes.
+func NewBudget(total int64) *Budget {
+	return &Budget{total: total, heap: HeapInUse}
+}
+
+// Add adds a cache with a weight. Caches must be added before Apply.
+func (b *Budget) Add(name string, c Cache, weight float64) {
+	b.shares = append(b.shares, share{name, c, weight})
+}
+
+// Apply sizes the caches from the part of the budget that baseline bytes,
+// the memory in use without caches, leave; at least a tenth of the budget.
+func (b *Budget) Apply(baseline uint64) {
+	available := b.total - int64(baseline)
+	available = max(available, int64(float64(b.total)*minCacheShare))
+	var weights float64
+	for _, s := range b.shares {
+		weights += s.weight
+	}
+	for _, s := range b.shares {
+		s.cache.SetBudget(max(int64(float64(available)*s.weight/weights), 1))
+	}
+}
+
+// Check trims every cache to half its cost if the heap exceeds the budget,
+// at most once per checkInterval. It reports whether it trimmed.
+func (b *Budget) Check() bool {
+	b.mu.Lock()
+	if time.Since(b.lastCheck) < checkInterval {
+		b.mu.Unlock()
+		return false
+	}
+	b.lastCheck = time.Now()
+	b.mu.Unlock()
+
+	if b.heap() <= uint64(b.total) {
+		return false
+	}
+	for _, s := range b.shares {
+		s.cache.Trim(pressureTrim)
+	}
+	b.mu.Lock()
+	b.trims++
+	b.mu.Unlock()
+	return true
+}
+
+// Total returns the budget in bytes.
+func (b *Budget) Total() int64 {
+	return b.total
+}
+
+// Trims returns the number of times Check trimmed the caches.
+func (b *Budget) Trims() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.trims
+}
+
+// HeapInUse returns the bytes of heap memory occupied by live objects and
+// by objects not yet swept by the garbage collector.
+func HeapInUse() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
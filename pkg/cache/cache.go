@@ -0,0 +1,133 @@
+// Package cache provides the bounded caches of the validator: an LRU cache
+// whose entries have an estimated cost in bytes, and a Budget that sizes
+// several of them from one memory budget and trims them when the heap
+// grows beyond it.
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Stats describes the contents and use of a cache.
+type Stats struct {
+	Entries   int    // Number of cached entries
+	Cost      int64  // Estimated size of the entries in bytes
+	Budget    int64  // Maximum cost (0 = unbounded)
+	Hits      uint64 // Lookups that found an entry
+	Misses    uint64 // Lookups that did not
+	Evictions uint64 // Entries evicted to stay within the budget
+}
+
+// LRU is a cache that evicts its least recently used entries when their
+// total cost exceeds its budget. It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	mu     sync.Mutex
+	cost   func(K, V) int64
+	budget int64
+	total  int64
+	order  *list.List // front = most recently used
+	items  map[K]*list.Element
+
+	hits, misses, evictions uint64
+}
+
+// entry is an element of an LRU's order list.
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	cost  int64
+}
+
+// NewLRU creates an unbounded LRU whose entries cost what cost estimates.
+func NewLRU[K comparable, V any](cost func(K, V) int64) *LRU[K, V] {
+	return &LRU[K, V]{
+		cost:  cost,
+		order: list.New(),
+		items: make(map[K]*list.Element),
+	}
+}
+
+// Get returns the cached value of a key, marking it as recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.hits++
+		c.order.MoveToFront(elem)
+		return elem.Value.(*entry[K, V]).value, true
+	}
+	c.misses++
+	var zero V
+	return zero, false
+}
+
+// Add caches a value, replacing any value cached for its key, and evicts
+// the least recently used entries beyond the budget. A value costing more
+// than the whole budget is not cached.
+func (c *LRU[K, V]) Add(key K, value V) {
+	cost := c.cost(key, value)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	if c.budget > 0 && cost > c.budget {
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, cost: cost})
+	c.total += cost
+	if c.budget > 0 {
+		c.evictTo(c.budget)
+	}
+}
+
+// SetBudget sets the maximum cost of the entries (0 = unbounded), evicting
+// entries beyond it.
+func (c *LRU[K, V]) SetBudget(budget int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.budget = max(budget, 0)
+	if c.budget > 0 {
+		c.evictTo(c.budget)
+	}
+}
+
+// Trim evicts the least recently used entries until the cost is at most a
+// fraction (0 to 1) of the current cost. The budget is unchanged.
+func (c *LRU[K, V]) Trim(fraction float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evictTo(max(int64(float64(c.total)*fraction), 0))
+}
+
+// Stats returns the contents and use of the cache.
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Entries:   len(c.items),
+		Cost:      c.total,
+		Budget:    c.budget,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// evictTo evicts the least recently used entries until the cost is at most
+// limit. The caller holds c.mu.
+func (c *LRU[K, V]) evictTo(limit int64) {
+	for c.total > limit && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+// remove removes an element. The caller holds c.mu.
+func (c *LRU[K, V]) remove(elem *list.Element) {
+	e := elem.Value.(*entry[K, V])
+	c.order.Remove(elem)
+	delete(c.items, e.key)
+	c.total -= e.cost
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// byteCost costs each entry its value.
+func byteCost(_ string, v int) int64 {
+	return int64(v)
+}
+
+func TestLRU(t *testing.T) {
+	c := NewLRU(byteCost)
+	c.Add("a", 10)
+	c.Add("b", 10)
+	c.Add("c", 10)
+	if st := c.Stats(); st.Entries != 3 || st.Cost != 30 || st.Budget != 0 {
+		t.Fatalf("unbounded stats = %+v", st)
+	}
+
+	c.Get("a") // b is now the least recently used
+	c.SetBudget(25)
+	if _, ok := c.Get("b"); ok {
+		t.Error("b not evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a evicted")
+	}
+
+	c.Add("d", 10) // evicts c
+	if _, ok := c.Get("c"); ok {
+		t.Error("c not evicted")
+	}
+	c.Add("big", 26)
+	if _, ok := c.Get("big"); ok {
+		t.Error("entry beyond the budget cached")
+	}
+
+	c.Add("a", 5) // replaces a
+	st := c.Stats()
+	if st.Entries != 2 || st.Cost != 15 || st.Evictions != 2 || st.Hits != 2 || st.Misses != 3 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	c.Trim(0.5)
+	if st := c.Stats(); st.Entries != 1 || st.Cost != 5 {
+		t.Errorf("after Trim stats = %+v", st)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	large, small := NewLRU(byteCost), NewLRU(byteCost)
+	b := NewBudget(1000)
+	b.Add("large", large, 3)
+	b.Add("small", small, 1)
+
+	b.Apply(600)
+	if large.Stats().Budget != 300 || small.Stats().Budget != 100 {
+		t.Errorf("budgets = %d, %d, want 300, 100", large.Stats().Budget, small.Stats().Budget)
+	}
+	b.Apply(2000) // The definitions alone exceed the budget
+	if large.Stats().Budget != 75 || small.Stats().Budget != 25 {
+		t.Errorf("minimum budgets = %d, %d, want 75, 25", large.Stats().Budget, small.Stats().Budget)
+	}
+
+	for _, k := range []string{"a", "b", "c", "d"} {
+		large.Add(k, 10)
+	}
+	heap := uint64(500)
+	b.heap = func() uint64 { return heap }
+	if b.Check() || b.Trims() != 0 {
+		t.Error("trimmed within the budget")
+	}
+	heap = 1500
+	if b.Check() {
+		t.Error("checked again within the check interval")
+	}
+	b.lastCheck = time.Time{}
+	if !b.Check() || b.Trims() != 1 {
+		t.Error("not trimmed beyond the budget")
+	}
+	if st := large.Stats(); st.Cost != 20 {
+		t.Errorf("cost after trim = %d, want 20", st.Cost)
+	}
+}
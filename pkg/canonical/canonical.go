@@ -0,0 +1,400 @@
+// Package canonical parses FHIR JSON while preserving the original property
+// order and re-serializes resources in canonical FHIR JSON property order.
+//
+// Canonical order follows the FHIR JSON rules: resourceType first, then
+// elements in the order of the StructureDefinition, with each primitive's
+// "_name" companion immediately after "name". Properties that are not
+// defined (e.g., unknown elements) keep their original relative order and are
+// written after the defined ones, so nothing is dropped.
+//
+// Numbers are kept as json.Number so decimal precision (e.g., "1.50") survives
+// a round trip.
+package canonical
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// Member is a single property of an Object.
+type Member struct {
+	Name  string
+	Value any
+}
+
+// Object is a JSON object that preserves property order.
+type Object struct {
+	Members []Member
+}
+
+// Get returns the value of the named property.
+func (o *Object) Get(name string) (any, bool) {
+	for _, m := range o.Members {
+		if m.Name == name {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Parse decodes JSON preserving property order. Objects are returned as
+// *Object, arrays as []any, numbers as json.Number, and strings, booleans and
+// null as string, bool and nil.
+func Parse(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := parseValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+// parseValue decodes the next value from the token stream.
+func parseValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := &Object{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := keyTok.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid object key %v", keyTok)
+				}
+				val, err := parseValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj.Members = append(obj.Members, Member{Name: key, Value: val})
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return obj, nil
+		case '[':
+			arr := []any{}
+			for dec.More() {
+				val, err := parseValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, val)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return arr, nil
+		}
+		return nil, fmt.Errorf("unexpected delimiter %v", t)
+	default:
+		return t, nil
+	}
+}
+
+// Marshal encodes a value produced by Parse as compact JSON in its current
+// property order. HTML characters are not escaped, matching FHIR JSON.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeValue(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeValue appends the JSON encoding of v to buf.
+func writeValue(buf *bytes.Buffer, v any) error {
+	switch val := v.(type) {
+	case *Object:
+		buf.WriteByte('{')
+		for i, m := range val.Members {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeString(buf, m.Name); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeValue(buf, m.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range val {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case string:
+		return writeString(buf, val)
+	case json.Number:
+		buf.WriteString(val.String())
+	case bool:
+		if val {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case nil:
+		buf.WriteString("null")
+	default:
+		return fmt.Errorf("unsupported value type %T", v)
+	}
+	return nil
+}
+
+// writeString appends a JSON string without HTML escaping.
+func writeString(buf *bytes.Buffer, s string) error {
+	var tmp bytes.Buffer
+	enc := json.NewEncoder(&tmp)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimSuffix(tmp.Bytes(), []byte("\n")))
+	return nil
+}
+
+// child is a direct child element of a path, in definition order.
+type child struct {
+	name     string // element name; for choice types the prefix without "[x]"
+	isChoice bool
+	elem     *registry.ElementDefinition
+}
+
+// Formatter reorders parsed resources into canonical FHIR JSON order using
+// the StructureDefinitions in a registry.
+type Formatter struct {
+	registry *registry.Registry
+
+	// childCache caches the ordered children of an element, keyed by
+	// "sdURL|version|path".
+	childCache sync.Map
+}
+
+// NewFormatter creates a Formatter backed by the given registry.
+func NewFormatter(reg *registry.Registry) *Formatter {
+	return &Formatter{registry: reg}
+}
+
+// Format parses a resource and returns it re-serialized in canonical order.
+func (f *Formatter) Format(data []byte) ([]byte, error) {
+	v, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(*Object)
+	if !ok {
+		return nil, fmt.Errorf("resource must be a JSON object")
+	}
+	f.Sort(obj)
+	return Marshal(obj)
+}
+
+// Sort reorders a parsed resource in place into canonical order.
+// Objects without a known resourceType are left unchanged.
+func (f *Formatter) Sort(resource *Object) {
+	sd, path := f.resourceDefinition(resource)
+	if sd == nil {
+		return
+	}
+	f.sortObject(resource, sd, path)
+}
+
+// resourceDefinition returns the base StructureDefinition for an object with a resourceType.
+func (f *Formatter) resourceDefinition(obj *Object) (*registry.StructureDefinition, string) {
+	rt, _ := obj.Get("resourceType")
+	resourceType, _ := rt.(string)
+	if resourceType == "" {
+		return nil, ""
+	}
+	sd := f.registry.GetByType(resourceType)
+	if sd == nil || sd.Snapshot == nil {
+		return nil, ""
+	}
+	return sd, resourceType
+}
+
+// sortObject orders the members of obj by the children of path in sd, then recurses.
+func (f *Formatter) sortObject(obj *Object, sd *registry.StructureDefinition, path string) {
+	children := f.children(sd, path)
+
+	type ranked struct {
+		member Member
+		rank   int
+		pos    int
+	}
+	items := make([]ranked, len(obj.Members))
+	for i, m := range obj.Members {
+		rank := len(children)*2 + 1 // undefined properties go last
+		name := strings.TrimPrefix(m.Name, "_")
+		var matched *child
+		for ci := range children {
+			if matchesChild(name, &children[ci]) {
+				matched = &children[ci]
+				// "name" before "_name"
+				rank = ci*2 + 1
+				if strings.HasPrefix(m.Name, "_") {
+					rank++
+				}
+				break
+			}
+		}
+		if m.Name == "resourceType" {
+			rank = 0
+		}
+		if matched != nil {
+			f.sortValue(m.Value, sd, matched, name)
+		}
+		items[i] = ranked{member: m, rank: rank, pos: i}
+	}
+
+	sort.SliceStable(items, func(a, b int) bool {
+		return items[a].rank < items[b].rank
+	})
+	for i := range items {
+		obj.Members[i] = items[i].member
+	}
+}
+
+// sortValue recurses into the value of a child element.
+func (f *Formatter) sortValue(v any, sd *registry.StructureDefinition, c *child, name string) {
+	switch val := v.(type) {
+	case *Object:
+		f.sortChildObject(val, sd, c, name)
+	case []any:
+		for _, item := range val {
+			if obj, ok := item.(*Object); ok {
+				f.sortChildObject(obj, sd, c, name)
+			}
+		}
+	}
+}
+
+// sortChildObject resolves the definition of a child object and sorts it.
+func (f *Formatter) sortChildObject(obj *Object, sd *registry.StructureDefinition, c *child, name string) {
+	// Inline resources (contained, Bundle.entry.resource, Parameters.parameter.resource)
+	if _, ok := obj.Get("resourceType"); ok {
+		f.Sort(obj)
+		return
+	}
+
+	elem := c.elem
+
+	// Recursive structures (e.g., Questionnaire.item.item)
+	if elem.ContentReference != nil {
+		ref := *elem.ContentReference
+		if idx := strings.Index(ref, "#"); idx >= 0 {
+			f.sortObject(obj, sd, ref[idx+1:])
+		}
+		return
+	}
+
+	// Backbone elements are defined inline in the same StructureDefinition
+	if len(f.children(sd, elem.Path)) > 0 {
+		f.sortObject(obj, sd, elem.Path)
+		return
+	}
+
+	typeCode := elementType(elem, c, name)
+	if typeCode == "" {
+		return
+	}
+	typeSD := f.registry.GetByType(typeCode)
+	if typeSD == nil || typeSD.Snapshot == nil {
+		return
+	}
+	f.sortObject(obj, typeSD, typeSD.Type)
+}
+
+// elementType returns the type code of an element value. For choice elements
+// the type comes from the property name suffix (e.g., "valueQuantity").
+func elementType(elem *registry.ElementDefinition, c *child, name string) string {
+	if !c.isChoice {
+		if len(elem.Type) == 1 {
+			return elem.Type[0].Code
+		}
+		return ""
+	}
+	suffix := strings.TrimPrefix(name, c.name)
+	for _, t := range elem.Type {
+		if strings.EqualFold(t.Code, suffix) {
+			return t.Code
+		}
+	}
+	return ""
+}
+
+// matchesChild reports whether a property name (without "_") belongs to a child element.
+func matchesChild(name string, c *child) bool {
+	if !c.isChoice {
+		return name == c.name
+	}
+	if len(name) <= len(c.name) || !strings.HasPrefix(name, c.name) {
+		return false
+	}
+	r := name[len(c.name)]
+	return r >= 'A' && r <= 'Z'
+}
+
+// children returns the direct children of path in sd, in definition order.
+// Slices repeat the same path and are collapsed into a single entry.
+func (f *Formatter) children(sd *registry.StructureDefinition, path string) []child {
+	key := sd.URL + "|" + sd.Version + "|" + path
+	if cached, ok := f.childCache.Load(key); ok {
+		if c, ok := cached.([]child); ok {
+			return c
+		}
+	}
+
+	var result []child
+	seen := make(map[string]bool)
+	prefix := path + "."
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		if !strings.HasPrefix(elem.Path, prefix) {
+			continue
+		}
+		rest := elem.Path[len(prefix):]
+		if strings.Contains(rest, ".") || seen[rest] {
+			continue
+		}
+		seen[rest] = true
+
+		c := child{name: rest, elem: elem}
+		if strings.HasSuffix(rest, "[x]") {
+			c.name = strings.TrimSuffix(rest, "[x]")
+			c.isChoice = true
+		}
+		result = append(result, c)
+	}
+
+	f.childCache.Store(key, result)
+	return result
+}
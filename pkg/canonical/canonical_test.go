@@ -0,0 +1,102 @@
+package canonical
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+)
+
+func TestParseMarshalRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"property order", `{"resourceType":"Patient","gender":"male","id":"p1","active":true}`},
+		{"decimal precision", `{"value":1.50,"big":12345678901234567890,"exp":1e-7}`},
+		{"nested and arrays", `{"b":[{"z":1,"a":2},null,"x"],"a":{"y":false}}`},
+		{"no html escaping", `{"div":"<div>a & b</div>"}`},
+		{"empty containers", `{"a":{},"b":[]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			out, err := Marshal(v)
+			if err != nil {
+				t.Fatalf("Marshal() error: %v", err)
+			}
+			if string(out) != tt.input {
+				t.Errorf("round trip = %s, want %s", out, tt.input)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{`{"a":`, `{"a":1} {"b":2}`, ``} {
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Errorf("Parse(%q) should fail", input)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	f := NewFormatter(testutil.Registry(t, "4.0.1"))
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "resource elements",
+			input: `{"gender":"male","birthDate":"1970-01-01","id":"p1","resourceType":"Patient","active":true}`,
+			want:  `{"resourceType":"Patient","id":"p1","active":true,"gender":"male","birthDate":"1970-01-01"}`,
+		},
+		{
+			name:  "primitive extension follows its value",
+			input: `{"resourceType":"Patient","_birthDate":{"extension":[{"valueDateTime":"1970-01-01T10:00:00Z","url":"http://hl7.org/fhir/StructureDefinition/patient-birthTime"}]},"gender":"male","birthDate":"1970-01-01"}`,
+			want:  `{"resourceType":"Patient","gender":"male","birthDate":"1970-01-01","_birthDate":{"extension":[{"url":"http://hl7.org/fhir/StructureDefinition/patient-birthTime","valueDateTime":"1970-01-01T10:00:00Z"}]}}`,
+		},
+		{
+			name:  "datatypes and backbone elements",
+			input: `{"resourceType":"Patient","contact":[{"name":{"given":["A"],"family":"B"},"relationship":[{"text":"x"}]}],"name":[{"given":["John"],"use":"official","family":"Doe"}]}`,
+			want:  `{"resourceType":"Patient","name":[{"use":"official","family":"Doe","given":["John"]}],"contact":[{"relationship":[{"text":"x"}],"name":{"family":"B","given":["A"]}}]}`,
+		},
+		{
+			name:  "choice types and decimal precision",
+			input: `{"valueQuantity":{"unit":"mg","value":1.50},"code":{"text":"x"},"status":"final","resourceType":"Observation"}`,
+			want:  `{"resourceType":"Observation","status":"final","code":{"text":"x"},"valueQuantity":{"value":1.50,"unit":"mg"}}`,
+		},
+		{
+			name:  "contained resources and unknown elements",
+			input: `{"resourceType":"Patient","unknownB":1,"contained":[{"name":"Org","resourceType":"Organization","id":"o1"}],"unknownA":2,"id":"p1"}`,
+			want:  `{"resourceType":"Patient","id":"p1","contained":[{"resourceType":"Organization","id":"o1","name":"Org"}],"unknownB":1,"unknownA":2}`,
+		},
+		{
+			name:  "content reference",
+			input: `{"resourceType":"Questionnaire","status":"draft","item":[{"type":"group","linkId":"1","item":[{"type":"string","linkId":"1.1"}]}]}`,
+			want:  `{"resourceType":"Questionnaire","status":"draft","item":[{"linkId":"1","type":"group","item":[{"linkId":"1.1","type":"string"}]}]}`,
+		},
+		{
+			name:  "unknown resource type unchanged",
+			input: `{"b":1,"resourceType":"NotAResource","a":2}`,
+			want:  `{"b":1,"resourceType":"NotAResource","a":2}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := f.Format([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("Format() =\n  %s\nwant\n  %s", out, tt.want)
+			}
+		})
+	}
+}
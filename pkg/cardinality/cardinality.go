@@ -36,7 +36,7 @@ func (v *Validator) Validate(resource []byte, sd *registry.StructureDefinition)
 	if err := json.Unmarshal(resource, &data); err != nil {
 		result.AddErrorWithID(
 			issue.DiagStructureInvalidJSON,
-			map[string]any{"error": err.Error()},
+			issue.Params{issue.String("error", err.Error())},
 		)
 		return result
 	}
@@ -74,6 +74,41 @@ func (v *Validator) ValidateData(data map[string]any, sd *registry.StructureDefi
 	return result
 }
 
+// ValidateElement validates the cardinality of the children of a single
+// element value. ParentPath is the SD path of the element's parent, name the
+// element name and fhirPath the path of value for issues. The element's own
+// cardinality depends on its siblings and is not checked.
+func (v *Validator) ValidateElement(value any, sd *registry.StructureDefinition, parentPath, name, fhirPath string) *issue.Result {
+	result := issue.GetPooledResult()
+	if sd.Snapshot == nil {
+		return result
+	}
+
+	elementSDPath := parentPath + "." + name
+	elemDef := v.findElementDefinition(sd, elementSDPath)
+	if elemDef == nil {
+		return result
+	}
+
+	typeName := ""
+	if len(elemDef.Type) == 1 {
+		typeName = elemDef.Type[0].Code
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateComplexElementCardinality(val, elementSDPath, fhirPath, typeName, sd, result)
+	case []any:
+		for i, item := range val {
+			if itemMap, ok := item.(map[string]any); ok {
+				itemPath := fmt.Sprintf("%s[%d]", fhirPath, i)
+				v.validateComplexElementCardinality(itemMap, elementSDPath, itemPath, typeName, sd, result)
+			}
+		}
+	}
+	return result
+}
+
 // validateElementCardinality validates cardinality for an element and its children.
 func (v *Validator) validateElementCardinality(
 	data map[string]any,
@@ -92,44 +127,72 @@ func (v *Validator) validateElementCardinality(
 			continue
 		}
 
-		// Handle choice types - extract base name without [x]
-		isChoiceType := strings.HasSuffix(child.Path, "[x]")
-		baseName := childName
-		if isChoiceType {
-			baseName = strings.TrimSuffix(childName, "[x]")
+		count := v.validateChildCount(data, &child, fhirPath, result)
+
+		// Recursively validate children for present elements
+		if count > 0 && !strings.HasSuffix(child.Path, "[x]") {
+			v.validatePresentElement(data, childName, sdPath, fhirPath, sd, result)
 		}
+	}
+}
 
-		// Count occurrences in data
-		count := v.countOccurrences(data, baseName, isChoiceType, &child)
+// ValidateChildCount validates the min and max cardinality of a single child
+// of data, without descending into it. ParentPath is the SD path of data,
+// name the child's element name (without [x] for choice elements) and
+// fhirPath the path of data for issues.
+func (v *Validator) ValidateChildCount(data map[string]any, sd *registry.StructureDefinition, parentPath, name, fhirPath string) *issue.Result {
+	result := issue.GetPooledResult()
+	if sd.Snapshot == nil {
+		return result
+	}
 
-		// Validate min cardinality
-		if child.Min > 0 && count < int(child.Min) {
+	for _, child := range v.getDirectChildren(sd, parentPath) {
+		if strings.TrimSuffix(getElementName(child.Path), "[x]") == name {
+			v.validateChildCount(data, &child, fhirPath, result)
+			break
+		}
+	}
+	return result
+}
+
+// validateChildCount validates the min and max cardinality of a child
+// element in data and returns the number of occurrences.
+func (v *Validator) validateChildCount(data map[string]any, child *registry.ElementDefinition, fhirPath string, result *issue.Result) int {
+	childName := getElementName(child.Path)
+
+	// Handle choice types - extract base name without [x]
+	isChoiceType := strings.HasSuffix(child.Path, "[x]")
+	baseName := childName
+	if isChoiceType {
+		baseName = strings.TrimSuffix(childName, "[x]")
+	}
+
+	// Count occurrences in data
+	count := v.countOccurrences(data, baseName, isChoiceType, child)
+
+	// Validate min cardinality
+	if child.Min > 0 && count < int(child.Min) {
+		childFHIRPath := fhirPath + "." + childName
+		result.AddErrorWithID(
+			issue.DiagCardinalityMin,
+			issue.Params{issue.String("path", childFHIRPath), issue.Int("min", int(child.Min)), issue.Int("count", count)},
+			childFHIRPath,
+		)
+	}
+
+	// Validate max cardinality
+	if child.Max != "" && child.Max != "*" {
+		maxInt, err := strconv.Atoi(child.Max)
+		if err == nil && count > maxInt {
 			childFHIRPath := fhirPath + "." + childName
 			result.AddErrorWithID(
-				issue.DiagCardinalityMin,
-				map[string]any{"path": childFHIRPath, "min": child.Min, "count": count},
+				issue.DiagCardinalityMax,
+				issue.Params{issue.String("path", childFHIRPath), issue.Int("max", maxInt), issue.Int("count", count)},
 				childFHIRPath,
 			)
 		}
-
-		// Validate max cardinality
-		if child.Max != "" && child.Max != "*" {
-			maxInt, err := strconv.Atoi(child.Max)
-			if err == nil && count > maxInt {
-				childFHIRPath := fhirPath + "." + childName
-				result.AddErrorWithID(
-					issue.DiagCardinalityMax,
-					map[string]any{"path": childFHIRPath, "max": maxInt, "count": count},
-					childFHIRPath,
-				)
-			}
-		}
-
-		// Recursively validate children for present elements
-		if count > 0 && !isChoiceType {
-			v.validatePresentElement(data, childName, sdPath, fhirPath, sd, result)
-		}
 	}
+	return count
 }
 
 // validatePresentElement validates cardinality for elements that are present in data.
@@ -294,35 +357,21 @@ func getElementName(path string) string {
 func (v *Validator) countOccurrences(data map[string]any, baseName string, isChoiceType bool, elemDef *registry.ElementDefinition) int {
 	if !isChoiceType {
 		// Simple element - check for exact match
-		value, exists := data[baseName]
-		if !exists {
-			return 0
-		}
-
-		// If it's an array, return the length
-		if arr, ok := value.([]any); ok {
-			return len(arr)
-		}
-
-		// Single value counts as 1
-		return 1
+		return occurrences(data, baseName)
 	}
 
-	// Choice type - look for any matching element
+	// Choice type - look for any matching element, or its shadow element
 	for key := range data {
-		if strings.HasPrefix(key, baseName) && len(key) > len(baseName) {
+		name := strings.TrimPrefix(key, "_")
+		if strings.HasPrefix(name, baseName) && len(name) > len(baseName) {
 			// Verify it's a valid choice type suffix
-			suffix := key[len(baseName):]
+			suffix := name[len(baseName):]
 			if suffix != "" && suffix[0] >= 'A' && suffix[0] <= 'Z' {
 				// Check if this suffix matches one of the allowed types
 				for _, t := range elemDef.Type {
 					if strings.EqualFold(suffix, t.Code) {
 						// Found a match - count occurrences
-						value := data[key]
-						if arr, ok := value.([]any); ok {
-							return len(arr)
-						}
-						return 1
+						return occurrences(data, name)
 					}
 				}
 			}
@@ -332,6 +381,27 @@ func (v *Validator) countOccurrences(data map[string]any, baseName string, isCho
 	return 0
 }
 
+// occurrences counts the occurrences of an element in data. A primitive's
+// shadow element (e.g., "_given") aligns with its values by index, and an
+// occurrence with extensions but no value (null in "given") still counts.
+func occurrences(data map[string]any, name string) int {
+	count := 0
+	if value, exists := data[name]; exists {
+		count = 1
+		if arr, ok := value.([]any); ok {
+			count = len(arr)
+		}
+	}
+	if shadow, exists := data["_"+name]; exists && shadow != nil {
+		shadowCount := 1
+		if arr, ok := shadow.([]any); ok {
+			shadowCount = len(arr)
+		}
+		count = max(count, shadowCount)
+	}
+	return count
+}
+
 // findElementDefinition finds an ElementDefinition by path.
 func (v *Validator) findElementDefinition(sd *registry.StructureDefinition, path string) *registry.ElementDefinition {
 	for i := range sd.Snapshot.Element {
@@ -315,3 +315,26 @@ func TestValidateNestedRequiredElements(t *testing.T) {
 		})
 	}
 }
+
+func TestOccurrences(t *testing.T) {
+	ext := map[string]any{"extension": []any{}}
+	tests := []struct {
+		name string
+		data map[string]any
+		want int
+	}{
+		{"absent", map[string]any{}, 0},
+		{"single value", map[string]any{"given": "Ann"}, 1},
+		{"array", map[string]any{"given": []any{"Ann", "Bo"}}, 2},
+		{"extensions only", map[string]any{"_given": ext}, 1},
+		{"null values with extensions", map[string]any{"given": []any{"Ann", nil}, "_given": []any{nil, ext}}, 2},
+		{"extensions only in an array", map[string]any{"_given": []any{ext, ext}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := occurrences(tt.data, "given"); got != tt.want {
+				t.Errorf("occurrences() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
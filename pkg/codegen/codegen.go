@@ -0,0 +1,486 @@
+// Package codegen generates typed Go models from StructureDefinitions, so
+// applications can build and read resources with the shape a profile
+// enforces.
+//
+// Each profile becomes a struct with one field per allowed element (and one
+// per allowed choice type); backbone elements and elements the profile
+// constrains become structs of their own, and the datatypes they use are
+// generated once. Fields carry json tags and validate tags in the syntax of
+// github.com/go-playground/validator: required, min and max for
+// cardinalities and maxLength, eq for fixed primitive values, oneof for
+// required bindings to small ValueSets, and excluded_with and
+// required_without_all for choice elements. Tags cover a subset of what the
+// validator checks; invariants, patterns, slices and regexes are not
+// expressed. Extensions on primitives ("_name" properties) are not modeled.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gofhir/validator/pkg/canonical"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// maxEnum is the largest expansion written as a oneof tag.
+const maxEnum = 50
+
+const (
+	systemTypePrefix = "http://hl7.org/fhirpath/System."
+	fhirTypeExt      = "http://hl7.org/fhir/StructureDefinition/structuredefinition-fhir-type"
+)
+
+// CodeSource lists the codes of ValueSets for required bindings.
+// *terminology.Registry implements it.
+type CodeSource interface {
+	EnumerateCodes(valueSetURL string, limit int) ([]string, bool)
+}
+
+// Generator builds Go models from the StructureDefinitions in a registry.
+type Generator struct {
+	registry *registry.Registry
+	codes    CodeSource
+}
+
+// New creates a Generator. Codes may be nil, in which case no oneof tags are
+// generated.
+func New(reg *registry.Registry, codes CodeSource) *Generator {
+	return &Generator{registry: reg, codes: codes}
+}
+
+// Go returns a gofmt-formatted Go source file in package pkgName with a
+// struct for each of the StructureDefinitions with the given canonical URLs
+// and the types they use.
+func (g *Generator) Go(pkgName string, profileURLs ...string) ([]byte, error) {
+	if !isIdentifier(pkgName) {
+		return nil, fmt.Errorf("invalid package name %q", pkgName)
+	}
+	b := &builder{Generator: g, names: make(map[string]string), used: make(map[string]bool)}
+	for _, url := range profileURLs {
+		sd := g.registry.GetByURL(url)
+		if sd == nil {
+			return nil, fmt.Errorf("profile %s not found", url)
+		}
+		if sd.Snapshot == nil || len(sd.Snapshot.Element) == 0 {
+			return nil, fmt.Errorf("profile %s has no snapshot", url)
+		}
+		b.typeStruct(sd)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gofhir-validator codegen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	if b.usesJSON {
+		buf.WriteString("import \"encoding/json\"\n\n")
+	}
+	for _, s := range b.structs {
+		fmt.Fprintf(&buf, "// %s\ntype %s struct {\n", s.doc, s.name)
+		for _, f := range s.fields {
+			fmt.Fprintf(&buf, "\t%s %s `%s`\n", f.name, f.goType, f.tag())
+		}
+		buf.WriteString("}\n\n")
+	}
+	return format.Source(buf.Bytes())
+}
+
+// builder holds the state of one Go call.
+type builder struct {
+	*Generator
+	structs  []*structType
+	names    map[string]string // Struct key to Go name
+	used     map[string]bool   // Go names taken
+	usesJSON bool
+}
+
+type structType struct {
+	name   string
+	doc    string
+	fields []*field
+}
+
+type field struct {
+	name     string // Go name
+	jsonName string
+	goType   string
+	rules    []string // validate tag rules
+}
+
+// tag returns the struct tag of the field.
+func (f *field) tag() string {
+	tag := `json:"` + f.jsonName
+	if f.jsonName != "resourceType" {
+		tag += ",omitempty"
+	}
+	tag += `"`
+	if len(f.rules) > 0 {
+		tag += ` validate:"` + strings.Join(f.rules, ",") + `"`
+	}
+	return tag
+}
+
+// typeStruct generates the struct of a datatype or profile and returns its
+// name.
+func (b *builder) typeStruct(sd *registry.StructureDefinition) string {
+	name := sd.Type
+	if sd.Derivation == "constraint" {
+		name = goName(sd.Name)
+	}
+	doc := fmt.Sprintf("%s is generated from %s.", name, sd.URL)
+	return b.define(sd.URL, name, doc, func(s *structType) {
+		b.fill(s, sd, sd.Snapshot.Element[0].ElementID(), sd.Kind == registry.KindResource)
+	})
+}
+
+// define adds a struct under name (made unique) unless one with the same key
+// exists, and returns its name. The struct is registered before it is filled
+// so recursive types resolve.
+func (b *builder) define(key, name, doc string, fill func(*structType)) string {
+	if existing, ok := b.names[key]; ok {
+		return existing
+	}
+	unique := name
+	for n := 2; b.used[unique]; n++ {
+		unique = name + strconv.Itoa(n)
+	}
+	if unique != name {
+		doc = unique + strings.TrimPrefix(doc, name)
+	}
+	b.names[key], b.used[unique] = unique, true
+	s := &structType{name: unique, doc: doc}
+	b.structs = append(b.structs, s)
+	fill(s)
+	return unique
+}
+
+// fill adds a field to s for each child of the element with parentID in sd.
+// Resource roots also get their resourceType.
+func (b *builder) fill(s *structType, sd *registry.StructureDefinition, parentID string, resource bool) {
+	if resource {
+		s.fields = append(s.fields, &field{name: "ResourceType", jsonName: "resourceType", goType: "string",
+			rules: []string{"required", "eq=" + sd.Type}})
+	}
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		name, ok := registry.ChildName(elem.ElementID(), parentID)
+		if !ok || elem.Max == "0" || hasField(s, name) {
+			continue
+		}
+		if base, isChoice := strings.CutSuffix(name, "[x]"); isChoice {
+			b.choice(s, sd, elem, base)
+			continue
+		}
+		owner := s.name + upperFirst(name)
+		s.fields = append(s.fields, b.field(sd, elem, elem.ElementID(), name, typeCode(elem), owner, b.registry.Repeats(elem)))
+	}
+}
+
+// choice adds a field for each type of a choice element, e.g.
+// ValueQuantity, tagged so that at most one is set (exactly one if the
+// element is required).
+func (b *builder) choice(s *structType, sd *registry.StructureDefinition, elem *registry.ElementDefinition, base string) {
+	_, fixedType, fixed := elem.GetFixed()
+	if !fixed {
+		_, fixedType, fixed = elem.GetPattern()
+	}
+	var fields []*field
+	for _, t := range elem.Type {
+		if t.Code == "" || (fixed && !strings.EqualFold(fixedType, t.Code)) {
+			continue
+		}
+		name := base + upperFirst(t.Code)
+		source := elem
+		if slice := sd.SliceNamed(elem.ElementID(), name); slice != nil {
+			source = slice
+		}
+		f := b.field(sd, source, source.ElementID(), name, t.Code, s.name+upperFirst(name), false)
+		f.rules = withoutRequired(f.rules)
+		fields = append(fields, f)
+	}
+
+	for _, f := range fields {
+		var others []string
+		for _, other := range fields {
+			if other != f {
+				others = append(others, other.name)
+			}
+		}
+		var rules []string
+		switch {
+		case elem.Min > 0 && len(others) == 0:
+			rules = append(rules, "required")
+		case elem.Min > 0:
+			// Rules of the value would also apply to the unset fields
+			rules = append(rules, "required_without_all="+strings.Join(others, " "))
+			f.rules = nil
+		case len(f.rules) > 0:
+			rules = append(rules, "omitempty")
+		}
+		if len(others) > 0 {
+			rules = append(rules, "excluded_with="+strings.Join(others, " "))
+		}
+		f.rules = append(rules, f.rules...)
+		s.fields = append(s.fields, f)
+	}
+}
+
+// field returns the field of a child element with the given type; owner
+// names the struct generated for its children, if it has any.
+func (b *builder) field(sd *registry.StructureDefinition, elem *registry.ElementDefinition, id, name, code, owner string, repeats bool) *field {
+	f := &field{name: fieldName(name), jsonName: name}
+	itemType, itemRules := b.itemType(sd, elem, id, code, owner)
+
+	if repeats {
+		f.goType = "[]" + strings.TrimPrefix(itemType, "*")
+		if elem.Min > 0 {
+			f.rules = append(f.rules, "min="+strconv.Itoa(int(elem.Min)))
+		} else if len(itemRules) > 0 || elem.Max != "*" {
+			f.rules = append(f.rules, "omitempty")
+		}
+		if elem.Max != "*" {
+			f.rules = append(f.rules, "max="+elem.Max)
+		}
+		if len(itemRules) > 0 {
+			f.rules = append(f.rules, "dive")
+			f.rules = append(f.rules, itemRules...)
+		}
+		return f
+	}
+
+	f.goType = itemType
+	if elem.Min > 0 {
+		f.rules = append(f.rules, "required")
+	} else if len(itemRules) > 0 {
+		f.rules = append(f.rules, "omitempty")
+	}
+	f.rules = append(f.rules, itemRules...)
+	return f
+}
+
+// itemType returns the Go type of one occurrence of elem and the validate
+// rules of its value.
+func (b *builder) itemType(sd *registry.StructureDefinition, elem *registry.ElementDefinition, id, code, owner string) (string, []string) {
+	if elem.ContentReference != nil && *elem.ContentReference != "" {
+		_, target, _ := strings.Cut(*elem.ContentReference, "#")
+		return "*" + b.backbone(sd, target, ""), nil
+	}
+	if strings.HasPrefix(code, systemTypePrefix) {
+		code = systemType(elem, code)
+	}
+	if b.registry.IsPrimitiveType(code) {
+		return b.primitive(elem, code)
+	}
+	if sd.HasChildren(id) {
+		return "*" + b.backbone(sd, id, owner), nil
+	}
+
+	var typeSD *registry.StructureDefinition
+	if code != "" {
+		typeSD = b.registry.GetByType(code)
+	}
+	if typeSD == nil || typeSD.Snapshot == nil || len(typeSD.Snapshot.Element) == 0 || typeSD.Kind == registry.KindResource {
+		// Resources (e.g., contained ones) are kept as raw JSON
+		b.usesJSON = true
+		return "json.RawMessage", nil
+	}
+	return "*" + b.typeStruct(typeSD), nil
+}
+
+// backbone generates the struct of an element with children in sd and
+// returns its name; owner is the preferred name, derived from the element
+// id when empty.
+func (b *builder) backbone(sd *registry.StructureDefinition, id, owner string) string {
+	if owner == "" {
+		for _, part := range strings.Split(id, ".") {
+			owner += upperFirst(part)
+		}
+	}
+	doc := fmt.Sprintf("%s is generated from element %s of %s.", owner, id, sd.URL)
+	return b.define(sd.URL+"#"+id, goName(owner), doc, func(s *structType) {
+		b.fill(s, sd, id, false)
+	})
+}
+
+// primitive returns the Go type of a primitive element and the rules of its
+// fixed value, required binding and maxLength.
+func (b *builder) primitive(elem *registry.ElementDefinition, code string) (string, []string) {
+	var rules []string
+	switch code {
+	case "boolean":
+		return "*bool", nil
+	case "integer", "positiveInt", "unsignedInt":
+		switch code {
+		case "positiveInt":
+			rules = append(rules, "min=1")
+		case "unsignedInt":
+			rules = append(rules, "min=0")
+		}
+		if v, ok := fixedValue(elem); ok {
+			rules = []string{"eq=" + v}
+		}
+		return "*int", rules
+	case "decimal":
+		b.usesJSON = true
+		return "json.Number", nil
+	}
+
+	if v, ok := fixedValue(elem); ok && tagSafe(v) {
+		rules = append(rules, "eq="+v)
+	} else if codes := b.enum(elem, code); codes != nil {
+		rules = append(rules, "oneof="+strings.Join(codes, " "))
+	}
+	if elem.MaxLength > 0 {
+		rules = append(rules, "max="+strconv.Itoa(elem.MaxLength))
+	}
+	return "string", rules
+}
+
+// enum returns the codes of a code element's required binding, or nil if
+// there is none, its expansion is too large, or a code cannot be written in
+// a oneof tag.
+func (b *builder) enum(elem *registry.ElementDefinition, code string) []string {
+	if b.codes == nil || code != "code" || elem.Binding == nil || elem.Binding.Strength != "required" || elem.Binding.ValueSet == "" {
+		return nil
+	}
+	codes, ok := b.codes.EnumerateCodes(elem.Binding.ValueSet, maxEnum)
+	if !ok {
+		return nil
+	}
+	for _, c := range codes {
+		if !tagSafe(c) || strings.Contains(c, " ") {
+			return nil
+		}
+	}
+	return codes
+}
+
+// systemType returns the FHIR type an element with a FHIRPath system type,
+// such as Element.id, declares with the fhir-type extension, or else the
+// closest primitive type.
+func systemType(elem *registry.ElementDefinition, code string) string {
+	for _, t := range elem.Type {
+		for _, ext := range t.Extension {
+			if ext.URL == fhirTypeExt && ext.ValueURL+ext.ValueURI != "" {
+				return ext.ValueURL + ext.ValueURI
+			}
+		}
+	}
+	switch strings.TrimPrefix(code, systemTypePrefix) {
+	case "Boolean":
+		return "boolean"
+	case "Integer":
+		return "integer"
+	case "Decimal":
+		return "decimal"
+	}
+	return "string"
+}
+
+// fixedValue returns the fixed value of a primitive element as text.
+func fixedValue(elem *registry.ElementDefinition) (string, bool) {
+	raw, _, ok := elem.GetFixed()
+	if !ok {
+		return "", false
+	}
+	v, err := canonical.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case fmt.Stringer:
+		return val.String(), true
+	}
+	return "", false
+}
+
+// withoutRequired returns rules without a leading required or omitempty.
+func withoutRequired(rules []string) []string {
+	if len(rules) > 0 && (rules[0] == "required" || rules[0] == "omitempty") {
+		return rules[1:]
+	}
+	return rules
+}
+
+// tagSafe reports whether a value can be written in a validate tag, which
+// separates rules with commas and alternatives with pipes.
+func tagSafe(s string) bool {
+	return s != "" && !strings.ContainsAny(s, ",|`\"\\")
+}
+
+// goName returns an exported Go identifier for a name such as
+// "observation-bodyweight" or "USCorePatientProfile".
+func goName(s string) string {
+	var name strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		name.WriteString(upperFirst(part))
+	}
+	if name.Len() == 0 || !unicode.IsLetter([]rune(name.String())[0]) {
+		return "T" + name.String()
+	}
+	return name.String()
+}
+
+// initialisms are written in upper case in field names, as in Go style.
+var initialisms = []string{"Id", "Oid", "Uri", "Url", "Uuid"}
+
+// fieldName returns the Go name of an element, e.g. VersionID for
+// "versionId".
+func fieldName(name string) string {
+	name = upperFirst(name)
+	for _, word := range initialisms {
+		for i := 0; ; {
+			j := strings.Index(name[i:], word)
+			if j < 0 {
+				break
+			}
+			j += i
+			end := j + len(word)
+			if end == len(name) || unicode.IsUpper(rune(name[end])) {
+				name = name[:j] + strings.ToUpper(word) + name[end:]
+			}
+			i = end
+		}
+	}
+	return name
+}
+
+// isIdentifier reports whether s is a valid Go package name.
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func hasField(s *structType, jsonName string) bool {
+	for _, f := range s.fields {
+		if f.jsonName == jsonName {
+			return true
+		}
+	}
+	return false
+}
+
+// typeCode returns the element's first type.
+func typeCode(elem *registry.ElementDefinition) string {
+	if len(elem.Type) == 0 {
+		return ""
+	}
+	return elem.Type[0].Code
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,179 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+var (
+	sharedValidator     *validator.Validator
+	errSharedValidator  error
+	sharedValidatorOnce sync.Once
+)
+
+func newTestGenerator(t *testing.T) *Generator {
+	t.Helper()
+	sharedValidatorOnce.Do(func() {
+		sharedValidator, errSharedValidator = validator.New()
+	})
+	if errSharedValidator != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", errSharedValidator)
+	}
+	return New(sharedValidator.Registry(), sharedValidator.Terminology())
+}
+
+// goField is a generated struct field.
+type goField struct {
+	typ string
+	tag reflect.StructTag
+}
+
+// generate returns the fields of the generated structs by struct name.
+func generate(t *testing.T, urls ...string) map[string]map[string]goField {
+	t.Helper()
+	src, err := newTestGenerator(t).Go("models", urls...)
+	if err != nil {
+		t.Fatalf("Go() error = %v", err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "models.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+
+	structs := make(map[string]map[string]goField)
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		fields := make(map[string]goField)
+		for _, f := range spec.Type.(*ast.StructType).Fields.List {
+			tag, _ := strconv.Unquote(f.Tag.Value)
+			fields[f.Names[0].Name] = goField{typ: string(src[f.Type.Pos()-1 : f.Type.End()-1]), tag: reflect.StructTag(tag)}
+		}
+		structs[spec.Name.Name] = fields
+		return false
+	})
+	return structs
+}
+
+func TestGoResource(t *testing.T) {
+	structs := generate(t, "http://hl7.org/fhir/StructureDefinition/Patient")
+
+	patient := structs["Patient"]
+	if patient == nil {
+		t.Fatal("Patient struct not generated")
+	}
+	tests := []struct {
+		field, typ, json, validate string
+	}{
+		{"ResourceType", "string", "resourceType", "required,eq=Patient"},
+		{"ID", "string", "id,omitempty", ""},
+		{"Active", "*bool", "active,omitempty", ""},
+		{"Name", "[]HumanName", "name,omitempty", ""},
+		{"Gender", "string", "gender,omitempty", "omitempty,oneof=female male other unknown"},
+		{"DeceasedBoolean", "*bool", "deceasedBoolean,omitempty", "excluded_with=DeceasedDateTime"},
+		{"Contained", "[]json.RawMessage", "contained,omitempty", ""},
+		{"Contact", "[]PatientContact", "contact,omitempty", ""},
+	}
+	for _, tt := range tests {
+		f, ok := patient[tt.field]
+		if !ok {
+			t.Errorf("Patient.%s not generated", tt.field)
+			continue
+		}
+		if f.typ != tt.typ || f.tag.Get("json") != tt.json || f.tag.Get("validate") != tt.validate {
+			t.Errorf("Patient.%s = %s `%s`, want %s json %q validate %q", tt.field, f.typ, f.tag, tt.typ, tt.json, tt.validate)
+		}
+	}
+	for _, name := range []string{"HumanName", "Extension", "PatientContact"} {
+		if structs[name] == nil {
+			t.Errorf("%s struct not generated", name)
+		}
+	}
+	if got := structs["Extension"]["URL"].tag.Get("validate"); got != "required" {
+		t.Errorf("Extension.URL validate = %q, want required", got)
+	}
+}
+
+func TestGoProfile(t *testing.T) {
+	structs := generate(t, "http://hl7.org/fhir/StructureDefinition/bodyweight")
+
+	obs := structs["ObservationBodyweight"]
+	if obs == nil {
+		t.Fatal("ObservationBodyweight struct not generated")
+	}
+	if got := obs["Category"].tag.Get("validate"); got != "min=1" {
+		t.Errorf("Category validate = %q, want min=1", got)
+	}
+	if got := obs["EffectiveDateTime"].tag.Get("validate"); got != "required_without_all=EffectivePeriod,excluded_with=EffectivePeriod" {
+		t.Errorf("EffectiveDateTime validate = %q", got)
+	}
+	if _, ok := obs["ValueString"]; ok {
+		t.Error("value[x] should be restricted to the profile's types")
+	}
+	if got := obs["ValueQuantity"].typ; got != "*ObservationBodyweightValueQuantity" {
+		t.Fatalf("ValueQuantity type = %s, want the constrained struct", got)
+	}
+
+	quantity := structs["ObservationBodyweightValueQuantity"]
+	if got := quantity["System"].tag.Get("validate"); got != "required,eq=http://unitsofmeasure.org" {
+		t.Errorf("ValueQuantity.System validate = %q", got)
+	}
+	if got := quantity["Code"].tag.Get("validate"); got != "required,oneof=[lb_av] g kg" {
+		t.Errorf("ValueQuantity.Code validate = %q", got)
+	}
+}
+
+func TestGoRecursive(t *testing.T) {
+	structs := generate(t, "http://hl7.org/fhir/StructureDefinition/Questionnaire")
+	if got := structs["QuestionnaireItem"]["Item"].typ; got != "[]QuestionnaireItem" {
+		t.Errorf("QuestionnaireItem.Item type = %q, want []QuestionnaireItem", got)
+	}
+}
+
+func TestGoErrors(t *testing.T) {
+	g := newTestGenerator(t)
+	if _, err := g.Go("models", "http://example.org/StructureDefinition/missing"); err == nil {
+		t.Error("Go() of an unknown profile should fail")
+	}
+	if _, err := g.Go("my-models", "http://hl7.org/fhir/StructureDefinition/Patient"); err == nil || !strings.Contains(err.Error(), "package name") {
+		t.Errorf("Go() with an invalid package name error = %v", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	fields := map[string]string{
+		"id":         "ID",
+		"versionId":  "VersionID",
+		"identifier": "Identifier",
+		"url":        "URL",
+		"valueUuid":  "ValueUUID",
+		"valueOid":   "ValueOID",
+		"linkId":     "LinkID",
+	}
+	for in, want := range fields {
+		if got := fieldName(in); got != want {
+			t.Errorf("fieldName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	types := map[string]string{
+		"observation-bodyweight": "ObservationBodyweight",
+		"USCorePatientProfile":   "USCorePatientProfile",
+		"3d-model":               "T3dModel",
+	}
+	for in, want := range types {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,14 +2,18 @@
 package constraint
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/gofhir/fhirpath"
 
+	"github.com/gofhir/validator/pkg/cache"
 	"github.com/gofhir/validator/pkg/issue"
 	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/terminology"
 )
 
 // Validator validates constraints defined in ElementDefinitions.
@@ -17,20 +21,77 @@ type Validator struct {
 	registry *registry.Registry
 
 	// Cache of compiled FHIRPath expressions.
-	exprCache   map[string]*fhirpath.Expression
-	exprCacheMu sync.RWMutex
+	exprCache *cache.LRU[string, *fhirpath.Expression]
+
+	// skipKeys holds constraint keys enforced by a dedicated phase.
+	skipKeys map[string]bool
+
+	// terminology answers memberOf() (see SetTerminology), with the
+	// answers cached in members.
+	terminology *terminology.Registry
+	members     *cache.LRU[memberKey, memberAnswer]
+
+	// rootConstraints caches the constraints of each profile's root element
+	// and its ancestors (see profileRootConstraints).
+	rootConstraints sync.Map // *registry.StructureDefinition -> []profileConstraint
+}
+
+// profileConstraint is a constraint with the profile that defined it.
+type profileConstraint struct {
+	registry.Constraint
+	profile   string
+	elementID string // ID of the root element the constraint is on
 }
 
 // New creates a new constraint Validator.
 func New(reg *registry.Registry) *Validator {
 	return &Validator{
 		registry:  reg,
-		exprCache: make(map[string]*fhirpath.Expression),
+		exprCache: cache.NewLRU(expressionCost),
+	}
+}
+
+// ExpressionCache returns the cache of compiled FHIRPath expressions, e.g.
+// to bound its size (see cache.Budget).
+func (v *Validator) ExpressionCache() cache.Cache {
+	return v.exprCache
+}
+
+// expressionCost estimates the memory used by a compiled expression, whose
+// parse tree grows with the length of the expression.
+func expressionCost(expr string, _ *fhirpath.Expression) int64 {
+	return int64(512 + 48*len(expr))
+}
+
+// SkipKeys excludes constraints from FHIRPath evaluation because another phase
+// enforces them with more specific diagnostics (e.g., dom-2..dom-5 by the
+// contained phase). Must be called before the Validator is used concurrently.
+func (v *Validator) SkipKeys(keys ...string) {
+	if v.skipKeys == nil {
+		v.skipKeys = make(map[string]bool, len(keys))
+	}
+	for _, k := range keys {
+		v.skipKeys[k] = true
 	}
 }
 
 // Validate validates all constraints in a resource.
 func (v *Validator) Validate(resourceData json.RawMessage, sd *registry.StructureDefinition, result *issue.Result) {
+	v.ValidateContext(context.Background(), resourceData, sd, result)
+}
+
+// ValidateContext is Validate with a context that bounds FHIRPath evaluation.
+// Evaluation stops early once ctx is done; callers should treat the result as
+// incomplete when ctx.Err() is non-nil.
+func (v *Validator) ValidateContext(ctx context.Context, resourceData json.RawMessage, sd *registry.StructureDefinition, result *issue.Result) {
+	v.ValidateResolving(ctx, resourceData, sd, nil, result)
+}
+
+// ValidateResolving is ValidateContext with the FHIRPath resolve() function
+// answered by res (nil = resolve() is always empty). A constraint that fails
+// after resolve() could not resolve one of its references is not reported
+// as failed; an informational issue names the reference instead.
+func (v *Validator) ValidateResolving(ctx context.Context, resourceData json.RawMessage, sd *registry.StructureDefinition, res Resolver, result *issue.Result) {
 	if sd == nil || sd.Snapshot == nil {
 		return
 	}
@@ -45,25 +106,23 @@ func (v *Validator) Validate(resourceData json.RawMessage, sd *registry.Structur
 		return
 	}
 
-	// Evaluate constraints on the root element.
-	for i := range sd.Snapshot.Element {
-		elem := &sd.Snapshot.Element[i]
-
-		// Only process root element constraints for now.
-		// Element-level constraints require extracting sub-resources.
-		if elem.Path != resourceType {
-			continue
-		}
-
-		v.evaluateConstraints(resourceData, elem.Constraint, resourceType, result)
-	}
+	// Evaluate constraints on the root element, including those of ancestor
+	// profiles. Element-level constraints require extracting sub-resources.
+	v.evaluateProfileConstraints(ctx, resourceData, v.profileRootConstraints(sd), resourceType, res, result)
 
 	// Validate constraints on contained resources.
-	v.validateContainedConstraints(resource, resourceType, result)
+	v.validateContainedConstraints(ctx, resource, resourceType, res, result)
+}
+
+// ValidateElement evaluates the constraints of an ElementDefinition against
+// the JSON of a single element, reporting violations at fhirPath. Validation
+// stops early once ctx is done.
+func (v *Validator) ValidateElement(ctx context.Context, elementData json.RawMessage, constraints []registry.Constraint, fhirPath string, result *issue.Result) {
+	v.evaluateConstraints(ctx, elementData, constraints, fhirPath, nil, result)
 }
 
 // validateContainedConstraints validates constraints on contained resources.
-func (v *Validator) validateContainedConstraints(resource map[string]any, baseFhirPath string, result *issue.Result) {
+func (v *Validator) validateContainedConstraints(ctx context.Context, resource map[string]any, baseFhirPath string, res Resolver, result *issue.Result) {
 	containedRaw, ok := resource["contained"]
 	if !ok {
 		return
@@ -100,71 +159,204 @@ func (v *Validator) validateContainedConstraints(resource map[string]any, baseFh
 		containedFhirPath := fmt.Sprintf("%s.contained[%d]", baseFhirPath, i)
 
 		// Evaluate constraints on the contained resource's root element.
-		for j := range containedSD.Snapshot.Element {
-			elem := &containedSD.Snapshot.Element[j]
-			if elem.Path != resourceType {
+		v.evaluateProfileConstraints(ctx, containedJSON, v.profileRootConstraints(containedSD), containedFhirPath, res, result)
+	}
+}
+
+// profileRootConstraints returns the constraints on the root element of sd
+// and of its ancestors along the baseDefinition chain, each with the profile
+// that defined it: the constraint's source if given, otherwise the most
+// distant ancestor that has it. Ancestor constraints missing from the
+// snapshot of sd (e.g., dropped by a snapshot generator) are included, so
+// that a derived profile never enforces less than its base.
+func (v *Validator) profileRootConstraints(sd *registry.StructureDefinition) []profileConstraint {
+	if cached, ok := v.rootConstraints.Load(sd); ok {
+		return cached.([]profileConstraint)
+	}
+
+	chain := []*registry.StructureDefinition{sd}
+	seen := map[string]bool{sd.URL: true}
+	for url := sd.BaseDefinition; url != "" && !seen[url]; {
+		base := v.registry.GetByURL(url)
+		if base == nil {
+			break
+		}
+		chain = append(chain, base)
+		seen[url] = true
+		url = base.BaseDefinition
+	}
+
+	// Origin of each key: the most distant ancestor with it
+	origin := make(map[string]string)
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, c := range rootElementConstraints(chain[i]) {
+			if _, ok := origin[c.Key]; !ok {
+				origin[c.Key] = chain[i].URL
+			}
+		}
+	}
+
+	var constraints []profileConstraint
+	added := make(map[string]bool)
+	for _, def := range chain {
+		for _, c := range rootElementConstraints(def) {
+			if added[c.Key] {
 				continue
 			}
-			v.evaluateConstraints(containedJSON, elem.Constraint, containedFhirPath, result)
+			added[c.Key] = true
+			profile := c.Source
+			if profile == "" {
+				profile = origin[c.Key]
+			}
+			elementID := def.Type
+			if defining := v.registry.GetByURL(profile); defining != nil {
+				elementID = defining.Type
+			}
+			constraints = append(constraints, profileConstraint{Constraint: c, profile: profile, elementID: elementID})
 		}
 	}
+
+	actual, _ := v.rootConstraints.LoadOrStore(sd, constraints)
+	return actual.([]profileConstraint)
 }
 
-// evaluateConstraints evaluates all constraints on an element.
-func (v *Validator) evaluateConstraints(data json.RawMessage, constraints []registry.Constraint, fhirPath string, result *issue.Result) {
+// rootElementConstraints returns the constraints on the root element of a
+// StructureDefinition's snapshot.
+func rootElementConstraints(sd *registry.StructureDefinition) []registry.Constraint {
+	if sd.Snapshot == nil {
+		return nil
+	}
+	for i := range sd.Snapshot.Element {
+		if elem := &sd.Snapshot.Element[i]; !strings.Contains(elem.Path, ".") {
+			return elem.Constraint
+		}
+	}
+	return nil
+}
+
+// evaluateProfileConstraints evaluates constraints, attributing violations to
+// the profile that defined them.
+func (v *Validator) evaluateProfileConstraints(ctx context.Context, data json.RawMessage, constraints []profileConstraint, fhirPath string, res Resolver, result *issue.Result) {
 	for _, c := range constraints {
-		if c.Expression == "" {
-			continue
+		if ctx.Err() != nil {
+			return
 		}
+		before := len(result.Issues)
+		v.evaluateConstraint(ctx, data, c.Constraint, fhirPath, res, result)
+		for i := before; i < len(result.Issues); i++ {
+			result.Issues[i].Profile = c.profile
+		}
+		result.Attribute(before, issue.Source{StructureDefinition: c.profile, ElementID: c.elementID})
+	}
+}
 
-		// Skip best-practice constraints (dom-6, etc.) for now.
-		// These are typically warnings about narrative, performer, etc.
-		if v.isBestPractice(c.Key) {
-			continue
+// evaluateConstraints evaluates all constraints on an element.
+func (v *Validator) evaluateConstraints(ctx context.Context, data json.RawMessage, constraints []registry.Constraint, fhirPath string, res Resolver, result *issue.Result) {
+	for _, c := range constraints {
+		if ctx.Err() != nil {
+			return
 		}
+		v.evaluateConstraint(ctx, data, c, fhirPath, res, result)
+	}
+}
 
-		// Get or compile the expression.
-		expr, err := v.getCompiledExpression(c.Expression)
-		if err != nil {
-			// Log compilation error but don't fail validation.
-			result.AddWarningWithID(
-				issue.DiagConstraintCompileError,
-				map[string]any{
-					"key":   c.Key,
-					"error": err.Error(),
-				},
-				fhirPath,
-			)
-			continue
+// evaluateConstraint evaluates a constraint on an element.
+func (v *Validator) evaluateConstraint(ctx context.Context, data json.RawMessage, c registry.Constraint, fhirPath string, res Resolver, result *issue.Result) {
+	if c.Expression == "" {
+		return
+	}
+
+	// Skip best-practice constraints (dom-6, etc.) for now.
+	// These are typically warnings about narrative, performer, etc.
+	if v.isBestPractice(c.Key) || v.skipKeys[c.Key] {
+		return
+	}
+	before := len(result.Issues)
+	defer func() { result.Attribute(before, issue.Source{Constraint: c.Key}) }()
+
+	// Get or compile the expression.
+	expr, err := v.getCompiledExpression(c.Expression)
+	if err != nil {
+		// Log compilation error but don't fail validation.
+		result.AddWarningWithID(
+			issue.DiagConstraintCompileError,
+			issue.Params{
+				issue.String("key", c.Key),
+				issue.String("error", err.Error()),
+			},
+			fhirPath,
+		)
+		return
+	}
+
+	// Evaluate the expression.
+	var evalResult fhirpath.Collection
+	var ev *evaluation
+	if v.terminology != nil || res != nil {
+		ev = &evaluation{v: v, resolver: res}
+		evalResult, err = ev.evaluate(ctx, expr, data)
+	} else {
+		evalResult, err = evaluate(ctx, expr, data)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return // Timed out or canceled: not a constraint problem
 		}
+		// Log evaluation error but don't fail validation.
+		result.AddWarningWithID(
+			issue.DiagConstraintEvalError,
+			issue.Params{
+				issue.String("key", c.Key),
+				issue.String("error", err.Error()),
+			},
+			fhirPath,
+		)
+		return
+	}
 
-		// Evaluate the expression.
-		evalResult, err := expr.Evaluate(data)
-		if err != nil {
-			// Log evaluation error but don't fail validation.
-			result.AddWarningWithID(
-				issue.DiagConstraintEvalError,
-				map[string]any{
-					"key":   c.Key,
-					"error": err.Error(),
+	// A memberOf() that could not be answered makes the outcome unknown.
+	if ev != nil && len(ev.valueSets) > 0 {
+		result.AddWarningWithID(
+			issue.DiagConstraintValueSetUnresolved,
+			issue.Params{
+				issue.String("key", c.Key),
+				issue.String("valueSet", ev.valueSets[0]),
+			},
+			fhirPath,
+		)
+		return
+	}
+
+	// Check if constraint passed.
+	if !v.constraintPassed(evalResult) {
+		// Failures that may come from an unresolved target are not reported.
+		if ev != nil && len(ev.references) > 0 {
+			result.AddInfoWithID(
+				issue.DiagConstraintReferenceUnresolved,
+				issue.Params{
+					issue.String("key", c.Key),
+					issue.String("reference", ev.references[0]),
 				},
 				fhirPath,
 			)
-			continue
+			return
 		}
+		v.addConstraintViolation(c, fhirPath, result)
+	}
+}
 
-		// Check if constraint passed.
-		if !v.constraintPassed(evalResult) {
-			v.addConstraintViolation(c, fhirPath, result)
-		}
+// evaluate evaluates a compiled expression, bounded by ctx when it can be
+// canceled. Without a cancelable context the evaluation is unbounded, as before.
+func evaluate(ctx context.Context, expr *fhirpath.Expression, data json.RawMessage) (fhirpath.Collection, error) {
+	if ctx.Done() == nil {
+		return expr.Evaluate(data)
 	}
+	return expr.EvaluateWithOptions(data, fhirpath.WithContext(ctx), fhirpath.WithTimeout(0))
 }
 
 // getCompiledExpression returns a cached compiled expression or compiles a new one.
 func (v *Validator) getCompiledExpression(expr string) (*fhirpath.Expression, error) {
-	v.exprCacheMu.RLock()
-	compiled, ok := v.exprCache[expr]
-	v.exprCacheMu.RUnlock()
+	compiled, ok := v.exprCache.Get(expr)
 	if ok {
 		return compiled, nil
 	}
@@ -176,9 +368,7 @@ func (v *Validator) getCompiledExpression(expr string) (*fhirpath.Expression, er
 	}
 
 	// Cache it.
-	v.exprCacheMu.Lock()
-	v.exprCache[expr] = compiled
-	v.exprCacheMu.Unlock()
+	v.exprCache.Add(expr, compiled)
 
 	return compiled, nil
 }
@@ -202,10 +392,10 @@ func (v *Validator) constraintPassed(result fhirpath.Collection) bool {
 
 // addConstraintViolation adds an issue for a failed constraint.
 func (v *Validator) addConstraintViolation(c registry.Constraint, fhirPath string, result *issue.Result) {
-	params := map[string]any{
-		"key":     c.Key,
-		"human":   c.Human,
-		"details": fmt.Sprintf("Constraint failed: %s: '%s'", c.Key, c.Human),
+	params := issue.Params{
+		issue.String("key", c.Key),
+		issue.String("human", c.Human),
+		issue.String("details", fmt.Sprintf("Constraint failed: %s: '%s'", c.Key, c.Human)),
 	}
 
 	if c.Severity == "error" {
@@ -0,0 +1,86 @@
+package constraint
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofhir/validator/pkg/cache"
+	"github.com/gofhir/validator/pkg/terminology"
+)
+
+// errUnresolvedValueSet is returned to FHIRPath by memberOf() for a ValueSet
+// that is not loaded.
+var errUnresolvedValueSet = errors.New("value set could not be resolved")
+
+// memberKey identifies a memberOf() check.
+type memberKey struct {
+	valueSet, system, code string
+}
+
+// memberAnswer is the outcome of a memberOf() check.
+type memberAnswer struct {
+	member, found bool
+}
+
+// memberCost estimates the memory used by a cached memberOf() answer.
+func memberCost(k memberKey, _ memberAnswer) int64 {
+	return int64(64 + len(k.valueSet) + len(k.system) + len(k.code))
+}
+
+// SetTerminology makes the FHIRPath memberOf() function check codes against
+// the ValueSets of reg. Without it, memberOf() is empty, which passes the
+// constraints that use it. Must be called before the Validator is used
+// concurrently.
+func (v *Validator) SetTerminology(reg *terminology.Registry) {
+	v.terminology = reg
+	v.members = cache.NewLRU(memberCost)
+}
+
+// MembershipCache returns the cache of memberOf() answers, or nil without a
+// terminology registry (see SetTerminology).
+func (v *Validator) MembershipCache() cache.Cache {
+	if v.members == nil {
+		return nil
+	}
+	return v.members
+}
+
+// MemberOf reports whether a code, Coding or CodeableConcept (as extracted by
+// the FHIRPath engine) is in a ValueSet: for a CodeableConcept, whether any
+// of its codings is.
+func (e *evaluation) MemberOf(ctx context.Context, value any, valueSetURL string) (bool, error) {
+	concept, _ := value.(map[string]any)
+	codings := []map[string]any{concept}
+	if list, ok := concept["coding"].([]map[string]any); ok {
+		codings = list
+	}
+	for _, coding := range codings {
+		system, _ := coding["system"].(string)
+		code, _ := coding["code"].(string)
+		if code == "" {
+			continue
+		}
+		a := e.v.member(ctx, memberKey{valueSet: valueSetURL, system: system, code: code})
+		if !a.found {
+			e.valueSets = append(e.valueSets, valueSetURL)
+			return false, errUnresolvedValueSet
+		}
+		if a.member {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// member checks a code against a ValueSet, caching the answer.
+func (v *Validator) member(ctx context.Context, k memberKey) memberAnswer {
+	if a, ok := v.members.Get(k); ok {
+		return a
+	}
+	var a memberAnswer
+	a.member, a.found = v.terminology.ValidateCodeContext(ctx, k.valueSet, k.system, k.code)
+	if ctx.Err() == nil {
+		v.members.Add(k, a)
+	}
+	return a
+}
@@ -0,0 +1,61 @@
+package constraint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/gofhir/fhirpath"
+	"github.com/gofhir/fhirpath/eval"
+)
+
+// errUnresolvedReference is returned to FHIRPath by resolve() for a
+// reference without a target.
+var errUnresolvedReference = errors.New("reference could not be resolved")
+
+// Resolver answers the FHIRPath resolve() function with the JSON of the
+// resource a reference points to. It returns nil and no error when the
+// reference does not resolve.
+type Resolver interface {
+	Resolve(ctx context.Context, reference string) ([]byte, error)
+}
+
+// evaluation is the FHIRPath terminology service and reference resolver of
+// one evaluation. It records the ValueSets and references that could not be
+// resolved, so that a constraint depending on them is not reported as
+// failed.
+type evaluation struct {
+	v        *Validator
+	resolver Resolver // nil = resolve() is empty
+
+	valueSets  []string // Unresolved ValueSets of memberOf()
+	references []string // Unresolved references of resolve()
+}
+
+// evaluate evaluates an expression with memberOf() and resolve() answered by
+// e.
+func (e *evaluation) evaluate(ctx context.Context, expr *fhirpath.Expression, data json.RawMessage) (fhirpath.Collection, error) {
+	ec := eval.NewContext(data)
+	ec.SetContext(ctx)
+	if e.v.terminology != nil {
+		ec.SetTerminologyService(e)
+	}
+	if e.resolver != nil {
+		ec.SetResolver(e)
+	}
+	return expr.EvaluateWithContext(ec)
+}
+
+// Resolve returns the target of a reference for resolve(), recording the
+// references that do not resolve.
+func (e *evaluation) Resolve(ctx context.Context, reference string) ([]byte, error) {
+	target, err := e.resolver.Resolve(ctx, reference)
+	if err == nil && target == nil {
+		err = errUnresolvedReference
+	}
+	if err != nil {
+		e.references = append(e.references, reference)
+		return nil, err
+	}
+	return target, nil
+}
@@ -0,0 +1,158 @@
+// Package contained validates the rules for contained resources defined on
+// DomainResource (dom-2 to dom-5) with explicit checks and diagnostics.
+//
+// The same rules exist as FHIRPath invariants, but a failing invariant only
+// reports the constraint key on the container. This phase reports the offending
+// contained resource and reference instead.
+package contained
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// ConstraintKeys lists the DomainResource invariants enforced by this package.
+// The constraint phase skips them to avoid reporting the same violation twice.
+var ConstraintKeys = []string{"dom-2", "dom-3", "dom-4", "dom-5"}
+
+// Validator validates contained resource rules.
+type Validator struct{}
+
+// New creates a new contained resource Validator.
+func New() *Validator {
+	return &Validator{}
+}
+
+// ValidateData validates contained resource rules for a pre-parsed resource,
+// including resources inside Bundle entries.
+func (v *Validator) ValidateData(resource map[string]any, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+	v.validateResource(resource, resourceType, result)
+}
+
+// validateResource checks the contained resources of a single container and
+// recurses into Bundle entries.
+func (v *Validator) validateResource(resource map[string]any, fhirPath string, result *issue.Result) {
+	if contained, ok := resource["contained"].([]any); ok && len(contained) > 0 {
+		v.validateContainer(resource, contained, fhirPath, result)
+	}
+
+	entries, ok := resource["entry"].([]any)
+	if !ok {
+		return
+	}
+	for i, entry := range entries {
+		entryMap, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		if res, ok := entryMap["resource"].(map[string]any); ok {
+			v.validateResource(res, fmt.Sprintf("%s.entry[%d].resource", fhirPath, i), result)
+		}
+	}
+}
+
+// validateContainer applies dom-2 to dom-5 to the contained resources of a container
+// and checks that every local (#id) reference resolves.
+func (v *Validator) validateContainer(container map[string]any, contained []any, fhirPath string, result *issue.Result) {
+	// Local references made from anywhere in the container, including other
+	// contained resources (dom-3 allows contained resources to reference each other).
+	refs := make(map[string]bool)
+	collectLocalReferences(container, refs)
+
+	ids := make(map[string]bool, len(contained))
+
+	for i, item := range contained {
+		res, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		itemPath := fmt.Sprintf("%s.contained[%d]", fhirPath, i)
+		id, _ := res["id"].(string)
+		if id != "" {
+			ids[id] = true
+		}
+
+		// dom-2: no nested contained resources
+		if nested, ok := res["contained"].([]any); ok && len(nested) > 0 {
+			result.AddErrorWithID(issue.DiagContainedNested, issue.Params{issue.String("id", id)}, itemPath+".contained")
+		}
+
+		if meta, ok := res["meta"].(map[string]any); ok {
+			// dom-4: no meta.versionId or meta.lastUpdated
+			for _, key := range []string{"versionId", "lastUpdated"} {
+				if _, ok := meta[key]; ok {
+					result.AddErrorWithID(issue.DiagContainedMeta, issue.Params{issue.String("id", id), issue.String("element", key)}, itemPath+".meta."+key)
+				}
+			}
+			// dom-5: no security labels
+			if _, ok := meta["security"]; ok {
+				result.AddErrorWithID(issue.DiagContainedSecurity, issue.Params{issue.String("id", id)}, itemPath+".meta.security")
+			}
+		}
+
+		// dom-3: referenced from the container, or references the container ("#")
+		if id == "" || refs["#"+id] || referencesContainer(res) {
+			continue
+		}
+		result.AddErrorWithID(issue.DiagContainedNotReferenced, issue.Params{issue.String("id", id)}, itemPath)
+	}
+
+	// Local references must resolve to a contained resource ("#" alone is the container).
+	reportUnresolved(container, ids, fhirPath, result)
+}
+
+// collectLocalReferences gathers every string value starting with "#" in the
+// resource tree. This covers Reference.reference as well as canonical and uri
+// elements, which dom-3 also accepts as references to contained resources.
+func collectLocalReferences(node any, refs map[string]bool) {
+	switch val := node.(type) {
+	case map[string]any:
+		for _, child := range val {
+			collectLocalReferences(child, refs)
+		}
+	case []any:
+		for _, child := range val {
+			collectLocalReferences(child, refs)
+		}
+	case string:
+		if strings.HasPrefix(val, "#") {
+			refs[val] = true
+		}
+	}
+}
+
+// referencesContainer reports whether a contained resource references its
+// container with a bare "#" reference.
+func referencesContainer(res map[string]any) bool {
+	refs := make(map[string]bool)
+	collectLocalReferences(res, refs)
+	return refs["#"]
+}
+
+// reportUnresolved reports Reference.reference values of the form "#id" that do
+// not match a contained resource. Nested Bundle entries are separate containers
+// and are skipped.
+func reportUnresolved(node any, ids map[string]bool, fhirPath string, result *issue.Result) {
+	switch val := node.(type) {
+	case map[string]any:
+		if ref, ok := val["reference"].(string); ok && strings.HasPrefix(ref, "#") && ref != "#" && !ids[ref[1:]] {
+			result.AddErrorWithID(issue.DiagContainedRefNotResolved, issue.Params{issue.String("reference", ref)}, fhirPath+".reference")
+		}
+		for key, child := range val {
+			if key == "entry" && val["resourceType"] == "Bundle" {
+				continue
+			}
+			reportUnresolved(child, ids, fhirPath+"."+key, result)
+		}
+	case []any:
+		for i, child := range val {
+			reportUnresolved(child, ids, fmt.Sprintf("%s[%d]", fhirPath, i), result)
+		}
+	}
+}
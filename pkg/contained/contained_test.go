@@ -0,0 +1,165 @@
+package contained
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource map[string]any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "contained referenced from container",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{
+					map[string]any{"resourceType": "Organization", "id": "org1"},
+				},
+				"managingOrganization": map[string]any{"reference": "#org1"},
+			},
+		},
+		{
+			name: "contained references container",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{
+					map[string]any{
+						"resourceType": "Provenance",
+						"id":           "prov",
+						"target":       []any{map[string]any{"reference": "#"}},
+					},
+				},
+			},
+		},
+		{
+			name: "contained referenced from another contained resource",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{
+					map[string]any{"resourceType": "Organization", "id": "org1"},
+					map[string]any{
+						"resourceType": "Practitioner",
+						"id":           "pr1",
+						"extension": []any{map[string]any{
+							"url":            "http://example.org/org",
+							"valueReference": map[string]any{"reference": "#org1"},
+						}},
+					},
+				},
+				"generalPractitioner": []any{map[string]any{"reference": "#pr1"}},
+			},
+		},
+		{
+			name: "dom-2 nested contained",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{
+					map[string]any{
+						"resourceType": "Organization",
+						"id":           "org1",
+						"contained":    []any{map[string]any{"resourceType": "Endpoint", "id": "ep"}},
+					},
+				},
+				"managingOrganization": map[string]any{"reference": "#org1"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagContainedNested},
+			wantPath: "Patient.contained[0].contained",
+		},
+		{
+			name: "dom-3 not referenced",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{
+					map[string]any{"resourceType": "Organization", "id": "org1"},
+				},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagContainedNotReferenced},
+			wantPath: "Patient.contained[0]",
+		},
+		{
+			name: "dom-4 versionId and lastUpdated",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{
+					map[string]any{
+						"resourceType": "Organization",
+						"id":           "org1",
+						"meta":         map[string]any{"versionId": "1", "lastUpdated": "2024-01-01T00:00:00Z"},
+					},
+				},
+				"managingOrganization": map[string]any{"reference": "#org1"},
+			},
+			wantIDs: []issue.DiagnosticID{issue.DiagContainedMeta, issue.DiagContainedMeta},
+		},
+		{
+			name: "dom-5 security label",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{
+					map[string]any{
+						"resourceType": "Organization",
+						"id":           "org1",
+						"meta":         map[string]any{"security": []any{map[string]any{"code": "R"}}},
+					},
+				},
+				"managingOrganization": map[string]any{"reference": "#org1"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagContainedSecurity},
+			wantPath: "Patient.contained[0].meta.security",
+		},
+		{
+			name: "unresolved local reference",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{
+					map[string]any{"resourceType": "Organization", "id": "org1"},
+				},
+				"managingOrganization": map[string]any{"reference": "#org1"},
+				"generalPractitioner":  []any{map[string]any{"reference": "#missing"}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagContainedRefNotResolved},
+			wantPath: "Patient.generalPractitioner[0].reference",
+		},
+		{
+			name: "bundle entry checked as its own container",
+			resource: map[string]any{
+				"resourceType": "Bundle",
+				"entry": []any{
+					map[string]any{"resource": map[string]any{
+						"resourceType": "Patient",
+						"contained": []any{
+							map[string]any{"resourceType": "Organization", "id": "org1"},
+						},
+					}},
+				},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagContainedNotReferenced},
+			wantPath: "Bundle.entry[0].resource.contained[0]",
+		},
+	}
+
+	v := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, result)
+
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("got %d issues, want %d: %+v", len(result.Issues), len(tt.wantIDs), result.Issues)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue %d: MessageID = %q, want %q", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("Expression = %q, want %q", result.Issues[0].Expression[0], tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+// Package crossversion converts resources between FHIR R4 (and R4B) and R5,
+// so a resource can be validated against the definitions and profiles of
+// another version than its own, e.g. during a migration from R4 to R5.
+//
+// The conversion applies the cross-version mappings of common resources
+// (Encounter, MedicationRequest, Procedure, DiagnosticReport, Immunization,
+// AllergyIntolerance and Coverage): renamed elements such as
+// Encounter.period to actualPeriod, changed types such as CodeableConcept and
+// Reference to CodeableReference, changed cardinalities and translated codes.
+// Other elements are kept as they are when the target version defines them.
+// Everything that cannot be converted is left out and reported, at its path
+// in the original resource:
+//
+//   - VERSION_ELEMENT_NOT_MAPPED: the element is not defined in the target
+//     version (e.g., Encounter.classHistory in R5)
+//   - VERSION_VALUE_NOT_MAPPED: the value has no equivalent (e.g., an R5
+//     CodeableReference with both a concept and a reference)
+//   - VERSION_REPETITION_NOT_MAPPED: a repeating element allows one value in
+//     the target version, and has more
+//
+// Contained resources and Bundle entries are converted too.
+package crossversion
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// release returns the release a version belongs to for conversion, R4 (also
+// for R4B) or R5, or "" for versions that cannot be converted.
+func release(version string) string {
+	switch loader.NormalizeVersion(version) {
+	case "4.0.1", "4.3.0":
+		return "R4"
+	case "5.0.0":
+		return "R5"
+	}
+	return ""
+}
+
+// Supported reports whether resources of a FHIR version can be converted.
+func Supported(version string) bool {
+	return release(version) != ""
+}
+
+// Converter converts resources of other FHIR versions to the version of its
+// registry. It is safe for concurrent use.
+type Converter struct {
+	registry *registry.Registry
+	walker   *walker.Walker
+	version  string
+	defined  sync.Map // Element path -> bool, in the target version
+}
+
+// New creates a Converter to the FHIR version of reg.
+func New(reg *registry.Registry, version string) *Converter {
+	return &Converter{registry: reg, walker: walker.New(reg), version: loader.NormalizeVersion(version)}
+}
+
+// Convert converts a resource of FHIR version from to the version of the
+// Converter, reporting what cannot be converted to result. The resource is
+// not modified. It fails when either version cannot be converted.
+func (c *Converter) Convert(resource map[string]any, from string, result *issue.Result) (map[string]any, error) {
+	from = loader.NormalizeVersion(from)
+	src, dst := release(from), release(c.version)
+	if src == "" || dst == "" {
+		return nil, fmt.Errorf("conversion from FHIR %s to FHIR %s is not supported", from, c.version)
+	}
+	if from == c.version {
+		return resource, nil
+	}
+
+	cv := &conversion{Converter: c, from: from, result: result}
+	if src != dst {
+		cv.up = dst == "R5"
+		cv.mappings = make(map[string][]*mapping)
+		for i := range mappings {
+			m := &mappings[i]
+			source := m.r4
+			if !cv.up {
+				source = m.r5
+			}
+			parent := source[:strings.LastIndexByte(source, '.')]
+			cv.mappings[parent] = append(cv.mappings[parent], m)
+		}
+	}
+
+	resourceType, _ := resource["resourceType"].(string)
+	result.AddInfoWithID(issue.DiagVersionConverted, issue.Params{issue.String("from", from), issue.String("to", c.version)}, resourceType)
+	return cv.resource(resource, resourceType), nil
+}
+
+// conversion is the state of a Convert call.
+type conversion struct {
+	*Converter
+	from     string
+	up       bool                  // R4 to R5; false for R5 to R4 and within a release
+	mappings map[string][]*mapping // By the path of their parent in the source version
+	result   *issue.Result
+}
+
+// resource converts a resource of any type; types the target version does
+// not define are left to validation to report.
+func (cv *conversion) resource(data map[string]any, fhirPath string) map[string]any {
+	resourceType, _ := data["resourceType"].(string)
+	if cv.registry.GetByType(resourceType) == nil {
+		return data
+	}
+	return cv.object(data, resourceType, resourceType, fhirPath)
+}
+
+// object converts an object: the resource or an element at srcPath in the
+// source version and dstPath in the target version.
+func (cv *conversion) object(data map[string]any, srcPath, dstPath, fhirPath string) map[string]any {
+	out := make(map[string]any, len(data))
+	chosen := make(map[string]*target) // Target of the values of mapped elements, for their shadows
+
+	// Values go before the _name shadows holding their extensions
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if sa, sb := strings.HasPrefix(a, "_"), strings.HasPrefix(b, "_"); sa != sb {
+			if sa {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a, b)
+	})
+
+	for _, key := range keys {
+		value := data[key]
+		if key == "resourceType" {
+			out[key] = value
+			continue
+		}
+		name, shadow := strings.CutPrefix(key, "_")
+		element := srcPath + "." + name
+		path := fhirPath + "." + key
+
+		if targets := cv.targets(srcPath, name); len(targets) > 0 {
+			if shadow {
+				cv.convertShadow(out, value, targets, chosen[name], element, dstPath, path)
+			} else {
+				chosen[name] = cv.convertMapped(out, value, targets, element, dstPath, path)
+			}
+			continue
+		}
+		if !cv.isDefined(dstPath + "." + name) {
+			cv.result.AddErrorWithID(issue.DiagVersionElementNotMapped,
+				issue.Params{issue.String("element", element), issue.String("version", cv.version)}, path)
+			continue
+		}
+		out[key] = cv.value(value, element, dstPath+"."+name, path)
+	}
+	return out
+}
+
+// value converts the value of an element, recursing into objects, arrays
+// and resources.
+func (cv *conversion) value(v any, srcPath, dstPath, fhirPath string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if _, ok := v["resourceType"].(string); ok {
+			return cv.resource(v, fhirPath)
+		}
+		return cv.object(v, srcPath, dstPath, fhirPath)
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = cv.value(item, srcPath, dstPath, fmt.Sprintf("%s[%d]", fhirPath, i))
+		}
+		return items
+	}
+	return v
+}
+
+// target is where a mapping puts a source element in the target version.
+type target struct {
+	name      string // Member name, with the type of choice elements
+	many      bool
+	convert   func(any) (any, bool) // nil = same value
+	primitive bool
+}
+
+// targets returns the targets of the mappings of a member of the object at
+// srcPath, in the order values are offered to them.
+func (cv *conversion) targets(srcPath, name string) []*target {
+	var targets []*target
+	for _, m := range cv.mappings[srcPath] {
+		source, dest := m.r4, m.r5
+		if !cv.up {
+			source, dest = m.r5, m.r4
+		}
+		many := cv.repeats(dest)
+		source, dest = source[strings.LastIndexByte(source, '.')+1:], dest[strings.LastIndexByte(dest, '.')+1:]
+
+		var typeSuffix string
+		if base, choice := strings.CutSuffix(source, "[x]"); choice {
+			suffix, ok := strings.CutPrefix(name, base)
+			if !ok || suffix == "" || !unicode.IsUpper(rune(suffix[0])) {
+				continue
+			}
+			typeSuffix = suffix
+			dest = strings.TrimSuffix(dest, "[x]")
+		} else if source != name {
+			continue
+		}
+
+		t := &target{name: dest + typeSuffix, many: many, primitive: true}
+		if m.convert != nil {
+			t.convert, t.primitive = m.convert.up, m.convert.primitive
+			if !cv.up {
+				t.convert = m.convert.down
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// convertMapped converts the values of a mapped element and returns the
+// target of its first value.
+func (cv *conversion) convertMapped(out map[string]any, value any, targets []*target, element, dstPath, fhirPath string) *target {
+	var first *target
+	cv.eachItem(value, fhirPath, func(item any, path string) {
+		for _, t := range targets {
+			converted := item
+			if t.convert != nil {
+				var ok bool
+				if converted, ok = t.convert(item); !ok {
+					continue
+				}
+			} else {
+				converted = cv.value(item, element, dstPath+"."+t.name, path)
+			}
+			cv.place(out, t, t.name, converted, element, path)
+			if first == nil {
+				first = t
+			}
+			return
+		}
+		cv.result.AddErrorWithID(issue.DiagVersionValueNotMapped,
+			issue.Params{issue.String("element", element), issue.String("version", cv.version)}, path)
+	})
+	return first
+}
+
+// convertShadow moves the extensions of a mapped primitive element to the
+// element its value went to, when that is still a primitive.
+func (cv *conversion) convertShadow(out map[string]any, value any, targets []*target, chosen *target, element, dstPath, fhirPath string) {
+	if chosen == nil {
+		chosen = targets[0]
+	}
+	if !chosen.primitive {
+		cv.result.AddErrorWithID(issue.DiagVersionValueNotMapped,
+			issue.Params{issue.String("element", element), issue.String("version", cv.version)}, fhirPath)
+		return
+	}
+	cv.eachItem(value, fhirPath, func(item any, path string) {
+		converted := cv.value(item, element, dstPath+"."+chosen.name, path)
+		cv.place(out, chosen, "_"+chosen.name, converted, element, path)
+	})
+}
+
+// eachItem calls fn with each item of an array value, or with the value.
+func (cv *conversion) eachItem(value any, fhirPath string, fn func(item any, path string)) {
+	items, isArray := value.([]any)
+	if !isArray {
+		fn(value, fhirPath)
+		return
+	}
+	for i, item := range items {
+		fn(item, fmt.Sprintf("%s[%d]", fhirPath, i))
+	}
+}
+
+// place adds a converted value to key, reporting values beyond the first
+// for elements that do not repeat in the target version.
+func (cv *conversion) place(out map[string]any, t *target, key string, value any, element, fhirPath string) {
+	if t.many {
+		items, _ := out[key].([]any)
+		out[key] = append(items, value)
+		return
+	}
+	if _, taken := out[key]; taken {
+		cv.result.AddErrorWithID(issue.DiagVersionRepetitionNotMapped,
+			issue.Params{issue.String("element", element), issue.String("version", cv.version)}, fhirPath)
+		return
+	}
+	out[key] = value
+}
+
+// repeats reports whether an element path of the target version allows more
+// than one value.
+func (cv *conversion) repeats(path string) bool {
+	elem := cv.registry.GetElementDefinition(path)
+	return elem != nil && cv.registry.Repeats(elem)
+}
+
+// isDefined reports whether the target version defines an element path
+// (without indices).
+func (cv *conversion) isDefined(path string) bool {
+	if defined, ok := cv.defined.Load(path); ok {
+		return defined.(bool)
+	}
+	resourceType, _, _ := strings.Cut(path, ".")
+	defined := false
+	if sd := cv.registry.GetByType(resourceType); sd != nil {
+		_, err := cv.walker.ResolvePath(sd.URL, path)
+		defined = err == nil
+	}
+	cv.defined.Store(path, defined)
+	return defined
+}
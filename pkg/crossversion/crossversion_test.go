@@ -0,0 +1,183 @@
+package crossversion
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// decode parses a JSON resource.
+func decode(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var data map[string]any
+	if err := json.Unmarshal([]byte(s), &data); err != nil {
+		t.Fatalf("invalid test resource: %v", err)
+	}
+	return data
+}
+
+// conversionIssues returns the ID and path of each issue but VERSION_CONVERTED.
+func conversionIssues(result *issue.Result) []string {
+	var issues []string
+	for _, iss := range result.Issues {
+		if iss.MessageID != string(issue.DiagVersionConverted) {
+			issues = append(issues, iss.MessageID+" "+iss.Expression[0])
+		}
+	}
+	sort.Strings(issues)
+	return issues
+}
+
+func TestConvertR4ToR5(t *testing.T) {
+	c := New(testutil.Registry(t, "5.0.0"), "R5")
+	encounter := decode(t, `{
+		"resourceType": "Encounter",
+		"status": "finished",
+		"_status": {"extension": [{"url": "http://example.org/x", "valueString": "x"}]},
+		"class": {"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "AMB"},
+		"classHistory": [{"class": {"code": "EMER"}, "period": {"start": "2024-01-01"}}],
+		"participant": [{"individual": {"reference": "Practitioner/1"}}],
+		"period": {"start": "2024-01-01"},
+		"reasonCode": [{"text": "Checkup"}],
+		"reasonReference": [{"reference": "Condition/1"}],
+		"diagnosis": [{"condition": {"reference": "Condition/1"}, "rank": 1}],
+		"hospitalization": {"admitSource": {"text": "Referral"}},
+		"contained": [{"resourceType": "Procedure", "id": "p", "status": "completed", "subject": {"reference": "Patient/1"},
+			"performedDateTime": "2024-01-01", "asserter": {"reference": "Patient/1"}}]
+	}`)
+	result := issue.NewResult()
+	converted, err := c.Convert(encounter, "4.0.1", result)
+	if err != nil {
+		t.Fatalf("Convert() error: %v", err)
+	}
+
+	want := decode(t, `{
+		"resourceType": "Encounter",
+		"status": "completed",
+		"_status": {"extension": [{"url": "http://example.org/x", "valueString": "x"}]},
+		"class": [{"coding": [{"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "AMB"}]}],
+		"participant": [{"actor": {"reference": "Practitioner/1"}}],
+		"actualPeriod": {"start": "2024-01-01"},
+		"reason": [{"value": [{"concept": {"text": "Checkup"}}]}, {"value": [{"reference": {"reference": "Condition/1"}}]}],
+		"diagnosis": [{"condition": [{"reference": {"reference": "Condition/1"}}]}],
+		"admission": {"admitSource": {"text": "Referral"}},
+		"contained": [{"resourceType": "Procedure", "id": "p", "status": "completed", "subject": {"reference": "Patient/1"},
+			"occurrenceDateTime": "2024-01-01"}]
+	}`)
+	if !reflect.DeepEqual(converted, want) {
+		got, _ := json.MarshalIndent(converted, "", "  ")
+		t.Errorf("Convert() =\n%s", got)
+	}
+
+	wantIssues := []string{
+		"VERSION_ELEMENT_NOT_MAPPED Encounter.classHistory",
+		"VERSION_ELEMENT_NOT_MAPPED Encounter.contained[0].asserter",
+		"VERSION_ELEMENT_NOT_MAPPED Encounter.diagnosis[0].rank",
+	}
+	if got := conversionIssues(result); !reflect.DeepEqual(got, wantIssues) {
+		t.Errorf("issues = %v, want %v", got, wantIssues)
+	}
+	if result.Issues[0].MessageID != string(issue.DiagVersionConverted) || result.Issues[0].Diagnostics !=
+		"Converted from FHIR 4.0.1 to FHIR 5.0.0 for validation; other issues use FHIR 5.0.0 paths" {
+		t.Errorf("first issue = %+v, want the conversion notice", result.Issues[0])
+	}
+	if _, ok := encounter["period"]; !ok {
+		t.Error("Convert() modified its input")
+	}
+}
+
+func TestConvertR5ToR4(t *testing.T) {
+	c := New(testutil.Registry(t, "4.0.1"), "4.0.1")
+	request := decode(t, `{
+		"resourceType": "MedicationRequest",
+		"status": "active",
+		"intent": "order",
+		"medication": {"concept": {"text": "Aspirin"}},
+		"subject": {"reference": "Patient/1"},
+		"reported": true,
+		"performer": [{"reference": "Practitioner/1"}, {"reference": "Practitioner/2"}],
+		"reason": [{"concept": {"text": "Pain"}}, {"reference": {"reference": "Condition/1"}},
+			{"concept": {"text": "Fever"}, "reference": {"reference": "Condition/2"}}],
+		"renderedDosageInstruction": "Once a day"
+	}`)
+	result := issue.NewResult()
+	converted, err := c.Convert(request, "R5", result)
+	if err != nil {
+		t.Fatalf("Convert() error: %v", err)
+	}
+
+	want := decode(t, `{
+		"resourceType": "MedicationRequest",
+		"status": "active",
+		"intent": "order",
+		"medicationCodeableConcept": {"text": "Aspirin"},
+		"subject": {"reference": "Patient/1"},
+		"reportedBoolean": true,
+		"performer": {"reference": "Practitioner/1"},
+		"reasonCode": [{"text": "Pain"}],
+		"reasonReference": [{"reference": "Condition/1"}]
+	}`)
+	if !reflect.DeepEqual(converted, want) {
+		got, _ := json.MarshalIndent(converted, "", "  ")
+		t.Errorf("Convert() =\n%s", got)
+	}
+
+	wantIssues := []string{
+		"VERSION_ELEMENT_NOT_MAPPED MedicationRequest.renderedDosageInstruction",
+		"VERSION_REPETITION_NOT_MAPPED MedicationRequest.performer[1]",
+		"VERSION_VALUE_NOT_MAPPED MedicationRequest.reason[2]",
+	}
+	if got := conversionIssues(result); !reflect.DeepEqual(got, wantIssues) {
+		t.Errorf("issues = %v, want %v", got, wantIssues)
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      *transform
+		r4, r5 any
+	}{
+		{"code", codes(map[string]string{"onleave": "on-hold"}, map[string]string{"on-hold": "onleave"}), "onleave", "on-hold"},
+		{"unchanged code", codes(nil, nil), "planned", "planned"},
+		{"coding", wrap("coding", true), map[string]any{"code": "AMB"}, map[string]any{"coding": []any{map[string]any{"code": "AMB"}}}},
+		{"code concept", codeConcept("http://example.org/cs"), "allergy",
+			map[string]any{"coding": []any{map[string]any{"system": "http://example.org/cs", "code": "allergy"}}}},
+		{"positiveInt", positiveIntString, json.Number("2"), "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := tt.t.up(tt.r4); !ok || !reflect.DeepEqual(got, tt.r5) {
+				t.Errorf("up(%v) = %v, %v, want %v", tt.r4, got, ok, tt.r5)
+			}
+			if got, ok := tt.t.down(tt.r5); !ok || !reflect.DeepEqual(got, tt.r4) {
+				t.Errorf("down(%v) = %v, %v, want %v", tt.r5, got, ok, tt.r4)
+			}
+		})
+	}
+
+	// Values without equivalent
+	if _, ok := codes(nil, map[string]string{"discontinued": ""}).down("discontinued"); ok {
+		t.Error("code without equivalent converted")
+	}
+	if _, ok := positiveIntString.down("first"); ok {
+		t.Error("non-numeric dose number converted to positiveInt")
+	}
+	if _, ok := wrap("concept", false).down(map[string]any{"concept": "a", "reference": "b"}); ok {
+		t.Error("CodeableReference with concept and reference unwrapped")
+	}
+}
+
+func TestConvertUnsupported(t *testing.T) {
+	c := New(testutil.Registry(t, "4.0.1"), "4.0.1")
+	if _, err := c.Convert(map[string]any{"resourceType": "Patient"}, "3.0.2", issue.NewResult()); err == nil {
+		t.Error("Convert() from STU3 succeeded, want an error")
+	}
+	if !Supported("R4B") || Supported("3.0.2") {
+		t.Error("Supported() should accept R4B and reject STU3")
+	}
+}
@@ -0,0 +1,207 @@
+package crossversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// mapping relates an element of R4 to its equivalent in R5. Paths start with
+// the resource type; a last name ending in [x] maps each type of a choice
+// element (e.g., performedPeriod to occurrencePeriod). Several mappings may
+// share a path on one side, such as reasonCode and reasonReference, which
+// both become reason: values go to the first mapping that can convert them.
+// Whether an element repeats is taken from its definition in the target
+// version.
+type mapping struct {
+	r4, r5  string
+	convert *transform // nil = same value, converted element by element
+}
+
+// transform converts a value between the types of the two sides of a
+// mapping. Up converts an R4 value to R5 and down an R5 value to R4; they
+// return false when the value has no equivalent.
+type transform struct {
+	up, down  func(any) (any, bool)
+	primitive bool // Values stay primitives, so their extensions (the _name shadow) carry over
+}
+
+// mappings are the R4/R5 differences of common resources that are more than
+// elements added or removed.
+var mappings = []mapping{
+	// Encounter
+	{r4: "Encounter.status", r5: "Encounter.status", convert: codes(
+		map[string]string{"arrived": "in-progress", "triaged": "in-progress", "onleave": "on-hold", "finished": "completed"},
+		map[string]string{"on-hold": "onleave", "completed": "finished", "discharged": "finished", "discontinued": ""},
+	)},
+	{r4: "Encounter.class", r5: "Encounter.class", convert: wrap("coding", true)},
+	{r4: "Encounter.serviceType", r5: "Encounter.serviceType", convert: wrap("concept", false)},
+	{r4: "Encounter.participant.individual", r5: "Encounter.participant.actor"},
+	{r4: "Encounter.period", r5: "Encounter.actualPeriod"},
+	{r4: "Encounter.reasonCode", r5: "Encounter.reason", convert: chain(wrap("concept", false), wrap("value", true))},
+	{r4: "Encounter.reasonReference", r5: "Encounter.reason", convert: chain(wrap("reference", false), wrap("value", true))},
+	{r4: "Encounter.diagnosis.condition", r5: "Encounter.diagnosis.condition", convert: wrap("reference", false)},
+	{r4: "Encounter.diagnosis.use", r5: "Encounter.diagnosis.use"},
+	{r4: "Encounter.hospitalization", r5: "Encounter.admission"},
+	{r4: "Encounter.location.physicalType", r5: "Encounter.location.form"},
+
+	// MedicationRequest
+	{r4: "MedicationRequest.medicationCodeableConcept", r5: "MedicationRequest.medication", convert: wrap("concept", false)},
+	{r4: "MedicationRequest.medicationReference", r5: "MedicationRequest.medication", convert: wrap("reference", false)},
+	{r4: "MedicationRequest.reportedBoolean", r5: "MedicationRequest.reported"},
+	{r4: "MedicationRequest.reportedReference", r5: "MedicationRequest.informationSource"},
+	{r4: "MedicationRequest.performer", r5: "MedicationRequest.performer"},
+	{r4: "MedicationRequest.reasonCode", r5: "MedicationRequest.reason", convert: wrap("concept", false)},
+	{r4: "MedicationRequest.reasonReference", r5: "MedicationRequest.reason", convert: wrap("reference", false)},
+	{r4: "MedicationRequest.dispenseRequest.performer", r5: "MedicationRequest.dispenseRequest.dispenser"},
+
+	// Procedure
+	{r4: "Procedure.category", r5: "Procedure.category"},
+	{r4: "Procedure.performed[x]", r5: "Procedure.occurrence[x]"},
+	{r4: "Procedure.reasonCode", r5: "Procedure.reason", convert: wrap("concept", false)},
+	{r4: "Procedure.reasonReference", r5: "Procedure.reason", convert: wrap("reference", false)},
+	{r4: "Procedure.complication", r5: "Procedure.complication", convert: wrap("concept", false)},
+	{r4: "Procedure.complicationDetail", r5: "Procedure.complication", convert: wrap("reference", false)},
+	{r4: "Procedure.usedCode", r5: "Procedure.used", convert: wrap("concept", false)},
+	{r4: "Procedure.usedReference", r5: "Procedure.used", convert: wrap("reference", false)},
+
+	// DiagnosticReport
+	{r4: "DiagnosticReport.imagingStudy", r5: "DiagnosticReport.study"},
+
+	// Immunization
+	{r4: "Immunization.manufacturer", r5: "Immunization.manufacturer", convert: wrap("reference", false)},
+	{r4: "Immunization.reportOrigin", r5: "Immunization.informationSource", convert: wrap("concept", false)},
+	{r4: "Immunization.reasonCode", r5: "Immunization.reason", convert: wrap("concept", false)},
+	{r4: "Immunization.reasonReference", r5: "Immunization.reason", convert: wrap("reference", false)},
+	{r4: "Immunization.reaction.detail", r5: "Immunization.reaction.manifestation", convert: wrap("reference", false)},
+	{r4: "Immunization.protocolApplied.doseNumberPositiveInt", r5: "Immunization.protocolApplied.doseNumber", convert: positiveIntString},
+	{r4: "Immunization.protocolApplied.doseNumberString", r5: "Immunization.protocolApplied.doseNumber"},
+	{r4: "Immunization.protocolApplied.seriesDosesPositiveInt", r5: "Immunization.protocolApplied.seriesDoses", convert: positiveIntString},
+	{r4: "Immunization.protocolApplied.seriesDosesString", r5: "Immunization.protocolApplied.seriesDoses"},
+
+	// AllergyIntolerance
+	{r4: "AllergyIntolerance.type", r5: "AllergyIntolerance.type", convert: codeConcept("http://hl7.org/fhir/allergy-intolerance-type")},
+	{r4: "AllergyIntolerance.reaction.manifestation", r5: "AllergyIntolerance.reaction.manifestation", convert: wrap("concept", false)},
+
+	// Coverage
+	{r4: "Coverage.subscriberId", r5: "Coverage.subscriberId", convert: wrap("value", false)},
+	{r4: "Coverage.class.value", r5: "Coverage.class.value", convert: wrap("value", false)},
+}
+
+// wrap converts a value to an object holding it in key (in a one-item array
+// if many), such as a CodeableConcept to the concept of a
+// CodeableReference. Objects with other content have no unwrapped form.
+func wrap(key string, many bool) *transform {
+	return &transform{
+		up: func(v any) (any, bool) {
+			if many {
+				v = []any{v}
+			}
+			return map[string]any{key: v}, true
+		},
+		down: func(v any) (any, bool) {
+			obj, ok := v.(map[string]any)
+			if !ok || len(obj) != 1 {
+				return nil, false
+			}
+			inner, ok := obj[key]
+			if !many || !ok {
+				return inner, ok
+			}
+			items, ok := inner.([]any)
+			if !ok || len(items) != 1 {
+				return nil, false
+			}
+			return items[0], true
+		},
+	}
+}
+
+// chain applies transforms in order upwards, and in reverse downwards.
+func chain(transforms ...*transform) *transform {
+	return &transform{
+		up: func(v any) (any, bool) {
+			for _, t := range transforms {
+				var ok bool
+				if v, ok = t.up(v); !ok {
+					return nil, false
+				}
+			}
+			return v, true
+		},
+		down: func(v any) (any, bool) {
+			for i := len(transforms) - 1; i >= 0; i-- {
+				var ok bool
+				if v, ok = transforms[i].down(v); !ok {
+					return nil, false
+				}
+			}
+			return v, true
+		},
+	}
+}
+
+// codes translates codes whose meaning changed between versions; an empty
+// translation marks a code without equivalent. Other codes are kept.
+func codes(up, down map[string]string) *transform {
+	translate := func(table map[string]string) func(any) (any, bool) {
+		return func(v any) (any, bool) {
+			code, ok := v.(string)
+			if !ok {
+				return v, true
+			}
+			translated, listed := table[code]
+			if !listed {
+				return code, true
+			}
+			return translated, translated != ""
+		}
+	}
+	return &transform{up: translate(up), down: translate(down), primitive: true}
+}
+
+// codeConcept converts a code to a CodeableConcept with one coding of
+// system, and back.
+func codeConcept(system string) *transform {
+	return &transform{
+		up: func(v any) (any, bool) {
+			return map[string]any{"coding": []any{map[string]any{"system": system, "code": v}}}, true
+		},
+		down: func(v any) (any, bool) {
+			concept, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			codings, _ := concept["coding"].([]any)
+			for _, c := range codings {
+				if coding, ok := c.(map[string]any); ok && coding["system"] == system {
+					code, ok := coding["code"].(string)
+					return code, ok
+				}
+			}
+			return nil, false
+		},
+	}
+}
+
+// positiveIntString converts a positiveInt to a string, and back when the
+// string is a positive integer.
+var positiveIntString = &transform{
+	up: func(v any) (any, bool) {
+		switch n := v.(type) {
+		case json.Number:
+			return n.String(), true
+		case float64:
+			return strconv.FormatFloat(n, 'f', -1, 64), true
+		}
+		return fmt.Sprint(v), true
+	},
+	down: func(v any) (any, bool) {
+		s, ok := v.(string)
+		if n, err := strconv.ParseUint(s, 10, 31); !ok || err != nil || n == 0 {
+			return nil, false
+		}
+		return json.Number(s), true
+	},
+	primitive: true,
+}
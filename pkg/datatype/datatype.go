@@ -0,0 +1,172 @@
+// Package datatype checks the semantics of complex datatype values beyond
+// their structure and primitive formats. For Attachment it checks that:
+//
+//   - contentType is a valid MIME type, and one of the allowed types if an
+//     allow-list is set (see SetAllowedContentTypes)
+//   - size matches the length of the decoded data
+//   - hash matches the SHA-1 hash of the decoded data
+//
+// The base64 encoding of data and hash is checked by the primitive phase;
+// values that do not decode are not compared here.
+package datatype
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // Attachment.hash is defined as SHA-1
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+var errMissingSubtype = errors.New("expected a type and a subtype (e.g., text/plain)")
+
+// Validator checks the datatype values of resources.
+type Validator struct {
+	walker       *walker.Walker
+	contentTypes []string // allowed content types; nil = any
+}
+
+// New creates a new datatype Validator.
+func New(reg *registry.Registry) *Validator {
+	return &Validator{walker: walker.New(reg)}
+}
+
+// SetAllowedContentTypes limits the content types of Attachments. Entries are
+// MIME types (e.g., "application/pdf") or wildcards of a top-level type
+// (e.g., "image/*"); other content types are reported as warnings. An empty
+// list allows any content type.
+func (v *Validator) SetAllowedContentTypes(types []string) {
+	v.contentTypes = nil
+	for _, t := range types {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			v.contentTypes = append(v.contentTypes, t)
+		}
+	}
+}
+
+// ValidateData checks every Attachment of a pre-parsed resource, including
+// those of contained and Bundle entry resources.
+func (v *Validator) ValidateData(resource map[string]any, sd *registry.StructureDefinition, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" || sd == nil {
+		return
+	}
+
+	v.validateResource(resource, sd, resourceType, resourceType, result)
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
+		if rc.FHIRPath != resourceType {
+			v.validateResource(rc.Data, rc.SD, rc.ResourceType, rc.FHIRPath, result)
+		}
+		return true
+	})
+}
+
+func (v *Validator) validateResource(data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, result *issue.Result) {
+	v.walker.WalkElements(data, sd, sdPath, fhirPath, func(ec *walker.ElementContext) bool {
+		if ec.Type != "Attachment" {
+			return true
+		}
+		v.validateAttachment(ec.Data, ec.FHIRPath, result)
+		return false
+	})
+}
+
+// validateAttachment checks the contentType, size and hash of an Attachment.
+func (v *Validator) validateAttachment(data map[string]any, fhirPath string, result *issue.Result) {
+	if contentType, ok := data["contentType"].(string); ok {
+		v.validateContentType(contentType, fhirPath+".contentType", result)
+	}
+
+	encoded, ok := data["data"].(string)
+	if !ok {
+		return
+	}
+	content, err := decodeBase64(encoded)
+	if err != nil {
+		return
+	}
+
+	if size, ok := attachmentSize(data["size"]); ok && size != int64(len(content)) {
+		result.AddErrorWithID(
+			issue.DiagAttachmentSizeMismatch,
+			issue.Params{issue.Int("size", int(size)), issue.Int("actual", len(content))},
+			fhirPath+".size",
+		)
+	}
+
+	if hash, ok := data["hash"].(string); ok {
+		want, err := decodeBase64(hash)
+		if err != nil {
+			return
+		}
+		if sum := sha1.Sum(content); !bytes.Equal(want, sum[:]) { //nolint:gosec // see import
+			result.AddErrorWithID(issue.DiagAttachmentHashMismatch, nil, fhirPath+".hash")
+		}
+	}
+}
+
+// validateContentType checks that a content type is a MIME type and, if an
+// allow-list is set, that it is allowed.
+func (v *Validator) validateContentType(contentType, fhirPath string, result *issue.Result) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if typ, subtype, ok := strings.Cut(mediaType, "/"); !ok || typ == "" || subtype == "" {
+			err = errMissingSubtype
+		}
+	}
+	if err != nil {
+		result.AddErrorWithID(
+			issue.DiagAttachmentInvalidContentType,
+			issue.Params{issue.String("contentType", contentType), issue.String("error", err.Error())},
+			fhirPath,
+		)
+		return
+	}
+
+	if len(v.contentTypes) > 0 && !v.allowed(mediaType) {
+		result.AddWarningWithID(
+			issue.DiagAttachmentContentTypeNotAllowed,
+			issue.Params{issue.String("contentType", contentType), issue.String("allowed", strings.Join(v.contentTypes, ", "))},
+			fhirPath,
+		)
+	}
+}
+
+// allowed reports whether a media type matches the allow-list.
+func (v *Validator) allowed(mediaType string) bool {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	for _, t := range v.contentTypes {
+		if t == mediaType || t == "*/*" || t == typ+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentSize returns Attachment.size, an unsignedInt in R4 and an
+// integer64 (a JSON string) in R5.
+func attachmentSize(value any) (int64, bool) {
+	switch s := value.(type) {
+	case json.Number:
+		n, err := s.Int64()
+		return n, err == nil
+	case float64:
+		return int64(s), s == float64(int64(s))
+	case string:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// decodeBase64 decodes a base64Binary value, ignoring whitespace.
+func decodeBase64(value string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+}
@@ -0,0 +1,142 @@
+package datatype
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// helloHash is the base64 SHA-1 hash of "Hello" (SGVsbG8=).
+const helloHash = "9/+ei3uy4Jtwk1pdeF4MxdnQq/A="
+
+// documentReference wraps an Attachment in a DocumentReference.
+func documentReference(attachment map[string]any) map[string]any {
+	return map[string]any{
+		"resourceType": "DocumentReference",
+		"status":       "current",
+		"content":      []any{map[string]any{"attachment": attachment}},
+	}
+}
+
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource map[string]any
+		allowed  []string
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "valid attachment",
+			resource: documentReference(map[string]any{
+				"contentType": "text/plain; charset=UTF-8",
+				"data":        "SGVsbG8=",
+				"size":        json.Number("5"),
+				"hash":        helloHash,
+			}),
+		},
+		{
+			name:     "content type without subtype",
+			resource: documentReference(map[string]any{"contentType": "pdf"}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagAttachmentInvalidContentType},
+			wantPath: "DocumentReference.content[0].attachment.contentType",
+		},
+		{
+			name:     "content type with invalid characters",
+			resource: documentReference(map[string]any{"contentType": "text/plain; charset"}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagAttachmentInvalidContentType},
+		},
+		{
+			name: "size does not match data",
+			resource: documentReference(map[string]any{
+				"data": "SGVsbG8=",
+				"size": json.Number("6"),
+			}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagAttachmentSizeMismatch},
+			wantPath: "DocumentReference.content[0].attachment.size",
+		},
+		{
+			name:     "size parsed as float64",
+			resource: documentReference(map[string]any{"data": "SGVsbG8=", "size": 5.0}),
+		},
+		{
+			name: "hash does not match data",
+			resource: documentReference(map[string]any{
+				"data": "SGVsbG8h",
+				"hash": helloHash,
+			}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagAttachmentHashMismatch},
+			wantPath: "DocumentReference.content[0].attachment.hash",
+		},
+		{
+			name: "invalid base64 left to the primitive phase",
+			resource: documentReference(map[string]any{
+				"data": "SGVsbG8",
+				"size": json.Number("1"),
+				"hash": helloHash,
+			}),
+		},
+		{
+			name: "size without data",
+			resource: documentReference(map[string]any{
+				"url":  "http://example.org/doc.pdf",
+				"size": json.Number("1024"),
+			}),
+		},
+		{
+			name: "attachment in contained resource",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"contained": []any{map[string]any{
+					"resourceType": "Patient",
+					"id":           "p",
+					"photo":        []any{map[string]any{"data": "SGVsbG8=", "size": json.Number("4")}},
+				}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagAttachmentSizeMismatch},
+			wantPath: "Observation.contained[0].photo[0].size",
+		},
+		{
+			name:     "allowed content type",
+			resource: documentReference(map[string]any{"contentType": "application/PDF"}),
+			allowed:  []string{"application/pdf", "image/*"},
+		},
+		{
+			name:     "allowed by wildcard",
+			resource: documentReference(map[string]any{"contentType": "image/png"}),
+			allowed:  []string{"application/pdf", "image/*"},
+		},
+		{
+			name:     "disallowed content type",
+			resource: documentReference(map[string]any{"contentType": "application/x-msdownload"}),
+			allowed:  []string{"application/pdf", "image/*"},
+			wantIDs:  []issue.DiagnosticID{issue.DiagAttachmentContentTypeNotAllowed},
+			wantPath: "DocumentReference.content[0].attachment.contentType",
+		},
+	}
+
+	reg := testutil.Registry(t, "4.0.1")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(reg)
+			v.SetAllowedContentTypes(tt.allowed)
+
+			resourceType, _ := tt.resource["resourceType"].(string)
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, reg.GetByType(resourceType), result)
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", testutil.IssueIDs(result), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
@@ -2,10 +2,15 @@
 package extension
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
+
+	"github.com/gofhir/fhirpath"
+	"github.com/gofhir/fhirpath/types"
 
 	"github.com/gofhir/validator/pkg/issue"
 	"github.com/gofhir/validator/pkg/primitive"
@@ -31,6 +36,17 @@ type Validator struct {
 	walker        *walker.Walker
 	termRegistry  *terminology.Registry
 	primValidator *primitive.Validator
+
+	// exprCache caches compiled contextInvariant expressions by source text.
+	exprCache sync.Map
+
+	// nestedCache caches the sub-extensions of complex extensions by
+	// StructureDefinition (see nestedSlices).
+	nestedCache sync.Map
+
+	// knownModifiers lists the modifierExtension URLs the receiver understands.
+	// Nil disables the check.
+	knownModifiers map[string]bool
 }
 
 // New creates a new extension Validator.
@@ -43,6 +59,30 @@ func New(reg *registry.Registry, termReg *terminology.Registry, primVal *primiti
 	}
 }
 
+// SetKnownModifierExtensions declares the modifierExtension URLs the receiving
+// system understands. Once set (even to an empty list), any modifierExtension
+// outside the list is an error, whether or not its StructureDefinition resolves,
+// because the spec requires receivers to refuse modifiers they don't handle.
+// A nil slice disables the check.
+func (v *Validator) SetKnownModifierExtensions(urls []string) {
+	if urls == nil {
+		v.knownModifiers = nil
+		return
+	}
+	v.knownModifiers = make(map[string]bool, len(urls))
+	for _, u := range urls {
+		v.knownModifiers[u] = true
+	}
+}
+
+// HasExtensions reports whether raw resource JSON may contain extensions or
+// modifierExtensions. It is a cheap byte scan used to skip the extension phase:
+// false positives (e.g., a string value "extension") only cost a normal run,
+// while a false result guarantees there is nothing to validate.
+func HasExtensions(raw []byte) bool {
+	return bytes.Contains(raw, []byte(`"extension"`)) || bytes.Contains(raw, []byte(`"modifierExtension"`))
+}
+
 // Validate validates all extensions in a resource.
 // Deprecated: Use ValidateData for better performance when JSON is already parsed.
 func (v *Validator) Validate(resourceData json.RawMessage, sd *registry.StructureDefinition, result *issue.Result) {
@@ -96,12 +136,12 @@ func (v *Validator) validateElement(data map[string]any, basePath, contextType s
 
 	// Check for extension array - use contextPath for extension validation
 	if extensions, ok := data[keyExtension]; ok {
-		v.validateExtensionArray(extensions, basePath+"."+keyExtension, contextPath, false, result)
+		v.validateExtensionArray(extensions, data, basePath+"."+keyExtension, contextPath, false, result)
 	}
 
 	// Check for modifierExtension array
 	if modifierExts, ok := data["modifierExtension"]; ok {
-		v.validateExtensionArray(modifierExts, basePath+".modifierExtension", contextPath, true, result)
+		v.validateExtensionArray(modifierExts, data, basePath+".modifierExtension", contextPath, true, result)
 	}
 
 	// Recurse into nested elements
@@ -112,7 +152,9 @@ func (v *Validator) validateElement(data map[string]any, basePath, contextType s
 			continue
 		}
 
-		elementPath := fmt.Sprintf("%s.%s", basePath, key)
+		// Extensions of a primitive (in its shadow element, e.g., "_given")
+		// are reported at the path of the value they extend, index for index
+		elementPath := fmt.Sprintf("%s.%s", basePath, strings.TrimPrefix(key, "_"))
 
 		switch val := value.(type) {
 		case map[string]any:
@@ -171,7 +213,8 @@ func (v *Validator) buildExtensionContextPath(basePath, contextType string) stri
 }
 
 // validateExtensionArray validates an array of extensions.
-func (v *Validator) validateExtensionArray(extensions any, basePath, contextPath string, isModifier bool, result *issue.Result) {
+// Container is the element holding the array, used to evaluate contextInvariants.
+func (v *Validator) validateExtensionArray(extensions any, container map[string]any, basePath, contextPath string, isModifier bool, result *issue.Result) {
 	extArray, ok := extensions.([]any)
 	if !ok {
 		return
@@ -184,13 +227,12 @@ func (v *Validator) validateExtensionArray(extensions any, basePath, contextPath
 		}
 
 		extPath := fmt.Sprintf("%s[%d]", basePath, i)
-		v.validateSingleExtension(extMap, extPath, contextPath, isModifier, result)
+		v.validateSingleExtension(extMap, container, extPath, contextPath, isModifier, result)
 	}
 }
 
 // ValidateSingleExtension validates a single extension.
-// The isModifier parameter is reserved for future use to validate modifierExtension-specific rules.
-func (v *Validator) validateSingleExtension(ext map[string]any, extPath, contextPath string, _ bool, result *issue.Result) {
+func (v *Validator) validateSingleExtension(ext, container map[string]any, extPath, contextPath string, isModifier bool, result *issue.Result) {
 	// Get extension URL
 	url, ok := ext["url"].(string)
 	if !ok || url == "" {
@@ -202,13 +244,24 @@ func (v *Validator) validateSingleExtension(ext map[string]any, extPath, context
 		return
 	}
 
+	// Receivers must refuse modifier extensions they don't understand
+	if isModifier && v.knownModifiers != nil && !v.knownModifiers[url] {
+		result.AddErrorWithID(
+			issue.DiagModifierNotUnderstood,
+			issue.Params{
+				issue.String("url", url),
+			},
+			extPath,
+		)
+	}
+
 	// Resolve extension StructureDefinition
 	extSD := v.registry.GetByURL(url)
 	if extSD == nil {
 		result.AddWarningWithID(
 			issue.DiagExtensionUnknown,
-			map[string]any{
-				"url": url,
+			issue.Params{
+				issue.String("url", url),
 			},
 			extPath,
 		)
@@ -219,13 +272,14 @@ func (v *Validator) validateSingleExtension(ext map[string]any, extPath, context
 	// Validate context
 	v.validateContext(extSD, contextPath, extPath, result)
 
+	// Validate context invariants
+	v.validateContextInvariants(extSD, ext, container, extPath, result)
+
 	// Validate value[x]
 	v.validateExtensionValue(ext, extSD, extPath, result)
 
-	// Validate nested extensions
-	if nestedExts, ok := ext[keyExtension]; ok {
-		v.validateNestedExtensions(nestedExts, extSD, extPath, result)
-	}
+	// Validate nested extensions, recursively
+	v.validateNestedExtensions(ext, extSD, rootID, extPath, result)
 }
 
 // validateContext validates that the extension is allowed in the current context.
@@ -247,14 +301,83 @@ func (v *Validator) validateContext(extSD *registry.StructureDefinition, context
 
 	result.AddErrorWithID(
 		issue.DiagExtensionInvalidContext,
-		map[string]any{
-			"url":     extSD.URL,
-			"context": contextPath,
+		issue.Params{
+			issue.String("url", extSD.URL),
+			issue.String("context", contextPath),
 		},
 		extPath,
 	)
 }
 
+// validateContextInvariants evaluates the extension's contextInvariant expressions
+// against the element containing the extension, with %extension bound to the
+// extension itself.
+func (v *Validator) validateContextInvariants(extSD *registry.StructureDefinition, ext, container map[string]any, extPath string, result *issue.Result) {
+	if len(extSD.ContextInvariant) == 0 || container == nil {
+		return
+	}
+
+	containerJSON, err := json.Marshal(container)
+	if err != nil {
+		return
+	}
+	extJSON, err := json.Marshal(ext)
+	if err != nil {
+		return
+	}
+	extCollection, err := types.JSONToCollection(extJSON)
+	if err != nil {
+		return
+	}
+
+	for _, expression := range extSD.ContextInvariant {
+		expr, err := v.getCompiledExpression(expression)
+		if err != nil {
+			result.AddWarningWithID(
+				issue.DiagConstraintCompileError,
+				issue.Params{issue.String("key", extSD.URL), issue.String("error", err.Error())},
+				extPath,
+			)
+			continue
+		}
+
+		evalResult, err := expr.EvaluateWithOptions(containerJSON, fhirpath.WithVariable("extension", extCollection))
+		if err != nil {
+			result.AddWarningWithID(
+				issue.DiagConstraintEvalError,
+				issue.Params{issue.String("key", extSD.URL), issue.String("error", err.Error())},
+				extPath,
+			)
+			continue
+		}
+
+		// Empty means not applicable; a non-boolean result is treated as passing.
+		if evalResult.Empty() {
+			continue
+		}
+		if b, err := evalResult.ToBoolean(); err == nil && !b {
+			result.AddErrorWithID(
+				issue.DiagExtensionContextInvariant,
+				issue.Params{issue.String("url", extSD.URL), issue.String("expression", expression)},
+				extPath,
+			)
+		}
+	}
+}
+
+// getCompiledExpression returns a cached compiled expression or compiles a new one.
+func (v *Validator) getCompiledExpression(expression string) (*fhirpath.Expression, error) {
+	if cached, ok := v.exprCache.Load(expression); ok {
+		return cached.(*fhirpath.Expression), nil
+	}
+	expr, err := fhirpath.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	v.exprCache.Store(expression, expr)
+	return expr, nil
+}
+
 // StripArrayIndices removes array indices from a FHIRPath expression.
 // E.g., "ValueSet.compose.include[1].concept[10]" -> "ValueSet.compose.include.concept".
 func stripArrayIndices(path string) string {
@@ -528,8 +651,12 @@ func (v *Validator) normalizeShadowPath(path string) string {
 
 // validateExtensionValue validates the value[x] of an extension.
 func (v *Validator) validateExtensionValue(ext map[string]any, extSD *registry.StructureDefinition, extPath string, result *issue.Result) {
-	// Find the value[x] element definition
-	valueDef := v.findValueDefinition(extSD)
+	v.validateValue(ext, extSD.URL, v.findValueDefinition(extSD), extPath, result)
+}
+
+// validateValue validates the value[x] of an extension or sub-extension
+// (identified by url in diagnostics) against its definition.
+func (v *Validator) validateValue(ext map[string]any, url string, valueDef *registry.ElementDefinition, extPath string, result *issue.Result) {
 	if valueDef == nil {
 		return
 	}
@@ -540,8 +667,8 @@ func (v *Validator) validateExtensionValue(ext map[string]any, extSD *registry.S
 		if v.hasValue(ext) {
 			result.AddErrorWithID(
 				issue.DiagExtensionValueNotAllowed,
-				map[string]any{
-					"url": extSD.URL,
+				issue.Params{
+					issue.String("url", url),
 				},
 				extPath,
 			)
@@ -554,8 +681,8 @@ func (v *Validator) validateExtensionValue(ext map[string]any, extSD *registry.S
 	if valueDef.Min > 0 && !v.hasValue(ext) && !hasNested {
 		result.AddErrorWithID(
 			issue.DiagExtensionValueRequired,
-			map[string]any{
-				"url": extSD.URL,
+			issue.Params{
+				issue.String("url", url),
 			},
 			extPath,
 		)
@@ -575,10 +702,10 @@ func (v *Validator) validateExtensionValue(ext map[string]any, extSD *registry.S
 	if !v.isTypeAllowed(valueType, valueDef.Type) {
 		result.AddErrorWithID(
 			issue.DiagExtensionInvalidValueType,
-			map[string]any{
-				"url":      extSD.URL,
-				"provided": valueType,
-				"allowed":  v.allowedTypesString(valueDef.Type),
+			issue.Params{
+				issue.String("url", url),
+				issue.String("provided", valueType),
+				issue.String("allowed", v.allowedTypesString(valueDef.Type)),
 			},
 			extPath+"."+valueKey,
 		)
@@ -657,7 +784,7 @@ func (v *Validator) validateValueStructure(value map[string]any, typeSD *registr
 		if !v.isValidChoiceType(key, choiceTypes) {
 			result.AddErrorWithID(
 				issue.DiagStructureUnknownElement,
-				map[string]any{"element": key},
+				issue.Params{issue.String("element", key)},
 				valuePath+"."+key,
 			)
 		}
@@ -824,103 +951,6 @@ func (v *Validator) allowedTypesString(types []registry.Type) string {
 	return strings.Join(names, ", ")
 }
 
-// validateNestedExtensions validates nested extensions against the parent SD.
-func (v *Validator) validateNestedExtensions(nestedExts any, parentSD *registry.StructureDefinition, parentPath string, result *issue.Result) {
-	extArray, ok := nestedExts.([]any)
-	if !ok {
-		return
-	}
-
-	for i, ext := range extArray {
-		extMap, ok := ext.(map[string]any)
-		if !ok {
-			continue
-		}
-
-		extPath := fmt.Sprintf("%s.extension[%d]", parentPath, i)
-		url, _ := extMap["url"].(string)
-
-		// For nested extensions, validate against parent SD's slice definitions
-		nestedDef := v.findNestedExtensionDef(parentSD, url)
-		if nestedDef == nil {
-			// Unknown nested extension
-			result.AddWarningWithID(
-				issue.DiagExtensionNestedUnknown,
-				map[string]any{
-					"url":    url,
-					"parent": parentSD.URL,
-				},
-				extPath,
-			)
-			continue
-		}
-
-		// Validate value type for nested extension
-		v.validateNestedExtensionValue(extMap, nestedDef, parentSD, extPath, result)
-	}
-}
-
-// findNestedExtensionDef finds the ElementDefinition for a nested extension by URL.
-func (v *Validator) findNestedExtensionDef(parentSD *registry.StructureDefinition, url string) *registry.ElementDefinition {
-	if parentSD.Snapshot == nil {
-		return nil
-	}
-
-	// Look for Extension.extension with fixedUri matching the URL
-	for i := range parentSD.Snapshot.Element {
-		elem := &parentSD.Snapshot.Element[i]
-		if elem.Path == "Extension.extension.url" {
-			// Use dynamic GetFixed() to extract fixedUri without hardcoding
-			fixedValue, typeSuffix, hasFixed := elem.GetFixed()
-			if hasFixed && typeSuffix == "Uri" {
-				// Parse the fixed URI value
-				var fixedURI string
-				if err := json.Unmarshal(fixedValue, &fixedURI); err == nil && fixedURI == url {
-					// Found the URL definition, now get the parent extension slice
-					// Look for the corresponding value[x] definition
-					for j := range parentSD.Snapshot.Element {
-						valElem := &parentSD.Snapshot.Element[j]
-						if valElem.Path == "Extension.extension.value[x]" && j > i-3 && j < i+3 {
-							return valElem
-						}
-					}
-				}
-			}
-		}
-	}
-	return nil
-}
-
-// validateNestedExtensionValue validates the value of a nested extension.
-func (v *Validator) validateNestedExtensionValue(ext map[string]any, valueDef *registry.ElementDefinition, parentSD *registry.StructureDefinition, extPath string, result *issue.Result) {
-	valueKey := v.findValueKey(ext)
-	if valueKey == "" {
-		if valueDef.Min > 0 {
-			result.AddErrorWithID(
-				issue.DiagExtensionValueRequired,
-				map[string]any{
-					"url": parentSD.URL,
-				},
-				extPath,
-			)
-		}
-		return
-	}
-
-	valueType := v.extractValueType(valueKey)
-	if !v.isTypeAllowed(valueType, valueDef.Type) {
-		result.AddErrorWithID(
-			issue.DiagExtensionInvalidValueType,
-			map[string]any{
-				"url":      parentSD.URL,
-				"provided": valueType,
-				"allowed":  v.allowedTypesString(valueDef.Type),
-			},
-			extPath+"."+valueKey,
-		)
-	}
-}
-
 // validateExtensionBinding validates the binding on an extension's value[x].
 func (v *Validator) validateExtensionBinding(value any, binding *registry.Binding, valuePath string, result *issue.Result) {
 	if v.termRegistry == nil {
@@ -952,10 +982,10 @@ func (v *Validator) validateCodeBinding(code, system string, binding *registry.B
 	// Check if system is external (requires terminology server)
 	if system != "" && v.termRegistry.IsExternalSystem(system) {
 		result.AddInfoWithID(
-			issue.DiagBindingCannotValidate,
-			map[string]any{
-				"code":   code,
-				"system": system,
+			cannotValidateID(v.termRegistry, system),
+			issue.Params{
+				issue.String("code", code),
+				issue.String("system", system),
 			},
 			fhirPath,
 		)
@@ -967,9 +997,9 @@ func (v *Validator) validateCodeBinding(code, system string, binding *registry.B
 		// ValueSet not found - emit warning
 		result.AddWarningWithID(
 			issue.DiagBindingValueSetNotFound,
-			map[string]any{
-				"valueSet": binding.ValueSet,
-				"code":     code,
+			issue.Params{
+				issue.String("valueSet", binding.ValueSet),
+				issue.String("code", code),
 			},
 			fhirPath,
 		)
@@ -980,18 +1010,18 @@ func (v *Validator) validateCodeBinding(code, system string, binding *registry.B
 		if binding.Strength == "required" {
 			result.AddErrorWithID(
 				issue.DiagBindingRequired,
-				map[string]any{
-					"code":     code,
-					"valueSet": binding.ValueSet,
+				issue.Params{
+					issue.String("code", code),
+					issue.String("valueSet", binding.ValueSet),
 				},
 				fhirPath,
 			)
 		} else if binding.Strength == "extensible" {
 			result.AddWarningWithID(
 				issue.DiagBindingExtensible,
-				map[string]any{
-					"code":     code,
-					"valueSet": binding.ValueSet,
+				issue.Params{
+					issue.String("code", code),
+					issue.String("valueSet", binding.ValueSet),
 				},
 				fhirPath,
 			)
@@ -1041,10 +1071,10 @@ func (v *Validator) validateCodingBinding(coding map[string]any, binding *regist
 	// Check if system is external (requires terminology server)
 	if system != "" && v.termRegistry.IsExternalSystem(system) {
 		result.AddInfoWithID(
-			issue.DiagBindingCannotValidate,
-			map[string]any{
-				"code":   code,
-				"system": system,
+			cannotValidateID(v.termRegistry, system),
+			issue.Params{
+				issue.String("code", code),
+				issue.String("system", system),
 			},
 			fhirPath,
 		)
@@ -1060,9 +1090,9 @@ func (v *Validator) validateCodingBinding(coding map[string]any, binding *regist
 		}
 		result.AddWarningWithID(
 			issue.DiagBindingValueSetNotFound,
-			map[string]any{
-				"valueSet": binding.ValueSet,
-				"code":     codeDisplay,
+			issue.Params{
+				issue.String("valueSet", binding.ValueSet),
+				issue.String("code", codeDisplay),
 			},
 			fhirPath,
 		)
@@ -1078,9 +1108,9 @@ func (v *Validator) validateCodingBinding(coding map[string]any, binding *regist
 		if binding.Strength == "required" {
 			result.AddErrorWithID(
 				issue.DiagBindingRequired,
-				map[string]any{
-					"code":     codeDisplay,
-					"valueSet": binding.ValueSet,
+				issue.Params{
+					issue.String("code", codeDisplay),
+					issue.String("valueSet", binding.ValueSet),
 				},
 				fhirPath,
 			)
@@ -1091,9 +1121,9 @@ func (v *Validator) validateCodingBinding(coding map[string]any, binding *regist
 			if system == "" || v.termRegistry.IsSystemInValueSet(binding.ValueSet, system) {
 				result.AddWarningWithID(
 					issue.DiagBindingExtensible,
-					map[string]any{
-						"code":     codeDisplay,
-						"valueSet": binding.ValueSet,
+					issue.Params{
+						issue.String("code", codeDisplay),
+						issue.String("valueSet", binding.ValueSet),
 					},
 					fhirPath,
 				)
@@ -1102,3 +1132,12 @@ func (v *Validator) validateCodingBinding(coding map[string]any, binding *regist
 		}
 	}
 }
+
+// cannotValidateID returns the diagnostic for a code from an external system:
+// one naming the terminology server when it does not support the system.
+func cannotValidateID(termRegistry *terminology.Registry, system string) issue.DiagnosticID {
+	if termRegistry.IsUnsupportedSystem(system) {
+		return issue.DiagBindingSystemUnsupported
+	}
+	return issue.DiagBindingCannotValidate
+}
@@ -0,0 +1,182 @@
+package extension
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+func TestValidateContextInvariants(t *testing.T) {
+	extSD := &registry.StructureDefinition{
+		URL: "http://example.org/fhir/StructureDefinition/pregnancy-note",
+		ContextInvariant: []string{
+			"gender = 'female'",
+			"%extension.value.exists()",
+		},
+	}
+	ext := map[string]any{
+		"url":         extSD.URL,
+		"valueString": "note",
+	}
+
+	tests := []struct {
+		name       string
+		ext        map[string]any
+		container  map[string]any
+		wantErrors int
+	}{
+		{
+			name:       "invariants hold",
+			ext:        ext,
+			container:  map[string]any{"resourceType": "Patient", "gender": "female"},
+			wantErrors: 0,
+		},
+		{
+			name:       "container invariant fails",
+			ext:        ext,
+			container:  map[string]any{"resourceType": "Patient", "gender": "male"},
+			wantErrors: 1,
+		},
+		{
+			name:       "extension invariant fails",
+			ext:        map[string]any{"url": extSD.URL},
+			container:  map[string]any{"resourceType": "Patient", "gender": "female"},
+			wantErrors: 1,
+		},
+		{
+			name:       "not applicable when element is absent",
+			ext:        ext,
+			container:  map[string]any{"resourceType": "Patient"},
+			wantErrors: 0,
+		},
+	}
+
+	v := New(registry.New(), nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.validateContextInvariants(extSD, tt.ext, tt.container, "Patient.extension[0]", result)
+
+			if got := result.ErrorCount(); got != tt.wantErrors {
+				t.Errorf("ErrorCount() = %d, want %d: %+v", got, tt.wantErrors, result.Issues)
+			}
+			for _, iss := range result.Issues {
+				if iss.Severity == issue.SeverityError && iss.MessageID != string(issue.DiagExtensionContextInvariant) {
+					t.Errorf("MessageID = %q, want %q", iss.MessageID, issue.DiagExtensionContextInvariant)
+				}
+			}
+		})
+	}
+}
+
+func TestKnownModifierExtensions(t *testing.T) {
+	const known = "http://example.org/fhir/StructureDefinition/known-modifier"
+	const other = "http://example.org/fhir/StructureDefinition/other-modifier"
+
+	tests := []struct {
+		name       string
+		known      []string
+		url        string
+		isModifier bool
+		wantError  bool
+	}{
+		{name: "check disabled", known: nil, url: other, isModifier: true, wantError: false},
+		{name: "known modifier", known: []string{known}, url: known, isModifier: true, wantError: false},
+		{name: "unknown modifier", known: []string{known}, url: other, isModifier: true, wantError: true},
+		{name: "empty list rejects all", known: []string{}, url: known, isModifier: true, wantError: true},
+		{name: "regular extension ignored", known: []string{known}, url: other, isModifier: false, wantError: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(registry.New(), nil, nil)
+			v.SetKnownModifierExtensions(tt.known)
+
+			result := issue.NewResult()
+			ext := map[string]any{"url": tt.url, "valueBoolean": true}
+			v.validateSingleExtension(ext, nil, "Patient.modifierExtension[0]", "Patient", tt.isModifier, result)
+
+			gotError := false
+			for _, iss := range result.Issues {
+				if iss.MessageID == string(issue.DiagModifierNotUnderstood) {
+					gotError = true
+				}
+			}
+			if gotError != tt.wantError {
+				t.Errorf("modifier not understood reported = %v, want %v: %+v", gotError, tt.wantError, result.Issues)
+			}
+		})
+	}
+}
+
+func TestValidateNestedExtensions(t *testing.T) {
+	const url = "http://example.org/fhir/StructureDefinition/race"
+	var snapshot registry.Snapshot
+	if err := json.Unmarshal([]byte(`{"element": [
+		{"id": "Extension", "path": "Extension", "min": 0, "max": "*"},
+		{"id": "Extension.extension", "path": "Extension.extension", "min": 0, "max": "*"},
+		{"id": "Extension.extension:category", "path": "Extension.extension", "sliceName": "category", "min": 1, "max": "2"},
+		{"id": "Extension.extension:category.extension", "path": "Extension.extension.extension", "min": 0, "max": "*"},
+		{"id": "Extension.extension:category.extension:source", "path": "Extension.extension.extension", "sliceName": "source", "min": 1, "max": "1"},
+		{"id": "Extension.extension:category.extension:source.url", "path": "Extension.extension.extension.url", "min": 1, "max": "1", "fixedUri": "source"},
+		{"id": "Extension.extension:category.extension:source.value[x]", "path": "Extension.extension.extension.value[x]", "min": 1, "max": "1", "type": [{"code": "uri"}]},
+		{"id": "Extension.extension:category.url", "path": "Extension.extension.url", "min": 1, "max": "1", "fixedUri": "category"},
+		{"id": "Extension.extension:category.value[x]", "path": "Extension.extension.value[x]", "min": 0, "max": "0"},
+		{"id": "Extension.extension:text", "path": "Extension.extension", "sliceName": "text", "min": 1, "max": "1"},
+		{"id": "Extension.extension:text.url", "path": "Extension.extension.url", "min": 1, "max": "1", "fixedUri": "text"},
+		{"id": "Extension.extension:text.value[x]", "path": "Extension.extension.value[x]", "min": 1, "max": "1", "type": [{"code": "string"}]}
+	]}`), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	extSD := &registry.StructureDefinition{URL: url, Snapshot: &snapshot}
+
+	category := func(sources ...map[string]any) map[string]any {
+		nested := make([]any, len(sources))
+		for i, s := range sources {
+			nested[i] = s
+		}
+		return map[string]any{"url": "category", "extension": nested}
+	}
+	source := map[string]any{"url": "source", "valueUri": "urn:x"}
+	text := map[string]any{"url": "text", "valueString": "x"}
+	race := func(nested ...map[string]any) map[string]any {
+		list := make([]any, len(nested))
+		for i, n := range nested {
+			list[i] = n
+		}
+		return map[string]any{"url": url, "extension": list}
+	}
+	tests := []struct {
+		name string
+		ext  map[string]any
+		want []issue.DiagnosticID
+	}{
+		{"complete", race(category(source), text), nil},
+		{"missing text", race(category(source)), []issue.DiagnosticID{issue.DiagExtensionNestedMin}},
+		{"no sub-extensions", map[string]any{"url": url}, []issue.DiagnosticID{issue.DiagExtensionNestedMin, issue.DiagExtensionNestedMin}},
+		{"repeated text", race(category(source), text, text), []issue.DiagnosticID{issue.DiagExtensionNestedMax}},
+		{"unknown sub-extension", race(category(source), text, map[string]any{"url": "other", "valueString": "x"}), []issue.DiagnosticID{issue.DiagExtensionNestedUnknown}},
+		{"wrong value type", race(category(source), map[string]any{"url": "text", "valueBoolean": true}), []issue.DiagnosticID{issue.DiagExtensionInvalidValueType}},
+		{"value on complex sub-extension", race(map[string]any{"url": "category", "valueString": "x", "extension": []any{source}}, text), []issue.DiagnosticID{issue.DiagExtensionValueNotAllowed}},
+		{"missing sub-sub-extension", race(category(), text), []issue.DiagnosticID{issue.DiagExtensionNestedMin}},
+		{"wrong sub-sub-extension value type", race(category(map[string]any{"url": "source", "valueString": "x"}), text), []issue.DiagnosticID{issue.DiagExtensionInvalidValueType}},
+	}
+
+	v := New(registry.New(), nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.validateNestedExtensions(tt.ext, extSD, rootID, "Patient.extension[0]", result)
+			var got []issue.DiagnosticID
+			for _, iss := range result.Issues {
+				got = append(got, issue.DiagnosticID(iss.MessageID))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("issues = %v, want %v: %+v", got, tt.want, result.Issues)
+			}
+		})
+	}
+}
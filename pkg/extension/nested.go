@@ -0,0 +1,170 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// rootID is the element ID of the extension itself in an extension's
+// StructureDefinition.
+const rootID = "Extension"
+
+// nestedSlice is a sub-extension of a complex extension: a slice of
+// Extension.extension, identified by its url.
+type nestedSlice struct {
+	url string
+	min uint32
+	max string
+
+	// id is the element ID of the slice (e.g.,
+	// "Extension.extension:ombCategory"), under which its own value[x] and
+	// sub-extensions are defined.
+	id       string
+	valueDef *registry.ElementDefinition
+
+	// profile is the StructureDefinition of the sub-extension when the
+	// slice refers to another extension instead of defining it inline.
+	profile string
+}
+
+// nestedKey identifies the sub-extension slices below an element of an
+// extension's StructureDefinition.
+type nestedKey struct {
+	sd       *registry.StructureDefinition
+	parentID string
+}
+
+// validateNestedExtensions validates the sub-extensions of an extension
+// against the Extension.extension slices below the element parentID of its
+// StructureDefinition (rootID for the extension itself): their values,
+// their own sub-extensions, recursively, and the number of each.
+func (v *Validator) validateNestedExtensions(ext map[string]any, extSD *registry.StructureDefinition, parentID, extPath string, result *issue.Result) {
+	slices := v.nestedSlices(extSD, parentID)
+	counts := make(map[string]int)
+	nested, _ := ext[keyExtension].([]any)
+	for i, n := range nested {
+		nestedExt, ok := n.(map[string]any)
+		if !ok {
+			continue
+		}
+		nestedPath := fmt.Sprintf("%s.extension[%d]", extPath, i)
+		url, _ := nestedExt["url"].(string)
+		counts[url]++
+
+		slice := findNestedSlice(slices, url)
+		switch {
+		case slice == nil:
+			result.AddWarningWithID(
+				issue.DiagExtensionNestedUnknown,
+				issue.Params{
+					issue.String("url", url),
+					issue.String("parent", extSD.URL),
+				},
+				nestedPath,
+			)
+		case slice.profile != "":
+			if sd := v.registry.GetByURL(slice.profile); sd != nil {
+				v.validateExtensionValue(nestedExt, sd, nestedPath, result)
+				v.validateNestedExtensions(nestedExt, sd, rootID, nestedPath, result)
+			}
+		default:
+			v.validateValue(nestedExt, url, slice.valueDef, nestedPath, result)
+			v.validateNestedExtensions(nestedExt, extSD, slice.id, nestedPath, result)
+		}
+	}
+
+	for _, slice := range slices {
+		count := counts[slice.url]
+		if count < int(slice.min) {
+			result.AddErrorWithID(
+				issue.DiagExtensionNestedMin,
+				issue.Params{issue.String("url", slice.url), issue.String("parent", extSD.URL), issue.Int("min", int(slice.min)), issue.Int("count", count)},
+				extPath,
+			)
+		}
+		if maxCount, err := strconv.Atoi(slice.max); err == nil && count > maxCount {
+			result.AddErrorWithID(
+				issue.DiagExtensionNestedMax,
+				issue.Params{issue.String("url", slice.url), issue.String("parent", extSD.URL), issue.Int("max", maxCount), issue.Int("count", count)},
+				extPath,
+			)
+		}
+	}
+}
+
+// findNestedSlice returns the slice of a sub-extension url, or nil.
+func findNestedSlice(slices []nestedSlice, url string) *nestedSlice {
+	for i := range slices {
+		if slices[i].url == url {
+			return &slices[i]
+		}
+	}
+	return nil
+}
+
+// nestedSlices returns the sub-extension slices an extension's
+// StructureDefinition defines directly below the element parentID.
+func (v *Validator) nestedSlices(extSD *registry.StructureDefinition, parentID string) []nestedSlice {
+	key := nestedKey{sd: extSD, parentID: parentID}
+	if cached, ok := v.nestedCache.Load(key); ok {
+		return cached.([]nestedSlice)
+	}
+
+	var slices []nestedSlice
+	if extSD.Snapshot != nil {
+		elements := make(map[string]*registry.ElementDefinition, len(extSD.Snapshot.Element))
+		for i := range extSD.Snapshot.Element {
+			elements[extSD.Snapshot.Element[i].ID] = &extSD.Snapshot.Element[i]
+		}
+		prefix := parentID + ".extension:"
+		for i := range extSD.Snapshot.Element {
+			elem := &extSD.Snapshot.Element[i]
+			name, ok := strings.CutPrefix(elem.ID, prefix)
+			if !ok || strings.ContainsAny(name, ".:") {
+				continue
+			}
+			slice := nestedSlice{min: elem.Min, max: elem.Max, id: elem.ID, valueDef: elements[elem.ID+".value[x]"]}
+			slice.url = fixedURI(elements[elem.ID+".url"])
+			if profile := extensionProfile(elem); profile != "" && slice.url == "" {
+				slice.url, slice.profile = profile, profile
+			}
+			if slice.url != "" {
+				slices = append(slices, slice)
+			}
+		}
+	}
+	actual, _ := v.nestedCache.LoadOrStore(key, slices)
+	return actual.([]nestedSlice)
+}
+
+// fixedURI returns the fixedUri of an element (e.g., the url of a slice), or
+// "".
+func fixedURI(elem *registry.ElementDefinition) string {
+	if elem == nil {
+		return ""
+	}
+	fixed, _, ok := elem.GetFixed()
+	if !ok {
+		return ""
+	}
+	var uri string
+	if json.Unmarshal(fixed, &uri) != nil {
+		return ""
+	}
+	return uri
+}
+
+// extensionProfile returns the URL of the extension a slice's type is
+// profiled to, without its version, or "".
+func extensionProfile(elem *registry.ElementDefinition) string {
+	if len(elem.Type) != 1 || len(elem.Type[0].Profile) != 1 {
+		return ""
+	}
+	url, _, _ := strings.Cut(elem.Type[0].Profile[0], "|")
+	return url
+}
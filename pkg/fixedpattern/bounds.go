@@ -0,0 +1,235 @@
+package fixedpattern
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// bounds holds the minValue[x]/maxValue[x] of an ElementDefinition.
+type bounds struct {
+	min, max         any // decoded bound values; nil when absent
+	minType, maxType string
+}
+
+// getBounds returns the cached bounds of an ElementDefinition, or nil if it has none.
+func (v *Validator) getBounds(ed *registry.ElementDefinition) *bounds {
+	if cached, ok := v.bounds.Load(ed); ok {
+		b, _ := cached.(*bounds)
+		return b
+	}
+
+	var b *bounds
+	minRaw, minType, hasMin := ed.GetMinValue()
+	maxRaw, maxType, hasMax := ed.GetMaxValue()
+	if hasMin || hasMax {
+		b = &bounds{minType: minType, maxType: maxType}
+		if hasMin {
+			b.min = decodeBound(minRaw)
+		}
+		if hasMax {
+			b.max = decodeBound(maxRaw)
+		}
+	}
+	v.bounds.Store(ed, b)
+	return b
+}
+
+// decodeBound decodes a bound value, keeping numbers exact.
+func decodeBound(raw json.RawMessage) any {
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.UseNumber()
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return nil
+	}
+	return val
+}
+
+// validateBounds checks a value against the element's minValue[x]/maxValue[x].
+// Values that cannot be compared with a bound (a different type, a Quantity in
+// other units, or dates at a precision too coarse to decide) are not reported.
+func (v *Validator) validateBounds(ed *registry.ElementDefinition, value any, path string, result *issue.Result) {
+	b := v.getBounds(ed)
+	if b == nil {
+		return
+	}
+
+	if b.min != nil {
+		if cmp, ok := compareBound(value, b.min, b.minType); ok && cmp < 0 {
+			result.AddErrorWithID(
+				issue.DiagValueBelowMin,
+				issue.Params{issue.String("value", formatBound(value)), issue.String("min", formatBound(b.min)), issue.String("type", b.minType)},
+				path,
+			)
+		}
+	}
+	if b.max != nil {
+		if cmp, ok := compareBound(value, b.max, b.maxType); ok && cmp > 0 {
+			result.AddErrorWithID(
+				issue.DiagValueAboveMax,
+				issue.Params{issue.String("value", formatBound(value)), issue.String("max", formatBound(b.max)), issue.String("type", b.maxType)},
+				path,
+			)
+		}
+	}
+}
+
+// compareBound compares a value with a bound of the given minValue/maxValue
+// type suffix. It returns -1, 0 or 1, and false if the two are not comparable.
+func compareBound(value, bound any, boundType string) (int, bool) {
+	switch boundType {
+	case "Integer", "Decimal", "PositiveInt", "UnsignedInt", "Integer64":
+		return compareNumbers(value, bound)
+	case "Date", "DateTime", "Instant":
+		vs, ok1 := value.(string)
+		bs, ok2 := bound.(string)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		return compareTemporal(vs, bs)
+	case "Time":
+		vs, ok1 := value.(string)
+		bs, ok2 := bound.(string)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		return compareTimes(vs, bs)
+	case "Quantity":
+		return compareQuantities(value, bound)
+	}
+	return 0, false
+}
+
+// toFloat converts a JSON number (float64, json.Number, or integer64 string) to float64.
+func toFloat(val any) (float64, bool) {
+	switch n := val.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// compareNumbers compares two numeric values.
+func compareNumbers(value, bound any) (int, bool) {
+	vf, ok1 := toFloat(value)
+	bf, ok2 := toFloat(bound)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	switch {
+	case vf < bf:
+		return -1, true
+	case vf > bf:
+		return 1, true
+	}
+	return 0, true
+}
+
+// compareQuantities compares the values of two Quantities. A bound without a
+// code applies to any unit; otherwise system and code must match.
+func compareQuantities(value, bound any) (int, bool) {
+	vq, ok1 := value.(map[string]any)
+	bq, ok2 := bound.(map[string]any)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	if code, _ := bq["code"].(string); code != "" {
+		valueCode, _ := vq["code"].(string)
+		valueSystem, _ := vq["system"].(string)
+		boundSystem, _ := bq["system"].(string)
+		if valueCode != code || (boundSystem != "" && valueSystem != boundSystem) {
+			return 0, false
+		}
+	}
+	return compareNumbers(vq["value"], bq["value"])
+}
+
+// dateTimeLayouts are the layouts of dateTime/instant values with a time part.
+var dateTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05"}
+
+// compareTemporal compares date, dateTime and instant values. Values with a
+// time part are compared as instants; otherwise the date parts are compared
+// at the shared precision, and values equal at that precision are only
+// comparable when both have the same precision (e.g., "2020" vs "2020-05" is
+// indeterminate).
+func compareTemporal(value, bound string) (int, bool) {
+	if strings.Contains(value, "T") && strings.Contains(bound, "T") {
+		vt, ok1 := parseDateTime(value)
+		bt, ok2 := parseDateTime(bound)
+		if ok1 && ok2 {
+			return vt.Compare(bt), true
+		}
+	}
+
+	vd, bd := datePart(value), datePart(bound)
+	n := min(len(vd), len(bd))
+	if cmp := strings.Compare(vd[:n], bd[:n]); cmp != 0 {
+		return cmp, true
+	}
+	if len(vd) != len(bd) || vd != value || bd != bound {
+		return 0, false
+	}
+	return 0, true
+}
+
+// parseDateTime parses a dateTime with a time part.
+func parseDateTime(s string) (time.Time, bool) {
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// datePart returns the date portion (YYYY, YYYY-MM or YYYY-MM-DD) of a value.
+func datePart(s string) string {
+	if idx := strings.IndexByte(s, 'T'); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
+
+// compareTimes compares two time values (hh:mm:ss with optional fraction).
+func compareTimes(value, bound string) (int, bool) {
+	const layout = "15:04:05.999999999"
+	vt, err1 := time.Parse(layout, value)
+	bt, err2 := time.Parse(layout, bound)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return vt.Compare(bt), true
+}
+
+// formatBound formats a value or bound for messages.
+func formatBound(val any) string {
+	switch x := val.(type) {
+	case map[string]any:
+		s, _ := toFloat(x["value"])
+		out := strconv.FormatFloat(s, 'f', -1, 64)
+		if code, ok := x["code"].(string); ok {
+			out += " " + code
+		} else if unit, ok := x["unit"].(string); ok {
+			out += " " + unit
+		}
+		return out
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case json.Number:
+		return x.String()
+	case string:
+		return x
+	}
+	return ""
+}
@@ -1,8 +1,12 @@
 package fixedpattern
 
 import (
+	"bytes"
 	"encoding/json"
+	"math/big"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 // DeepEqual compares two JSON values for exact equality.
@@ -15,11 +19,12 @@ func DeepEqual(actual, expected json.RawMessage) bool {
 		return false
 	}
 
-	var a, e any
-	if err := json.Unmarshal(actual, &a); err != nil {
+	a, err := decode(actual)
+	if err != nil {
 		return false
 	}
-	if err := json.Unmarshal(expected, &e); err != nil {
+	e, err := decode(expected)
+	if err != nil {
 		return false
 	}
 
@@ -39,11 +44,12 @@ func ContainsPattern(actual, pattern json.RawMessage) bool {
 		return false // Pattern exists but actual is nil
 	}
 
-	var a, p any
-	if err := json.Unmarshal(actual, &a); err != nil {
+	a, err := decode(actual)
+	if err != nil {
 		return false
 	}
-	if err := json.Unmarshal(pattern, &p); err != nil {
+	p, err := decode(pattern)
+	if err != nil {
 		return false
 	}
 
@@ -96,8 +102,25 @@ func matchRecursive(actual, pattern any) bool {
 	}
 }
 
+// decode parses a JSON value keeping numbers as json.Number, so decimals are
+// compared as written rather than as float64.
+func decode(data json.RawMessage) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// decimal is a JSON number compared by value and precision: 0.0100 and 0.01
+// are different decimals, while 100 and 1e2 are the same.
+type decimal struct {
+	value string // exact rational value
+	scale int    // digits after the decimal point
+}
+
 // normalizeJSON normalizes JSON values for comparison.
-// Converts all numbers to float64 (JSON standard) and ensures consistent types.
+// Converts all numbers to decimals and ensures consistent types.
 func normalizeJSON(v any) any {
 	switch val := v.(type) {
 	case map[string]any:
@@ -112,15 +135,33 @@ func normalizeJSON(v any) any {
 			result[i] = normalizeJSON(v)
 		}
 		return result
-	case float64:
-		// JSON numbers are always float64 after unmarshaling
-		return val
-	case int:
-		// Convert to float64 for consistency
-		return float64(val)
-	case int64:
-		return float64(val)
+	case json.Number:
+		return toDecimal(val)
 	default:
 		return val
 	}
 }
+
+// toDecimal converts a JSON number literal to a decimal. Literals that do
+// not parse are kept as they are, so they only equal the same literal.
+func toDecimal(n json.Number) any {
+	r, ok := new(big.Rat).SetString(n.String())
+	if !ok {
+		return n
+	}
+	return decimal{value: r.RatString(), scale: decimalScale(n.String())}
+}
+
+// decimalScale returns the number of digits after the decimal point a number
+// literal is written with, taking its exponent into account.
+func decimalScale(literal string) int {
+	mantissa, exp, _ := strings.Cut(strings.ToLower(literal), "e")
+	scale := 0
+	if _, frac, ok := strings.Cut(mantissa, "."); ok {
+		scale = len(frac)
+	}
+	if e, err := strconv.Atoi(exp); err == nil {
+		scale -= e
+	}
+	return max(scale, 0)
+}
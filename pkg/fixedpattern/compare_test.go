@@ -37,6 +37,30 @@ func TestDeepEqual(t *testing.T) {
 			expected: `43`,
 			want:     false,
 		},
+		{
+			name:     "decimals with different precision",
+			actual:   `0.0100`,
+			expected: `0.01`,
+			want:     false,
+		},
+		{
+			name:     "decimals with same precision",
+			actual:   `0.0100`,
+			expected: `0.0100`,
+			want:     true,
+		},
+		{
+			name:     "decimal written with exponent",
+			actual:   `1.5e2`,
+			expected: `150`,
+			want:     true,
+		},
+		{
+			name:     "decimals beyond float64 precision",
+			actual:   `0.12345678901234567890`,
+			expected: `0.12345678901234567891`,
+			want:     false,
+		},
 		{
 			name:     "equal booleans",
 			actual:   `true`,
@@ -173,6 +197,18 @@ func TestContainsPattern(t *testing.T) {
 			pattern: `"world"`,
 			want:    false,
 		},
+		{
+			name:    "decimal with different precision",
+			actual:  `{"value": 2.50, "code": "mg"}`,
+			pattern: `{"value": 2.5}`,
+			want:    false,
+		},
+		{
+			name:    "decimal with same precision",
+			actual:  `{"value": 2.50, "code": "mg"}`,
+			pattern: `{"value": 2.50}`,
+			want:    true,
+		},
 
 		// Objects - pattern matching (partial)
 		{
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/gofhir/validator/pkg/issue"
 	"github.com/gofhir/validator/pkg/registry"
@@ -14,6 +15,8 @@ import (
 // Validator validates fixed[x] and pattern[x] constraints.
 type Validator struct {
 	registry *registry.Registry
+	indexes  sync.Map // SD URL|version -> *elementIndex
+	bounds   sync.Map // *ElementDefinition -> *bounds (nil when unbounded)
 }
 
 // New creates a new fixed/pattern validator.
@@ -50,26 +53,84 @@ func (v *Validator) ValidateData(resource map[string]any, sd *registry.Structure
 		return
 	}
 
-	// Build an index of ElementDefinitions by path for quick lookup
-	// For sliced elements (like Bundle.entry:Solicitud.request.method), multiple elements
-	// share the same path. We prioritize base elements (without slice in ID) over slices,
-	// since the slice-specific constraints are validated by the slicing validator.
-	elemIndex := make(map[string]*registry.ElementDefinition)
+	// Validate all elements recursively
+	v.validateElement(resource, resourceType, resourceType, resourceType, v.getIndex(sd), result)
+
+	// Validate contained resources
+	v.validateContained(resource, resourceType, result)
+}
+
+// elementIndex indexes the ElementDefinitions of a snapshot by path.
+type elementIndex struct {
+	// byPath maps element paths to definitions. Choice type slices
+	// (e.g., "Observation.value[x]:valueQuantity.system") are also indexed under
+	// their renamed path ("Observation.valueQuantity.system").
+	byPath map[string]*registry.ElementDefinition
+	// parents holds the paths that have child elements in the snapshot.
+	parents map[string]bool
+}
+
+// getIndex returns the cached element index for a StructureDefinition.
+func (v *Validator) getIndex(sd *registry.StructureDefinition) *elementIndex {
+	if sd.URL == "" {
+		return buildIndex(sd)
+	}
+	key := sd.URL + "|" + sd.Version
+	if cached, ok := v.indexes.Load(key); ok {
+		return cached.(*elementIndex)
+	}
+	idx := buildIndex(sd)
+	v.indexes.Store(key, idx)
+	return idx
+}
+
+// buildIndex builds the element index for a snapshot.
+// For sliced elements (like Bundle.entry:Solicitud.request.method), multiple elements
+// share the same path. Slice-specific constraints are validated by the slicing
+// validator, so only base elements and choice type slices are indexed.
+func buildIndex(sd *registry.StructureDefinition) *elementIndex {
+	idx := &elementIndex{
+		byPath:  make(map[string]*registry.ElementDefinition),
+		parents: make(map[string]bool),
+	}
 	for i := range sd.Snapshot.Element {
 		elem := &sd.Snapshot.Element[i]
-		// Skip slice-specific elements - identified by ":" in their ID
-		// e.g., "Bundle.entry:Solicitud.request.method" is a slice-specific element
+		path := elem.Path
 		if elem.ID != "" && strings.Contains(elem.ID, ":") {
-			continue
+			renamed, ok := typeSlicePath(elem.ID)
+			if !ok {
+				continue
+			}
+			path = renamed
+		}
+		idx.byPath[path] = elem
+		if dot := strings.LastIndex(path, "."); dot > 0 {
+			idx.parents[path[:dot]] = true
+		}
+		if dot := strings.LastIndex(elem.Path, "."); dot > 0 {
+			idx.parents[elem.Path[:dot]] = true
 		}
-		elemIndex[elem.Path] = elem
 	}
+	return idx
+}
 
-	// Validate all elements recursively
-	v.validateElement(resource, resourceType, resourceType, elemIndex, result)
-
-	// Validate contained resources
-	v.validateContained(resource, resourceType, result)
+// typeSlicePath returns the renamed path of an element ID whose only slices are
+// choice type slices, e.g. "Observation.value[x]:valueQuantity.system" becomes
+// "Observation.valueQuantity.system".
+func typeSlicePath(id string) (string, bool) {
+	segments := strings.Split(id, ".")
+	for i, seg := range segments {
+		name, slice, found := strings.Cut(seg, ":")
+		if !found {
+			continue
+		}
+		base, isChoice := strings.CutSuffix(name, "[x]")
+		if !isChoice || len(slice) <= len(base) || !strings.HasPrefix(slice, base) {
+			return "", false
+		}
+		segments[i] = slice
+	}
+	return strings.Join(segments, "."), true
 }
 
 // validateContained validates fixed/pattern in contained resources.
@@ -103,24 +164,20 @@ func (v *Validator) validateContained(resource map[string]any, baseFhirPath stri
 
 		containedFhirPath := fmt.Sprintf("%s.contained[%d]", baseFhirPath, i)
 
-		// Build element index for contained resource
-		elemIndex := make(map[string]*registry.ElementDefinition)
-		for j := range containedSD.Snapshot.Element {
-			elem := &containedSD.Snapshot.Element[j]
-			elemIndex[elem.Path] = elem
-		}
-
 		// Validate contained resource
-		v.validateElement(resourceMap, resourceType, containedFhirPath, elemIndex, result)
+		v.validateElement(resourceMap, resourceType, resourceType, containedFhirPath, v.getIndex(containedSD), result)
 	}
 }
 
 // validateElement recursively validates fixed/pattern constraints.
+// sdPath is the path built from the JSON property names (e.g., "Observation.valueQuantity"),
+// basePath the matching snapshot path (e.g., "Observation.value[x]").
 func (v *Validator) validateElement(
 	data map[string]any,
 	sdPath string,
+	basePath string,
 	fhirPath string,
-	elemIndex map[string]*registry.ElementDefinition,
+	idx *elementIndex,
 	result *issue.Result,
 ) {
 	for key, value := range data {
@@ -132,8 +189,11 @@ func (v *Validator) validateElement(
 		elementSDPath := sdPath + "." + key
 		elementFHIRPath := fhirPath + "." + key
 
-		// Check for choice types (value[x])
-		ed := v.resolveElementDef(elementSDPath, key, elemIndex)
+		// Prefer the renamed choice type slice, then the base path (handles value[x])
+		ed := idx.byPath[elementSDPath]
+		if ed == nil {
+			ed = v.resolveElementDef(basePath+"."+key, key, idx.byPath)
+		}
 		if ed == nil {
 			continue // Element not found, structural validator handles this
 		}
@@ -144,12 +204,13 @@ func (v *Validator) validateElement(
 		// Recurse into children
 		switch val := value.(type) {
 		case map[string]any:
-			v.validateElement(val, elementSDPath, elementFHIRPath, elemIndex, result)
+			v.validateChildren(val, ed, key, elementSDPath, elementFHIRPath, idx, result)
 		case []any:
 			for i, item := range val {
 				itemPath := fmt.Sprintf("%s[%d]", elementFHIRPath, i)
 				if itemMap, ok := item.(map[string]any); ok {
-					v.validateElement(itemMap, elementSDPath, itemPath, elemIndex, result)
+					v.validateBounds(ed, itemMap, itemPath, result)
+					v.validateChildren(itemMap, ed, key, elementSDPath, itemPath, idx, result)
 				} else {
 					// For primitive arrays, validate each item against fixed/pattern
 					v.validateFixedPatternValue(ed, item, itemPath, result)
@@ -159,6 +220,53 @@ func (v *Validator) validateElement(
 	}
 }
 
+// validateChildren validates the children of a complex value. When the snapshot
+// does not constrain the children but the element's type is profiled (e.g., a
+// Coding profile), the children are validated against that profile instead.
+func (v *Validator) validateChildren(
+	data map[string]any,
+	ed *registry.ElementDefinition,
+	key string,
+	sdPath string,
+	fhirPath string,
+	idx *elementIndex,
+	result *issue.Result,
+) {
+	if idx.parents[sdPath] || idx.parents[ed.Path] {
+		v.validateElement(data, sdPath, ed.Path, fhirPath, idx, result)
+		return
+	}
+
+	profileSD := v.typeProfile(ed, key)
+	if profileSD == nil {
+		return
+	}
+	profileIdx := v.getIndex(profileSD)
+	if root := profileIdx.byPath[profileSD.Type]; root != nil {
+		v.validateFixedPattern(root, data, fhirPath, result)
+	}
+	v.validateElement(data, profileSD.Type, profileSD.Type, fhirPath, profileIdx, result)
+}
+
+// typeProfile returns the StructureDefinition of the profile declared on the
+// element's type. For choice elements the type is selected by the property
+// name suffix (e.g., "valueQuantity" selects Quantity).
+func (v *Validator) typeProfile(ed *registry.ElementDefinition, key string) *registry.StructureDefinition {
+	for _, t := range ed.Type {
+		if len(t.Profile) == 0 || t.Code == "" {
+			continue
+		}
+		if len(ed.Type) > 1 && !strings.HasSuffix(key, strings.ToUpper(t.Code[:1])+t.Code[1:]) {
+			continue
+		}
+		sd := v.registry.GetByURL(t.Profile[0])
+		if sd != nil && sd.Snapshot != nil {
+			return sd
+		}
+	}
+	return nil
+}
+
 // resolveElementDef finds the ElementDefinition for a path, handling choice types.
 func (v *Validator) resolveElementDef(path, key string, elemIndex map[string]*registry.ElementDefinition) *registry.ElementDefinition {
 	// Try exact match first
@@ -168,11 +276,14 @@ func (v *Validator) resolveElementDef(path, key string, elemIndex map[string]*re
 
 	// Try choice type pattern (e.g., "value[x]" for "valueString")
 	basePath := path[:len(path)-len(key)-1] // Remove ".key" suffix
+	if ed := elemIndex[basePath+"."+choiceBaseName(key)+"[x]"]; ed != nil {
+		return ed
+	}
 	for candidatePath, ed := range elemIndex {
 		if strings.HasPrefix(candidatePath, basePath+".") && strings.HasSuffix(candidatePath, "[x]") {
 			// Extract the base name from the choice type (e.g., "value" from "Patient.value[x]")
 			choiceBase := candidatePath[len(basePath)+1 : len(candidatePath)-3]
-			if strings.HasPrefix(strings.ToLower(key), strings.ToLower(choiceBase)) {
+			if !strings.Contains(choiceBase, ".") && strings.HasPrefix(key, choiceBase) {
 				return ed
 			}
 		}
@@ -181,8 +292,23 @@ func (v *Validator) resolveElementDef(path, key string, elemIndex map[string]*re
 	return nil
 }
 
-// validateFixedPattern validates fixed/pattern constraints for a value.
+// choiceBaseName returns the name before the first upper-case letter of a
+// choice property (e.g., "value" for "valueCodeableConcept").
+func choiceBaseName(key string) string {
+	for i, r := range key {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			return key[:i]
+		}
+	}
+	return key
+}
+
+// validateFixedPattern validates fixed/pattern constraints and minValue/maxValue bounds for a value.
 func (v *Validator) validateFixedPattern(ed *registry.ElementDefinition, value any, path string, result *issue.Result) {
+	if _, isArray := value.([]any); !isArray {
+		v.validateBounds(ed, value, path, result)
+	}
+
 	// Convert value to JSON for comparison
 	valueJSON, err := json.Marshal(value)
 	if err != nil {
@@ -197,6 +323,8 @@ func (v *Validator) validateFixedPattern(ed *registry.ElementDefinition, value a
 
 // validateFixedPatternValue validates a single primitive value.
 func (v *Validator) validateFixedPatternValue(ed *registry.ElementDefinition, value any, path string, result *issue.Result) {
+	v.validateBounds(ed, value, path, result)
+
 	valueJSON, err := json.Marshal(value)
 	if err != nil {
 		return
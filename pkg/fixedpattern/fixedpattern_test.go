@@ -0,0 +1,342 @@
+package fixedpattern
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// Test profiles: an Observation profile whose code is a profiled CodeableConcept
+// whose coding is itself profiled, and a profile with a pattern on value[x].
+var testProfiles = [][]byte{
+	[]byte(`{
+		"resourceType": "StructureDefinition",
+		"url": "http://example.org/StructureDefinition/loinc-coding",
+		"name": "LoincCoding", "kind": "complex-type", "abstract": false,
+		"type": "Coding", "derivation": "constraint",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Coding",
+		"snapshot": {"element": [
+			{"id": "Coding", "path": "Coding"},
+			{"id": "Coding.system", "path": "Coding.system", "type": [{"code": "uri"}], "fixedUri": "http://loinc.org"},
+			{"id": "Coding.code", "path": "Coding.code", "type": [{"code": "code"}]}
+		]}
+	}`),
+	[]byte(`{
+		"resourceType": "StructureDefinition",
+		"url": "http://example.org/StructureDefinition/loinc-concept",
+		"name": "LoincConcept", "kind": "complex-type", "abstract": false,
+		"type": "CodeableConcept", "derivation": "constraint",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/CodeableConcept",
+		"snapshot": {"element": [
+			{"id": "CodeableConcept", "path": "CodeableConcept"},
+			{"id": "CodeableConcept.coding", "path": "CodeableConcept.coding",
+			 "type": [{"code": "Coding", "profile": ["http://example.org/StructureDefinition/loinc-coding"]}]},
+			{"id": "CodeableConcept.text", "path": "CodeableConcept.text", "type": [{"code": "string"}]}
+		]}
+	}`),
+	[]byte(`{
+		"resourceType": "StructureDefinition",
+		"url": "http://example.org/StructureDefinition/loinc-observation",
+		"name": "LoincObservation", "kind": "resource", "abstract": false,
+		"type": "Observation", "derivation": "constraint",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Observation",
+		"snapshot": {"element": [
+			{"id": "Observation", "path": "Observation"},
+			{"id": "Observation.status", "path": "Observation.status", "type": [{"code": "code"}]},
+			{"id": "Observation.code", "path": "Observation.code",
+			 "type": [{"code": "CodeableConcept", "profile": ["http://example.org/StructureDefinition/loinc-concept"]}]},
+			{"id": "Observation.value[x]", "path": "Observation.value[x]",
+			 "type": [{"code": "Quantity"}, {"code": "CodeableConcept"}],
+			 "patternCodeableConcept": {"coding": [{"system": "http://snomed.info/sct", "code": "260385009"}]}}
+		]}
+	}`),
+	[]byte(`{
+		"resourceType": "StructureDefinition",
+		"url": "http://example.org/StructureDefinition/bounded-observation",
+		"name": "BoundedObservation", "kind": "resource", "abstract": false,
+		"type": "Observation", "derivation": "constraint",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Observation",
+		"snapshot": {"element": [
+			{"id": "Observation", "path": "Observation"},
+			{"id": "Observation.effective[x]", "path": "Observation.effective[x]",
+			 "type": [{"code": "dateTime"}], "minValueDateTime": "2000-01-01"},
+			{"id": "Observation.value[x]", "path": "Observation.value[x]", "type": [{"code": "Quantity"}]},
+			{"id": "Observation.value[x]:valueQuantity", "path": "Observation.value[x]", "sliceName": "valueQuantity",
+			 "type": [{"code": "Quantity"}],
+			 "minValueQuantity": {"value": 0, "system": "http://unitsofmeasure.org", "code": "kg"},
+			 "maxValueQuantity": {"value": 500, "system": "http://unitsofmeasure.org", "code": "kg"}},
+			{"id": "Observation.component", "path": "Observation.component"},
+			{"id": "Observation.component.value[x]", "path": "Observation.component.value[x]",
+			 "type": [{"code": "integer"}], "minValueInteger": 1, "maxValueInteger": 10}
+		]}
+	}`),
+}
+
+var (
+	testRegistry     *registry.Registry
+	testRegistryErr  error
+	testRegistryOnce sync.Once
+)
+
+// getTestRegistry loads the embedded R4 packages and the test profiles once for all tests.
+func getTestRegistry(t *testing.T) *registry.Registry {
+	t.Helper()
+	testRegistryOnce.Do(func() {
+		packages := testutil.Packages(t, "4.0.1")
+		profiles, err := loader.NewLoader("").LoadFromResources(testProfiles)
+		if err != nil {
+			testRegistryErr = err
+			return
+		}
+		testRegistry = registry.New()
+		testRegistryErr = testRegistry.LoadFromPackages(append(packages, profiles))
+	})
+	if testRegistryErr != nil {
+		t.Fatalf("Failed to load registry: %v", testRegistryErr)
+	}
+	return testRegistry
+}
+
+func TestValidateDataNested(t *testing.T) {
+	vitalSignsCategory := []any{map[string]any{"coding": []any{map[string]any{
+		"system": "http://terminology.hl7.org/CodeSystem/observation-category", "code": "vital-signs",
+	}}}}
+
+	tests := []struct {
+		name      string
+		profile   string
+		resource  map[string]any
+		wantPaths []string
+	}{
+		{
+			name:    "bodyweight valueQuantity matches type slice",
+			profile: "http://hl7.org/fhir/StructureDefinition/bodyweight",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"status":       "final",
+				"category":     vitalSignsCategory,
+				"valueQuantity": map[string]any{
+					"value": 70.0, "unit": "kg", "system": "http://unitsofmeasure.org", "code": "kg",
+				},
+			},
+		},
+		{
+			name:    "bodyweight valueQuantity system violates type slice",
+			profile: "http://hl7.org/fhir/StructureDefinition/bodyweight",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"status":       "final",
+				"category":     vitalSignsCategory,
+				"valueQuantity": map[string]any{
+					"value": 154.0, "unit": "lb", "system": "http://example.org/units", "code": "lb",
+				},
+			},
+			wantPaths: []string{"Observation.valueQuantity.system"},
+		},
+		{
+			name:    "pattern on value[x] satisfied",
+			profile: "http://example.org/StructureDefinition/loinc-observation",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"valueCodeableConcept": map[string]any{
+					"coding": []any{map[string]any{"system": "http://snomed.info/sct", "code": "260385009", "display": "Negative"}},
+				},
+			},
+		},
+		{
+			name:    "pattern on value[x] violated",
+			profile: "http://example.org/StructureDefinition/loinc-observation",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"valueCodeableConcept": map[string]any{
+					"coding": []any{map[string]any{"system": "http://snomed.info/sct", "code": "10828004"}},
+				},
+			},
+			wantPaths: []string{"Observation.valueCodeableConcept"},
+		},
+		{
+			name:    "nested type profiles satisfied",
+			profile: "http://example.org/StructureDefinition/loinc-observation",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"code":         map[string]any{"coding": []any{map[string]any{"system": "http://loinc.org", "code": "29463-7"}}},
+			},
+		},
+		{
+			name:    "nested type profiles violated",
+			profile: "http://example.org/StructureDefinition/loinc-observation",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"code": map[string]any{"coding": []any{
+					map[string]any{"system": "http://loinc.org", "code": "29463-7"},
+					map[string]any{"system": "http://snomed.info/sct", "code": "27113001"},
+				}},
+			},
+			wantPaths: []string{"Observation.code.coding[1].system"},
+		},
+	}
+
+	reg := getTestRegistry(t)
+	v := New(reg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sd := reg.GetByURL(tt.profile)
+			if sd == nil {
+				t.Fatalf("profile %s not found", tt.profile)
+			}
+
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, sd, result)
+
+			if len(result.Issues) != len(tt.wantPaths) {
+				t.Fatalf("got %d issues, want %d: %v", len(result.Issues), len(tt.wantPaths), result.Issues)
+			}
+			for i, want := range tt.wantPaths {
+				if got := result.Issues[i].Expression[0]; got != want {
+					t.Errorf("issue[%d] path = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDataBounds(t *testing.T) {
+	kg := func(value float64) map[string]any {
+		return map[string]any{"value": value, "system": "http://unitsofmeasure.org", "code": "kg"}
+	}
+
+	tests := []struct {
+		name     string
+		resource map[string]any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "within bounds",
+			resource: map[string]any{
+				"resourceType":      "Observation",
+				"effectiveDateTime": "2021-03-04T10:00:00Z",
+				"valueQuantity":     kg(72.5),
+				"component":         []any{map[string]any{"valueInteger": 10.0}},
+			},
+		},
+		{
+			name:     "quantity above max",
+			resource: map[string]any{"resourceType": "Observation", "valueQuantity": kg(501)},
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueAboveMax},
+			wantPath: "Observation.valueQuantity",
+		},
+		{
+			name:     "quantity below min",
+			resource: map[string]any{"resourceType": "Observation", "valueQuantity": kg(-1)},
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueBelowMin},
+			wantPath: "Observation.valueQuantity",
+		},
+		{
+			name: "quantity in other units not compared",
+			resource: map[string]any{
+				"resourceType":  "Observation",
+				"valueQuantity": map[string]any{"value": 1200.0, "system": "http://unitsofmeasure.org", "code": "[lb_av]"},
+			},
+		},
+		{
+			name:     "dateTime before min",
+			resource: map[string]any{"resourceType": "Observation", "effectiveDateTime": "1999-12-31"},
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueBelowMin},
+			wantPath: "Observation.effectiveDateTime",
+		},
+		{
+			name: "integer in repeating backbone above max",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"component":    []any{map[string]any{"valueInteger": 3.0}, map[string]any{"valueInteger": 11.0}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagValueAboveMax},
+			wantPath: "Observation.component[1].valueInteger",
+		},
+	}
+
+	reg := getTestRegistry(t)
+	v := New(reg)
+	sd := reg.GetByURL("http://example.org/StructureDefinition/bounded-observation")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, sd, result)
+
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("got %d issues, want %d: %v", len(result.Issues), len(tt.wantIDs), result.Issues)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %q, want %q", result.Issues[0].Expression[0], tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestCompareBound(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     any
+		bound     any
+		boundType string
+		want      int
+		wantOK    bool
+	}{
+		{"integer below", 1.0, json.Number("2"), "Integer", -1, true},
+		{"decimal equal", 2.5, json.Number("2.50"), "Decimal", 0, true},
+		{"integer64 string", "9007199254740993", json.Number("1"), "Integer64", 1, true},
+		{"string vs integer", "abc", json.Number("1"), "Integer", 0, false},
+		{"date before", "2019-12-31", "2020-01-01", "Date", -1, true},
+		{"year vs day same year", "2020", "2020-06-01", "Date", 0, false},
+		{"year decides", "2019", "2020-06-01", "Date", -1, true},
+		{"dateTime with zones", "2020-01-01T01:00:00+02:00", "2020-01-01T00:00:00Z", "DateTime", -1, true},
+		{"dateTime vs date same day", "2020-01-01T10:00:00Z", "2020-01-01", "DateTime", 0, false},
+		{"time after", "13:30:00", "12:00:00", "Time", 1, true},
+		{"quantity no bound code", map[string]any{"value": 5.0, "code": "mg"}, map[string]any{"value": json.Number("10")}, "Quantity", -1, true},
+		{"quantity other system", map[string]any{"value": 5.0, "system": "x", "code": "kg"}, map[string]any{"value": json.Number("1"), "system": "http://unitsofmeasure.org", "code": "kg"}, "Quantity", 0, false},
+		{"unsupported type", "a", "b", "String", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := compareBound(tt.value, tt.bound, tt.boundType)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("compareBound() = %d, %v; want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTypeSlicePath(t *testing.T) {
+	tests := []struct {
+		id     string
+		want   string
+		wantOK bool
+	}{
+		{"Observation.value[x]:valueQuantity", "Observation.valueQuantity", true},
+		{"Observation.value[x]:valueQuantity.system", "Observation.valueQuantity.system", true},
+		{"Observation.category:VSCat.coding.code", "", false},
+		{"Observation.component:SystolicBP.value[x].code", "", false},
+		{"Observation.component.value[x]:valueQuantity.code", "Observation.component.valueQuantity.code", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, ok := typeSlicePath(tt.id)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("typeSlicePath(%q) = %q, %v; want %q, %v", tt.id, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+// Package generate builds FHIR instances from StructureDefinitions: a minimal
+// instance with only the elements a profile requires, or an example that also
+// populates its mustSupport elements.
+//
+// Generated instances carry the profile's fixed and pattern values, one entry
+// for each required slice (so discriminator values are filled in), codes from
+// required bindings, and placeholder values that satisfy the primitive type
+// regexes. Elements are written in StructureDefinition order.
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/canonical"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// Mode selects which optional elements are generated.
+type Mode int
+
+const (
+	Minimal Mode = iota // Only elements with min > 0 and required slices
+	Example             // Also mustSupport elements and slices
+)
+
+// maxDepth bounds recursion through nested datatypes.
+const maxDepth = 12
+
+// CodeSource supplies codes for required and extensible bindings.
+// *terminology.Registry implements it.
+type CodeSource interface {
+	ExampleCode(valueSetURL string) (system, code string, ok bool)
+}
+
+// Generator builds instances from the StructureDefinitions in a registry.
+type Generator struct {
+	registry *registry.Registry
+	codes    CodeSource
+}
+
+// New creates a Generator. Codes may be nil, in which case coded elements
+// with required bindings get placeholder codes.
+func New(reg *registry.Registry, codes CodeSource) *Generator {
+	return &Generator{registry: reg, codes: codes}
+}
+
+// Generate returns an instance of the StructureDefinition with the given
+// canonical URL as compact JSON. Instances of resource profiles declare the
+// profile in meta.profile.
+func (g *Generator) Generate(profileURL string, mode Mode) ([]byte, error) {
+	sd := g.registry.GetByURL(profileURL)
+	if sd == nil {
+		return nil, fmt.Errorf("profile %s not found", profileURL)
+	}
+	if sd.Snapshot == nil || len(sd.Snapshot.Element) == 0 {
+		return nil, fmt.Errorf("profile %s has no snapshot", profileURL)
+	}
+
+	b := &builder{Generator: g, mode: mode}
+	obj := &canonical.Object{}
+	if sd.Kind == registry.KindResource {
+		obj.Members = append(obj.Members, canonical.Member{Name: "resourceType", Value: sd.Type})
+		if sd.Derivation == "constraint" {
+			b.metaPath, b.profile = sd.Type+".meta", profileURL
+		}
+	}
+	b.fill(obj, sd, sd.Snapshot.Element[0].ElementID(), 0)
+	return canonical.Marshal(obj)
+}
+
+// builder holds the state of one Generate call.
+type builder struct {
+	*Generator
+	mode     Mode
+	metaPath string // Root meta element that receives meta.profile
+	profile  string
+}
+
+// wanted reports whether an element is generated in the current mode.
+func (b *builder) wanted(elem *registry.ElementDefinition) bool {
+	return elem.Max != "0" && (elem.Min > 0 || (b.mode == Example && elem.MustSupport))
+}
+
+// fill adds the wanted children of the element with parentID in sd to obj.
+func (b *builder) fill(obj *canonical.Object, sd *registry.StructureDefinition, parentID string, depth int) {
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		name, ok := registry.ChildName(elem.ElementID(), parentID)
+		if !ok || elem.Max == "0" || hasMember(obj, name) {
+			continue
+		}
+		slices := sd.SlicesOf(elem.ElementID())
+
+		if base, isChoice := strings.CutSuffix(name, "[x]"); isChoice {
+			if name, value := b.choice(sd, elem, base, slices, depth); value != nil {
+				obj.Members = append(obj.Members, canonical.Member{Name: name, Value: value})
+			}
+			continue
+		}
+
+		if depth == 0 && elem.Path == b.metaPath {
+			obj.Members = append(obj.Members, canonical.Member{Name: name, Value: b.meta(sd, elem, depth)})
+			continue
+		}
+
+		var items []any
+		for _, slice := range slices {
+			if !b.wanted(slice) {
+				continue
+			}
+			for n := 0; n < max(1, int(slice.Min)); n++ {
+				if value := b.value(sd, slice, typeCode(slice), depth); value != nil {
+					items = append(items, value)
+				}
+			}
+		}
+		if len(items) < int(elem.Min) || (len(items) == 0 && b.wanted(elem)) {
+			for n := len(items); n < max(1, int(elem.Min)); n++ {
+				if value := b.value(sd, elem, typeCode(elem), depth); value != nil {
+					items = append(items, value)
+				}
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		if b.registry.Repeats(elem) {
+			obj.Members = append(obj.Members, canonical.Member{Name: name, Value: items})
+		} else {
+			obj.Members = append(obj.Members, canonical.Member{Name: name, Value: items[0]})
+		}
+	}
+}
+
+// choice generates a choice element, using its first wanted type slice (or
+// first type slice, or first type) to pick the type. It returns the JSON
+// property name, e.g. "valueQuantity", and the value.
+func (b *builder) choice(sd *registry.StructureDefinition, elem *registry.ElementDefinition, base string, slices []*registry.ElementDefinition, depth int) (string, any) {
+	source, code := elem, typeCode(elem)
+	for _, slice := range slices {
+		if b.wanted(slice) {
+			source, code = slice, typeCode(slice)
+			break
+		}
+	}
+	if source == elem && !b.wanted(elem) {
+		return "", nil
+	}
+	if _, suffix, ok := source.GetFixed(); ok {
+		code = suffix
+	} else if _, suffix, ok := source.GetPattern(); ok {
+		code = suffix
+	}
+	if code == "" {
+		return "", nil
+	}
+	return base + upperFirst(code), b.value(sd, source, code, depth)
+}
+
+// meta generates the root meta element with the profile in meta.profile.
+func (b *builder) meta(sd *registry.StructureDefinition, elem *registry.ElementDefinition, depth int) any {
+	meta, ok := b.value(sd, elem, typeCode(elem), depth).(*canonical.Object)
+	if !ok {
+		meta = &canonical.Object{}
+	}
+	if !hasMember(meta, "profile") {
+		meta.Members = append(meta.Members, canonical.Member{Name: "profile", Value: []any{b.profile}})
+	}
+	return meta
+}
+
+// value generates the value of one occurrence of elem with the given type,
+// or nil if none can be generated.
+func (b *builder) value(sd *registry.StructureDefinition, elem *registry.ElementDefinition, code string, depth int) any {
+	if depth > maxDepth {
+		return nil
+	}
+	if raw, _, ok := elem.GetFixed(); ok {
+		if v, err := canonical.Parse(raw); err == nil {
+			return v
+		}
+	}
+	var obj *canonical.Object
+	if raw, _, ok := elem.GetPattern(); ok {
+		v, err := canonical.Parse(raw)
+		if err != nil {
+			return nil
+		}
+		if obj, ok = v.(*canonical.Object); !ok {
+			return v
+		}
+	}
+
+	if b.registry.IsPrimitiveType(code) {
+		return b.primitive(elem, code)
+	}
+	if obj == nil {
+		obj = &canonical.Object{}
+	}
+
+	id := elem.ElementID()
+	var typeSD *registry.StructureDefinition
+	switch {
+	case elem.ContentReference != nil && *elem.ContentReference != "":
+		b.fill(obj, sd, strings.TrimPrefix(*elem.ContentReference, "#"), depth+1)
+	case sd.HasChildren(id):
+		b.fill(obj, sd, id, depth+1)
+	default:
+		if typeSD = b.typeDefinition(elem, code); typeSD == nil {
+			return nil
+		}
+		b.fill(obj, typeSD, typeSD.Snapshot.Element[0].ElementID(), depth+1)
+	}
+
+	// Nothing is required inside: use a bound code, or a placeholder child so
+	// the element is not empty
+	if len(obj.Members) == 0 {
+		if coded := b.coded(elem, code); coded != nil {
+			return coded
+		}
+		if typeSD == nil {
+			typeSD = b.typeDefinition(elem, code)
+		}
+		if typeSD != nil {
+			b.placeholder(obj, typeSD, elem, code)
+		}
+	}
+	if len(obj.Members) == 0 {
+		return nil
+	}
+	return obj
+}
+
+// coded generates a Coding or CodeableConcept for a required or extensible
+// binding from the CodeSource, or returns nil.
+func (b *builder) coded(elem *registry.ElementDefinition, code string) any {
+	if b.codes == nil || elem.Binding == nil || elem.Binding.ValueSet == "" ||
+		(elem.Binding.Strength != "required" && elem.Binding.Strength != "extensible") {
+		return nil
+	}
+	if code != "Coding" && code != "CodeableConcept" {
+		return nil
+	}
+	system, value, ok := b.codes.ExampleCode(elem.Binding.ValueSet)
+	if !ok {
+		return nil
+	}
+	coding := &canonical.Object{Members: []canonical.Member{
+		{Name: "system", Value: system},
+		{Name: "code", Value: value},
+	}}
+	if code == "Coding" {
+		return coding
+	}
+	return &canonical.Object{Members: []canonical.Member{{Name: "coding", Value: []any{coding}}}}
+}
+
+// placeholder fills a complex value that has no required children, so that
+// it is not empty: a Reference gets a reference to its target type, other
+// types their first preferred primitive child.
+func (b *builder) placeholder(obj *canonical.Object, typeSD *registry.StructureDefinition, elem *registry.ElementDefinition, code string) {
+	if code == "Reference" {
+		target := "Patient"
+		for _, t := range elem.Type {
+			if t.Code == code && len(t.TargetProfile) > 0 {
+				if targetSD := b.registry.GetByURL(t.TargetProfile[0]); targetSD != nil && !strings.HasSuffix(targetSD.URL, "/Resource") {
+					target = targetSD.Type
+				}
+			}
+		}
+		obj.Members = append(obj.Members, canonical.Member{Name: "reference", Value: target + "/example"})
+		return
+	}
+
+	rootID := typeSD.Snapshot.Element[0].ElementID()
+	var fallback *registry.ElementDefinition
+	for _, preferred := range []string{"value", "text", "display", "code", ""} {
+		for i := range typeSD.Snapshot.Element {
+			child := &typeSD.Snapshot.Element[i]
+			name, ok := registry.ChildName(child.ElementID(), rootID)
+			if !ok || name == "id" || child.Max == "0" || !b.registry.IsPrimitiveType(typeCode(child)) {
+				continue
+			}
+			if name == preferred || (preferred == "" && fallback == nil) {
+				fallback = child
+				break
+			}
+		}
+		if fallback != nil {
+			break
+		}
+	}
+	if fallback == nil {
+		return
+	}
+	name, _ := registry.ChildName(fallback.ElementID(), rootID)
+	if value := b.primitive(fallback, typeCode(fallback)); value != nil {
+		obj.Members = append(obj.Members, canonical.Member{Name: name, Value: value})
+	}
+}
+
+// typeDefinition returns the StructureDefinition of an element's type: its
+// type profile if it declares one, or else the base type. Abstract types are
+// not generated.
+func (b *builder) typeDefinition(elem *registry.ElementDefinition, code string) *registry.StructureDefinition {
+	if code == "" {
+		return nil
+	}
+	var typeSD *registry.StructureDefinition
+	for _, t := range elem.Type {
+		if t.Code == code && len(t.Profile) > 0 {
+			typeSD = b.registry.GetByURL(t.Profile[0])
+			break
+		}
+	}
+	if typeSD == nil {
+		typeSD = b.registry.GetByType(code)
+	}
+	if typeSD == nil || typeSD.Abstract || typeSD.Snapshot == nil || len(typeSD.Snapshot.Element) == 0 {
+		return nil
+	}
+	return typeSD
+}
+
+// typeCode returns the element's first type.
+func typeCode(elem *registry.ElementDefinition) string {
+	if len(elem.Type) == 0 {
+		return ""
+	}
+	return elem.Type[0].Code
+}
+
+func hasMember(obj *canonical.Object, name string) bool {
+	_, ok := obj.Get(name)
+	return ok
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,154 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+var (
+	sharedValidator     *validator.Validator
+	errSharedValidator  error
+	sharedValidatorOnce sync.Once
+)
+
+func newTestGenerator(t *testing.T) (*Generator, *validator.Validator) {
+	t.Helper()
+	sharedValidatorOnce.Do(func() {
+		sharedValidator, errSharedValidator = validator.New()
+	})
+	if errSharedValidator != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", errSharedValidator)
+	}
+	return New(sharedValidator.Registry(), sharedValidator.Terminology()), sharedValidator
+}
+
+func TestGenerateValidates(t *testing.T) {
+	g, v := newTestGenerator(t)
+
+	profiles := []string{
+		"http://hl7.org/fhir/StructureDefinition/Patient",
+		"http://hl7.org/fhir/StructureDefinition/Observation",
+		"http://hl7.org/fhir/StructureDefinition/bodyweight",
+		"http://hl7.org/fhir/StructureDefinition/Questionnaire",
+		"http://hl7.org/fhir/StructureDefinition/MedicationRequest",
+	}
+	for _, profile := range profiles {
+		for _, mode := range []Mode{Minimal, Example} {
+			data, err := g.Generate(profile, mode)
+			if err != nil {
+				t.Fatalf("Generate(%s) error = %v", profile, err)
+			}
+			result, err := v.Validate(context.Background(), data)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			for _, iss := range result.Issues {
+				// Invariants are not evaluated while generating
+				if iss.MessageID == string(issue.DiagConstraintFailed) {
+					continue
+				}
+				if iss.Severity == issue.SeverityError || iss.Severity == issue.SeverityFatal {
+					t.Errorf("%s (mode %d): %s at %v\n%s", profile, mode, iss.Diagnostics, iss.Expression, data)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateSlices(t *testing.T) {
+	g, _ := newTestGenerator(t)
+	data, err := g.Generate("http://hl7.org/fhir/StructureDefinition/bodyweight", Minimal)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var obs struct {
+		ResourceType string `json:"resourceType"`
+		Meta         struct {
+			Profile []string `json:"profile"`
+		} `json:"meta"`
+		Category []struct {
+			Coding []struct {
+				System string `json:"system"`
+				Code   string `json:"code"`
+			} `json:"coding"`
+		} `json:"category"`
+		Code struct {
+			Coding []struct {
+				System string `json:"system"`
+				Code   string `json:"code"`
+			} `json:"coding"`
+		} `json:"code"`
+		ValueQuantity *struct {
+			System string `json:"system"`
+		} `json:"valueQuantity"`
+	}
+	if err := json.Unmarshal(data, &obs); err != nil {
+		t.Fatalf("generated invalid JSON: %v\n%s", err, data)
+	}
+	if obs.ResourceType != "Observation" || len(obs.Meta.Profile) != 1 {
+		t.Errorf("resourceType = %q, meta.profile = %v", obs.ResourceType, obs.Meta.Profile)
+	}
+	if len(obs.Category) != 1 || len(obs.Category[0].Coding) == 0 || obs.Category[0].Coding[0].Code != "vital-signs" {
+		t.Errorf("category slice not filled from its pattern: %+v", obs.Category)
+	}
+	if len(obs.Code.Coding) == 0 || obs.Code.Coding[0].Code != "29463-7" || obs.Code.Coding[0].System != "http://loinc.org" {
+		t.Errorf("code.coding slice not filled from its fixed values: %+v", obs.Code.Coding)
+	}
+	if obs.ValueQuantity != nil {
+		t.Errorf("minimal instance has optional valueQuantity")
+	}
+
+	data, err = g.Generate("http://hl7.org/fhir/StructureDefinition/bodyweight", Example)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	obs.ValueQuantity = nil
+	if err := json.Unmarshal(data, &obs); err != nil || obs.ValueQuantity == nil || obs.ValueQuantity.System != "http://unitsofmeasure.org" {
+		t.Errorf("example instance has no mustSupport valueQuantity: %s", data)
+	}
+
+	if _, err := g.Generate("http://example.org/missing", Minimal); err == nil {
+		t.Error("Generate(unknown profile) should fail")
+	}
+}
+
+func TestPrimitivePlaceholders(t *testing.T) {
+	g, _ := newTestGenerator(t)
+	for code, value := range primitives {
+		sd := g.registry.GetByType(code)
+		if sd == nil || sd.Snapshot == nil {
+			continue
+		}
+		for _, elem := range sd.Snapshot.Element {
+			if elem.Path != code+".value" {
+				continue
+			}
+			for _, typ := range elem.Type {
+				for _, ext := range typ.Extension {
+					if ext.URL != "http://hl7.org/fhir/StructureDefinition/regex" {
+						continue
+					}
+					re := regexp.MustCompile("^(?:" + ext.ValueString + ")$")
+					text, err := json.Marshal(value)
+					if err != nil {
+						t.Fatal(err)
+					}
+					var s string
+					if json.Unmarshal(text, &s) != nil {
+						s = string(text)
+					}
+					if !re.MatchString(s) {
+						t.Errorf("%s placeholder %q does not match %s", code, s, ext.ValueString)
+					}
+				}
+			}
+		}
+	}
+}
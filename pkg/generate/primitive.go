@@ -0,0 +1,54 @@
+package generate
+
+import (
+	"encoding/json"
+
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// primitives holds a placeholder value for each FHIR primitive type that
+// satisfies the type's regex.
+var primitives = map[string]any{
+	"base64Binary": "ZXhhbXBsZQ==",
+	"boolean":      true,
+	"canonical":    "http://example.org/fhir/example",
+	"code":         "example",
+	"date":         "2024-01-01",
+	"dateTime":     "2024-01-01T12:00:00Z",
+	"decimal":      json.Number("1.0"),
+	"id":           "example",
+	"instant":      "2024-01-01T12:00:00.000Z",
+	"integer":      json.Number("1"),
+	"integer64":    "1",
+	"markdown":     "example",
+	"oid":          "urn:oid:1.2.3.4",
+	"positiveInt":  json.Number("1"),
+	"string":       "example",
+	"time":         "12:00:00",
+	"unsignedInt":  json.Number("0"),
+	"uri":          "http://example.org/fhir/example",
+	"url":          "http://example.org/fhir/example",
+	"uuid":         "urn:uuid:a4fd4a9e-38a4-4f47-9d66-6b5c5e4a5d1f",
+	"xhtml":        `<div xmlns="http://www.w3.org/1999/xhtml">example</div>`,
+}
+
+// primitive returns a placeholder value for a primitive element: a code from
+// a required or extensible binding for code elements, or else the type's
+// placeholder, truncated to maxLength.
+func (b *builder) primitive(elem *registry.ElementDefinition, code string) any {
+	if code == "code" && b.codes != nil && elem.Binding != nil && elem.Binding.ValueSet != "" &&
+		(elem.Binding.Strength == "required" || elem.Binding.Strength == "extensible") {
+		if _, value, ok := b.codes.ExampleCode(elem.Binding.ValueSet); ok {
+			return value
+		}
+	}
+
+	value, ok := primitives[code]
+	if !ok {
+		value = "example"
+	}
+	if s, isString := value.(string); isString && elem.MaxLength > 0 && len(s) > elem.MaxLength {
+		return s[:elem.MaxLength]
+	}
+	return value
+}
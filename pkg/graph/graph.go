@@ -0,0 +1,502 @@
+// Package graph validates a set of resources, such as the entries of a Bundle,
+// against a GraphDefinition: starting from the resources of the graph's start
+// type, each link must lead to resources of the allowed types and profiles,
+// in the number the link's cardinality allows.
+//
+// Both the R4/R4B form of GraphDefinition (nested link.target) and the R5
+// form (node and link with sourceId/targetId) are supported. Links with a
+// path follow the references the FHIRPath expression selects. Links without a
+// path whose target has search params (reverse links, such as Observations
+// whose subject is the Patient) match the resources of the target type that
+// reference the source resource; the params themselves are not evaluated.
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofhir/fhirpath"
+	"github.com/gofhir/fhirpath/types"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Definition is a parsed GraphDefinition.
+type Definition struct {
+	// URL is the canonical URL of the GraphDefinition.
+	URL string
+	// Name is the computer-friendly name of the GraphDefinition.
+	Name string
+
+	start *node
+}
+
+// Start returns the resource type the graph starts from.
+func (d *Definition) Start() string {
+	return d.start.resourceType
+}
+
+// node is a resource in the graph: the start, or a link target.
+type node struct {
+	resourceType string
+	profile      string
+	links        []*link
+}
+
+// link is a relationship from a node to its targets.
+type link struct {
+	path    string
+	params  string // Search for reverse links
+	min     int
+	max     string // "*" or a number
+	targets []*node
+}
+
+// label describes the link in diagnostics.
+func (l *link) label() string {
+	if l.path != "" {
+		return l.path
+	}
+	return strings.ReplaceAll(l.targetTypes(), ", ", "|") + "?" + l.params
+}
+
+// graphDefinition covers the R4/R4B and R5 forms of GraphDefinition.
+type graphDefinition struct {
+	ResourceType string      `json:"resourceType"`
+	URL          string      `json:"url"`
+	Name         string      `json:"name"`
+	Start        string      `json:"start"`
+	Profile      string      `json:"profile"` // R4/R4B
+	Link         []graphLink `json:"link"`
+	Node         []graphNode `json:"node"` // R5
+}
+
+type graphLink struct {
+	Path     string        `json:"path"`
+	Min      *int          `json:"min"`
+	Max      string        `json:"max"`
+	Target   []graphTarget `json:"target"` // R4/R4B
+	SourceID string        `json:"sourceId"`
+	TargetID string        `json:"targetId"`
+	Params   string        `json:"params"`
+}
+
+type graphTarget struct {
+	Type    string      `json:"type"`
+	Params  string      `json:"params"`
+	Profile string      `json:"profile"`
+	Link    []graphLink `json:"link"`
+}
+
+type graphNode struct {
+	NodeID  string `json:"nodeId"`
+	Type    string `json:"type"`
+	Profile string `json:"profile"`
+}
+
+// Parse parses a GraphDefinition resource.
+func Parse(data []byte) (*Definition, error) {
+	var gd graphDefinition
+	if err := json.Unmarshal(data, &gd); err != nil {
+		return nil, fmt.Errorf("invalid GraphDefinition: %w", err)
+	}
+	if gd.ResourceType != "GraphDefinition" {
+		return nil, fmt.Errorf("invalid GraphDefinition: resourceType is %q", gd.ResourceType)
+	}
+	if gd.Start == "" {
+		return nil, errors.New("invalid GraphDefinition: start is missing")
+	}
+
+	def := &Definition{URL: gd.URL, Name: gd.Name}
+	if len(gd.Node) > 0 {
+		start, err := parseNodes(gd)
+		if err != nil {
+			return nil, err
+		}
+		def.start = start
+		return def, nil
+	}
+	def.start = &node{resourceType: gd.Start, profile: gd.Profile, links: parseLinks(gd.Link)}
+	return def, nil
+}
+
+// parseLinks converts R4/R4B links with nested targets.
+func parseLinks(links []graphLink) []*link {
+	out := make([]*link, 0, len(links))
+	for _, gl := range links {
+		l := newLink(gl)
+		for _, t := range gl.Target {
+			l.targets = append(l.targets, &node{resourceType: t.Type, profile: t.Profile, links: parseLinks(t.Link)})
+			if l.params == "" {
+				l.params = t.Params
+			}
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// parseNodes converts the R5 node list and links between node ids.
+func parseNodes(gd graphDefinition) (*node, error) {
+	nodes := make(map[string]*node, len(gd.Node))
+	for _, n := range gd.Node {
+		nodes[n.NodeID] = &node{resourceType: n.Type, profile: n.Profile}
+	}
+	for i, gl := range gd.Link {
+		source, target := nodes[gl.SourceID], nodes[gl.TargetID]
+		if source == nil || target == nil {
+			return nil, fmt.Errorf("invalid GraphDefinition: link %d connects unknown nodes %q and %q", i, gl.SourceID, gl.TargetID)
+		}
+		l := newLink(gl)
+		l.targets = []*node{target}
+		source.links = append(source.links, l)
+	}
+	start := nodes[gd.Start]
+	if start == nil {
+		return nil, fmt.Errorf("invalid GraphDefinition: start node %q is not defined", gd.Start)
+	}
+	return start, nil
+}
+
+func newLink(gl graphLink) *link {
+	l := &link{path: gl.Path, params: gl.Params, max: gl.Max}
+	if gl.Min != nil {
+		l.min = *gl.Min
+	}
+	if l.max == "" {
+		l.max = "*"
+	}
+	return l
+}
+
+// Resource is a member of the set validated against a graph.
+type Resource struct {
+	// Data is the parsed resource.
+	Data map[string]any
+	// Raw is the resource JSON, for FHIRPath evaluation.
+	Raw []byte
+	// FullURL is the Bundle entry fullUrl, if any.
+	FullURL string
+	// Path locates the resource in issues (e.g., "Bundle.entry[2].resource").
+	Path string
+}
+
+// key returns "Type/id", or "" without an id.
+func (r *Resource) key() string {
+	resourceType, _ := r.Data["resourceType"].(string)
+	id, _ := r.Data["id"].(string)
+	if resourceType == "" || id == "" {
+		return ""
+	}
+	return resourceType + "/" + id
+}
+
+// name describes the resource in diagnostics.
+func (r *Resource) name() string {
+	if key := r.key(); key != "" {
+		return key
+	}
+	return r.Path
+}
+
+func (r *Resource) resourceType() string {
+	resourceType, _ := r.Data["resourceType"].(string)
+	return resourceType
+}
+
+// ProfileChecker reports whether a resource conforms to a profile. An error
+// means the conformance could not be determined.
+type ProfileChecker func(ctx context.Context, resource []byte, profile string) (bool, error)
+
+// Validator validates resource sets against a GraphDefinition.
+type Validator struct {
+	def      *Definition
+	profiles ProfileChecker
+
+	exprMu    sync.Mutex
+	exprCache map[string]*fhirpath.Expression
+}
+
+// New creates a Validator for def. Profiles of the graph's nodes are checked
+// with profiles; if it is nil, they are ignored.
+func New(def *Definition, profiles ProfileChecker) *Validator {
+	return &Validator{def: def, profiles: profiles, exprCache: make(map[string]*fhirpath.Expression)}
+}
+
+// visit is a resource reached at a node of the graph.
+type visit struct {
+	resource int
+	node     *node
+}
+
+// run holds the state of one validation.
+type run struct {
+	v         *Validator
+	ctx       context.Context
+	resources []Resource
+	result    *issue.Result
+	visited   map[visit]bool
+	conforms  map[string]bool // "index|profile"
+}
+
+// Validate validates resources against the graph, starting from each
+// resource of the start type. It returns ctx's error if ctx ends.
+func (v *Validator) Validate(ctx context.Context, resources []Resource, result *issue.Result) error {
+	r := &run{
+		v:         v,
+		ctx:       ctx,
+		resources: resources,
+		result:    result,
+		visited:   make(map[visit]bool),
+		conforms:  make(map[string]bool),
+	}
+
+	started := false
+	for i := range resources {
+		if resources[i].resourceType() != v.def.start.resourceType {
+			continue
+		}
+		started = true
+		r.checkProfile(i, v.def.start.profile, "start")
+		r.walk(i, v.def.start)
+	}
+	if !started {
+		result.AddErrorWithID(
+			issue.DiagGraphStartNotFound,
+			issue.Params{issue.String("type", v.def.start.resourceType), issue.String("graph", v.def.name())},
+			v.def.start.resourceType,
+		)
+	}
+	return ctx.Err()
+}
+
+// name describes the GraphDefinition in diagnostics.
+func (d *Definition) name() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return d.Name
+}
+
+// walk checks the links of the resource at index i reached at node n.
+func (r *run) walk(i int, n *node) {
+	if r.visited[visit{i, n}] || r.ctx.Err() != nil {
+		return
+	}
+	r.visited[visit{i, n}] = true
+
+	source := &r.resources[i]
+	for _, l := range n.links {
+		var matched []visit
+		if l.path != "" {
+			matched = r.followPath(source, l)
+		} else {
+			matched = r.followReverse(i, l)
+		}
+
+		if len(matched) < l.min || l.max != "*" && len(matched) > atoi(l.max) {
+			r.result.AddErrorWithID(
+				issue.DiagGraphLinkCardinality,
+				issue.Params{
+					issue.String("link", l.label()),
+					issue.String("resource", source.name()),
+					issue.Int("count", len(matched)),
+					issue.Int("min", l.min),
+					issue.String("max", l.max),
+				},
+				source.Path,
+			)
+		}
+
+		for _, m := range matched {
+			r.checkProfile(m.resource, m.node.profile, l.label())
+			r.walk(m.resource, m.node)
+		}
+	}
+}
+
+// followPath returns the resources the references selected by the link's
+// path lead to, with the target node of each.
+func (r *run) followPath(source *Resource, l *link) []visit {
+	refs, err := r.v.references(r.ctx, source.Raw, l.path)
+	if err != nil {
+		r.result.AddWarningWithID(
+			issue.DiagGraphPathInvalid,
+			issue.Params{issue.String("link", l.path), issue.String("error", err.Error())},
+			source.Path,
+		)
+		return nil
+	}
+
+	var matched []visit
+	for _, ref := range refs {
+		j := r.resolve(ref)
+		if j < 0 {
+			r.result.AddWarningWithID(
+				issue.DiagGraphTargetNotFound,
+				issue.Params{issue.String("link", l.label()), issue.String("resource", source.name()), issue.String("reference", ref)},
+				source.Path,
+			)
+			continue
+		}
+		target := l.target(r.resources[j].resourceType())
+		if target == nil {
+			r.result.AddErrorWithID(
+				issue.DiagGraphTargetType,
+				issue.Params{
+					issue.String("link", l.label()),
+					issue.String("resource", source.name()),
+					issue.String("type", r.resources[j].resourceType()),
+					issue.String("types", l.targetTypes()),
+				},
+				source.Path,
+			)
+			continue
+		}
+		matched = append(matched, visit{j, target})
+	}
+	return matched
+}
+
+// followReverse returns the resources of the link's target types that
+// reference the resource at index i.
+func (r *run) followReverse(i int, l *link) []visit {
+	var matched []visit
+	for j := range r.resources {
+		if j == i {
+			continue
+		}
+		target := l.target(r.resources[j].resourceType())
+		if target == nil {
+			continue
+		}
+		for _, ref := range walker.CollectReferences(r.resources[j].Data, nil) {
+			if r.resolve(ref) == i {
+				matched = append(matched, visit{j, target})
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// target returns the link's target node for a resource type, or nil.
+func (l *link) target(resourceType string) *node {
+	for _, t := range l.targets {
+		if t.resourceType == resourceType || t.resourceType == "" {
+			return t
+		}
+	}
+	return nil
+}
+
+// targetTypes lists the link's target types.
+func (l *link) targetTypes() string {
+	types := make([]string, len(l.targets))
+	for i, t := range l.targets {
+		types[i] = t.resourceType
+	}
+	return strings.Join(types, ", ")
+}
+
+// checkProfile reports the resource at index i if it does not conform to
+// profile, which the graph requires through the link described by via.
+func (r *run) checkProfile(i int, profile, via string) {
+	if profile == "" || r.v.profiles == nil {
+		return
+	}
+	key := strconv.Itoa(i) + "|" + profile
+	if _, done := r.conforms[key]; done {
+		return
+	}
+
+	resource := &r.resources[i]
+	ok, err := r.v.profiles(r.ctx, resource.Raw, profile)
+	r.conforms[key] = ok
+	switch {
+	case err != nil:
+		r.result.AddWarningWithID(
+			issue.DiagGraphProfileUnchecked,
+			issue.Params{issue.String("resource", resource.name()), issue.String("profile", profile), issue.String("error", err.Error())},
+			resource.Path,
+		)
+	case !ok:
+		r.result.AddErrorWithID(
+			issue.DiagGraphTargetProfile,
+			issue.Params{issue.String("resource", resource.name()), issue.String("profile", profile), issue.String("link", via)},
+			resource.Path,
+		)
+	}
+}
+
+// resolve returns the index of the resource a reference points to, by
+// fullUrl or "Type/id"; -1 if none does.
+func (r *run) resolve(ref string) int {
+	ref = strings.Split(ref, "/_history/")[0]
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return -1
+	}
+	for i := range r.resources {
+		res := &r.resources[i]
+		if res.FullURL != "" && (res.FullURL == ref || strings.HasSuffix(res.FullURL, "/"+ref)) {
+			return i
+		}
+		if key := res.key(); key != "" && (key == ref || strings.HasSuffix(ref, "/"+key)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// references evaluates a link path on a resource and returns the reference
+// strings of the Reference elements it selects.
+func (v *Validator) references(ctx context.Context, raw []byte, path string) ([]string, error) {
+	expr, err := v.compile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := expr.EvaluateWithOptions(raw, fhirpath.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, 0, len(values))
+	for _, value := range values {
+		obj, ok := value.(*types.ObjectValue)
+		if !ok {
+			continue
+		}
+		if ref, ok := obj.Get("reference"); ok {
+			refs = append(refs, ref.String())
+		}
+	}
+	return refs, nil
+}
+
+func (v *Validator) compile(expr string) (*fhirpath.Expression, error) {
+	v.exprMu.Lock()
+	defer v.exprMu.Unlock()
+	if compiled, ok := v.exprCache[expr]; ok {
+		return compiled, nil
+	}
+	compiled, err := fhirpath.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	v.exprCache[expr] = compiled
+	return compiled, nil
+}
+
+// atoi parses a link max, treating an invalid value as unbounded.
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return int(^uint(0) >> 1)
+	}
+	return n
+}
@@ -0,0 +1,158 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// r4Graph requires a Composition with one Patient subject, at least one
+// Observation about that Patient, and allows one Organization custodian.
+const r4Graph = `{
+  "resourceType": "GraphDefinition",
+  "url": "http://example.org/GraphDefinition/episode",
+  "name": "Episode",
+  "start": "Composition",
+  "link": [
+    {"path": "Composition.subject", "min": 1, "max": "1",
+     "target": [{"type": "Patient", "profile": "http://example.org/StructureDefinition/patient",
+       "link": [{"min": 1, "max": "*", "target": [{"type": "Observation", "params": "patient={ref}"}]}]}]},
+    {"path": "custodian", "max": "1", "target": [{"type": "Organization"}]}
+  ]
+}`
+
+// r5Graph is r4Graph in the R5 node/link form, without the profile.
+const r5Graph = `{
+  "resourceType": "GraphDefinition",
+  "name": "Episode",
+  "start": "composition",
+  "node": [
+    {"nodeId": "composition", "type": "Composition"},
+    {"nodeId": "patient", "type": "Patient"},
+    {"nodeId": "observation", "type": "Observation"},
+    {"nodeId": "organization", "type": "Organization"}
+  ],
+  "link": [
+    {"sourceId": "composition", "path": "Composition.subject", "min": 1, "max": "1", "targetId": "patient"},
+    {"sourceId": "patient", "min": 1, "max": "*", "targetId": "observation", "params": "patient={ref}"},
+    {"sourceId": "composition", "path": "custodian", "max": "1", "targetId": "organization"}
+  ]
+}`
+
+func resources(t *testing.T, jsons ...string) []Resource {
+	t.Helper()
+	out := make([]Resource, len(jsons))
+	for i, s := range jsons {
+		if err := json.Unmarshal([]byte(s), &out[i].Data); err != nil {
+			t.Fatal(err)
+		}
+		out[i].Raw = []byte(s)
+		out[i].Path = out[i].resourceType()
+	}
+	return out
+}
+
+func messageIDs(r *issue.Result) []string {
+	ids := make([]string, 0, len(r.Issues))
+	for _, iss := range r.Issues {
+		ids = append(ids, iss.MessageID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestValidate(t *testing.T) {
+	const (
+		composition    = `{"resourceType": "Composition", "id": "c", "subject": {"reference": "Patient/p"}}`
+		patient        = `{"resourceType": "Patient", "id": "p"}`
+		observation    = `{"resourceType": "Observation", "id": "o", "subject": {"reference": "Patient/p"}}`
+		organization   = `{"resourceType": "Organization", "id": "g"}`
+		nonConformant  = `{"resourceType": "Patient", "id": "p", "active": false}`
+		wrongSubject   = `{"resourceType": "Composition", "id": "c", "subject": {"reference": "Organization/g"}}`
+		missingSubject = `{"resourceType": "Composition", "id": "c", "subject": {"reference": "Patient/x"}}`
+	)
+	conforms := func(_ context.Context, resource []byte, profile string) (bool, error) {
+		if profile != "http://example.org/StructureDefinition/patient" {
+			return false, errors.New("unknown profile")
+		}
+		var data map[string]any
+		err := json.Unmarshal(resource, &data)
+		return data["active"] != false, err
+	}
+
+	tests := []struct {
+		name      string
+		resources []string
+		want      []issue.DiagnosticID
+	}{
+		{"complete", []string{composition, patient, observation, organization}, nil},
+		{"no start", []string{patient, observation}, []issue.DiagnosticID{issue.DiagGraphStartNotFound}},
+		{"missing reverse link", []string{composition, patient}, []issue.DiagnosticID{issue.DiagGraphLinkCardinality}},
+		{"profile", []string{composition, nonConformant, observation}, []issue.DiagnosticID{issue.DiagGraphTargetProfile}},
+		{"wrong type", []string{wrongSubject, organization}, []issue.DiagnosticID{issue.DiagGraphLinkCardinality, issue.DiagGraphTargetType}},
+		{"target not in set", []string{missingSubject, patient, observation}, []issue.DiagnosticID{issue.DiagGraphLinkCardinality, issue.DiagGraphTargetNotFound}},
+	}
+
+	def, err := Parse([]byte(r4Graph))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			if err := New(def, conforms).Validate(context.Background(), resources(t, tt.resources...), result); err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+			want := make([]string, len(tt.want))
+			for i, id := range tt.want {
+				want[i] = string(id)
+			}
+			if got := messageIDs(result); !slices.Equal(got, want) {
+				t.Errorf("issues = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateR5(t *testing.T) {
+	def, err := Parse([]byte(r5Graph))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if def.Start() != "Composition" {
+		t.Errorf("Start() = %q, want Composition", def.Start())
+	}
+
+	set := resources(t,
+		`{"resourceType": "Composition", "id": "c", "subject": {"reference": "Patient/p"},
+		  "custodian": {"reference": "Organization/g"}}`,
+		`{"resourceType": "Patient", "id": "p"}`,
+		`{"resourceType": "Organization", "id": "g"}`,
+		`{"resourceType": "Organization", "id": "h"}`,
+	)
+	result := issue.NewResult()
+	if err := New(def, nil).Validate(context.Background(), set, result); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if got := messageIDs(result); !slices.Equal(got, []string{string(issue.DiagGraphLinkCardinality)}) {
+		t.Errorf("issues = %v, want one missing Observation", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"not JSON":      `{`,
+		"wrong type":    `{"resourceType": "Patient"}`,
+		"no start":      `{"resourceType": "GraphDefinition"}`,
+		"unknown start": `{"resourceType": "GraphDefinition", "start": "a", "node": [{"nodeId": "b", "type": "Patient"}]}`,
+		"unknown node":  `{"resourceType": "GraphDefinition", "start": "a", "node": [{"nodeId": "a", "type": "Patient"}], "link": [{"sourceId": "a", "targetId": "b"}]}`,
+	} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: Parse() should fail", name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package identifier validates Identifier values with checks registered by
+// Identifier.system, such as check digits of national identifiers or the
+// syntax of urn:oid: values. Built-in checks cover a few common systems (see
+// Builtins); callers register checks for their own systems with
+// Registry.Register or validator.WithIdentifierValidator.
+package identifier
+
+import (
+	"sync"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Func checks an Identifier value for a system. It returns an error
+// describing why the value is invalid, or nil if it is valid.
+type Func func(value string) error
+
+// Registry holds the identifier checks by system. It is safe for concurrent
+// use.
+type Registry struct {
+	mu    sync.RWMutex
+	funcs map[string]Func
+}
+
+// NewRegistry creates a Registry with the built-in checks.
+func NewRegistry() *Registry {
+	return &Registry{funcs: Builtins()}
+}
+
+// Register sets the check for a system, replacing any previous one. A nil
+// check removes it.
+func (r *Registry) Register(system string, fn Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fn == nil {
+		delete(r.funcs, system)
+		return
+	}
+	r.funcs[system] = fn
+}
+
+// Lookup returns the check for a system, or nil if there is none.
+func (r *Registry) Lookup(system string) Func {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.funcs[system]
+}
+
+// Validator checks the Identifier values of resources.
+type Validator struct {
+	systems *Registry
+	walker  *walker.Walker
+}
+
+// New creates a new identifier Validator using the checks in systems.
+func New(reg *registry.Registry, systems *Registry) *Validator {
+	return &Validator{
+		systems: systems,
+		walker:  walker.New(reg),
+	}
+}
+
+// ValidateData checks every Identifier of a pre-parsed resource, including
+// those of contained and Bundle entry resources, whose system has a check.
+func (v *Validator) ValidateData(resource map[string]any, sd *registry.StructureDefinition, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" || sd == nil {
+		return
+	}
+
+	v.validateResource(resource, sd, resourceType, resourceType, result)
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
+		if rc.FHIRPath != resourceType {
+			v.validateResource(rc.Data, rc.SD, rc.ResourceType, rc.FHIRPath, result)
+		}
+		return true
+	})
+}
+
+func (v *Validator) validateResource(data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, result *issue.Result) {
+	v.walker.WalkElements(data, sd, sdPath, fhirPath, func(ec *walker.ElementContext) bool {
+		if ec.Type != "Identifier" {
+			return true
+		}
+		system, _ := ec.Data["system"].(string)
+		value, _ := ec.Data["value"].(string)
+		if system == "" || value == "" {
+			return true
+		}
+		if check := v.systems.Lookup(system); check != nil {
+			if err := check(value); err != nil {
+				result.AddErrorWithID(
+					issue.DiagIdentifierInvalid,
+					issue.Params{issue.String("value", value), issue.String("system", system), issue.String("error", err.Error())},
+					ec.FHIRPath+".value",
+				)
+			}
+		}
+		return true
+	})
+}
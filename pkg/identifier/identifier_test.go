@@ -0,0 +1,66 @@
+package identifier
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		system, value string
+		valid         bool
+	}{
+		{SystemURI, "urn:oid:2.16.840.1.113883.4.1", true},
+		{SystemURI, "urn:oid:2.16.0840", false},
+		{SystemURI, "urn:uuid:a76d9bbf-f293-4fb7-ad4c-2851cac77162", true},
+		{SystemURI, "urn:uuid:a76d9bbf", false},
+		{SystemURI, "http://example.org/id/1", true},
+		{SystemURI, "12345", false},
+		{SystemRUN, "12345678-5", true},
+		{SystemRUN, "12.345.678-5", true},
+		{SystemRUN, "12345678-4", false},
+		{SystemRUN, "10000013-K", true},
+		{SystemRUN, "12345678", false},
+		{SystemSSN, "123-45-6789", true},
+		{SystemSSN, "123456789", true},
+		{SystemSSN, "666-45-6789", false},
+		{SystemSSN, "123-00-6789", false},
+		{SystemSSN, "123-45-678", false},
+		{SystemNPI, "1234567893", true},
+		{SystemNPI, "1234567890", false},
+		{SystemNPI, "123456789", false},
+	}
+
+	builtins := Builtins()
+	for _, tt := range tests {
+		err := builtins[tt.system](tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("check(%s, %q) = %v, want valid %v", tt.system, tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if r.Lookup(SystemSSN) == nil {
+		t.Fatal("Lookup(SSN) = nil, want built-in check")
+	}
+
+	r.Register("http://example.org/mrn", func(value string) error {
+		if len(value) != 6 {
+			return errors.New("expected 6 characters")
+		}
+		return nil
+	})
+	if check := r.Lookup("http://example.org/mrn"); check == nil || check("ABC123") != nil || check("ABC") == nil {
+		t.Error("registered check is not applied")
+	}
+
+	r.Register(SystemSSN, nil)
+	if r.Lookup(SystemSSN) != nil {
+		t.Error("Register(nil) should remove the check")
+	}
+	if NewRegistry().Lookup(SystemSSN) == nil {
+		t.Error("Register on one registry changed the built-ins of another")
+	}
+}
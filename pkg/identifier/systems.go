@@ -0,0 +1,130 @@
+package identifier
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Identifier systems with built-in checks.
+const (
+	SystemURI = "urn:ietf:rfc:3986"
+	SystemRUN = "http://regcivil.cl/Validacion/RUN"
+	SystemSSN = "http://hl7.org/fhir/sid/us-ssn"
+	SystemNPI = "http://hl7.org/fhir/sid/us-npi"
+)
+
+// Builtins returns the built-in checks by system:
+//
+//   - urn:ietf:rfc:3986: an absolute URI; urn:oid: and urn:uuid: values must
+//     be a valid OID or UUID
+//   - Chilean RUN: digits with a hyphenated modulo 11 check digit (0-9 or K)
+//   - US SSN: ddd-dd-dddd or nine digits, without the area, group or serial
+//     numbers never assigned
+//   - US NPI: ten digits with a Luhn check digit
+func Builtins() map[string]Func {
+	return map[string]Func{
+		SystemURI: validateURI,
+		SystemRUN: validateRUN,
+		SystemSSN: validateSSN,
+		SystemNPI: validateNPI,
+	}
+}
+
+var (
+	oidPattern  = regexp.MustCompile(`^[0-2](\.(0|[1-9][0-9]*))+$`)
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	runPattern  = regexp.MustCompile(`^([0-9]{1,3}(\.?[0-9]{3})*)-([0-9kK])$`)
+	ssnPattern  = regexp.MustCompile(`^([0-9]{3})-?([0-9]{2})-?([0-9]{4})$`)
+)
+
+func validateURI(value string) error {
+	if oid, ok := strings.CutPrefix(value, "urn:oid:"); ok {
+		if !oidPattern.MatchString(oid) {
+			return fmt.Errorf("%q is not a valid OID", oid)
+		}
+		return nil
+	}
+	if uuid, ok := strings.CutPrefix(value, "urn:uuid:"); ok {
+		if !uuidPattern.MatchString(uuid) {
+			return fmt.Errorf("%q is not a valid UUID", uuid)
+		}
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return errors.New("not an absolute URI")
+	}
+	return nil
+}
+
+func validateRUN(value string) error {
+	m := runPattern.FindStringSubmatch(value)
+	if m == nil {
+		return errors.New("expected digits, a hyphen and a check digit (e.g., 12345678-5)")
+	}
+	body := strings.ReplaceAll(m[1], ".", "")
+	sum, factor := 0, 2
+	for i := len(body) - 1; i >= 0; i-- {
+		sum += int(body[i]-'0') * factor
+		factor++
+		if factor > 7 {
+			factor = 2
+		}
+	}
+	var want string
+	switch check := 11 - sum%11; check {
+	case 11:
+		want = "0"
+	case 10:
+		want = "K"
+	default:
+		want = fmt.Sprint(check)
+	}
+	if !strings.EqualFold(m[3], want) {
+		return fmt.Errorf("check digit is %s, expected %s", m[3], want)
+	}
+	return nil
+}
+
+func validateSSN(value string) error {
+	m := ssnPattern.FindStringSubmatch(value)
+	if m == nil {
+		return errors.New("expected ddd-dd-dddd")
+	}
+	area, group, serial := m[1], m[2], m[3]
+	switch {
+	case area == "000" || area == "666" || area[0] == '9':
+		return fmt.Errorf("area number %s is never assigned", area)
+	case group == "00":
+		return errors.New("group number 00 is never assigned")
+	case serial == "0000":
+		return errors.New("serial number 0000 is never assigned")
+	}
+	return nil
+}
+
+func validateNPI(value string) error {
+	if len(value) != 10 || strings.Trim(value, "0123456789") != "" {
+		return errors.New("expected 10 digits")
+	}
+	// The Luhn check digit is computed over the NPI prefixed with 80840.
+	digits := "80840" + value
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	if sum%10 != 0 {
+		return errors.New("invalid check digit")
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package issue
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the locale of the built-in diagnostic templates.
+const DefaultLocale = "en"
+
+// localeFiles holds the translation bundles shipped with the validator, one
+// JSON object per locale mapping diagnostic IDs to templates.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	localesOnce sync.Once
+	localesMu   sync.RWMutex
+	locales     map[string]map[DiagnosticID]string
+)
+
+// CatalogEntry describes a diagnostic in the catalog.
+type CatalogEntry struct {
+	ID       DiagnosticID `json:"id"`
+	Severity Severity     `json:"severity"`
+	Code     Code         `json:"code"`
+	Template string       `json:"template"`
+	Params   []string     `json:"params,omitempty"` // Placeholders in the template, in order of first use
+}
+
+// Catalog returns every diagnostic the validator can report, sorted by ID,
+// with its default severity, issue code, English template and parameters.
+func Catalog() []CatalogEntry {
+	entries := make([]CatalogEntry, 0, len(diagnosticTemplates))
+	for id, tmpl := range diagnosticTemplates {
+		entries = append(entries, CatalogEntry{
+			ID:       id,
+			Severity: tmpl.Severity,
+			Code:     tmpl.Code,
+			Template: tmpl.Template,
+			Params:   templateParams(tmpl.Template),
+		})
+	}
+	slices.SortFunc(entries, func(a, b CatalogEntry) int {
+		return strings.Compare(string(a.ID), string(b.ID))
+	})
+	return entries
+}
+
+// Locales returns the available locales, including DefaultLocale, sorted.
+func Locales() []string {
+	loadLocales()
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+
+	names := []string{DefaultLocale}
+	for name := range locales {
+		if name != DefaultLocale {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// HasLocale reports whether templates are available for a locale.
+func HasLocale(locale string) bool {
+	return localeTemplates(locale) != nil || normalizeLocale(locale) == DefaultLocale
+}
+
+// RegisterLocale adds or replaces the translated templates for a locale.
+// Templates use the same {placeholder} names as the English catalog;
+// diagnostics without a translation keep their English message.
+func RegisterLocale(locale string, templates map[DiagnosticID]string) {
+	loadLocales()
+	localesMu.Lock()
+	defer localesMu.Unlock()
+	locales[normalizeLocale(locale)] = templates
+}
+
+// LocalizedTemplate returns the template for a diagnostic in a locale,
+// falling back to the English template.
+func LocalizedTemplate(id DiagnosticID, locale string) (string, bool) {
+	if t, ok := localeTemplates(locale)[id]; ok {
+		return t, true
+	}
+	tmpl, ok := diagnosticTemplates[id]
+	return tmpl.Template, ok
+}
+
+// Localize rewrites the messages of catalog diagnostics into a locale.
+// Parameters are recovered by matching each message against its English
+// template; messages that do not match, or have no translation, are kept.
+func (r *Result) Localize(locale string) {
+	translated := localeTemplates(locale)
+	if translated == nil {
+		return
+	}
+
+	for i := range r.Issues {
+		id := DiagnosticID(r.Issues[i].MessageID)
+		target, ok := translated[id]
+		if !ok {
+			continue
+		}
+		params, ok := matchTemplate(diagnosticTemplates[id].Template, r.Issues[i].Diagnostics)
+		if !ok {
+			continue
+		}
+		r.Issues[i].Diagnostics = formatTemplate(target, params)
+	}
+}
+
+// localeTemplates returns the translations for a locale ("es", "es-CL" and
+// "es_CL" all select "es" unless a regional bundle exists), or nil.
+func localeTemplates(locale string) map[DiagnosticID]string {
+	loadLocales()
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+
+	locale = normalizeLocale(locale)
+	if t, ok := locales[locale]; ok {
+		return t
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		return locales[lang]
+	}
+	return nil
+}
+
+// normalizeLocale lower-cases a locale and uses '-' as region separator.
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(locale), "_", "-")
+}
+
+// loadLocales parses the embedded translation bundles once.
+func loadLocales() {
+	localesOnce.Do(func() {
+		locales = make(map[string]map[DiagnosticID]string)
+		files, err := localeFiles.ReadDir("locales")
+		if err != nil {
+			panic(fmt.Sprintf("issue: reading embedded locales: %v", err))
+		}
+		for _, f := range files {
+			data, err := localeFiles.ReadFile(path.Join("locales", f.Name()))
+			if err != nil {
+				panic(fmt.Sprintf("issue: reading locale %s: %v", f.Name(), err))
+			}
+			var templates map[DiagnosticID]string
+			if err := json.Unmarshal(data, &templates); err != nil {
+				panic(fmt.Sprintf("issue: parsing locale %s: %v", f.Name(), err))
+			}
+			locales[strings.TrimSuffix(f.Name(), ".json")] = templates
+		}
+	})
+}
+
+// templateParams lists the placeholders of a template in order of first use.
+func templateParams(template string) []string {
+	var params []string
+	for _, seg := range compileTemplate(template) {
+		if seg.key != "" && !slices.Contains(params, seg.key) {
+			params = append(params, seg.key)
+		}
+	}
+	return params
+}
+
+// matchTemplate recovers the parameters of a message formatted from
+// template. Each placeholder matches up to the next occurrence of the
+// literal text that follows it.
+func matchTemplate(template, message string) (Params, bool) {
+	segs := compileTemplate(template)
+	params := make(Params, 0, len(segs)/2)
+
+	rest := message
+	for i, seg := range segs {
+		if seg.key == "" {
+			if !strings.HasPrefix(rest, seg.text) {
+				return nil, false
+			}
+			rest = rest[len(seg.text):]
+			continue
+		}
+
+		value := rest
+		if i+1 < len(segs) {
+			next := segs[i+1]
+			if next.key != "" {
+				return nil, false // adjacent placeholders are ambiguous
+			}
+			end := strings.Index(rest, next.text)
+			if end < 0 {
+				return nil, false
+			}
+			value = rest[:end]
+		}
+		if prev, seen := params.Get(seg.key); seen {
+			if prev.str != value {
+				return nil, false
+			}
+		} else {
+			params = append(params, String(seg.key, value))
+		}
+		rest = rest[len(value):]
+	}
+	return params, rest == ""
+}
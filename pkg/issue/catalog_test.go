@@ -0,0 +1,117 @@
+package issue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	catalog := Catalog()
+	if len(catalog) != len(diagnosticTemplates) {
+		t.Fatalf("Catalog() has %d entries, want %d", len(catalog), len(diagnosticTemplates))
+	}
+	if !slices.IsSortedFunc(catalog, func(a, b CatalogEntry) int { return strings.Compare(string(a.ID), string(b.ID)) }) {
+		t.Error("Catalog() is not sorted by ID")
+	}
+
+	for _, entry := range catalog {
+		if entry.Template == "" || entry.Severity == "" || entry.Code == "" {
+			t.Errorf("incomplete catalog entry: %+v", entry)
+		}
+		if entry.ID == DiagCardinalityMin && !slices.Equal(entry.Params, []string{"path", "min", "count"}) {
+			t.Errorf("%s params = %v, want [path min count]", entry.ID, entry.Params)
+		}
+	}
+}
+
+func TestLocaleBundlesMatchCatalog(t *testing.T) {
+	for _, locale := range Locales() {
+		if locale == DefaultLocale {
+			continue
+		}
+		translated := localeTemplates(locale)
+		for _, entry := range Catalog() {
+			tmpl, ok := translated[entry.ID]
+			if !ok {
+				t.Errorf("locale %s: missing translation for %s", locale, entry.ID)
+				continue
+			}
+			got := templateParams(tmpl)
+			slices.Sort(got)
+			want := slices.Clone(entry.Params)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("locale %s: %s uses params %v, want %v", locale, entry.ID, got, want)
+			}
+		}
+		for id := range translated {
+			if _, ok := diagnosticTemplates[id]; !ok {
+				t.Errorf("locale %s: translation for unknown diagnostic %s", locale, id)
+			}
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	r := NewResult()
+	r.AddErrorWithID(DiagCardinalityMin, Params{String("path", "Patient.name"), Int("min", 1), Int("count", 0)}, "Patient.name")
+	r.AddWarningWithID(DiagConstraintEvalError, Params{String("key", "pat-1"), String("error", "unexpected ': ' in expression")})
+	r.AddError(CodeStructure, "Not from the catalog")
+
+	r.Localize("es-CL")
+
+	want := []string{
+		"La cardinalidad mínima de 'Patient.name' es 1, pero se encontraron 0",
+		"No se pudo evaluar la restricción 'pat-1': unexpected ': ' in expression",
+		"Not from the catalog",
+	}
+	for i, w := range want {
+		if r.Issues[i].Diagnostics != w {
+			t.Errorf("Issues[%d] = %q, want %q", i, r.Issues[i].Diagnostics, w)
+		}
+	}
+}
+
+func TestRegisterLocale(t *testing.T) {
+	RegisterLocale("xx_YY", map[DiagnosticID]string{DiagExtensionUnknown: "??? '{url}'"})
+
+	if !HasLocale("xx-yy") || HasLocale("xx") || HasLocale("zz") || !HasLocale("EN") {
+		t.Errorf("HasLocale() does not match the registered locales: %v", Locales())
+	}
+	if tmpl, _ := LocalizedTemplate(DiagExtensionNoURL, "xx-YY"); tmpl != diagnosticTemplates[DiagExtensionNoURL].Template {
+		t.Errorf("LocalizedTemplate() = %q, want English fallback", tmpl)
+	}
+
+	r := NewResult()
+	r.AddWarningWithID(DiagExtensionUnknown, Params{String("url", "http://example.org/ext")})
+	r.Localize("xx-yy")
+	if got := r.Issues[0].Diagnostics; got != "??? 'http://example.org/ext'" {
+		t.Errorf("Localize() = %q", got)
+	}
+}
+
+func TestMatchTemplate(t *testing.T) {
+	tests := []struct {
+		template, message string
+		want              map[string]string // nil = no match
+	}{
+		{"Unknown element '{element}'", "Unknown element 'foo'", map[string]string{"element": "foo"}},
+		{"{details}", "anything: at all", map[string]string{"details": "anything: at all"}},
+		{"{a} and {a}", "x and y", nil},
+		{"Unknown element '{element}'", "Unknown extension 'foo'", nil},
+		{"Value '{value}' exceeds", "Value 'x' exceeds and more", nil},
+	}
+	for _, tt := range tests {
+		got, ok := matchTemplate(tt.template, tt.message)
+		if ok != (tt.want != nil) {
+			t.Errorf("matchTemplate(%q, %q) ok = %v", tt.template, tt.message, ok)
+			continue
+		}
+		for k, v := range tt.want {
+			if p, _ := got.Get(k); p.Value() != v {
+				t.Errorf("matchTemplate(%q, %q)[%s] = %v, want %v", tt.template, tt.message, k, p.Value(), v)
+			}
+		}
+	}
+}
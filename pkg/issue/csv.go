@@ -0,0 +1,91 @@
+package issue
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// CSVHeader lists the columns written by CSVWriter, one row per issue.
+var CSVHeader = []string{"file", "resourceType", "id", "path", "severity", "code", "diagnosticId", "message"}
+
+// CSVWriter writes the issues of one or more results as CSV or TSV rows for
+// spreadsheet triage. The header is written before the first row.
+type CSVWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+// NewCSVWriter returns a writer using comma as field separator (',' for CSV,
+// '\t' for TSV).
+func NewCSVWriter(w io.Writer, comma rune) *CSVWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &CSVWriter{w: cw}
+}
+
+// Write adds one row per issue of r, labelled with the file it came from.
+func (c *CSVWriter) Write(file string, r *Result) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	var resourceType, id string
+	if r.Stats != nil {
+		resourceType, id = r.Stats.ResourceType, r.Stats.ResourceID
+	}
+	for _, iss := range r.Issues {
+		var path string
+		if len(iss.Expression) > 0 {
+			path = iss.Expression[0]
+		}
+		row := []string{file, resourceType, id, path, string(iss.Severity), string(iss.Code), iss.MessageID, iss.Diagnostics}
+		for i := range row {
+			row[i] = escapeFormula(row[i])
+		}
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered rows and reports write errors. The header is
+// written even when there were no issues.
+func (c *CSVWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *CSVWriter) writeHeader() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+	return c.w.Write(CSVHeader)
+}
+
+// WriteCSV writes the issues of r as CSV with a header row. The file column
+// is left empty.
+func (r *Result) WriteCSV(w io.Writer) error {
+	cw := NewCSVWriter(w, ',')
+	if err := cw.Write("", r); err != nil {
+		return err
+	}
+	return cw.Flush()
+}
+
+// escapeFormula prefixes cells that spreadsheets would evaluate as formulas
+// (e.g., an invalid code "=HYPERLINK(...)" echoed in a message) with a quote.
+func escapeFormula(s string) string {
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package issue
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	r := NewResult()
+	r.Stats = &Stats{ResourceType: "Patient", ResourceID: "p1"}
+	r.AddErrorWithID(DiagStructureUnknownElement, Params{String("element", "foo")}, "Patient.foo")
+	r.AddWarning(CodeValue, "=HYPERLINK(\"http://example.org\"), with, commas")
+
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	want := [][]string{
+		CSVHeader,
+		{"", "Patient", "p1", "Patient.foo", "error", "structure", "STRUCTURE_UNKNOWN_ELEMENT", "Unknown element 'foo'"},
+		{"", "Patient", "p1", "", "warning", "value", "", "'=HYPERLINK(\"http://example.org\"), with, commas"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestCSVWriterTSV(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewCSVWriter(&buf, '\t')
+
+	a := NewResult()
+	a.AddError(CodeStructure, "first", "Patient.a")
+	if err := cw.Write("a.json", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Write("b.json", NewResult()); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != strings.Join(CSVHeader, "\t") {
+		t.Fatalf("unexpected TSV output:\n%s", buf.String())
+	}
+	if lines[1] != "a.json\t\t\tPatient.a\terror\tstructure\t\tfirst" {
+		t.Errorf("row = %q", lines[1])
+	}
+
+	buf.Reset()
+	if err := NewCSVWriter(&buf, ',').Flush(); err != nil || strings.TrimSpace(buf.String()) != strings.Join(CSVHeader, ",") {
+		t.Errorf("empty export = %q, %v; want header only", buf.String(), err)
+	}
+}
@@ -1,22 +1,49 @@
 // Package issue provides diagnostic message templates for FHIR validation.
 package issue
 
-import (
-	"fmt"
-	"strings"
-)
-
 // DiagnosticID identifies a specific diagnostic message.
 type DiagnosticID string
 
 // Diagnostic IDs for structural validation (M1).
 const (
-	DiagStructureUnknownElement    DiagnosticID = "STRUCTURE_UNKNOWN_ELEMENT"
-	DiagStructureInvalidJSON       DiagnosticID = "STRUCTURE_INVALID_JSON"
-	DiagStructureNoResourceType    DiagnosticID = "STRUCTURE_NO_RESOURCE_TYPE"
-	DiagStructureUnknownResource   DiagnosticID = "STRUCTURE_UNKNOWN_RESOURCE"
-	DiagStructureInvalidChoiceType DiagnosticID = "STRUCTURE_INVALID_CHOICE_TYPE"
-	DiagStructureNoType            DiagnosticID = "STRUCTURE_NO_TYPE"
+	DiagStructureUnknownElement       DiagnosticID = "STRUCTURE_UNKNOWN_ELEMENT"
+	DiagStructureInvalidJSON          DiagnosticID = "STRUCTURE_INVALID_JSON"
+	DiagStructureNoResourceType       DiagnosticID = "STRUCTURE_NO_RESOURCE_TYPE"
+	DiagStructureUnknownResource      DiagnosticID = "STRUCTURE_UNKNOWN_RESOURCE"
+	DiagStructureInvalidChoiceType    DiagnosticID = "STRUCTURE_INVALID_CHOICE_TYPE"
+	DiagStructureChoiceMultiple       DiagnosticID = "STRUCTURE_CHOICE_MULTIPLE"
+	DiagStructureChoiceShadowMismatch DiagnosticID = "STRUCTURE_CHOICE_SHADOW_MISMATCH"
+	DiagStructureNoType               DiagnosticID = "STRUCTURE_NO_TYPE"
+	DiagStructureShadowMisaligned     DiagnosticID = "STRUCTURE_SHADOW_MISALIGNED"
+	DiagStructureShadowNull           DiagnosticID = "STRUCTURE_SHADOW_NULL"
+)
+
+// Diagnostic IDs for JSON syntax rules encoding/json does not enforce.
+const (
+	DiagJSONDuplicateKey DiagnosticID = "JSON_DUPLICATE_KEY"
+	DiagJSONTrailingData DiagnosticID = "JSON_TRAILING_DATA"
+	DiagJSONInvalidUTF8  DiagnosticID = "JSON_INVALID_UTF8"
+)
+
+// Diagnostic IDs for input limits.
+const (
+	DiagLimitResourceSize DiagnosticID = "LIMIT_RESOURCE_SIZE"
+	DiagLimitNestingDepth DiagnosticID = "LIMIT_NESTING_DEPTH"
+	DiagLimitElementCount DiagnosticID = "LIMIT_ELEMENT_COUNT"
+)
+
+// Diagnostic IDs for phases cut short by a deadline or cancellation.
+const (
+	DiagPhaseTimeout     DiagnosticID = "PHASE_TIMEOUT"
+	DiagPhaseInterrupted DiagnosticID = "PHASE_INTERRUPTED"
+)
+
+// Diagnostic IDs for profile resolution.
+const (
+	DiagCanonicalVersionAmbiguous DiagnosticID = "CANONICAL_VERSION_AMBIGUOUS"
+	DiagProfileNotFound           DiagnosticID = "PROFILE_NOT_FOUND"
+	DiagProfileUnresolved         DiagnosticID = "PROFILE_UNRESOLVED"
+	DiagProfileUnresolvedPartial  DiagnosticID = "PROFILE_UNRESOLVED_PARTIAL"
 )
 
 // Diagnostic IDs for cardinality validation (M2).
@@ -27,13 +54,22 @@ const (
 
 // Diagnostic IDs for binding validation (M7).
 const (
-	DiagBindingRequired         DiagnosticID = "BINDING_REQUIRED"
-	DiagBindingExtensible       DiagnosticID = "BINDING_EXTENSIBLE"
-	DiagBindingDisplayMismatch  DiagnosticID = "BINDING_DISPLAY_MISMATCH"
-	DiagBindingTextOnlyWarning  DiagnosticID = "BINDING_TEXT_ONLY_WARNING"
-	DiagBindingCannotValidate   DiagnosticID = "BINDING_CANNOT_VALIDATE"
-	DiagBindingValueSetNotFound DiagnosticID = "BINDING_VALUESET_NOT_FOUND"
-	DiagCodeNotInCodeSystem     DiagnosticID = "CODE_NOT_IN_CODESYSTEM"
+	DiagBindingRequired          DiagnosticID = "BINDING_REQUIRED"
+	DiagBindingExtensible        DiagnosticID = "BINDING_EXTENSIBLE"
+	DiagBindingDisplayMismatch   DiagnosticID = "BINDING_DISPLAY_MISMATCH"
+	DiagBindingTextOnlyWarning   DiagnosticID = "BINDING_TEXT_ONLY_WARNING"
+	DiagBindingCannotValidate    DiagnosticID = "BINDING_CANNOT_VALIDATE"
+	DiagBindingSystemUnsupported DiagnosticID = "BINDING_SYSTEM_UNSUPPORTED"
+	DiagBindingValueSetNotFound  DiagnosticID = "BINDING_VALUESET_NOT_FOUND"
+	DiagCodeNotInCodeSystem      DiagnosticID = "CODE_NOT_IN_CODESYSTEM"
+	DiagBindingCodeInactive      DiagnosticID = "BINDING_CODE_INACTIVE"
+	DiagBindingCodeAbstract      DiagnosticID = "BINDING_CODE_ABSTRACT"
+	DiagCodeInactive             DiagnosticID = "CODE_INACTIVE"
+	DiagCodeDeprecated           DiagnosticID = "CODE_DEPRECATED"
+	DiagQuantityCodeNoSystem     DiagnosticID = "QUANTITY_CODE_NO_SYSTEM"
+	DiagUCUMInvalidUnit          DiagnosticID = "UCUM_INVALID_UNIT"
+	DiagUCUMUnitMismatch         DiagnosticID = "UCUM_UNIT_MISMATCH"
+	DiagUCUMUnitIncompatible     DiagnosticID = "UCUM_UNIT_INCOMPATIBLE"
 )
 
 // Diagnostic IDs for extension validation (M8).
@@ -45,19 +81,102 @@ const (
 	DiagExtensionValueNotAllowed  DiagnosticID = "EXTENSION_VALUE_NOT_ALLOWED"
 	DiagExtensionInvalidValueType DiagnosticID = "EXTENSION_INVALID_VALUE_TYPE"
 	DiagExtensionNestedUnknown    DiagnosticID = "EXTENSION_NESTED_UNKNOWN"
+	DiagExtensionNestedMin        DiagnosticID = "EXTENSION_NESTED_MIN"
+	DiagExtensionNestedMax        DiagnosticID = "EXTENSION_NESTED_MAX"
+	DiagExtensionContextInvariant DiagnosticID = "EXTENSION_CONTEXT_INVARIANT"
+	DiagModifierNotUnderstood     DiagnosticID = "MODIFIER_EXTENSION_NOT_UNDERSTOOD"
 )
 
 // Diagnostic IDs for reference validation (M9).
 const (
-	DiagReferenceInvalidFormat DiagnosticID = "REFERENCE_INVALID_FORMAT"
-	DiagReferenceInvalidTarget DiagnosticID = "REFERENCE_INVALID_TARGET"
-	DiagReferenceTypeMismatch  DiagnosticID = "REFERENCE_TYPE_MISMATCH"
-	DiagReferenceNotInBundle   DiagnosticID = "REFERENCE_NOT_IN_BUNDLE"
+	DiagReferenceInvalidFormat     DiagnosticID = "REFERENCE_INVALID_FORMAT"
+	DiagReferenceInvalidTarget     DiagnosticID = "REFERENCE_INVALID_TARGET"
+	DiagReferenceTypeMismatch      DiagnosticID = "REFERENCE_TYPE_MISMATCH"
+	DiagReferenceNotInBundle       DiagnosticID = "REFERENCE_NOT_IN_BUNDLE"
+	DiagReferenceNotResolved       DiagnosticID = "REFERENCE_NOT_RESOLVED"
+	DiagReferenceRetiredType       DiagnosticID = "REFERENCE_RETIRED_TYPE"
+	DiagReferenceConditional       DiagnosticID = "REFERENCE_CONDITIONAL_INVALID"
+	DiagReferenceAggregation       DiagnosticID = "REFERENCE_AGGREGATION"
+	DiagReferenceVersionRequired   DiagnosticID = "REFERENCE_VERSION_REQUIRED"
+	DiagReferenceVersionNotAllowed DiagnosticID = "REFERENCE_VERSION_NOT_ALLOWED"
+	DiagReferenceTargetProfile     DiagnosticID = "REFERENCE_TARGET_PROFILE"
 )
 
 // Diagnostic IDs for Bundle validation.
 const (
-	DiagBundleFullURLMismatch DiagnosticID = "BUNDLE_FULLURL_ID_MISMATCH"
+	DiagBundleFullURLMismatch       DiagnosticID = "BUNDLE_FULLURL_ID_MISMATCH"
+	DiagBundleFullURLDuplicate      DiagnosticID = "BUNDLE_FULLURL_DUPLICATE"
+	DiagBundleRequestMismatch       DiagnosticID = "BUNDLE_REQUEST_MISMATCH"
+	DiagBundleReferenceCycle        DiagnosticID = "BUNDLE_REFERENCE_CYCLE"
+	DiagBundleResourceDuplicate     DiagnosticID = "BUNDLE_RESOURCE_DUPLICATE"
+	DiagCompositionSectionDuplicate DiagnosticID = "COMPOSITION_SECTION_ENTRY_DUPLICATE"
+	DiagCodingDuplicate             DiagnosticID = "CODING_DUPLICATE"
+)
+
+// Diagnostic IDs for cross-resource uniqueness.
+const (
+	DiagUniqueDuplicate DiagnosticID = "UNIQUE_DUPLICATE"
+)
+
+// Diagnostic IDs for GraphDefinition validation.
+const (
+	DiagGraphStartNotFound    DiagnosticID = "GRAPH_START_NOT_FOUND"
+	DiagGraphLinkCardinality  DiagnosticID = "GRAPH_LINK_CARDINALITY"
+	DiagGraphPathInvalid      DiagnosticID = "GRAPH_PATH_INVALID"
+	DiagGraphTargetNotFound   DiagnosticID = "GRAPH_TARGET_NOT_FOUND"
+	DiagGraphTargetType       DiagnosticID = "GRAPH_TARGET_TYPE"
+	DiagGraphTargetProfile    DiagnosticID = "GRAPH_TARGET_PROFILE"
+	DiagGraphProfileUnchecked DiagnosticID = "GRAPH_PROFILE_UNCHECKED"
+)
+
+// Diagnostic IDs for narrative validation.
+const (
+	DiagXHTMLInvalid              DiagnosticID = "XHTML_INVALID"
+	DiagXHTMLActiveContent        DiagnosticID = "XHTML_ACTIVE_CONTENT"
+	DiagXHTMLElementNotAllowed    DiagnosticID = "XHTML_ELEMENT_NOT_ALLOWED"
+	DiagXHTMLAttributeNotAllowed  DiagnosticID = "XHTML_ATTRIBUTE_NOT_ALLOWED"
+	DiagXHTMLExternalReference    DiagnosticID = "XHTML_EXTERNAL_REFERENCE"
+	DiagNarrativeLanguageMismatch DiagnosticID = "NARRATIVE_LANGUAGE_MISMATCH"
+)
+
+// Diagnostic IDs for contained resource rules (dom-2 to dom-5).
+const (
+	DiagContainedNested         DiagnosticID = "CONTAINED_NESTED"
+	DiagContainedMeta           DiagnosticID = "CONTAINED_META"
+	DiagContainedSecurity       DiagnosticID = "CONTAINED_SECURITY"
+	DiagContainedNotReferenced  DiagnosticID = "CONTAINED_NOT_REFERENCED"
+	DiagContainedRefNotResolved DiagnosticID = "CONTAINED_REFERENCE_NOT_RESOLVED"
+)
+
+// Diagnostic IDs for the Provenance/AuditEvent rule pack.
+const (
+	DiagProvenanceTargetNotResolved DiagnosticID = "PROVENANCE_TARGET_NOT_RESOLVED"
+	DiagProvenanceAgentNoIdentity   DiagnosticID = "PROVENANCE_AGENT_NO_IDENTITY"
+	DiagSignatureNoFormat           DiagnosticID = "SIGNATURE_NO_FORMAT"
+	DiagSignatureInvalidFormat      DiagnosticID = "SIGNATURE_INVALID_FORMAT"
+	DiagAuditCodingIncomplete       DiagnosticID = "AUDIT_CODING_INCOMPLETE"
+)
+
+// Diagnostic IDs for the sanity phase.
+const (
+	DiagSanityPeriodOrder               DiagnosticID = "SANITY_PERIOD_ORDER"
+	DiagSanityBirthDateFuture           DiagnosticID = "SANITY_BIRTHDATE_FUTURE"
+	DiagSanityDeceasedBeforeBirth       DiagnosticID = "SANITY_DECEASED_BEFORE_BIRTH"
+	DiagSanityEffectiveOutsideEncounter DiagnosticID = "SANITY_EFFECTIVE_OUTSIDE_ENCOUNTER"
+)
+
+// Diagnostic IDs for cross-version conversion.
+const (
+	DiagVersionConverted           DiagnosticID = "VERSION_CONVERTED"
+	DiagVersionElementNotMapped    DiagnosticID = "VERSION_ELEMENT_NOT_MAPPED"
+	DiagVersionValueNotMapped      DiagnosticID = "VERSION_VALUE_NOT_MAPPED"
+	DiagVersionRepetitionNotMapped DiagnosticID = "VERSION_REPETITION_NOT_MAPPED"
+)
+
+// Diagnostic IDs for the business rules phase.
+const (
+	DiagBusinessRuleFailed    DiagnosticID = "BUSINESS_RULE_FAILED"
+	DiagBusinessRuleEvalError DiagnosticID = "BUSINESS_RULE_EVAL_ERROR"
 )
 
 // Diagnostic IDs for constraint validation (M10).
@@ -65,6 +184,15 @@ const (
 	DiagConstraintFailed       DiagnosticID = "CONSTRAINT_FAILED"
 	DiagConstraintCompileError DiagnosticID = "CONSTRAINT_COMPILE_ERROR"
 	DiagConstraintEvalError    DiagnosticID = "CONSTRAINT_EVAL_ERROR"
+
+	DiagConstraintValueSetUnresolved  DiagnosticID = "CONSTRAINT_VALUESET_UNRESOLVED"
+	DiagConstraintReferenceUnresolved DiagnosticID = "CONSTRAINT_REFERENCE_UNRESOLVED"
+)
+
+// Diagnostic IDs for minValue[x]/maxValue[x] bounds.
+const (
+	DiagValueBelowMin DiagnosticID = "VALUE_BELOW_MIN"
+	DiagValueAboveMax DiagnosticID = "VALUE_ABOVE_MAX"
 )
 
 // Diagnostic IDs for slicing validation.
@@ -72,6 +200,81 @@ const (
 	DiagSlicingNoMatch        DiagnosticID = "SLICING_NO_MATCH"
 	DiagSlicingCardinalityMin DiagnosticID = "SLICING_CARDINALITY_MIN"
 	DiagSlicingCardinalityMax DiagnosticID = "SLICING_CARDINALITY_MAX"
+	DiagSlicingMinExceedsMax  DiagnosticID = "SLICING_MIN_EXCEEDS_MAX"
+	DiagSlicingRequiredClosed DiagnosticID = "SLICING_REQUIRED_CLOSED"
+	DiagSlicingProhibited     DiagnosticID = "SLICING_PROHIBITED"
+)
+
+// Diagnostic IDs for obligation evaluation.
+const (
+	DiagObligationMissing    DiagnosticID = "OBLIGATION_MISSING"
+	DiagObligationProhibited DiagnosticID = "OBLIGATION_PROHIBITED"
+	DiagObligationHandle     DiagnosticID = "OBLIGATION_HANDLE"
+)
+
+// Diagnostic IDs for identifier validation.
+const (
+	DiagIdentifierInvalid DiagnosticID = "IDENTIFIER_INVALID"
+)
+
+// Diagnostic IDs for operation parameter validation.
+const (
+	DiagOperationParameterUnknown DiagnosticID = "OPERATION_PARAMETER_UNKNOWN"
+	DiagOperationParameterMin     DiagnosticID = "OPERATION_PARAMETER_MIN"
+	DiagOperationParameterMax     DiagnosticID = "OPERATION_PARAMETER_MAX"
+	DiagOperationParameterType    DiagnosticID = "OPERATION_PARAMETER_TYPE"
+)
+
+// Diagnostic IDs for subscription validation.
+const (
+	DiagSubscriptionCriteriaInvalid  DiagnosticID = "SUBSCRIPTION_CRITERIA_INVALID"
+	DiagSubscriptionUnknownParameter DiagnosticID = "SUBSCRIPTION_UNKNOWN_PARAMETER"
+	DiagSubscriptionTopicUnresolved  DiagnosticID = "SUBSCRIPTION_TOPIC_UNRESOLVED"
+	DiagSubscriptionFilterNotAllowed DiagnosticID = "SUBSCRIPTION_FILTER_NOT_ALLOWED"
+	DiagSubscriptionEndpointRequired DiagnosticID = "SUBSCRIPTION_ENDPOINT_REQUIRED"
+	DiagSubscriptionEndpointInvalid  DiagnosticID = "SUBSCRIPTION_ENDPOINT_INVALID"
+	DiagSubscriptionPayloadInvalid   DiagnosticID = "SUBSCRIPTION_PAYLOAD_INVALID"
+	DiagSubscriptionPayloadRequired  DiagnosticID = "SUBSCRIPTION_PAYLOAD_REQUIRED"
+)
+
+// Diagnostic IDs for SearchParameter expression validation.
+const (
+	DiagSearchParamExpressionInvalid DiagnosticID = "SEARCHPARAM_EXPRESSION_INVALID"
+	DiagSearchParamExpressionBase    DiagnosticID = "SEARCHPARAM_EXPRESSION_BASE"
+	DiagSearchParamExpressionElement DiagnosticID = "SEARCHPARAM_EXPRESSION_ELEMENT"
+	DiagSearchParamExpressionType    DiagnosticID = "SEARCHPARAM_EXPRESSION_TYPE"
+)
+
+// Diagnostic IDs for StructureDefinition authoring validation.
+const (
+	DiagSDElementParent   DiagnosticID = "SD_ELEMENT_PARENT"
+	DiagSDElementOrder    DiagnosticID = "SD_ELEMENT_ORDER"
+	DiagSDSlicingMissing  DiagnosticID = "SD_SLICING_MISSING"
+	DiagSDTypeNotInBase   DiagnosticID = "SD_TYPE_NOT_IN_BASE"
+	DiagSDTargetNotInBase DiagnosticID = "SD_TARGET_NOT_IN_BASE"
+	DiagSDBindingWeaker   DiagnosticID = "SD_BINDING_WEAKER"
+)
+
+// Diagnostic IDs for ValueSet and CodeSystem authoring validation.
+const (
+	DiagValueSetSystemUnresolved          DiagnosticID = "VALUESET_SYSTEM_UNRESOLVED"
+	DiagValueSetFilterPropertyUnknown     DiagnosticID = "VALUESET_FILTER_PROPERTY_UNKNOWN"
+	DiagValueSetFilterOperatorInvalid     DiagnosticID = "VALUESET_FILTER_OPERATOR_INVALID"
+	DiagValueSetConceptDuplicate          DiagnosticID = "VALUESET_CONCEPT_DUPLICATE"
+	DiagCodeSystemConceptDuplicate        DiagnosticID = "CODESYSTEM_CONCEPT_DUPLICATE"
+	DiagCodeSystemDisplayMissing          DiagnosticID = "CODESYSTEM_DISPLAY_MISSING"
+	DiagCodeSystemContentMismatch         DiagnosticID = "CODESYSTEM_CONTENT_MISMATCH"
+	DiagCodeSystemCountMismatch           DiagnosticID = "CODESYSTEM_COUNT_MISMATCH"
+	DiagCodeSystemHierarchyMeaningMissing DiagnosticID = "CODESYSTEM_HIERARCHY_MEANING_MISSING"
+	DiagCodeSystemHierarchyCodeUnknown    DiagnosticID = "CODESYSTEM_HIERARCHY_CODE_UNKNOWN"
+)
+
+// Diagnostic IDs for datatype validation.
+const (
+	DiagAttachmentInvalidContentType    DiagnosticID = "ATTACHMENT_INVALID_CONTENT_TYPE"
+	DiagAttachmentContentTypeNotAllowed DiagnosticID = "ATTACHMENT_CONTENT_TYPE_NOT_ALLOWED"
+	DiagAttachmentSizeMismatch          DiagnosticID = "ATTACHMENT_SIZE_MISMATCH"
+	DiagAttachmentHashMismatch          DiagnosticID = "ATTACHMENT_HASH_MISMATCH"
 )
 
 // Diagnostic IDs for primitive type validation (M3).
@@ -95,6 +298,10 @@ const (
 	DiagTypeInvalidUnsignedInt DiagnosticID = "TYPE_INVALID_UNSIGNED_INT"
 	DiagTypeWrongJSONType      DiagnosticID = "TYPE_WRONG_JSON_TYPE"
 	DiagTypeInvalidFormat      DiagnosticID = "TYPE_INVALID_FORMAT"
+	DiagTypeMaxLength          DiagnosticID = "TYPE_MAX_LENGTH"
+	DiagTypeDecimalPrecision   DiagnosticID = "TYPE_DECIMAL_PRECISION"
+	DiagTypeDecimalPlaces      DiagnosticID = "TYPE_DECIMAL_PLACES"
+	DiagTypeBase64TooLarge     DiagnosticID = "TYPE_BASE64_TOO_LARGE"
 )
 
 // DiagnosticTemplate defines the structure for a diagnostic message.
@@ -132,13 +339,33 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 	DiagStructureInvalidChoiceType: {
 		Severity: SeverityError,
 		Code:     CodeStructure,
-		Template: "Invalid choice type '{element}' for {path}",
+		Template: "Invalid choice type '{element}' for {path} (allowed types: {allowed})",
+	},
+	DiagStructureChoiceMultiple: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Only one of {elements} may be present for {path}",
+	},
+	DiagStructureChoiceShadowMismatch: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "'{shadow}' does not match the choice type '{element}' present for {path}",
 	},
 	DiagStructureNoType: {
 		Severity: SeverityError,
 		Code:     CodeStructure,
 		Template: "StructureDefinition has no type",
 	},
+	DiagStructureShadowMisaligned: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "'_{element}' has {shadows} item(s) but '{element}' has {values}; a repeating primitive and its extensions must align by index, with null where an item has none",
+	},
+	DiagStructureShadowNull: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Item {index} of '{element}' is null in both '{element}' and '_{element}'",
+	},
 
 	// Cardinality (M2)
 	DiagCardinalityMin: {
@@ -151,6 +378,21 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Code:     CodeValue,
 		Template: "Maximum cardinality of '{path}' is {max}, but found {count}",
 	},
+	DiagSlicingMinExceedsMax: {
+		Severity: SeverityWarning,
+		Code:     CodeStructure,
+		Template: "Profile '{profile}' is inconsistent: the slices of '{path}' require at least {sum} occurrences, but the element allows at most {max}",
+	},
+	DiagSlicingRequiredClosed: {
+		Severity: SeverityWarning,
+		Code:     CodeStructure,
+		Template: "Profile '{profile}' is inconsistent: '{path}' is required, but its slicing is closed and every slice has max = 0",
+	},
+	DiagSlicingProhibited: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Element '{path}' is not allowed: its slicing is closed and every slice has max = 0",
+	},
 
 	// Primitive Types (M3)
 	DiagTypeWrongJSONType: {
@@ -188,6 +430,31 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Code:     CodeValue,
 		Template: "Error parsing JSON: the primitive value must be a string",
 	},
+	DiagTypeMaxLength: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Value is {length} characters long, exceeding maxLength {max}",
+	},
+	DiagTypeDecimalPlaces: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Decimal value '{value}' has {places} decimal places, exceeding maxDecimalPlaces {max}",
+	},
+	DiagTypeDecimalPrecision: {
+		Severity: SeverityWarning,
+		Code:     CodeValue,
+		Template: "Decimal value '{value}' has {digits} significant digits; only {max} are guaranteed to be supported",
+	},
+	DiagTypeInvalidBase64: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The value is not valid base64 content: {error}",
+	},
+	DiagTypeBase64TooLarge: {
+		Severity: SeverityError,
+		Code:     CodeTooLong,
+		Template: "Decoded base64 content is {size} bytes, exceeding the limit of {max} bytes",
+	},
 
 	// Binding (M7)
 	DiagBindingRequired: {
@@ -215,6 +482,11 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Code:     CodeInformational,
 		Template: "Code '{code}' in system '{system}' cannot be validated - external terminology system requires a terminology server",
 	},
+	DiagBindingSystemUnsupported: {
+		Severity: SeverityInformation,
+		Code:     CodeInformational,
+		Template: "Code '{code}' in system '{system}' cannot be validated - system unsupported by the terminology server",
+	},
 	DiagBindingValueSetNotFound: {
 		Severity: SeverityWarning,
 		Code:     CodeNotFound,
@@ -225,6 +497,46 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Code:     CodeInvalid,
 		Template: "The code '{code}' is not valid in the CodeSystem '{system}'",
 	},
+	DiagBindingCodeInactive: {
+		Severity: SeverityError,
+		Code:     CodeCodeInvalid,
+		Template: "The code '{code}' is inactive and the value set '{valueSet}' excludes inactive codes",
+	},
+	DiagBindingCodeAbstract: {
+		Severity: SeverityError,
+		Code:     CodeCodeInvalid,
+		Template: "The code '{code}' is abstract and cannot be selected from the value set '{valueSet}'",
+	},
+	DiagCodeInactive: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "The code '{code}' has status '{status}' and its use should be reviewed",
+	},
+	DiagCodeDeprecated: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "The code '{code}' is deprecated and should no longer be used",
+	},
+	DiagQuantityCodeNoSystem: {
+		Severity: SeverityError,
+		Code:     CodeInvariant,
+		Template: "Quantity has the code '{code}' but no system (qty-3)",
+	},
+	DiagUCUMInvalidUnit: {
+		Severity: SeverityError,
+		Code:     CodeCodeInvalid,
+		Template: "The unit '{code}' is not a valid UCUM unit: {error}",
+	},
+	DiagUCUMUnitMismatch: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The unit '{code}' is not the unit required by the profile ({expected}); the value must be converted",
+	},
+	DiagUCUMUnitIncompatible: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The unit '{code}' does not measure the same kind of quantity as the unit required by the profile ({expected})",
+	},
 
 	// Extension (M8)
 	DiagExtensionNoURL: {
@@ -262,6 +574,26 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Code:     CodeExtension,
 		Template: "Unknown nested extension '{url}' in parent '{parent}'",
 	},
+	DiagExtensionNestedMin: {
+		Severity: SeverityError,
+		Code:     CodeRequired,
+		Template: "Extension '{parent}' requires at least {min} '{url}' sub-extension(s), but found {count}",
+	},
+	DiagExtensionNestedMax: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Extension '{parent}' allows at most {max} '{url}' sub-extension(s), but found {count}",
+	},
+	DiagExtensionContextInvariant: {
+		Severity: SeverityError,
+		Code:     CodeInvariant,
+		Template: "Extension '{url}' context invariant failed: {expression}",
+	},
+	DiagModifierNotUnderstood: {
+		Severity: SeverityError,
+		Code:     CodeNotSupported,
+		Template: "Modifier extension '{url}' is not understood by this system and the resource must be rejected",
+	},
 
 	// Reference (M9)
 	DiagReferenceInvalidFormat: {
@@ -284,6 +616,41 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Code:     CodeNotFound,
 		Template: "URN reference is not locally contained within the bundle {reference}",
 	},
+	DiagReferenceNotResolved: {
+		Severity: SeverityError,
+		Code:     CodeNotFound,
+		Template: "Unable to resolve reference '{reference}'",
+	},
+	DiagReferenceRetiredType: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "Reference '{reference}' targets retired resource type '{type}'",
+	},
+	DiagReferenceAggregation: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Reference '{reference}' is {mode}, but the element only allows {allowed} references",
+	},
+	DiagReferenceVersionRequired: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Reference '{reference}' must be version specific (e.g., Type/id/_history/1)",
+	},
+	DiagReferenceVersionNotAllowed: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Reference '{reference}' must not be version specific",
+	},
+	DiagReferenceTargetProfile: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "The resource referenced by '{reference}' does not conform to any of the target profiles: {profiles}",
+	},
+	DiagReferenceConditional: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Conditional reference '{reference}' is not valid: {reason}",
+	},
 
 	// Bundle validation
 	DiagBundleFullURLMismatch: {
@@ -291,6 +658,234 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Code:     CodeValue,
 		Template: "fullUrl '{fullUrl}' is not consistent with resource id '{id}'",
 	},
+	DiagBundleFullURLDuplicate: {
+		Severity: SeverityError,
+		Code:     CodeDuplicate,
+		Template: "fullUrl '{fullUrl}' is already used by entry {entry}",
+	},
+	DiagBundleRequestMismatch: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Entry request '{method} {url}' does not match the entry: {reason}",
+	},
+	DiagBundleReferenceCycle: {
+		Severity: SeverityInformation,
+		Code:     CodeInformational,
+		Template: "Entries {entries} reference each other in a cycle",
+	},
+	DiagBundleResourceDuplicate: {
+		Severity: SeverityError,
+		Code:     CodeDuplicate,
+		Template: "Resource '{resource}' is already in entry {entry}",
+	},
+	DiagCompositionSectionDuplicate: {
+		Severity: SeverityWarning,
+		Code:     CodeDuplicate,
+		Template: "Section entry '{reference}' is already listed at index {index}",
+	},
+	DiagCodingDuplicate: {
+		Severity: SeverityInformation,
+		Code:     CodeDuplicate,
+		Template: "Coding '{system}|{code}' repeats coding {index} of the CodeableConcept",
+	},
+
+	// Cross-resource uniqueness
+	DiagUniqueDuplicate: {
+		Severity: SeverityError,
+		Code:     CodeDuplicate,
+		Template: "{type} {rule} '{key}' is already used by {first}",
+	},
+
+	// GraphDefinition validation
+	DiagGraphStartNotFound: {
+		Severity: SeverityError,
+		Code:     CodeNotFound,
+		Template: "No {type} resource to start GraphDefinition '{graph}' from",
+	},
+	DiagGraphLinkCardinality: {
+		Severity: SeverityError,
+		Code:     CodeRequired,
+		Template: "Link '{link}' of {resource} leads to {count} resource(s), but the graph requires {min}..{max}",
+	},
+	DiagGraphPathInvalid: {
+		Severity: SeverityWarning,
+		Code:     CodeProcessing,
+		Template: "Could not evaluate link path '{link}': {error}",
+	},
+	DiagGraphTargetNotFound: {
+		Severity: SeverityWarning,
+		Code:     CodeNotFound,
+		Template: "Link '{link}' of {resource} refers to '{reference}', which is not in the resource set",
+	},
+	DiagGraphTargetType: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Link '{link}' of {resource} leads to a {type}, but the graph allows {types}",
+	},
+	DiagGraphTargetProfile: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "{resource} does not conform to profile '{profile}' required by link '{link}'",
+	},
+	DiagGraphProfileUnchecked: {
+		Severity: SeverityWarning,
+		Code:     CodeNotFound,
+		Template: "Could not check {resource} against profile '{profile}': {error}",
+	},
+
+	// Narrative
+	DiagXHTMLInvalid: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Invalid XHTML: {error}",
+	},
+	DiagXHTMLActiveContent: {
+		Severity: SeverityError,
+		Code:     CodeSecurity,
+		Template: "XHTML contains active content: {detail}",
+	},
+	DiagXHTMLElementNotAllowed: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Element <{element}> is not allowed in narrative XHTML",
+	},
+	DiagXHTMLAttributeNotAllowed: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Attribute '{attribute}' is not allowed on <{element}> in narrative XHTML",
+	},
+	DiagXHTMLExternalReference: {
+		Severity: SeverityWarning,
+		Code:     CodeSecurity,
+		Template: "Narrative <{element}> references external content '{reference}'",
+	},
+	DiagNarrativeLanguageMismatch: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "Narrative language '{lang}' does not match resource language '{language}'",
+	},
+
+	// Contained resources
+	DiagContainedNested: {
+		Severity: SeverityError,
+		Code:     CodeInvariant,
+		Template: "Contained resource '{id}' SHALL NOT contain nested contained resources (dom-2)",
+	},
+	DiagContainedMeta: {
+		Severity: SeverityError,
+		Code:     CodeInvariant,
+		Template: "Contained resource '{id}' SHALL NOT have meta.{element} (dom-4)",
+	},
+	DiagContainedSecurity: {
+		Severity: SeverityError,
+		Code:     CodeInvariant,
+		Template: "Contained resource '{id}' SHALL NOT have a security label (dom-5)",
+	},
+	DiagContainedNotReferenced: {
+		Severity: SeverityError,
+		Code:     CodeInvariant,
+		Template: "Contained resource '{id}' is not referenced from the container and does not reference it (dom-3)",
+	},
+	DiagContainedRefNotResolved: {
+		Severity: SeverityError,
+		Code:     CodeNotFound,
+		Template: "Local reference '{reference}' does not match any contained resource",
+	},
+
+	// Provenance/AuditEvent rule pack
+	DiagProvenanceTargetNotResolved: {
+		Severity: SeverityError,
+		Code:     CodeNotFound,
+		Template: "Provenance target '{reference}' does not resolve to an entry in the Bundle",
+	},
+	DiagProvenanceAgentNoIdentity: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Provenance agent must be identified by a reference or identifier, not only a display",
+	},
+	DiagSignatureNoFormat: {
+		Severity: SeverityError,
+		Code:     CodeRequired,
+		Template: "Signature with data must declare sigFormat",
+	},
+	DiagSignatureInvalidFormat: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Signature {element} '{value}' is not a valid MIME type",
+	},
+	DiagAuditCodingIncomplete: {
+		Severity: SeverityError,
+		Code:     CodeCodeInvalid,
+		Template: "Coding must have both system and code",
+	},
+
+	// Sanity
+	DiagSanityPeriodOrder: {
+		Severity: SeverityError,
+		Code:     CodeInvariant,
+		Template: "Period start '{start}' is after its end '{end}' (per-1)",
+	},
+	DiagSanityBirthDateFuture: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Birth date '{birthDate}' is in the future",
+	},
+	DiagSanityDeceasedBeforeBirth: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Deceased date '{deceased}' is before the birth date '{birthDate}'",
+	},
+	DiagSanityEffectiveOutsideEncounter: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "Effective time is outside the period of encounter '{reference}'",
+	},
+
+	// Cross-version conversion
+	DiagVersionConverted: {
+		Severity: SeverityInformation,
+		Code:     CodeInformational,
+		Template: "Converted from FHIR {from} to FHIR {to} for validation; other issues use FHIR {to} paths",
+	},
+	DiagVersionElementNotMapped: {
+		Severity: SeverityError,
+		Code:     CodeNotSupported,
+		Template: "Element '{element}' is not defined in FHIR {version} and was not converted",
+	},
+	DiagVersionValueNotMapped: {
+		Severity: SeverityError,
+		Code:     CodeNotSupported,
+		Template: "The value of '{element}' has no equivalent in FHIR {version} and was not converted",
+	},
+	DiagVersionRepetitionNotMapped: {
+		Severity: SeverityError,
+		Code:     CodeNotSupported,
+		Template: "'{element}' allows a single value in FHIR {version}; only the first was converted",
+	},
+
+	// Business rules
+	DiagBusinessRuleFailed: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "{message} (rule {rule})",
+	},
+	DiagBusinessRuleEvalError: {
+		Severity: SeverityWarning,
+		Code:     CodeProcessing,
+		Template: "Could not evaluate business rule '{rule}': {error}",
+	},
+
+	// Bounds
+	DiagValueBelowMin: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Value '{value}' is less than the minimum '{min}' (minValue{type})",
+	},
+	DiagValueAboveMax: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "Value '{value}' is greater than the maximum '{max}' (maxValue{type})",
+	},
 
 	// Slicing
 	DiagSlicingNoMatch: {
@@ -309,6 +904,290 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Template: "Maximum cardinality of '{path}' is {max}, but found {count}",
 	},
 
+	// Strict JSON syntax
+	DiagJSONDuplicateKey: {
+		Severity: SeverityFatal,
+		Code:     CodeStructure,
+		Template: "Duplicate property '{name}' in a JSON object; the resource was not validated",
+	},
+	DiagJSONTrailingData: {
+		Severity: SeverityFatal,
+		Code:     CodeStructure,
+		Template: "Unexpected data after the end of the resource at byte offset {offset}; it was not validated",
+	},
+	DiagJSONInvalidUTF8: {
+		Severity: SeverityFatal,
+		Code:     CodeStructure,
+		Template: "Invalid UTF-8 byte sequence at byte offset {offset}; it was not validated",
+	},
+
+	// Input limits
+	DiagLimitResourceSize: {
+		Severity: SeverityFatal,
+		Code:     CodeTooCostly,
+		Template: "Resource is {size} bytes, exceeding the maximum of {max} bytes; it was not validated",
+	},
+	DiagLimitNestingDepth: {
+		Severity: SeverityFatal,
+		Code:     CodeTooCostly,
+		Template: "Resource exceeds the maximum nesting depth of {max} at byte offset {offset}; it was not validated",
+	},
+	DiagLimitElementCount: {
+		Severity: SeverityFatal,
+		Code:     CodeTooCostly,
+		Template: "Resource exceeds the maximum of {max} elements at byte offset {offset}; it was not validated",
+	},
+
+	// Phase deadlines
+	DiagPhaseTimeout: {
+		Severity: SeverityWarning,
+		Code:     CodeTimeout,
+		Template: "Validation incomplete: phase '{phase}' timed out after {timeout}",
+	},
+	DiagPhaseInterrupted: {
+		Severity: SeverityWarning,
+		Code:     CodeIncomplete,
+		Template: "Validation incomplete: phase '{phase}' was interrupted ({reason}); later phases were not run",
+	},
+
+	// Profile resolution
+	DiagCanonicalVersionAmbiguous: {
+		Severity: SeverityWarning,
+		Code:     CodeInformational,
+		Template: "Profile '{url}' is loaded in versions {versions}; validating against version {version}",
+	},
+	DiagProfileNotFound: {
+		Severity: SeverityWarning,
+		Code:     CodeNotFound,
+		Template: "Profile '{url}' not found in registry",
+	},
+	DiagProfileUnresolved: {
+		Severity: SeverityWarning,
+		Code:     CodeNotFound,
+		Template: "Profile '{url}' declared in meta.profile could not be resolved; validation performed against base {type} only",
+	},
+	DiagProfileUnresolvedPartial: {
+		Severity: SeverityWarning,
+		Code:     CodeNotFound,
+		Template: "Profile '{url}' declared in meta.profile could not be resolved; validation performed against {profiles} only",
+	},
+
+	// Obligations
+	DiagObligationMissing: {
+		Severity: SeverityError,
+		Code:     CodeRequired,
+		Template: "Element '{path}' {strength} be populated by actor '{actor}' (obligation {code})",
+	},
+	DiagObligationProhibited: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Element '{path}' SHALL NOT be populated by actor '{actor}' (obligation {code})",
+	},
+	DiagObligationHandle: {
+		Severity: SeverityInformation,
+		Code:     CodeInformational,
+		Template: "Element '{path}' {strength} be handled by actor '{actor}' (obligation {code})",
+	},
+
+	// Identifiers
+	DiagIdentifierInvalid: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The identifier '{value}' is not valid for the system '{system}': {error}",
+	},
+
+	// Operation parameters
+	DiagOperationParameterUnknown: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Parameter '{name}' is not an input parameter of operation '{operation}'",
+	},
+	DiagOperationParameterMin: {
+		Severity: SeverityError,
+		Code:     CodeRequired,
+		Template: "Parameter '{name}' occurs {count} time(s), but operation '{operation}' requires at least {min}",
+	},
+	DiagOperationParameterMax: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Parameter '{name}' occurs more than {max} time(s), the most operation '{operation}' allows",
+	},
+	DiagOperationParameterType: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Parameter '{name}' has type '{type}', but operation '{operation}' expects '{expected}'",
+	},
+
+	// Subscriptions
+	DiagSubscriptionCriteriaInvalid: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The criteria '{criteria}' is not a valid search query: {error}",
+	},
+	DiagSubscriptionUnknownParameter: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "'{name}' is not a search parameter of resource type '{resourceType}'",
+	},
+	DiagSubscriptionTopicUnresolved: {
+		Severity: SeverityInformation,
+		Code:     CodeNotFound,
+		Template: "The SubscriptionTopic '{topic}' is not loaded; its filters are not checked",
+	},
+	DiagSubscriptionFilterNotAllowed: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "The SubscriptionTopic '{topic}' does not allow filtering by '{name}'",
+	},
+	DiagSubscriptionEndpointRequired: {
+		Severity: SeverityError,
+		Code:     CodeRequired,
+		Template: "A {channel} channel requires an endpoint",
+	},
+	DiagSubscriptionEndpointInvalid: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The endpoint '{endpoint}' of a {channel} channel must be a {scheme} URL",
+	},
+	DiagSubscriptionPayloadInvalid: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The payload '{payload}' is not a FHIR MIME type (application/fhir+json or application/fhir+xml)",
+	},
+	DiagSubscriptionPayloadRequired: {
+		Severity: SeverityError,
+		Code:     CodeRequired,
+		Template: "Notifications with {content} content require a payload MIME type",
+	},
+
+	// SearchParameter expressions
+	DiagSearchParamExpressionInvalid: {
+		Severity: SeverityError,
+		Code:     CodeInvalid,
+		Template: "The expression '{expression}' is not valid FHIRPath: {error}",
+	},
+	DiagSearchParamExpressionBase: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The expression navigates from '{type}', which is not a base of the search parameter ({base})",
+	},
+	DiagSearchParamExpressionElement: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The expression path '{path}' is not an element of '{type}'",
+	},
+	DiagSearchParamExpressionType: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "The expression path '{path}' selects {type}, which a {searchType} search parameter cannot index",
+	},
+
+	// StructureDefinition authoring
+	DiagSDElementParent: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Element '{path}' is not preceded by its parent element '{parent}'",
+	},
+	DiagSDElementOrder: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Element '{path}' is out of order: the base defines it before '{previous}'",
+	},
+	DiagSDSlicingMissing: {
+		Severity: SeverityError,
+		Code:     CodeStructure,
+		Template: "Slice '{slice}' of '{path}' is not preceded by a slicing declaration on '{path}'",
+	},
+	DiagSDTypeNotInBase: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Type '{type}' of element '{path}' is not allowed by the base element (types: {base})",
+	},
+	DiagSDTargetNotInBase: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Target profile '{target}' of element '{path}' is not allowed by the base element (targets: {base})",
+	},
+	DiagSDBindingWeaker: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "The {strength} binding of element '{path}' is weaker than the {base} binding of the base element",
+	},
+
+	// ValueSet and CodeSystem authoring
+	DiagValueSetSystemUnresolved: {
+		Severity: SeverityWarning,
+		Code:     CodeNotFound,
+		Template: "CodeSystem '{system}' is not loaded, so the codes it contributes cannot be checked",
+	},
+	DiagValueSetFilterPropertyUnknown: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Filter property '{property}' is not a filter or property of CodeSystem '{system}'",
+	},
+	DiagValueSetFilterOperatorInvalid: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Filter operator '{op}' is not supported for property '{property}' of CodeSystem '{system}' (operators: {allowed})",
+	},
+	DiagValueSetConceptDuplicate: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "Code '{code}' of system '{system}' is listed more than once",
+	},
+	DiagCodeSystemConceptDuplicate: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Code '{code}' is defined more than once",
+	},
+	DiagCodeSystemDisplayMissing: {
+		Severity: SeverityInformation,
+		Code:     CodeInformational,
+		Template: "Concept '{code}' has no display",
+	},
+	DiagCodeSystemContentMismatch: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "CodeSystem content is '{content}' but it defines {concepts} concepts",
+	},
+	DiagCodeSystemCountMismatch: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "CodeSystem count is {count} but it defines {concepts} concepts",
+	},
+	DiagCodeSystemHierarchyMeaningMissing: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "CodeSystem has a concept hierarchy but no hierarchyMeaning",
+	},
+	DiagCodeSystemHierarchyCodeUnknown: {
+		Severity: SeverityError,
+		Code:     CodeBusinessRule,
+		Template: "Hierarchy property '{property}' refers to code '{code}', which the CodeSystem does not define",
+	},
+
+	// Datatypes
+	DiagAttachmentInvalidContentType: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The content type '{contentType}' is not a valid MIME type: {error}",
+	},
+	DiagAttachmentContentTypeNotAllowed: {
+		Severity: SeverityWarning,
+		Code:     CodeBusinessRule,
+		Template: "The content type '{contentType}' is not allowed (allowed: {allowed})",
+	},
+	DiagAttachmentSizeMismatch: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The attachment size {size} does not match the {actual} bytes of its data",
+	},
+	DiagAttachmentHashMismatch: {
+		Severity: SeverityError,
+		Code:     CodeValue,
+		Template: "The attachment hash does not match the SHA-1 hash of its data",
+	},
+
 	// Constraint (M10)
 	DiagConstraintFailed: {
 		Severity: SeverityError,
@@ -325,10 +1204,20 @@ var diagnosticTemplates = map[DiagnosticID]DiagnosticTemplate{
 		Code:     CodeProcessing,
 		Template: "Could not evaluate constraint '{key}': {error}",
 	},
+	DiagConstraintValueSetUnresolved: {
+		Severity: SeverityWarning,
+		Code:     CodeNotFound,
+		Template: "Could not evaluate constraint '{key}': ValueSet '{valueSet}' used by memberOf() could not be resolved",
+	},
+	DiagConstraintReferenceUnresolved: {
+		Severity: SeverityInformation,
+		Code:     CodeInformational,
+		Template: "Constraint '{key}' not checked: reference '{reference}' used by resolve() could not be resolved",
+	},
 }
 
 // FormatDiagnostic formats a diagnostic message with the given parameters.
-func FormatDiagnostic(id DiagnosticID, params map[string]any) string {
+func FormatDiagnostic(id DiagnosticID, params Params) string {
 	tmpl, ok := diagnosticTemplates[id]
 	if !ok {
 		return string(id)
@@ -345,18 +1234,8 @@ func GetDiagnosticTemplate(id DiagnosticID) (DiagnosticTemplate, bool) {
 	return tmpl, ok
 }
 
-// formatTemplate replaces {placeholder} with values from params.
-func formatTemplate(template string, params map[string]any) string {
-	result := template
-	for key, value := range params {
-		placeholder := "{" + key + "}"
-		result = strings.ReplaceAll(result, placeholder, fmt.Sprint(value))
-	}
-	return result
-}
-
 // AddErrorWithID adds an error using a diagnostic template.
-func (r *Result) AddErrorWithID(id DiagnosticID, params map[string]any, expression ...string) {
+func (r *Result) AddErrorWithID(id DiagnosticID, params Params, expression ...string) {
 	tmpl, ok := diagnosticTemplates[id]
 	if !ok {
 		r.AddError(CodeProcessing, string(id), expression...)
@@ -373,7 +1252,7 @@ func (r *Result) AddErrorWithID(id DiagnosticID, params map[string]any, expressi
 }
 
 // AddWarningWithID adds a warning using a diagnostic template.
-func (r *Result) AddWarningWithID(id DiagnosticID, params map[string]any, expression ...string) {
+func (r *Result) AddWarningWithID(id DiagnosticID, params Params, expression ...string) {
 	tmpl, ok := diagnosticTemplates[id]
 	if !ok {
 		r.AddWarning(CodeProcessing, string(id), expression...)
@@ -390,7 +1269,7 @@ func (r *Result) AddWarningWithID(id DiagnosticID, params map[string]any, expres
 }
 
 // AddInfoWithID adds an informational message using a diagnostic template.
-func (r *Result) AddInfoWithID(id DiagnosticID, params map[string]any, expression ...string) {
+func (r *Result) AddInfoWithID(id DiagnosticID, params Params, expression ...string) {
 	tmpl, ok := diagnosticTemplates[id]
 	if !ok {
 		r.AddInfo(CodeInformational, string(id), expression...)
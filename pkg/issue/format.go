@@ -0,0 +1,115 @@
+package issue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// segment is a piece of a compiled template: literal text, or a placeholder
+// when key is set (text then holds the original "{key}" for missing params).
+type segment struct {
+	text string
+	key  string
+}
+
+// compiledTemplates caches templates split into segments, keyed by template text.
+var compiledTemplates sync.Map // map[string][]segment
+
+// formatBufPool holds scratch buffers for formatting diagnostics.
+var formatBufPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 256)
+		return &buf
+	},
+}
+
+// compileTemplate splits a template into literal and placeholder segments.
+func compileTemplate(template string) []segment {
+	if segs, ok := compiledTemplates.Load(template); ok {
+		return segs.([]segment)
+	}
+
+	var segs []segment
+	rest := template
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			break
+		}
+		end += open
+		if open > 0 {
+			segs = append(segs, segment{text: rest[:open]})
+		}
+		segs = append(segs, segment{text: rest[open : end+1], key: rest[open+1 : end]})
+		rest = rest[end+1:]
+	}
+	if rest != "" {
+		segs = append(segs, segment{text: rest})
+	}
+
+	compiledTemplates.Store(template, segs)
+	return segs
+}
+
+// formatTemplate replaces {placeholder} with values from params. Placeholders
+// without a value are left as is. Substituted values are not expanded again.
+func formatTemplate(template string, params Params) string {
+	if len(params) == 0 {
+		return template
+	}
+
+	bp := formatBufPool.Get().(*[]byte)
+	buf := (*bp)[:0]
+	for _, seg := range compileTemplate(template) {
+		if seg.key == "" {
+			buf = append(buf, seg.text...)
+			continue
+		}
+		param, ok := params.Get(seg.key)
+		if !ok {
+			buf = append(buf, seg.text...)
+			continue
+		}
+		buf = param.appendTo(buf)
+	}
+	out := string(buf)
+
+	*bp = buf
+	formatBufPool.Put(bp)
+	return out
+}
+
+// appendTo appends the fmt.Sprint form of the parameter's value, without
+// going through fmt for the types diagnostics use most.
+func (p Param) appendTo(buf []byte) []byte {
+	switch p.kind {
+	case paramString:
+		return append(buf, p.str...)
+	case paramInt:
+		return strconv.AppendInt(buf, p.num, 10)
+	}
+	switch v := p.value.(type) {
+	case string:
+		return append(buf, v...)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case float64:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case error:
+		return append(buf, v.Error()...)
+	case fmt.Stringer:
+		return append(buf, v.String()...)
+	default:
+		return fmt.Append(buf, v)
+	}
+}
@@ -0,0 +1,60 @@
+package issue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFormatTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   Params
+		want     string
+	}{
+		{"no params", "Element '{path}' is required", nil, "Element '{path}' is required"},
+		{"string and int", "Minimum cardinality of '{path}' is {min}", Params{String("path", "Patient.name"), Int("min", 1)}, "Minimum cardinality of 'Patient.name' is 1"},
+		{"repeated placeholder", "{a}-{a}", Params{String("a", "x")}, "x-x"},
+		{"missing param kept", "{a} and {b}", Params{Any("a", true)}, "true and {b}"},
+		{"values are not expanded", "{a} {b}", Params{String("a", "{b}"), String("b", "B")}, "{b} B"},
+		{"negative int", "{v}", Params{Int("v", -3)}, "-3"},
+		{"float", "{v}", Params{Any("v", 1.5)}, "1.5"},
+		{"error", "failed: {error}", Params{Any("error", errors.New("boom"))}, "failed: boom"},
+		{"stringer", "after {timeout}", Params{Any("timeout", 2*time.Second)}, "after 2s"},
+		{"other types", "{list}", Params{Any("list", []string{"a", "b"})}, "[a b]"},
+		{"unterminated brace", "open { brace", Params{Int("x", 1)}, "open { brace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatTemplate(tt.template, tt.params); got != tt.want {
+				t.Errorf("formatTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkAddWithID(b *testing.B) {
+	r := GetPooledResult()
+	defer ReleaseResult(r)
+	b.ReportAllocs()
+	for b.Loop() {
+		r.Issues = r.Issues[:0]
+		r.AddErrorWithID(DiagCardinalityMin, Params{String("path", "Patient.name"), Int("min", 1), Int("count", 0)}, "Patient.name")
+		r.AddWarningWithID(DiagConstraintEvalError, Params{String("key", "pat-1"), String("error", "boom")})
+	}
+}
+
+func TestAddWithIDAllocations(t *testing.T) {
+	r := NewResult()
+	r.Issues = make([]Issue, 0, 2000)
+	path := "Patient.name"
+	allocs := testing.AllocsPerRun(1000, func() {
+		r.AddErrorWithID(DiagCardinalityMin, Params{String("path", path), Int("min", 1), Int("count", 0)}, path)
+	})
+	// The formatted message and the expression slice; the params stay on the stack
+	if allocs > 2 {
+		t.Errorf("AddErrorWithID() allocs = %v, want at most 2", allocs)
+	}
+}
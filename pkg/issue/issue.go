@@ -69,11 +69,22 @@ type Issue struct {
 	// Location contains line and column information
 	Location *Location
 
-	// Source identifies the validation phase that generated this issue
-	Source string
+	// Source identifies the phase and the definition whose rule produced the
+	// issue, when known.
+	Source *Source
 
 	// MessageID is the identifier from the error catalog
 	MessageID string
+
+	// Profile is the canonical URL of the profile whose rule produced the
+	// issue: for a constraint, the profile in the baseDefinition chain that
+	// defined it; otherwise the profile validated against. Empty for issues
+	// not tied to a profile, such as JSON syntax errors.
+	Profile string
+
+	// SystemVersion is the code system, as "system|version", that a binding
+	// issue's code was checked against, when its version is known.
+	SystemVersion string
 }
 
 // Location represents the position in the source JSON.
@@ -86,18 +97,98 @@ type Location struct {
 type Stats struct {
 	// ResourceType is the type of resource validated
 	ResourceType string
+	// ResourceID is the id of the resource validated, if any
+	ResourceID string
 	// ResourceSize is the size of the input in bytes
 	ResourceSize int
 	// ProfileURL is the profile used for validation
 	ProfileURL string
 	// IsCustomProfile indicates if a custom profile was used (vs core)
 	IsCustomProfile bool
+	// AppliedProfiles lists the profiles the resource was validated
+	// against: the resolved profiles, or else the core definition of its type
+	AppliedProfiles []string
+	// UnresolvedProfiles lists the requested and declared profiles that
+	// could not be resolved
+	UnresolvedProfiles []string
+	// ProfilesEvaluated lists the profiles validated against in order of
+	// precedence, with where each was asked for and whether the resource
+	// conforms to it
+	ProfilesEvaluated []ProfileOutcome
 	// Duration is the total validation time
 	Duration int64 // nanoseconds
-	// ElementsChecked is the number of elements validated
+	// ElementsChecked is the number of elements validated: the resource
+	// itself, each object member, and each item of a repeating member
 	ElementsChecked int
+	// PhaseIssues counts the issues reported by each validation phase
+	// (e.g., "binding"), keyed by phase name
+	PhaseIssues map[string]int
+	// MustSupport is the share of mustSupport elements of the applied
+	// profiles that the resource populates, among those whose parent is
+	// present
+	MustSupport Coverage
+	// Terminology is the share of coded values checked against their
+	// bindings rather than accepted unchecked (unknown ValueSet, or a code
+	// system needing a terminology server that is not available)
+	Terminology Coverage
+	// Score is the data quality score from 0 to 100 (see
+	// validator.WithQualityScoring), set when Scored is true
+	Score  float64
+	Scored bool
 	// PhasesRun is the number of validation phases executed
 	PhasesRun int
+	// SkippedPhases lists phases skipped by fast-path pre-scans
+	// (e.g., "extension" when the resource has no extensions) or disabled
+	// in the validator configuration
+	SkippedPhases []string
+	// IncompletePhases lists phases cut short by a phase timeout or by
+	// cancellation of the validation context
+	IncompletePhases []string
+}
+
+// Coverage is how many of Total items were Covered.
+type Coverage struct {
+	Total   int `json:"total"`
+	Covered int `json:"covered"`
+}
+
+// Ratio returns Covered/Total, or 1 when there is nothing to cover.
+func (c Coverage) Ratio() float64 {
+	if c.Total == 0 {
+		return 1
+	}
+	return float64(c.Covered) / float64(c.Total)
+}
+
+// Add returns the sum of c and o.
+func (c Coverage) Add(o Coverage) Coverage {
+	return Coverage{Total: c.Total + o.Total, Covered: c.Covered + o.Covered}
+}
+
+// ProfileOrigin tells where a profile a resource was validated against was
+// asked for.
+type ProfileOrigin string
+
+// Profile origins, from the highest precedence to the lowest.
+const (
+	ProfileOriginCall   ProfileOrigin = "call"   // Per-call option (validator.ValidateWithProfile)
+	ProfileOriginConfig ProfileOrigin = "config" // Validator option (validator.WithProfile, -ig)
+	ProfileOriginMeta   ProfileOrigin = "meta"   // Declared in meta.profile
+	ProfileOriginGlobal ProfileOrigin = "global" // ImplementationGuide global profile
+	ProfileOriginPolicy ProfileOrigin = "policy" // Profiles of the resource type's policy
+	ProfileOriginBase   ProfileOrigin = "base"   // Core definition of the resource type
+)
+
+// ProfileOutcome is the outcome of validating a resource against one
+// profile.
+type ProfileOutcome struct {
+	URL    string        `json:"url"`
+	Origin ProfileOrigin `json:"origin"`
+	// Valid reports whether no errors were found against the profile, after
+	// severity overrides, suppressions and strict policies
+	Valid bool `json:"valid"`
+	// Errors is the number of errors found against the profile
+	Errors int `json:"errors"`
 }
 
 // DurationMs returns the duration in milliseconds.
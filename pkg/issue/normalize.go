@@ -0,0 +1,89 @@
+package issue
+
+import (
+	"slices"
+	"strings"
+)
+
+// severityRank orders severities from most to least severe.
+var severityRank = map[Severity]int{
+	SeverityFatal:       0,
+	SeverityError:       1,
+	SeverityWarning:     2,
+	SeverityInformation: 3,
+}
+
+// Normalize sorts issues by path, severity, code and message, and removes
+// semantically identical issues: those with the same severity, code, first
+// expression and message, such as a problem reported by two phases or
+// against two profiles. Resource-level issues without an expression sort
+// first; array indexes in paths compare numerically.
+func (r *Result) Normalize() {
+	slices.SortStableFunc(r.Issues, compareIssues)
+	r.Issues = slices.CompactFunc(r.Issues, func(a, b Issue) bool {
+		return a.Severity == b.Severity &&
+			a.Code == b.Code &&
+			a.Diagnostics == b.Diagnostics &&
+			firstExpression(a) == firstExpression(b)
+	})
+}
+
+// compareIssues orders two issues for Normalize.
+func compareIssues(a, b Issue) int {
+	if c := comparePaths(firstExpression(a), firstExpression(b)); c != 0 {
+		return c
+	}
+	if c := severityRank[a.Severity] - severityRank[b.Severity]; c != 0 {
+		return c
+	}
+	if c := strings.Compare(string(a.Code), string(b.Code)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Diagnostics, b.Diagnostics)
+}
+
+// firstExpression returns the issue's primary FHIRPath expression, if any.
+func firstExpression(i Issue) string {
+	if len(i.Expression) == 0 {
+		return ""
+	}
+	return i.Expression[0]
+}
+
+// comparePaths compares FHIRPath expressions lexically, except that runs of
+// digits compare by value so that name[2] sorts before name[10].
+func comparePaths(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, nb := digitRun(a), digitRun(b)
+			// Without leading zeros, the longer run is the larger number
+			ta, tb := strings.TrimLeft(a[:na], "0"), strings.TrimLeft(b[:nb], "0")
+			if len(ta) != len(tb) {
+				return len(ta) - len(tb)
+			}
+			if c := strings.Compare(ta, tb); c != 0 {
+				return c
+			}
+			a, b = a[na:], b[nb:]
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+// digitRun returns the length of the run of digits at the start of s.
+func digitRun(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,68 @@
+package issue
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	r := NewResult()
+	r.AddWarning(CodeValue, "name[10] warning", "Patient.name[10]")
+	r.AddError(CodeStructure, "Unknown element 'foo'", "Patient.foo")
+	r.AddError(CodeValue, "name[2] error", "Patient.name[2]")
+	r.AddWarning(CodeValue, "name[2] warning", "Patient.name[2]")
+	r.AddError(CodeStructure, "Unknown element 'foo'", "Patient.foo") // reported again by another phase
+	r.AddError(CodeStructure, "Unknown element 'foo'")                // same message, no path: not a duplicate
+	r.AddInfo(CodeInformational, "resource-level info")
+
+	r.Normalize()
+
+	want := []string{
+		"Unknown element 'foo'",
+		"resource-level info",
+		"Unknown element 'foo'",
+		"name[2] error",
+		"name[2] warning",
+		"name[10] warning",
+	}
+	if len(r.Issues) != len(want) {
+		t.Fatalf("Normalize() left %d issues, want %d: %v", len(r.Issues), len(want), r.Issues)
+	}
+	for i, w := range want {
+		if r.Issues[i].Diagnostics != w {
+			t.Errorf("Issues[%d] = %q, want %q", i, r.Issues[i].Diagnostics, w)
+		}
+	}
+	if r.Issues[0].Severity != SeverityError || len(r.Issues[0].Expression) != 0 {
+		t.Errorf("resource-level error should sort before information: %v", r.Issues[:2])
+	}
+}
+
+func TestComparePaths(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // sign only
+	}{
+		{"Patient.name[2]", "Patient.name[10]", -1},
+		{"Patient.name[10].given[0]", "Patient.name[9].given[1]", 1},
+		{"Patient.name[02]", "Patient.name[2]", 0},
+		{"Patient.address", "Patient.name", -1},
+		{"Patient.name", "Patient.name[0]", -1},
+		{"", "Patient", -1},
+	}
+	for _, tt := range tests {
+		got := comparePaths(tt.a, tt.b)
+		if sign(got) != tt.want {
+			t.Errorf("comparePaths(%q, %q) = %d, want sign %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
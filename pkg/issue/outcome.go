@@ -0,0 +1,60 @@
+package issue
+
+// Extension URLs of the issue position in the source, as the HL7 validator
+// reports it.
+const (
+	extIssueLine = "http://hl7.org/fhir/StructureDefinition/operationoutcome-issue-line"
+	extIssueCol  = "http://hl7.org/fhir/StructureDefinition/operationoutcome-issue-col"
+)
+
+// OperationOutcome is the FHIR OperationOutcome form of a Result, ready to be
+// encoded as JSON.
+type OperationOutcome struct {
+	ResourceType string         `json:"resourceType"`
+	Issue        []OutcomeIssue `json:"issue"`
+}
+
+// OutcomeIssue is an OperationOutcome.issue.
+type OutcomeIssue struct {
+	Extension   []OutcomeExtension `json:"extension,omitempty"`
+	Severity    Severity           `json:"severity"`
+	Code        Code               `json:"code"`
+	Diagnostics string             `json:"diagnostics,omitempty"`
+	Expression  []string           `json:"expression,omitempty"`
+}
+
+// OutcomeExtension is an integer extension of an OutcomeIssue.
+type OutcomeExtension struct {
+	URL          string `json:"url"`
+	ValueInteger int    `json:"valueInteger"`
+}
+
+// OperationOutcome converts the issues to an OperationOutcome. Since an
+// OperationOutcome needs at least one issue, a Result without issues gives a
+// single informational one.
+func (r *Result) OperationOutcome() *OperationOutcome {
+	oo := &OperationOutcome{ResourceType: "OperationOutcome", Issue: make([]OutcomeIssue, 0, max(len(r.Issues), 1))}
+	for _, iss := range r.Issues {
+		oi := OutcomeIssue{
+			Severity:    iss.Severity,
+			Code:        iss.Code,
+			Diagnostics: iss.Diagnostics,
+			Expression:  iss.Expression,
+		}
+		if iss.Location != nil {
+			oi.Extension = []OutcomeExtension{
+				{URL: extIssueLine, ValueInteger: iss.Location.Line},
+				{URL: extIssueCol, ValueInteger: iss.Location.Column},
+			}
+		}
+		oo.Issue = append(oo.Issue, oi)
+	}
+	if len(oo.Issue) == 0 {
+		oo.Issue = append(oo.Issue, OutcomeIssue{
+			Severity:    SeverityInformation,
+			Code:        CodeInformational,
+			Diagnostics: "No issues detected during validation",
+		})
+	}
+	return oo
+}
@@ -0,0 +1,34 @@
+package issue
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOperationOutcome(t *testing.T) {
+	r := NewResult()
+	r.AddIssue(Issue{
+		Severity:    SeverityError,
+		Code:        CodeRequired,
+		Diagnostics: "Patient.name: minimum required = 1",
+		Expression:  []string{"Patient.name"},
+		Location:    &Location{Line: 3, Column: 5},
+	})
+
+	data, err := json.Marshal(r.OperationOutcome())
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	want := `{"resourceType":"OperationOutcome","issue":[{"extension":[` +
+		`{"url":"http://hl7.org/fhir/StructureDefinition/operationoutcome-issue-line","valueInteger":3},` +
+		`{"url":"http://hl7.org/fhir/StructureDefinition/operationoutcome-issue-col","valueInteger":5}],` +
+		`"severity":"error","code":"required","diagnostics":"Patient.name: minimum required = 1","expression":["Patient.name"]}]}`
+	if string(data) != want {
+		t.Errorf("OperationOutcome() =\n%s\nwant\n%s", data, want)
+	}
+
+	oo := NewResult().OperationOutcome()
+	if len(oo.Issue) != 1 || oo.Issue[0].Severity != SeverityInformation {
+		t.Errorf("OperationOutcome() without issues = %+v, want one information issue", oo.Issue)
+	}
+}
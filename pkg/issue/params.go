@@ -0,0 +1,72 @@
+package issue
+
+// paramKind tells which field of a Param holds its value.
+type paramKind uint8
+
+const (
+	paramString paramKind = iota
+	paramInt
+	paramAny
+)
+
+// Param is the value of one {placeholder} of a diagnostic template. Strings
+// and integers are kept in typed fields, so building Params for the common
+// diagnostics does not box values into interfaces.
+type Param struct {
+	key   string
+	kind  paramKind
+	str   string
+	num   int64
+	value any
+}
+
+// Params holds the values of a diagnostic's placeholders, e.g.
+//
+//	issue.Params{issue.String("path", "Patient.name"), issue.Int("min", 1)}
+//
+// Params are read while formatting and never retained, so a literal passed
+// to AddErrorWithID and the like does not escape to the heap.
+type Params []Param
+
+// String returns a string parameter.
+func String(key, value string) Param {
+	return Param{key: key, kind: paramString, str: value}
+}
+
+// Int returns an integer parameter.
+func Int(key string, value int) Param {
+	return Param{key: key, kind: paramInt, num: int64(value)}
+}
+
+// Any returns a parameter formatted like fmt.Sprint (errors and
+// fmt.Stringers use their String or Error method).
+func Any(key string, value any) Param {
+	return Param{key: key, kind: paramAny, value: value}
+}
+
+// Key returns the placeholder the parameter fills.
+func (p Param) Key() string {
+	return p.key
+}
+
+// Value returns the parameter's value.
+func (p Param) Value() any {
+	switch p.kind {
+	case paramString:
+		return p.str
+	case paramInt:
+		return int(p.num)
+	default:
+		return p.value
+	}
+}
+
+// Get returns the parameter for a placeholder.
+func (ps Params) Get(key string) (Param, bool) {
+	for _, p := range ps {
+		if p.key == key {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
@@ -0,0 +1,104 @@
+package issue
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResultPolicy decides whether validation results are acceptable, for gating
+// CI pipelines on issue severity and warning budgets. The zero value fails
+// on any warning; use DefaultResultPolicy for the usual errors-only gate.
+type ResultPolicy struct {
+	// FailOn is the least severe severity that fails the results
+	// (default SeverityError; fatal issues always fail).
+	FailOn Severity
+
+	// MaxWarnings is the number of warnings tolerated across all results;
+	// negative means no limit.
+	MaxWarnings int
+}
+
+// PolicyVerdict is the outcome of evaluating a ResultPolicy.
+type PolicyVerdict struct {
+	Passed   bool
+	Reason   string // Why the results failed; empty when they passed
+	Errors   int    // Error and fatal issues
+	Warnings int
+	Info     int
+}
+
+// DefaultResultPolicy fails on errors and tolerates any number of warnings.
+func DefaultResultPolicy() ResultPolicy {
+	return ResultPolicy{FailOn: SeverityError, MaxWarnings: -1}
+}
+
+// ParseSeverity parses a severity name: fatal, error, warning, or
+// information (also "info"), case-insensitively.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "fatal":
+		return SeverityFatal, nil
+	case "error":
+		return SeverityError, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "information", "info":
+		return SeverityInformation, nil
+	}
+	return "", fmt.Errorf("unknown severity %q (use fatal, error, warning or info)", s)
+}
+
+// Tally accumulates the issue counts of results, so that a ResultPolicy can
+// judge a stream of results without keeping them.
+type Tally struct {
+	Fatal    int
+	Errors   int // Error and fatal issues
+	Warnings int
+	Info     int
+}
+
+// Add counts the issues of r. A nil result is ignored.
+func (t *Tally) Add(r *Result) {
+	if r == nil {
+		return
+	}
+	t.Fatal += len(r.Filter(SeverityFatal).Issues)
+	t.Errors += r.ErrorCount()
+	t.Warnings += r.WarningCount()
+	t.Info += r.InfoCount()
+}
+
+// Evaluate applies the policy to the combined issues of results. Nil
+// results are ignored.
+func (p ResultPolicy) Evaluate(results ...*Result) PolicyVerdict {
+	var t Tally
+	for _, r := range results {
+		t.Add(r)
+	}
+	return p.EvaluateTally(t)
+}
+
+// EvaluateTally applies the policy to the issue counts of a Tally.
+func (p ResultPolicy) EvaluateTally(t Tally) PolicyVerdict {
+	v := PolicyVerdict{Errors: t.Errors, Warnings: t.Warnings, Info: t.Info}
+
+	failOn, ok := severityRank[p.FailOn]
+	if !ok {
+		failOn = severityRank[SeverityError]
+	}
+
+	switch {
+	case t.Fatal > 0:
+		v.Reason = fmt.Sprintf("%d fatal issue(s)", t.Fatal)
+	case v.Errors > 0 && failOn >= severityRank[SeverityError]:
+		v.Reason = fmt.Sprintf("%d error(s)", v.Errors)
+	case v.Warnings > 0 && failOn >= severityRank[SeverityWarning]:
+		v.Reason = fmt.Sprintf("%d warning(s) with fail-on %s", v.Warnings, p.FailOn)
+	case v.Info > 0 && failOn >= severityRank[SeverityInformation]:
+		v.Reason = fmt.Sprintf("%d information issue(s) with fail-on %s", v.Info, p.FailOn)
+	case p.MaxWarnings >= 0 && v.Warnings > p.MaxWarnings:
+		v.Reason = fmt.Sprintf("%d warning(s) exceed the maximum of %d", v.Warnings, p.MaxWarnings)
+	}
+	v.Passed = v.Reason == ""
+	return v
+}
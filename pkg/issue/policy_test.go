@@ -0,0 +1,81 @@
+package issue
+
+import (
+	"testing"
+)
+
+func TestResultPolicyEvaluate(t *testing.T) {
+	clean := NewResult()
+	clean.AddInfo(CodeInformational, "note")
+
+	warned := NewResult()
+	warned.AddWarning(CodeInvariant, "dom-6", "Patient")
+	warned.AddWarning(CodeValue, "unknown code", "Patient.gender")
+
+	failed := NewResult()
+	failed.AddError(CodeRequired, "missing status", "Observation.status")
+
+	tests := []struct {
+		name    string
+		policy  ResultPolicy
+		results []*Result
+		pass    bool
+	}{
+		{"default passes warnings", DefaultResultPolicy(), []*Result{clean, warned}, true},
+		{"default fails errors", DefaultResultPolicy(), []*Result{clean, failed}, false},
+		{"fail on warning", ResultPolicy{FailOn: SeverityWarning, MaxWarnings: -1}, []*Result{warned}, false},
+		{"fail on info", ResultPolicy{FailOn: SeverityInformation, MaxWarnings: -1}, []*Result{clean}, false},
+		{"within warning budget", ResultPolicy{FailOn: SeverityError, MaxWarnings: 2}, []*Result{warned}, true},
+		{"budget spans results", ResultPolicy{FailOn: SeverityError, MaxWarnings: 3}, []*Result{warned, warned}, false},
+		{"zero value fails on any warning", ResultPolicy{}, []*Result{warned}, false},
+		{"zero value passes info", ResultPolicy{}, []*Result{clean}, true},
+		{"fail on fatal ignores errors", ResultPolicy{FailOn: SeverityFatal, MaxWarnings: -1}, []*Result{failed}, true},
+		{"nil results ignored", DefaultResultPolicy(), []*Result{nil, clean}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.policy.Evaluate(tt.results...)
+			if v.Passed != tt.pass {
+				t.Errorf("Evaluate() passed = %v, want %v (reason %q)", v.Passed, tt.pass, v.Reason)
+			}
+			if v.Passed != (v.Reason == "") {
+				t.Errorf("Evaluate() reason %q inconsistent with passed = %v", v.Reason, v.Passed)
+			}
+
+			var tally Tally
+			for _, r := range tt.results {
+				tally.Add(r)
+			}
+			if got := tt.policy.EvaluateTally(tally); got != v {
+				t.Errorf("EvaluateTally() = %+v, want %+v", got, v)
+			}
+		})
+	}
+}
+
+func TestResultPolicyFatalAlwaysFails(t *testing.T) {
+	r := NewResult()
+	r.AddIssue(Issue{Severity: SeverityFatal, Code: CodeStructure, Diagnostics: "not JSON"})
+
+	v := ResultPolicy{FailOn: SeverityFatal, MaxWarnings: -1}.Evaluate(r)
+	if v.Passed || v.Errors != 1 {
+		t.Errorf("Evaluate() = %+v, want failure with 1 error", v)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for in, want := range map[string]Severity{
+		"error":   SeverityError,
+		"Warning": SeverityWarning,
+		"info":    SeverityInformation,
+		"fatal":   SeverityFatal,
+	} {
+		got, err := ParseSeverity(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSeverity(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseSeverity("severe"); err == nil {
+		t.Error("ParseSeverity(\"severe\") should fail")
+	}
+}
@@ -0,0 +1,88 @@
+package issue
+
+import "strings"
+
+// Source attributes an issue to the artifact that defines the rule it
+// reports, so that profile-driven errors can be traced back to the
+// StructureDefinition, ValueSet or invariant behind them. Fields are empty
+// when unknown.
+type Source struct {
+	// Phase is the validation phase that reported the issue (e.g.,
+	// "binding").
+	Phase string `json:"phase,omitempty"`
+
+	// StructureDefinition is the canonical URL of the definition of the
+	// element: the profile, or the type definition for elements the profile
+	// does not constrain (e.g., HumanName).
+	StructureDefinition string `json:"structureDefinition,omitempty"`
+
+	// ElementID is the ElementDefinition.id of the element in
+	// StructureDefinition (e.g., "Patient.identifier:mrn").
+	ElementID string `json:"elementId,omitempty"`
+
+	// Constraint is the key of the invariant that failed (e.g.,
+	// "us-core-6").
+	Constraint string `json:"constraint,omitempty"`
+
+	// ValueSet is the canonical URL of the ValueSet of a binding.
+	ValueSet string `json:"valueSet,omitempty"`
+}
+
+// String formats the source as "structureDefinition#elementId", followed by
+// the constraint key or ValueSet, e.g.,
+// "http://hl7.org/fhir/StructureDefinition/Patient#Patient (pat-1)".
+func (s *Source) String() string {
+	var b strings.Builder
+	b.WriteString(s.StructureDefinition)
+	if s.ElementID != "" {
+		b.WriteString("#" + s.ElementID)
+	}
+	switch {
+	case s.Constraint != "":
+		b.WriteString(" (" + s.Constraint + ")")
+	case s.ValueSet != "":
+		b.WriteString(" (bound to " + s.ValueSet + ")")
+	}
+	if b.Len() == 0 {
+		return s.Phase
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Attribute fills in the unset Source fields of the issues from index from
+// onwards with those of src. Phases attribute the issues they add at the
+// most specific level first (e.g., a constraint key), and callers further
+// out add what they know (e.g., the profile).
+func (r *Result) Attribute(from int, src Source) {
+	for i := max(from, 0); i < len(r.Issues); i++ {
+		r.Issues[i].Attribute(src)
+	}
+}
+
+// Attribute fills in the unset Source fields of the issue with those of src.
+func (i *Issue) Attribute(src Source) {
+	if i.Source == nil {
+		i.Source = &src
+		return
+	}
+	i.Source.fill(src)
+}
+
+// fill sets the empty fields of s from src.
+func (s *Source) fill(src Source) {
+	if s.Phase == "" {
+		s.Phase = src.Phase
+	}
+	if s.StructureDefinition == "" {
+		s.StructureDefinition = src.StructureDefinition
+	}
+	if s.ElementID == "" {
+		s.ElementID = src.ElementID
+	}
+	if s.Constraint == "" {
+		s.Constraint = src.Constraint
+	}
+	if s.ValueSet == "" {
+		s.ValueSet = src.ValueSet
+	}
+}
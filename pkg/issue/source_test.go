@@ -0,0 +1,23 @@
+package issue
+
+import "testing"
+
+func TestAttribute(t *testing.T) {
+	r := NewResult()
+	r.AddError(CodeInvariant, "outer", "Patient")
+	before := len(r.Issues)
+	r.AddError(CodeInvariant, "inner", "Patient")
+	r.Attribute(before, Source{Constraint: "pat-1"})
+	r.Attribute(0, Source{Phase: "constraint", StructureDefinition: "http://example.org/Patient", ElementID: "Patient", Constraint: "other"})
+
+	if got := r.Issues[0].Source; got == nil || got.Constraint != "other" || got.Phase != "constraint" {
+		t.Errorf("outer source = %+v", got)
+	}
+	got := r.Issues[1].Source
+	if got == nil || got.Constraint != "pat-1" || got.ElementID != "Patient" || got.Phase != "constraint" {
+		t.Fatalf("inner source = %+v, want the constraint kept and the rest filled in", got)
+	}
+	if want := "http://example.org/Patient#Patient (pat-1)"; got.String() != want {
+		t.Errorf("String() = %q, want %q", got.String(), want)
+	}
+}
@@ -0,0 +1,88 @@
+package issue
+
+import "math"
+
+// Summary aggregates the results of a batch run into data quality figures
+// for dashboards: issue counts by severity, phase and diagnostic, coverage
+// totals, and the spread of quality scores. The zero value is ready to use;
+// Summary is not safe for concurrent use.
+type Summary struct {
+	Resources int `json:"resources"`
+	Valid     int `json:"valid"`
+	// Failed counts resources whose results have errors, or that could not
+	// be validated at all
+	Failed   int `json:"failed"`
+	Errors   int `json:"errors"` // Error and fatal issues
+	Warnings int `json:"warnings"`
+	Info     int `json:"info"`
+
+	ResourceTypes   map[string]int `json:"resourceTypes,omitempty"`
+	PhaseIssues     map[string]int `json:"phaseIssues,omitempty"`
+	Diagnostics     map[string]int `json:"diagnostics,omitempty"` // Issues by MessageID
+	ElementsChecked int            `json:"elementsChecked"`
+	MustSupport     Coverage       `json:"mustSupport"`
+	Terminology     Coverage       `json:"terminology"`
+
+	// MeanScore and MinScore are over the scored resources (see
+	// Stats.Score); both are zero when none were scored.
+	MeanScore float64 `json:"meanScore"`
+	MinScore  float64 `json:"minScore"`
+
+	scored   int
+	scoreSum float64
+}
+
+// Add accumulates r. A nil result counts as a resource that could not be
+// validated.
+func (s *Summary) Add(r *Result) {
+	s.Resources++
+	if r == nil {
+		s.Failed++
+		return
+	}
+	if r.HasErrors() {
+		s.Failed++
+	} else {
+		s.Valid++
+	}
+	s.Errors += r.ErrorCount()
+	s.Warnings += r.WarningCount()
+	s.Info += r.InfoCount()
+
+	for i := range r.Issues {
+		if id := r.Issues[i].MessageID; id != "" {
+			increment(&s.Diagnostics, id, 1)
+		}
+	}
+
+	st := r.Stats
+	if st == nil {
+		return
+	}
+	if st.ResourceType != "" {
+		increment(&s.ResourceTypes, st.ResourceType, 1)
+	}
+	for phase, n := range st.PhaseIssues {
+		increment(&s.PhaseIssues, phase, n)
+	}
+	s.ElementsChecked += st.ElementsChecked
+	s.MustSupport = s.MustSupport.Add(st.MustSupport)
+	s.Terminology = s.Terminology.Add(st.Terminology)
+
+	if st.Scored {
+		if s.scored == 0 || st.Score < s.MinScore {
+			s.MinScore = st.Score
+		}
+		s.scored++
+		s.scoreSum += st.Score
+		s.MeanScore = math.Round(s.scoreSum/float64(s.scored)*10) / 10
+	}
+}
+
+// increment adds n to m[key], allocating m on first use.
+func increment(m *map[string]int, key string, n int) {
+	if *m == nil {
+		*m = make(map[string]int)
+	}
+	(*m)[key] += n
+}
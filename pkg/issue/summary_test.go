@@ -0,0 +1,66 @@
+package issue
+
+import "testing"
+
+func TestSummaryAdd(t *testing.T) {
+	valid := NewResult()
+	valid.Stats = &Stats{
+		ResourceType:    "Patient",
+		ElementsChecked: 10,
+		PhaseIssues:     map[string]int{"binding": 1},
+		MustSupport:     Coverage{Total: 4, Covered: 3},
+		Terminology:     Coverage{Total: 2, Covered: 2},
+		Score:           95,
+		Scored:          true,
+	}
+	valid.AddWarningWithID(DiagBindingExtensible, Params{String("code", "x"), String("valueSet", "vs")}, "Patient.gender")
+
+	invalid := NewResult()
+	invalid.Stats = &Stats{
+		ResourceType:    "Observation",
+		ElementsChecked: 5,
+		PhaseIssues:     map[string]int{"binding": 1, "cardinality": 1},
+		MustSupport:     Coverage{Total: 2},
+		Terminology:     Coverage{Total: 2, Covered: 1},
+		Score:           60,
+		Scored:          true,
+	}
+	invalid.AddErrorWithID(DiagBindingRequired, Params{String("code", "x"), String("valueSet", "vs")}, "Observation.status")
+	invalid.AddError(CodeRequired, "missing code", "Observation.code")
+
+	var s Summary
+	s.Add(valid)
+	s.Add(invalid)
+	s.Add(nil)
+
+	if s.Resources != 3 || s.Valid != 1 || s.Failed != 2 {
+		t.Errorf("resources/valid/failed = %d/%d/%d, want 3/1/2", s.Resources, s.Valid, s.Failed)
+	}
+	if s.Errors != 2 || s.Warnings != 1 {
+		t.Errorf("errors/warnings = %d/%d, want 2/1", s.Errors, s.Warnings)
+	}
+	if s.ResourceTypes["Patient"] != 1 || s.ResourceTypes["Observation"] != 1 {
+		t.Errorf("ResourceTypes = %v", s.ResourceTypes)
+	}
+	if s.PhaseIssues["binding"] != 2 || s.PhaseIssues["cardinality"] != 1 {
+		t.Errorf("PhaseIssues = %v", s.PhaseIssues)
+	}
+	if s.Diagnostics[string(DiagBindingRequired)] != 1 || s.Diagnostics[string(DiagBindingExtensible)] != 1 {
+		t.Errorf("Diagnostics = %v", s.Diagnostics)
+	}
+	if s.ElementsChecked != 15 || s.MustSupport != (Coverage{Total: 6, Covered: 3}) || s.Terminology != (Coverage{Total: 4, Covered: 3}) {
+		t.Errorf("elements/mustSupport/terminology = %d/%+v/%+v", s.ElementsChecked, s.MustSupport, s.Terminology)
+	}
+	if s.MeanScore != 77.5 || s.MinScore != 60 {
+		t.Errorf("MeanScore, MinScore = %v, %v, want 77.5, 60", s.MeanScore, s.MinScore)
+	}
+}
+
+func TestCoverageRatio(t *testing.T) {
+	if r := (Coverage{}).Ratio(); r != 1 {
+		t.Errorf("empty Ratio() = %v, want 1", r)
+	}
+	if r := (Coverage{Total: 4, Covered: 1}).Ratio(); r != 0.25 {
+		t.Errorf("Ratio() = %v, want 0.25", r)
+	}
+}
@@ -0,0 +1,77 @@
+package issue
+
+import "strings"
+
+// Suppression removes matching issues from results. Every non-empty field
+// must match; a Suppression with no fields set matches nothing.
+type Suppression struct {
+	ID       DiagnosticID // Diagnostic ID, e.g. CONSTRAINT_FAILED
+	Code     Code         // Issue code, e.g. invariant
+	Path     string       // FHIRPath of the element; also matches its descendants
+	Contains string       // Text the (English) message must contain, e.g. "dom-6"
+}
+
+// Matches reports whether the suppression applies to an issue.
+func (s Suppression) Matches(i Issue) bool {
+	if s == (Suppression{}) {
+		return false
+	}
+	if s.ID != "" && DiagnosticID(i.MessageID) != s.ID {
+		return false
+	}
+	if s.Code != "" && i.Code != s.Code {
+		return false
+	}
+	if s.Contains != "" && !strings.Contains(i.Diagnostics, s.Contains) {
+		return false
+	}
+	if s.Path != "" && !pathWithin(firstExpression(i), s.Path) {
+		return false
+	}
+	return true
+}
+
+// Suppress removes the issues matched by any of the suppressions.
+func (r *Result) Suppress(suppressions []Suppression) {
+	if len(suppressions) == 0 {
+		return
+	}
+	kept := r.Issues[:0]
+	for _, iss := range r.Issues {
+		suppressed := false
+		for _, s := range suppressions {
+			if s.Matches(iss) {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			kept = append(kept, iss)
+		}
+	}
+	clear(r.Issues[len(kept):])
+	r.Issues = kept
+}
+
+// OverrideSeverities changes the severity of issues by diagnostic ID.
+func (r *Result) OverrideSeverities(overrides map[DiagnosticID]Severity) {
+	if len(overrides) == 0 {
+		return
+	}
+	for i := range r.Issues {
+		if sev, ok := overrides[DiagnosticID(r.Issues[i].MessageID)]; ok {
+			r.Issues[i].Severity = sev
+		}
+	}
+}
+
+// pathWithin reports whether path is prefix or one of its descendants:
+// Patient.name matches Patient.name, Patient.name[0] and
+// Patient.name[0].given, but not Patient.nameSuffix.
+func pathWithin(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest := path[len(prefix):]
+	return rest == "" || rest[0] == '.' || rest[0] == '['
+}
@@ -0,0 +1,45 @@
+package issue
+
+import (
+	"testing"
+)
+
+func TestSuppress(t *testing.T) {
+	r := NewResult()
+	r.AddWarningWithID(DiagConstraintFailed, Params{
+		String("details", "Constraint failed: dom-6: 'A resource should have narrative for robust management'"),
+	}, "Patient")
+	r.AddError(CodeValue, "bad given", "Patient.name[0].given[0]")
+	r.AddError(CodeValue, "bad suffix", "Patient.nameSuffix")
+	r.AddError(CodeStructure, "no path")
+
+	r.Suppress([]Suppression{
+		{ID: DiagConstraintFailed, Contains: "dom-6"},
+		{Path: "Patient.name"},
+		{}, // matches nothing
+	})
+
+	var got []string
+	for _, iss := range r.Issues {
+		got = append(got, iss.Diagnostics)
+	}
+	want := []string{"bad suffix", "no path"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Suppress() left %q, want %q", got, want)
+	}
+}
+
+func TestOverrideSeverities(t *testing.T) {
+	r := NewResult()
+	r.AddWarningWithID(DiagConstraintFailed, Params{String("details", "Constraint failed: dom-6")}, "Patient")
+	r.AddWarning(CodeValue, "no ID")
+
+	r.OverrideSeverities(map[DiagnosticID]Severity{DiagConstraintFailed: SeverityInformation})
+
+	if r.Issues[0].Severity != SeverityInformation {
+		t.Errorf("overridden severity = %s, want information", r.Issues[0].Severity)
+	}
+	if r.Issues[1].Severity != SeverityWarning {
+		t.Errorf("issue without diagnostic ID changed to %s", r.Issues[1].Severity)
+	}
+}
@@ -0,0 +1,514 @@
+// Package jsonschema exports StructureDefinitions as JSON Schema (draft
+// 2020-12) documents, for lightweight client-side validation and for use as
+// OpenAPI components.
+//
+// A schema covers what JSON Schema can express of a profile: the properties
+// it allows (with the "_name" companions of primitives and one property per
+// choice type), cardinalities, the regexes of primitive types and maxLength,
+// fixed values as const, pattern values as partial matches, required and
+// bounded slices as contains, and enums for code and Coding elements with
+// required bindings to small ValueSets. Invariants, other bindings and
+// references are not exported; a document that passes the schema may still
+// fail FHIR validation.
+//
+// Datatypes and recursive elements are emitted once under $defs and
+// referenced with $ref; elements the profile constrains are inlined.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/canonical"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// Draft is the JSON Schema dialect of exported schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// maxEnum is the largest expansion exported as an enum.
+const maxEnum = 50
+
+const (
+	systemTypePrefix = "http://hl7.org/fhirpath/System."
+	fhirTypeExt      = "http://hl7.org/fhir/StructureDefinition/structuredefinition-fhir-type"
+	regexExt         = "http://hl7.org/fhir/StructureDefinition/regex"
+)
+
+// CodeSource lists the codes of ValueSets for required bindings.
+// *terminology.Registry implements it.
+type CodeSource interface {
+	EnumerateCodes(valueSetURL string, limit int) ([]string, bool)
+}
+
+// Exporter builds JSON Schemas from the StructureDefinitions in a registry.
+type Exporter struct {
+	registry *registry.Registry
+	codes    CodeSource
+}
+
+// New creates an Exporter. Codes may be nil, in which case no enums are
+// exported.
+func New(reg *registry.Registry, codes CodeSource) *Exporter {
+	return &Exporter{registry: reg, codes: codes}
+}
+
+// Export returns the JSON Schema of the StructureDefinition with the given
+// canonical URL as compact JSON.
+func (e *Exporter) Export(profileURL string) ([]byte, error) {
+	sd := e.registry.GetByURL(profileURL)
+	if sd == nil {
+		return nil, fmt.Errorf("profile %s not found", profileURL)
+	}
+	if sd.Snapshot == nil || len(sd.Snapshot.Element) == 0 {
+		return nil, fmt.Errorf("profile %s has no snapshot", profileURL)
+	}
+
+	b := &builder{Exporter: e, defs: &canonical.Object{}, names: make(map[string]string), used: make(map[string]bool)}
+	root := b.complex(sd, sd.Snapshot.Element[0].ElementID(), sd.Kind == registry.KindResource)
+
+	schema := &canonical.Object{Members: []canonical.Member{
+		{Name: "$schema", Value: Draft},
+		{Name: "$id", Value: sd.URL},
+		{Name: "title", Value: sd.Name},
+	}}
+	schema.Members = append(schema.Members, root.Members...)
+	if len(b.defs.Members) > 0 {
+		schema.Members = append(schema.Members, canonical.Member{Name: "$defs", Value: b.defs})
+	}
+	return canonical.Marshal(schema)
+}
+
+// builder holds the state of one Export call.
+type builder struct {
+	*Exporter
+	defs  *canonical.Object
+	names map[string]string // Definition key to name in $defs
+	used  map[string]bool   // Names taken in $defs
+}
+
+// complex returns the object schema of the children of the element with
+// parentID in sd. Resource roots also get their resourceType.
+func (b *builder) complex(sd *registry.StructureDefinition, parentID string, resource bool) *canonical.Object {
+	props := &canonical.Object{}
+	var required, allOf []any
+	if resource {
+		props.Members = append(props.Members, member("resourceType", object("const", sd.Type)))
+		required = append(required, "resourceType")
+	}
+
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		name, ok := registry.ChildName(elem.ElementID(), parentID)
+		if !ok || elem.Max == "0" || hasMember(props, name) {
+			continue
+		}
+		if base, isChoice := strings.CutSuffix(name, "[x]"); isChoice {
+			if choice := b.choice(sd, elem, base, props); choice != nil {
+				allOf = append(allOf, choice)
+			}
+			continue
+		}
+
+		code := typeCode(elem)
+		primitive := b.registry.IsPrimitiveType(code)
+		schema := b.item(sd, elem, elem.ElementID(), code)
+		var shadow *canonical.Object
+		if primitive {
+			shadow = b.element()
+		}
+		if b.registry.Repeats(elem) {
+			if primitive {
+				// Occurrences with only extensions are null in the value array
+				schema = object("anyOf", []any{schema, object("type", "null")})
+				shadow = object("type", "array", "items", object("anyOf", []any{shadow, object("type", "null")}))
+			}
+			schema = b.array(sd, elem, schema)
+		}
+		props.Members = append(props.Members, member(name, schema))
+		if primitive {
+			props.Members = append(props.Members, member("_"+name, shadow))
+		}
+
+		if elem.Min > 0 {
+			if primitive {
+				// A primitive may have only extensions
+				allOf = append(allOf, object("anyOf", []any{
+					object("required", []any{name}),
+					object("required", []any{"_" + name}),
+				}))
+			} else {
+				required = append(required, name)
+			}
+		}
+	}
+
+	schema := object("type", "object", "properties", props)
+	if len(required) > 0 {
+		schema.Members = append(schema.Members, member("required", required))
+	}
+	if len(allOf) > 0 {
+		schema.Members = append(schema.Members, member("allOf", allOf))
+	}
+	schema.Members = append(schema.Members, member("additionalProperties", false))
+	return schema
+}
+
+// choice adds a property for each type of a choice element to props, e.g.
+// "valueQuantity", and returns the schema that allows at most one of them
+// (exactly one if the element is required), or nil if none is needed.
+func (b *builder) choice(sd *registry.StructureDefinition, elem *registry.ElementDefinition, base string, props *canonical.Object) *canonical.Object {
+	_, fixedType, fixed := elem.GetFixed()
+	if !fixed {
+		_, fixedType, fixed = elem.GetPattern()
+	}
+
+	var branches []any
+	for _, t := range elem.Type {
+		if t.Code == "" || (fixed && !strings.EqualFold(fixedType, t.Code)) {
+			continue
+		}
+		name := base + upperFirst(t.Code)
+		source := elem
+		if slice := sd.SliceNamed(elem.ElementID(), name); slice != nil {
+			source = slice
+		}
+		props.Members = append(props.Members, member(name, b.item(sd, source, source.ElementID(), t.Code)))
+		if b.registry.IsPrimitiveType(t.Code) {
+			props.Members = append(props.Members, member("_"+name, b.element()))
+		}
+		branches = append(branches, object("required", []any{name}))
+	}
+
+	if len(branches) == 0 || (len(branches) == 1 && elem.Min == 0) {
+		return nil
+	}
+	if len(branches) == 1 {
+		return branches[0].(*canonical.Object)
+	}
+	if elem.Min == 0 {
+		branches = append(branches, object("not", object("anyOf", slices.Clone(branches))))
+	}
+	return object("oneOf", branches)
+}
+
+// array returns the schema of a repeating element whose items match item,
+// with its cardinality and the occurrences its slices allow.
+func (b *builder) array(sd *registry.StructureDefinition, elem *registry.ElementDefinition, item *canonical.Object) *canonical.Object {
+	schema := object("type", "array", "items", item)
+	if elem.Min > 0 {
+		schema.Members = append(schema.Members, member("minItems", number(int(elem.Min))))
+	}
+	if n, err := strconv.Atoi(elem.Max); err == nil {
+		schema.Members = append(schema.Members, member("maxItems", number(n)))
+	}
+
+	var contains []any
+	for _, slice := range sd.SlicesOf(elem.ElementID()) {
+		maxSlice, err := strconv.Atoi(slice.Max)
+		if slice.Min == 0 && err != nil {
+			continue
+		}
+		c := object("contains", b.item(sd, slice, slice.ElementID(), typeCode(slice)),
+			"minContains", number(int(slice.Min)))
+		if err == nil {
+			c.Members = append(c.Members, member("maxContains", number(maxSlice)))
+		}
+		contains = append(contains, c)
+	}
+	if len(contains) > 0 {
+		schema.Members = append(schema.Members, member("allOf", contains))
+	}
+	return schema
+}
+
+// item returns the schema of one occurrence of elem with the given type: a
+// const for fixed values, or a reference to its type or its inlined children,
+// narrowed by its pattern, maxLength and required binding.
+func (b *builder) item(sd *registry.StructureDefinition, elem *registry.ElementDefinition, id, code string) *canonical.Object {
+	if raw, _, ok := elem.GetFixed(); ok {
+		if v, err := canonical.Parse(raw); err == nil {
+			return object("const", v)
+		}
+	}
+
+	var schema *canonical.Object
+	switch {
+	case elem.ContentReference != nil && *elem.ContentReference != "":
+		_, target, _ := strings.Cut(*elem.ContentReference, "#")
+		schema = ref(b.define(sd.URL+"#"+target, target, func() *canonical.Object {
+			return b.complex(sd, target, false)
+		}))
+	case strings.HasPrefix(code, systemTypePrefix):
+		schema = b.systemType(elem, code)
+	case b.registry.IsPrimitiveType(code):
+		schema = ref(b.primitiveDef(code))
+	case sd.HasChildren(id):
+		schema = b.complex(sd, id, false)
+	default:
+		schema = b.typeSchema(elem, code)
+	}
+
+	if elem.MaxLength > 0 {
+		schema.Members = append(schema.Members, member("maxLength", number(elem.MaxLength)))
+	}
+	if codes := b.enum(elem, code); codes != nil {
+		if code == "code" {
+			schema.Members = append(schema.Members, member("enum", codes))
+		} else {
+			schema = and(schema, object("properties", object("code", object("enum", codes))))
+		}
+	}
+	if raw, _, ok := elem.GetPattern(); ok {
+		if v, err := canonical.Parse(raw); err == nil {
+			schema = and(schema, pattern(v))
+		}
+	}
+	return schema
+}
+
+// typeSchema returns a reference to the definition of an element's type: its
+// type profile if it declares one, or else the base type. Resources (e.g.,
+// contained ones) are only required to name their type.
+func (b *builder) typeSchema(elem *registry.ElementDefinition, code string) *canonical.Object {
+	var typeSD *registry.StructureDefinition
+	for _, t := range elem.Type {
+		if t.Code == code && len(t.Profile) > 0 {
+			typeSD = b.registry.GetByURL(t.Profile[0])
+			break
+		}
+	}
+	if typeSD == nil {
+		typeSD = b.registry.GetByType(code)
+	}
+	if typeSD == nil || typeSD.Snapshot == nil || len(typeSD.Snapshot.Element) == 0 {
+		return &canonical.Object{}
+	}
+	if typeSD.Kind == registry.KindResource && (typeSD.Abstract || typeSD.Derivation != "constraint") {
+		return object("type", "object",
+			"properties", object("resourceType", object("type", "string")),
+			"required", []any{"resourceType"})
+	}
+	return ref(b.typeDef(typeSD))
+}
+
+// typeDef adds the definition of a datatype or profile to $defs and returns
+// its name.
+func (b *builder) typeDef(typeSD *registry.StructureDefinition) string {
+	if typeSD == nil {
+		return ""
+	}
+	name := typeSD.Type
+	if typeSD.Derivation == "constraint" && typeSD.ID != "" {
+		name = typeSD.ID
+	}
+	return b.define(typeSD.URL, name, func() *canonical.Object {
+		return b.complex(typeSD, typeSD.Snapshot.Element[0].ElementID(), typeSD.Kind == registry.KindResource)
+	})
+}
+
+// element returns the schema of the "_name" companion of a primitive.
+func (b *builder) element() *canonical.Object {
+	return ref(b.typeDef(b.registry.GetByType("Element")))
+}
+
+// primitiveDef adds the definition of a primitive type to $defs and returns
+// its name.
+func (b *builder) primitiveDef(code string) string {
+	return b.define(code, code, func() *canonical.Object {
+		schema := object("type", jsonType(code))
+		switch code {
+		case "positiveInt":
+			schema.Members = append(schema.Members, member("minimum", number(1)))
+		case "unsignedInt":
+			schema.Members = append(schema.Members, member("minimum", number(0)))
+		}
+		if regex := b.regex(code); regex != "" && jsonType(code) == "string" {
+			schema.Members = append(schema.Members, member("pattern", "^(?:"+regex+")$"))
+		}
+		return schema
+	})
+}
+
+// systemType returns the schema of an element with a FHIRPath system type,
+// such as Element.id: the FHIR type it declares with the fhir-type
+// extension, or else the matching JSON type.
+func (b *builder) systemType(elem *registry.ElementDefinition, code string) *canonical.Object {
+	for _, t := range elem.Type {
+		for _, ext := range t.Extension {
+			if ext.URL != fhirTypeExt {
+				continue
+			}
+			if fhirType := ext.ValueURL + ext.ValueURI; b.registry.IsPrimitiveType(fhirType) {
+				return ref(b.primitiveDef(fhirType))
+			}
+		}
+	}
+	switch strings.TrimPrefix(code, systemTypePrefix) {
+	case "Boolean":
+		return object("type", "boolean")
+	case "Integer":
+		return object("type", "integer")
+	case "Decimal":
+		return object("type", "number")
+	}
+	return object("type", "string")
+}
+
+// define adds a definition to $defs under name (made unique) unless one with
+// the same key exists, and returns its name. The definition is registered
+// before it is built so recursive references resolve.
+func (b *builder) define(key, name string, build func() *canonical.Object) string {
+	if existing, ok := b.names[key]; ok {
+		return existing
+	}
+	unique := name
+	for n := 2; b.used[unique]; n++ {
+		unique = name + "-" + strconv.Itoa(n)
+	}
+	b.names[key], b.used[unique] = unique, true
+	idx := len(b.defs.Members)
+	b.defs.Members = append(b.defs.Members, canonical.Member{Name: unique})
+	b.defs.Members[idx].Value = build()
+	return unique
+}
+
+// enum returns the codes of a code or Coding element's required binding, or
+// nil if there is none or its expansion is too large to list.
+func (b *builder) enum(elem *registry.ElementDefinition, code string) []any {
+	if b.codes == nil || elem.Binding == nil || elem.Binding.Strength != "required" || elem.Binding.ValueSet == "" {
+		return nil
+	}
+	if code != "code" && code != "Coding" {
+		return nil
+	}
+	codes, ok := b.codes.EnumerateCodes(elem.Binding.ValueSet, maxEnum)
+	if !ok {
+		return nil
+	}
+	values := make([]any, len(codes))
+	for i, c := range codes {
+		values[i] = c
+	}
+	return values
+}
+
+// regex returns the regex of a primitive type, from its value element.
+func (b *builder) regex(code string) string {
+	sd := b.registry.GetByType(code)
+	if sd == nil || sd.Snapshot == nil {
+		return ""
+	}
+	for _, elem := range sd.Snapshot.Element {
+		if elem.Path != sd.Type+".value" {
+			continue
+		}
+		for _, t := range elem.Type {
+			for _, ext := range t.Extension {
+				if ext.URL == regexExt {
+					return ext.ValueString
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// pattern returns the schema a value matches when it contains a pattern:
+// objects have at least the pattern's properties, and arrays an item matching
+// each of the pattern's items.
+func pattern(v any) *canonical.Object {
+	switch val := v.(type) {
+	case *canonical.Object:
+		props := &canonical.Object{}
+		required := make([]any, 0, len(val.Members))
+		for _, m := range val.Members {
+			props.Members = append(props.Members, member(m.Name, pattern(m.Value)))
+			required = append(required, m.Name)
+		}
+		return object("type", "object", "properties", props, "required", required)
+	case []any:
+		if len(val) == 1 {
+			return object("type", "array", "contains", pattern(val[0]))
+		}
+		contains := make([]any, len(val))
+		for i, item := range val {
+			contains[i] = object("contains", pattern(item))
+		}
+		return object("type", "array", "allOf", contains)
+	}
+	return object("const", v)
+}
+
+// and returns a schema that matches both schemas, merging them when they
+// share no keyword.
+func and(schema, extra *canonical.Object) *canonical.Object {
+	for _, m := range extra.Members {
+		if hasMember(schema, m.Name) {
+			return object("allOf", []any{schema, extra})
+		}
+	}
+	return &canonical.Object{Members: slices.Concat(schema.Members, extra.Members)}
+}
+
+// jsonType returns the JSON type of a primitive type's values.
+func jsonType(code string) string {
+	switch code {
+	case "boolean":
+		return "boolean"
+	case "integer", "positiveInt", "unsignedInt":
+		return "integer"
+	case "decimal":
+		return "number"
+	}
+	return "string"
+}
+
+// ref returns a schema that references the definition with the given name.
+// Types without a definition match any value.
+func ref(name string) *canonical.Object {
+	if name == "" {
+		return &canonical.Object{}
+	}
+	return object("$ref", "#/$defs/"+name)
+}
+
+// object returns an object schema from alternating keyword names and values.
+func object(kv ...any) *canonical.Object {
+	obj := &canonical.Object{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		obj.Members = append(obj.Members, member(kv[i].(string), kv[i+1]))
+	}
+	return obj
+}
+
+func member(name string, value any) canonical.Member {
+	return canonical.Member{Name: name, Value: value}
+}
+
+func number(n int) json.Number {
+	return json.Number(strconv.Itoa(n))
+}
+
+func hasMember(obj *canonical.Object, name string) bool {
+	_, ok := obj.Get(name)
+	return ok
+}
+
+// typeCode returns the element's first type.
+func typeCode(elem *registry.ElementDefinition) string {
+	if len(elem.Type) == 0 {
+		return ""
+	}
+	return elem.Type[0].Code
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,174 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/canonical"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+var (
+	sharedValidator     *validator.Validator
+	errSharedValidator  error
+	sharedValidatorOnce sync.Once
+)
+
+func newTestExporter(t *testing.T) *Exporter {
+	t.Helper()
+	sharedValidatorOnce.Do(func() {
+		sharedValidator, errSharedValidator = validator.New()
+	})
+	if errSharedValidator != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", errSharedValidator)
+	}
+	return New(sharedValidator.Registry(), sharedValidator.Terminology())
+}
+
+// schema is the subset of JSON Schema keywords the tests inspect.
+type schema struct {
+	Schema               string             `json:"$schema"`
+	ID                   string             `json:"$id"`
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Const                any                `json:"const"`
+	Enum                 []string           `json:"enum"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Contains             *schema            `json:"contains"`
+	MinContains          *int               `json:"minContains"`
+	AllOf                []*schema          `json:"allOf"`
+	OneOf                []*schema          `json:"oneOf"`
+	Pattern              string             `json:"pattern"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Defs                 map[string]*schema `json:"$defs"`
+}
+
+func export(t *testing.T, url string) *schema {
+	t.Helper()
+	data, err := newTestExporter(t).Export(url)
+	if err != nil {
+		t.Fatalf("Export(%s) error = %v", url, err)
+	}
+	var s schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("Export(%s) returned invalid JSON: %v", url, err)
+	}
+	return &s
+}
+
+func TestExportResource(t *testing.T) {
+	s := export(t, "http://hl7.org/fhir/StructureDefinition/Patient")
+
+	if s.Schema != Draft || s.ID != "http://hl7.org/fhir/StructureDefinition/Patient" {
+		t.Errorf("$schema, $id = %q, %q", s.Schema, s.ID)
+	}
+	if s.Properties["resourceType"].Const != "Patient" || !slices.Contains(s.Required, "resourceType") {
+		t.Errorf("resourceType = %+v, required %v", s.Properties["resourceType"], s.Required)
+	}
+	if s.AdditionalProperties == nil || *s.AdditionalProperties {
+		t.Error("additionalProperties should be false")
+	}
+
+	gender := s.Properties["gender"]
+	if gender.Ref != "#/$defs/code" || !slices.Equal(gender.Enum, []string{"female", "male", "other", "unknown"}) {
+		t.Errorf("gender = %+v, want code with required binding enum", gender)
+	}
+	if s.Properties["_gender"] == nil || s.Properties["_birthDate"] == nil {
+		t.Error("primitives should allow their _name companion")
+	}
+
+	name := s.Properties["name"]
+	if name.Type != "array" || name.Items.Ref != "#/$defs/HumanName" || s.Defs["HumanName"] == nil {
+		t.Errorf("name = %+v, want array of $defs/HumanName", name)
+	}
+	if s.Defs["dateTime"] == nil || s.Defs["dateTime"].Pattern == "" {
+		t.Error("primitive definitions should carry the type regex")
+	}
+
+	if s.Properties["deceasedBoolean"] == nil || s.Properties["deceasedDateTime"] == nil || s.Properties["deceased[x]"] != nil {
+		t.Error("choice elements should have one property per type")
+	}
+	var choice *schema
+	for _, sub := range s.AllOf {
+		if len(sub.OneOf) > 0 && slices.Contains(sub.OneOf[0].Required, "deceasedBoolean") {
+			choice = sub
+		}
+	}
+	if choice == nil || len(choice.OneOf) != 3 {
+		t.Errorf("deceased[x] should allow at most one type, got %+v", choice)
+	}
+}
+
+func TestExportProfile(t *testing.T) {
+	s := export(t, "http://hl7.org/fhir/StructureDefinition/bodyweight")
+
+	if s.Properties["resourceType"].Const != "Observation" {
+		t.Errorf("resourceType = %v, want Observation", s.Properties["resourceType"].Const)
+	}
+	for _, name := range []string{"category", "code", "subject"} {
+		if !slices.Contains(s.Required, name) {
+			t.Errorf("required = %v, want %s", s.Required, name)
+		}
+	}
+	if s.Properties["valueString"] != nil || s.Properties["valueQuantity"] == nil {
+		t.Error("value[x] should be restricted to the profile's types")
+	}
+
+	// The VSCat slice is required
+	category := s.Properties["category"]
+	if category.MinItems == nil || *category.MinItems != 1 || len(category.AllOf) != 1 || category.AllOf[0].Contains == nil {
+		t.Fatalf("category = %+v, want minItems and contains for the VSCat slice", category)
+	}
+	coding := category.AllOf[0].Contains.Properties["coding"]
+	if coding == nil || coding.Items.Properties["code"].Const != "vital-signs" {
+		t.Errorf("category:VSCat should fix the coding code, got %+v", coding)
+	}
+
+	quantity := s.Properties["valueQuantity"]
+	if quantity.Properties["system"].Const != "http://unitsofmeasure.org" {
+		t.Errorf("valueQuantity.system = %+v, want fixed UCUM", quantity.Properties["system"])
+	}
+	if !slices.Equal(quantity.Properties["code"].Enum, []string{"[lb_av]", "g", "kg"}) {
+		t.Errorf("valueQuantity.code enum = %v", quantity.Properties["code"].Enum)
+	}
+}
+
+func TestExportRecursive(t *testing.T) {
+	s := export(t, "http://hl7.org/fhir/StructureDefinition/Questionnaire")
+
+	item := s.Properties["item"].Items
+	if item == nil || item.Properties["item"].Items.Ref != "#/$defs/Questionnaire.item" {
+		t.Fatalf("Questionnaire.item.item should reference the item definition")
+	}
+	if s.Defs["Questionnaire.item"] == nil || s.Defs["Questionnaire.item"].Properties["linkId"] == nil {
+		t.Error("$defs should define Questionnaire.item")
+	}
+}
+
+func TestExportNotFound(t *testing.T) {
+	if _, err := newTestExporter(t).Export("http://example.org/StructureDefinition/missing"); err == nil {
+		t.Error("Export() of an unknown profile should fail")
+	}
+}
+
+func TestPattern(t *testing.T) {
+	v, err := canonical.Parse([]byte(`{"coding": [{"system": "http://loinc.org", "code": "1234-5"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := canonical.Marshal(pattern(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"object","properties":{"coding":{"type":"array","contains":{"type":"object","properties":{` +
+		`"system":{"const":"http://loinc.org"},"code":{"const":"1234-5"}},"required":["system","code"]}}},"required":["coding"]}`
+	if string(data) != want {
+		t.Errorf("pattern() = %s\nwant %s", data, want)
+	}
+}
@@ -0,0 +1,132 @@
+// Package limits guards validation against oversized or pathological input.
+//
+// Check scans the raw JSON bytes once, before the resource is parsed, so that
+// deeply nested or gigantic documents are rejected without allocating their
+// parsed form. The scan is a tokenizer only; malformed JSON is left for the
+// parser to report.
+package limits
+
+import (
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// Limits configures the input guards. A zero value disables a guard.
+type Limits struct {
+	MaxBytes    int // Maximum resource size in bytes
+	MaxDepth    int // Maximum nesting depth of JSON objects and arrays
+	MaxElements int // Maximum number of values (properties and array items)
+}
+
+// Enabled reports whether any guard is configured.
+func (l Limits) Enabled() bool {
+	return l.MaxBytes > 0 || l.MaxDepth > 0 || l.MaxElements > 0
+}
+
+// Check scans a resource and adds a fatal issue to result for the first limit
+// it exceeds. It returns false if a limit was exceeded, in which case the
+// resource must not be validated further.
+func Check(data []byte, l Limits, result *issue.Result) bool {
+	if l.MaxBytes > 0 && len(data) > l.MaxBytes {
+		result.AddErrorWithID(issue.DiagLimitResourceSize, issue.Params{issue.Int("size", len(data)), issue.Int("max", l.MaxBytes)})
+		return false
+	}
+	if l.MaxDepth <= 0 && l.MaxElements <= 0 {
+		return true
+	}
+
+	s := scanner{data: data}
+	switch s.scan(l) {
+	case exceededDepth:
+		result.AddErrorWithID(issue.DiagLimitNestingDepth, issue.Params{issue.Int("max", l.MaxDepth), issue.Int("offset", s.pos)})
+		return false
+	case exceededElements:
+		result.AddErrorWithID(issue.DiagLimitElementCount, issue.Params{issue.Int("max", l.MaxElements), issue.Int("offset", s.pos)})
+		return false
+	}
+	return true
+}
+
+// outcome is the result of a scan.
+type outcome int
+
+const (
+	withinLimits outcome = iota
+	exceededDepth
+	exceededElements
+)
+
+// scanner walks JSON bytes tracking container nesting and counting values.
+type scanner struct {
+	data []byte
+	pos  int
+}
+
+// scan runs until the end of data or the first exceeded limit.
+func (s *scanner) scan(l Limits) outcome {
+	var stack []byte // open containers: '{' or '['
+	expectKey := false
+	depth, elements := 0, 0
+
+	countValue := func() bool {
+		elements++
+		return l.MaxElements <= 0 || elements <= l.MaxElements
+	}
+
+	for ; s.pos < len(s.data); s.pos++ {
+		switch c := s.data[s.pos]; c {
+		case '{', '[':
+			if !countValue() {
+				return exceededElements
+			}
+			depth++
+			if l.MaxDepth > 0 && depth > l.MaxDepth {
+				return exceededDepth
+			}
+			stack = append(stack, c)
+			expectKey = c == '{'
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+				depth--
+			}
+			expectKey = false
+		case ',':
+			expectKey = len(stack) > 0 && stack[len(stack)-1] == '{'
+		case '"':
+			if !expectKey && !countValue() {
+				return exceededElements
+			}
+			expectKey = false
+			s.skipString()
+		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 't', 'f', 'n':
+			if !countValue() {
+				return exceededElements
+			}
+			s.skipLiteral()
+		}
+	}
+	return withinLimits
+}
+
+// skipString advances pos to the closing quote of the string starting at pos.
+func (s *scanner) skipString() {
+	for s.pos++; s.pos < len(s.data); s.pos++ {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos++
+		case '"':
+			return
+		}
+	}
+}
+
+// skipLiteral advances pos to the last byte of the number or literal at pos.
+func (s *scanner) skipLiteral() {
+	for s.pos+1 < len(s.data) {
+		switch s.data[s.pos+1] {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return
+		}
+		s.pos++
+	}
+}
@@ -0,0 +1,63 @@
+package limits
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestCheck(t *testing.T) {
+	patient := `{"resourceType":"Patient","name":[{"family":"Chalmers","given":["Peter","James"]}],"active":true,"multipleBirthInteger":-2}`
+	nestedContained := `{"resourceType":"Patient","contained":[{"resourceType":"Patient","contained":[{"resourceType":"Patient"}]}]}`
+
+	tests := []struct {
+		name     string
+		resource string
+		limits   Limits
+		wantID   issue.DiagnosticID // empty = within limits
+	}{
+		{"no limits", patient, Limits{}, ""},
+		{"size within limit", patient, Limits{MaxBytes: len(patient)}, ""},
+		{"size exceeded", patient, Limits{MaxBytes: len(patient) - 1}, issue.DiagLimitResourceSize},
+		{"depth within limit", patient, Limits{MaxDepth: 4}, ""},
+		{"depth exceeded", patient, Limits{MaxDepth: 3}, issue.DiagLimitNestingDepth},
+		{"nested contained depth exceeded", nestedContained, Limits{MaxDepth: 4}, issue.DiagLimitNestingDepth},
+		// root, resourceType, name, name[0], family, given, Peter, James, active, multipleBirthInteger
+		{"elements within limit", patient, Limits{MaxElements: 10}, ""},
+		{"elements exceeded", patient, Limits{MaxElements: 9}, issue.DiagLimitElementCount},
+		{"brackets in strings are ignored", `{"text":"{[{[{[\"]}]"}`, Limits{MaxDepth: 1, MaxElements: 2}, ""},
+		{"large array", `{"item":[` + strings.Repeat(`1,`, 999) + `1]}`, Limits{MaxElements: 1000}, issue.DiagLimitElementCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			ok := Check([]byte(tt.resource), tt.limits, result)
+
+			if tt.wantID == "" {
+				if !ok || len(result.Issues) != 0 {
+					t.Errorf("Check() = %v, %v; want within limits", ok, result.Issues)
+				}
+				return
+			}
+			if ok || len(result.Issues) != 1 {
+				t.Fatalf("Check() = %v, %v; want one %s issue", ok, result.Issues, tt.wantID)
+			}
+			if got := result.Issues[0]; got.MessageID != string(tt.wantID) || got.Severity != issue.SeverityFatal {
+				t.Errorf("issue = %s/%s, want %s/fatal", got.MessageID, got.Severity, tt.wantID)
+			}
+		})
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	resource := []byte(`{"resourceType":"Bundle","entry":[` +
+		strings.Repeat(`{"resource":{"resourceType":"Observation","status":"final","valueQuantity":{"value":1.5,"unit":"kg"}}},`, 999) +
+		`{}]}`)
+	l := Limits{MaxDepth: 64, MaxElements: 1_000_000}
+	b.SetBytes(int64(len(resource)))
+	for b.Loop() {
+		Check(resource, l, issue.NewResult())
+	}
+}
@@ -0,0 +1,157 @@
+package loader
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Canonicals indexes the versions of canonical resources (StructureDefinitions,
+// ValueSets, CodeSystems) loaded from packages, and decides which definition
+// an unversioned reference to each URL resolves to.
+//
+// Registries keep their own map of default definitions and consult Add while
+// loading; Canonicals holds every version for "url|version" references.
+type Canonicals[T any] struct {
+	mu        sync.RWMutex
+	policy    VersionPolicy
+	versions  map[string]map[string]*T
+	packages  map[string]map[string]*Package // url -> version -> package it was first loaded from
+	sources   map[string]*Package            // url -> package of the default definition
+	defined   map[string]map[string]string   // url -> package name -> resource version
+	ambiguous map[string]bool
+}
+
+// NewCanonicals creates an empty index using the given version policy.
+func NewCanonicals[T any](policy VersionPolicy) *Canonicals[T] {
+	return &Canonicals[T]{
+		policy:    policy,
+		versions:  make(map[string]map[string]*T),
+		packages:  make(map[string]map[string]*Package),
+		sources:   make(map[string]*Package),
+		defined:   make(map[string]map[string]string),
+		ambiguous: make(map[string]bool),
+	}
+}
+
+// SetPolicy changes the version policy for resources added afterwards.
+func (c *Canonicals[T]) SetPolicy(policy VersionPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policy = policy
+}
+
+// Add records the definition of url at version from pkg and reports whether
+// it becomes the default definition of url. Between versions of the same
+// package the policy decides; between different packages the first loaded
+// definition stays the default unless override is set, in which case the last
+// one wins.
+func (c *Canonicals[T]) Add(pkg *Package, url, version string, res *T, override bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.versions[url] == nil {
+		c.versions[url] = make(map[string]*T)
+		c.packages[url] = make(map[string]*Package)
+		c.defined[url] = make(map[string]string)
+	}
+	if _, exists := c.versions[url][version]; !exists {
+		c.versions[url][version] = res
+		c.packages[url][version] = pkg
+	}
+	if prev, exists := c.defined[url][pkg.Name]; exists && prev != version {
+		c.ambiguous[url] = true
+	} else if !exists {
+		c.defined[url][pkg.Name] = version
+	}
+
+	src, exists := c.sources[url]
+	var isDefault bool
+	switch {
+	case !exists:
+		isDefault = true
+	case src.Name == pkg.Name:
+		isDefault = src.Version != pkg.Version && c.policy.Prefer(pkg.Version, src.Version)
+	default:
+		isDefault = override
+	}
+	if isDefault {
+		c.sources[url] = pkg
+	}
+	return isDefault
+}
+
+// Get returns the definition of url selected by version: the same version,
+// or else the highest loaded version it is a prefix of. Returns nil if no
+// loaded version matches.
+func (c *Canonicals[T]) Get(url, version string) *T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	byVersion := c.versions[url]
+	if res, ok := byVersion[version]; ok {
+		return res
+	}
+	resolved, ok := ResolveVersion(version, mapKeys(byVersion))
+	if !ok {
+		return nil
+	}
+	return byVersion[resolved]
+}
+
+// Source returns the package the definition of url at version was loaded
+// from, or nil if that version is not loaded.
+func (c *Canonicals[T]) Source(url, version string) *Package {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.packages[url][version]
+}
+
+// Versions returns the loaded versions of url, lowest first.
+func (c *Canonicals[T]) Versions(url string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	versions := mapKeys(c.versions[url])
+	slices.SortFunc(versions, CompareVersions)
+	return versions
+}
+
+// Ambiguous reports whether different versions of the same package define
+// url with different versions, so that an unversioned reference to it depends
+// on the version policy.
+func (c *Canonicals[T]) Ambiguous(url string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ambiguous[url]
+}
+
+// AmbiguousCount returns the number of ambiguous URLs.
+func (c *Canonicals[T]) AmbiguousCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.ambiguous)
+}
+
+func mapKeys[T any](m map[string]*T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// RewriteCanonical rewrites the longest prefix of url found in mapping (a
+// mirror base URL -> published base URL) and reports whether it did.
+func RewriteCanonical(url string, mapping map[string]string) (string, bool) {
+	var from string
+	for prefix := range mapping {
+		if len(prefix) > len(from) && strings.HasPrefix(url, prefix) {
+			from = prefix
+		}
+	}
+	if from == "" {
+		return url, false
+	}
+	return mapping[from] + url[len(from):], true
+}
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -46,6 +47,24 @@ type Package struct {
 	Resources   map[string]json.RawMessage // URL or resourceType/id -> raw JSON
 }
 
+// ResourcesOfType returns the package's resources of a type, each once,
+// in the order of their resourceType/id keys.
+func (p *Package) ResourcesOfType(resourceType string) []json.RawMessage {
+	prefix := resourceType + "/"
+	var keys []string
+	for key := range p.Resources {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	resources := make([]json.RawMessage, len(keys))
+	for i, key := range keys {
+		resources[i] = p.Resources[key]
+	}
+	return resources
+}
+
 // PackageManifest represents the package.json of a FHIR NPM package.
 type PackageManifest struct {
 	Name         string            `json:"name"`
@@ -64,8 +83,9 @@ var DefaultPackages = map[string][]PackageRef{
 	},
 	"4.3.0": {
 		{Name: "hl7.fhir.r4b.core", Version: "4.3.0"},
+		// HL7 publishes no R4B terminology or extensions package; R4B-specific
+		// CodeSystems and ValueSets come with the core package
 		{Name: "hl7.terminology.r4", Version: "7.0.1"},
-		// R4B has no stable extensions package, use R4 as fallback
 		{Name: "hl7.fhir.uv.extensions.r4", Version: "5.2.0"},
 	},
 	"5.0.0": {
@@ -75,6 +95,28 @@ var DefaultPackages = map[string][]PackageRef{
 	},
 }
 
+// versionAliases maps release names and major.minor versions to the full
+// FHIR version used as key in DefaultPackages.
+var versionAliases = map[string]string{
+	"r4":  "4.0.1",
+	"4.0": "4.0.1",
+	"r4b": "4.3.0",
+	"4.3": "4.3.0",
+	"r5":  "5.0.0",
+	"5.0": "5.0.0",
+}
+
+// NormalizeVersion resolves a FHIR version alias (e.g., "R4B", "r4", "4.3") to
+// the full version string (e.g., "4.3.0"). Unrecognized values are returned
+// unchanged so that callers report them as unknown versions.
+func NormalizeVersion(version string) string {
+	v := strings.ToLower(strings.TrimSpace(version))
+	if full, ok := versionAliases[v]; ok {
+		return full
+	}
+	return strings.TrimSpace(version)
+}
+
 // Loader loads FHIR packages from the NPM cache.
 type Loader struct {
 	basePath string
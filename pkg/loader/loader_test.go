@@ -1,6 +1,7 @@
 package loader
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -27,6 +28,20 @@ func TestPackageRefString(t *testing.T) {
 	}
 }
 
+func TestResourcesOfType(t *testing.T) {
+	b, a := json.RawMessage(`{"id":"b"}`), json.RawMessage(`{"id":"a"}`)
+	pkg := &Package{Resources: map[string]json.RawMessage{
+		"OperationDefinition/b":                    b,
+		"http://example.org/OperationDefinition/b": b,
+		"OperationDefinition/a":                    a,
+		"SearchParameter/c":                        json.RawMessage(`{}`),
+	}}
+	got := pkg.ResourcesOfType("OperationDefinition")
+	if len(got) != 2 || string(got[0]) != string(a) || string(got[1]) != string(b) {
+		t.Errorf("ResourcesOfType() = %s, want a then b", got)
+	}
+}
+
 func TestParsePackageSpec(t *testing.T) {
 	tests := []struct {
 		spec        string
@@ -47,6 +62,77 @@ func TestParsePackageSpec(t *testing.T) {
 	}
 }
 
+func TestNormalizeVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"4.0.1", "4.0.1"},
+		{"R4", "4.0.1"},
+		{"4.0", "4.0.1"},
+		{"4.3.0", "4.3.0"},
+		{"R4B", "4.3.0"},
+		{"r4b", "4.3.0"},
+		{"4.3", "4.3.0"},
+		{"r5", "5.0.0"},
+		{" 5.0 ", "5.0.0"},
+		{"3.0.2", "3.0.2"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeVersion(tt.version); got != tt.want {
+			t.Errorf("NormalizeVersion(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"3.1.1", "6.1.0", -1},
+		{"6.1.0", "6.1.0", 0},
+		{"10.0.0", "9.1.0", 1},
+		{"6.1", "6.1.0", 0},
+		{"6.1.0-ballot", "6.1.0", -1},
+		{"6.1.0-ballot", "6.0.0", 1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	available := []string{"3.1.1", "6.1.0", "6.0.0", "6.1.2"}
+	tests := []struct {
+		requested string
+		want      string
+		wantOK    bool
+	}{
+		{"3.1.1", "3.1.1", true},
+		{"6.1", "6.1.2", true},
+		{"6", "6.1.2", true},
+		{"6.1.1", "", false},
+		{"4.0.1", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ResolveVersion(tt.requested, available)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ResolveVersion(%q) = (%q, %v), want (%q, %v)", tt.requested, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	url, version := SplitCanonical("http://example.org/ValueSet/x|4.0.1")
+	if url != "http://example.org/ValueSet/x" || version != "4.0.1" {
+		t.Errorf("SplitCanonical() = (%q, %q)", url, version)
+	}
+}
+
 func TestDefaultPackagesConfig(t *testing.T) {
 	// Verify all expected versions are configured
 	versions := []string{"4.0.1", "4.3.0", "5.0.0"}
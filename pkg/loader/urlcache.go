@@ -0,0 +1,227 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotCached is returned in offline mode for a package URL that has not
+// been downloaded before.
+var ErrNotCached = errors.New("package not in the URL cache")
+
+// DefaultURLCachePath returns the default directory for packages downloaded
+// from URLs, under the user cache directory.
+func DefaultURLCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gofhir-validator", "packages")
+}
+
+// URLOptions controls how LoadFromURLCached obtains a package.
+type URLOptions struct {
+	// CacheDir is where downloaded packages are kept ("" = DefaultURLCachePath).
+	CacheDir string
+	// Checksum pins the hex-encoded SHA-256 of the .tgz; other content is
+	// rejected ("" = not pinned).
+	Checksum string
+	// Offline uses only the cache and fails with ErrNotCached instead of
+	// making network calls.
+	Offline bool
+}
+
+// URLStatus tells where LoadFromURLCached got a package from.
+type URLStatus int
+
+const (
+	URLDownloaded  URLStatus = iota // Downloaded and cached
+	URLNotModified                  // Cached copy revalidated with the server
+	URLCached                       // Cached copy used without a network call
+	URLStale                        // Cached copy used because the server could not be reached
+)
+
+// String returns a description of the status for logging.
+func (s URLStatus) String() string {
+	switch s {
+	case URLNotModified:
+		return "cached, not modified"
+	case URLCached:
+		return "cached"
+	case URLStale:
+		return "cached, server unreachable"
+	}
+	return "downloaded"
+}
+
+// urlCacheEntry is the metadata stored next to a cached package.
+type urlCacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	SHA256       string `json:"sha256"`
+}
+
+// LoadFromURLCached loads a FHIR package from a remote .tgz URL through a
+// persistent cache keyed by URL. A cached copy is revalidated with the
+// server (ETag / Last-Modified) and reused when unchanged or when the server
+// cannot be reached. A cached copy matching a pinned checksum is used
+// without a network call, since pinned content cannot change. Ctx bounds the
+// download.
+func (l *Loader) LoadFromURLCached(ctx context.Context, url string, opts URLOptions) (*Package, URLStatus, error) {
+	dir := opts.CacheDir
+	if dir == "" {
+		dir = DefaultURLCachePath()
+	}
+	checksum := strings.ToLower(opts.Checksum)
+
+	var base string
+	var entry *urlCacheEntry
+	var cached []byte
+	if dir != "" {
+		key := sha256.Sum256([]byte(url))
+		base = filepath.Join(dir, hex.EncodeToString(key[:]))
+		entry, cached = readURLCache(base)
+	}
+
+	switch {
+	case cached != nil && (opts.Offline || entry.SHA256 == checksum):
+		if checksum != "" && entry.SHA256 != checksum {
+			return nil, 0, fmt.Errorf("cached package from %s: checksum %s does not match pinned %s", url, entry.SHA256, checksum)
+		}
+		pkg, err := l.loadFromTgzReader(bytes.NewReader(cached), url)
+		return pkg, URLCached, err
+	case opts.Offline:
+		return nil, 0, fmt.Errorf("%s: %w (offline)", url, ErrNotCached)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	// A cached copy that does not match the pin must be replaced, not revalidated
+	if cached != nil && checksum == "" {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if cached != nil && checksum == "" {
+			pkg, loadErr := l.loadFromTgzReader(bytes.NewReader(cached), url)
+			return pkg, URLStale, loadErr
+		}
+		return nil, 0, fmt.Errorf("failed to download package from %s: %w", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		pkg, err := l.loadFromTgzReader(bytes.NewReader(cached), url)
+		return pkg, URLNotModified, err
+	case resp.StatusCode >= http.StatusInternalServerError && cached != nil && checksum == "":
+		pkg, err := l.loadFromTgzReader(bytes.NewReader(cached), url)
+		return pkg, URLStale, err
+	case resp.StatusCode != http.StatusOK:
+		return nil, 0, fmt.Errorf("failed to download package: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download package from %s: %w", url, err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	if checksum != "" && digest != checksum {
+		return nil, 0, fmt.Errorf("package from %s: checksum %s does not match pinned %s", url, digest, checksum)
+	}
+
+	pkg, err := l.loadFromTgzReader(bytes.NewReader(data), url)
+	if err != nil {
+		return nil, 0, err
+	}
+	if base != "" {
+		// Caching is best effort; a read-only cache only costs the download
+		_ = writeURLCache(base, &urlCacheEntry{
+			URL:          url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			SHA256:       digest,
+		}, data)
+	}
+	return pkg, URLDownloaded, nil
+}
+
+// readURLCache returns the cached package stored at base, or nil if there is
+// none or it does not match its recorded checksum.
+func readURLCache(base string) (*urlCacheEntry, []byte) {
+	meta, err := os.ReadFile(base + ".json")
+	if err != nil {
+		return nil, nil
+	}
+	var entry urlCacheEntry
+	if json.Unmarshal(meta, &entry) != nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(base + ".tgz")
+	if err != nil {
+		return nil, nil
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != entry.SHA256 {
+		return nil, nil
+	}
+	return &entry, data
+}
+
+// writeURLCache stores a package and its metadata at base. Each file is
+// written to a temporary file and renamed, so concurrent readers never see
+// a partial package; the checksum check in readURLCache covers a package
+// and metadata from different writes.
+func writeURLCache(base string, entry *urlCacheEntry, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(base+".tgz", data); err != nil {
+		return err
+	}
+	return writeFileAtomic(base+".json", meta)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,108 @@
+package loader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// testTgz returns a package .tgz with only a package.json.
+func testTgz(t *testing.T, version string) []byte {
+	t.Helper()
+	manifest := []byte(`{"name": "example.ig", "version": "` + version + `"}`)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "package/package.json", Mode: 0o644, Size: int64(len(manifest))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoadFromURLCached(t *testing.T) {
+	tgz := testTgz(t, "1.0.0")
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(tgz)
+	}))
+	url := server.URL + "/example.ig.tgz"
+	cache := t.TempDir()
+	l := NewLoader("")
+
+	load := func(opts URLOptions) (URLStatus, error) {
+		t.Helper()
+		opts.CacheDir = cache
+		pkg, status, err := l.LoadFromURLCached(context.Background(), url, opts)
+		if err == nil && (pkg.Name != "example.ig" || pkg.Version != "1.0.0") {
+			t.Errorf("loaded %s#%s", pkg.Name, pkg.Version)
+		}
+		return status, err
+	}
+
+	if _, err := load(URLOptions{Offline: true}); !errors.Is(err, ErrNotCached) {
+		t.Errorf("offline before download: error = %v, want ErrNotCached", err)
+	}
+	if requests.Load() != 0 {
+		t.Errorf("offline mode made %d requests", requests.Load())
+	}
+
+	if status, err := load(URLOptions{}); err != nil || status != URLDownloaded {
+		t.Fatalf("first load = (%v, %v), want downloaded", status, err)
+	}
+	if status, err := load(URLOptions{}); err != nil || status != URLNotModified || notModified.Load() != 1 {
+		t.Errorf("second load = (%v, %v), want revalidated", status, err)
+	}
+
+	before := requests.Load()
+	if status, err := load(URLOptions{Checksum: sha256Hex(tgz)}); err != nil || status != URLCached {
+		t.Errorf("pinned load = (%v, %v), want cached", status, err)
+	}
+	if status, err := load(URLOptions{Offline: true}); err != nil || status != URLCached {
+		t.Errorf("offline load = (%v, %v), want cached", status, err)
+	}
+	if requests.Load() != before {
+		t.Errorf("pinned and offline loads made %d requests", requests.Load()-before)
+	}
+
+	wrong := sha256Hex([]byte("other"))
+	if _, err := load(URLOptions{Checksum: wrong}); err == nil {
+		t.Error("load with a wrong pinned checksum should fail")
+	}
+	if _, err := load(URLOptions{Checksum: wrong, Offline: true}); err == nil {
+		t.Error("offline load with a wrong pinned checksum should fail")
+	}
+
+	server.Close()
+	if status, err := load(URLOptions{}); err != nil || status != URLStale {
+		t.Errorf("load with the server down = (%v, %v), want stale", status, err)
+	}
+}
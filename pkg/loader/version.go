@@ -0,0 +1,125 @@
+package loader
+
+import (
+	"strconv"
+	"strings"
+)
+
+// VersionPolicy selects which definition of a canonical URL is used when it
+// is referenced without a version and several versions of the same package
+// define it (e.g., hl7.fhir.us.core 3.1.1 and 6.1.0 both loaded).
+type VersionPolicy int
+
+const (
+	// VersionLatest uses the definition from the highest package version.
+	VersionLatest VersionPolicy = iota
+	// VersionFirstLoaded uses the definition from the package loaded first.
+	VersionFirstLoaded
+)
+
+// String returns the policy name as accepted by ParseVersionPolicy.
+func (p VersionPolicy) String() string {
+	if p == VersionFirstLoaded {
+		return "first"
+	}
+	return "latest"
+}
+
+// ParseVersionPolicy parses a policy name: "latest" or "first".
+func ParseVersionPolicy(s string) (VersionPolicy, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "latest":
+		return VersionLatest, true
+	case "first":
+		return VersionFirstLoaded, true
+	}
+	return VersionLatest, false
+}
+
+// Prefer reports whether the definition from package version candidate
+// replaces the one from current under the policy, when both come from
+// versions of the same package and candidate is loaded after current.
+func (p VersionPolicy) Prefer(candidate, current string) bool {
+	return p == VersionLatest && CompareVersions(candidate, current) > 0
+}
+
+// SplitCanonical splits a canonical reference "url|version" into its URL and
+// version. The version is empty when the reference has none.
+func SplitCanonical(canonical string) (url, version string) {
+	if idx := strings.LastIndex(canonical, "|"); idx != -1 {
+		return canonical[:idx], canonical[idx+1:]
+	}
+	return canonical, ""
+}
+
+// ResolveVersion returns the version among available that a canonical
+// reference version selects: the same version, or else the highest version
+// it is a prefix of (e.g., "6.1" selects "6.1.0"), as the FHIR canonical
+// matching rules allow.
+func ResolveVersion(requested string, available []string) (string, bool) {
+	var best string
+	found := false
+	for _, v := range available {
+		if v == requested {
+			return v, true
+		}
+		if strings.HasPrefix(v, requested+".") && (!found || CompareVersions(v, best) > 0) {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
+
+// CompareVersions compares two dotted versions such as "3.1.1" and "6.1.0",
+// numerically where both parts are numbers. A pre-release ("6.1.0-ballot")
+// sorts before its release. It returns -1, 0 or +1.
+func CompareVersions(a, b string) int {
+	aRelease, aPre, _ := strings.Cut(a, "-")
+	bRelease, bPre, _ := strings.Cut(b, "-")
+	aParts := strings.Split(aRelease, ".")
+	bParts := strings.Split(bRelease, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var ap, bp string
+		if i < len(aParts) {
+			ap = aParts[i]
+		}
+		if i < len(bParts) {
+			bp = bParts[i]
+		}
+		if c := comparePart(ap, bp); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// comparePart compares one version part, numerically when both are numbers.
+// A missing part counts as zero.
+func comparePart(a, b string) int {
+	if a == "" {
+		a = "0"
+	}
+	if b == "" {
+		b = "0"
+	}
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	if aErr == nil && bErr == nil {
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
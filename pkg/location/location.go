@@ -3,8 +3,6 @@
 package location
 
 import (
-	"encoding/json"
-	"fmt"
 	"strconv"
 	"strings"
 )
@@ -17,20 +15,18 @@ type Location struct {
 
 // Find locates the position of a FHIRPath expression in JSON source.
 // Returns nil if the path cannot be found.
+//
+// Object members resolve to the position just after their key and array items
+// to the start of the first item or the end of the preceding one. The source
+// is scanned in place without decoding, so lookups do not allocate beyond the
+// returned Location.
 func Find(jsonData []byte, fhirPath string) *Location {
 	if len(jsonData) == 0 || fhirPath == "" {
 		return nil
 	}
 
-	segments := parseFHIRPath(fhirPath)
-	if len(segments) == 0 {
-		return nil
-	}
-
-	dec := json.NewDecoder(strings.NewReader(string(jsonData)))
-
-	offset, err := navigateToPath(dec, segments)
-	if err != nil {
+	offset, ok := findOffset(jsonData, trimResourceType(fhirPath))
+	if !ok {
 		return nil
 	}
 
@@ -43,178 +39,228 @@ func Find(jsonData []byte, fhirPath string) *Location {
 //   - "Patient.identifier[0].value" -> ["identifier", "0", "value"]
 //   - "Bundle.entry[0].resource.id" -> ["entry", "0", "resource", "id"]
 func parseFHIRPath(path string) []string {
-	// Remove resource type prefix if present (Patient.identifier -> identifier)
+	var segments []string
+	path = trimResourceType(path)
+	for seg, rest := nextSegment(path); seg != ""; seg, rest = nextSegment(rest) {
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// trimResourceType removes a resource type prefix (Patient.identifier -> identifier).
+func trimResourceType(path string) string {
 	if idx := strings.Index(path, "."); idx > 0 {
-		first := path[:idx]
 		// Check if first segment looks like a resource type (starts with uppercase)
-		if first != "" && first[0] >= 'A' && first[0] <= 'Z' {
-			path = path[idx+1:]
+		if first := path[:idx]; first[0] >= 'A' && first[0] <= 'Z' {
+			return path[idx+1:]
 		}
 	}
+	return path
+}
 
-	var segments []string
-	current := ""
-
-	for i := 0; i < len(path); i++ {
-		ch := path[i]
-		switch ch {
+// nextSegment returns the first segment of path (a name or an array index)
+// and the remainder. It returns an empty segment at the end of the path.
+func nextSegment(path string) (seg, rest string) {
+	for path != "" {
+		switch path[0] {
 		case '.':
-			if current != "" {
-				segments = append(segments, current)
-				current = ""
-			}
+			path = path[1:]
+			continue
 		case '[':
-			if current != "" {
-				segments = append(segments, current)
-				current = ""
-			}
-			// Read array index
-			j := i + 1
-			for j < len(path) && path[j] != ']' {
-				j++
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return path[1:], ""
 			}
-			if j > i+1 {
-				segments = append(segments, path[i+1:j])
+			if end == 1 {
+				path = path[2:]
+				continue
 			}
-			i = j
-		default:
-			current += string(ch)
+			return path[1:end], path[end+1:]
 		}
+		end := strings.IndexAny(path, ".[")
+		if end < 0 {
+			return path, ""
+		}
+		return path[:end], path[end:]
 	}
-	if current != "" {
-		segments = append(segments, current)
-	}
-
-	return segments
+	return "", ""
 }
 
-// navigateToPath navigates through JSON to find the target path.
-func navigateToPath(dec *json.Decoder, segments []string) (int, error) {
-	segIdx := 0
-
-	for segIdx < len(segments) {
-		target := segments[segIdx]
+// findOffset navigates data along the segments of path and returns the
+// offset of the last one.
+//
+// Children of a primitive (e.g., "given[1].extension") are looked up in its
+// shadow element ("_given", at the same index), as is a primitive that has
+// only a shadow element.
+func findOffset(data []byte, path string) (int, bool) {
+	pos := skipSpace(data, 0)
+	offset := -1
+	// The object holding the last key, the key and its index, if any
+	parent, key, index := -1, "", -1
 
-		// Check if target is array index
-		if idx, err := strconv.Atoi(target); err == nil {
-			// Navigate to array index
-			offset, err := navigateToArrayIndex(dec, idx)
-			if err != nil {
-				return 0, err
-			}
-			segIdx++
-			if segIdx == len(segments) {
-				return offset, nil
-			}
+	for seg, rest := nextSegment(path); seg != ""; seg, rest = nextSegment(rest) {
+		var ok bool
+		if idx, err := strconv.Atoi(seg); err == nil {
+			offset, pos, ok = findIndex(data, pos, idx)
+			index = idx
 		} else {
-			// Navigate to object key
-			offset, err := navigateToKey(dec, target)
-			if err != nil {
-				return 0, err
-			}
-			segIdx++
-			if segIdx == len(segments) {
-				return offset, nil
+			at := pos
+			offset, pos, ok = findKey(data, at, seg)
+			switch {
+			case ok:
+			case at < len(data) && data[at] == '{':
+				// A primitive with extensions but no value
+				offset, pos, ok = findKey(data, at, "_"+seg)
+			case parent >= 0:
+				if at, ok = shadowValue(data, parent, key, index); ok {
+					offset, pos, ok = findKey(data, at, seg)
+				}
 			}
+			parent, key, index = at, seg, -1
+		}
+		if !ok {
+			return 0, false
 		}
 	}
-
-	return 0, fmt.Errorf("path not found")
+	return offset, offset >= 0
 }
 
-// navigateToKey finds a key in the current JSON object.
-func navigateToKey(dec *json.Decoder, key string) (int, error) {
-	for {
-		tok, err := dec.Token()
-		if err != nil {
-			return 0, fmt.Errorf("key %q not found: %w", key, err)
-		}
-
-		// Looking for the key — capture offset AFTER reading the token
-		// so the position is on the same line as the key itself.
-		if k, ok := tok.(string); ok && k == key {
-			return int(dec.InputOffset()), nil
-		}
-
-		// Handle delimiters
-		if delim, ok := tok.(json.Delim); ok {
-			switch delim {
-			case '{':
-				// Enter object, continue searching
-			case '[':
-				// Skip the array
-				if err := skipRest(dec, '['); err != nil {
-					return 0, err
-				}
-			case '}', ']':
-				return 0, fmt.Errorf("key %q not found in object", key)
-			}
-		}
+// shadowValue returns the start of the shadow element of the primitive key
+// in the object at parent, or of its item index if index is not -1.
+func shadowValue(data []byte, parent int, key string, index int) (int, bool) {
+	_, value, ok := findKey(data, parent, "_"+key)
+	if ok && index >= 0 {
+		_, value, ok = findIndex(data, value, index)
 	}
+	return value, ok
 }
 
-// navigateToArrayIndex navigates to a specific index in a JSON array.
-func navigateToArrayIndex(dec *json.Decoder, targetIdx int) (int, error) {
-	// We should be at the start of an array value
-	tok, err := dec.Token()
-	if err != nil {
-		return 0, err
-	}
-	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
-		return 0, fmt.Errorf("expected array, got %v", tok)
+// findKey looks up key in the object starting at pos. It returns the offset
+// just after the key and the start of its value.
+func findKey(data []byte, pos int, key string) (offset, value int, ok bool) {
+	if pos >= len(data) || data[pos] != '{' {
+		return 0, 0, false
 	}
+	pos = skipSpace(data, pos+1)
 
-	idx := 0
-	for dec.More() {
-		offset := int(dec.InputOffset())
-		if idx == targetIdx {
-			return offset, nil
+	for pos < len(data) && data[pos] == '"' {
+		end := skipString(data, pos)
+		if end < 0 {
+			return 0, 0, false
 		}
-		// Skip this element
-		if err := skipValue(dec); err != nil {
-			return 0, err
+		name := data[pos+1 : end-1]
+
+		pos = skipSpace(data, end)
+		if pos >= len(data) || data[pos] != ':' {
+			return 0, 0, false
+		}
+		pos = skipSpace(data, pos+1)
+
+		if string(name) == key {
+			return end, pos, true
 		}
-		idx++
-	}
 
-	// Consume closing ]
-	if _, err := dec.Token(); err != nil {
-		return 0, err
+		if pos = skipValue(data, pos); pos < 0 {
+			return 0, 0, false
+		}
+		pos = skipSpace(data, pos)
+		if pos >= len(data) || data[pos] != ',' {
+			return 0, 0, false
+		}
+		pos = skipSpace(data, pos+1)
 	}
-	return 0, fmt.Errorf("array index %d out of bounds (size %d)", targetIdx, idx)
+	return 0, 0, false
 }
 
-// skipValue skips a single JSON value (primitive, object, or array).
-func skipValue(dec *json.Decoder) error {
-	tok, err := dec.Token()
-	if err != nil {
-		return err
+// findIndex looks up item idx in the array starting at pos. It returns the
+// start of the first item, or the end of the preceding item, and the start
+// of the item itself.
+func findIndex(data []byte, pos, idx int) (offset, value int, ok bool) {
+	if pos >= len(data) || data[pos] != '[' {
+		return 0, 0, false
+	}
+	pos = skipSpace(data, pos+1)
+	if pos >= len(data) || data[pos] == ']' {
+		return 0, 0, false
 	}
 
-	if delim, ok := tok.(json.Delim); ok {
-		return skipRest(dec, delim)
+	offset = pos
+	for i := 0; i < idx; i++ {
+		if offset = skipValue(data, pos); offset < 0 {
+			return 0, 0, false
+		}
+		pos = skipSpace(data, offset)
+		if pos >= len(data) || data[pos] != ',' {
+			return 0, 0, false
+		}
+		pos = skipSpace(data, pos+1)
 	}
-	return nil
+	return offset, pos, true
 }
 
-// skipRest skips the rest of an object or array after the opening delimiter.
-func skipRest(dec *json.Decoder, _ json.Delim) error {
-	depth := 1
-	for depth > 0 {
-		tok, err := dec.Token()
-		if err != nil {
-			return err
-		}
-		if delim, ok := tok.(json.Delim); ok {
-			switch delim {
+// skipValue returns the offset just after the JSON value starting at pos, or
+// -1 if the value is malformed.
+func skipValue(data []byte, pos int) int {
+	if pos >= len(data) {
+		return -1
+	}
+	switch data[pos] {
+	case '"':
+		return skipString(data, pos)
+	case '{', '[':
+		depth := 0
+		for ; pos < len(data); pos++ {
+			switch data[pos] {
 			case '{', '[':
 				depth++
 			case '}', ']':
-				depth--
+				if depth--; depth == 0 {
+					return pos + 1
+				}
+			case '"':
+				if pos = skipString(data, pos); pos < 0 {
+					return -1
+				}
+				pos-- // compensate for the loop increment
+			}
+		}
+		return -1
+	default:
+		for ; pos < len(data); pos++ {
+			switch data[pos] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				return pos
 			}
 		}
+		return pos
+	}
+}
+
+// skipString returns the offset just after the string starting at pos, or
+// -1 if it is not terminated.
+func skipString(data []byte, pos int) int {
+	for pos++; pos < len(data); pos++ {
+		switch data[pos] {
+		case '\\':
+			pos++
+		case '"':
+			return pos + 1
+		}
+	}
+	return -1
+}
+
+// skipSpace returns the offset of the first non-whitespace byte at or after pos.
+func skipSpace(data []byte, pos int) int {
+	for pos < len(data) {
+		switch data[pos] {
+		case ' ', '\t', '\n', '\r':
+			pos++
+		default:
+			return pos
+		}
 	}
-	return nil
+	return pos
 }
 
 // offsetToLineCol converts a byte offset to line and column numbers.
@@ -245,3 +245,65 @@ func TestFindBundleEntry(t *testing.T) {
 		})
 	}
 }
+
+func TestFindMatchesKeysAtCurrentLevel(t *testing.T) {
+	jsonData := []byte(`{"resourceType":"Patient","contact":[{"name":{"text":"id"}}],"id":"p1"}`)
+
+	loc := Find(jsonData, "Patient.id")
+	if loc == nil || loc.Column != 66 {
+		t.Errorf("Find(Patient.id) = %+v, want column 66", loc)
+	}
+	if loc := Find(jsonData, "Patient.text"); loc != nil {
+		t.Errorf("Find(Patient.text) = %+v, want nil for a nested key", loc)
+	}
+}
+
+func TestFindShadowElement(t *testing.T) {
+	jsonData := []byte(`{
+  "resourceType": "Patient",
+  "name": [
+    {
+      "given": ["Ann", "Bo"],
+      "_given": [
+        null,
+        {
+          "extension": [{"url": "http://example.org/nick"}]
+        }
+      ]
+    }
+  ],
+  "_birthDate": {
+    "extension": [{"url": "http://example.org/time"}]
+  }
+}`)
+
+	tests := []struct {
+		fhirPath string
+		wantLine int // 0 = not found
+	}{
+		{"Patient.name[0].given[1]", 5},
+		{"Patient.name[0].given[1].extension", 9},
+		{"Patient.name[0].given[1].extension[0].url", 9},
+		{"Patient.name[0].given[0].extension", 0},
+		{"Patient.birthDate", 14},
+		{"Patient.birthDate.extension[0]", 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fhirPath, func(t *testing.T) {
+			loc := Find(jsonData, tt.fhirPath)
+			if tt.wantLine == 0 {
+				if loc != nil {
+					t.Errorf("Find(%q) = %+v, want nil", tt.fhirPath, loc)
+				}
+				return
+			}
+			if loc == nil {
+				t.Fatalf("Find(%q) = nil", tt.fhirPath)
+			}
+			if loc.Line != tt.wantLine {
+				t.Errorf("Find(%q).Line = %d, want %d", tt.fhirPath, loc.Line, tt.wantLine)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// scanState is what a container expects next.
+type scanState int
+
+const (
+	expectKey scanState = iota
+	expectColon
+	expectValue
+	expectComma
+)
+
+// container is a JSON object or array around a position.
+type container struct {
+	array        bool
+	state        scanState
+	key          string   // Object: the last member name read
+	index        int      // Array: index of the current item
+	keys         []string // Object: its member names
+	resourceType string   // Object: its resourceType member
+}
+
+// cursor is the JSON context of a position in a document, which is usually
+// being edited and need not be valid JSON.
+type cursor struct {
+	// containers are the objects and arrays around the position, outermost
+	// first. Their member names and resourceType cover the whole document.
+	containers []container
+
+	// inKey is set at a member name of the innermost object, inValue at a
+	// value of the innermost container.
+	inKey, inValue bool
+
+	// start and end are the byte range of the string, number or literal at
+	// the position, or -1 between tokens; closed is set when a string token
+	// is terminated.
+	start, end int
+	closed     bool
+
+	// prefix is the token text before the position, without the quote.
+	prefix string
+}
+
+// token returns the string at the cursor, or "" when it is not in one.
+func (c *cursor) token(text []byte) string {
+	if c.start < 0 || !c.closed || text[c.start] != '"' {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(text[c.start:c.end], &s) != nil {
+		return ""
+	}
+	return s
+}
+
+// scanCursor scans text for the context of the byte offset.
+func scanCursor(text []byte, offset int) *cursor {
+	var stack []*container
+	var c *cursor
+	var open []*container // The containers of c, as scanning goes on
+	snapshot := func(start, end int) {
+		c = &cursor{start: start, end: end}
+		open = append(open, stack...)
+		for _, ct := range stack {
+			c.containers = append(c.containers, *ct)
+		}
+		if len(stack) == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		c.inKey = !top.array && top.state == expectKey
+		c.inValue = top.state == expectValue
+	}
+	// value records a value read in the innermost container.
+	value := func() {
+		if len(stack) > 0 {
+			stack[len(stack)-1].state = expectComma
+		}
+	}
+
+	pos := 0
+	for {
+		pos = skipSpace(text, pos)
+		if c == nil && offset <= pos && (pos >= len(text) || offset < pos || !isLiteral(text[pos])) {
+			snapshot(-1, -1)
+		}
+		if pos >= len(text) {
+			break
+		}
+		start := pos
+		var top *container
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch ch := text[pos]; ch {
+		case '{', '[':
+			value()
+			ct := &container{array: ch == '['}
+			if ct.array {
+				ct.state = expectValue
+			}
+			stack = append(stack, ct)
+			pos++
+		case '}', ']':
+			if top != nil {
+				stack = stack[:len(stack)-1]
+			}
+			pos++
+		case ':':
+			if top != nil && !top.array {
+				top.state = expectValue
+			}
+			pos++
+		case ',':
+			if top != nil {
+				if top.array {
+					top.index++
+					top.state = expectValue
+				} else {
+					top.state = expectKey
+				}
+			}
+			pos++
+		case '"':
+			end, closed := scanString(text, pos)
+			if c == nil && start < offset && (offset < end || offset == end && !closed) {
+				snapshot(start, end)
+				c.closed = closed
+				c.prefix = string(text[start+1 : offset])
+			}
+			var s string
+			if closed {
+				_ = json.Unmarshal(text[start:end], &s)
+			}
+			switch {
+			case top != nil && !top.array && top.state == expectKey:
+				top.key = s
+				top.keys = append(top.keys, s)
+				top.state = expectColon
+			case top != nil && !top.array && top.state == expectValue && top.key == "resourceType":
+				top.resourceType = s
+				value()
+			default:
+				value()
+			}
+			pos = end
+		default:
+			end := pos + 1
+			for end < len(text) && isLiteral(text[end]) && !isSpace(text[end]) {
+				end++
+			}
+			if c == nil && start <= offset && offset <= end {
+				snapshot(start, end)
+				c.prefix = string(text[start:offset])
+			}
+			value()
+			pos = end
+		}
+	}
+
+	// Member names and resource types may follow the position
+	for i, ct := range open {
+		c.containers[i].keys = ct.keys
+		c.containers[i].resourceType = ct.resourceType
+	}
+	return c
+}
+
+// scanString returns the offset just after the string starting at pos and
+// whether it is terminated on its line.
+func scanString(text []byte, pos int) (int, bool) {
+	for pos++; pos < len(text); pos++ {
+		switch text[pos] {
+		case '\\':
+			pos++
+		case '"':
+			return pos + 1, true
+		case '\n':
+			return pos, false
+		}
+	}
+	return len(text), false
+}
+
+// isLiteral reports whether ch can be part of a number or literal.
+func isLiteral(ch byte) bool {
+	switch ch {
+	case '{', '}', '[', ']', ':', ',', '"':
+		return false
+	}
+	return true
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func skipSpace(text []byte, pos int) int {
+	for pos < len(text) && isSpace(text[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// offsetAt converts a protocol position to a byte offset, clamped to its
+// line and to the text.
+func offsetAt(text []byte, p position) int {
+	offset := 0
+	for line := 0; line < p.Line; line++ {
+		i := indexByte(text, offset, '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset = i + 1
+	}
+	for units := 0; units < p.Character && offset < len(text) && text[offset] != '\n'; {
+		r, size := utf8.DecodeRune(text[offset:])
+		offset += size
+		units += utf16Len(r)
+	}
+	return offset
+}
+
+// positionAt converts a byte offset to a protocol position.
+func positionAt(text []byte, offset int) position {
+	offset = min(offset, len(text))
+	var p position
+	for i := 0; i < offset; {
+		r, size := utf8.DecodeRune(text[i:])
+		if r == '\n' {
+			p.Line++
+			p.Character = 0
+		} else {
+			p.Character += utf16Len(r)
+		}
+		i += size
+	}
+	return p
+}
+
+// lineOffset returns the byte offset of the 1-based line and byte column.
+func lineOffset(text []byte, line, column int) int {
+	offset := 0
+	for ; line > 1; line-- {
+		i := indexByte(text, offset, '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset = i + 1
+	}
+	return min(offset+column-1, len(text))
+}
+
+// indexByte returns the offset of the first b at or after from, or -1.
+func indexByte(text []byte, from int, b byte) int {
+	if i := bytes.IndexByte(text[from:], b); i >= 0 {
+		return from + i
+	}
+	return -1
+}
+
+// utf16Len is the number of UTF-16 code units of r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
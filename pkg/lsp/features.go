@@ -0,0 +1,357 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// maxCodes is the largest expansion offered as code completions.
+const maxCodes = 200
+
+// diagnose validates a document and converts its issues to diagnostics.
+func (s *Server) diagnose(ctx context.Context, text []byte) []diagnostic {
+	diagnostics := []diagnostic{}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(text, &members); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			at := positionAt(text, int(syntax.Offset))
+			diagnostics = append(diagnostics, diagnostic{
+				Range:    textRange{Start: at, End: at},
+				Severity: severityError,
+				Source:   "gofhir-validator",
+				Message:  "Invalid JSON: " + syntax.Error(),
+			})
+		}
+		return diagnostics
+	}
+	if _, ok := members["resourceType"]; !ok {
+		return diagnostics
+	}
+
+	result, err := s.validator.Validate(ctx, text, s.opts...)
+	if err != nil {
+		return append(diagnostics, diagnostic{Severity: severityError, Source: "gofhir-validator", Message: err.Error()})
+	}
+	for _, iss := range result.Issues {
+		severity := severityError
+		switch iss.Severity {
+		case issue.SeverityWarning:
+			severity = severityWarning
+		case issue.SeverityInformation:
+			severity = severityInformation
+		}
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    issueRange(text, iss.Location),
+			Severity: severity,
+			Code:     iss.MessageID,
+			Source:   "gofhir-validator",
+			Message:  iss.Diagnostics,
+		})
+	}
+	return diagnostics
+}
+
+// issueRange is the range of an issue: the member name at its location, or
+// the rest of the line for array items. Issues without a location are put
+// at the start of the document.
+func issueRange(text []byte, loc *issue.Location) textRange {
+	if loc == nil {
+		return textRange{}
+	}
+	offset := lineOffset(text, loc.Line, loc.Column)
+	start, end := offset, offset
+	if offset > 0 && text[offset-1] == '"' {
+		if i := bytes.LastIndexByte(text[:offset-1], '"'); i >= 0 {
+			start = i
+		}
+	} else {
+		for end < len(text) && text[end] != '\n' && text[end] != '\r' {
+			end++
+		}
+		end = start + len(bytes.TrimRight(text[start:end], " \t,"))
+	}
+	return textRange{Start: positionAt(text, start), End: positionAt(text, end)}
+}
+
+// hover describes the element of the member name or value at p.
+func (s *Server) hover(text []byte, p position) *hover {
+	c := scanCursor(text, offsetAt(text, p))
+	if c.start < 0 || len(c.containers) == 0 {
+		return nil
+	}
+	n := len(c.containers) - 1
+	var member string
+	switch {
+	case c.inKey:
+		if member = c.token(text); member == "" {
+			return nil
+		}
+	case c.inValue && !c.containers[n].array:
+		member = c.containers[n].key
+	case !c.inValue:
+		return nil
+	}
+	path, el := s.resolve(text, c, n, member)
+	if el == nil {
+		return nil
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: describe(path, el)},
+		Range:    &textRange{Start: positionAt(text, c.start), End: positionAt(text, c.end)},
+	}
+}
+
+// describe formats an element for hover: its path, type, cardinality,
+// documentation and binding.
+func describe(path string, el *walker.ResolvedElement) string {
+	def := el.Element
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**", path)
+	if types := typeNames(el); types != "" {
+		fmt.Fprintf(&b, " `%s`", types)
+	}
+	fmt.Fprintf(&b, " %d..%s", def.Min, def.Max)
+	short := def.GetShort()
+	if short != "" {
+		b.WriteString("\n\n" + short)
+	}
+	if definition := def.GetDefinition(); definition != "" && definition != short {
+		b.WriteString("\n\n" + definition)
+	}
+	if def.Binding != nil && def.Binding.ValueSet != "" {
+		fmt.Fprintf(&b, "\n\nBinding (%s): %s", def.Binding.Strength, def.Binding.ValueSet)
+	}
+	fmt.Fprintf(&b, "\n\n*%s*", el.Source)
+	return b.String()
+}
+
+// typeNames returns the element's type, or its allowed types.
+func typeNames(el *walker.ResolvedElement) string {
+	if el.Type != "" {
+		return el.Type
+	}
+	var types []string
+	for _, t := range el.Element.Type {
+		types = append(types, t.Code)
+	}
+	return strings.Join(types, " | ")
+}
+
+// complete returns the member names or codes that can be written at p.
+func (s *Server) complete(text []byte, p position) []completionItem {
+	c := scanCursor(text, offsetAt(text, p))
+	if len(c.containers) == 0 {
+		return nil
+	}
+	n := len(c.containers) - 1
+	switch {
+	case c.inKey:
+		return s.completeMembers(text, c, n)
+	case c.inValue:
+		return s.completeCodes(text, c, n)
+	}
+	return nil
+}
+
+// completeMembers returns the member names the object at the cursor may
+// have and does not have yet.
+func (s *Server) completeMembers(text []byte, c *cursor, n int) []completionItem {
+	obj := c.containers[n]
+	editing := c.token(text)
+	present := func(name string) bool {
+		return name != editing && slices.Contains(obj.keys, name)
+	}
+	if obj.resourceType == "" && n == 0 {
+		if present("resourceType") {
+			return nil
+		}
+		return []completionItem{c.complete(text, "resourceType", ": ", kindProperty)}
+	}
+
+	_, el := s.resolve(text, c, n, "")
+	if el == nil {
+		return nil
+	}
+	var items []completionItem
+	for _, child := range el.Children {
+		for _, m := range memberNames(child) {
+			if present(m.name) {
+				continue
+			}
+			item := c.complete(text, m.name, ": ", kindProperty)
+			item.Detail = fmt.Sprintf("%s %d..%s", m.typeName, child.Min, child.Max)
+			if short := child.GetShort(); short != "" {
+				item.Documentation = &markupContent{Kind: "markdown", Value: short}
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// memberName is a JSON member name of an element and its type.
+type memberName struct {
+	name, typeName string
+}
+
+// memberNames returns the JSON names of an element: its name, or one name
+// per type of a choice element (e.g., valueQuantity).
+func memberNames(def *registry.ElementDefinition) []memberName {
+	name := def.Path[strings.LastIndexByte(def.Path, '.')+1:]
+	var types []string
+	for _, t := range def.Type {
+		types = append(types, t.Code)
+	}
+	base, choice := strings.CutSuffix(name, "[x]")
+	if !choice {
+		return []memberName{{name, strings.Join(types, " | ")}}
+	}
+	var members []memberName
+	for _, t := range types {
+		members = append(members, memberName{base + strings.ToUpper(t[:1]) + t[1:], t})
+	}
+	return members
+}
+
+// completeCodes returns the codes of the ValueSet bound to the element of
+// the value at the cursor. The binding of a Coding's code is that of the
+// Coding, or of its CodeableConcept.
+func (s *Server) completeCodes(text []byte, c *cursor, n int) []completionItem {
+	var member string
+	if !c.containers[n].array {
+		member = c.containers[n].key
+	}
+	path, el := s.resolve(text, c, n, member)
+	if el == nil {
+		return nil
+	}
+	if member == "code" && el.Element.Binding == nil {
+		parent, r := c.elementPath(n)
+		candidates := []string{parent}
+		if base, ok := strings.CutSuffix(parent, ".coding"); ok {
+			candidates = append(candidates, base)
+		}
+		for _, p := range candidates {
+			if bound := s.resolvePath(text, c, r, p); bound != nil && bound.Element.Binding != nil {
+				el = bound
+				break
+			}
+		}
+	}
+	binding := el.Element.Binding
+	if binding == nil || binding.ValueSet == "" {
+		return nil
+	}
+	codes, ok := s.validator.Terminology().EnumerateCodes(binding.ValueSet, maxCodes)
+	if !ok {
+		return nil
+	}
+	items := make([]completionItem, 0, len(codes))
+	for _, code := range codes {
+		item := c.complete(text, code, "", kindEnumMember)
+		item.Detail = fmt.Sprintf("%s (%s)", path, binding.Strength)
+		items = append(items, item)
+	}
+	return items
+}
+
+// complete returns a completion of value at the cursor: inside a string it
+// replaces the string's content, elsewhere it writes the quoted value
+// followed by suffix.
+func (c *cursor) complete(text []byte, value, suffix string, kind int) completionItem {
+	item := completionItem{Label: value, Kind: kind}
+	quoted, _ := json.Marshal(value)
+	switch {
+	case c.start < 0:
+		item.InsertText = string(quoted) + suffix
+	case text[c.start] == '"':
+		end := c.end
+		if c.closed {
+			end--
+		}
+		item.TextEdit = &textEdit{
+			Range:   textRange{Start: positionAt(text, c.start+1), End: positionAt(text, end)},
+			NewText: value,
+		}
+	default:
+		item.TextEdit = &textEdit{
+			Range:   textRange{Start: positionAt(text, c.start), End: positionAt(text, c.end)},
+			NewText: string(quoted) + suffix,
+		}
+	}
+	return item
+}
+
+// elementPath returns the element path of container n, e.g.
+// "Patient.name" for the object of Patient.name[0], and the index of the
+// container of its resource; the path is "" outside of resources.
+func (c *cursor) elementPath(n int) (string, int) {
+	r := n
+	for r >= 0 && (c.containers[r].array || c.containers[r].resourceType == "") {
+		r--
+	}
+	if r < 0 {
+		return "", -1
+	}
+	path := c.containers[r].resourceType
+	for _, ct := range c.containers[r:n] {
+		if !ct.array {
+			path += "." + strings.TrimPrefix(ct.key, "_")
+		}
+	}
+	return path, r
+}
+
+// resolve returns the path and definition of member of container n, or of
+// the container itself when member is "" or resourceType.
+func (s *Server) resolve(text []byte, c *cursor, n int, member string) (string, *walker.ResolvedElement) {
+	path, r := c.elementPath(n)
+	if path == "" {
+		return "", nil
+	}
+	if member != "" && member != "resourceType" {
+		path += "." + strings.TrimPrefix(member, "_")
+	}
+	return path, s.resolvePath(text, c, r, path)
+}
+
+// resolvePath returns the definition of path in the profile of the
+// resource of container r, or nil.
+func (s *Server) resolvePath(text []byte, c *cursor, r int, path string) *walker.ResolvedElement {
+	el, err := s.walker.ResolvePath(s.profile(text, c, r), path)
+	if err != nil {
+		return nil
+	}
+	return el
+}
+
+// profile returns the profile of the resource of container r: the first
+// loaded profile of the document's meta.profile for the document's own
+// resource, or the base definition of its type.
+func (s *Server) profile(text []byte, c *cursor, r int) string {
+	resourceType := c.containers[r].resourceType
+	if r == 0 {
+		var doc struct {
+			Meta struct {
+				Profile []string `json:"profile"`
+			} `json:"meta"`
+		}
+		_ = json.Unmarshal(text, &doc)
+		for _, url := range doc.Meta.Profile {
+			if sd := s.validator.Registry().GetByURL(url); sd != nil && sd.Type == resourceType {
+				return url
+			}
+		}
+	}
+	return "http://hl7.org/fhir/StructureDefinition/" + resourceType
+}
@@ -0,0 +1,250 @@
+// Package lsp implements a Language Server Protocol server for FHIR resources
+// in JSON, for editors:
+//
+//   - Diagnostics: documents are validated when they are opened and on every
+//     change, and the issues are published at their line and column.
+//     Documents without a resourceType are not FHIR resources and get none.
+//   - Hover: the short description, definition, type, cardinality and
+//     binding of the element under the cursor, from its profile.
+//   - Completion: the member names an object may have and, for coded
+//     elements with a ValueSet that expands locally, its codes.
+//
+// Elements are resolved in the first loaded profile of the resource's
+// meta.profile, or in its base definition; contained and Bundle entry
+// resources use their base definitions. The server reads messages from a
+// reader and writes to a writer, usually stdin and stdout:
+//
+//	err := lsp.New(v).Serve(ctx, os.Stdin, os.Stdout)
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofhir/validator/pkg/validator"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Server is a language server over a Validator. A Server serves one client.
+type Server struct {
+	validator *validator.Validator
+	walker    *walker.Walker
+	opts      []validator.ValidateOption
+
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+
+	mu  sync.Mutex // guards out
+	out *bufio.Writer
+}
+
+// document is an open text document.
+type document struct {
+	version int
+	text    []byte
+}
+
+// New creates a Server validating documents with v and the per-call options
+// (e.g., ValidateWithProfile).
+func New(v *validator.Validator, opts ...validator.ValidateOption) *Server {
+	return &Server{
+		validator: v,
+		walker:    walker.New(v.Registry()),
+		opts:      opts,
+		docs:      make(map[string]*document),
+	}
+}
+
+// Serve reads messages from in and writes responses and notifications to
+// out until the client sends exit, in is closed or ctx is done. It returns
+// nil after a shutdown request and exit, or at the end of in.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = bufio.NewWriter(out)
+	r := bufio.NewReader(in)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		msg, err := readMessage(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(ctx, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a request or notification. Errors are those of writing
+// to the client.
+func (s *Server) handle(ctx context.Context, msg *message) error {
+	if msg.ID == nil {
+		s.notify(ctx, msg)
+		return nil
+	}
+	if !s.initialized && msg.Method != "initialize" {
+		return s.replyError(msg.ID, codeServerNotInitialized, "server not initialized")
+	}
+	if s.shutdown {
+		return s.replyError(msg.ID, codeInvalidRequest, "server is shutting down")
+	}
+
+	switch msg.Method {
+	case "initialize":
+		s.initialized = true
+		return s.reply(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // full
+				"hoverProvider":      true,
+				"completionProvider": map[string]any{"triggerCharacters": []string{`"`}},
+			},
+			"serverInfo": map[string]any{"name": "gofhir-validator"},
+		})
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(msg.ID, nil)
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.replyError(msg.ID, codeInvalidParams, err.Error())
+		}
+		var result any // a nil *hover is not null
+		if doc := s.docs[params.TextDocument.URI]; doc != nil {
+			if h := s.hover(doc.text, params.Position); h != nil {
+				result = h
+			}
+		}
+		return s.reply(msg.ID, result)
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.replyError(msg.ID, codeInvalidParams, err.Error())
+		}
+		list := &completionList{Items: []completionItem{}}
+		if doc := s.docs[params.TextDocument.URI]; doc != nil {
+			list.Items = append(list.Items, s.complete(doc.text, params.Position)...)
+		}
+		return s.reply(msg.ID, list)
+	default:
+		return s.replyError(msg.ID, codeMethodNotFound, "method not supported: "+msg.Method)
+	}
+}
+
+// notify handles a notification. Malformed and unknown notifications are
+// ignored, as the protocol requires.
+func (s *Server) notify(ctx context.Context, msg *message) {
+	if !s.initialized {
+		return
+	}
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return
+		}
+		doc := &document{version: params.TextDocument.Version, text: []byte(params.TextDocument.Text)}
+		s.docs[params.TextDocument.URI] = doc
+		s.publish(ctx, params.TextDocument.URI, doc)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return
+		}
+		doc := s.docs[params.TextDocument.URI]
+		if doc == nil {
+			return
+		}
+		for _, change := range params.ContentChanges {
+			if change.Range == nil {
+				doc.text = []byte(change.Text)
+				continue
+			}
+			start, end := offsetAt(doc.text, change.Range.Start), offsetAt(doc.text, change.Range.End)
+			doc.text = append(doc.text[:start:start], append([]byte(change.Text), doc.text[end:]...)...)
+		}
+		doc.version = params.TextDocument.Version
+		s.publish(ctx, params.TextDocument.URI, doc)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return
+		}
+		delete(s.docs, params.TextDocument.URI)
+		_ = s.send(notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+			Params: publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []diagnostic{}}})
+	}
+}
+
+// publish validates a document and publishes its diagnostics.
+func (s *Server) publish(ctx context.Context, uri string, doc *document) {
+	version := doc.version
+	_ = s.send(notification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+		Params: publishDiagnosticsParams{URI: uri, Version: &version, Diagnostics: s.diagnose(ctx, doc.text)}})
+}
+
+func (s *Server) reply(id *json.RawMessage, result any) error {
+	return s.send(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, msg string) error {
+	return s.send(errorResponse{JSONRPC: "2.0", ID: id, Error: responseError{Code: code, Message: msg}})
+}
+
+// send writes a message with its Content-Length header.
+func (s *Server) send(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	if _, err := s.out.Write(data); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+// readMessage reads a message with its headers.
+func readMessage(r *bufio.Reader) (*message, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if len(header) == 0 && errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("read message: %w", err)
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("decode message: %w", err)
+	}
+	return &msg, nil
+}
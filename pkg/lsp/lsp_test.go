@@ -0,0 +1,210 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+var (
+	sharedValidator     *validator.Validator
+	errSharedValidator  error
+	sharedValidatorOnce sync.Once
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	sharedValidatorOnce.Do(func() {
+		sharedValidator, errSharedValidator = validator.New()
+	})
+	if errSharedValidator != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", errSharedValidator)
+	}
+	return New(sharedValidator)
+}
+
+func TestScanCursor(t *testing.T) {
+	text := `{"resourceType": "Patient", "name": [{"given": ["Ann", "B"]}], "gen`
+	tests := []struct {
+		at      string // Text just before the position
+		inKey   bool
+		inValue bool
+		path    string
+		prefix  string
+	}{
+		{at: `{"resourceType": "Patient", "na`, inKey: true, path: "Patient", prefix: "na"},
+		{at: `"given": ["Ann", "`, inValue: true, path: "Patient.name.given", prefix: ""},
+		{at: `[{"given": [`, inValue: true, path: "Patient.name.given"},
+		{at: `{"resourceType": "Patient", "name": [{`, inKey: true, path: "Patient.name"},
+		{at: `"gen`, inKey: true, path: "Patient", prefix: "gen"},
+	}
+	for _, tt := range tests {
+		c := scanCursor([]byte(text), strings.LastIndex(text, tt.at)+len(tt.at))
+		path, _ := c.elementPath(len(c.containers) - 1)
+		if c.inKey != tt.inKey || c.inValue != tt.inValue || path != tt.path || c.prefix != tt.prefix {
+			t.Errorf("at %q: inKey=%v inValue=%v path=%q prefix=%q, want %v %v %q %q",
+				tt.at, c.inKey, c.inValue, path, c.prefix, tt.inKey, tt.inValue, tt.path, tt.prefix)
+		}
+	}
+
+	// The resourceType of an object may follow the position
+	c := scanCursor([]byte(`{"contained": [{"id": "o", "resourceType": "Organization"}]}`), 17)
+	if path, r := c.elementPath(len(c.containers) - 1); path != "Organization" || r != 2 {
+		t.Errorf("contained path = %q (resource %d), want Organization", path, r)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	text := []byte("{\n  \"a\": \"é𝄞x\"\n}")
+	p := position{Line: 1, Character: 11} // After the G clef, two UTF-16 units
+	offset := offsetAt(text, p)
+	if string(text[offset:offset+1]) != "x" {
+		t.Errorf("offsetAt(%v) = %d (%q), want x", p, offset, text[offset:])
+	}
+	if got := positionAt(text, offset); got != p {
+		t.Errorf("positionAt(%d) = %v, want %v", offset, got, p)
+	}
+	if got := lineOffset(text, 2, 3); got != 4 {
+		t.Errorf("lineOffset(2, 3) = %d, want 4", got)
+	}
+}
+
+// reply is a message from the server.
+type reply struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// serve runs a session with the messages and returns the server's replies.
+func serve(t *testing.T, s *Server, msgs ...map[string]any) []reply {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, msg := range msgs {
+		msg["jsonrpc"] = "2.0"
+		data, _ := json.Marshal(msg)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+	if err := s.Serve(context.Background(), &in, &out); err != nil {
+		t.Fatalf("Serve() error: %v", err)
+	}
+
+	var replies []reply
+	r := textproto.NewReader(bufio.NewReader(&out))
+	for {
+		header, err := r.ReadMIMEHeader()
+		if err != nil {
+			return replies
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r.R, body); err != nil {
+			t.Fatalf("truncated message: %v", err)
+		}
+		var rep reply
+		if err := json.Unmarshal(body, &rep); err != nil {
+			t.Fatalf("invalid message %s: %v", body, err)
+		}
+		replies = append(replies, rep)
+	}
+}
+
+func TestServe(t *testing.T) {
+	s := newTestServer(t)
+	const uri = "file:///patient.json"
+	text := "{\n  \"resourceType\": \"Patient\",\n  \"gender\": \"bogus\",\n  \"name\": [{\"fam\": \"x\"}]\n}"
+	edited := "{\n  \"resourceType\": \"Patient\",\n  \"gender\": \"\"\n}"
+	at := func(line, character int) map[string]any {
+		return map[string]any{"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": line, "character": character}}
+	}
+
+	replies := serve(t, s,
+		map[string]any{"id": 1, "method": "initialize", "params": map[string]any{}},
+		map[string]any{"method": "initialized", "params": map[string]any{}},
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "json", "version": 1, "text": text}}},
+		map[string]any{"id": 2, "method": "textDocument/hover", "params": at(2, 5)},
+		map[string]any{"id": 3, "method": "textDocument/completion", "params": at(3, 15)},
+		map[string]any{"method": "textDocument/didChange", "params": map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []map[string]any{{"text": edited}}}},
+		map[string]any{"id": 4, "method": "textDocument/completion", "params": at(2, 13)},
+		map[string]any{"id": 5, "method": "textDocument/definition", "params": at(2, 5)},
+		map[string]any{"id": 6, "method": "shutdown"},
+		map[string]any{"method": "exit"},
+	)
+
+	results := map[int]reply{}
+	var published []publishDiagnosticsParams
+	for _, rep := range replies {
+		if rep.ID != nil {
+			results[*rep.ID] = rep
+		}
+		if rep.Method == "textDocument/publishDiagnostics" {
+			var params publishDiagnosticsParams
+			_ = json.Unmarshal(rep.Params, &params)
+			published = append(published, params)
+		}
+	}
+
+	if len(published) != 2 || *published[1].Version != 2 {
+		t.Fatalf("published = %+v, want diagnostics for versions 1 and 2", published)
+	}
+	var gender, unknown bool
+	for _, d := range published[0].Diagnostics {
+		switch d.Range.Start.Line {
+		case 2:
+			gender = d.Severity == severityError && d.Range.Start.Character == 2 && d.Range.End.Character == 10
+		case 3:
+			unknown = d.Range.Start.Character == 12
+		}
+	}
+	if !gender || !unknown {
+		t.Errorf("diagnostics = %+v, want the gender binding and the unknown member", published[0].Diagnostics)
+	}
+
+	var h hover
+	if err := json.Unmarshal(results[2].Result, &h); err != nil || !strings.HasPrefix(h.Contents.Value, "**Patient.gender** `code` 0..1") ||
+		h.Range == nil || h.Range.Start.Character != 2 {
+		t.Errorf("hover = %s", results[2].Result)
+	}
+
+	var members completionList
+	_ = json.Unmarshal(results[3].Result, &members)
+	var family *completionItem
+	for i := range members.Items {
+		if members.Items[i].Label == "family" {
+			family = &members.Items[i]
+		}
+	}
+	if family == nil || family.TextEdit == nil || family.TextEdit.Range.Start.Character != 13 || family.TextEdit.Range.End.Character != 16 {
+		t.Errorf("member completions = %s, want family replacing fam", results[3].Result)
+	}
+
+	var codes completionList
+	_ = json.Unmarshal(results[4].Result, &codes)
+	var labels []string
+	for _, item := range codes.Items {
+		labels = append(labels, item.Label)
+	}
+	if !slices.Equal(labels, []string{"female", "male", "other", "unknown"}) {
+		t.Errorf("code completions = %v", labels)
+	}
+
+	if results[5].Error == nil || results[5].Error.Code != codeMethodNotFound {
+		t.Errorf("unsupported request reply = %+v", results[5])
+	}
+}
@@ -0,0 +1,97 @@
+// Package metrics defines the instrumentation hooks of the validator, so
+// that server operators can see where validation time goes:
+//
+//   - metrics are created through a Registerer, which adapts a metrics
+//     library such as Prometheus (see the names below)
+//   - spans are started through a Tracer, which adapts a tracing library
+//     such as OpenTelemetry, around each validation and each phase
+//
+// Both are interfaces so that the validator does not depend on those
+// libraries; the adapters are a few lines each (see docs/USAGE.md).
+package metrics
+
+import "context"
+
+// Metric names, in Prometheus conventions. Durations are in seconds.
+const (
+	// PhaseDuration is a histogram of phase durations, labeled by phase.
+	PhaseDuration = "gofhir_validator_phase_duration_seconds"
+	// ValidationDuration is a histogram of validation durations, labeled by
+	// resource type.
+	ValidationDuration = "gofhir_validator_validation_duration_seconds"
+	// ActiveValidations is a gauge of the validations in progress.
+	ActiveValidations = "gofhir_validator_active_validations"
+	// TerminologyCacheRequests is a counter of ValueSet expansion cache
+	// lookups, labeled by result ("hit" or "miss"); the hit rate is
+	// hits / (hits + misses).
+	TerminologyCacheRequests = "gofhir_validator_terminology_cache_requests_total"
+	// LoadDuration is a histogram of the time taken to load the packages of
+	// a validator.
+	LoadDuration = "gofhir_validator_load_duration_seconds"
+)
+
+// Label names of the metrics.
+const (
+	LabelPhase        = "phase"
+	LabelResourceType = "resource_type"
+	LabelResult       = "result"
+)
+
+// Registerer creates metrics. Label values are passed to each observation
+// in the order of the label names given at creation.
+type Registerer interface {
+	Histogram(name, help string, labels ...string) Histogram
+	Counter(name, help string, labels ...string) Counter
+	Gauge(name, help string, labels ...string) Gauge
+}
+
+// Histogram records a distribution of values.
+type Histogram interface {
+	Observe(value float64, labelValues ...string)
+}
+
+// Counter records a monotonically increasing value.
+type Counter interface {
+	Add(delta float64, labelValues ...string)
+}
+
+// Gauge records a value that goes up and down.
+type Gauge interface {
+	Add(delta float64, labelValues ...string)
+}
+
+// Attribute is a key-value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value any // string, bool, int or float64
+}
+
+// Tracer starts spans. The returned context carries the span, so that
+// spans started from it are its children.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is an operation being traced.
+type Span interface {
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attrs ...Attribute)
+	// End completes the span.
+	End()
+}
+
+// Span names.
+const (
+	// SpanValidate covers a Validate or ValidateMap call.
+	SpanValidate = "gofhir.validate"
+	// SpanPhasePrefix, followed by the phase name, covers one phase.
+	SpanPhasePrefix = "gofhir.validate."
+)
+
+// Span attribute keys.
+const (
+	AttrResourceType = "fhir.resource_type"
+	AttrProfile      = "fhir.profile"
+	AttrErrors       = "fhir.validation.errors"
+	AttrWarnings     = "fhir.validation.warnings"
+)
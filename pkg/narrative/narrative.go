@@ -0,0 +1,281 @@
+// Package narrative validates resource narratives (Narrative.div).
+//
+// The div must be well-formed XHTML restricted to the element and attribute set
+// the FHIR specification allows for narrative (txt-1). Active content such as
+// scripts, event handlers and javascript: URLs is rejected, and references to
+// external images or stylesheets are flagged. When the div declares a language
+// that differs from Resource.language, a warning is reported.
+package narrative
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// xhtmlNamespace is the namespace required on the Narrative div.
+const xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+
+// xmlNamespace is the namespace of the xml:lang attribute.
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// allowedElements is the HTML element set permitted in narrative: the basic
+// formatting elements of HTML 4.0 chapters 7-11 and 15, plus a, img, map and area.
+var allowedElements = map[string]bool{
+	"a": true, "abbr": true, "acronym": true, "address": true, "area": true,
+	"b": true, "bdo": true, "big": true, "blockquote": true, "br": true,
+	"caption": true, "cite": true, "code": true, "col": true, "colgroup": true,
+	"dd": true, "del": true, "dfn": true, "div": true, "dl": true, "dt": true,
+	"em": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"hr": true, "i": true, "img": true, "ins": true, "kbd": true, "li": true,
+	"map": true, "ol": true, "p": true, "pre": true, "q": true, "samp": true,
+	"small": true, "span": true, "strong": true, "sub": true, "sup": true,
+	"table": true, "tbody": true, "td": true, "tfoot": true, "th": true,
+	"thead": true, "tr": true, "tt": true, "u": true, "ul": true, "var": true,
+}
+
+// commonAttributes are allowed on every narrative element.
+var commonAttributes = map[string]bool{
+	"id": true, "class": true, "style": true, "title": true,
+	"lang": true, "dir": true, "accesskey": true, "tabindex": true,
+}
+
+// elementAttributes are the additional attributes allowed on specific elements.
+var elementAttributes = map[string]map[string]bool{
+	"a":          {"href": true, "name": true, "rel": true, "rev": true, "shape": true, "coords": true, "hreflang": true, "type": true, "charset": true},
+	"img":        {"src": true, "alt": true, "longdesc": true, "height": true, "width": true, "usemap": true, "ismap": true, "border": true, "hspace": true, "vspace": true, "align": true},
+	"area":       {"href": true, "alt": true, "shape": true, "coords": true, "nohref": true},
+	"map":        {"name": true},
+	"table":      {"summary": true, "width": true, "border": true, "frame": true, "rules": true, "cellspacing": true, "cellpadding": true, "align": true, "bgcolor": true},
+	"td":         {"abbr": true, "axis": true, "headers": true, "scope": true, "rowspan": true, "colspan": true, "align": true, "char": true, "charoff": true, "valign": true, "nowrap": true, "width": true, "height": true, "bgcolor": true},
+	"th":         {"abbr": true, "axis": true, "headers": true, "scope": true, "rowspan": true, "colspan": true, "align": true, "char": true, "charoff": true, "valign": true, "nowrap": true, "width": true, "height": true, "bgcolor": true},
+	"tr":         {"align": true, "char": true, "charoff": true, "valign": true, "bgcolor": true},
+	"thead":      {"align": true, "char": true, "charoff": true, "valign": true},
+	"tbody":      {"align": true, "char": true, "charoff": true, "valign": true},
+	"tfoot":      {"align": true, "char": true, "charoff": true, "valign": true},
+	"col":        {"span": true, "width": true, "align": true, "char": true, "charoff": true, "valign": true},
+	"colgroup":   {"span": true, "width": true, "align": true, "char": true, "charoff": true, "valign": true},
+	"ol":         {"type": true, "start": true, "compact": true},
+	"ul":         {"type": true, "compact": true},
+	"li":         {"type": true, "value": true},
+	"blockquote": {"cite": true},
+	"q":          {"cite": true},
+	"ins":        {"cite": true, "datetime": true},
+	"del":        {"cite": true, "datetime": true},
+	"bdo":        {"dir": true},
+	"caption":    {"align": true},
+	"p":          {"align": true},
+	"div":        {"align": true},
+	"h1":         {"align": true},
+	"h2":         {"align": true},
+	"h3":         {"align": true},
+	"h4":         {"align": true},
+	"h5":         {"align": true},
+	"h6":         {"align": true},
+	"hr":         {"align": true, "noshade": true, "size": true, "width": true},
+	"br":         {"clear": true},
+	"pre":        {"width": true},
+}
+
+// Validator validates Narrative.div content.
+type Validator struct {
+	walker *walker.Walker
+}
+
+// New creates a new narrative Validator.
+func New(reg *registry.Registry) *Validator {
+	return &Validator{walker: walker.New(reg)}
+}
+
+// ValidateData validates the narrative of a pre-parsed resource and of its
+// contained resources and Bundle entries.
+func (v *Validator) ValidateData(resource map[string]any, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+
+	v.walker.Walk(resource, resourceType, resourceType, func(ctx *walker.ResourceContext) bool {
+		v.validateResource(ctx.Data, ctx.FHIRPath, result)
+		return true
+	})
+}
+
+// validateResource validates text.div of a single resource.
+func (v *Validator) validateResource(data map[string]any, fhirPath string, result *issue.Result) {
+	text, ok := data["text"].(map[string]any)
+	if !ok {
+		return
+	}
+	div, ok := text["div"].(string)
+	if !ok {
+		return
+	}
+
+	divPath := fhirPath + ".text.div"
+	lang := ValidateXHTML(div, divPath, result)
+
+	resourceLang, _ := data["language"].(string)
+	if lang != "" && resourceLang != "" && !strings.EqualFold(lang, resourceLang) {
+		result.AddWarningWithID(
+			issue.DiagNarrativeLanguageMismatch,
+			issue.Params{issue.String("lang", lang), issue.String("language", resourceLang)},
+			divPath,
+		)
+	}
+}
+
+// ValidateXHTML checks narrative XHTML and returns the language declared on the
+// root div (lang or xml:lang), if any.
+func ValidateXHTML(value, fhirPath string, result *issue.Result) string {
+	dec := xml.NewDecoder(strings.NewReader(value))
+	dec.Strict = true
+	dec.Entity = xml.HTMLEntity
+
+	depth := 0
+	rootSeen := false
+	lang := ""
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			result.AddErrorWithID(issue.DiagXHTMLInvalid, issue.Params{issue.String("error", err.Error())}, fhirPath)
+			return lang
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				if rootSeen {
+					result.AddErrorWithID(issue.DiagXHTMLInvalid, issue.Params{issue.String("error", "content must be a single div element")}, fhirPath)
+					return lang
+				}
+				rootSeen = true
+				if t.Name.Local != "div" || t.Name.Space != xhtmlNamespace {
+					result.AddErrorWithID(issue.DiagXHTMLInvalid, issue.Params{issue.String("error", "root element must be a div in the XHTML namespace")}, fhirPath)
+				}
+				lang = declaredLanguage(t)
+			}
+			depth++
+			checkElement(t, fhirPath, result)
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && strings.TrimSpace(string(t)) != "" {
+				result.AddErrorWithID(issue.DiagXHTMLInvalid, issue.Params{issue.String("error", "text outside the root div")}, fhirPath)
+				return lang
+			}
+		case xml.ProcInst:
+			if t.Target != "xml" {
+				result.AddErrorWithID(issue.DiagXHTMLInvalid, issue.Params{issue.String("error", "processing instructions are not allowed")}, fhirPath)
+			}
+		}
+	}
+
+	if !rootSeen {
+		result.AddErrorWithID(issue.DiagXHTMLInvalid, issue.Params{issue.String("error", "content must be a div element")}, fhirPath)
+	}
+	return lang
+}
+
+// declaredLanguage returns the lang or xml:lang attribute of an element.
+func declaredLanguage(el xml.StartElement) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == "lang" && (attr.Name.Space == "" || attr.Name.Space == xmlNamespace) {
+			return strings.TrimSpace(attr.Value)
+		}
+	}
+	return ""
+}
+
+// checkElement validates an element and its attributes against the allowed
+// narrative set, reporting active content and external references.
+func checkElement(el xml.StartElement, fhirPath string, result *issue.Result) {
+	name := el.Name.Local
+
+	switch {
+	case strings.EqualFold(name, "script"):
+		result.AddErrorWithID(issue.DiagXHTMLActiveContent, issue.Params{issue.String("detail", "script element")}, fhirPath)
+		return
+	case el.Name.Space != xhtmlNamespace || !allowedElements[name]:
+		result.AddErrorWithID(issue.DiagXHTMLElementNotAllowed, issue.Params{issue.String("element", name)}, fhirPath)
+		return
+	}
+
+	for _, attr := range el.Attr {
+		attrName := attr.Name.Local
+		lower := strings.ToLower(attrName)
+
+		// Namespace declarations and xml:lang
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attrName == "xmlns") {
+			continue
+		}
+		if attr.Name.Space == xmlNamespace && attrName == "lang" {
+			continue
+		}
+
+		if strings.HasPrefix(lower, "on") {
+			result.AddErrorWithID(
+				issue.DiagXHTMLActiveContent,
+				issue.Params{issue.String("detail", fmt.Sprintf("event handler attribute '%s' on <%s>", attrName, name))},
+				fhirPath,
+			)
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Value)), "javascript:") {
+			result.AddErrorWithID(
+				issue.DiagXHTMLActiveContent,
+				issue.Params{issue.String("detail", fmt.Sprintf("javascript URL in '%s' on <%s>", attrName, name))},
+				fhirPath,
+			)
+			continue
+		}
+		if attr.Name.Space != "" || (!commonAttributes[lower] && !elementAttributes[name][lower]) {
+			result.AddErrorWithID(
+				issue.DiagXHTMLAttributeNotAllowed,
+				issue.Params{issue.String("attribute", attrName), issue.String("element", name)},
+				fhirPath,
+			)
+			continue
+		}
+
+		checkExternalReference(name, lower, attr.Value, fhirPath, result)
+	}
+}
+
+// checkExternalReference flags attributes that make a reader fetch external
+// content when rendering: image sources and url() in inline styles. Hyperlinks
+// (a/@href) are not fetched on render and are allowed.
+func checkExternalReference(element, attr, value, fhirPath string, result *issue.Result) {
+	var ref string
+	switch {
+	case element == "img" && attr == "src":
+		ref = strings.TrimSpace(value)
+		if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(strings.ToLower(ref), "data:") {
+			return
+		}
+	case attr == "style":
+		lower := strings.ToLower(value)
+		idx := strings.Index(lower, "url(")
+		if idx < 0 {
+			return
+		}
+		ref = strings.TrimSpace(value[idx:])
+	default:
+		return
+	}
+
+	result.AddWarningWithID(
+		issue.DiagXHTMLExternalReference,
+		issue.Params{issue.String("reference", ref), issue.String("element", element)},
+		fhirPath,
+	)
+}
@@ -0,0 +1,97 @@
+package narrative
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+func TestValidateXHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		wantID   issue.DiagnosticID
+		wantLang string
+	}{
+		{
+			name:  "valid div",
+			value: `<div xmlns="http://www.w3.org/1999/xhtml"><p class="x">Hello &amp; <b>world</b>&nbsp;</p><table border="1"><tr><td colspan="2">c</td></tr></table></div>`,
+		},
+		{
+			name:     "declared language",
+			value:    `<div xmlns="http://www.w3.org/1999/xhtml" xml:lang="es" lang="es"><p>Hola</p></div>`,
+			wantLang: "es",
+		},
+		{name: "not well-formed", value: `<div xmlns="http://www.w3.org/1999/xhtml"><p>unclosed</div>`, wantID: issue.DiagXHTMLInvalid},
+		{name: "missing namespace", value: `<div><p>text</p></div>`, wantID: issue.DiagXHTMLInvalid},
+		{name: "root not div", value: `<p xmlns="http://www.w3.org/1999/xhtml">text</p>`, wantID: issue.DiagXHTMLInvalid},
+		{name: "plain text", value: `just text`, wantID: issue.DiagXHTMLInvalid},
+		{name: "script element", value: `<div xmlns="http://www.w3.org/1999/xhtml"><script>alert(1)</script></div>`, wantID: issue.DiagXHTMLActiveContent},
+		{name: "event handler", value: `<div xmlns="http://www.w3.org/1999/xhtml"><img src="#a" onerror="x()"/></div>`, wantID: issue.DiagXHTMLActiveContent},
+		{name: "javascript url", value: `<div xmlns="http://www.w3.org/1999/xhtml"><a href=" JavaScript:x()">x</a></div>`, wantID: issue.DiagXHTMLActiveContent},
+		{name: "element not allowed", value: `<div xmlns="http://www.w3.org/1999/xhtml"><iframe/></div>`, wantID: issue.DiagXHTMLElementNotAllowed},
+		{name: "form not allowed", value: `<div xmlns="http://www.w3.org/1999/xhtml"><form><input/></form></div>`, wantID: issue.DiagXHTMLElementNotAllowed},
+		{name: "attribute not allowed", value: `<div xmlns="http://www.w3.org/1999/xhtml"><p href="x">t</p></div>`, wantID: issue.DiagXHTMLAttributeNotAllowed},
+		{name: "external image", value: `<div xmlns="http://www.w3.org/1999/xhtml"><img src="http://example.org/a.png"/></div>`, wantID: issue.DiagXHTMLExternalReference},
+		{name: "style url", value: `<div xmlns="http://www.w3.org/1999/xhtml"><p style="background: url(http://example.org/t.png)">t</p></div>`, wantID: issue.DiagXHTMLExternalReference},
+		{name: "data image allowed", value: `<div xmlns="http://www.w3.org/1999/xhtml"><img src="data:image/png;base64,AAAA"/></div>`},
+		{name: "external link allowed", value: `<div xmlns="http://www.w3.org/1999/xhtml"><a href="http://example.org">x</a></div>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			lang := ValidateXHTML(tt.value, "Patient.text.div", result)
+
+			if lang != tt.wantLang {
+				t.Errorf("lang = %q, want %q", lang, tt.wantLang)
+			}
+			if tt.wantID == "" {
+				if len(result.Issues) != 0 {
+					t.Errorf("expected no issues, got %v", testutil.IssueIDs(result))
+				}
+				return
+			}
+			if len(result.Issues) == 0 || result.Issues[0].MessageID != string(tt.wantID) {
+				t.Errorf("issues = %v, want %s", testutil.IssueIDs(result), tt.wantID)
+			}
+		})
+	}
+}
+
+func TestValidateResourceLanguage(t *testing.T) {
+	tests := []struct {
+		name        string
+		language    string
+		div         string
+		wantWarning bool
+	}{
+		{"matching language", "en-US", `<div xmlns="http://www.w3.org/1999/xhtml" lang="en-us">x</div>`, false},
+		{"no div language", "en", `<div xmlns="http://www.w3.org/1999/xhtml">x</div>`, false},
+		{"no resource language", "", `<div xmlns="http://www.w3.org/1999/xhtml" lang="fr">x</div>`, false},
+		{"mismatch", "en", `<div xmlns="http://www.w3.org/1999/xhtml" xml:lang="fr">x</div>`, true},
+	}
+
+	v := New(registry.New())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]any{
+				"resourceType": "Patient",
+				"text":         map[string]any{"status": "generated", "div": tt.div},
+			}
+			if tt.language != "" {
+				data["language"] = tt.language
+			}
+
+			result := issue.NewResult()
+			v.validateResource(data, "Patient", result)
+
+			got := len(result.Issues) == 1 && result.Issues[0].MessageID == string(issue.DiagNarrativeLanguageMismatch)
+			if got != tt.wantWarning {
+				t.Errorf("language mismatch reported = %v, want %v: %v", got, tt.wantWarning, testutil.IssueIDs(result))
+			}
+		})
+	}
+}
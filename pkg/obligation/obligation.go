@@ -0,0 +1,277 @@
+// Package obligation evaluates obligation extensions
+// (http://hl7.org/fhir/StructureDefinition/obligation) declared on profile
+// elements, for a single configured actor.
+//
+// Obligations replace mustSupport in recent IGs: each one pairs a code such as
+// "SHALL:populate" or "SHOULD:handle" with the actors it applies to. Only the
+// obligations that can be judged from an instance are enforced:
+//
+//   - populate: a missing element is an error (SHALL) or a warning (SHOULD)
+//   - populate-if-known: a missing element is a warning (SHALL) or information (SHOULD)
+//   - SHALL NOT:populate: a present element is an error
+//   - handle: a present element is reported as information, as a reminder
+//     that the receiving actor must process it
+//
+// Obligations without an actor apply to every actor. Obligations on slices
+// are not evaluated.
+package obligation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// ExtensionURL is the canonical URL of the obligation extension.
+const ExtensionURL = "http://hl7.org/fhir/StructureDefinition/obligation"
+
+// Obligation strengths.
+const (
+	StrengthShall    = "SHALL"
+	StrengthShould   = "SHOULD"
+	StrengthMay      = "MAY"
+	StrengthShallNot = "SHALL NOT"
+)
+
+// Obligation is a single obligation declared on a profile element.
+type Obligation struct {
+	Path     string   // Element path (e.g., "Patient.name.family")
+	Code     string   // Full obligation code (e.g., "SHALL:populate")
+	Strength string   // SHALL | SHOULD | MAY | SHALL NOT
+	Verb     string   // populate | populate-if-known | handle | display | ...
+	Actors   []string // Actor canonicals; empty means all actors
+}
+
+// AppliesTo reports whether the obligation binds the given actor.
+func (o Obligation) AppliesTo(actor string) bool {
+	if len(o.Actors) == 0 {
+		return true
+	}
+	for _, a := range o.Actors {
+		if a == actor || strings.HasPrefix(a, actor+"|") {
+			return true
+		}
+	}
+	return false
+}
+
+// Validator evaluates the obligations of a profile for one actor.
+type Validator struct {
+	actor string
+	// cache holds the obligations of each profile that apply to the actor
+	cache sync.Map // SD URL|version -> []Obligation
+}
+
+// New creates a new obligation Validator for the given actor canonical URL.
+func New(actor string) *Validator {
+	return &Validator{actor: actor}
+}
+
+// Actor returns the actor whose obligations are enforced.
+func (v *Validator) Actor() string {
+	return v.actor
+}
+
+// ValidateData evaluates the profile's obligations for the configured actor
+// against a pre-parsed resource.
+func (v *Validator) ValidateData(resource map[string]any, sd *registry.StructureDefinition, result *issue.Result) {
+	if sd == nil || sd.Snapshot == nil {
+		return
+	}
+
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+
+	for _, ob := range v.getObligations(sd) {
+		v.evaluate(resource, resourceType, ob, result)
+	}
+}
+
+// getObligations returns the cached obligations of a profile that apply to the actor.
+func (v *Validator) getObligations(sd *registry.StructureDefinition) []Obligation {
+	key := sd.URL + "|" + sd.Version
+	if sd.URL != "" {
+		if cached, ok := v.cache.Load(key); ok {
+			if obligations, ok := cached.([]Obligation); ok {
+				return obligations
+			}
+		}
+	}
+
+	var obligations []Obligation
+	for _, ob := range Extract(sd) {
+		if ob.AppliesTo(v.actor) {
+			obligations = append(obligations, ob)
+		}
+	}
+
+	if sd.URL != "" {
+		v.cache.Store(key, obligations)
+	}
+	return obligations
+}
+
+// Extract returns every obligation declared on the snapshot elements of a
+// profile, for all actors. Obligations on the root element and on slices are skipped.
+func Extract(sd *registry.StructureDefinition) []Obligation {
+	if sd == nil || sd.Snapshot == nil {
+		return nil
+	}
+
+	var obligations []Obligation
+	for i := range sd.Snapshot.Element {
+		ed := &sd.Snapshot.Element[i]
+		if !strings.Contains(ed.Path, ".") || strings.Contains(ed.ID, ":") {
+			continue
+		}
+		for _, raw := range ed.GetExtensions(ExtensionURL) {
+			obligations = append(obligations, parseObligations(ed.Path, raw)...)
+		}
+	}
+	return obligations
+}
+
+// obligationExtension is the JSON shape of an obligation extension.
+type obligationExtension struct {
+	Extension []struct {
+		URL            string `json:"url"`
+		ValueCode      string `json:"valueCode"`
+		ValueCanonical string `json:"valueCanonical"`
+	} `json:"extension"`
+}
+
+// parseObligations parses an obligation extension. An extension may carry
+// several code and actor sub-extensions; every code applies to every actor.
+func parseObligations(path string, raw json.RawMessage) []Obligation {
+	var ext obligationExtension
+	if err := json.Unmarshal(raw, &ext); err != nil {
+		return nil
+	}
+
+	var codes, actors []string
+	for _, sub := range ext.Extension {
+		switch sub.URL {
+		case "code":
+			if sub.ValueCode != "" {
+				codes = append(codes, sub.ValueCode)
+			}
+		case "actor":
+			if sub.ValueCanonical != "" {
+				actors = append(actors, sub.ValueCanonical)
+			}
+		}
+	}
+
+	obligations := make([]Obligation, 0, len(codes))
+	for _, code := range codes {
+		strength, verb, ok := strings.Cut(code, ":")
+		if !ok {
+			continue
+		}
+		obligations = append(obligations, Obligation{
+			Path:     path,
+			Code:     code,
+			Strength: strength,
+			Verb:     verb,
+			Actors:   actors,
+		})
+	}
+	return obligations
+}
+
+// evaluate checks one obligation against every occurrence of the element's parent.
+func (v *Validator) evaluate(resource map[string]any, resourceType string, ob Obligation, result *issue.Result) {
+	relative := strings.TrimPrefix(ob.Path, resourceType+".")
+	if relative == ob.Path {
+		return // Obligation belongs to a different type
+	}
+
+	segments := strings.Split(relative, ".")
+	name := segments[len(segments)-1]
+
+	for _, parent := range collectParents(resource, resourceType, segments[:len(segments)-1]) {
+		present := isPresent(parent.data, name)
+		elementPath := parent.path + "." + strings.TrimSuffix(name, "[x]")
+		params := issue.Params{issue.String("path", elementPath), issue.String("strength", ob.Strength), issue.String("actor", v.actor), issue.String("code", ob.Code)}
+
+		switch ob.Verb {
+		case "populate":
+			switch {
+			case ob.Strength == StrengthShall && !present:
+				result.AddErrorWithID(issue.DiagObligationMissing, params, elementPath)
+			case ob.Strength == StrengthShould && !present:
+				result.AddWarningWithID(issue.DiagObligationMissing, params, elementPath)
+			case ob.Strength == StrengthShallNot && present:
+				result.AddErrorWithID(issue.DiagObligationProhibited, params, elementPath)
+			}
+		case "populate-if-known":
+			switch {
+			case ob.Strength == StrengthShall && !present:
+				result.AddWarningWithID(issue.DiagObligationMissing, params, elementPath)
+			case ob.Strength == StrengthShould && !present:
+				result.AddInfoWithID(issue.DiagObligationMissing, params, elementPath)
+			}
+		case "handle":
+			if present && (ob.Strength == StrengthShall || ob.Strength == StrengthShould) {
+				result.AddInfoWithID(issue.DiagObligationHandle, params, elementPath)
+			}
+		}
+	}
+}
+
+// node is an object in the resource together with its FHIRPath.
+type node struct {
+	data map[string]any
+	path string
+}
+
+// collectParents returns every object reached by following segments from the
+// resource root, expanding arrays. Choice segments ("value[x]") are not followed.
+func collectParents(resource map[string]any, resourceType string, segments []string) []node {
+	current := []node{{data: resource, path: resourceType}}
+	for _, segment := range segments {
+		var next []node
+		for _, n := range current {
+			switch val := n.data[segment].(type) {
+			case map[string]any:
+				next = append(next, node{data: val, path: n.path + "." + segment})
+			case []any:
+				for i, item := range val {
+					if m, ok := item.(map[string]any); ok {
+						next = append(next, node{data: m, path: fmt.Sprintf("%s.%s[%d]", n.path, segment, i)})
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// isPresent reports whether an element is populated in an object. Choice
+// elements match any typed variant, and primitives may be present through
+// their "_name" extension companion alone.
+func isPresent(data map[string]any, name string) bool {
+	if base, ok := strings.CutSuffix(name, "[x]"); ok {
+		for key := range data {
+			if len(key) > len(base) && strings.HasPrefix(key, base) && key[len(base)] >= 'A' && key[len(base)] <= 'Z' {
+				return true
+			}
+		}
+		return false
+	}
+
+	if val, ok := data[name]; ok && val != nil {
+		if arr, isArr := val.([]any); !isArr || len(arr) > 0 {
+			return true
+		}
+	}
+	_, ok := data["_"+name]
+	return ok
+}
@@ -0,0 +1,162 @@
+package obligation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+const (
+	creatorActor  = "http://example.org/ActorDefinition/creator"
+	consumerActor = "http://example.org/ActorDefinition/consumer"
+)
+
+var testProfile = []byte(`{
+	"resourceType": "StructureDefinition",
+	"url": "http://example.org/StructureDefinition/obligation-patient",
+	"type": "Patient", "kind": "resource", "derivation": "constraint",
+	"snapshot": {"element": [
+		{"id": "Patient", "path": "Patient", "min": 0, "max": "*"},
+		{"id": "Patient.identifier", "path": "Patient.identifier", "min": 0, "max": "*",
+		 "extension": [{"url": "http://hl7.org/fhir/StructureDefinition/obligation", "extension": [
+			{"url": "code", "valueCode": "SHALL:populate"},
+			{"url": "actor", "valueCanonical": "http://example.org/ActorDefinition/creator"}]},
+		 {"url": "http://hl7.org/fhir/StructureDefinition/obligation", "extension": [
+			{"url": "code", "valueCode": "SHALL:handle"},
+			{"url": "actor", "valueCanonical": "http://example.org/ActorDefinition/consumer"}]}]},
+		{"id": "Patient.name", "path": "Patient.name", "min": 0, "max": "*"},
+		{"id": "Patient.name.family", "path": "Patient.name.family", "min": 0, "max": "1",
+		 "extension": [{"url": "http://hl7.org/fhir/StructureDefinition/obligation", "extension": [
+			{"url": "code", "valueCode": "SHOULD:populate"}]}]},
+		{"id": "Patient.deceased[x]", "path": "Patient.deceased[x]", "min": 0, "max": "1",
+		 "extension": [{"url": "http://hl7.org/fhir/StructureDefinition/obligation", "extension": [
+			{"url": "code", "valueCode": "SHALL NOT:populate"},
+			{"url": "actor", "valueCanonical": "http://example.org/ActorDefinition/creator"}]}]},
+		{"id": "Patient.birthDate", "path": "Patient.birthDate", "min": 0, "max": "1",
+		 "extension": [{"url": "http://hl7.org/fhir/StructureDefinition/obligation", "extension": [
+			{"url": "code", "valueCode": "SHALL:populate-if-known"},
+			{"url": "actor", "valueCanonical": "http://example.org/ActorDefinition/creator"}]}]}
+	]}
+}`)
+
+func loadTestProfile(t *testing.T) *registry.StructureDefinition {
+	t.Helper()
+	var sd registry.StructureDefinition
+	if err := json.Unmarshal(testProfile, &sd); err != nil {
+		t.Fatalf("Failed to parse test profile: %v", err)
+	}
+	return &sd
+}
+
+func TestExtract(t *testing.T) {
+	obligations := Extract(loadTestProfile(t))
+	if len(obligations) != 5 {
+		t.Fatalf("Expected 5 obligations, got %d: %+v", len(obligations), obligations)
+	}
+
+	first := obligations[0]
+	if first.Path != "Patient.identifier" || first.Strength != StrengthShall || first.Verb != "populate" {
+		t.Errorf("Unexpected first obligation: %+v", first)
+	}
+	if !first.AppliesTo(creatorActor) || first.AppliesTo(consumerActor) {
+		t.Errorf("Obligation should apply to the creator only: %+v", first)
+	}
+	if family := obligations[2]; !family.AppliesTo(creatorActor) || !family.AppliesTo(consumerActor) {
+		t.Errorf("Obligation without actor should apply to all actors: %+v", family)
+	}
+}
+
+func TestValidateData(t *testing.T) {
+	sd := loadTestProfile(t)
+
+	type want struct {
+		id       issue.DiagnosticID
+		severity issue.Severity
+		path     string
+	}
+
+	tests := []struct {
+		name     string
+		actor    string
+		resource map[string]any
+		want     []want
+	}{
+		{
+			name:  "creator populates everything",
+			actor: creatorActor,
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"identifier":   []any{map[string]any{"value": "1"}},
+				"name":         []any{map[string]any{"family": "Chalmers"}},
+				"birthDate":    "1974-12-25",
+			},
+		},
+		{
+			name:  "creator misses required and known elements",
+			actor: creatorActor,
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"name":         []any{map[string]any{"given": []any{"Peter"}}},
+			},
+			want: []want{
+				{issue.DiagObligationMissing, issue.SeverityError, "Patient.identifier"},
+				{issue.DiagObligationMissing, issue.SeverityWarning, "Patient.name[0].family"},
+				{issue.DiagObligationMissing, issue.SeverityWarning, "Patient.birthDate"},
+			},
+		},
+		{
+			name:  "creator populates a prohibited choice element",
+			actor: creatorActor,
+			resource: map[string]any{
+				"resourceType":    "Patient",
+				"identifier":      []any{map[string]any{"value": "1"}},
+				"birthDate":       "1974-12-25",
+				"deceasedBoolean": false,
+			},
+			want: []want{
+				{issue.DiagObligationProhibited, issue.SeverityError, "Patient.deceased"},
+			},
+		},
+		{
+			name:  "consumer is reminded to handle present elements",
+			actor: consumerActor,
+			resource: map[string]any{
+				"resourceType":    "Patient",
+				"identifier":      []any{map[string]any{"value": "1"}},
+				"deceasedBoolean": false,
+			},
+			want: []want{
+				{issue.DiagObligationHandle, issue.SeverityInformation, "Patient.identifier"},
+			},
+		},
+		{
+			name:  "primitive present through its extension companion",
+			actor: creatorActor,
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"identifier":   []any{map[string]any{"value": "1"}},
+				"_birthDate":   map[string]any{"extension": []any{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			New(tt.actor).ValidateData(tt.resource, sd, result)
+
+			if len(result.Issues) != len(tt.want) {
+				t.Fatalf("Expected %d issues, got %d: %+v", len(tt.want), len(result.Issues), result.Issues)
+			}
+			for i, w := range tt.want {
+				got := result.Issues[i]
+				if got.MessageID != string(w.id) || got.Severity != w.severity || got.Expression[0] != w.path {
+					t.Errorf("Issue %d = %s/%s at %v, want %s/%s at %s",
+						i, got.MessageID, got.Severity, got.Expression, w.id, w.severity, w.path)
+				}
+			}
+		})
+	}
+}
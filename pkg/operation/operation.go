@@ -0,0 +1,296 @@
+// Package operation validates the Parameters resource of an operation call
+// against the OperationDefinition of the operation: every parameter must be
+// an input parameter of the operation, occur within its min/max, and carry a
+// value, resource or parts of its type. Parts of parameters are checked the
+// same way against the parts of their definition.
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// Definition is the part of an OperationDefinition used for validation.
+type Definition struct {
+	URL       string      `json:"url"`
+	Version   string      `json:"version"`
+	Code      string      `json:"code"`
+	Parameter []Parameter `json:"parameter"`
+}
+
+// Parameter is a parameter of an OperationDefinition, or a part of one.
+type Parameter struct {
+	Name        string      `json:"name"`
+	Use         string      `json:"use"` // in | out (top-level parameters only)
+	Min         int         `json:"min"`
+	Max         string      `json:"max"`                   // number or "*"
+	Type        string      `json:"type"`                  // empty for parameters made of parts
+	AllowedType []string    `json:"allowedType,omitempty"` // R5: types allowed for type Any
+	Part        []Parameter `json:"part,omitempty"`
+}
+
+// Parse parses an OperationDefinition.
+func Parse(data []byte) (*Definition, error) {
+	var head struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("invalid OperationDefinition: %w", err)
+	}
+	if head.ResourceType != "OperationDefinition" {
+		return nil, fmt.Errorf("expected an OperationDefinition, got %q", head.ResourceType)
+	}
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("invalid OperationDefinition: %w", err)
+	}
+	return &def, nil
+}
+
+// label returns the name of the operation used in messages.
+func (d *Definition) label() string {
+	if d.Code != "" {
+		return "$" + d.Code
+	}
+	return d.URL
+}
+
+// Registry holds the OperationDefinitions of loaded packages by URL. It is
+// safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	byURL map[string]*Definition // url and url|version -> definition
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{byURL: make(map[string]*Definition)}
+}
+
+// LoadFromPackages loads the OperationDefinitions of packages. When several
+// packages define a URL the first one loaded is its default definition.
+func (r *Registry) LoadFromPackages(packages []*loader.Package) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pkg := range packages {
+		for _, data := range pkg.ResourcesOfType("OperationDefinition") {
+			def, err := Parse(data)
+			if err != nil || def.URL == "" {
+				continue
+			}
+			if _, exists := r.byURL[def.URL]; !exists {
+				r.byURL[def.URL] = def
+			}
+			if def.Version != "" {
+				r.byURL[def.URL+"|"+def.Version] = def
+			}
+		}
+	}
+}
+
+// Get returns the OperationDefinition with a canonical URL ("url" or
+// "url|version"), or nil if none is loaded.
+func (r *Registry) Get(url string) *Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.byURL[url]
+}
+
+// Count returns the number of loaded OperationDefinitions.
+func (r *Registry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n := 0
+	for key := range r.byURL {
+		if !strings.Contains(key, "|") {
+			n++
+		}
+	}
+	return n
+}
+
+// Validator checks Parameters resources against OperationDefinitions.
+type Validator struct {
+	registry *registry.Registry
+}
+
+// New creates a new operation Validator. The registry tells resource types
+// from datatypes.
+func New(reg *registry.Registry) *Validator {
+	return &Validator{registry: reg}
+}
+
+// ValidateData checks the parameters of a pre-parsed Parameters resource
+// against the input parameters of def.
+func (v *Validator) ValidateData(def *Definition, params map[string]any, result *issue.Result) {
+	var inputs []Parameter
+	for _, p := range def.Parameter {
+		if p.Use == "in" {
+			inputs = append(inputs, p)
+		}
+	}
+	items, _ := params["parameter"].([]any)
+	v.validateParameters(def, inputs, items, "", "Parameters", result)
+}
+
+// validateParameters checks a list of parameters or parts against their
+// definitions. Prefix is the name of the enclosing parameter ("" at the top
+// level) and fhirPath the path of the element holding the list.
+func (v *Validator) validateParameters(def *Definition, defs []Parameter, items []any, prefix, fhirPath string, result *issue.Result) {
+	element := "parameter"
+	if prefix != "" {
+		element = "part"
+	}
+
+	byName := make(map[string]*Parameter, len(defs))
+	for i := range defs {
+		byName[defs[i].Name] = &defs[i]
+	}
+
+	counts := make(map[string]int, len(defs))
+	for i, item := range items {
+		param, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		itemPath := fmt.Sprintf("%s.%s[%d]", fhirPath, element, i)
+		name, _ := param["name"].(string)
+		pd, ok := byName[name]
+		if !ok {
+			result.AddErrorWithID(
+				issue.DiagOperationParameterUnknown,
+				issue.Params{issue.String("name", qualified(prefix, name)), issue.String("operation", def.label())},
+				itemPath+".name",
+			)
+			continue
+		}
+
+		counts[name]++
+		if limit, ok := maxOccurs(pd.Max); ok && counts[name] == limit+1 {
+			result.AddErrorWithID(
+				issue.DiagOperationParameterMax,
+				issue.Params{issue.String("name", qualified(prefix, name)), issue.Int("max", limit), issue.String("operation", def.label())},
+				itemPath,
+			)
+		}
+		v.validateParameter(def, pd, param, qualified(prefix, name), itemPath, result)
+	}
+
+	for _, pd := range defs {
+		if count := counts[pd.Name]; count < pd.Min {
+			result.AddErrorWithID(
+				issue.DiagOperationParameterMin,
+				issue.Params{issue.String("name", qualified(prefix, pd.Name)), issue.Int("min", pd.Min), issue.Int("count", count), issue.String("operation", def.label())},
+				fhirPath,
+			)
+		}
+	}
+}
+
+// validateParameter checks the value, resource or parts of a parameter.
+func (v *Validator) validateParameter(def *Definition, pd *Parameter, param map[string]any, name, fhirPath string, result *issue.Result) {
+	actual, valuePath := parameterType(param, v.registry)
+	if actual == "" {
+		parts, ok := param["part"].([]any)
+		if !ok {
+			return // Neither value, resource nor parts: reported by inv-1
+		}
+		if len(pd.Part) > 0 {
+			v.validateParameters(def, pd.Part, parts, name, fhirPath, result)
+			return
+		}
+		actual, valuePath = "part", "part"
+	}
+
+	if !v.typeAllowed(pd, actual) {
+		expected := pd.Type
+		switch {
+		case expected == "" && len(pd.Part) > 0:
+			expected = "part"
+		case len(pd.AllowedType) > 0:
+			expected = strings.Join(pd.AllowedType, " | ")
+		}
+		result.AddErrorWithID(
+			issue.DiagOperationParameterType,
+			issue.Params{issue.String("name", name), issue.String("type", actual), issue.String("expected", expected), issue.String("operation", def.label())},
+			fhirPath+"."+valuePath,
+		)
+	}
+}
+
+// typeAllowed reports whether a value or resource type conforms to the type
+// of a parameter definition.
+func (v *Validator) typeAllowed(pd *Parameter, actual string) bool {
+	if len(pd.AllowedType) > 0 {
+		for _, t := range pd.AllowedType {
+			if v.conforms(t, actual) {
+				return true
+			}
+		}
+		return false
+	}
+	if pd.Type == "" {
+		return len(pd.Part) == 0
+	}
+	return v.conforms(pd.Type, actual)
+}
+
+// conforms reports whether a type conforms to an expected type, which may be
+// an abstract type such as Resource or Any.
+func (v *Validator) conforms(expected, actual string) bool {
+	isResource := v.registry.IsResourceType(actual)
+	switch expected {
+	case actual, "Any":
+		return true
+	case "Resource":
+		return isResource
+	case "DomainResource":
+		return v.registry.IsDomainResource(actual)
+	case "Element", "DataType", "Type":
+		return !isResource
+	}
+	return false
+}
+
+// parameterType returns the type of a parameter's value or resource and the
+// element holding it, or "" if the parameter has neither.
+func parameterType(param map[string]any, reg *registry.Registry) (typeName, element string) {
+	if resource, ok := param["resource"].(map[string]any); ok {
+		resourceType, _ := resource["resourceType"].(string)
+		return resourceType, "resource"
+	}
+	for key := range param {
+		suffix, ok := strings.CutPrefix(key, "value")
+		if !ok || suffix == "" {
+			continue
+		}
+		if primitive := strings.ToLower(suffix[:1]) + suffix[1:]; reg.IsPrimitiveType(primitive) {
+			return primitive, key
+		}
+		return suffix, key
+	}
+	return "", ""
+}
+
+// maxOccurs parses the max of a parameter definition; "*" has no limit.
+func maxOccurs(value string) (int, bool) {
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// qualified returns the name of a part within its parameter (e.g.,
+// "resource.mode").
+func qualified(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
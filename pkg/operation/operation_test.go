@@ -0,0 +1,201 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+)
+
+const lookupDefinition = `{
+	"resourceType": "OperationDefinition",
+	"id": "lookup",
+	"url": "http://example.org/OperationDefinition/lookup",
+	"version": "1.0.0",
+	"code": "lookup",
+	"parameter": [
+		{"name": "code", "use": "in", "min": 1, "max": "1", "type": "code"},
+		{"name": "system", "use": "in", "min": 0, "max": "1", "type": "uri"},
+		{"name": "coding", "use": "in", "min": 0, "max": "1", "type": "Coding"},
+		{"name": "patient", "use": "in", "min": 0, "max": "1", "type": "Patient"},
+		{"name": "context", "use": "in", "min": 0, "max": "*", "type": "Resource"},
+		{"name": "property", "use": "in", "min": 0, "max": "*", "part": [
+			{"name": "code", "min": 1, "max": "1", "type": "code"},
+			{"name": "value", "min": 0, "max": "1", "type": "Any"}
+		]},
+		{"name": "display", "use": "out", "min": 1, "max": "1", "type": "string"}
+	]
+}`
+
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   []any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "valid parameters",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "1234-5"},
+				map[string]any{"name": "system", "valueUri": "http://loinc.org"},
+				map[string]any{"name": "patient", "resource": map[string]any{"resourceType": "Patient"}},
+				map[string]any{"name": "context", "resource": map[string]any{"resourceType": "Encounter"}},
+				map[string]any{"name": "context", "resource": map[string]any{"resourceType": "Bundle"}},
+				map[string]any{"name": "property", "part": []any{
+					map[string]any{"name": "code", "valueCode": "parent"},
+					map[string]any{"name": "value", "valueCoding": map[string]any{"code": "x"}},
+				}},
+			},
+		},
+		{
+			name:     "missing required parameter",
+			params:   []any{map[string]any{"name": "system", "valueUri": "http://loinc.org"}},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterMin},
+			wantPath: "Parameters",
+		},
+		{
+			name: "unknown parameter",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "a"},
+				map[string]any{"name": "language", "valueCode": "en"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterUnknown},
+			wantPath: "Parameters.parameter[1].name",
+		},
+		{
+			name: "output parameter as input",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "a"},
+				map[string]any{"name": "display", "valueString": "A"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterUnknown},
+			wantPath: "Parameters.parameter[1].name",
+		},
+		{
+			name: "parameter repeated beyond max",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "a"},
+				map[string]any{"name": "code", "valueCode": "b"},
+				map[string]any{"name": "code", "valueCode": "c"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterMax},
+			wantPath: "Parameters.parameter[1]",
+		},
+		{
+			name:     "value of another type",
+			params:   []any{map[string]any{"name": "code", "valueString": "a"}},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterType},
+			wantPath: "Parameters.parameter[0].valueString",
+		},
+		{
+			name: "complex value of another type",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "a"},
+				map[string]any{"name": "coding", "valueCodeableConcept": map[string]any{"text": "x"}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterType},
+			wantPath: "Parameters.parameter[1].valueCodeableConcept",
+		},
+		{
+			name: "resource of another type",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "a"},
+				map[string]any{"name": "patient", "resource": map[string]any{"resourceType": "Practitioner"}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterType},
+			wantPath: "Parameters.parameter[1].resource",
+		},
+		{
+			name: "value for a resource parameter",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "a"},
+				map[string]any{"name": "context", "valueString": "Encounter/1"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterType},
+			wantPath: "Parameters.parameter[1].valueString",
+		},
+		{
+			name: "value for a parameter made of parts",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "a"},
+				map[string]any{"name": "property", "valueCode": "parent"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagOperationParameterType},
+			wantPath: "Parameters.parameter[1].valueCode",
+		},
+		{
+			name: "parts checked against their definition",
+			params: []any{
+				map[string]any{"name": "code", "valueCode": "a"},
+				map[string]any{"name": "property", "part": []any{
+					map[string]any{"name": "value", "valueString": "x"},
+					map[string]any{"name": "weight", "valueDecimal": 1},
+				}},
+			},
+			wantIDs: []issue.DiagnosticID{
+				issue.DiagOperationParameterUnknown,
+				issue.DiagOperationParameterMin,
+			},
+			wantPath: "Parameters.parameter[1].part[1].name",
+		},
+	}
+
+	def, err := Parse([]byte(lookupDefinition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := New(testutil.Registry(t, "4.0.1"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(def, map[string]any{"resourceType": "Parameters", "parameter": tt.params}, result)
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", testutil.IssueIDs(result), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	l := loader.NewLoader("")
+	pkg, err := l.LoadFromResources([][]byte{[]byte(lookupDefinition)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	r.LoadFromPackages([]*loader.Package{pkg})
+
+	if r.Count() != 1 {
+		t.Errorf("Count() = %d, want 1", r.Count())
+	}
+	for _, url := range []string{
+		"http://example.org/OperationDefinition/lookup",
+		"http://example.org/OperationDefinition/lookup|1.0.0",
+	} {
+		if def := r.Get(url); def == nil || def.Code != "lookup" {
+			t.Errorf("Get(%q) = %v", url, def)
+		}
+	}
+	if r.Get("http://example.org/OperationDefinition/lookup|2.0.0") != nil {
+		t.Error("Get() returned a version that is not loaded")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse([]byte(`{"resourceType": "Parameters"}`)); err == nil {
+		t.Error("Parse(Parameters) succeeded")
+	}
+	if _, err := Parse([]byte(`{`)); err == nil {
+		t.Error("Parse(invalid JSON) succeeded")
+	}
+}
@@ -0,0 +1,196 @@
+// Package patch derives the element paths changed by JSON Patch (RFC 6902)
+// and FHIRPath Patch documents, for validator.Revalidate.
+//
+// Paths are FHIRPath-style with JSON element names and indexes, such as
+// "Patient.name[0].given[1]". A path is never more specific than the patch
+// allows: a FHIRPath Patch path with functions such as where() is cut back to
+// the element before the first function.
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPatchOp is one operation of a JSON Patch document.
+type jsonPatchOp struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+}
+
+// JSONPatchPaths returns the paths changed by a JSON Patch document applied
+// to a resource of the given type. "test" operations change nothing, and
+// "move" changes both its source and target.
+func JSONPatchPaths(resourceType string, doc []byte) ([]string, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(doc, &ops); err != nil {
+		return nil, fmt.Errorf("invalid JSON Patch: %w", err)
+	}
+
+	var paths []string
+	for i, op := range ops {
+		var pointers []string
+		switch op.Op {
+		case "add", "remove", "replace", "copy":
+			pointers = []string{op.Path}
+		case "move":
+			pointers = []string{op.From, op.Path}
+		case "test":
+			continue
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op %q", i, op.Op)
+		}
+		for _, pointer := range pointers {
+			path, err := pointerPath(resourceType, pointer)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			paths = appendUnique(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// pointerPath converts a JSON Pointer into an element path. The "-" token
+// (the end of an array) selects the whole array.
+func pointerPath(resourceType, pointer string) (string, error) {
+	if pointer == "" {
+		return resourceType, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("invalid JSON Pointer %q", pointer)
+	}
+
+	var b strings.Builder
+	b.WriteString(resourceType)
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if i > 0 {
+			if token == "-" {
+				break
+			}
+			if _, err := strconv.Atoi(token); err == nil {
+				b.WriteString("[" + token + "]")
+				continue
+			}
+		}
+		if token == "" || token == "-" {
+			return "", fmt.Errorf("invalid JSON Pointer %q", pointer)
+		}
+		b.WriteString("." + token)
+	}
+	return b.String(), nil
+}
+
+// parameters is the part of a Parameters resource FHIRPath Patch uses.
+type parameters struct {
+	ResourceType string      `json:"resourceType"`
+	Parameter    []parameter `json:"parameter"`
+}
+
+type parameter struct {
+	Name        string      `json:"name"`
+	ValueCode   string      `json:"valueCode,omitempty"`
+	ValueString string      `json:"valueString,omitempty"`
+	Part        []parameter `json:"part,omitempty"`
+}
+
+// FHIRPathPatchPaths returns the paths changed by a FHIRPath Patch document,
+// a Parameters resource with one "operation" parameter per change.
+func FHIRPathPatchPaths(doc []byte) ([]string, error) {
+	var params parameters
+	if err := json.Unmarshal(doc, &params); err != nil {
+		return nil, fmt.Errorf("invalid FHIRPath Patch: %w", err)
+	}
+	if params.ResourceType != "Parameters" {
+		return nil, fmt.Errorf("invalid FHIRPath Patch: resourceType is %q, want Parameters", params.ResourceType)
+	}
+
+	var paths []string
+	for i, p := range params.Parameter {
+		if p.Name != "operation" {
+			continue
+		}
+		var opType, path, name string
+		for _, part := range p.Part {
+			switch part.Name {
+			case "type":
+				opType = part.ValueCode
+			case "path":
+				path = part.ValueString
+			case "name":
+				name = part.ValueString
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("operation %d: missing path", i)
+		}
+
+		path, exact := elementPath(path)
+		if path == "" {
+			return nil, fmt.Errorf("operation %d: path does not start with an element name", i)
+		}
+		switch opType {
+		case "add":
+			// Add creates a child of the (exactly) selected element
+			if exact && name != "" {
+				path += "." + name
+			}
+		case "insert", "delete", "replace", "move":
+		default:
+			return nil, fmt.Errorf("operation %d: unknown type %q", i, opType)
+		}
+		paths = appendUnique(paths, path)
+	}
+	return paths, nil
+}
+
+// elementPath returns the longest prefix of a FHIRPath expression made of
+// element names and indexes, and whether that prefix is the whole expression.
+func elementPath(expr string) (string, bool) {
+	parts := strings.Split(expr, ".")
+	for i, part := range parts {
+		if !isElementStep(part) {
+			if i == 0 {
+				return "", false
+			}
+			return strings.Join(parts[:i], "."), false
+		}
+	}
+	return expr, true
+}
+
+// isElementStep reports whether s is a name with an optional [n] index.
+func isElementStep(s string) bool {
+	name, index, hasIndex := strings.Cut(s, "[")
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	if !hasIndex {
+		return true
+	}
+	digits, ok := strings.CutSuffix(index, "]")
+	if !ok || digits == "" {
+		return false
+	}
+	_, err := strconv.Atoi(digits)
+	return err == nil
+}
+
+func appendUnique(paths []string, path string) []string {
+	for _, p := range paths {
+		if p == path {
+			return paths
+		}
+	}
+	return append(paths, path)
+}
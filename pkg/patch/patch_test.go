@@ -0,0 +1,93 @@
+package patch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONPatchPaths(t *testing.T) {
+	doc := `[
+  {"op": "test", "path": "/id", "value": "1"},
+  {"op": "replace", "path": "/name/0/given/1", "value": "Jim"},
+  {"op": "add", "path": "/telecom/-", "value": {"system": "phone"}},
+  {"op": "remove", "path": "/contact/0/telecom/0"},
+  {"op": "move", "from": "/address/1", "path": "/address/0"},
+  {"op": "replace", "path": "/name/0/given/1", "value": "Joe"},
+  {"op": "add", "path": "/extension/0/url~1x", "value": "y"}
+]`
+	got, err := JSONPatchPaths("Patient", []byte(doc))
+	if err != nil {
+		t.Fatalf("JSONPatchPaths() error: %v", err)
+	}
+	want := []string{
+		"Patient.name[0].given[1]",
+		"Patient.telecom",
+		"Patient.contact[0].telecom[0]",
+		"Patient.address[1]",
+		"Patient.address[0]",
+		"Patient.extension[0].url/x",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSONPatchPaths() = %q, want %q", got, want)
+	}
+
+	for name, doc := range map[string]string{
+		"not a list":  `{"op": "remove"}`,
+		"unknown op":  `[{"op": "merge", "path": "/name"}]`,
+		"bad pointer": `[{"op": "remove", "path": "name"}]`,
+	} {
+		if _, err := JSONPatchPaths("Patient", []byte(doc)); err == nil {
+			t.Errorf("%s: JSONPatchPaths() should fail", name)
+		}
+	}
+
+	if got, _ := JSONPatchPaths("Patient", []byte(`[{"op": "replace", "path": "", "value": {}}]`)); !reflect.DeepEqual(got, []string{"Patient"}) {
+		t.Errorf("whole-document replace = %q, want [Patient]", got)
+	}
+}
+
+func TestFHIRPathPatchPaths(t *testing.T) {
+	doc := `{
+  "resourceType": "Parameters",
+  "parameter": [
+    {"name": "operation", "part": [
+      {"name": "type", "valueCode": "add"},
+      {"name": "path", "valueString": "Patient.contact[0]"},
+      {"name": "name", "valueString": "gender"},
+      {"name": "value", "valueCode": "male"}
+    ]},
+    {"name": "operation", "part": [
+      {"name": "type", "valueCode": "replace"},
+      {"name": "path", "valueString": "Patient.name.where(use = 'old').given"}
+    ]},
+    {"name": "operation", "part": [
+      {"name": "type", "valueCode": "add"},
+      {"name": "path", "valueString": "Patient.identifier.first()"},
+      {"name": "name", "valueString": "system"}
+    ]},
+    {"name": "operation", "part": [
+      {"name": "type", "valueCode": "delete"},
+      {"name": "path", "valueString": "Patient.birthDate"}
+    ]}
+  ]
+}`
+	got, err := FHIRPathPatchPaths([]byte(doc))
+	if err != nil {
+		t.Fatalf("FHIRPathPatchPaths() error: %v", err)
+	}
+	want := []string{"Patient.contact[0].gender", "Patient.name", "Patient.identifier", "Patient.birthDate"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FHIRPathPatchPaths() = %q, want %q", got, want)
+	}
+
+	for name, doc := range map[string]string{
+		"not Parameters": `{"resourceType": "Patient"}`,
+		"missing path":   `{"resourceType": "Parameters", "parameter": [{"name": "operation", "part": [{"name": "type", "valueCode": "delete"}]}]}`,
+		"unknown type":   `{"resourceType": "Parameters", "parameter": [{"name": "operation", "part": [{"name": "type", "valueCode": "merge"}, {"name": "path", "valueString": "Patient"}]}]}`,
+		"function path":  `{"resourceType": "Parameters", "parameter": [{"name": "operation", "part": [{"name": "type", "valueCode": "delete"}, {"name": "path", "valueString": "%resource.name"}]}]}`,
+	} {
+		if _, err := FHIRPathPatchPaths([]byte(doc)); err == nil {
+			t.Errorf("%s: FHIRPathPatchPaths() should fail", name)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package phase names the validation phases run by the validator, for
+// selecting which of them run (see validator.WithPhases and
+// validator.ValidateWithPhases).
+//
+// Names are the identifiers reported in Stats.SkippedPhases and in phase
+// timeout diagnostics. Parse also accepts a few aliases, such as
+// "terminology" for Binding and "references" for Reference.
+package phase
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Name identifies a validation phase.
+type Name string
+
+// Validation phases, in the order they run.
+const (
+	Structure     Name = "structural"
+	Cardinality   Name = "cardinality"
+	Primitives    Name = "primitive"
+	Binding       Name = "binding"
+	Extensions    Name = "extension"
+	Reference     Name = "reference"
+	Contained     Name = "contained"
+	Narrative     Name = "narrative"
+	Constraints   Name = "constraint"
+	FixedPattern  Name = "fixed-pattern"
+	Slicing       Name = "slicing"
+	Identifiers   Name = "identifier"
+	Datatypes     Name = "datatype"
+	Subscription  Name = "subscription"
+	Bundle        Name = "bundle"        // Only runs for Bundles
+	Sanity        Name = "sanity"        // Only runs with validator.WithSanityChecks
+	Audit         Name = "audit"         // Only runs with validator.WithAuditRules
+	Obligations   Name = "obligation"    // Only runs with validator.WithActor
+	Authoring     Name = "authoring"     // Only runs with validator.WithAuthorMode
+	BusinessRules Name = "business-rule" // Only runs with validator.WithBusinessRules
+	Uniqueness    Name = "uniqueness"    // Only runs with validator.WithUniquenessChecks or in a validator.Session
+)
+
+// Terminology is an alias of Binding: the phase that checks codes against
+// their ValueSet bindings.
+const Terminology = Binding
+
+var all = []Name{
+	Structure, Cardinality, Primitives, Binding, Extensions, Reference,
+	Contained, Narrative, Constraints, FixedPattern, Slicing, Identifiers, Datatypes, Subscription, Bundle, Sanity,
+	Audit, Obligations, Authoring, BusinessRules, Uniqueness,
+}
+
+// aliases maps alternative spellings to phase names.
+var aliases = map[string]Name{
+	"structure":      Structure,
+	"primitives":     Primitives,
+	"terminology":    Binding,
+	"bindings":       Binding,
+	"extensions":     Extensions,
+	"references":     Reference,
+	"constraints":    Constraints,
+	"invariants":     Constraints,
+	"fixed":          FixedPattern,
+	"pattern":        FixedPattern,
+	"identifiers":    Identifiers,
+	"datatypes":      Datatypes,
+	"subscriptions":  Subscription,
+	"bundles":        Bundle,
+	"obligations":    Obligations,
+	"business-rules": BusinessRules,
+	"rules":          BusinessRules,
+	"unique":         Uniqueness,
+}
+
+// All returns every phase name, in the order the phases run.
+func All() []Name {
+	return append([]Name(nil), all...)
+}
+
+// Parse resolves a phase name or alias, case-insensitively.
+func Parse(s string) (Name, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, name := range all {
+		if string(name) == s {
+			return name, nil
+		}
+	}
+	if name, ok := aliases[s]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown validation phase %q (available: %s)", s, strings.Join(names(all), ", "))
+}
+
+// Resolve parses a list of names, returning the canonical names. Names of
+// plugin phases given in plugins are accepted as they are.
+func Resolve(names []Name, plugins ...Name) ([]Name, error) {
+	resolved := make([]Name, 0, len(names))
+	for _, n := range names {
+		if slices.Contains(plugins, n) {
+			resolved = append(resolved, n)
+			continue
+		}
+		name, err := Parse(string(n))
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, name)
+	}
+	return resolved, nil
+}
+
+// Set is a selection of phases. The zero value selects every phase.
+type Set struct {
+	only     map[Name]bool // nil = every phase
+	disabled map[Name]bool
+}
+
+// NewSet selects the phases in only (every phase if empty) except those in
+// disabled. Names must be canonical (see Resolve).
+func NewSet(only, disabled []Name) Set {
+	var s Set
+	if len(only) > 0 {
+		s.only = make(map[Name]bool, len(only))
+		for _, n := range only {
+			s.only[n] = true
+		}
+	}
+	if len(disabled) > 0 {
+		s.disabled = make(map[Name]bool, len(disabled))
+		for _, n := range disabled {
+			s.disabled[n] = true
+		}
+	}
+	return s
+}
+
+// Enabled reports whether a phase is selected.
+func (s Set) Enabled(name Name) bool {
+	return (s.only == nil || s.only[name]) && !s.disabled[name]
+}
+
+// Without returns a copy of the set that also excludes the given phases.
+func (s Set) Without(names ...Name) Set {
+	disabled := make(map[Name]bool, len(s.disabled)+len(names))
+	for n := range s.disabled {
+		disabled[n] = true
+	}
+	for _, n := range names {
+		disabled[n] = true
+	}
+	return Set{only: s.only, disabled: disabled}
+}
+
+func names(list []Name) []string {
+	out := make([]string, len(list))
+	for i, n := range list {
+		out[i] = string(n)
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package phase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestParse(t *testing.T) {
+	for in, want := range map[string]Name{
+		"structural":  Structure,
+		"Structure":   Structure,
+		"terminology": Binding,
+		"references":  Reference,
+		"constraint":  Constraints,
+		"invariants":  Constraints,
+	} {
+		got, err := Parse(in)
+		if err != nil || got != want {
+			t.Errorf("Parse(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := Parse("spelling"); err == nil {
+		t.Error("Parse(\"spelling\") should fail")
+	}
+}
+
+func TestSet(t *testing.T) {
+	var all Set
+	for _, name := range All() {
+		if !all.Enabled(name) {
+			t.Errorf("zero Set should enable %s", name)
+		}
+	}
+
+	s := NewSet([]Name{Structure, Binding}, []Name{Binding})
+	if !s.Enabled(Structure) || s.Enabled(Binding) || s.Enabled(Narrative) {
+		t.Errorf("NewSet(only structure, binding; without binding) selected wrong phases")
+	}
+
+	w := s.Without(Structure)
+	if w.Enabled(Structure) || !s.Enabled(Structure) {
+		t.Errorf("Without(structure) should disable structure in the copy only")
+	}
+}
+
+func TestCheckPlugins(t *testing.T) {
+	run := func(context.Context, *Context, *issue.Result) {}
+	if err := CheckPlugins([]Plugin{{Name: "payer", Run: run}, {Name: "claims", Run: run}}); err != nil {
+		t.Errorf("CheckPlugins() error: %v", err)
+	}
+	for name, plugins := range map[string][]Plugin{
+		"no name":  {{Run: run}},
+		"no Run":   {{Name: "payer"}},
+		"twice":    {{Name: "payer", Run: run}, {Name: "payer", Run: run}},
+		"built-in": {{Name: "terminology", Run: run}},
+	} {
+		if err := CheckPlugins(plugins); err == nil {
+			t.Errorf("CheckPlugins(%s) should fail", name)
+		}
+	}
+
+	got, err := Resolve([]Name{"payer", "structural"}, "payer")
+	if err != nil || len(got) != 2 || got[0] != "payer" || got[1] != Structure {
+		t.Errorf("Resolve() = %v, %v; want [payer structure]", got, err)
+	}
+	if _, err := Resolve([]Name{"payer"}); err == nil {
+		t.Error("Resolve() should reject a plugin name it is not given")
+	}
+}
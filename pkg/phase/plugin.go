@@ -0,0 +1,85 @@
+package phase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Plugin is a validation phase supplied by the caller, such as a payer's
+// business rules, registered with validator.WithPhasePlugin. Plugins run
+// after the built-in phases, in the order they are registered, once per
+// profile the resource is validated against. Their names select and disable
+// them like the names of built-in phases, and phase timeouts, tracing and
+// metrics apply to them as well.
+type Plugin struct {
+	// Name identifies the phase in phase selections, Stats.SkippedPhases
+	// and timeout diagnostics. It must not be a built-in name or alias.
+	Name Name
+
+	// ResourceTypes limits the phase to resources of these types; nil runs
+	// it for every resource.
+	ResourceTypes []string
+
+	// Run checks a resource, adding issues to result (e.g., with
+	// result.AddError). It is called concurrently by concurrent validations
+	// and should return early once ctx is done.
+	Run func(ctx context.Context, rc *Context, result *issue.Result)
+}
+
+// Context is the resource a Plugin checks, with the loaded definitions to
+// interpret it.
+type Context struct {
+	// Resource is the decoded resource; numbers are json.Number.
+	Resource map[string]any
+
+	// Raw is the resource JSON.
+	Raw []byte
+
+	// ResourceType is the type of Resource (e.g., "Claim").
+	ResourceType string
+
+	// Profile is the StructureDefinition the resource is being validated
+	// against: a profile, or the core definition of its type.
+	Profile *registry.StructureDefinition
+
+	// Registry holds the loaded StructureDefinitions, e.g., for type
+	// classification with IsResourceType or IsPrimitiveType.
+	Registry *registry.Registry
+
+	// Walker visits contained and Bundle entry resources (Walk), and
+	// element values with their definitions and resolved types
+	// (WalkElements); ResolvePath looks up the definition of a path.
+	Walker *walker.Walker
+}
+
+// Applies reports whether the plugin runs for resources of a type.
+func (p *Plugin) Applies(resourceType string) bool {
+	return p.ResourceTypes == nil || slices.Contains(p.ResourceTypes, resourceType)
+}
+
+// CheckPlugins reports plugins without a name or a Run function, and plugin
+// names that repeat or clash with built-in phase names and aliases.
+func CheckPlugins(plugins []Plugin) error {
+	seen := make(map[Name]bool, len(plugins))
+	for _, p := range plugins {
+		switch {
+		case p.Name == "":
+			return errors.New("phase plugin without a name")
+		case p.Run == nil:
+			return fmt.Errorf("phase plugin %q has no Run function", p.Name)
+		case seen[p.Name]:
+			return fmt.Errorf("phase plugin %q registered twice", p.Name)
+		}
+		if _, err := Parse(string(p.Name)); err == nil {
+			return fmt.Errorf("phase plugin %q clashes with a built-in phase", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
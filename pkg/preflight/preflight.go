@@ -0,0 +1,267 @@
+// Package preflight reports the terminology content a set of profiles depends
+// on, so implementers know which ValueSets and CodeSystems to provision (or
+// which terminology server to configure) before running bulk validation.
+package preflight
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/terminology"
+)
+
+// Status classifies how a ValueSet or CodeSystem can be checked.
+type Status string
+
+// Status constants, from best to worst.
+const (
+	// StatusLocal means codes can be checked against loaded content.
+	StatusLocal Status = "local"
+	// StatusServer means the content is loaded only partially or not at all
+	// by design (large external terminologies such as SNOMED CT or LOINC,
+	// CodeSystems with content not-present, example or fragment), so a
+	// terminology server is needed to check codes.
+	StatusServer Status = "server"
+	// StatusMissing means the resource, or a CodeSystem or ValueSet it
+	// depends on, is not loaded.
+	StatusMissing Status = "missing"
+)
+
+func (s Status) rank() int {
+	switch s {
+	case StatusServer:
+		return 1
+	case StatusMissing:
+		return 2
+	}
+	return 0
+}
+
+func worse(a, b Status) Status {
+	if b.rank() > a.rank() {
+		return b
+	}
+	return a
+}
+
+// Binding is an element bound to a ValueSet.
+type Binding struct {
+	// Profile is the canonical URL of the StructureDefinition.
+	Profile string
+	// ElementID is the ElementDefinition.id (or path when no id is present).
+	ElementID string
+	// Strength is the binding strength (required or extensible).
+	Strength string
+}
+
+// ValueSetStatus reports a ValueSet referenced by one or more bindings.
+type ValueSetStatus struct {
+	// URL is the binding's ValueSet reference, possibly "url|version".
+	URL string
+	// Status is the worst status of the ValueSet, the CodeSystems it draws
+	// codes from and the ValueSets it includes.
+	Status Status
+	// CodeSystems are the systems the ValueSet needs for its expansion,
+	// including those of nested ValueSets. Systems whose codes are listed
+	// explicitly in the compose are not needed and not included.
+	CodeSystems []string
+	// Bindings are the elements bound to the ValueSet.
+	Bindings []Binding
+}
+
+// CodeSystemStatus reports a CodeSystem needed by one or more ValueSets.
+type CodeSystemStatus struct {
+	// URL is the system URL, possibly "url|version".
+	URL string
+	// Status is how codes from the system can be checked.
+	Status Status
+	// Content is the loaded CodeSystem's content (complete, fragment, ...),
+	// empty when the CodeSystem is not loaded.
+	Content string
+	// ValueSets are the bound ValueSets that need the system.
+	ValueSets []string
+}
+
+// Report is the result of a terminology preflight check.
+type Report struct {
+	// ValueSets are sorted by URL.
+	ValueSets []ValueSetStatus
+	// CodeSystems are sorted by URL.
+	CodeSystems []CodeSystemStatus
+}
+
+// Count returns the number of ValueSets with the given status.
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, vs := range r.ValueSets {
+		if vs.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Check scans the required and extensible bindings of the given profiles
+// (preferred and example bindings are never validated) and reports whether
+// each bound ValueSet can be checked locally, needs a terminology server, or
+// is missing. Unknown profile URLs are ignored.
+func Check(reg *registry.Registry, term *terminology.Registry, profileURLs []string) *Report {
+	c := &checker{
+		term:        term,
+		valueSets:   make(map[string]*ValueSetStatus),
+		codeSystems: make(map[string]*CodeSystemStatus),
+	}
+	for _, url := range profileURLs {
+		sd := reg.GetByURL(url)
+		if sd == nil {
+			continue
+		}
+		for _, elem := range elements(sd) {
+			if elem.Binding == nil || elem.Binding.ValueSet == "" {
+				continue
+			}
+			if elem.Binding.Strength != "required" && elem.Binding.Strength != "extensible" {
+				continue
+			}
+			id := elem.ID
+			if id == "" {
+				id = elem.Path
+			}
+			vs := c.valueSet(elem.Binding.ValueSet)
+			vs.Bindings = append(vs.Bindings, Binding{Profile: sd.URL, ElementID: id, Strength: elem.Binding.Strength})
+		}
+	}
+
+	report := &Report{}
+	for _, vs := range c.valueSets {
+		report.ValueSets = append(report.ValueSets, *vs)
+	}
+	for _, cs := range c.codeSystems {
+		sort.Strings(cs.ValueSets)
+		report.CodeSystems = append(report.CodeSystems, *cs)
+	}
+	sort.Slice(report.ValueSets, func(i, j int) bool { return report.ValueSets[i].URL < report.ValueSets[j].URL })
+	sort.Slice(report.CodeSystems, func(i, j int) bool { return report.CodeSystems[i].URL < report.CodeSystems[j].URL })
+	return report
+}
+
+// ProfilesInPackages returns the StructureDefinitions loaded from the named
+// packages (any version), for scoping Check to an implementation guide.
+func ProfilesInPackages(reg *registry.Registry, packages ...string) []string {
+	var urls []string
+	for _, url := range reg.AllURLs() {
+		if src, ok := reg.Source(url); ok && slices.Contains(packages, src.Name) {
+			urls = append(urls, url)
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+type checker struct {
+	term        *terminology.Registry
+	valueSets   map[string]*ValueSetStatus
+	codeSystems map[string]*CodeSystemStatus
+}
+
+// valueSet returns the status entry for a bound ValueSet, computing it on
+// first use.
+func (c *checker) valueSet(url string) *ValueSetStatus {
+	if vs, ok := c.valueSets[url]; ok {
+		return vs
+	}
+	systems := make(map[string]bool)
+	status := c.resolve(url, systems, make(map[string]bool))
+
+	vs := &ValueSetStatus{URL: url, Status: status}
+	for system := range systems {
+		vs.CodeSystems = append(vs.CodeSystems, system)
+		cs := c.codeSystem(system)
+		cs.ValueSets = append(cs.ValueSets, url)
+	}
+	sort.Strings(vs.CodeSystems)
+	c.valueSets[url] = vs
+	return vs
+}
+
+// resolve walks a ValueSet's compose, collecting the systems it needs and
+// returning its worst status. seen guards against include cycles.
+func (c *checker) resolve(url string, systems, seen map[string]bool) Status {
+	if seen[url] {
+		return StatusLocal
+	}
+	seen[url] = true
+
+	vs := c.term.GetValueSet(url)
+	if vs == nil {
+		return StatusMissing
+	}
+	status := StatusLocal
+	for _, inc := range vs.Compose.Include {
+		if inc.System != "" && len(inc.Concept) == 0 {
+			system := inc.System
+			if inc.Version != "" {
+				system += "|" + inc.Version
+			}
+			systems[system] = true
+			status = worse(status, c.codeSystem(system).Status)
+		}
+		for _, nested := range inc.ValueSet {
+			status = worse(status, c.resolve(nested, systems, seen))
+		}
+	}
+	return status
+}
+
+// codeSystem returns the status entry for a system, computing it on first use.
+func (c *checker) codeSystem(system string) *CodeSystemStatus {
+	if cs, ok := c.codeSystems[system]; ok {
+		return cs
+	}
+	cs := &CodeSystemStatus{URL: system}
+	url, _ := loader.SplitCanonical(system)
+	switch loaded := c.term.GetCodeSystem(system); {
+	case c.term.IsImplicitSystem(url) && (loaded == nil || len(loaded.Concept) == 0):
+		cs.Status = StatusLocal
+		if loaded != nil {
+			cs.Content = loaded.Content
+		}
+	case c.term.IsExternalSystem(url):
+		cs.Status = StatusServer
+		if loaded != nil {
+			cs.Content = loaded.Content
+		}
+	case loaded == nil:
+		cs.Status = StatusMissing
+	default:
+		cs.Content = loaded.Content
+		cs.Status = contentStatus(loaded.Content)
+	}
+	c.codeSystems[system] = cs
+	return cs
+}
+
+// contentStatus maps CodeSystem.content to a status. Supplements and
+// CodeSystems without a content value are treated as complete.
+func contentStatus(content string) Status {
+	switch strings.ToLower(content) {
+	case "not-present", "example", "fragment":
+		return StatusServer
+	}
+	return StatusLocal
+}
+
+// elements returns the snapshot elements of a StructureDefinition, or the
+// differential when it has no snapshot.
+func elements(sd *registry.StructureDefinition) []registry.ElementDefinition {
+	if sd.Snapshot != nil && len(sd.Snapshot.Element) > 0 {
+		return sd.Snapshot.Element
+	}
+	if sd.Differential != nil {
+		return sd.Differential.Element
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package preflight
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/terminology"
+)
+
+const profileURL = "http://example.org/StructureDefinition/my-observation"
+
+var testResources = map[string]string{
+	profileURL: `{"resourceType": "StructureDefinition", "url": "` + profileURL + `",
+		"type": "Observation", "kind": "resource", "derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Observation", "path": "Observation"},
+			{"id": "Observation.status", "path": "Observation.status",
+				"binding": {"strength": "required", "valueSet": "http://example.org/ValueSet/status"}},
+			{"id": "Observation.category", "path": "Observation.category",
+				"binding": {"strength": "extensible", "valueSet": "http://example.org/ValueSet/category"}},
+			{"id": "Observation.code", "path": "Observation.code",
+				"binding": {"strength": "extensible", "valueSet": "http://example.org/ValueSet/lab"}},
+			{"id": "Observation.method", "path": "Observation.method",
+				"binding": {"strength": "required", "valueSet": "http://example.org/ValueSet/method"}},
+			{"id": "Observation.bodySite", "path": "Observation.bodySite",
+				"binding": {"strength": "required", "valueSet": "http://example.org/ValueSet/unknown"}},
+			{"id": "Observation.interpretation", "path": "Observation.interpretation",
+				"binding": {"strength": "required", "valueSet": "http://example.org/ValueSet/nested"}},
+			{"id": "Observation.dataAbsentReason", "path": "Observation.dataAbsentReason",
+				"binding": {"strength": "preferred", "valueSet": "http://example.org/ValueSet/absent"}}
+		]}}`,
+	"http://example.org/ValueSet/status": `{"resourceType": "ValueSet", "url": "http://example.org/ValueSet/status",
+		"compose": {"include": [{"system": "http://example.org/CodeSystem/status"}]}}`,
+	"http://example.org/ValueSet/category": `{"resourceType": "ValueSet", "url": "http://example.org/ValueSet/category",
+		"compose": {"include": [{"system": "http://example.org/CodeSystem/category"}]}}`,
+	"http://example.org/ValueSet/lab": `{"resourceType": "ValueSet", "url": "http://example.org/ValueSet/lab",
+		"compose": {"include": [{"system": "http://loinc.org"},
+			{"system": "http://example.org/CodeSystem/not-loaded", "concept": [{"code": "a"}]}]}}`,
+	"http://example.org/ValueSet/method": `{"resourceType": "ValueSet", "url": "http://example.org/ValueSet/method",
+		"compose": {"include": [{"system": "http://example.org/CodeSystem/not-loaded"}]}}`,
+	"http://example.org/ValueSet/nested": `{"resourceType": "ValueSet", "url": "http://example.org/ValueSet/nested",
+		"compose": {"include": [{"valueSet": ["http://example.org/ValueSet/status", "http://example.org/ValueSet/category"]}]}}`,
+	"http://example.org/CodeSystem/status": `{"resourceType": "CodeSystem", "url": "http://example.org/CodeSystem/status",
+		"content": "complete", "concept": [{"code": "final"}]}`,
+	"http://example.org/CodeSystem/category": `{"resourceType": "CodeSystem", "url": "http://example.org/CodeSystem/category",
+		"content": "fragment", "concept": [{"code": "lab"}]}`,
+}
+
+func newTestRegistries(t *testing.T) (*registry.Registry, *terminology.Registry) {
+	t.Helper()
+	pkg := &loader.Package{Name: "example.ig", Version: "1.0.0", Resources: make(map[string]json.RawMessage)}
+	for url, data := range testResources {
+		pkg.Resources[url] = json.RawMessage(data)
+	}
+	packages := []*loader.Package{pkg}
+
+	reg := registry.New()
+	if err := reg.LoadFromPackages(packages); err != nil {
+		t.Fatalf("registry LoadFromPackages failed: %v", err)
+	}
+	term := terminology.NewRegistry()
+	if err := term.LoadFromPackages(packages); err != nil {
+		t.Fatalf("terminology LoadFromPackages failed: %v", err)
+	}
+	return reg, term
+}
+
+func TestCheck(t *testing.T) {
+	reg, term := newTestRegistries(t)
+	report := Check(reg, term, ProfilesInPackages(reg, "example.ig"))
+
+	wantValueSets := map[string]Status{
+		"http://example.org/ValueSet/status":   StatusLocal,
+		"http://example.org/ValueSet/category": StatusServer,  // fragment CodeSystem
+		"http://example.org/ValueSet/lab":      StatusServer,  // LOINC; explicit concepts need no CodeSystem
+		"http://example.org/ValueSet/method":   StatusMissing, // CodeSystem not loaded
+		"http://example.org/ValueSet/unknown":  StatusMissing,
+		"http://example.org/ValueSet/nested":   StatusServer, // worst of the included ValueSets
+	}
+	got := make(map[string]Status)
+	for _, vs := range report.ValueSets {
+		got[vs.URL] = vs.Status
+	}
+	if !reflect.DeepEqual(got, wantValueSets) {
+		t.Errorf("ValueSet statuses = %v, want %v", got, wantValueSets)
+	}
+	if report.Count(StatusMissing) != 2 || report.Count(StatusServer) != 3 || report.Count(StatusLocal) != 1 {
+		t.Errorf("Count() = local %d, server %d, missing %d; want 1, 3, 2",
+			report.Count(StatusLocal), report.Count(StatusServer), report.Count(StatusMissing))
+	}
+
+	wantCodeSystems := []CodeSystemStatus{
+		{URL: "http://example.org/CodeSystem/category", Status: StatusServer, Content: "fragment",
+			ValueSets: []string{"http://example.org/ValueSet/category", "http://example.org/ValueSet/nested"}},
+		{URL: "http://example.org/CodeSystem/not-loaded", Status: StatusMissing,
+			ValueSets: []string{"http://example.org/ValueSet/method"}},
+		{URL: "http://example.org/CodeSystem/status", Status: StatusLocal, Content: "complete",
+			ValueSets: []string{"http://example.org/ValueSet/nested", "http://example.org/ValueSet/status"}},
+		{URL: "http://loinc.org", Status: StatusServer,
+			ValueSets: []string{"http://example.org/ValueSet/lab"}},
+	}
+	if !reflect.DeepEqual(report.CodeSystems, wantCodeSystems) {
+		t.Errorf("CodeSystems = %+v, want %+v", report.CodeSystems, wantCodeSystems)
+	}
+
+	for _, vs := range report.ValueSets {
+		if vs.URL != "http://example.org/ValueSet/method" {
+			continue
+		}
+		want := []Binding{{Profile: profileURL, ElementID: "Observation.method", Strength: "required"}}
+		if !reflect.DeepEqual(vs.Bindings, want) {
+			t.Errorf("Bindings = %+v, want %+v", vs.Bindings, want)
+		}
+	}
+}
+
+func TestProfilesInPackages(t *testing.T) {
+	reg, _ := newTestRegistries(t)
+	if got := ProfilesInPackages(reg, "example.ig"); !reflect.DeepEqual(got, []string{profileURL}) {
+		t.Errorf("ProfilesInPackages(example.ig) = %v, want [%s]", got, profileURL)
+	}
+	if got := ProfilesInPackages(reg, "other.ig"); len(got) != 0 {
+		t.Errorf("ProfilesInPackages(other.ig) = %v, want none", got)
+	}
+}
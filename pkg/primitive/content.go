@@ -0,0 +1,185 @@
+package primitive
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofhir/validator/pkg/canonical"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// typeBase64Binary has content checks beyond the regex. Narrative xhtml is
+// checked by the narrative package.
+const typeBase64Binary = "base64Binary"
+
+// maxDecimalDigits is the precision implementations are required to support
+// for decimal values (FHIR datatypes: "at least 18 digits").
+const maxDecimalDigits = 18
+
+// maxDecimalPlacesURL is the ElementDefinition extension limiting the digits
+// after the decimal point of a decimal element.
+const maxDecimalPlacesURL = "http://hl7.org/fhir/StructureDefinition/maxDecimalPlaces"
+
+// SetMaxBase64Size limits the decoded size of base64Binary values in bytes.
+// Zero (the default) disables the limit.
+func (v *Validator) SetMaxBase64Size(size int) {
+	v.maxBase64Size = size
+}
+
+// validateMaxLength checks a string value against ElementDefinition.maxLength.
+// Length is counted in characters, not bytes.
+func validateMaxLength(value string, maxLength int, fhirPath string, result *issue.Result) {
+	if maxLength <= 0 {
+		return
+	}
+	if length := utf8.RuneCountInString(value); length > maxLength {
+		result.AddErrorWithID(
+			issue.DiagTypeMaxLength,
+			issue.Params{issue.Int("length", length), issue.Int("max", maxLength)},
+			fhirPath,
+		)
+	}
+}
+
+// validateMaxDecimalPlaces reports a decimal written with more digits after
+// the decimal point than the element's maxDecimalPlaces extension allows.
+func validateMaxDecimalPlaces(value any, ed *registry.ElementDefinition, fhirPath string, result *issue.Result) {
+	maxPlaces, ok := maxDecimalPlaces(ed)
+	if !ok {
+		return
+	}
+	literal := decimalLiteral(value)
+	if places := decimalPlaces(literal); places > maxPlaces {
+		result.AddErrorWithID(
+			issue.DiagTypeDecimalPlaces,
+			issue.Params{issue.String("value", truncateValue(literal)), issue.Int("places", places), issue.Int("max", maxPlaces)},
+			fhirPath,
+		)
+	}
+}
+
+// maxDecimalPlaces returns the value of the maxDecimalPlaces extension of an
+// ElementDefinition.
+func maxDecimalPlaces(ed *registry.ElementDefinition) (int, bool) {
+	for _, raw := range ed.GetExtensions(maxDecimalPlacesURL) {
+		var ext struct {
+			ValueInteger *int `json:"valueInteger"`
+		}
+		if json.Unmarshal(raw, &ext) == nil && ext.ValueInteger != nil {
+			return *ext.ValueInteger, true
+		}
+	}
+	return 0, false
+}
+
+// decimalLiteral returns a decimal as written in the resource. Values parsed
+// as float64 use their shortest representation.
+func decimalLiteral(value any) string {
+	switch v := value.(type) {
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// decimalPlaces returns the number of digits after the decimal point of a
+// number literal, taking its exponent into account (e.g., 2 for "1.5e-1").
+func decimalPlaces(literal string) int {
+	mantissa, exp, _ := strings.Cut(strings.ToLower(literal), "e")
+	places := 0
+	if _, frac, ok := strings.Cut(mantissa, "."); ok {
+		places = len(frac)
+	}
+	if e, err := strconv.Atoi(exp); err == nil {
+		places -= e
+	}
+	return max(places, 0)
+}
+
+// validateContent applies type-specific content checks to a string value.
+func (v *Validator) validateContent(value, typeName, fhirPath string, result *issue.Result) {
+	if typeName == typeBase64Binary {
+		v.validateBase64(value, fhirPath, result)
+	}
+}
+
+// validateBase64 decodes a base64Binary value and enforces the configured size limit.
+// Whitespace is ignored, as allowed by the base64Binary regex.
+func (v *Validator) validateBase64(value, fhirPath string, result *issue.Result) {
+	compact := strings.Join(strings.Fields(value), "")
+	decoded, err := base64.StdEncoding.DecodeString(compact)
+	if err != nil {
+		result.AddErrorWithID(
+			issue.DiagTypeInvalidBase64,
+			issue.Params{issue.String("error", err.Error())},
+			fhirPath,
+		)
+		return
+	}
+	if v.maxBase64Size > 0 && len(decoded) > v.maxBase64Size {
+		result.AddErrorWithID(
+			issue.DiagTypeBase64TooLarge,
+			issue.Params{issue.Int("size", len(decoded)), issue.Int("max", v.maxBase64Size)},
+			fhirPath,
+		)
+	}
+}
+
+// ValidateDecimalPrecision reports JSON numbers in the raw resource with more
+// significant digits than implementations are required to support. It works on
+// the raw bytes so it also covers callers that parse numbers as float64, which
+// loses the original digits.
+func (v *Validator) ValidateDecimalPrecision(raw []byte, rootPath string, result *issue.Result) {
+	tree, err := canonical.Parse(raw)
+	if err != nil {
+		return
+	}
+	checkPrecision(tree, rootPath, result)
+}
+
+// checkPrecision walks a parsed tree and reports numbers exceeding maxDecimalDigits.
+func checkPrecision(node any, fhirPath string, result *issue.Result) {
+	switch val := node.(type) {
+	case *canonical.Object:
+		for _, m := range val.Members {
+			checkPrecision(m.Value, fhirPath+"."+m.Name, result)
+		}
+	case []any:
+		for i, item := range val {
+			checkPrecision(item, fmt.Sprintf("%s[%d]", fhirPath, i), result)
+		}
+	case json.Number:
+		if digits := significantDigits(val.String()); digits > maxDecimalDigits {
+			result.AddWarningWithID(
+				issue.DiagTypeDecimalPrecision,
+				issue.Params{issue.String("value", truncateValue(val.String())), issue.Int("digits", digits), issue.Int("max", maxDecimalDigits)},
+				fhirPath,
+			)
+		}
+	}
+}
+
+// significantDigits counts the significant digits of a JSON number literal.
+// Leading zeros are not significant; trailing zeros are, since FHIR decimals
+// preserve precision (e.g., "1.50" has three).
+func significantDigits(literal string) int {
+	mantissa := literal
+	if idx := strings.IndexAny(mantissa, "eE"); idx >= 0 {
+		mantissa = mantissa[:idx]
+	}
+	mantissa = strings.TrimPrefix(mantissa, "-")
+	mantissa = strings.Replace(mantissa, ".", "", 1)
+	mantissa = strings.TrimLeft(mantissa, "0")
+	if mantissa == "" {
+		return 1
+	}
+	return len(mantissa)
+}
@@ -0,0 +1,172 @@
+package primitive
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+func TestValidateMaxLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		maxLength int
+		wantError bool
+	}{
+		{"no limit", "anything", 0, false},
+		{"within limit", "abc", 3, false},
+		{"exceeds limit", "abcd", 3, true},
+		{"counts characters not bytes", "ñññ", 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			validateMaxLength(tt.value, tt.maxLength, "Patient.name[0].family", result)
+			if result.HasErrors() != tt.wantError {
+				t.Errorf("HasErrors() = %v, want %v: %v", result.HasErrors(), tt.wantError, testutil.IssueIDs(result))
+			}
+		})
+	}
+}
+
+func TestValidateBase64(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		maxSize int
+		wantID  issue.DiagnosticID
+	}{
+		{"valid", "SGVsbG8=", 0, ""},
+		{"valid with whitespace", "SGVs\n bG8=", 0, ""},
+		{"bad padding", "SGVsbG8", 0, issue.DiagTypeInvalidBase64},
+		{"within limit", "SGVsbG8=", 5, ""},
+		{"exceeds limit", "SGVsbG8=", 4, issue.DiagTypeBase64TooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(registry.New())
+			v.SetMaxBase64Size(tt.maxSize)
+
+			result := issue.NewResult()
+			v.validateBase64(tt.value, "Attachment.data", result)
+
+			if tt.wantID == "" {
+				if len(result.Issues) != 0 {
+					t.Errorf("expected no issues, got %v", testutil.IssueIDs(result))
+				}
+				return
+			}
+			if len(result.Issues) != 1 || result.Issues[0].MessageID != string(tt.wantID) {
+				t.Errorf("issues = %v, want %s", testutil.IssueIDs(result), tt.wantID)
+			}
+		})
+	}
+}
+
+func TestValidateDecimalPrecision(t *testing.T) {
+	v := New(registry.New())
+	raw := []byte(`{"resourceType":"Observation","valueQuantity":{"value":1.50},` +
+		`"component":[{"valueQuantity":{"value":0.0000000000012345678901234567890}},` +
+		`{"valueQuantity":{"value":1234567890.123456789}}]}`)
+
+	result := issue.NewResult()
+	v.ValidateDecimalPrecision(raw, "Observation", result)
+
+	if len(result.Issues) != 2 {
+		t.Fatalf("got %d issues, want 2: %v", len(result.Issues), testutil.IssueIDs(result))
+	}
+	wantPaths := []string{
+		"Observation.component[0].valueQuantity.value",
+		"Observation.component[1].valueQuantity.value",
+	}
+	for i, want := range wantPaths {
+		if got := result.Issues[i].Expression[0]; got != want {
+			t.Errorf("issue %d path = %q, want %q", i, got, want)
+		}
+		if result.Issues[i].Severity != issue.SeverityWarning {
+			t.Errorf("issue %d severity = %s, want warning", i, result.Issues[i].Severity)
+		}
+	}
+}
+
+func TestSignificantDigits(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    int
+	}{
+		{"0", 1},
+		{"1.50", 3},
+		{"-0.001", 1},
+		{"100", 3},
+		{"1.2e10", 2},
+		{"0.0000000000012345678901234567890", 20},
+	}
+
+	for _, tt := range tests {
+		if got := significantDigits(tt.literal); got != tt.want {
+			t.Errorf("significantDigits(%q) = %d, want %d", tt.literal, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMaxDecimalPlaces(t *testing.T) {
+	var snapshot registry.Snapshot
+	if err := json.Unmarshal([]byte(`{"element": [{"id": "Quantity.value", "path": "Quantity.value",
+		"extension": [{"url": "http://hl7.org/fhir/StructureDefinition/maxDecimalPlaces", "valueInteger": 2}]}]}`), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	ed := &snapshot.Element[0]
+
+	tests := []struct {
+		name      string
+		value     any
+		wantError bool
+	}{
+		{"within limit", json.Number("1.25"), false},
+		{"trailing zeros count", json.Number("0.100"), true},
+		{"exponent shifts places", json.Number("125e-3"), true},
+		{"integer", json.Number("12"), false},
+		{"parsed as float64", 0.125, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			validateMaxDecimalPlaces(tt.value, ed, "Quantity.value", result)
+			if result.HasErrors() != tt.wantError {
+				t.Errorf("HasErrors() = %v, want %v: %v", result.HasErrors(), tt.wantError, testutil.IssueIDs(result))
+			}
+		})
+	}
+
+	result := issue.NewResult()
+	validateMaxDecimalPlaces(json.Number("0.125"), &registry.ElementDefinition{Path: "Quantity.value"}, "Quantity.value", result)
+	if len(result.Issues) != 0 {
+		t.Errorf("without extension: issues = %v", testutil.IssueIDs(result))
+	}
+}
+
+func TestDecimalPlaces(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    int
+	}{
+		{"1", 0},
+		{"0.0100", 4},
+		{"-2.5", 1},
+		{"1.5e-1", 2},
+		{"1.25E+2", 0},
+		{"1e3", 0},
+	}
+
+	for _, tt := range tests {
+		if got := decimalPlaces(tt.literal); got != tt.want {
+			t.Errorf("decimalPlaces(%q) = %d, want %d", tt.literal, got, tt.want)
+		}
+	}
+}
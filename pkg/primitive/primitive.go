@@ -28,7 +28,9 @@ type Validator struct {
 	regexCache   map[string]*regexp.Regexp
 	regexCacheMu sync.RWMutex
 	// idxCache caches element indexes by SD URL
-	idxCache sync.Map // map[string]*elementIndex
+	idxCache sync.Map // SD URL|version -> *elementIndex
+	// maxBase64Size limits decoded base64Binary content in bytes (0 = unlimited)
+	maxBase64Size int
 }
 
 // New creates a new primitive type Validator.
@@ -74,7 +76,7 @@ func (v *Validator) Validate(resource []byte, sd *registry.StructureDefinition)
 	if err := json.Unmarshal(resource, &data); err != nil {
 		result.AddErrorWithID(
 			issue.DiagStructureInvalidJSON,
-			map[string]any{"error": err.Error()},
+			issue.Params{issue.String("error", err.Error())},
 		)
 		return result
 	}
@@ -152,8 +154,9 @@ func (v *Validator) getOrBuildIndex(sd *registry.StructureDefinition) *elementIn
 		return buildElementIndex(sd)
 	}
 
-	// Check cache
-	if cached, ok := v.idxCache.Load(sd.URL); ok {
+	// Check cache; versions of one canonical have their own index
+	key := sd.URL + "|" + sd.Version
+	if cached, ok := v.idxCache.Load(key); ok {
 		if idx, ok := cached.(*elementIndex); ok {
 			return idx
 		}
@@ -161,10 +164,30 @@ func (v *Validator) getOrBuildIndex(sd *registry.StructureDefinition) *elementIn
 
 	// Build and cache
 	idx := buildElementIndex(sd)
-	v.idxCache.Store(sd.URL, idx)
+	v.idxCache.Store(key, idx)
 	return idx
 }
 
+// ValidateElement validates the primitive types of a single element value and
+// its descendants. ParentPath is the SD path of the element's parent, name the
+// element name as it appears in the JSON and fhirPath the path of value for
+// issues.
+func (v *Validator) ValidateElement(value any, sd *registry.StructureDefinition, parentPath, name, fhirPath string) *issue.Result {
+	result := issue.GetPooledResult()
+
+	idx := v.getOrBuildIndex(sd)
+	ctx := &validationContext{
+		rootSD:  sd,
+		rootIdx: idx,
+	}
+
+	elementSDPath := parentPath + "." + name
+	if resolved := v.resolveElementDefinition(elementSDPath, name, idx); resolved != nil {
+		v.validateValue(value, resolved, elementSDPath, fhirPath, idx, ctx, result)
+	}
+	return result
+}
+
 // validateElement recursively validates primitive types in an element and its children.
 func (v *Validator) validateElement(
 	data map[string]any,
@@ -188,6 +211,22 @@ func (v *Validator) validateElement(
 			continue
 		}
 
+		// A null value whose shadow element (e.g., "_given") holds its
+		// extensions is valid; the structural validator checks the alignment
+		if _, hasShadow := data["_"+key]; hasShadow {
+			if items, ok := value.([]any); ok {
+				for i, item := range items {
+					if item != nil {
+						v.validateValue(item, resolved, elementSDPath, fmt.Sprintf("%s[%d]", elementFHIRPath, i), idx, ctx, result)
+					}
+				}
+				continue
+			}
+			if value == nil {
+				continue
+			}
+		}
+
 		v.validateValue(value, resolved, elementSDPath, elementFHIRPath, idx, ctx, result)
 	}
 }
@@ -354,17 +393,21 @@ func (v *Validator) validatePrimitiveValue(
 	if !isTypeCompatible(actualType, expectedType, typeName) {
 		result.AddErrorWithID(
 			issue.DiagTypeWrongJSONType,
-			map[string]any{"expected": jsonTypeName(expectedType)},
+			issue.Params{issue.String("expected", jsonTypeName(expectedType))},
 			fhirPath,
 		)
 		return
 	}
 
-	// For string-based types, validate regex pattern
+	// For string-based types, validate regex pattern, maxLength and content
 	if actualType == jsonTypeString {
 		strVal, ok := value.(string)
 		if ok {
 			v.validateStringFormat(strVal, typeName, fhirPath, result)
+			if resolved.elemDef != nil {
+				validateMaxLength(strVal, resolved.elemDef.MaxLength, fhirPath, result)
+			}
+			v.validateContent(strVal, typeName, fhirPath, result)
 		}
 	}
 
@@ -374,6 +417,9 @@ func (v *Validator) validatePrimitiveValue(
 		// Use appropriate format to avoid scientific notation for integers
 		numStr := formatNumericValue(value, typeName)
 		v.validateStringFormat(numStr, typeName, fhirPath, result)
+		if typeName == typeDecimal && resolved.elemDef != nil {
+			validateMaxDecimalPlaces(value, resolved.elemDef, fhirPath, result)
+		}
 	}
 }
 
@@ -385,6 +431,8 @@ func formatNumericValue(value any, typeName string) string {
 	case typeInteger, typePositiveInt, typeUnsignedInt:
 		// For integer types, format as integer to avoid scientific notation
 		switch v := value.(type) {
+		case json.Number:
+			return v.String()
 		case float64:
 			// Check if this is actually a whole number
 			if v == float64(int64(v)) {
@@ -402,6 +450,9 @@ func formatNumericValue(value any, typeName string) string {
 	case typeDecimal:
 		// For decimal, preserve the numeric precision
 		switch v := value.(type) {
+		case json.Number:
+			// The literal as written, so trailing zeros are kept (e.g., "1.50")
+			return v.String()
 		case float64:
 			// Use a format that avoids scientific notation for reasonable values
 			// but still handles very large/small numbers
@@ -426,7 +477,7 @@ func getJSONType(value any) jsonType {
 	switch value.(type) {
 	case bool:
 		return jsonTypeBoolean
-	case float64, int, int64, float32:
+	case json.Number, float64, int, int64, float32:
 		return jsonTypeNumber
 	case string:
 		return jsonTypeString
@@ -498,7 +549,7 @@ func (v *Validator) validateStringFormat(value, typeName, fhirPath string, resul
 	if !regex.MatchString(value) {
 		result.AddErrorWithID(
 			issue.DiagTypeInvalidFormat,
-			map[string]any{"value": truncateValue(value), "type": typeName},
+			issue.Params{issue.String("value", truncateValue(value)), issue.String("type", typeName)},
 			fhirPath,
 		)
 	}
@@ -587,20 +638,21 @@ func (v *Validator) ValidateSinglePrimitive(value any, typeName, fhirPath string
 	if !isTypeCompatible(actualType, expectedType, typeName) {
 		result.AddErrorWithID(
 			issue.DiagTypeWrongJSONType,
-			map[string]any{
-				"expected": jsonTypeName(expectedType),
-				"actual":   jsonTypeName(actualType),
+			issue.Params{
+				issue.String("expected", jsonTypeName(expectedType)),
+				issue.String("actual", jsonTypeName(actualType)),
 			},
 			fhirPath,
 		)
 		return false
 	}
 
-	// For string-based types, validate regex pattern from SD
+	// For string-based types, validate regex pattern from SD and content
 	if actualType == jsonTypeString {
 		strVal, ok := value.(string)
 		if ok {
 			v.validateStringFormat(strVal, typeName, fhirPath, result)
+			v.validateContent(strVal, typeName, fhirPath, result)
 		}
 	}
 
@@ -0,0 +1,315 @@
+// Package profilecompare reports differences between two StructureDefinitions,
+// typically two versions of the same profile, so IG maintainers can review the
+// compatibility impact of a change.
+package profilecompare
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/fixedpattern"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// ChangeKind classifies a difference between two profiles.
+type ChangeKind string
+
+// ChangeKind constants.
+const (
+	ChangeElementAdded         ChangeKind = "element-added"
+	ChangeElementRemoved       ChangeKind = "element-removed"
+	ChangeCardinalityTightened ChangeKind = "cardinality-tightened"
+	ChangeCardinalityLoosened  ChangeKind = "cardinality-loosened"
+	ChangeBinding              ChangeKind = "binding-changed"
+	ChangeFixedValue           ChangeKind = "fixed-value-changed"
+	ChangePatternValue         ChangeKind = "pattern-value-changed"
+)
+
+// Change describes a single difference at an element.
+type Change struct {
+	// Kind classifies the change.
+	Kind ChangeKind
+	// ElementID is the ElementDefinition.id (or path when no id is present).
+	ElementID string
+	// Old is a textual rendering of the value in the old profile (empty when added).
+	Old string
+	// New is a textual rendering of the value in the new profile (empty when removed).
+	New string
+	// Required marks an added element that instances conforming to the old
+	// profile must now carry: it has min >= 1 and so does each ancestor not
+	// present in the old profile.
+	Required bool
+}
+
+// Breaking reports whether the change can make instances that conformed to the
+// old profile fail against the new one.
+func (c Change) Breaking() bool {
+	switch c.Kind {
+	case ChangeElementRemoved, ChangeCardinalityTightened, ChangeFixedValue, ChangePatternValue:
+		return true
+	case ChangeElementAdded:
+		// A new required element rejects instances that never had it.
+		return c.Required
+	case ChangeBinding:
+		// A stronger binding, or a different value set under an enforced
+		// (extensible/required) binding, can reject previously valid codes.
+		newRank := bindingStrengthRank(c.New)
+		return newRank > bindingStrengthRank(c.Old) || newRank >= rankExtensible
+	}
+	return false
+}
+
+// Report is the result of comparing two StructureDefinitions.
+type Report struct {
+	OldURL  string
+	NewURL  string
+	Changes []Change
+}
+
+// HasBreakingChanges reports whether any change is breaking.
+func (r *Report) HasBreakingChanges() bool {
+	for _, c := range r.Changes {
+		if c.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare returns the differences between oldSD and newSD. Elements are matched by
+// ElementDefinition.id so that slices are compared with their counterparts.
+// The snapshot is used when present, otherwise the differential.
+func Compare(oldSD, newSD *registry.StructureDefinition) *Report {
+	report := &Report{}
+	if oldSD != nil {
+		report.OldURL = oldSD.URL
+	}
+	if newSD != nil {
+		report.NewURL = newSD.URL
+	}
+
+	oldElems, oldOrder := indexElements(oldSD)
+	newElems, newOrder := indexElements(newSD)
+
+	for _, id := range oldOrder {
+		if _, ok := newElems[id]; !ok {
+			report.Changes = append(report.Changes, Change{
+				Kind:      ChangeElementRemoved,
+				ElementID: id,
+				Old:       cardinality(oldElems[id]),
+			})
+		}
+	}
+
+	for _, id := range newOrder {
+		newElem := newElems[id]
+		oldElem, ok := oldElems[id]
+		if !ok {
+			report.Changes = append(report.Changes, Change{
+				Kind:      ChangeElementAdded,
+				ElementID: id,
+				New:       cardinality(newElem),
+				Required:  requiredAddition(id, oldElems, newElems),
+			})
+			continue
+		}
+		report.Changes = append(report.Changes, compareElement(id, oldElem, newElem)...)
+	}
+
+	sort.SliceStable(report.Changes, func(i, j int) bool {
+		return report.Changes[i].ElementID < report.Changes[j].ElementID
+	})
+
+	return report
+}
+
+// requiredAddition reports whether the element added with id must be present
+// in instances that conformed to the old profile. A required child of a new
+// optional element, such as the url of an added extension slice, is only
+// required when the optional element is used.
+func requiredAddition(id string, oldElems, newElems map[string]*registry.ElementDefinition) bool {
+	for {
+		if _, existed := oldElems[id]; existed {
+			return true
+		}
+		if elem := newElems[id]; elem == nil || elem.Min < 1 {
+			return false
+		}
+		i := strings.LastIndexByte(id, '.')
+		if i < 0 {
+			return true
+		}
+		id = id[:i]
+	}
+}
+
+// compareElement compares two ElementDefinitions with the same id.
+func compareElement(id string, oldElem, newElem *registry.ElementDefinition) []Change {
+	var changes []Change
+
+	if kind, changed := compareCardinality(oldElem, newElem); changed {
+		changes = append(changes, Change{
+			Kind:      kind,
+			ElementID: id,
+			Old:       cardinality(oldElem),
+			New:       cardinality(newElem),
+		})
+	}
+
+	if oldB, newB := bindingString(oldElem.Binding), bindingString(newElem.Binding); oldB != newB {
+		changes = append(changes, Change{
+			Kind:      ChangeBinding,
+			ElementID: id,
+			Old:       oldB,
+			New:       newB,
+		})
+	}
+
+	oldFixed, oldFixedType, _ := oldElem.GetFixed()
+	newFixed, newFixedType, _ := newElem.GetFixed()
+	if !sameValue(oldFixed, oldFixedType, newFixed, newFixedType) {
+		changes = append(changes, Change{
+			Kind:      ChangeFixedValue,
+			ElementID: id,
+			Old:       valueString(oldFixed),
+			New:       valueString(newFixed),
+		})
+	}
+
+	oldPattern, oldPatternType, _ := oldElem.GetPattern()
+	newPattern, newPatternType, _ := newElem.GetPattern()
+	if !sameValue(oldPattern, oldPatternType, newPattern, newPatternType) {
+		changes = append(changes, Change{
+			Kind:      ChangePatternValue,
+			ElementID: id,
+			Old:       valueString(oldPattern),
+			New:       valueString(newPattern),
+		})
+	}
+
+	return changes
+}
+
+// compareCardinality classifies a cardinality change. A change that narrows the
+// allowed range in one bound and widens it in the other is reported as tightened,
+// since it can still reject previously valid instances.
+func compareCardinality(oldElem, newElem *registry.ElementDefinition) (ChangeKind, bool) {
+	oldMax, newMax := maxValue(oldElem.Max), maxValue(newElem.Max)
+	if oldElem.Min == newElem.Min && oldMax == newMax {
+		return "", false
+	}
+	if newElem.Min > oldElem.Min || newMax < oldMax {
+		return ChangeCardinalityTightened, true
+	}
+	return ChangeCardinalityLoosened, true
+}
+
+// indexElements maps element ids to definitions, preserving document order.
+func indexElements(sd *registry.StructureDefinition) (byID map[string]*registry.ElementDefinition, order []string) {
+	byID = make(map[string]*registry.ElementDefinition)
+	if sd == nil {
+		return byID, nil
+	}
+
+	var elements []registry.ElementDefinition
+	switch {
+	case sd.Snapshot != nil:
+		elements = sd.Snapshot.Element
+	case sd.Differential != nil:
+		elements = sd.Differential.Element
+	}
+
+	for i := range elements {
+		elem := &elements[i]
+		id := elem.ID
+		if id == "" {
+			id = elem.Path
+		}
+		if _, dup := byID[id]; dup {
+			continue
+		}
+		byID[id] = elem
+		order = append(order, id)
+	}
+	return byID, order
+}
+
+// maxValue converts ElementDefinition.max to a comparable number ("*" is unbounded).
+func maxValue(maxStr string) int {
+	if maxStr == "*" || maxStr == "" {
+		return int(^uint(0) >> 1)
+	}
+	n, err := strconv.Atoi(maxStr)
+	if err != nil {
+		return int(^uint(0) >> 1)
+	}
+	return n
+}
+
+// cardinality renders min..max.
+func cardinality(elem *registry.ElementDefinition) string {
+	return fmt.Sprintf("%d..%s", elem.Min, elem.Max)
+}
+
+// bindingString renders a binding as "strength valueSet".
+func bindingString(b *registry.Binding) string {
+	if b == nil {
+		return ""
+	}
+	return b.Strength + " " + b.ValueSet
+}
+
+// Binding strength ranks, from weakest to strongest.
+const (
+	rankNone = iota
+	rankExample
+	rankPreferred
+	rankExtensible
+	rankRequired
+)
+
+// bindingStrengthRank ranks a rendered binding by its strength.
+func bindingStrengthRank(binding string) int {
+	strength, _, _ := strings.Cut(binding, " ")
+	switch strength {
+	case "required":
+		return rankRequired
+	case "extensible":
+		return rankExtensible
+	case "preferred":
+		return rankPreferred
+	case "example":
+		return rankExample
+	}
+	return rankNone
+}
+
+// sameValue compares two fixed[x]/pattern[x] values including their type suffix.
+func sameValue(oldVal json.RawMessage, oldType string, newVal json.RawMessage, newType string) bool {
+	if oldVal == nil && newVal == nil {
+		return true
+	}
+	if oldVal == nil || newVal == nil || oldType != newType {
+		return false
+	}
+	return fixedpattern.DeepEqual(oldVal, newVal)
+}
+
+// valueString renders a raw JSON value compactly for reports.
+func valueString(raw json.RawMessage) string {
+	if raw == nil {
+		return ""
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(raw)
+	}
+	return string(out)
+}
@@ -0,0 +1,127 @@
+package profilecompare
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+func mustSD(t *testing.T, data string) *registry.StructureDefinition {
+	t.Helper()
+	var sd registry.StructureDefinition
+	if err := json.Unmarshal([]byte(data), &sd); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return &sd
+}
+
+const oldProfile = `{
+	"resourceType": "StructureDefinition",
+	"url": "http://example.org/StructureDefinition/my-patient",
+	"snapshot": {"element": [
+		{"id": "Patient", "path": "Patient", "min": 0, "max": "*"},
+		{"id": "Patient.identifier", "path": "Patient.identifier", "min": 0, "max": "*"},
+		{"id": "Patient.gender", "path": "Patient.gender", "min": 0, "max": "1",
+		 "binding": {"strength": "preferred", "valueSet": "http://hl7.org/fhir/ValueSet/administrative-gender"}},
+		{"id": "Patient.active", "path": "Patient.active", "min": 0, "max": "1", "fixedBoolean": true},
+		{"id": "Patient.photo", "path": "Patient.photo", "min": 0, "max": "*"}
+	]}
+}`
+
+const newProfile = `{
+	"resourceType": "StructureDefinition",
+	"url": "http://example.org/StructureDefinition/my-patient",
+	"snapshot": {"element": [
+		{"id": "Patient", "path": "Patient", "min": 0, "max": "*"},
+		{"id": "Patient.identifier", "path": "Patient.identifier", "min": 1, "max": "*"},
+		{"id": "Patient.gender", "path": "Patient.gender", "min": 0, "max": "1",
+		 "binding": {"strength": "required", "valueSet": "http://hl7.org/fhir/ValueSet/administrative-gender"}},
+		{"id": "Patient.active", "path": "Patient.active", "min": 0, "max": "1", "fixedBoolean": false},
+		{"id": "Patient.birthDate", "path": "Patient.birthDate", "min": 0, "max": "1"}
+	]}
+}`
+
+func TestCompare(t *testing.T) {
+	report := Compare(mustSD(t, oldProfile), mustSD(t, newProfile))
+
+	want := map[string]ChangeKind{
+		"Patient.identifier": ChangeCardinalityTightened,
+		"Patient.gender":     ChangeBinding,
+		"Patient.active":     ChangeFixedValue,
+		"Patient.photo":      ChangeElementRemoved,
+		"Patient.birthDate":  ChangeElementAdded,
+	}
+
+	if len(report.Changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(report.Changes), len(want), report.Changes)
+	}
+	for _, c := range report.Changes {
+		if want[c.ElementID] != c.Kind {
+			t.Errorf("%s: got %s, want %s", c.ElementID, c.Kind, want[c.ElementID])
+		}
+	}
+
+	if !report.HasBreakingChanges() {
+		t.Error("expected breaking changes")
+	}
+}
+
+func TestCompareIdentical(t *testing.T) {
+	report := Compare(mustSD(t, oldProfile), mustSD(t, oldProfile))
+	if len(report.Changes) != 0 {
+		t.Errorf("expected no changes, got %+v", report.Changes)
+	}
+}
+
+func TestCompareAddedRequired(t *testing.T) {
+	required := strings.Replace(oldProfile,
+		`{"id": "Patient.photo", "path": "Patient.photo", "min": 0, "max": "*"}`,
+		`{"id": "Patient.photo", "path": "Patient.photo", "min": 0, "max": "*"},
+		{"id": "Patient.birthDate", "path": "Patient.birthDate", "min": 1, "max": "1"}`, 1)
+	report := Compare(mustSD(t, oldProfile), mustSD(t, required))
+	if len(report.Changes) != 1 || report.Changes[0].Kind != ChangeElementAdded || !report.HasBreakingChanges() {
+		t.Errorf("expected a breaking element-added change, got %+v", report.Changes)
+	}
+}
+
+func TestCompareAddedOptionalSlice(t *testing.T) {
+	sliced := strings.Replace(oldProfile,
+		`{"id": "Patient.photo", "path": "Patient.photo", "min": 0, "max": "*"}`,
+		`{"id": "Patient.photo", "path": "Patient.photo", "min": 0, "max": "*"},
+		{"id": "Patient.extension:foo", "path": "Patient.extension", "sliceName": "foo", "min": 0, "max": "1"},
+		{"id": "Patient.extension:foo.url", "path": "Patient.extension.url", "min": 1, "max": "1"}`, 1)
+	report := Compare(mustSD(t, oldProfile), mustSD(t, sliced))
+	if len(report.Changes) != 2 {
+		t.Fatalf("got %d changes, want 2: %+v", len(report.Changes), report.Changes)
+	}
+	if report.HasBreakingChanges() {
+		t.Errorf("an optional slice with a required child is not breaking: %+v", report.Changes)
+	}
+}
+
+func TestChangeBreaking(t *testing.T) {
+	tests := []struct {
+		name   string
+		change Change
+		want   bool
+	}{
+		{"added", Change{Kind: ChangeElementAdded, New: "0..1"}, false},
+		{"added required", Change{Kind: ChangeElementAdded, New: "1..*", Required: true}, true},
+		{"added required under optional", Change{Kind: ChangeElementAdded, New: "1..1"}, false},
+		{"removed", Change{Kind: ChangeElementRemoved}, true},
+		{"loosened", Change{Kind: ChangeCardinalityLoosened}, false},
+		{"binding weakened", Change{Kind: ChangeBinding, Old: "required vs", New: "preferred vs"}, false},
+		{"binding strengthened", Change{Kind: ChangeBinding, Old: "preferred vs", New: "required vs"}, true},
+		{"required valueset swapped", Change{Kind: ChangeBinding, Old: "required a", New: "required b"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.change.Breaking(); got != tt.want {
+				t.Errorf("Breaking() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package reference
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
@@ -17,6 +18,11 @@ type BundleContext struct {
 	// FullURLIndex maps fullUrl values to their resource types.
 	// e.g., "urn:uuid:abc-123" -> "Patient"
 	FullURLIndex map[string]string
+	// Type is the Bundle type (e.g., "transaction").
+	Type string
+
+	// resources maps fullUrl values to the entry resources.
+	resources map[string]map[string]any
 }
 
 // NewBundleContext creates a BundleContext from a Bundle resource.
@@ -24,7 +30,9 @@ type BundleContext struct {
 func NewBundleContext(bundle map[string]any) *BundleContext {
 	ctx := &BundleContext{
 		FullURLIndex: make(map[string]string),
+		resources:    make(map[string]map[string]any),
 	}
+	ctx.Type, _ = bundle["type"].(string)
 
 	entries, ok := bundle["entry"].([]any)
 	if !ok {
@@ -50,11 +58,43 @@ func NewBundleContext(bundle map[string]any) *BundleContext {
 
 		resourceType, _ := resourceMap["resourceType"].(string)
 		ctx.FullURLIndex[fullURL] = resourceType
+		ctx.resources[fullURL] = resourceMap
 	}
 
 	return ctx
 }
 
+// Resolves reports whether a relative reference (e.g., "Patient/123") matches an
+// entry fullUrl, either exactly or as the trailing "Type/id" of an absolute fullUrl.
+func (c *BundleContext) Resolves(ref string) bool {
+	_, ok := c.resolve(ref)
+	return ok
+}
+
+// Resource returns the entry resource a reference resolves to (see Resolves),
+// or nil.
+func (c *BundleContext) Resource(ref string) map[string]any {
+	fullURL, ok := c.resolve(ref)
+	if !ok {
+		return nil
+	}
+	return c.resources[fullURL]
+}
+
+// resolve returns the entry fullUrl a reference matches.
+func (c *BundleContext) resolve(ref string) (string, bool) {
+	ref = strings.Split(ref, "/_history/")[0]
+	if _, ok := c.FullURLIndex[ref]; ok {
+		return ref, true
+	}
+	for fullURL := range c.FullURLIndex {
+		if strings.HasSuffix(fullURL, "/"+ref) {
+			return fullURL, true
+		}
+	}
+	return "", false
+}
+
 // ValidateBundleFullUrls validates that fullUrl is consistent with resource.id for all entries.
 // Per FHIR spec: "fullUrl SHALL NOT disagree with the id in the resource"
 // This applies when fullUrl is a URL (not urn:uuid or urn:oid).
@@ -100,9 +140,9 @@ func ValidateBundleFullUrls(bundle map[string]any, result *issue.Result) {
 		if resourceID != expectedID {
 			result.AddErrorWithID(
 				issue.DiagBundleFullURLMismatch,
-				map[string]any{
-					"fullUrl": fullURL,
-					"id":      resourceID,
+				issue.Params{
+					issue.String("fullUrl", fullURL),
+					issue.String("id", resourceID),
 				},
 				fmt.Sprintf("Bundle.entry[%d]", i),
 			)
@@ -148,10 +188,31 @@ var (
 	urnOIDPattern  = regexp.MustCompile(`^urn:oid:[012](\.[1-9]\d*)+$`)
 )
 
+// ResolveMode controls whether references must resolve to a resource that is
+// available to the validator.
+type ResolveMode int
+
+const (
+	// ResolveNone performs format and target type checks only (default).
+	ResolveNone ResolveMode = iota
+
+	// ResolveLocal additionally requires fragment references (#id) to resolve
+	// against the container's contained resources and, inside a Bundle,
+	// relative and URN references to resolve against an entry fullUrl.
+	ResolveLocal
+)
+
 // Validator validates Reference elements.
 type Validator struct {
 	registry *registry.Registry
 	walker   *walker.Walker
+
+	resolveMode  ResolveMode
+	retiredTypes map[string]bool
+
+	resolver        Resolver        // fetches targets outside the resource and Bundle
+	source          Resolver        // resources the caller has; nil = none
+	targetValidator TargetValidator // nil = targets are not validated against target profiles
 }
 
 // New creates a new reference Validator.
@@ -162,6 +223,39 @@ func New(reg *registry.Registry) *Validator {
 	}
 }
 
+// SetResolveMode configures whether references must resolve locally.
+// Existence checks apply to every Reference element, including Reference(Any).
+func (v *Validator) SetResolveMode(mode ResolveMode) {
+	v.resolveMode = mode
+}
+
+// SetRetiredResourceTypes configures resource type names that are no longer
+// part of the specification (e.g., "BodySite", "ProcedureRequest" in R4).
+// References targeting them produce a warning instead of passing silently,
+// which matters most for Reference(Any) elements where no target type check applies.
+func (v *Validator) SetRetiredResourceTypes(types []string) {
+	if len(types) == 0 {
+		v.retiredTypes = nil
+		return
+	}
+	v.retiredTypes = make(map[string]bool, len(types))
+	for _, t := range types {
+		v.retiredTypes[t] = true
+	}
+}
+
+// scope holds the resolution context of the resource whose references are being validated.
+type scope struct {
+	// bundle indexes the enclosing Bundle's entries (nil outside a Bundle).
+	bundle *BundleContext
+	// containedIDs holds the ids of the resources contained by the current container.
+	containedIDs map[string]bool
+	// container is the resource whose contained resources fragment references resolve to.
+	container map[string]any
+	// targets caches reference targets and their target profile results.
+	targets *targetCache
+}
+
 // Validate validates all Reference elements in a resource.
 // Deprecated: Use ValidateData for better performance when JSON is already parsed.
 func (v *Validator) Validate(resourceData json.RawMessage, sd *registry.StructureDefinition, result *issue.Result) {
@@ -186,6 +280,12 @@ func (v *Validator) ValidateData(resource map[string]any, sd *registry.Structure
 // ValidateDataWithBundle validates all Reference elements in a pre-parsed FHIR resource
 // within the context of a Bundle. This enables validation of urn:uuid references.
 func (v *Validator) ValidateDataWithBundle(resource map[string]any, sd *registry.StructureDefinition, bundleCtx *BundleContext, result *issue.Result) {
+	v.ValidateDataWithBundleContext(context.Background(), resource, sd, bundleCtx, result)
+}
+
+// ValidateDataWithBundleContext is ValidateDataWithBundle with a context that
+// is passed to the Resolver and the TargetValidator.
+func (v *Validator) ValidateDataWithBundleContext(ctx context.Context, resource map[string]any, sd *registry.StructureDefinition, bundleCtx *BundleContext, result *issue.Result) {
 	if sd == nil || sd.Snapshot == nil {
 		return
 	}
@@ -195,27 +295,65 @@ func (v *Validator) ValidateDataWithBundle(resource map[string]any, sd *registry
 		return
 	}
 
+	// Contained ids by container path, so contained resources resolve
+	// fragment references against their container.
+	containedIDs := map[string]map[string]bool{
+		resourceType: collectContainedIDs(resource),
+	}
+	containers := map[string]map[string]any{resourceType: resource}
+	targets := newTargetCache(ctx)
+
 	// Validate references in root resource
-	v.validateElementWithPaths(resource, sd, resourceType, resourceType, bundleCtx, result)
+	rootScope := &scope{bundle: bundleCtx, containedIDs: containedIDs[resourceType], container: resource, targets: targets}
+	v.validateElementWithPaths(resource, sd, resourceType, resourceType, rootScope, result)
 
 	// Walk all nested resources (contained + Bundle entries) using the generic walker.
-	v.walker.Walk(resource, resourceType, resourceType, func(ctx *walker.ResourceContext) bool {
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
 		// Skip root resource (already validated above)
-		if ctx.FHIRPath == resourceType {
+		if rc.FHIRPath == resourceType {
 			return true
 		}
 
+		nestedScope := &scope{bundle: bundleCtx, targets: targets}
+		if rc.IsContained {
+			nestedScope.containedIDs = containedIDs[rc.ParentPath]
+			nestedScope.container = containers[rc.ParentPath]
+		} else {
+			containedIDs[rc.FHIRPath] = collectContainedIDs(rc.Data)
+			containers[rc.FHIRPath] = rc.Data
+			nestedScope.containedIDs = containedIDs[rc.FHIRPath]
+			nestedScope.container = rc.Data
+		}
+
 		// Validate references in the nested resource
 		// Use ResourceType for SD lookup, FHIRPath for error reporting
-		v.validateElementWithPaths(ctx.Data, ctx.SD, ctx.ResourceType, ctx.FHIRPath, bundleCtx, result)
-		return true
+		v.validateElementWithPaths(rc.Data, rc.SD, rc.ResourceType, rc.FHIRPath, nestedScope, result)
+		return ctx.Err() == nil
 	})
 }
 
+// collectContainedIDs returns the ids of a resource's contained resources.
+func collectContainedIDs(resource map[string]any) map[string]bool {
+	contained, ok := resource["contained"].([]any)
+	if !ok {
+		return nil
+	}
+
+	ids := make(map[string]bool, len(contained))
+	for _, item := range contained {
+		if res, ok := item.(map[string]any); ok {
+			if id, ok := res["id"].(string); ok && id != "" {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
 // ValidateElementWithPaths validates references with separate paths for SD lookup and error reporting.
 // SdPath is used to look up ElementDefinitions in the StructureDefinition.
 // FhirPath is used for error reporting (e.g., "Bundle.entry[0].resource.subject").
-func (v *Validator) validateElementWithPaths(data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, bundleCtx *BundleContext, result *issue.Result) {
+func (v *Validator) validateElementWithPaths(data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, sc *scope, result *issue.Result) {
 	for key, value := range data {
 		if key == "resourceType" {
 			continue
@@ -232,21 +370,21 @@ func (v *Validator) validateElementWithPaths(data map[string]any, sd *registry.S
 
 		// Check if this element is a Reference type
 		if v.isReferenceType(elemDef) {
-			v.validateReference(value, elemDef, elementFhirPath, bundleCtx, result)
+			v.validateReference(value, elemDef, elementFhirPath, sc, result)
 		}
 
 		// Recurse into complex types
 		switch val := value.(type) {
 		case map[string]any:
-			v.validateComplexElement(val, elemDef, elementFhirPath, bundleCtx, result)
+			v.validateComplexElement(val, elemDef, elementFhirPath, sc, result)
 		case []any:
 			for i, item := range val {
 				itemPath := fmt.Sprintf("%s[%d]", elementFhirPath, i)
 				if mapItem, ok := item.(map[string]any); ok {
 					if v.isReferenceType(elemDef) {
-						v.validateReference(mapItem, elemDef, itemPath, bundleCtx, result)
+						v.validateReference(mapItem, elemDef, itemPath, sc, result)
 					}
-					v.validateComplexElement(mapItem, elemDef, itemPath, bundleCtx, result)
+					v.validateComplexElement(mapItem, elemDef, itemPath, sc, result)
 				}
 			}
 		}
@@ -254,7 +392,7 @@ func (v *Validator) validateElementWithPaths(data map[string]any, sd *registry.S
 }
 
 // validateComplexElement validates references within a complex element.
-func (v *Validator) validateComplexElement(data map[string]any, parentDef *registry.ElementDefinition, basePath string, bundleCtx *BundleContext, result *issue.Result) {
+func (v *Validator) validateComplexElement(data map[string]any, parentDef *registry.ElementDefinition, basePath string, sc *scope, result *issue.Result) {
 	if len(parentDef.Type) == 0 {
 		return
 	}
@@ -282,20 +420,20 @@ func (v *Validator) validateComplexElement(data map[string]any, parentDef *regis
 		}
 
 		if v.isReferenceType(elemDef) {
-			v.validateReference(value, elemDef, elementPath, bundleCtx, result)
+			v.validateReference(value, elemDef, elementPath, sc, result)
 		}
 
 		switch val := value.(type) {
 		case map[string]any:
-			v.validateComplexElement(val, elemDef, elementPath, bundleCtx, result)
+			v.validateComplexElement(val, elemDef, elementPath, sc, result)
 		case []any:
 			for i, item := range val {
 				itemPath := fmt.Sprintf("%s[%d]", elementPath, i)
 				if mapItem, ok := item.(map[string]any); ok {
 					if v.isReferenceType(elemDef) {
-						v.validateReference(mapItem, elemDef, itemPath, bundleCtx, result)
+						v.validateReference(mapItem, elemDef, itemPath, sc, result)
 					}
-					v.validateComplexElement(mapItem, elemDef, itemPath, bundleCtx, result)
+					v.validateComplexElement(mapItem, elemDef, itemPath, sc, result)
 				}
 			}
 		}
@@ -313,7 +451,7 @@ func (v *Validator) isReferenceType(elemDef *registry.ElementDefinition) bool {
 }
 
 // validateReference validates a single Reference value.
-func (v *Validator) validateReference(value any, elemDef *registry.ElementDefinition, fhirPath string, bundleCtx *BundleContext, result *issue.Result) {
+func (v *Validator) validateReference(value any, elemDef *registry.ElementDefinition, fhirPath string, sc *scope, result *issue.Result) {
 	refMap, ok := value.(map[string]any)
 	if !ok {
 		return
@@ -337,12 +475,24 @@ func (v *Validator) validateReference(value any, elemDef *registry.ElementDefini
 		return
 	}
 
+	var bundleCtx *BundleContext
+	if sc != nil {
+		bundleCtx = sc.bundle
+	}
+
+	// Conditional references ("Patient?identifier=...") are resolved by the
+	// server when it processes a transaction or batch
+	if bundleCtx != nil && bundleCtx.IsTransaction() && strings.Contains(refStr, "?") {
+		v.validateConditionalReference(refStr, elemDef, fhirPath, bundleCtx, result)
+		return
+	}
+
 	// Validate reference format
 	if !v.isValidReferenceFormat(refStr) {
 		result.AddErrorWithID(
 			issue.DiagReferenceInvalidFormat,
-			map[string]any{
-				"reference": refStr,
+			issue.Params{
+				issue.String("reference", refStr),
 			},
 			fhirPath+".reference",
 		)
@@ -356,9 +506,9 @@ func (v *Validator) validateReference(value any, elemDef *registry.ElementDefini
 	if refType != "" && extractedType != "" && refType != extractedType {
 		result.AddErrorWithID(
 			issue.DiagReferenceTypeMismatch,
-			map[string]any{
-				"type":      refType,
-				"reference": extractedType,
+			issue.Params{
+				issue.String("type", refType),
+				issue.String("reference", extractedType),
 			},
 			fhirPath,
 		)
@@ -373,8 +523,8 @@ func (v *Validator) validateReference(value any, elemDef *registry.ElementDefini
 			if _, found := bundleCtx.FullURLIndex[refStr]; !found {
 				result.AddWarningWithID(
 					issue.DiagReferenceNotInBundle,
-					map[string]any{
-						"reference": refStr,
+					issue.Params{
+						issue.String("reference", refStr),
 					},
 					fhirPath,
 				)
@@ -382,15 +532,196 @@ func (v *Validator) validateReference(value any, elemDef *registry.ElementDefini
 		}
 	}
 
+	// Existence checks apply regardless of the allowed targets.
+	if v.resolveMode == ResolveLocal {
+		v.validateResolution(refStr, sc, fhirPath, result)
+	}
+
+	v.validateRetiredType(refStr, bundleCtx, fhirPath, result)
+	v.validateAggregation(refStr, elemDef, fhirPath, sc, result)
+	v.validateVersioning(refStr, elemDef, fhirPath, result)
+
 	// Validate targetProfile - check if reference target type is allowed.
 	// This validates structural conformance based on the StructureDefinition.
 	v.validateTargetProfile(extractedType, refStr, elemDef, fhirPath, bundleCtx, result)
+	v.validateTargetConformance(refStr, elemDef, fhirPath, sc, result)
+}
+
+// validateConditionalReference checks a conditional reference inside a
+// transaction or batch: a resource type followed by a search.
+func (v *Validator) validateConditionalReference(refStr string, elemDef *registry.ElementDefinition, fhirPath string, bundleCtx *BundleContext, result *issue.Result) {
+	reason := conditionalReferenceProblem(refStr)
+	resourceType, _, _ := strings.Cut(refStr, "?")
+	if reason == "" && !v.registry.IsResourceType(resourceType) {
+		reason = fmt.Sprintf("unknown resource type %q", resourceType)
+	}
+	if reason != "" {
+		result.AddErrorWithID(
+			issue.DiagReferenceConditional,
+			issue.Params{issue.String("reference", refStr), issue.String("reason", reason)},
+			fhirPath+".reference",
+		)
+		return
+	}
+
+	v.validateTargetProfile(resourceType, refStr, elemDef, fhirPath, bundleCtx, result)
+}
+
+// validateResolution checks that a local reference resolves to a resource the
+// validator can see. Fragment references must match a contained resource of the
+// container (a bare "#" refers to the container itself). Inside a Bundle, URN and
+// relative references must match an entry fullUrl or, with a Source, a
+// resource of the Source; outside a Bundle, relative references must resolve
+// in the Source if there is one. Absolute URLs may point to external servers
+// and are not required to resolve.
+func (v *Validator) validateResolution(refStr string, sc *scope, fhirPath string, result *issue.Result) {
+	if strings.HasPrefix(refStr, "#") {
+		id := strings.TrimPrefix(refStr, "#")
+		if id == "" || (sc != nil && sc.containedIDs[id]) {
+			return
+		}
+		result.AddErrorWithID(
+			issue.DiagReferenceNotResolved,
+			issue.Params{issue.String("reference", refStr)},
+			fhirPath+".reference",
+		)
+		return
+	}
+
+	if sc == nil || (sc.bundle == nil && v.source == nil) {
+		return
+	}
+
+	// URN references not found in the Bundle are already reported as REFERENCE_NOT_IN_BUNDLE.
+	if strings.HasPrefix(refStr, "urn:") || strings.HasPrefix(refStr, "http://") || strings.HasPrefix(refStr, "https://") {
+		return
+	}
+
+	// Under ResolveLocal an unresolved local reference is an error whether it is
+	// a fragment or Bundle-relative: the caller asked for resolution, and both
+	// point at data the validator was given.
+	if (sc.bundle == nil || !sc.bundle.Resolves(refStr)) && v.sourceTarget(refStr, sc) == nil {
+		result.AddErrorWithID(
+			issue.DiagReferenceNotResolved,
+			issue.Params{issue.String("reference", refStr)},
+			fhirPath+".reference",
+		)
+	}
+}
+
+// validateRetiredType warns when a reference targets a configured retired resource type.
+// The type is taken from the reference string itself because retired types are usually
+// absent from the registry, so extractResourceType cannot recognize them.
+func (v *Validator) validateRetiredType(refStr string, bundleCtx *BundleContext, fhirPath string, result *issue.Result) {
+	if len(v.retiredTypes) == 0 {
+		return
+	}
+
+	typeName := referencedTypeName(refStr)
+	if typeName == "" && bundleCtx != nil {
+		typeName = bundleCtx.FullURLIndex[refStr]
+	}
+
+	if v.retiredTypes[typeName] {
+		result.AddWarningWithID(
+			issue.DiagReferenceRetiredType,
+			issue.Params{
+				issue.String("type", typeName),
+				issue.String("reference", refStr),
+			},
+			fhirPath+".reference",
+		)
+	}
+}
+
+// validateAggregation checks a reference against the aggregation modes of the
+// element's Reference type (ElementDefinition.type.aggregation). Fragment
+// references are contained, references that resolve to an entry of the
+// enclosing Bundle are bundled and all others are referenced. As in the HL7
+// validator, "referenced" also admits bundled references.
+func (v *Validator) validateAggregation(refStr string, elemDef *registry.ElementDefinition, fhirPath string, sc *scope, result *issue.Result) {
+	var allowed []string
+	for _, t := range elemDef.Type {
+		if t.Code == "Reference" {
+			allowed = append(allowed, t.Aggregation...)
+		}
+	}
+	if len(allowed) == 0 {
+		return
+	}
+
+	mode := "referenced"
+	switch {
+	case strings.HasPrefix(refStr, "#"):
+		mode = "contained"
+	case sc != nil && sc.bundle != nil && sc.bundle.Resolves(refStr):
+		mode = "bundled"
+	}
+
+	for _, a := range allowed {
+		if a == mode || (a == "referenced" && mode == "bundled") {
+			return
+		}
+	}
+	result.AddErrorWithID(
+		issue.DiagReferenceAggregation,
+		issue.Params{issue.String("reference", refStr), issue.String("mode", mode), issue.String("allowed", strings.Join(allowed, ", "))},
+		fhirPath+".reference",
+	)
+}
+
+// validateVersioning checks a literal reference against the versioning rule
+// of the element's Reference type: "specific" requires a version-specific
+// reference (.../_history/n) and "independent" forbids one. Fragment and URN
+// references carry no version and are not checked.
+func (v *Validator) validateVersioning(refStr string, elemDef *registry.ElementDefinition, fhirPath string, result *issue.Result) {
+	if strings.HasPrefix(refStr, "#") || strings.HasPrefix(refStr, "urn:") {
+		return
+	}
+
+	versioning := ""
+	for _, t := range elemDef.Type {
+		if t.Code == "Reference" && t.Versioning != "" {
+			versioning = t.Versioning
+		}
+	}
+
+	versioned := strings.Contains(refStr, "/_history/")
+	switch {
+	case versioning == "specific" && !versioned:
+		result.AddErrorWithID(issue.DiagReferenceVersionRequired, issue.Params{issue.String("reference", refStr)}, fhirPath+".reference")
+	case versioning == "independent" && versioned:
+		result.AddErrorWithID(issue.DiagReferenceVersionNotAllowed, issue.Params{issue.String("reference", refStr)}, fhirPath+".reference")
+	}
+}
+
+// referencedTypeName returns the type segment of a relative or absolute literal
+// reference without consulting the registry (e.g., "BodySite/1" -> "BodySite").
+func referencedTypeName(ref string) string {
+	if strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "urn:") {
+		return ""
+	}
+	ref = strings.Split(ref, "/_history/")[0]
+	parts := strings.Split(ref, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
 }
 
 // validateTargetProfile validates that the reference target type matches allowed targetProfiles.
 // Per FHIR spec, ElementDefinition.type[].targetProfile restricts which resource types
-// can be referenced. If no targetProfile is specified, any resource type is allowed.
+// can be referenced. If no targetProfile is specified, any resource type is allowed
+// and the type check is skipped entirely (format and existence checks still apply).
 func (v *Validator) validateTargetProfile(extractedType, refStr string, elemDef *registry.ElementDefinition, fhirPath string, bundleCtx *BundleContext, result *issue.Result) {
+	// Get all targetProfiles from all Reference types in the element definition
+	allowedProfiles := v.getTargetProfiles(elemDef)
+
+	// Reference(Any): no target type check applies
+	if isAnyReference(allowedProfiles) {
+		return
+	}
+
 	// Can't validate if we couldn't extract the type.
 	// This happens for fragment (#) and URN references.
 	if extractedType == "" {
@@ -405,23 +736,15 @@ func (v *Validator) validateTargetProfile(extractedType, refStr string, elemDef
 		}
 	}
 
-	// Get all targetProfiles from all Reference types in the element definition
-	allowedProfiles := v.getTargetProfiles(elemDef)
-
-	// If no targetProfiles specified, any type is allowed (Reference(Any))
-	if len(allowedProfiles) == 0 {
-		return
-	}
-
 	// Check if the extracted type matches any of the allowed profiles
 	if !v.typeMatchesProfiles(extractedType, allowedProfiles) {
 		// Build list of allowed types for error message
 		allowedTypes := v.extractTypesFromProfiles(allowedProfiles)
 		result.AddErrorWithID(
 			issue.DiagReferenceInvalidTarget,
-			map[string]any{
-				"type":    extractedType,
-				"allowed": strings.Join(allowedTypes, ", "),
+			issue.Params{
+				issue.String("type", extractedType),
+				issue.String("allowed", strings.Join(allowedTypes, ", ")),
 			},
 			fhirPath+".reference",
 		)
@@ -439,6 +762,21 @@ func (v *Validator) getTargetProfiles(elemDef *registry.ElementDefinition) []str
 	return profiles
 }
 
+// isAnyReference reports whether a set of targetProfiles places no restriction on the
+// target type. That is the case when no targetProfile is declared (Reference(Any)) or
+// when Resource itself is listed (Reference(Resource)).
+func isAnyReference(profiles []string) bool {
+	if len(profiles) == 0 {
+		return true
+	}
+	for _, p := range profiles {
+		if p == "http://hl7.org/fhir/StructureDefinition/Resource" {
+			return true
+		}
+	}
+	return false
+}
+
 // typeMatchesProfiles checks if a resource type matches any of the allowed profile URLs.
 // Profile URLs are in the format: http://hl7.org/fhir/StructureDefinition/[ResourceType]
 func (v *Validator) typeMatchesProfiles(resourceType string, profiles []string) bool {
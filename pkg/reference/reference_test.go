@@ -1,9 +1,14 @@
 package reference
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
 	"github.com/gofhir/validator/pkg/registry"
 )
 
@@ -377,3 +382,271 @@ func TestExtractTypesFromProfiles(t *testing.T) {
 		})
 	}
 }
+
+func TestReferenceAnyResolution(t *testing.T) {
+	anyRef := &registry.ElementDefinition{
+		Type: []registry.Type{{Code: "Reference"}},
+	}
+
+	tests := []struct {
+		name         string
+		mode         ResolveMode
+		ref          string
+		sc           *scope
+		expectErrors int
+		expectWarns  int
+	}{
+		{
+			name: "fragment not resolved ignored without resolve mode",
+			mode: ResolveNone,
+			ref:  "#missing",
+			sc:   &scope{},
+		},
+		{
+			name:         "fragment not resolved",
+			mode:         ResolveLocal,
+			ref:          "#missing",
+			sc:           &scope{containedIDs: map[string]bool{"org1": true}},
+			expectErrors: 1,
+		},
+		{
+			name: "fragment resolved",
+			mode: ResolveLocal,
+			ref:  "#org1",
+			sc:   &scope{containedIDs: map[string]bool{"org1": true}},
+		},
+		{
+			name: "relative resolved in bundle",
+			mode: ResolveLocal,
+			ref:  "Patient/123",
+			sc: &scope{bundle: &BundleContext{FullURLIndex: map[string]string{
+				"http://example.org/fhir/Patient/123": "Patient",
+			}}},
+		},
+		{
+			name: "relative not resolved in bundle",
+			mode: ResolveLocal,
+			ref:  "Patient/456",
+			sc: &scope{bundle: &BundleContext{FullURLIndex: map[string]string{
+				"http://example.org/fhir/Patient/123": "Patient",
+			}}},
+			expectErrors: 1,
+		},
+		{
+			name: "relative outside bundle is not resolved",
+			mode: ResolveLocal,
+			ref:  "Patient/456",
+			sc:   &scope{},
+		},
+		{
+			name: "relative resolved in source",
+			mode: ResolveLocal,
+			ref:  "Patient/p1",
+			sc:   &scope{},
+		},
+		{
+			name:         "relative not resolved in source",
+			mode:         ResolveLocal,
+			ref:          "Patient/456",
+			sc:           &scope{targets: newTargetCache(context.Background())},
+			expectErrors: 1,
+		},
+		{
+			name: "relative resolved in source within bundle",
+			mode: ResolveLocal,
+			ref:  "Patient/p1",
+			sc: &scope{bundle: &BundleContext{FullURLIndex: map[string]string{
+				"http://example.org/fhir/Patient/123": "Patient",
+			}}},
+		},
+		{
+			name: "absolute not in source",
+			mode: ResolveLocal,
+			ref:  "http://other.org/fhir/Patient/456",
+			sc:   &scope{},
+		},
+	}
+
+	source := NewSource(map[string]json.RawMessage{
+		"http://example.org/fhir/Patient/p1": json.RawMessage(`{"resourceType": "Patient", "id": "p1"}`),
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Validator{registry: mockRegistry(), resolveMode: tt.mode}
+			if strings.Contains(tt.name, "source") {
+				v.SetSource(source)
+			}
+			result := issue.NewResult()
+			v.validateReference(map[string]any{"reference": tt.ref}, anyRef, "Test.ref", tt.sc, result)
+
+			if result.ErrorCount() != tt.expectErrors || result.WarningCount() != tt.expectWarns {
+				t.Errorf("got %d errors, %d warnings; want %d errors, %d warnings",
+					result.ErrorCount(), result.WarningCount(), tt.expectErrors, tt.expectWarns)
+				for _, iss := range result.Issues {
+					t.Logf("  Issue: %s", iss.Diagnostics)
+				}
+			}
+		})
+	}
+}
+
+func TestRetiredResourceTypes(t *testing.T) {
+	anyRef := &registry.ElementDefinition{
+		Type: []registry.Type{{Code: "Reference"}},
+	}
+
+	v := &Validator{registry: mockRegistry()}
+	v.SetRetiredResourceTypes([]string{"BodySite"})
+
+	result := issue.NewResult()
+	v.validateReference(map[string]any{"reference": "BodySite/1"}, anyRef, "Test.ref", &scope{}, result)
+	if result.WarningCount() != 1 || result.Issues[0].MessageID != string(issue.DiagReferenceRetiredType) {
+		t.Errorf("expected 1 retired type warning, got %+v", result.Issues)
+	}
+
+	result = issue.NewResult()
+	v.validateReference(map[string]any{"reference": "Patient/1"}, anyRef, "Test.ref", &scope{}, result)
+	if len(result.Issues) != 0 {
+		t.Errorf("expected no issues for current type, got %+v", result.Issues)
+	}
+}
+
+func TestIsAnyReference(t *testing.T) {
+	if !isAnyReference(nil) {
+		t.Error("no targetProfile should be Reference(Any)")
+	}
+	if !isAnyReference([]string{"http://hl7.org/fhir/StructureDefinition/Resource"}) {
+		t.Error("Reference(Resource) should be Reference(Any)")
+	}
+	if isAnyReference([]string{"http://hl7.org/fhir/StructureDefinition/Patient"}) {
+		t.Error("Reference(Patient) should not be Reference(Any)")
+	}
+}
+
+func TestReferenceAggregationAndVersioning(t *testing.T) {
+	bundle := &BundleContext{FullURLIndex: map[string]string{
+		"http://example.org/fhir/Patient/123": "Patient",
+	}}
+
+	tests := []struct {
+		name        string
+		refType     registry.Type
+		ref         string
+		sc          *scope
+		expectIssue issue.DiagnosticID
+	}{
+		{"contained allowed", registry.Type{Aggregation: []string{"contained"}}, "#p1", &scope{}, ""},
+		{"contained not allowed", registry.Type{Aggregation: []string{"referenced"}}, "#p1", &scope{}, issue.DiagReferenceAggregation},
+		{"remote not allowed", registry.Type{Aggregation: []string{"contained", "bundled"}}, "Patient/9", &scope{}, issue.DiagReferenceAggregation},
+		{"bundled allowed", registry.Type{Aggregation: []string{"bundled"}}, "Patient/123", &scope{bundle: bundle}, ""},
+		{"bundled admitted by referenced", registry.Type{Aggregation: []string{"referenced"}}, "Patient/123", &scope{bundle: bundle}, ""},
+		{"bundled required outside bundle", registry.Type{Aggregation: []string{"bundled"}}, "Patient/123", &scope{}, issue.DiagReferenceAggregation},
+		{"specific version present", registry.Type{Versioning: "specific"}, "Patient/1/_history/2", &scope{}, ""},
+		{"specific version missing", registry.Type{Versioning: "specific"}, "Patient/1", &scope{}, issue.DiagReferenceVersionRequired},
+		{"independent with version", registry.Type{Versioning: "independent"}, "Patient/1/_history/2", &scope{}, issue.DiagReferenceVersionNotAllowed},
+		{"specific ignores fragments", registry.Type{Versioning: "specific"}, "#p1", &scope{}, ""},
+		{"either", registry.Type{Versioning: "either"}, "Patient/1", &scope{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.refType.Code = "Reference"
+			elemDef := &registry.ElementDefinition{Type: []registry.Type{tt.refType}}
+			v := &Validator{registry: mockRegistry()}
+			result := issue.NewResult()
+			v.validateReference(map[string]any{"reference": tt.ref}, elemDef, "Test.ref", tt.sc, result)
+
+			var ids []string
+			for _, iss := range result.Issues {
+				ids = append(ids, iss.MessageID)
+			}
+			switch {
+			case tt.expectIssue == "" && len(ids) != 0:
+				t.Errorf("issues = %v, want none", ids)
+			case tt.expectIssue != "" && (len(ids) != 1 || ids[0] != string(tt.expectIssue)):
+				t.Errorf("issues = %v, want [%s]", ids, tt.expectIssue)
+			}
+		})
+	}
+}
+
+func TestTargetConformance(t *testing.T) {
+	const profileURL = "http://example.org/StructureDefinition/named-org"
+	reg := mockRegistry()
+	err := reg.LoadFromPackages([]*loader.Package{{Name: "example.ig", Version: "1.0.0", Resources: map[string]json.RawMessage{
+		profileURL: json.RawMessage(`{"resourceType": "StructureDefinition", "url": "` + profileURL + `", "kind": "resource",
+			"type": "Organization", "derivation": "constraint",
+			"snapshot": {"element": [{"id": "Organization", "path": "Organization"}]}}`),
+	}}})
+	if err != nil {
+		t.Fatalf("LoadFromPackages() error: %v", err)
+	}
+	profile := reg.GetByURL(profileURL)
+	elemDef := &registry.ElementDefinition{Type: []registry.Type{{Code: "Reference", TargetProfile: []string{profileURL}}}}
+
+	bundle := NewBundleContext(map[string]any{"entry": []any{
+		map[string]any{"fullUrl": "http://example.org/fhir/Organization/1",
+			"resource": map[string]any{"resourceType": "Organization", "id": "1", "name": "Lab"}},
+		map[string]any{"fullUrl": "http://example.org/fhir/Organization/2",
+			"resource": map[string]any{"resourceType": "Organization", "id": "2"}},
+	}})
+
+	calls := 0
+	v := &Validator{registry: reg}
+	v.SetTargetValidator(func(_ context.Context, target map[string]any, sd *registry.StructureDefinition) bool {
+		calls++
+		return sd == profile && target["name"] != nil
+	})
+
+	sc := &scope{bundle: bundle, targets: newTargetCache(context.Background())}
+	result := issue.NewResult()
+	for _, ref := range []string{"Organization/1", "Organization/2", "Organization/2", "Organization/3"} {
+		v.validateReference(map[string]any{"reference": ref}, elemDef, "Test.ref", sc, result)
+	}
+
+	if len(result.Issues) != 2 || result.Issues[0].MessageID != string(issue.DiagReferenceTargetProfile) {
+		t.Errorf("issues = %+v, want 2 target profile errors for Organization/2", result.Issues)
+	}
+	if calls != 2 {
+		t.Errorf("target validator called %d times, want 2 (results are cached per target)", calls)
+	}
+}
+
+func TestSource(t *testing.T) {
+	source := NewSource(map[string]json.RawMessage{
+		"Patient/p1": json.RawMessage(`{"resourceType": "Patient", "id": "p1"}`),
+		"http://example.org/fhir/Organization/o1": json.RawMessage(`{"resourceType": "Organization", "id": "o1"}`),
+		"Practitioner/bad":                        json.RawMessage(`[]`),
+	})
+
+	tests := []struct {
+		ref     string
+		wantID  string
+		wantErr bool
+	}{
+		{ref: "Patient/p1", wantID: "p1"},
+		{ref: "Patient/p1/_history/2", wantID: "p1"},
+		{ref: "http://other.org/fhir/Patient/p1", wantID: "p1"},
+		{ref: "http://example.org/fhir/Organization/o1", wantID: "o1"},
+		{ref: "Organization/o1", wantID: "o1"},
+		{ref: "Patient/p2"},
+		{ref: "Practitioner/bad", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			res, err := source.Resolve(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, want error %v", err, tt.wantErr)
+			}
+			if id, _ := res["id"].(string); id != tt.wantID {
+				t.Errorf("Resolve() = %v, want id %q", res, tt.wantID)
+			}
+		})
+	}
+
+	first, _ := source.Resolve(context.Background(), "Patient/p1")
+	second, _ := source.Resolve(context.Background(), "Patient/p1")
+	if fmt.Sprintf("%p", first) != fmt.Sprintf("%p", second) {
+		t.Error("resources are parsed again on each Resolve")
+	}
+}
@@ -0,0 +1,288 @@
+package reference
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Resolver fetches the resource a reference points to when it does not
+// resolve within the resource or its Bundle, e.g., from a FHIR server or a
+// document store. It returns nil and no error when the resource is unknown.
+// It is called concurrently by concurrent validations.
+type Resolver interface {
+	Resolve(ctx context.Context, reference string) (map[string]any, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(ctx context.Context, reference string) (map[string]any, error)
+
+// Resolve implements Resolver.
+func (f ResolverFunc) Resolve(ctx context.Context, reference string) (map[string]any, error) {
+	return f(ctx, reference)
+}
+
+// Source is a Resolver over resources the caller already has, such as the
+// entries of an incoming transaction and the server resources they refer to,
+// keyed by relative ("Patient/123") or absolute reference. A key also matches
+// references to its trailing "Type/id", and version-specific references match
+// the unversioned key. Resources are parsed on first use.
+type Source struct {
+	resources map[string]json.RawMessage
+	keys      map[string]string // reference or "Type/id" -> key in resources
+	parsed    sync.Map          // key -> map[string]any
+}
+
+// NewSource creates a Source over resources keyed by reference.
+func NewSource(resources map[string]json.RawMessage) *Source {
+	s := &Source{resources: resources, keys: make(map[string]string, 2*len(resources))}
+	for key := range resources {
+		s.keys[key] = key
+	}
+	for key := range resources {
+		if tail := relativeTail(key); tail != "" {
+			if _, ok := s.keys[tail]; !ok {
+				s.keys[tail] = key
+			}
+		}
+	}
+	return s
+}
+
+// Resolve implements Resolver. It returns an error if the resource is not a
+// JSON object.
+func (s *Source) Resolve(_ context.Context, reference string) (map[string]any, error) {
+	reference = strings.Split(reference, "/_history/")[0]
+	key, ok := s.keys[reference]
+	if !ok {
+		if key, ok = s.keys[relativeTail(reference)]; !ok {
+			return nil, nil
+		}
+	}
+	if res, ok := s.parsed.Load(key); ok {
+		return res.(map[string]any), nil
+	}
+	var res map[string]any
+	if err := json.Unmarshal(s.resources[key], &res); err != nil {
+		return nil, fmt.Errorf("reference source %s: %w", key, err)
+	}
+	actual, _ := s.parsed.LoadOrStore(key, res)
+	return actual.(map[string]any), nil
+}
+
+// relativeTail returns the trailing "Type/id" of an absolute reference, or ""
+// for other references.
+func relativeTail(reference string) string {
+	if !strings.HasPrefix(reference, "http://") && !strings.HasPrefix(reference, "https://") {
+		return ""
+	}
+	parts := strings.Split(strings.TrimSuffix(reference, "/"), "/")
+	if len(parts) < 5 {
+		return "" // scheme, empty, host, type, id
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+// TargetValidator reports whether a resolved reference target conforms to a
+// profile.
+type TargetValidator func(ctx context.Context, target map[string]any, profile *registry.StructureDefinition) bool
+
+// SetResolver configures the Resolver used to fetch reference targets for
+// target profile validation (nil = only contained and Bundle targets).
+func (v *Validator) SetResolver(r Resolver) {
+	v.resolver = r
+}
+
+// SetSource configures resources the caller already has (see Source, or any
+// Resolver that answers without network access). The source is consulted
+// before the Resolver, and with ResolveLocal, relative references that do not
+// resolve within the Bundle must resolve in it, also outside a Bundle.
+func (v *Validator) SetSource(s Resolver) {
+	v.source = s
+}
+
+// SetTargetValidator enables validating resolved reference targets against the
+// constraining profiles listed in targetProfile (e.g., us-core-organization).
+// Targets whose type does not match are left to the target type check.
+func (v *Validator) SetTargetValidator(fn TargetValidator) {
+	v.targetValidator = fn
+}
+
+// targetCache holds the targets fetched and the target profile results of a
+// single ValidateData call, so a target referenced several times is fetched
+// and validated once per profile.
+type targetCache struct {
+	ctx     context.Context
+	fetched map[string]map[string]any // reference -> resource (nil = unresolved)
+	sourced map[string]map[string]any // reference -> resource from the Source
+	results map[string]bool           // target|profile -> conforms
+}
+
+func newTargetCache(ctx context.Context) *targetCache {
+	return &targetCache{
+		ctx:     ctx,
+		fetched: make(map[string]map[string]any),
+		sourced: make(map[string]map[string]any),
+		results: make(map[string]bool),
+	}
+}
+
+// validateTargetConformance validates the resolved target of a reference
+// against the element's constraining target profiles of the target's type.
+// The reference is valid if the target conforms to any of them.
+func (v *Validator) validateTargetConformance(refStr string, elemDef *registry.ElementDefinition, fhirPath string, sc *scope, result *issue.Result) {
+	if v.targetValidator == nil || sc == nil || sc.targets == nil {
+		return
+	}
+
+	var profiles []*registry.StructureDefinition
+	for _, url := range v.getTargetProfiles(elemDef) {
+		if sd := v.registry.GetByURL(url); sd != nil && sd.Derivation == "constraint" {
+			profiles = append(profiles, sd)
+		}
+	}
+	if len(profiles) == 0 {
+		return
+	}
+
+	target := v.resolveTarget(refStr, sc)
+	if target == nil {
+		return
+	}
+	targetType, _ := target["resourceType"].(string)
+
+	var failed []string
+	for _, sd := range profiles {
+		if sd.Type != targetType {
+			continue
+		}
+		key := fmt.Sprintf("%p|%s", target, sd.URL)
+		conforms, ok := sc.targets.results[key]
+		if !ok {
+			conforms = v.targetValidator(sc.targets.ctx, target, sd)
+			sc.targets.results[key] = conforms
+		}
+		if conforms {
+			return
+		}
+		failed = append(failed, sd.URL)
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	result.AddErrorWithID(
+		issue.DiagReferenceTargetProfile,
+		issue.Params{issue.String("reference", refStr), issue.String("profiles", strings.Join(failed, ", "))},
+		fhirPath+".reference",
+	)
+}
+
+// resolveTarget returns the resource a reference points to: a contained
+// resource of the container, an entry of the enclosing Bundle, a resource of
+// the Source, or a resource fetched with the Resolver.
+func (v *Validator) resolveTarget(refStr string, sc *scope) map[string]any {
+	if id, ok := strings.CutPrefix(refStr, "#"); ok {
+		return walker.ContainedResource(sc.container, id)
+	}
+	if sc.bundle != nil {
+		if target := sc.bundle.Resource(refStr); target != nil {
+			return target
+		}
+	}
+	if strings.HasPrefix(refStr, "urn:") {
+		return nil
+	}
+	if target := v.sourceTarget(refStr, sc); target != nil {
+		return target
+	}
+	if v.resolver == nil {
+		return nil
+	}
+
+	if target, ok := sc.targets.fetched[refStr]; ok {
+		return target
+	}
+	target, err := v.resolver.Resolve(sc.targets.ctx, refStr)
+	if err != nil {
+		target = nil
+	}
+	sc.targets.fetched[refStr] = target
+	return target
+}
+
+// sourceTarget returns the resource a reference resolves to in the Source,
+// or nil.
+func (v *Validator) sourceTarget(refStr string, sc *scope) map[string]any {
+	if v.source == nil {
+		return nil
+	}
+	if sc.targets == nil {
+		target, _ := v.source.Resolve(context.Background(), refStr)
+		return target
+	}
+	if target, ok := sc.targets.sourced[refStr]; ok {
+		return target
+	}
+	target, err := v.source.Resolve(sc.targets.ctx, refStr)
+	if err != nil {
+		target = nil
+	}
+	sc.targets.sourced[refStr] = target
+	return target
+}
+
+// Targets resolves the references of a resource to their targets as target
+// profile checks do: a contained resource, an entry when the resource is a
+// Bundle, a resource of the Source, or one fetched with the Resolver. Each
+// target is fetched once. It answers the FHIRPath resolve() function of
+// constraints (see constraint.Resolver) and is not safe for concurrent use.
+type Targets struct {
+	v        *Validator
+	ctx      context.Context
+	resource map[string]any
+	sc       *scope            // Created on first use
+	json     map[string][]byte // reference -> target JSON
+}
+
+// Targets returns the Targets of a resource, with ctx passed to the Source
+// and the Resolver.
+func (v *Validator) Targets(ctx context.Context, resource map[string]any) *Targets {
+	return &Targets{v: v, ctx: ctx, resource: resource}
+}
+
+// Resource returns the resource a reference resolves to, or nil.
+func (t *Targets) Resource(reference string) map[string]any {
+	if t.sc == nil {
+		t.sc = &scope{container: t.resource, targets: newTargetCache(t.ctx)}
+		if resourceType, _ := t.resource["resourceType"].(string); resourceType == "Bundle" {
+			t.sc.bundle = NewBundleContext(t.resource)
+		}
+		t.json = make(map[string][]byte)
+	}
+	return t.v.resolveTarget(reference, t.sc)
+}
+
+// Resolve returns the JSON of the resource a reference resolves to, or nil
+// when it does not resolve.
+func (t *Targets) Resolve(_ context.Context, reference string) ([]byte, error) {
+	if data, ok := t.json[reference]; ok {
+		return data, nil
+	}
+	target := t.Resource(reference)
+	if target == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(target)
+	if err != nil {
+		return nil, err
+	}
+	t.json[reference] = data
+	return data, nil
+}
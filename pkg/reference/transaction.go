@@ -0,0 +1,244 @@
+package reference
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Bundle types whose entries are requests to process.
+const (
+	bundleTransaction = "transaction"
+	bundleBatch       = "batch"
+)
+
+// resourceTypePattern matches the resource type of a conditional reference
+// or request URL.
+var resourceTypePattern = regexp.MustCompile(`^[A-Z][A-Za-z]+$`)
+
+// IsTransaction reports whether the Bundle is a transaction or a batch,
+// whose entries may use conditional references and must carry requests.
+func (c *BundleContext) IsTransaction() bool {
+	return c.Type == bundleTransaction || c.Type == bundleBatch
+}
+
+// ValidateTransaction validates the entries of a transaction or batch Bundle:
+// each entry's request must agree with its content, and conditional request
+// URLs must be well formed. Entries that reference
+// each other in a cycle are reported for information. Other Bundle types are
+// ignored. URN references without a matching fullUrl are reported by the
+// reference validator, given a BundleContext for the Bundle.
+func ValidateTransaction(bundle map[string]any, result *issue.Result) {
+	bundleType, _ := bundle["type"].(string)
+	if bundleType != bundleTransaction && bundleType != bundleBatch {
+		return
+	}
+	entries, ok := bundle["entry"].([]any)
+	if !ok {
+		return
+	}
+
+	fullURLs := make([]string, len(entries))
+	for i, entry := range entries {
+		entryMap, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		fullURLs[i], _ = entryMap["fullUrl"].(string)
+
+		if request, ok := entryMap["request"].(map[string]any); ok {
+			resource, _ := entryMap["resource"].(map[string]any)
+			validateRequest(request, resource, fmt.Sprintf("Bundle.entry[%d].request", i), result)
+		}
+	}
+
+	reportCycles(entries, fullURLs, result)
+}
+
+// validateRequest checks that an entry's request agrees with its resource.
+func validateRequest(request, resource map[string]any, requestPath string, result *issue.Result) {
+	method, _ := request["method"].(string)
+	reqURL, _ := request["url"].(string)
+	mismatch := func(reason string) {
+		result.AddErrorWithID(
+			issue.DiagBundleRequestMismatch,
+			issue.Params{issue.String("method", method), issue.String("url", reqURL), issue.String("reason", reason)},
+			requestPath,
+		)
+	}
+
+	switch method {
+	case "POST", "PUT", "PATCH":
+		if resource == nil {
+			mismatch("a resource is required")
+			return
+		}
+	case "GET", "HEAD", "DELETE":
+		if resource != nil {
+			mismatch("the entry must not contain a resource")
+		}
+	}
+
+	path, query, conditional := strings.Cut(reqURL, "?")
+	if conditional {
+		if reason := conditionalQueryProblem(query); reason != "" {
+			mismatch("invalid conditional URL: " + reason)
+		}
+	}
+	if ifNoneExist, _ := request["ifNoneExist"].(string); ifNoneExist != "" {
+		if reason := conditionalQueryProblem(strings.TrimPrefix(ifNoneExist, "?")); reason != "" {
+			mismatch("invalid ifNoneExist: " + reason)
+		}
+	}
+
+	if method != "POST" && method != "PUT" {
+		return
+	}
+	resourceType, _ := resource["resourceType"].(string)
+	urlType, id, hasID := strings.Cut(path, "/")
+	if urlType != resourceType {
+		mismatch(fmt.Sprintf("the URL names %q but the resource is a %s", urlType, resourceType))
+		return
+	}
+	switch {
+	case method == "POST" && hasID:
+		mismatch("a create must not include an id in the URL")
+	case method == "PUT" && !hasID && !conditional:
+		mismatch("an update requires an id or a search in the URL")
+	case method == "PUT" && hasID:
+		if resourceID, _ := resource["id"].(string); resourceID != "" && resourceID != id {
+			mismatch(fmt.Sprintf("the URL id %q differs from the resource id %q", id, resourceID))
+		}
+	}
+}
+
+// conditionalReferenceProblem describes what is wrong with a conditional
+// reference ("Type?search"), or returns "" if it is well formed.
+func conditionalReferenceProblem(ref string) string {
+	resourceType, query, _ := strings.Cut(ref, "?")
+	if !resourceTypePattern.MatchString(resourceType) {
+		return fmt.Sprintf("%q is not a resource type", resourceType)
+	}
+	return conditionalQueryProblem(query)
+}
+
+// conditionalQueryProblem describes what is wrong with the search part of a
+// conditional reference or request, or returns "" if it is well formed.
+func conditionalQueryProblem(query string) string {
+	if query == "" {
+		return "the search is empty"
+	}
+	for _, param := range strings.Split(query, "&") {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || name == "" || value == "" {
+			return fmt.Sprintf("search parameter %q is not of the form name=value", param)
+		}
+		if _, err := url.QueryUnescape(value); err != nil {
+			return fmt.Sprintf("search parameter %q is not correctly escaped", param)
+		}
+	}
+	return ""
+}
+
+// reportCycles reports groups of entries whose resources reference each
+// other in a cycle.
+func reportCycles(entries []any, fullURLs []string, result *issue.Result) {
+	graph := make([][]int, len(entries))
+	for i, entry := range entries {
+		entryMap, _ := entry.(map[string]any)
+		resource, _ := entryMap["resource"].(map[string]any)
+		for _, ref := range walker.CollectReferences(resource, nil) {
+			if j := resolveEntry(ref, fullURLs); j >= 0 && j != i && !slices.Contains(graph[i], j) {
+				graph[i] = append(graph[i], j)
+			}
+		}
+	}
+
+	for _, component := range stronglyConnected(graph) {
+		if len(component) < 2 {
+			continue
+		}
+		slices.Sort(component)
+		names := make([]string, len(component))
+		for k, i := range component {
+			names[k] = fmt.Sprintf("Bundle.entry[%d]", i)
+		}
+		result.AddInfoWithID(
+			issue.DiagBundleReferenceCycle,
+			issue.Params{issue.String("entries", strings.Join(names, ", "))},
+			names[0],
+		)
+	}
+}
+
+// resolveEntry returns the index of the entry a reference points to, by
+// exact fullUrl or, for relative references, by the fullUrl's trailing
+// "Type/id"; -1 if none does.
+func resolveEntry(ref string, fullURLs []string) int {
+	ref = strings.Split(ref, "/_history/")[0]
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return -1
+	}
+	for i, fullURL := range fullURLs {
+		if fullURL != "" && (fullURL == ref || strings.HasSuffix(fullURL, "/"+ref)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// stronglyConnected returns the strongly connected components of a graph
+// (Tarjan's algorithm).
+func stronglyConnected(graph [][]int) [][]int {
+	index := make([]int, len(graph))
+	low := make([]int, len(graph))
+	onStack := make([]bool, len(graph))
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int
+	var components [][]int
+	next := 0
+
+	var visit func(n int)
+	visit = func(n int) {
+		index[n], low[n] = next, next
+		next++
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, m := range graph[n] {
+			if index[m] < 0 {
+				visit(m)
+				low[n] = min(low[n], low[m])
+			} else if onStack[m] {
+				low[n] = min(low[n], index[m])
+			}
+		}
+		if low[n] != index[n] {
+			return
+		}
+		var component []int
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[m] = false
+			component = append(component, m)
+			if m == n {
+				break
+			}
+		}
+		components = append(components, component)
+	}
+
+	for n := range graph {
+		if index[n] < 0 {
+			visit(n)
+		}
+	}
+	return components
+}
@@ -0,0 +1,122 @@
+package reference
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+func TestValidateTransaction(t *testing.T) {
+	var bundle map[string]any
+	err := json.Unmarshal([]byte(`{
+  "resourceType": "Bundle",
+  "type": "transaction",
+  "entry": [
+    {"fullUrl": "urn:uuid:1", "resource": {"resourceType": "Patient", "link": [{"other": {"reference": "urn:uuid:2"}}]},
+     "request": {"method": "POST", "url": "Patient", "ifNoneExist": "identifier=http://x|1"}},
+    {"fullUrl": "urn:uuid:2", "resource": {"resourceType": "Patient", "link": [{"other": {"reference": "urn:uuid:1"}}]},
+     "request": {"method": "POST", "url": "Patient/2"}},
+    {"fullUrl": "urn:uuid:2", "resource": {"resourceType": "Observation", "id": "a"},
+     "request": {"method": "PUT", "url": "Observation/b"}},
+    {"resource": {"resourceType": "Patient"}, "request": {"method": "DELETE", "url": "Patient?identifier"}},
+    {"request": {"method": "PUT", "url": "Patient/1"}},
+    {"resource": {"resourceType": "Patient"}, "request": {"method": "PUT", "url": "Observation/1"}},
+    {"request": {"method": "GET", "url": "Patient?name=peter"}}
+  ]
+}`), &bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := issue.NewResult()
+	ValidateTransaction(bundle, result)
+
+	want := map[string]issue.DiagnosticID{
+		"Bundle.entry[1].request": issue.DiagBundleRequestMismatch, // POST with an id
+		"Bundle.entry[2].request": issue.DiagBundleRequestMismatch, // id differs
+		"Bundle.entry[4].request": issue.DiagBundleRequestMismatch, // PUT without resource
+		"Bundle.entry[5].request": issue.DiagBundleRequestMismatch, // type differs
+		"Bundle.entry[0]":         issue.DiagBundleReferenceCycle,  // entries 0 and 1
+	}
+	got := make(map[string]issue.DiagnosticID)
+	for _, iss := range result.Issues {
+		got[iss.Expression[0]] = issue.DiagnosticID(iss.MessageID)
+	}
+	// DELETE with a resource and a malformed search reports twice at entry[3]
+	if got["Bundle.entry[3].request"] != issue.DiagBundleRequestMismatch {
+		t.Errorf("entry[3]: missing request mismatch")
+	}
+	delete(got, "Bundle.entry[3].request")
+	for path, id := range want {
+		if got[path] != id {
+			t.Errorf("%s: got %q, want %s", path, got[path], id)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected issues: %v", result.Issues)
+	}
+
+	// Other Bundle types are not checked
+	bundle["type"] = "collection"
+	result = issue.NewResult()
+	ValidateTransaction(bundle, result)
+	if len(result.Issues) != 0 {
+		t.Errorf("collection Bundle: got %v", result.Issues)
+	}
+}
+
+func TestConditionalReferenceProblem(t *testing.T) {
+	tests := map[string]bool{
+		"Patient?identifier=http://x|1":     true,
+		"Patient?name=peter&birthdate=1974": true,
+		"Patient?identifier=a%20b":          true,
+		"Patient?":                          false,
+		"Patient?identifier":                false,
+		"Patient?identifier=":               false,
+		"patient?name=x":                    false,
+		"Patient?name=x&&gender=male":       false,
+		"Patient?name=%zz":                  false,
+	}
+	for ref, valid := range tests {
+		if got := conditionalReferenceProblem(ref) == ""; got != valid {
+			t.Errorf("conditionalReferenceProblem(%q) valid = %v, want %v", ref, got, valid)
+		}
+	}
+}
+
+func TestTransactionReferences(t *testing.T) {
+	anyRef := &registry.ElementDefinition{
+		Type: []registry.Type{{Code: "Reference"}},
+	}
+	transaction := &scope{bundle: &BundleContext{Type: "transaction", FullURLIndex: map[string]string{"urn:uuid:1": "Patient"}}}
+	collection := &scope{bundle: &BundleContext{Type: "collection", FullURLIndex: map[string]string{}}}
+
+	tests := []struct {
+		name string
+		ref  string
+		sc   *scope
+		want issue.DiagnosticID
+	}{
+		{"URN resolved", "urn:uuid:1", transaction, ""},
+		{"URN unresolved", "urn:uuid:9", transaction, issue.DiagReferenceNotInBundle},
+		{"malformed conditional", "Patient?identifier", transaction, issue.DiagReferenceConditional},
+		{"conditional outside transaction", "Patient?identifier=1", collection, issue.DiagReferenceInvalidFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Validator{registry: mockRegistry()}
+			result := issue.NewResult()
+			v.validateReference(map[string]any{"reference": tt.ref}, anyRef, "Test.ref", tt.sc, result)
+
+			var got issue.DiagnosticID
+			if len(result.Issues) > 0 {
+				got = issue.DiagnosticID(result.Issues[0].MessageID)
+			}
+			if got != tt.want || len(result.Issues) > 1 {
+				t.Errorf("got %v, want %q", result.Issues, tt.want)
+			}
+		})
+	}
+}
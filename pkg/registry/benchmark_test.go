@@ -1,9 +1,11 @@
 package registry
 
 import (
+	"runtime"
 	"testing"
 
 	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/specs"
 )
 
 var benchRegistry *Registry
@@ -104,3 +106,54 @@ func BenchmarkTypeCategoryChecks(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkLoadFromPackagesMemory compares the heap retained by a registry
+// after loading (only headers are parsed) and after parsing every
+// definition, with and without sharing equal element data.
+func BenchmarkLoadFromPackagesMemory(b *testing.B) {
+	packages, err := loader.NewLoader("").LoadFromEmbeddedData(specs.GetPackages("4.0.1"))
+	if err != nil {
+		b.Skipf("Cannot load embedded FHIR packages: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		interning bool
+		parseAll  bool
+	}{
+		{"headers", true, false},
+		{"parsed-plain", false, true},
+		{"parsed-interned", true, true},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				before := heapAlloc()
+				r := New()
+				if !tc.interning {
+					r.interner = nil
+				}
+				if err := r.LoadFromPackages(packages); err != nil {
+					b.Fatalf("LoadFromPackages failed: %v", err)
+				}
+				if tc.parseAll {
+					for _, url := range r.AllURLs() {
+						r.GetByURL(url)
+					}
+				}
+				retained += heapAlloc() - before
+				runtime.KeepAlive(r)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N)/(1<<20), "MiB-retained")
+		})
+	}
+}
+
+// heapAlloc returns the bytes of live heap objects after a collection.
+func heapAlloc() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
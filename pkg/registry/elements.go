@@ -0,0 +1,81 @@
+package registry
+
+import "strings"
+
+// Snapshot navigation by element id and JSON representation, shared by the
+// walker and the code and schema generators.
+
+// ElementID returns the element's id, or its path for definitions without ids.
+func (ed *ElementDefinition) ElementID() string {
+	if ed.ID != "" {
+		return ed.ID
+	}
+	return ed.Path
+}
+
+// ChildName returns the name of an element whose id is a direct child of
+// parentID, excluding slices.
+func ChildName(id, parentID string) (string, bool) {
+	rest, ok := strings.CutPrefix(id, parentID+".")
+	if !ok || rest == "" || strings.ContainsAny(rest, ".:") {
+		return "", false
+	}
+	return rest, true
+}
+
+// ElementByID returns the snapshot element with the given id, or nil.
+func (sd *StructureDefinition) ElementByID(id string) *ElementDefinition {
+	for i := range sd.Snapshot.Element {
+		if sd.Snapshot.Element[i].ElementID() == id {
+			return &sd.Snapshot.Element[i]
+		}
+	}
+	return nil
+}
+
+// SlicesOf returns the slices defined on the element with id, excluding
+// reslices.
+func (sd *StructureDefinition) SlicesOf(id string) []*ElementDefinition {
+	prefix := id + ":"
+	var slices []*ElementDefinition
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		rest, ok := strings.CutPrefix(elem.ElementID(), prefix)
+		if ok && rest != "" && !strings.ContainsAny(rest, "./:") {
+			slices = append(slices, elem)
+		}
+	}
+	return slices
+}
+
+// SliceNamed returns the slice with the given name of the element with id,
+// or nil.
+func (sd *StructureDefinition) SliceNamed(id, name string) *ElementDefinition {
+	for _, slice := range sd.SlicesOf(id) {
+		if slice.SliceName != nil && *slice.SliceName == name {
+			return slice
+		}
+	}
+	return nil
+}
+
+// HasChildren reports whether sd defines children of the element with id.
+func (sd *StructureDefinition) HasChildren(id string) bool {
+	prefix := id + "."
+	for i := range sd.Snapshot.Element {
+		if strings.HasPrefix(sd.Snapshot.Element[i].ElementID(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Repeats reports whether elem is represented as a JSON array, which depends
+// on the base definition: a profile may restrict a list to max 1.
+func (r *Registry) Repeats(elem *ElementDefinition) bool {
+	maxCard := elem.Max
+	if base := r.GetElementDefinition(elem.Path); base != nil {
+		maxCard = base.Max
+	}
+	return maxCard != "1" && maxCard != "0"
+}
@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/specs"
+)
+
+func TestElementNavigation(t *testing.T) {
+	name := func(s string) *string { return &s }
+	sd := &StructureDefinition{Snapshot: &Snapshot{Element: []ElementDefinition{
+		{ID: "Patient", Path: "Patient"},
+		{ID: "Patient.identifier", Path: "Patient.identifier"},
+		{ID: "Patient.identifier:mrn", Path: "Patient.identifier", SliceName: name("mrn")},
+		{ID: "Patient.identifier:mrn/local", Path: "Patient.identifier", SliceName: name("mrn/local")},
+		{ID: "Patient.identifier:mrn.system", Path: "Patient.identifier.system"},
+		{Path: "Patient.active"},
+	}}}
+
+	if got := sd.Snapshot.Element[5].ElementID(); got != "Patient.active" {
+		t.Errorf("ElementID() without id = %q, want the path", got)
+	}
+	if sd.ElementByID("Patient.active") != &sd.Snapshot.Element[5] || sd.ElementByID("Patient.name") != nil {
+		t.Error("ElementByID() returned the wrong element")
+	}
+	if slices := sd.SlicesOf("Patient.identifier"); len(slices) != 1 || slices[0] != &sd.Snapshot.Element[2] {
+		t.Errorf("SlicesOf() = %v, want the mrn slice without its reslice", slices)
+	}
+	if sd.SliceNamed("Patient.identifier", "mrn") != &sd.Snapshot.Element[2] || sd.SliceNamed("Patient.identifier", "other") != nil {
+		t.Error("SliceNamed() returned the wrong slice")
+	}
+	if !sd.HasChildren("Patient.identifier:mrn") || sd.HasChildren("Patient.active") {
+		t.Error("HasChildren() is wrong")
+	}
+
+	tests := []struct {
+		id, parent, want string
+		ok               bool
+	}{
+		{"Patient.identifier", "Patient", "identifier", true},
+		{"Patient.identifier.system", "Patient", "", false},
+		{"Patient.identifier:mrn", "Patient", "", false},
+		{"Patient.identifier:mrn.system", "Patient.identifier:mrn", "system", true},
+	}
+	for _, tt := range tests {
+		if got, ok := ChildName(tt.id, tt.parent); got != tt.want || ok != tt.ok {
+			t.Errorf("ChildName(%q, %q) = %q, %v, want %q, %v", tt.id, tt.parent, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRepeats(t *testing.T) {
+	packages, err := loader.NewLoader("").LoadFromEmbeddedData(specs.GetPackages("4.0.1"))
+	if err != nil {
+		t.Skipf("Cannot load embedded FHIR packages: %v", err)
+	}
+	r := New()
+	if err := r.LoadFromPackages(packages); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+
+	// A profile restricting a list to max 1 keeps the base's JSON array
+	restricted := *r.GetElementDefinition("Patient.name")
+	restricted.Max = "1"
+	if !r.Repeats(&restricted) || r.Repeats(r.GetElementDefinition("Patient.gender")) {
+		t.Error("Repeats() should follow the max of the base definition")
+	}
+}
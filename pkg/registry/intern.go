@@ -0,0 +1,219 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/maphash"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// interner deduplicates the element data of the StructureDefinitions of a
+// registry. Profiles repeat most elements of their base definitions in their
+// snapshots, and extension definitions repeat the elements of Extension, so
+// equal strings, raw elements, type lists, constraint lists and bindings are
+// stored once and shared. Shared data is immutable: nothing in the registry
+// or its callers modifies loaded elements.
+//
+// Values are keyed by a hash of their content and compared on a hit, so a
+// hash collision only leaves the colliding value unshared. An interner is
+// safe for concurrent use.
+type interner struct {
+	mu          sync.Mutex
+	seed        maphash.Seed
+	strings     map[string]string
+	raws        map[uint64]json.RawMessage
+	types       map[uint64][]Type
+	constraints map[uint64][]Constraint
+	bindings    map[uint64]*Binding
+}
+
+// newInterner creates an empty interner.
+func newInterner() *interner {
+	return &interner{
+		seed:        maphash.MakeSeed(),
+		strings:     make(map[string]string),
+		raws:        make(map[uint64]json.RawMessage),
+		types:       make(map[uint64][]Type),
+		constraints: make(map[uint64][]Constraint),
+		bindings:    make(map[uint64]*Binding),
+	}
+}
+
+// elements interns the elements of a StructureDefinition.
+func (in *interner) elements(snapshot *Snapshot, differential *Differential) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if snapshot != nil {
+		in.elementList(snapshot.Element)
+	}
+	if differential != nil {
+		in.elementList(differential.Element)
+	}
+}
+
+// elementList interns each element of a snapshot or differential.
+func (in *interner) elementList(elems []ElementDefinition) {
+	for i := range elems {
+		ed := &elems[i]
+		ed.ID = in.string(ed.ID)
+		ed.Path = in.string(ed.Path)
+		ed.Max = in.string(ed.Max)
+		ed.SliceName = in.stringPtr(ed.SliceName)
+		ed.ContentReference = in.stringPtr(ed.ContentReference)
+		ed.Type = in.typeList(ed.Type)
+		ed.Constraint = in.constraintList(ed.Constraint)
+		ed.Binding = in.binding(ed.Binding)
+		ed.raw = in.raw(ed.raw)
+	}
+}
+
+// string returns the shared copy of s.
+func (in *interner) string(s string) string {
+	if s == "" {
+		return ""
+	}
+	if shared, ok := in.strings[s]; ok {
+		return shared
+	}
+	in.strings[s] = s
+	return s
+}
+
+// strings interns each string of a list in place.
+func (in *interner) stringList(list []string) {
+	for i, s := range list {
+		list[i] = in.string(s)
+	}
+}
+
+// stringPtr returns a pointer to the shared copy of *s.
+func (in *interner) stringPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	shared := in.string(*s)
+	return &shared
+}
+
+// raw returns the shared copy of a raw element.
+func (in *interner) raw(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return data
+	}
+	key := maphash.Bytes(in.seed, data)
+	if shared, ok := in.raws[key]; ok {
+		if bytes.Equal(shared, data) {
+			return shared
+		}
+		return data
+	}
+	in.raws[key] = data
+	return data
+}
+
+// typeList returns the shared copy of an element's types.
+func (in *interner) typeList(types []Type) []Type {
+	if len(types) == 0 {
+		return types
+	}
+	var h maphash.Hash
+	h.SetSeed(in.seed)
+	for _, t := range types {
+		writeStrings(&h, t.Code, t.Versioning)
+		writeStrings(&h, t.Profile...)
+		writeStrings(&h, t.TargetProfile...)
+		writeStrings(&h, t.Aggregation...)
+		for _, ext := range t.Extension {
+			writeStrings(&h, ext.URL, ext.ValueString, ext.ValueURL, ext.ValueURI, ext.ValueCanonical)
+		}
+	}
+	key := h.Sum64()
+	if shared, ok := in.types[key]; ok {
+		if reflect.DeepEqual(shared, types) {
+			return shared
+		}
+		return types
+	}
+	for i := range types {
+		t := &types[i]
+		t.Code = in.string(t.Code)
+		t.Versioning = in.string(t.Versioning)
+		in.stringList(t.Profile)
+		in.stringList(t.TargetProfile)
+		in.stringList(t.Aggregation)
+		for j := range t.Extension {
+			t.Extension[j].URL = in.string(t.Extension[j].URL)
+			t.Extension[j].ValueString = in.string(t.Extension[j].ValueString)
+			t.Extension[j].ValueURL = in.string(t.Extension[j].ValueURL)
+		}
+	}
+	in.types[key] = types
+	return types
+}
+
+// constraintList returns the shared copy of an element's constraints.
+func (in *interner) constraintList(constraints []Constraint) []Constraint {
+	if len(constraints) == 0 {
+		return constraints
+	}
+	var h maphash.Hash
+	h.SetSeed(in.seed)
+	for _, c := range constraints {
+		writeStrings(&h, c.Key, c.Severity, c.Human, c.Expression)
+	}
+	key := h.Sum64()
+	if shared, ok := in.constraints[key]; ok {
+		if slices.Equal(shared, constraints) {
+			return shared
+		}
+		return constraints
+	}
+	for i := range constraints {
+		c := &constraints[i]
+		c.Key = in.string(c.Key)
+		c.Severity = in.string(c.Severity)
+		c.Human = in.string(c.Human)
+		c.Expression = in.string(c.Expression)
+	}
+	in.constraints[key] = constraints
+	return constraints
+}
+
+// binding returns the shared copy of an element's binding.
+func (in *interner) binding(b *Binding) *Binding {
+	if b == nil {
+		return nil
+	}
+	var h maphash.Hash
+	h.SetSeed(in.seed)
+	writeStrings(&h, b.Strength, b.ValueSet)
+	for _, ext := range b.Extension {
+		writeStrings(&h, ext.URL, ext.ValueString, ext.ValueURL, ext.ValueURI, ext.ValueCanonical)
+	}
+	key := h.Sum64()
+	if shared, ok := in.bindings[key]; ok {
+		if reflect.DeepEqual(shared, b) {
+			return shared
+		}
+		return b
+	}
+	b.Strength = in.string(b.Strength)
+	b.ValueSet = in.string(b.ValueSet)
+	for i := range b.Extension {
+		b.Extension[i].URL = in.string(b.Extension[i].URL)
+		b.Extension[i].ValueString = in.string(b.Extension[i].ValueString)
+	}
+	in.bindings[key] = b
+	return b
+}
+
+// writeStrings writes strings to a hash, each followed by a separator so
+// that ("ab", "c") and ("a", "bc") hash differently.
+func writeStrings(h *maphash.Hash, values ...string) {
+	for _, s := range values {
+		_, _ = h.WriteString(s)
+		_ = h.WriteByte(0)
+	}
+}
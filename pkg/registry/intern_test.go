@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/specs"
+)
+
+func TestInterning(t *testing.T) {
+	packages, err := loader.NewLoader("").LoadFromEmbeddedData(specs.GetPackages("4.0.1"))
+	if err != nil {
+		t.Skipf("Cannot load embedded FHIR packages: %v", err)
+	}
+	interned := New()
+	if err := interned.LoadFromPackages(packages); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+	plain := New()
+	plain.interner = nil
+	if err := plain.LoadFromPackages(packages); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+
+	// Interning must not change any definition
+	for _, url := range plain.AllURLs() {
+		a, b := *interned.GetByURL(url), *plain.GetByURL(url)
+		a.lazy, b.lazy = nil, nil
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("%s differs when interned", url)
+		}
+	}
+
+	// Extension definitions share the elements inherited from Extension
+	a := interned.GetByURL("http://hl7.org/fhir/StructureDefinition/patient-birthPlace")
+	b := interned.GetByURL("http://hl7.org/fhir/StructureDefinition/patient-nationality")
+	if a == nil || b == nil {
+		t.Fatal("extension definitions not found")
+	}
+	ea, eb := a.Snapshot.Element[1], b.Snapshot.Element[1] // Extension.id
+	if ea.Path != "Extension.id" || eb.Path != "Extension.id" {
+		t.Fatalf("paths = %q, %q, want Extension.id", ea.Path, eb.Path)
+	}
+	if unsafe.StringData(ea.Path) != unsafe.StringData(eb.Path) {
+		t.Error("equal paths are not shared")
+	}
+	if &ea.raw[0] != &eb.raw[0] {
+		t.Error("equal raw elements are not shared")
+	}
+	if &ea.Type[0] != &eb.Type[0] {
+		t.Error("equal types are not shared")
+	}
+	if ca, cb := a.Snapshot.Element[0].Constraint, b.Snapshot.Element[0].Constraint; len(ca) == 0 || &ca[0] != &cb[0] {
+		t.Error("equal constraints are not shared")
+	}
+}
@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// header is a StructureDefinition without its elements. Loading parses only
+// headers; most definitions of large packages are never used, and their
+// elements are most of their size.
+type header struct {
+	StructureDefinition
+	Snapshot     skipped `json:"snapshot"`
+	Differential skipped `json:"differential"`
+}
+
+// skipped is a JSON value that is checked for syntax but not decoded.
+type skipped struct{}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (*skipped) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// lazyElements parses the elements of a StructureDefinition once.
+type lazyElements struct {
+	once sync.Once
+	in   *interner // Shares the parsed element data (nil = no sharing)
+}
+
+// parsed returns sd with its snapshot and differential parsed from its raw
+// JSON on first use. Definitions whose elements do not parse have neither.
+func (sd *StructureDefinition) parsed() *StructureDefinition {
+	if sd == nil || sd.lazy == nil {
+		return sd
+	}
+	sd.lazy.once.Do(func() {
+		var elements struct {
+			Snapshot     *Snapshot     `json:"snapshot"`
+			Differential *Differential `json:"differential"`
+		}
+		if err := json.Unmarshal(sd.raw, &elements); err != nil {
+			return
+		}
+		if sd.lazy.in != nil {
+			sd.lazy.in.elements(elements.Snapshot, elements.Differential)
+		}
+		sd.Snapshot = elements.Snapshot
+		sd.Differential = elements.Differential
+	})
+	return sd
+}
+
+// parsedAll returns sds, each parsed.
+func parsedAll(sds []*StructureDefinition) []*StructureDefinition {
+	for _, sd := range sds {
+		sd.parsed()
+	}
+	return sds
+}
@@ -0,0 +1,116 @@
+package registry
+
+// members iterates over the top-level members of a raw JSON object without
+// decoding it. Keys and values are returned as slices of the source.
+type members struct {
+	data []byte
+	pos  int
+}
+
+// newMembers positions an iterator inside the object in data. It returns
+// false if data is not a JSON object.
+func newMembers(data []byte) (members, bool) {
+	m := members{data: data}
+	m.pos = m.skipSpace(0)
+	if m.pos >= len(data) || data[m.pos] != '{' {
+		return m, false
+	}
+	m.pos = m.skipSpace(m.pos + 1)
+	return m, true
+}
+
+// next returns the next member's key (without quotes) and raw value. It
+// returns false at the end of the object or on malformed input.
+func (m *members) next() (key, value []byte, ok bool) {
+	d := m.data
+	if m.pos >= len(d) || d[m.pos] != '"' {
+		return nil, nil, false
+	}
+	end := m.skipString(m.pos)
+	if end < 0 {
+		return nil, nil, false
+	}
+	key = d[m.pos+1 : end-1]
+
+	pos := m.skipSpace(end)
+	if pos >= len(d) || d[pos] != ':' {
+		return nil, nil, false
+	}
+	start := m.skipSpace(pos + 1)
+	end = m.skipValue(start)
+	if end < 0 {
+		return nil, nil, false
+	}
+	value = d[start:end]
+
+	pos = m.skipSpace(end)
+	if pos < len(d) && d[pos] == ',' {
+		pos = m.skipSpace(pos + 1)
+	}
+	m.pos = pos
+	return key, value, true
+}
+
+// skipValue returns the offset just after the value starting at pos, or -1.
+func (m *members) skipValue(pos int) int {
+	d := m.data
+	if pos >= len(d) {
+		return -1
+	}
+	switch d[pos] {
+	case '"':
+		return m.skipString(pos)
+	case '{', '[':
+		depth := 0
+		for ; pos < len(d); pos++ {
+			switch d[pos] {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return pos + 1
+				}
+			case '"':
+				if pos = m.skipString(pos); pos < 0 {
+					return -1
+				}
+				pos-- // compensate for the loop increment
+			}
+		}
+		return -1
+	default:
+		for ; pos < len(d); pos++ {
+			switch d[pos] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				return pos
+			}
+		}
+		return pos
+	}
+}
+
+// skipString returns the offset just after the string starting at pos, or -1.
+func (m *members) skipString(pos int) int {
+	for pos++; pos < len(m.data); pos++ {
+		switch m.data[pos] {
+		case '\\':
+			pos++
+		case '"':
+			return pos + 1
+		}
+	}
+	return -1
+}
+
+// skipSpace returns the offset of the first non-whitespace byte at or after pos.
+func (m *members) skipSpace(pos int) int {
+	for pos < len(m.data) {
+		switch m.data[pos] {
+		case ' ', '\t', '\n', '\r':
+			pos++
+		default:
+			return pos
+		}
+	}
+	return pos
+}
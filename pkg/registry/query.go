@@ -0,0 +1,150 @@
+package registry
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/loader"
+)
+
+// Read-only queries for tooling built on the validator (IDE plugins, docs
+// generators). Results are sorted by URL; the returned definitions are shared
+// with the validator and must not be modified.
+
+var pathIndexRegex = regexp.MustCompile(`\[\d+\]`)
+
+// ProfilesForType returns the profiles (constraint StructureDefinitions) on
+// the given type, e.g. every Patient profile for "Patient".
+func (r *Registry) ProfilesForType(typeName string) []*StructureDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var profiles []*StructureDefinition
+	for _, sd := range r.byURL {
+		if sd.Derivation == "constraint" && sd.Type == typeName {
+			profiles = append(profiles, sd)
+		}
+	}
+	sortByURL(profiles)
+	return parsedAll(profiles)
+}
+
+// ExtensionsForContext returns the extension definitions that may be used on
+// the element at path, such as "Patient", "Patient.name" or
+// "Patient.name[0].family" (indices are ignored). An extension applies when
+// one of its element contexts names the path, a type of the element (directly
+// or as "HumanName.family"), or an abstract type it derives from; extensions
+// without contexts apply everywhere.
+func (r *Registry) ExtensionsForContext(path string) []*StructureDefinition {
+	path = pathIndexRegex.ReplaceAllString(path, "")
+	aliases, types := r.resolvePath(path)
+	isRoot := !strings.Contains(path, ".")
+
+	matches := func(expression string) bool {
+		switch expression {
+		case "Element":
+			return true
+		case "Resource":
+			return isRoot && r.IsResourceType(path)
+		case "DomainResource":
+			return isRoot && r.IsDomainResource(path)
+		case "CanonicalResource":
+			return isRoot && r.IsCanonicalResource(path)
+		case "MetadataResource":
+			return isRoot && r.IsMetadataResource(path)
+		}
+		return slices.Contains(aliases, expression) || slices.Contains(types, expression)
+	}
+
+	r.mu.RLock()
+	var candidates []*StructureDefinition
+	for _, sd := range r.byURL {
+		if sd.Type == "Extension" && sd.Derivation == "constraint" {
+			candidates = append(candidates, sd)
+		}
+	}
+	r.mu.RUnlock()
+
+	var extensions []*StructureDefinition
+	for _, sd := range candidates {
+		if len(sd.Context) == 0 {
+			extensions = append(extensions, sd)
+			continue
+		}
+		for _, ctx := range sd.Context {
+			if ctx.Type == "element" && matches(ctx.Expression) {
+				extensions = append(extensions, sd)
+				break
+			}
+		}
+	}
+	sortByURL(extensions)
+	return parsedAll(extensions)
+}
+
+// resolvePath returns the equivalent paths of an element, rooted at the
+// resource and at each datatype along the way ("Patient.name.family" is also
+// "HumanName.family"), and the element's types.
+func (r *Registry) resolvePath(path string) (aliases, types []string) {
+	segments := strings.Split(path, ".")
+	aliases = []string{segments[0]}
+	types = []string{segments[0]}
+	for _, segment := range segments[1:] {
+		next := make([]string, 0, len(aliases)+len(types))
+		for _, alias := range aliases {
+			next = append(next, alias+"."+segment)
+		}
+		for _, typeName := range types {
+			if alias := typeName + "." + segment; !slices.Contains(next, alias) {
+				next = append(next, alias)
+			}
+		}
+		aliases = next
+
+		types = nil
+		for _, alias := range aliases {
+			ed := r.GetElementDefinition(alias)
+			if ed == nil {
+				continue
+			}
+			for _, t := range ed.Type {
+				if t.Code != "" && !slices.Contains(types, t.Code) {
+					types = append(types, t.Code)
+				}
+			}
+		}
+	}
+	return aliases, types
+}
+
+// Snapshot returns the snapshot elements of the StructureDefinition a
+// canonical URL (optionally "url|version") resolves to, or nil if it is not
+// loaded or has no snapshot.
+func (r *Registry) Snapshot(url string) []ElementDefinition {
+	sd := r.GetByURL(url)
+	if sd == nil || sd.Snapshot == nil {
+		return nil
+	}
+	return sd.Snapshot.Element
+}
+
+// Source returns the package the StructureDefinition a canonical URL
+// (optionally "url|version") resolves to was loaded from.
+func (r *Registry) Source(url string) (loader.PackageRef, bool) {
+	sd := r.GetByURL(url)
+	if sd == nil {
+		return loader.PackageRef{}, false
+	}
+	pkg := r.versions.Source(sd.URL, sd.Version)
+	if pkg == nil {
+		return loader.PackageRef{}, false
+	}
+	return loader.PackageRef{Name: pkg.Name, Version: pkg.Version}, true
+}
+
+func sortByURL(sds []*StructureDefinition) {
+	slices.SortFunc(sds, func(a, b *StructureDefinition) int {
+		return strings.Compare(a.URL, b.URL)
+	})
+}
@@ -2,9 +2,9 @@
 package registry
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"strings"
 	"sync"
 
 	"github.com/gofhir/validator/pkg/loader"
@@ -13,6 +13,7 @@ import (
 // StructureDefinition.Kind constants.
 const (
 	KindResource = "resource"
+	KindLogical  = "logical"
 )
 
 // StructureDefinition represents a minimal view of a FHIR StructureDefinition.
@@ -21,6 +22,7 @@ type StructureDefinition struct {
 	ResourceType   string `json:"resourceType"`
 	ID             string `json:"id"`
 	URL            string `json:"url"`
+	Version        string `json:"version"`
 	Name           string `json:"name"`
 	Kind           string `json:"kind"` // resource, complex-type, primitive-type, logical
 	Abstract       bool   `json:"abstract"`
@@ -30,12 +32,17 @@ type StructureDefinition struct {
 
 	// Context defines where an extension can be used
 	Context []ExtensionContext `json:"context,omitempty"`
+	// ContextInvariant holds FHIRPath expressions that must be true of the
+	// element containing the extension
+	ContextInvariant []string `json:"contextInvariant,omitempty"`
 
 	Snapshot     *Snapshot     `json:"snapshot,omitempty"`
 	Differential *Differential `json:"differential,omitempty"`
 
 	// Raw JSON for full access when needed
 	raw json.RawMessage
+	// Parses Snapshot and Differential from raw on first use (nil = parsed)
+	lazy *lazyElements
 }
 
 // ExtensionContext defines where an extension can be used.
@@ -98,15 +105,17 @@ func (d *Differential) UnmarshalJSON(data []byte) error {
 
 // ElementDefinition represents a FHIR ElementDefinition.
 type ElementDefinition struct {
-	ID         string       `json:"id"`
-	Path       string       `json:"path"`
-	SliceName  *string      `json:"sliceName,omitempty"`
-	Min        uint32       `json:"min"`
-	Max        string       `json:"max"`
-	Type       []Type       `json:"type,omitempty"`
-	Binding    *Binding     `json:"binding,omitempty"`
-	Constraint []Constraint `json:"constraint,omitempty"`
-	Slicing    *Slicing     `json:"slicing,omitempty"`
+	ID          string       `json:"id"`
+	Path        string       `json:"path"`
+	SliceName   *string      `json:"sliceName,omitempty"`
+	Min         uint32       `json:"min"`
+	Max         string       `json:"max"`
+	MaxLength   int          `json:"maxLength,omitempty"`
+	Type        []Type       `json:"type,omitempty"`
+	Binding     *Binding     `json:"binding,omitempty"`
+	Constraint  []Constraint `json:"constraint,omitempty"`
+	Slicing     *Slicing     `json:"slicing,omitempty"`
+	MustSupport bool         `json:"mustSupport,omitempty"`
 
 	// ContentReference references another element's definition for recursive structures.
 	// Format: "#ElementPath" (e.g., "#Questionnaire.item" for Questionnaire.item.item)
@@ -123,6 +132,11 @@ func (ed *ElementDefinition) SetRaw(data json.RawMessage) {
 	ed.raw = data
 }
 
+// Raw returns the ElementDefinition as loaded, in JSON.
+func (ed *ElementDefinition) Raw() json.RawMessage {
+	return ed.raw
+}
+
 // GetFixed extracts fixed[x] value dynamically from raw JSON.
 // Returns the value, type suffix (e.g., "Uri", "Code", "Coding"), and whether it exists.
 // This approach avoids hardcoding the 45+ possible fixed[x] types.
@@ -137,22 +151,81 @@ func (ed *ElementDefinition) GetPattern() (value json.RawMessage, typeSuffix str
 	return extractPrefixedValue(ed.raw, "pattern")
 }
 
-// extractPrefixedValue finds a key with the given prefix in the raw JSON.
-// Used for polymorphic properties like fixed[x] and pattern[x].
-func extractPrefixedValue(raw json.RawMessage, prefix string) (json.RawMessage, string, bool) {
-	if raw == nil {
-		return nil, "", false
+// GetMinValue extracts minValue[x] dynamically from raw JSON.
+// Returns the value, type suffix (e.g., "Integer", "Date", "Quantity"), and whether it exists.
+func (ed *ElementDefinition) GetMinValue() (value json.RawMessage, typeSuffix string, exists bool) {
+	return extractPrefixedValue(ed.raw, "minValue")
+}
+
+// GetMaxValue extracts maxValue[x] dynamically from raw JSON.
+// Returns the value, type suffix (e.g., "Integer", "Date", "Quantity"), and whether it exists.
+func (ed *ElementDefinition) GetMaxValue() (value json.RawMessage, typeSuffix string, exists bool) {
+	return extractPrefixedValue(ed.raw, "maxValue")
+}
+
+// GetExtensions returns the extensions with the given URL declared on the
+// ElementDefinition itself (e.g., obligation extensions), as raw JSON.
+func (ed *ElementDefinition) GetExtensions(url string) []json.RawMessage {
+	if ed.raw == nil || !bytes.Contains(ed.raw, []byte(url)) {
+		return nil
+	}
+
+	var obj struct {
+		Extension []json.RawMessage `json:"extension"`
 	}
+	if err := json.Unmarshal(ed.raw, &obj); err != nil {
+		return nil
+	}
+
+	var matches []json.RawMessage
+	for _, ext := range obj.Extension {
+		var head struct {
+			URL string `json:"url"`
+		}
+		if json.Unmarshal(ext, &head) == nil && head.URL == url {
+			matches = append(matches, ext)
+		}
+	}
+	return matches
+}
+
+// GetShort returns the element's short description from raw JSON.
+func (ed *ElementDefinition) GetShort() string {
+	return ed.docs().Short
+}
 
-	var obj map[string]json.RawMessage
-	if err := json.Unmarshal(raw, &obj); err != nil {
+// GetDefinition returns the element's full definition text from raw JSON.
+func (ed *ElementDefinition) GetDefinition() string {
+	return ed.docs().Definition
+}
+
+// elementDocs holds the documentation of an ElementDefinition. It is decoded
+// on demand rather than kept for every loaded element.
+type elementDocs struct {
+	Short      string `json:"short"`
+	Definition string `json:"definition"`
+}
+
+func (ed *ElementDefinition) docs() elementDocs {
+	var docs elementDocs
+	if ed.raw != nil {
+		_ = json.Unmarshal(ed.raw, &docs)
+	}
+	return docs
+}
+
+// extractPrefixedValue finds a key with the given prefix in the raw JSON.
+// Used for polymorphic properties like fixed[x] and pattern[x]. The object is
+// scanned in place, so lookups on the validation hot path do not decode it.
+func extractPrefixedValue(raw json.RawMessage, prefix string) (json.RawMessage, string, bool) {
+	m, ok := newMembers(raw)
+	if !ok {
 		return nil, "", false
 	}
 
-	for key, value := range obj {
-		if strings.HasPrefix(key, prefix) {
-			typeSuffix := strings.TrimPrefix(key, prefix)
-			return value, typeSuffix, true
+	for key, value, ok := m.next(); ok; key, value, ok = m.next() {
+		if len(key) >= len(prefix) && string(key[:len(prefix)]) == prefix {
+			return json.RawMessage(value), string(key[len(prefix):]), true
 		}
 	}
 	return nil, "", false
@@ -163,20 +236,25 @@ type Type struct {
 	Code          string      `json:"code"`
 	Profile       []string    `json:"profile,omitempty"`
 	TargetProfile []string    `json:"targetProfile,omitempty"`
+	Aggregation   []string    `json:"aggregation,omitempty"` // contained | referenced | bundled
+	Versioning    string      `json:"versioning,omitempty"`  // either | independent | specific
 	Extension     []Extension `json:"extension,omitempty"`
 }
 
 // Extension represents a FHIR extension.
 type Extension struct {
-	URL         string `json:"url"`
-	ValueString string `json:"valueString,omitempty"`
-	ValueURL    string `json:"valueUrl,omitempty"`
+	URL            string `json:"url"`
+	ValueString    string `json:"valueString,omitempty"`
+	ValueURL       string `json:"valueUrl,omitempty"`
+	ValueURI       string `json:"valueUri,omitempty"`
+	ValueCanonical string `json:"valueCanonical,omitempty"`
 }
 
 // Binding represents a terminology binding.
 type Binding struct {
-	Strength string `json:"strength"` // required | extensible | preferred | example
-	ValueSet string `json:"valueSet"`
+	Strength  string      `json:"strength"` // required | extensible | preferred | example
+	ValueSet  string      `json:"valueSet"`
+	Extension []Extension `json:"extension,omitempty"`
 }
 
 // Constraint represents a FHIRPath constraint/invariant.
@@ -185,6 +263,7 @@ type Constraint struct {
 	Severity   string `json:"severity"` // error | warning
 	Human      string `json:"human"`
 	Expression string `json:"expression"`
+	Source     string `json:"source,omitempty"` // Canonical URL of the profile that defined the constraint
 }
 
 // Slicing represents slicing rules for an element.
@@ -202,64 +281,90 @@ type Discriminator struct {
 // Registry holds loaded StructureDefinitions indexed by URL.
 type Registry struct {
 	mu              sync.RWMutex
-	byURL           map[string]*StructureDefinition
+	byURL           map[string]*StructureDefinition // Default definition of each URL
+	versions        *loader.Canonicals[StructureDefinition]
+	mapping         map[string]string               // Mirror base URL -> published base URL
 	byType          map[string]*StructureDefinition // For base types like "Patient", "HumanName"
 	elementDefCache map[string]*ElementDefinition   // path -> ElementDefinition cache
 
 	// Type classification caches - computed once after loading for O(1) lookups
-	domainResources    map[string]bool // types that inherit from DomainResource
-	canonicalResources map[string]bool // types with 'url' element
-	metadataResources  map[string]bool // canonical + name/status/experimental
+	domainResources map[string]bool // types that inherit from DomainResource
+	// Canonical kind of each queried resource type - computed on first query,
+	// since it needs the type's elements
+	canonicalKinds map[string]canonicalKind
+
+	interner *interner // Shares equal element data between definitions (nil = no sharing)
 }
 
 // New creates a new empty Registry.
 func New() *Registry {
 	return &Registry{
-		byURL:              make(map[string]*StructureDefinition),
-		byType:             make(map[string]*StructureDefinition),
-		elementDefCache:    make(map[string]*ElementDefinition),
-		domainResources:    make(map[string]bool),
-		canonicalResources: make(map[string]bool),
-		metadataResources:  make(map[string]bool),
+		byURL:           make(map[string]*StructureDefinition),
+		versions:        loader.NewCanonicals[StructureDefinition](loader.VersionLatest),
+		byType:          make(map[string]*StructureDefinition),
+		elementDefCache: make(map[string]*ElementDefinition),
+		domainResources: make(map[string]bool),
+		canonicalKinds:  make(map[string]canonicalKind),
+		interner:        newInterner(),
 	}
 }
 
+// SetVersionPolicy sets how an unversioned URL resolves when several versions
+// of the same package define it. It must be called before LoadFromPackages.
+func (r *Registry) SetVersionPolicy(policy loader.VersionPolicy) {
+	r.versions.SetPolicy(policy)
+}
+
 // LoadFromPackages loads StructureDefinitions from a slice of packages.
 // For extension definitions, contexts are MERGED from all packages to support
 // both R4 naming (from core) and expanded contexts (from extension packages).
 // See ADR-001 for rationale.
+//
+// When several packages define a URL the first one loaded is its default
+// definition, except between versions of the same package, where the version
+// policy decides. Every version stays available as "url|version".
+//
+// Only the header of each definition (URL, type, kind, contexts...) is
+// parsed on load; its snapshot and differential are parsed when the registry
+// first returns it. Equal element data (strings, raw elements, types,
+// constraints and bindings) is shared between definitions, so elements must
+// not be modified.
 func (r *Registry) LoadFromPackages(packages []*loader.Package) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	for _, pkg := range packages {
 		for key, data := range pkg.Resources {
-			// Quick check if this is a StructureDefinition
-			var peek struct {
-				ResourceType string `json:"resourceType"`
-			}
-			if err := json.Unmarshal(data, &peek); err != nil {
+			var h header
+			if err := json.Unmarshal(data, &h); err != nil {
 				continue
 			}
-			if peek.ResourceType != "StructureDefinition" {
-				continue
-			}
-
-			var sd StructureDefinition
-			if err := json.Unmarshal(data, &sd); err != nil {
+			if h.ResourceType != "StructureDefinition" {
 				continue
 			}
+			sd := h.StructureDefinition
 			sd.raw = data
+			sd.lazy = &lazyElements{in: r.interner}
 
 			// Index by URL
 			if sd.URL != "" {
-				if existing, exists := r.byURL[sd.URL]; exists {
+				isDefault := r.versions.Add(pkg, sd.URL, sd.Version, &sd, false)
+				existing, exists := r.byURL[sd.URL]
+				switch {
+				case !exists:
+					r.byURL[sd.URL] = &sd
+				case isDefault:
+					// A later version of the same package replaces the default
+					r.mergeExtensionContexts(&sd, existing)
+					r.byURL[sd.URL] = &sd
+					if r.byType[sd.Type] == existing {
+						r.byType[sd.Type] = &sd
+					}
+				default:
 					// Merge extension contexts from multiple package definitions
 					// This allows both R4 naming (RequestGroup) and R5 naming (RequestOrchestration)
 					// as well as broader contexts (CanonicalResource) from extension packages
 					r.mergeExtensionContexts(existing, &sd)
-				} else {
-					r.byURL[sd.URL] = &sd
 				}
 			}
 
@@ -294,19 +399,48 @@ func (r *Registry) buildTypeClassificationCaches() {
 		if r.inheritsFromUnlocked(sd, domainResourceURL) {
 			r.domainResources[typeName] = true
 		}
+	}
+}
+
+// canonicalKind classifies a resource type as canonical or metadata resource.
+type canonicalKind uint8
+
+const (
+	notCanonical      canonicalKind = iota
+	canonicalResource               // has a 'url' element
+	metadataResource                // canonical + name/status/experimental
+)
 
+// canonicalKindOf returns the canonical kind of a type, classifying it from
+// its elements on first query.
+func (r *Registry) canonicalKindOf(typeName string) canonicalKind {
+	r.mu.RLock()
+	kind, ok := r.canonicalKinds[typeName]
+	sd := r.byType[typeName]
+	r.mu.RUnlock()
+	if ok {
+		return kind
+	}
+
+	if sd != nil && sd.Kind == KindResource {
+		sd.parsed()
 		// Check if CanonicalResource (has .url element)
 		if r.hasElementUnlocked(sd, typeName+".url") {
-			r.canonicalResources[typeName] = true
+			kind = canonicalResource
 
 			// Check if MetadataResource (canonical + name/status/experimental)
 			if r.hasRequiredElementUnlocked(sd, typeName+".status") &&
 				r.hasElementUnlocked(sd, typeName+".name") &&
 				r.hasElementUnlocked(sd, typeName+".experimental") {
-				r.metadataResources[typeName] = true
+				kind = metadataResource
 			}
 		}
 	}
+
+	r.mu.Lock()
+	r.canonicalKinds[typeName] = kind
+	r.mu.Unlock()
+	return kind
 }
 
 // mergeExtensionContexts adds unique contexts from newSD to existingSD.
@@ -333,18 +467,98 @@ func (r *Registry) mergeExtensionContexts(existing, newSD *StructureDefinition)
 	}
 }
 
-// GetByURL returns a StructureDefinition by its canonical URL.
+// SetCanonicalMapping rewrites canonical URL prefixes during lookups, so that
+// references to an internal mirror of a package (mirror base URL -> published
+// base URL) resolve to the loaded definitions. A URL that is loaded as is
+// takes precedence. It must be set before the Registry is used concurrently.
+func (r *Registry) SetCanonicalMapping(mapping map[string]string) {
+	r.mapping = mapping
+}
+
+// GetByURL returns a StructureDefinition by its canonical URL. A versioned
+// reference ("url|6.1.0", or "url|6.1" for the highest 6.1.x) returns that
+// version, or nil if it is not loaded; an unversioned one returns the default
+// definition.
 func (r *Registry) GetByURL(url string) *StructureDefinition {
+	if sd := r.getByURL(url); sd != nil || len(r.mapping) == 0 {
+		return sd
+	}
+	if mapped, ok := loader.RewriteCanonical(url, r.mapping); ok {
+		return r.getByURL(mapped)
+	}
+	return nil
+}
+
+// getByURL is GetByURL without the canonical mapping.
+func (r *Registry) getByURL(url string) *StructureDefinition {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.byURL[url]
+	sd, ok := r.byURL[url]
+	r.mu.RUnlock()
+	if ok {
+		return sd.parsed()
+	}
+
+	base, version := loader.SplitCanonical(url)
+	if version == "" {
+		return nil
+	}
+	r.mu.RLock()
+	sd = r.byURL[base]
+	r.mu.RUnlock()
+	if sd != nil && sd.Version == version {
+		return sd.parsed()
+	}
+	return r.versions.Get(base, version).parsed()
+}
+
+// Versions returns the loaded versions of a StructureDefinition URL, lowest first.
+func (r *Registry) Versions(url string) []string {
+	return r.versions.Versions(url)
+}
+
+// IsAmbiguous reports whether several versions of the same package define
+// url with different versions, so that an unversioned reference resolves
+// according to the version policy.
+func (r *Registry) IsAmbiguous(url string) bool {
+	return r.versions.Ambiguous(url)
+}
+
+// AmbiguousCount returns the number of ambiguous URLs (see IsAmbiguous).
+func (r *Registry) AmbiguousCount() int {
+	return r.versions.AmbiguousCount()
 }
 
 // GetByType returns a StructureDefinition for a type name (e.g., "Patient", "HumanName").
 func (r *Registry) GetByType(typeName string) *StructureDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.byType[typeName]
+	return r.byType[typeName].parsed()
+}
+
+// GetCustomType returns the StructureDefinition of a logical model or custom
+// resource whose instances carry the given resourceType: a non-abstract
+// specialization of kind "logical" or "resource" whose type, or whose root
+// element path, equals the name. Logical models often declare their type as a
+// canonical URL while their element paths start with the model name.
+// Returns nil if no such definition is loaded.
+func (r *Registry) GetCustomType(name string) *StructureDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	isCustom := func(sd *StructureDefinition) bool {
+		return sd != nil && !sd.Abstract && sd.Derivation != "constraint" &&
+			(sd.Kind == KindLogical || sd.Kind == KindResource)
+	}
+
+	if sd := r.byType[name]; isCustom(sd) {
+		return sd.parsed()
+	}
+	for _, sd := range r.byURL {
+		if isCustom(sd) && sd.parsed().Snapshot != nil && len(sd.Snapshot.Element) > 0 && sd.Snapshot.Element[0].Path == name {
+			return sd
+		}
+	}
+	return nil
 }
 
 // GetElementDefinition returns the ElementDefinition for a given path.
@@ -483,25 +697,22 @@ func (r *Registry) IsDomainResource(typeName string) bool {
 // CanonicalResources have globally unique identifiers and can be referenced by URL.
 // Note: In R4, url is optional in most canonical resources; only StructureDefinition requires it.
 // Examples: StructureDefinition, ValueSet, CodeSystem, CapabilityStatement, etc.
-// Uses pre-computed cache for O(1) lookups.
+// Cached per type after the first query.
 func (r *Registry) IsCanonicalResource(typeName string) bool {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.canonicalResources[typeName]
+	return r.canonicalKindOf(typeName) != notCanonical
 }
 
 // IsMetadataResource checks if the given type is a MetadataResource.
 // Derived from StructureDefinition: is CanonicalResource + has name, status, experimental.
 // MetadataResources are publishable conformance resources.
 // Examples: StructureDefinition, ValueSet, CodeSystem, SearchParameter, etc.
-// Uses pre-computed cache for O(1) lookups.
+// Cached per type after the first query.
 func (r *Registry) IsMetadataResource(typeName string) bool {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.metadataResources[typeName]
+	return r.canonicalKindOf(typeName) == metadataResource
 }
 
-// Unlocked versions for use inside buildTypeClassificationCaches (called while lock is held).
+// Unlocked versions for use inside buildTypeClassificationCaches (called while
+// lock is held) and canonicalKindOf.
 
 // inheritsFromUnlocked checks inheritance without acquiring locks.
 // Used during cache building when the lock is already held.
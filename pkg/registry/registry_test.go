@@ -1,6 +1,8 @@
 package registry
 
 import (
+	"encoding/json"
+	"slices"
 	"testing"
 
 	"github.com/gofhir/validator/pkg/loader"
@@ -70,6 +72,94 @@ func TestRegistryGetByURL(t *testing.T) {
 	t.Logf("Patient SD: Kind=%s, Type=%s, Base=%s", sd.Kind, sd.Type, sd.BaseDefinition)
 }
 
+// versionedPackage returns a package version defining the profile url with
+// the package version as its version.
+func versionedPackage(name, version, url string) *loader.Package {
+	sd := json.RawMessage(`{"resourceType": "StructureDefinition", "url": "` + url + `",
+		"version": "` + version + `", "type": "Patient", "derivation": "constraint"}`)
+	return &loader.Package{Name: name, Version: version, Resources: map[string]json.RawMessage{url: sd}}
+}
+
+func TestRegistryVersions(t *testing.T) {
+	const url = "http://example.org/StructureDefinition/patient"
+	packages := []*loader.Package{
+		versionedPackage("example.ig", "3.1.1", url),
+		versionedPackage("example.ig", "6.1.0", url),
+		versionedPackage("other.ig", "1.0.0", url),
+	}
+
+	tests := []struct {
+		name   string
+		policy loader.VersionPolicy
+		want   string
+	}{
+		{"latest", loader.VersionLatest, "6.1.0"},
+		{"first", loader.VersionFirstLoaded, "3.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New()
+			r.SetVersionPolicy(tt.policy)
+			if err := r.LoadFromPackages(packages); err != nil {
+				t.Fatalf("LoadFromPackages failed: %v", err)
+			}
+			if got := r.GetByURL(url).Version; got != tt.want {
+				t.Errorf("GetByURL() version = %q, want %q", got, tt.want)
+			}
+			for _, version := range []string{"3.1.1", "6.1.0", "1.0.0"} {
+				if sd := r.GetByURL(url + "|" + version); sd == nil || sd.Version != version {
+					t.Errorf("GetByURL(|%s) = %v", version, sd)
+				}
+			}
+			if sd := r.GetByURL(url + "|6.1"); sd == nil || sd.Version != "6.1.0" {
+				t.Errorf("GetByURL(|6.1) = %v, want 6.1.0", sd)
+			}
+			if sd := r.GetByURL(url + "|5.0.0"); sd != nil {
+				t.Errorf("GetByURL(|5.0.0) = %v, want nil", sd)
+			}
+			if !r.IsAmbiguous(url) {
+				t.Error("IsAmbiguous() = false, want true")
+			}
+			if got := r.Versions(url); !slices.Equal(got, []string{"1.0.0", "3.1.1", "6.1.0"}) {
+				t.Errorf("Versions() = %v", got)
+			}
+		})
+	}
+
+	// Different packages defining a URL are not ambiguous; the first one wins
+	r := New()
+	if err := r.LoadFromPackages(packages[1:]); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+	if r.IsAmbiguous(url) || r.GetByURL(url).Version != "6.1.0" {
+		t.Errorf("other packages: ambiguous = %v, version = %q", r.IsAmbiguous(url), r.GetByURL(url).Version)
+	}
+}
+
+func TestRegistryCanonicalMapping(t *testing.T) {
+	const url = "http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient"
+	r := New()
+	if err := r.LoadFromPackages([]*loader.Package{versionedPackage("hl7.fhir.us.core", "6.1.0", url)}); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+	r.SetCanonicalMapping(map[string]string{
+		"https://internal.example.org/":         "http://example.org/",
+		"https://internal.example.org/fhir/us/": "http://hl7.org/fhir/us/",
+	})
+
+	for _, ref := range []string{
+		"https://internal.example.org/fhir/us/core/StructureDefinition/us-core-patient",
+		"https://internal.example.org/fhir/us/core/StructureDefinition/us-core-patient|6.1.0",
+	} {
+		if sd := r.GetByURL(ref); sd == nil || sd.URL != url {
+			t.Errorf("GetByURL(%q) = %v, want %s", ref, sd, url)
+		}
+	}
+	if sd := r.GetByURL("https://internal.example.org/StructureDefinition/other"); sd != nil {
+		t.Errorf("GetByURL(unmapped) = %v, want nil", sd)
+	}
+}
+
 func TestRegistryGetByType(t *testing.T) {
 	l := loader.NewLoader("")
 	packages, err := l.LoadVersion("4.0.1")
@@ -423,3 +513,75 @@ func TestRegistryIsMetadataResource(t *testing.T) {
 		t.Error("IsMetadataResource(Patient) = true, want false")
 	}
 }
+
+func TestRegistryQueries(t *testing.T) {
+	const url = "http://example.org/StructureDefinition/patient"
+	r := New()
+	if err := r.LoadFromPackages([]*loader.Package{
+		versionedPackage("example.ig", "3.1.1", url),
+		versionedPackage("example.ig", "6.1.0", url),
+		versionedPackage("other.ig", "1.0.0", "http://example.org/StructureDefinition/another-patient"),
+	}); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+
+	profiles := r.ProfilesForType("Patient")
+	if len(profiles) != 2 || profiles[0].URL != "http://example.org/StructureDefinition/another-patient" || profiles[1].Version != "6.1.0" {
+		t.Errorf("ProfilesForType(Patient) = %v", profiles)
+	}
+	if profiles := r.ProfilesForType("Observation"); len(profiles) != 0 {
+		t.Errorf("ProfilesForType(Observation) = %v, want none", profiles)
+	}
+
+	for ref, want := range map[string]string{
+		url:            "example.ig#6.1.0",
+		url + "|3.1.1": "example.ig#3.1.1",
+		"http://example.org/StructureDefinition/another-patient": "other.ig#1.0.0",
+	} {
+		if src, ok := r.Source(ref); !ok || src.String() != want {
+			t.Errorf("Source(%q) = %v, %v; want %s", ref, src, ok, want)
+		}
+	}
+	if _, ok := r.Source("http://example.org/StructureDefinition/missing"); ok {
+		t.Error("Source(missing) should report not found")
+	}
+	if elements := r.Snapshot(url); elements != nil {
+		t.Errorf("Snapshot() of a definition without snapshot = %v, want nil", elements)
+	}
+}
+
+func TestRegistryExtensionsForContext(t *testing.T) {
+	l := loader.NewLoader("")
+	packages, err := l.LoadVersion("4.0.1")
+	if err != nil {
+		t.Skipf("Cannot load FHIR packages: %v", err)
+	}
+	r := New()
+	if err := r.LoadFromPackages(packages); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+
+	has := func(extensions []*StructureDefinition, url string) bool {
+		return slices.ContainsFunc(extensions, func(sd *StructureDefinition) bool { return sd.URL == url })
+	}
+	const (
+		birthPlace = "http://hl7.org/fhir/StructureDefinition/patient-birthPlace"
+		ownPrefix  = "http://hl7.org/fhir/StructureDefinition/humanname-own-prefix"
+	)
+
+	patient := r.ExtensionsForContext("Patient")
+	if !has(patient, birthPlace) || has(patient, ownPrefix) {
+		t.Errorf("ExtensionsForContext(Patient): birthPlace = %v, own-prefix = %v", has(patient, birthPlace), has(patient, ownPrefix))
+	}
+	family := r.ExtensionsForContext("Patient.name[0].family")
+	if !has(family, ownPrefix) || has(family, birthPlace) {
+		t.Errorf("ExtensionsForContext(Patient.name.family): birthPlace = %v, own-prefix = %v", has(family, birthPlace), has(family, ownPrefix))
+	}
+
+	if elements := r.Snapshot("http://hl7.org/fhir/StructureDefinition/Patient"); len(elements) == 0 || elements[0].Path != "Patient" {
+		t.Errorf("Snapshot(Patient) has %d elements", len(elements))
+	}
+	if src, ok := r.Source("http://hl7.org/fhir/StructureDefinition/Patient"); !ok || src.Name != "hl7.fhir.r4.core" {
+		t.Errorf("Source(Patient) = %v, %v", src, ok)
+	}
+}
@@ -0,0 +1,202 @@
+// Package report renders validation results as a self-contained HTML page
+// for sharing with people who do not read OperationOutcomes: a summary per
+// file, then collapsible issue lists grouped by severity and element path.
+package report
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+//go:embed report.html.tmpl
+var templateFS embed.FS
+
+var pageTemplate = template.Must(template.New("report.html.tmpl").Funcs(template.FuncMap{
+	"duration": formatDuration,
+}).ParseFS(templateFS, "report.html.tmpl"))
+
+// noPath groups issues that carry no FHIRPath expression.
+const noPath = "(resource)"
+
+// File is the outcome of validating one input.
+type File struct {
+	Name     string
+	Result   *issue.Result // Nil when the input could not be validated
+	Err      error         // Why the input could not be validated
+	Duration time.Duration
+}
+
+// Options configures the report header.
+type Options struct {
+	Title       string    // Page title (default "FHIR Validation Report")
+	FHIRVersion string    // FHIR version the files were validated against
+	Generated   time.Time // Report time (default now)
+	Quiet       bool      // Omit information-level issues
+}
+
+// page is the data passed to the HTML template.
+type page struct {
+	Title       string
+	FHIRVersion string
+	Generated   string
+	Total       counts
+	Duration    time.Duration
+	Files       []fileView
+}
+
+type counts struct {
+	Files, Valid, Invalid   int
+	Fatal, Errors, Warnings int
+	Information             int
+}
+
+type fileView struct {
+	Name       string
+	Valid      bool
+	Err        string
+	Profiles   []string
+	Resource   string
+	Duration   time.Duration
+	Counts     counts
+	Severities []severityGroup
+}
+
+type severityGroup struct {
+	Severity issue.Severity
+	Count    int
+	Paths    []pathGroup
+}
+
+type pathGroup struct {
+	Path   string
+	Issues []issue.Issue
+}
+
+// severities lists the report sections in order.
+var severities = []issue.Severity{
+	issue.SeverityFatal,
+	issue.SeverityError,
+	issue.SeverityWarning,
+	issue.SeverityInformation,
+}
+
+// WriteHTML writes a self-contained HTML report (inline CSS, no scripts or
+// external resources) for the given files.
+func WriteHTML(w io.Writer, files []File, opts Options) error {
+	if opts.Title == "" {
+		opts.Title = "FHIR Validation Report"
+	}
+	if opts.Generated.IsZero() {
+		opts.Generated = time.Now()
+	}
+
+	p := page{
+		Title:       opts.Title,
+		FHIRVersion: opts.FHIRVersion,
+		Generated:   opts.Generated.Format(time.RFC1123),
+	}
+	for _, f := range files {
+		view := newFileView(f, opts.Quiet)
+		p.Files = append(p.Files, view)
+		p.Duration += f.Duration
+		p.Total.add(view.Counts)
+	}
+	return pageTemplate.Execute(w, p)
+}
+
+// newFileView groups a file's issues for display.
+func newFileView(f File, quiet bool) fileView {
+	view := fileView{Name: f.Name, Duration: f.Duration, Counts: counts{Files: 1}}
+	if f.Err != nil {
+		view.Err = f.Err.Error()
+	}
+	if f.Result == nil {
+		view.Counts.Invalid = 1
+		return view
+	}
+
+	if s := f.Result.Stats; s != nil {
+		view.Resource = s.ResourceType
+		if len(s.AppliedProfiles) > 0 {
+			view.Profiles = s.AppliedProfiles
+		} else if s.ProfileURL != "" {
+			view.Profiles = []string{s.ProfileURL}
+		}
+	}
+
+	// Paths keep the order of their first issue, which follows the resource
+	// for normalized results (see issue.Result.Normalize)
+	groups := make(map[issue.Severity]*severityGroup)
+	pathIndex := make(map[issue.Severity]map[string]int)
+	for _, iss := range f.Result.Issues {
+		switch iss.Severity {
+		case issue.SeverityFatal:
+			view.Counts.Fatal++
+		case issue.SeverityError:
+			view.Counts.Errors++
+		case issue.SeverityWarning:
+			view.Counts.Warnings++
+		case issue.SeverityInformation:
+			view.Counts.Information++
+			if quiet {
+				continue
+			}
+		}
+
+		group := groups[iss.Severity]
+		if group == nil {
+			group = &severityGroup{Severity: iss.Severity}
+			groups[iss.Severity] = group
+			pathIndex[iss.Severity] = make(map[string]int)
+		}
+		path := noPath
+		if len(iss.Expression) > 0 {
+			path = iss.Expression[0]
+		}
+		i, ok := pathIndex[iss.Severity][path]
+		if !ok {
+			i = len(group.Paths)
+			pathIndex[iss.Severity][path] = i
+			group.Paths = append(group.Paths, pathGroup{Path: path})
+		}
+		group.Paths[i].Issues = append(group.Paths[i].Issues, iss)
+		group.Count++
+	}
+
+	for _, sev := range severities {
+		if group := groups[sev]; group != nil {
+			view.Severities = append(view.Severities, *group)
+		}
+	}
+
+	view.Valid = view.Err == "" && view.Counts.Fatal == 0 && view.Counts.Errors == 0
+	if view.Valid {
+		view.Counts.Valid = 1
+	} else {
+		view.Counts.Invalid = 1
+	}
+	return view
+}
+
+// add accumulates another file's counts.
+func (c *counts) add(o counts) {
+	c.Files += o.Files
+	c.Valid += o.Valid
+	c.Invalid += o.Invalid
+	c.Fatal += o.Fatal
+	c.Errors += o.Errors
+	c.Warnings += o.Warnings
+	c.Information += o.Information
+}
+
+// formatDuration rounds durations for display.
+func formatDuration(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestWriteHTML(t *testing.T) {
+	invalid := issue.NewResult()
+	invalid.Stats = &issue.Stats{ResourceType: "Patient", ProfileURL: "http://hl7.org/fhir/StructureDefinition/Patient"}
+	invalid.AddError(issue.CodeStructure, "Unknown element '<script>'", "Patient.foo")
+	invalid.AddError(issue.CodeValue, "Second problem", "Patient.foo")
+	invalid.AddWarning(issue.CodeInvariant, "Constraint failed: dom-6")
+	invalid.AddInfo(issue.CodeInformational, "Informational note", "Patient.name[0]")
+
+	valid := issue.NewResult()
+	valid.Stats = &issue.Stats{ResourceType: "Observation"}
+
+	files := []File{
+		{Name: "bad.json", Result: invalid, Duration: 1500 * time.Microsecond},
+		{Name: "good.json", Result: valid, Duration: time.Millisecond},
+		{Name: "missing.json", Err: errors.New("failed to read file")},
+	}
+
+	var buf bytes.Buffer
+	err := WriteHTML(&buf, files, Options{
+		FHIRVersion: "4.0.1",
+		Generated:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("WriteHTML() error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"<title>FHIR Validation Report</title>",
+		"FHIR 4.0.1",
+		"3 file(s)",
+		"1 valid, 2 invalid",
+		"http://hl7.org/fhir/StructureDefinition/Patient",
+		"<summary>error (2)</summary>",
+		"<summary>Patient.foo (2)</summary>",
+		"<summary>(resource) (1)</summary>",
+		"Informational note",
+		"Unknown element &#39;&lt;script&gt;&#39;",
+		"failed to read file",
+		"No issues.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+	if strings.Contains(out, "<script") || strings.Contains(out, "http-equiv") || strings.Contains(out, "<link") {
+		t.Error("report should be self-contained, without scripts or external resources")
+	}
+
+	buf.Reset()
+	if err := WriteHTML(&buf, files[:1], Options{Quiet: true}); err != nil {
+		t.Fatalf("WriteHTML() error: %v", err)
+	}
+	if strings.Contains(buf.String(), "Informational note") {
+		t.Error("quiet report should omit information-level issues")
+	}
+}
@@ -0,0 +1,255 @@
+// Package reportstore records validation runs in a SQL database, so that data
+// quality can be trended over time: one row per run, one per validated
+// resource and one per issue. It works with any database/sql driver for
+// SQLite or PostgreSQL; the validator does not depend on one.
+//
+//	db, _ := sql.Open("sqlite", "reports.db") // e.g., modernc.org/sqlite
+//	store, err := reportstore.Open(ctx, db, reportstore.SQLite)
+//	run, err := store.StartRun(ctx, reportstore.RunInfo{Label: "nightly"})
+//	err = pool.RunTo(ctx, jobs, run)
+//	err = run.Finish(ctx)
+package reportstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// Dialect is the SQL dialect of a database.
+type Dialect int
+
+// Supported dialects.
+const (
+	SQLite   Dialect = iota // "?" placeholders
+	Postgres                // "$1" placeholders
+)
+
+// String returns the dialect name.
+func (d Dialect) String() string {
+	if d == Postgres {
+		return "postgres"
+	}
+	return "sqlite"
+}
+
+// schema creates the tables and indexes; {{bool}}, {{real}} and {{time}}
+// are replaced by the dialect's column types.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS validation_runs (
+		run_id TEXT PRIMARY KEY,
+		label TEXT,
+		fhir_version TEXT,
+		started_at {{time}} NOT NULL,
+		finished_at {{time}},
+		resources INTEGER NOT NULL DEFAULT 0,
+		valid INTEGER NOT NULL DEFAULT 0,
+		errors INTEGER NOT NULL DEFAULT 0,
+		warnings INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS validation_results (
+		run_id TEXT NOT NULL REFERENCES validation_runs (run_id),
+		job TEXT NOT NULL,
+		resource_type TEXT,
+		resource_id TEXT,
+		profile TEXT,
+		valid {{bool}} NOT NULL,
+		errors INTEGER NOT NULL,
+		warnings INTEGER NOT NULL,
+		info INTEGER NOT NULL,
+		duration_ms {{real}},
+		elements INTEGER,
+		must_support_total INTEGER,
+		must_support_covered INTEGER,
+		terminology_total INTEGER,
+		terminology_covered INTEGER,
+		score {{real}},
+		PRIMARY KEY (run_id, job)
+	)`,
+	`CREATE TABLE IF NOT EXISTS validation_issues (
+		run_id TEXT NOT NULL,
+		job TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		code TEXT NOT NULL,
+		diagnostic_id TEXT,
+		path TEXT,
+		phase TEXT,
+		profile TEXT,
+		diagnostics TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS validation_results_type ON validation_results (resource_type, run_id)`,
+	`CREATE INDEX IF NOT EXISTS validation_issues_run ON validation_issues (run_id, job)`,
+	`CREATE INDEX IF NOT EXISTS validation_issues_diagnostic ON validation_issues (diagnostic_id, run_id)`,
+}
+
+// columnTypes are the column types of each dialect.
+var columnTypes = map[Dialect]*strings.Replacer{
+	SQLite:   strings.NewReplacer("{{bool}}", "INTEGER", "{{real}}", "REAL", "{{time}}", "TEXT"),
+	Postgres: strings.NewReplacer("{{bool}}", "BOOLEAN", "{{real}}", "DOUBLE PRECISION", "{{time}}", "TIMESTAMPTZ"),
+}
+
+// Store records validation runs in a database.
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// Open returns a Store writing to db, creating its tables if needed.
+func Open(ctx context.Context, db *sql.DB, dialect Dialect) (*Store, error) {
+	types, ok := columnTypes[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unknown dialect %d", dialect)
+	}
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, types.Replace(stmt)); err != nil {
+			return nil, fmt.Errorf("create report tables: %w", err)
+		}
+	}
+	return &Store{db: db, dialect: dialect}, nil
+}
+
+// RunInfo describes a validation run.
+type RunInfo struct {
+	ID          string    // Unique run identifier (default: the start time)
+	Label       string    // Free-form name, e.g., the dataset or pipeline
+	FHIRVersion string    // FHIR version validated against
+	Started     time.Time // Default: now
+}
+
+// Run is a validation run being recorded. It implements worker.ResultSink;
+// like the other sinks, it is not safe for concurrent use.
+type Run struct {
+	store *Store
+	id    string
+	ctx   context.Context
+
+	resources, valid, errors, warnings int
+}
+
+// StartRun records the start of a run and returns it, to write its results
+// to. ctx also bounds the writes of the run's results.
+func (s *Store) StartRun(ctx context.Context, info RunInfo) (*Run, error) {
+	if info.Started.IsZero() {
+		info.Started = time.Now()
+	}
+	info.Started = info.Started.UTC()
+	if info.ID == "" {
+		info.ID = info.Started.Format("20060102T150405.000000000Z")
+	}
+	if _, err := s.db.ExecContext(ctx, s.bind(`INSERT INTO validation_runs
+		(run_id, label, fhir_version, started_at) VALUES (?, ?, ?, ?)`),
+		info.ID, info.Label, info.FHIRVersion, s.timestamp(info.Started)); err != nil {
+		return nil, fmt.Errorf("start run: %w", err)
+	}
+	return &Run{store: s, id: info.ID, ctx: ctx}, nil
+}
+
+// ID returns the run identifier.
+func (r *Run) ID() string {
+	return r.id
+}
+
+// WriteResult records the result of one job, with its issues, in one
+// transaction. It implements worker.ResultSink.
+func (r *Run) WriteResult(jobID string, result *issue.Result) (err error) {
+	s := r.store
+	tx, err := s.db.BeginTx(r.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	st := result.Stats
+	if st == nil {
+		st = &issue.Stats{}
+	}
+	var score any
+	if st.Scored {
+		score = st.Score
+	}
+	valid, errors, warnings := !result.HasErrors(), result.ErrorCount(), result.WarningCount()
+	if _, err = tx.ExecContext(r.ctx, s.bind(`INSERT INTO validation_results
+		(run_id, job, resource_type, resource_id, profile, valid, errors, warnings, info, duration_ms,
+		elements, must_support_total, must_support_covered, terminology_total, terminology_covered, score)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		r.id, jobID, st.ResourceType, st.ResourceID, st.ProfileURL, valid, errors, warnings, result.InfoCount(),
+		st.DurationMs(), st.ElementsChecked, st.MustSupport.Total, st.MustSupport.Covered,
+		st.Terminology.Total, st.Terminology.Covered, score); err != nil {
+		return err
+	}
+
+	insertIssue := s.bind(`INSERT INTO validation_issues
+		(run_id, job, severity, code, diagnostic_id, path, phase, profile, diagnostics)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	for _, iss := range result.Issues {
+		var path, phase string
+		if len(iss.Expression) > 0 {
+			path = iss.Expression[0]
+		}
+		if iss.Source != nil {
+			phase = iss.Source.Phase
+		}
+		if _, err = tx.ExecContext(r.ctx, insertIssue, r.id, jobID, string(iss.Severity), string(iss.Code),
+			iss.MessageID, path, phase, iss.Profile, iss.Diagnostics); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	r.resources++
+	if valid {
+		r.valid++
+	}
+	r.errors += errors
+	r.warnings += warnings
+	return nil
+}
+
+// Finish records the end of the run and its totals.
+func (r *Run) Finish(ctx context.Context) error {
+	s := r.store
+	if _, err := s.db.ExecContext(ctx, s.bind(`UPDATE validation_runs
+		SET finished_at = ?, resources = ?, valid = ?, errors = ?, warnings = ? WHERE run_id = ?`),
+		s.timestamp(time.Now().UTC()), r.resources, r.valid, r.errors, r.warnings, r.id); err != nil {
+		return fmt.Errorf("finish run: %w", err)
+	}
+	return nil
+}
+
+// bind rewrites the "?" placeholders of query for the dialect.
+func (s *Store) bind(query string) string {
+	if s.dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// timestamp returns t as the dialect stores it: RFC 3339 text in SQLite,
+// whose drivers disagree on time.Time, and a time.Time for PostgreSQL.
+func (s *Store) timestamp(t time.Time) any {
+	if s.dialect == Postgres {
+		return t
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,90 @@
+package reportstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db, recorder := testutil.OpenSQLRecorder(t)
+	store, err := Open(ctx, db, SQLite)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	schema := recorder.Take()
+	if len(schema) != 6 || !strings.Contains(schema[1], "valid INTEGER NOT NULL") || !strings.Contains(schema[0], "started_at TEXT") {
+		t.Fatalf("schema = %q", schema)
+	}
+
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	run, err := store.StartRun(ctx, RunInfo{Label: "nightly", FHIRVersion: "4.0.1", Started: started})
+	if err != nil {
+		t.Fatalf("StartRun() error: %v", err)
+	}
+	if run.ID() != "20240301T120000.000000000Z" {
+		t.Errorf("ID() = %q", run.ID())
+	}
+
+	result := issue.NewResult()
+	result.Stats = &issue.Stats{
+		ResourceType: "Patient", ResourceID: "p1", ProfileURL: "http://example.org/p",
+		Duration: int64(2 * time.Millisecond), ElementsChecked: 7,
+		MustSupport: issue.Coverage{Total: 4, Covered: 3}, Terminology: issue.Coverage{Total: 1, Covered: 1},
+	}
+	result.AddIssue(issue.Issue{Severity: issue.SeverityError, Code: issue.CodeValue, Diagnostics: "bad gender",
+		Expression: []string{"Patient.gender"}, MessageID: "BINDING_REQUIRED", Source: &issue.Source{Phase: "binding"}})
+	if err := run.WriteResult("a.json", result); err != nil {
+		t.Fatalf("WriteResult() error: %v", err)
+	}
+	if err := run.WriteResult("b.json", issue.NewResult()); err != nil {
+		t.Fatalf("WriteResult() error: %v", err)
+	}
+	if err := run.Finish(ctx); err != nil {
+		t.Fatalf("Finish() error: %v", err)
+	}
+
+	execs := recorder.Take()
+	if len(execs) != 5 {
+		t.Fatalf("statements:\n%s", strings.Join(execs, "\n"))
+	}
+	want := []string{
+		`["20240301T120000.000000000Z","nightly","4.0.1","2024-03-01T12:00:00Z"]`,
+		`["20240301T120000.000000000Z","a.json","Patient","p1","http://example.org/p",false,1,0,0,2,7,4,3,1,1,null]`,
+		`["20240301T120000.000000000Z","a.json","error","value","BINDING_REQUIRED","Patient.gender","binding","","bad gender"]`,
+		`["20240301T120000.000000000Z","b.json","","","",true,0,0,0,0,0,0,0,0,0,null]`,
+	}
+	for i, args := range want {
+		if !strings.HasSuffix(execs[i], " "+args) {
+			t.Errorf("statement %d = %s\nwant arguments %s", i, execs[i], args)
+		}
+	}
+	if !strings.HasPrefix(execs[4], "UPDATE validation_runs") || !strings.HasSuffix(execs[4], `,2,1,1,0,"20240301T120000.000000000Z"]`) {
+		t.Errorf("finish = %s", execs[4])
+	}
+}
+
+func TestPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, recorder := testutil.OpenSQLRecorder(t)
+	store, err := Open(ctx, db, Postgres)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	schema := recorder.Take()
+	if !strings.Contains(schema[1], "valid BOOLEAN NOT NULL") || !strings.Contains(schema[0], "started_at TIMESTAMPTZ") {
+		t.Errorf("schema = %q", schema)
+	}
+
+	if _, err := store.StartRun(ctx, RunInfo{ID: "r1"}); err != nil {
+		t.Fatalf("StartRun() error: %v", err)
+	}
+	if execs := recorder.Take(); len(execs) != 1 || !strings.Contains(execs[0], "VALUES ($1, $2, $3, $4)") {
+		t.Errorf("insert = %q, want $n placeholders", execs)
+	}
+}
@@ -0,0 +1,345 @@
+// Package rules evaluates business rules loaded at runtime: co-occurrence
+// constraints that implementation guides state in prose rather than as
+// invariants, such as "subscriberId is required unless the beneficiary is
+// the subscriber".
+//
+// Rules are written in a small line-oriented language. A rule starts with
+// its id and resource type and is followed by its clauses, one per line:
+//
+//	# Coverage.subscriberId is required when relationship != self
+//	rule cov-subscriber on Coverage
+//	  when relationship.coding.where(code = 'self').empty()
+//	  require subscriberId
+//	  message subscriberId is required unless the beneficiary is the subscriber
+//
+//	rule pat-deceased on Patient
+//	  when deceasedBoolean = true
+//	  forbid deceasedDateTime
+//	  severity warning
+//
+// Clauses are FHIRPath expressions evaluated on the resource:
+//
+//   - when: the rule applies only if the expression is true (all when
+//     clauses must be; an empty result is false)
+//   - require: the expression must return something
+//   - forbid: the expression must return nothing
+//   - assert: the expression must not be false (an empty result passes,
+//     as for invariants)
+//
+// A rule needs at least one require, forbid or assert clause; each failing
+// one is reported. severity is error (the default), warning or information,
+// and message replaces the generated message. Blank lines and lines starting
+// with # are ignored.
+package rules
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/fhirpath"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// clauses are the keywords of the lines of a rule.
+var clauses = []string{"when", "require", "forbid", "assert", "severity", "message"}
+
+// identifierRegex matches rule ids and resource types.
+var identifierRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// simplePathRegex matches expressions that are element paths (e.g.,
+// "subscriber.reference"), which are reported at that path.
+var simplePathRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(\.[A-Za-z][A-Za-z0-9]*)*$`)
+
+// Rule is a business rule.
+type Rule struct {
+	ID           string
+	ResourceType string
+	Severity     issue.Severity
+	Message      string // Empty = generated from the clauses
+
+	when   []clause
+	checks []clause
+}
+
+// clause is a compiled FHIRPath clause of a rule.
+type clause struct {
+	keyword string // when, require, forbid or assert
+	expr    string
+	program *fhirpath.Expression
+}
+
+// Set is a parsed set of business rules. It is safe for concurrent use.
+type Set struct {
+	rules  []*Rule
+	byType map[string][]*Rule
+}
+
+// Parse reads the rules of one or more sources into a Set. Errors give the
+// line of the problem, and the file name of sources that are *os.File (or
+// have a Name method). Rule ids must be unique across sources.
+func Parse(sources ...io.Reader) (*Set, error) {
+	s := &Set{byType: map[string][]*Rule{}}
+	seen := map[string]bool{}
+	for i, src := range sources {
+		name := fmt.Sprintf("rules %d", i+1)
+		if named, ok := src.(interface{ Name() string }); ok {
+			name = named.Name()
+		}
+		rules, err := parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, r := range rules {
+			if seen[r.ID] {
+				return nil, fmt.Errorf("%s: duplicate rule id %q", name, r.ID)
+			}
+			seen[r.ID] = true
+			s.rules = append(s.rules, r)
+			s.byType[r.ResourceType] = append(s.byType[r.ResourceType], r)
+		}
+	}
+	return s, nil
+}
+
+// parse reads the rules of a source.
+func parse(src io.Reader) ([]*Rule, error) {
+	var rules []*Rule
+	var current *Rule
+	var start int // Line of the current rule
+	finish := func() error {
+		if current != nil && len(current.checks) == 0 {
+			return fmt.Errorf("line %d: rule %q has no require, forbid or assert clause", start, current.ID)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(src)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(text, " ")
+		rest = strings.TrimSpace(rest)
+
+		if keyword == "rule" {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			fields := strings.Fields(rest)
+			if len(fields) != 3 || fields[1] != "on" {
+				return nil, fmt.Errorf("line %d: expected \"rule <id> on <ResourceType>\"", line)
+			}
+			if !identifierRegex.MatchString(fields[0]) || !identifierRegex.MatchString(fields[2]) {
+				return nil, fmt.Errorf("line %d: invalid rule id or resource type in %q", line, text)
+			}
+			current = &Rule{ID: fields[0], ResourceType: fields[2], Severity: issue.SeverityError}
+			start = line
+			rules = append(rules, current)
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %q before the first rule", line, keyword)
+		}
+		if !slices.Contains(clauses, keyword) {
+			return nil, fmt.Errorf("line %d: unknown clause %q", line, keyword)
+		}
+		if rest == "" {
+			return nil, fmt.Errorf("line %d: %s needs a value", line, keyword)
+		}
+
+		switch keyword {
+		case "when", "require", "forbid", "assert":
+			program, err := fhirpath.Compile(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid expression %q: %w", line, rest, err)
+			}
+			c := clause{keyword: keyword, expr: rest, program: program}
+			if keyword == "when" {
+				current.when = append(current.when, c)
+			} else {
+				current.checks = append(current.checks, c)
+			}
+		case "severity":
+			switch severity := issue.Severity(rest); severity {
+			case issue.SeverityError, issue.SeverityWarning, issue.SeverityInformation:
+				current.Severity = severity
+			default:
+				return nil, fmt.Errorf("line %d: invalid severity %q (expected error, warning or information)", line, rest)
+			}
+		case "message":
+			current.Message = rest
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Rules returns the rules of the set, in the order they were read.
+func (s *Set) Rules() []*Rule {
+	return append([]*Rule(nil), s.rules...)
+}
+
+// Validator applies a Set of business rules.
+type Validator struct {
+	set    *Set
+	walker *walker.Walker
+}
+
+// New creates a Validator applying the rules of set.
+func New(reg *registry.Registry, set *Set) *Validator {
+	return &Validator{set: set, walker: walker.New(reg)}
+}
+
+// ValidateData applies the rules to a pre-parsed resource, including
+// contained resources and Bundle entries. Evaluation stops when ctx is
+// canceled.
+func (v *Validator) ValidateData(ctx context.Context, resource map[string]any, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
+		rules := v.set.byType[rc.ResourceType]
+		if len(rules) == 0 {
+			return ctx.Err() == nil
+		}
+		data, err := json.Marshal(rc.Data)
+		if err != nil {
+			return true
+		}
+		for _, r := range rules {
+			if !r.apply(ctx, data, rc.FHIRPath, result) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// apply evaluates the rule on a resource and reports its failing clauses.
+// It returns false when ctx is done.
+func (r *Rule) apply(ctx context.Context, data json.RawMessage, fhirPath string, result *issue.Result) bool {
+	for _, c := range r.when {
+		values, ok := r.evaluate(ctx, c, data, fhirPath, result)
+		if !ok {
+			return ctx.Err() == nil
+		}
+		if !isTrue(values, false) {
+			return true
+		}
+	}
+
+	for _, c := range r.checks {
+		values, ok := r.evaluate(ctx, c, data, fhirPath, result)
+		if !ok {
+			if ctx.Err() != nil {
+				return false
+			}
+			continue
+		}
+		var failed bool
+		switch c.keyword {
+		case "require":
+			failed = values.Empty()
+		case "forbid":
+			failed = !values.Empty()
+		case "assert":
+			failed = !isTrue(values, true)
+		}
+		if failed {
+			r.report(c, fhirPath, result)
+		}
+	}
+	return true
+}
+
+// evaluate evaluates a clause, reporting evaluation errors. Without a
+// cancelable context the evaluation is unbounded.
+func (r *Rule) evaluate(ctx context.Context, c clause, data json.RawMessage, fhirPath string, result *issue.Result) (fhirpath.Collection, bool) {
+	var values fhirpath.Collection
+	var err error
+	if ctx.Done() == nil {
+		values, err = c.program.Evaluate(data)
+	} else {
+		values, err = c.program.EvaluateWithOptions(data, fhirpath.WithContext(ctx), fhirpath.WithTimeout(0))
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			result.AddWarningWithID(
+				issue.DiagBusinessRuleEvalError,
+				issue.Params{issue.String("rule", r.ID), issue.String("error", err.Error())},
+				fhirPath,
+			)
+		}
+		return nil, false
+	}
+	return values, true
+}
+
+// isTrue reports whether a result is true; empty results are ifEmpty, and
+// non-boolean results are true.
+func isTrue(values fhirpath.Collection, ifEmpty bool) bool {
+	if values.Empty() {
+		return ifEmpty
+	}
+	b, err := values.ToBoolean()
+	return err != nil || b
+}
+
+// report adds the issue of a failing clause, at the element of the clause
+// when it is a plain element path.
+func (r *Rule) report(c clause, fhirPath string, result *issue.Result) {
+	path := fhirPath
+	if c.keyword != "assert" && simplePathRegex.MatchString(c.expr) {
+		path += "." + c.expr
+	}
+	params := issue.Params{issue.String("rule", r.ID), issue.String("message", r.message(c))}
+	switch r.Severity {
+	case issue.SeverityWarning:
+		result.AddWarningWithID(issue.DiagBusinessRuleFailed, params, path)
+	case issue.SeverityInformation:
+		result.AddInfoWithID(issue.DiagBusinessRuleFailed, params, path)
+	default:
+		result.AddErrorWithID(issue.DiagBusinessRuleFailed, params, path)
+	}
+}
+
+// message returns the message of the rule, or one generated from a
+// failing clause and the rule's conditions.
+func (r *Rule) message(c clause) string {
+	if r.Message != "" {
+		return r.Message
+	}
+	var msg string
+	switch c.keyword {
+	case "require":
+		msg = fmt.Sprintf("'%s' is required", c.expr)
+	case "forbid":
+		msg = fmt.Sprintf("'%s' is not allowed", c.expr)
+	default:
+		msg = fmt.Sprintf("'%s' is not satisfied", c.expr)
+	}
+	if len(r.when) > 0 {
+		conditions := make([]string, len(r.when))
+		for i, w := range r.when {
+			conditions[i] = w.expr
+		}
+		msg += " when " + strings.Join(conditions, " and ")
+	}
+	return msg
+}
@@ -0,0 +1,188 @@
+package rules
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+const testRules = `
+# Coverage.subscriberId is required when relationship != self
+rule cov-subscriber on Coverage
+  when relationship.coding.where(code = 'self').empty()
+  require subscriberId
+  message subscriberId is required unless the beneficiary is the subscriber
+
+rule pat-deceased on Patient
+  when deceasedBoolean = true
+  forbid deceasedDateTime
+
+rule pat-contact on Patient
+  assert contact.all(name.exists() or organization.exists())
+  severity warning
+`
+
+func TestParse(t *testing.T) {
+	set, err := Parse(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	rules := set.Rules()
+	if len(rules) != 3 {
+		t.Fatalf("Parse() read %d rules, want 3", len(rules))
+	}
+	if r := rules[0]; r.ID != "cov-subscriber" || r.ResourceType != "Coverage" || r.Severity != issue.SeverityError ||
+		len(r.when) != 1 || len(r.checks) != 1 || !strings.HasPrefix(r.Message, "subscriberId is required") {
+		t.Errorf("rules[0] = %+v", r)
+	}
+	if r := rules[2]; r.Severity != issue.SeverityWarning || r.checks[0].keyword != "assert" {
+		t.Errorf("rules[2] = %+v", r)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  string
+	}{
+		{"clause before rule", "require id", "line 1: \"require\" before the first rule"},
+		{"malformed rule", "rule r1 Patient\n  require id", "line 1: expected"},
+		{"no checks", "rule r1 on Patient\n  when active\n\nrule r2 on Patient\n  require id", "line 1: rule \"r1\" has no require"},
+		{"no checks at end", "rule r1 on Patient", "line 1: rule \"r1\" has no require"},
+		{"invalid expression", "rule r1 on Patient\n  require name.where(", "line 2: invalid expression"},
+		{"invalid severity", "rule r1 on Patient\n  require id\n  severity fatal", "line 3: invalid severity"},
+		{"unknown clause", "rule r1 on Patient\n  requires id", "line 2: unknown clause \"requires\""},
+		{"empty clause", "rule r1 on Patient\n  require", "line 2: require needs a value"},
+		{"unknown empty clause", "rule r1 on Patient\n  required", "line 2: unknown clause \"required\""},
+		{"duplicate id", "rule r1 on Patient\n  require id\nrule r1 on Coverage\n  require id", "duplicate rule id \"r1\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.rules))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Ids must be unique across sources, which errors name
+	_, err := Parse(strings.NewReader("rule r1 on Patient\n  require id"), strings.NewReader("rule r1 on Patient\n  require id"))
+	if err == nil || err.Error() != `rules 2: duplicate rule id "r1"` {
+		t.Errorf("Parse() error = %v, want the duplicate in the second source", err)
+	}
+}
+
+func TestValidateData(t *testing.T) {
+	set, err := Parse(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	v := New(testutil.Registry(t, "4.0.1"), set)
+
+	relationship := func(code string) map[string]any {
+		return map[string]any{"coding": []any{map[string]any{
+			"system": "http://terminology.hl7.org/CodeSystem/subscriber-relationship", "code": code,
+		}}}
+	}
+	tests := []struct {
+		name     string
+		resource map[string]any
+		want     []string // Expression and severity of each issue
+	}{
+		{
+			name:     "subscriber missing",
+			resource: map[string]any{"resourceType": "Coverage", "relationship": relationship("spouse")},
+			want:     []string{"Coverage.subscriberId error"},
+		},
+		{
+			name:     "subscriber present",
+			resource: map[string]any{"resourceType": "Coverage", "relationship": relationship("spouse"), "subscriberId": "A1"},
+		},
+		{
+			name:     "condition not met",
+			resource: map[string]any{"resourceType": "Coverage", "relationship": relationship("self")},
+		},
+		{
+			name:     "deceased flag",
+			resource: map[string]any{"resourceType": "Patient", "deceasedBoolean": true},
+		},
+		{
+			name:     "deceased flag and date",
+			resource: map[string]any{"resourceType": "Patient", "deceasedBoolean": true, "deceasedDateTime": "2024-01-01"},
+			want:     []string{"Patient.deceasedDateTime error"},
+		},
+		{
+			name: "assertion in a contained resource",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"contained": []any{map[string]any{
+					"resourceType": "Patient", "id": "p1", "contact": []any{map[string]any{"gender": "male"}},
+				}},
+			},
+			want: []string{"Observation.contained[0] warning"},
+		},
+		{
+			name: "Bundle entries",
+			resource: map[string]any{
+				"resourceType": "Bundle",
+				"type":         "collection",
+				"entry": []any{
+					map[string]any{"resource": map[string]any{"resourceType": "Coverage"}},
+					map[string]any{"resource": map[string]any{"resourceType": "Coverage", "subscriberId": "A1"}},
+				},
+			},
+			want: []string{"Bundle.entry[0].resource.subscriberId error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(context.Background(), tt.resource, result)
+			var got []string
+			for _, iss := range result.Issues {
+				if iss.MessageID != string(issue.DiagBusinessRuleFailed) {
+					t.Errorf("unexpected issue: %+v", iss)
+					continue
+				}
+				got = append(got, iss.Expression[0]+" "+string(iss.Severity))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("issues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	set, err := Parse(strings.NewReader("rule r1 on Patient\n  when active\n  when gender = 'male'\n  require birthDate\n  forbid name.where(use = 'anonymous')"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	result := issue.NewResult()
+	New(testutil.Registry(t, "4.0.1"), set).ValidateData(context.Background(), map[string]any{
+		"resourceType": "Patient",
+		"active":       true,
+		"gender":       "male",
+		"name":         []any{map[string]any{"use": "anonymous"}},
+	}, result)
+
+	want := []string{
+		"'birthDate' is required when active and gender = 'male' (rule r1)",
+		"'name.where(use = 'anonymous')' is not allowed when active and gender = 'male' (rule r1)",
+	}
+	if len(result.Issues) != len(want) {
+		t.Fatalf("issues = %+v, want %d", result.Issues, len(want))
+	}
+	for i, iss := range result.Issues {
+		if iss.Diagnostics != want[i] {
+			t.Errorf("issue %d = %q, want %q", i, iss.Diagnostics, want[i])
+		}
+	}
+	if expr := result.Issues[1].Expression[0]; expr != "Patient" {
+		t.Errorf("forbid expression issue at %q, want the resource", expr)
+	}
+}
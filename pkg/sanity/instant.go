@@ -0,0 +1,53 @@
+package sanity
+
+import "time"
+
+// Time zone offsets range from -12:00 to +14:00, so a date without a time
+// starts as early as 14 hours before UTC midnight and ends as late as 12
+// hours after it.
+const (
+	earliestOffset = 14 * time.Hour
+	latestOffset   = 12 * time.Hour
+)
+
+// instant is the range [lo, hi) of instants a FHIR date, dateTime or instant
+// denotes.
+type instant struct {
+	lo, hi time.Time
+}
+
+// parseInstant parses a FHIR date, dateTime or instant value.
+func parseInstant(s string) (instant, bool) {
+	if s == "" {
+		return instant{}, false
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return instant{lo: t, hi: t.Add(time.Nanosecond)}, true
+	}
+
+	var lo, hi time.Time
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		lo, hi = t, t.AddDate(0, 0, 1)
+	} else if t, err := time.Parse("2006-01", s); err == nil {
+		lo, hi = t, t.AddDate(0, 1, 0)
+	} else if t, err := time.Parse("2006", s); err == nil {
+		lo, hi = t, t.AddDate(1, 0, 0)
+	} else {
+		return instant{}, false
+	}
+	return instant{lo: lo.Add(-earliestOffset), hi: hi.Add(latestOffset)}, true
+}
+
+// after reports whether the value a is certainly after the value b. Values
+// that are empty or cannot be parsed are never after one another.
+func after(a, b string) bool {
+	ia, ok := parseInstant(a)
+	if !ok {
+		return false
+	}
+	ib, ok := parseInstant(b)
+	if !ok {
+		return false
+	}
+	return !ia.lo.Before(ib.hi)
+}
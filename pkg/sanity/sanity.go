@@ -0,0 +1,223 @@
+// Package sanity provides an opt-in phase of cross-field temporal checks that
+// catch data errors schema validation misses:
+//
+//   - period-order: a Period's start is not after its end (per-1), for every
+//     Period of the resource, including nested ones
+//   - birthdate-future: a birthDate is not in the future
+//   - deceased-after-birth: Patient.deceasedDateTime is not before birthDate
+//   - effective-within-encounter: effective[x] lies within the period of the
+//     referenced Encounter when it is contained or in the same Bundle
+//
+// Dates of different precision are compared by the range of instants they
+// denote, so only values that are certainly out of order are reported.
+package sanity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/reference"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Rule identifies a check of the sanity phase.
+type Rule string
+
+// Sanity rules.
+const (
+	RulePeriodOrder              Rule = "period-order"
+	RuleBirthDateFuture          Rule = "birthdate-future"
+	RuleDeceasedAfterBirth       Rule = "deceased-after-birth"
+	RuleEffectiveWithinEncounter Rule = "effective-within-encounter"
+)
+
+var all = []Rule{RulePeriodOrder, RuleBirthDateFuture, RuleDeceasedAfterBirth, RuleEffectiveWithinEncounter}
+
+// Rules returns every rule name.
+func Rules() []Rule {
+	return append([]Rule(nil), all...)
+}
+
+// ParseRule resolves a rule name, case-insensitively.
+func ParseRule(s string) (Rule, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, r := range all {
+		if string(r) == s {
+			return r, nil
+		}
+	}
+	names := make([]string, len(all))
+	for i, r := range all {
+		names[i] = string(r)
+	}
+	return "", fmt.Errorf("unknown sanity rule %q (available: %s)", s, strings.Join(names, ", "))
+}
+
+// Validator applies the enabled sanity rules.
+type Validator struct {
+	registry *registry.Registry
+	walker   *walker.Walker
+	enabled  map[Rule]bool
+	now      func() time.Time
+}
+
+// New creates a sanity Validator running the rules in only (every rule if
+// empty) except those in disabled.
+func New(reg *registry.Registry, only, disabled []Rule) *Validator {
+	if len(only) == 0 {
+		only = all
+	}
+	v := &Validator{
+		registry: reg,
+		walker:   walker.New(reg),
+		enabled:  make(map[Rule]bool, len(only)),
+		now:      time.Now,
+	}
+	for _, r := range only {
+		if !slices.Contains(disabled, r) {
+			v.enabled[r] = true
+		}
+	}
+	return v
+}
+
+// SetClock sets the function returning the current time, against which
+// future dates are checked (default time.Now).
+func (v *Validator) SetClock(now func() time.Time) {
+	v.now = now
+}
+
+// Enabled reports whether a rule runs.
+func (v *Validator) Enabled(r Rule) bool {
+	return v.enabled[r]
+}
+
+// ValidateData applies the enabled rules to a pre-parsed resource, including
+// contained resources and Bundle entries.
+func (v *Validator) ValidateData(resource map[string]any, sd *registry.StructureDefinition, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" || sd == nil {
+		return
+	}
+
+	var bundleCtx *reference.BundleContext
+	if resourceType == "Bundle" && v.enabled[RuleEffectiveWithinEncounter] {
+		bundleCtx = reference.NewBundleContext(resource)
+	}
+
+	// Containers by path, so contained resources resolve fragment references
+	// against their container.
+	containers := map[string]map[string]any{}
+	now := v.now()
+
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
+		resSD := rc.SD
+		container := rc.Data
+		if rc.FHIRPath == resourceType {
+			resSD = sd
+		} else if rc.IsContained {
+			container = containers[rc.ParentPath]
+		}
+		containers[rc.FHIRPath] = rc.Data
+
+		if v.enabled[RulePeriodOrder] && resSD != nil {
+			v.validatePeriods(rc.Data, resSD, rc.ResourceType, rc.FHIRPath, result)
+		}
+		if v.registry.GetElementDefinition(rc.ResourceType+".birthDate") != nil {
+			v.validateBirthDate(rc.Data, rc.ResourceType, rc.FHIRPath, now, result)
+		}
+		if v.enabled[RuleEffectiveWithinEncounter] {
+			v.validateEffective(rc.Data, rc.FHIRPath, container, bundleCtx, result)
+		}
+		return true
+	})
+}
+
+// validatePeriods reports every Period of a resource whose start is after its end.
+func (v *Validator) validatePeriods(data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, result *issue.Result) {
+	v.walker.WalkElements(data, sd, sdPath, fhirPath, func(ec *walker.ElementContext) bool {
+		if ec.Type != "Period" {
+			return true
+		}
+		start, _ := ec.Data["start"].(string)
+		end, _ := ec.Data["end"].(string)
+		if after(start, end) {
+			result.AddErrorWithID(issue.DiagSanityPeriodOrder, issue.Params{issue.String("start", start), issue.String("end", end)}, ec.FHIRPath)
+		}
+		return false
+	})
+}
+
+// validateBirthDate checks a birthDate against the current time and, for
+// Patients, against deceasedDateTime.
+func (v *Validator) validateBirthDate(data map[string]any, resourceType, fhirPath string, now time.Time, result *issue.Result) {
+	birthDate, _ := data["birthDate"].(string)
+	birth, ok := parseInstant(birthDate)
+	if !ok {
+		return
+	}
+
+	if v.enabled[RuleBirthDateFuture] && birth.lo.After(now) {
+		result.AddErrorWithID(issue.DiagSanityBirthDateFuture, issue.Params{issue.String("birthDate", birthDate)}, fhirPath+".birthDate")
+	}
+
+	if v.enabled[RuleDeceasedAfterBirth] && v.registry.GetElementDefinition(resourceType+".deceased[x]") != nil {
+		deceased, _ := data["deceasedDateTime"].(string)
+		if after(birthDate, deceased) {
+			result.AddErrorWithID(
+				issue.DiagSanityDeceasedBeforeBirth,
+				issue.Params{issue.String("deceased", deceased), issue.String("birthDate", birthDate)},
+				fhirPath+".deceasedDateTime",
+			)
+		}
+	}
+}
+
+// validateEffective checks that effective[x] lies within the period of the
+// Encounter the resource references, if the Encounter is contained or an
+// entry of the Bundle.
+func (v *Validator) validateEffective(data map[string]any, fhirPath string, container map[string]any, bundleCtx *reference.BundleContext, result *issue.Result) {
+	encRef, _ := data["encounter"].(map[string]any)
+	ref, _ := encRef["reference"].(string)
+	if ref == "" {
+		return
+	}
+
+	var first, last, element string
+	if value, ok := data["effectiveDateTime"].(string); ok {
+		first, last, element = value, value, "effectiveDateTime"
+	} else if value, ok := data["effectiveInstant"].(string); ok {
+		first, last, element = value, value, "effectiveInstant"
+	} else if period, ok := data["effectivePeriod"].(map[string]any); ok {
+		first, _ = period["start"].(string)
+		last, _ = period["end"].(string)
+		element = "effectivePeriod"
+	} else {
+		return
+	}
+
+	var encounter map[string]any
+	if id, ok := strings.CutPrefix(ref, "#"); ok {
+		encounter = walker.ContainedResource(container, id)
+	} else if bundleCtx != nil {
+		encounter = bundleCtx.Resource(ref)
+	}
+	if resourceType, _ := encounter["resourceType"].(string); resourceType != "Encounter" {
+		return
+	}
+	period, _ := encounter["period"].(map[string]any)
+	start, _ := period["start"].(string)
+	end, _ := period["end"].(string)
+
+	if after(start, first) || after(last, end) {
+		result.AddWarningWithID(
+			issue.DiagSanityEffectiveOutsideEncounter,
+			issue.Params{issue.String("reference", ref)},
+			fhirPath+"."+element,
+		)
+	}
+}
@@ -0,0 +1,205 @@
+package sanity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// encounterBundle wraps an Observation in a collection Bundle with an
+// Encounter entry that lasted the morning of 2024-03-10 (UTC).
+func encounterBundle(obs map[string]any) map[string]any {
+	obs["resourceType"] = "Observation"
+	obs["encounter"] = map[string]any{"reference": "Encounter/e1"}
+	return map[string]any{
+		"resourceType": "Bundle",
+		"type":         "collection",
+		"entry": []any{
+			map[string]any{
+				"fullUrl": "http://example.org/fhir/Encounter/e1",
+				"resource": map[string]any{
+					"resourceType": "Encounter",
+					"id":           "e1",
+					"period":       map[string]any{"start": "2024-03-10T08:00:00Z", "end": "2024-03-10T12:00:00Z"},
+				},
+			},
+			map[string]any{
+				"fullUrl":  "http://example.org/fhir/Observation/o1",
+				"resource": obs,
+			},
+		},
+	}
+}
+
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource map[string]any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "period in order",
+			resource: map[string]any{
+				"resourceType": "Encounter",
+				"period":       map[string]any{"start": "2024-01-01", "end": "2024-01-02"},
+			},
+		},
+		{
+			name: "period start after end",
+			resource: map[string]any{
+				"resourceType": "Encounter",
+				"period":       map[string]any{"start": "2024-02-01", "end": "2024-01-01"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSanityPeriodOrder},
+			wantPath: "Encounter.period",
+		},
+		{
+			name: "nested period start after end",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"name":         []any{map[string]any{"family": "Doe", "period": map[string]any{"start": "2021", "end": "2019"}}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSanityPeriodOrder},
+			wantPath: "Patient.name[0].period",
+		},
+		{
+			name: "same day at different precision",
+			resource: map[string]any{
+				"resourceType": "Encounter",
+				"period":       map[string]any{"start": "2024-01-01T23:00:00-05:00", "end": "2024-01-01"},
+			},
+		},
+		{
+			name: "birth date in the future",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"birthDate":    "2031-05-01",
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSanityBirthDateFuture},
+			wantPath: "Patient.birthDate",
+		},
+		{
+			name: "birth date in the current year",
+			resource: map[string]any{
+				"resourceType": "Practitioner",
+				"birthDate":    "2030",
+			},
+		},
+		{
+			name: "deceased before birth",
+			resource: map[string]any{
+				"resourceType":     "Patient",
+				"birthDate":        "1990-06-15",
+				"deceasedDateTime": "1989-01-01T10:00:00Z",
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSanityDeceasedBeforeBirth},
+			wantPath: "Patient.deceasedDateTime",
+		},
+		{
+			name: "deceased on the day of birth",
+			resource: map[string]any{
+				"resourceType":     "Patient",
+				"birthDate":        "1990-06-15",
+				"deceasedDateTime": "1990-06-15T03:00:00+02:00",
+			},
+		},
+		{
+			name:     "effective within encounter",
+			resource: encounterBundle(map[string]any{"effectiveDateTime": "2024-03-10T09:30:00Z"}),
+		},
+		{
+			name:     "effective after encounter",
+			resource: encounterBundle(map[string]any{"effectiveDateTime": "2024-03-11T09:30:00Z"}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSanityEffectiveOutsideEncounter},
+			wantPath: "Bundle.entry[1].resource.effectiveDateTime",
+		},
+		{
+			name: "effective period starting before encounter",
+			resource: encounterBundle(map[string]any{
+				"effectivePeriod": map[string]any{"start": "2024-03-10T07:00:00Z", "end": "2024-03-10T09:00:00Z"},
+			}),
+			wantIDs:  []issue.DiagnosticID{issue.DiagSanityEffectiveOutsideEncounter},
+			wantPath: "Bundle.entry[1].resource.effectivePeriod",
+		},
+		{
+			name: "effective outside contained encounter",
+			resource: map[string]any{
+				"resourceType": "Observation",
+				"contained": []any{map[string]any{
+					"resourceType": "Encounter",
+					"id":           "enc",
+					"period":       map[string]any{"start": "2024-03-10"},
+				}},
+				"encounter":         map[string]any{"reference": "#enc"},
+				"effectiveDateTime": "2024-03-01",
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSanityEffectiveOutsideEncounter},
+			wantPath: "Observation.effectiveDateTime",
+		},
+		{
+			name: "unresolved encounter not checked",
+			resource: map[string]any{
+				"resourceType":      "Observation",
+				"encounter":         map[string]any{"reference": "Encounter/unknown"},
+				"effectiveDateTime": "2024-03-01",
+			},
+		},
+	}
+
+	reg := testutil.Registry(t, "4.0.1")
+	v := New(reg, nil, nil)
+	v.SetClock(func() time.Time { return time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resourceType, _ := tt.resource["resourceType"].(string)
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, reg.GetByType(resourceType), result)
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", testutil.IssueIDs(result), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestRuleSelection(t *testing.T) {
+	reg := testutil.Registry(t, "4.0.1")
+	patient := map[string]any{
+		"resourceType":     "Patient",
+		"birthDate":        "2999-01-01",
+		"deceasedDateTime": "2000-01-01T00:00:00Z",
+	}
+
+	v := New(reg, nil, []Rule{RuleBirthDateFuture})
+	result := issue.NewResult()
+	v.ValidateData(patient, reg.GetByType("Patient"), result)
+	if ids := testutil.IssueIDs(result); len(ids) != 1 || ids[0] != string(issue.DiagSanityDeceasedBeforeBirth) {
+		t.Errorf("with birthdate-future disabled: issues = %v", ids)
+	}
+
+	v = New(reg, []Rule{RuleBirthDateFuture}, nil)
+	result = issue.NewResult()
+	v.ValidateData(patient, reg.GetByType("Patient"), result)
+	if ids := testutil.IssueIDs(result); len(ids) != 1 || ids[0] != string(issue.DiagSanityBirthDateFuture) {
+		t.Errorf("with only birthdate-future: issues = %v", ids)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	if r, err := ParseRule(" Period-Order "); err != nil || r != RulePeriodOrder {
+		t.Errorf("ParseRule = %q, %v", r, err)
+	}
+	if _, err := ParseRule("bogus"); err == nil {
+		t.Error("ParseRule(bogus) succeeded")
+	}
+}
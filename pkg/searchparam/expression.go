@@ -0,0 +1,596 @@
+package searchparam
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofhir/fhirpath"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// indexableTypes are the element types each search parameter type can index.
+// Composite and special parameters are not checked.
+var indexableTypes = map[string][]string{
+	"number":    {"integer", "integer64", "decimal", "positiveInt", "unsignedInt"},
+	"date":      {"date", "dateTime", "instant", "Period", "Timing"},
+	"string":    {"string", "markdown", "id", "HumanName", "Address", "Narrative"},
+	"token":     {"boolean", "code", "id", "string", "uri", "url", "canonical", "oid", "uuid", "Coding", "CodeableConcept", "CodeableReference", "Identifier", "ContactPoint"},
+	"reference": {"Reference", "CodeableReference", "canonical", "uri", "url"},
+	"quantity":  {"Quantity", "SimpleQuantity", "Money", "Age", "Count", "Distance", "Duration", "Range", "SampledData"},
+	"uri":       {"uri", "url", "canonical", "oid", "uuid"},
+}
+
+// Functions by how they change the type of their input.
+var (
+	// filterFunctions return part of their input.
+	filterFunctions = map[string]bool{
+		"where": true, "first": true, "last": true, "tail": true, "skip": true,
+		"take": true, "single": true, "distinct": true, "trace": true, "exclude": true,
+	}
+	// booleanFunctions return a boolean.
+	booleanFunctions = map[string]bool{
+		"exists": true, "empty": true, "not": true, "all": true, "allTrue": true,
+		"anyTrue": true, "allFalse": true, "anyFalse": true, "hasValue": true,
+		"is": true, "startsWith": true, "endsWith": true, "contains": true,
+		"matches": true, "subsetOf": true, "supersetOf": true, "isDistinct": true,
+		"memberOf": true, "conformsTo": true, "htmlChecks": true,
+	}
+)
+
+// Validator checks the expressions of SearchParameter resources: each must be
+// valid FHIRPath, navigate only elements of the parameter's base resources,
+// and select elements of a type the parameter type can index. It is meant for
+// IG authors, whose SearchParameters servers index without further checks.
+type Validator struct {
+	registry *registry.Registry
+	walker   *walker.Walker
+}
+
+// NewValidator creates a new SearchParameter expression Validator.
+func NewValidator(reg *registry.Registry) *Validator {
+	return &Validator{registry: reg, walker: walker.New(reg)}
+}
+
+// ValidateData checks the SearchParameters of a pre-parsed resource: the
+// resource itself, contained resources and Bundle entries.
+func (v *Validator) ValidateData(resource map[string]any, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+	if resourceType == "SearchParameter" {
+		v.validateSearchParameter(resource, resourceType, result)
+	}
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
+		if rc.FHIRPath != resourceType && rc.ResourceType == "SearchParameter" {
+			v.validateSearchParameter(rc.Data, rc.FHIRPath, result)
+		}
+		return true
+	})
+}
+
+// validateSearchParameter checks the expression of a SearchParameter.
+func (v *Validator) validateSearchParameter(data map[string]any, fhirPath string, result *issue.Result) {
+	expression, _ := data["expression"].(string)
+	if strings.TrimSpace(expression) == "" {
+		return
+	}
+	fhirPath += ".expression"
+	if _, err := fhirpath.Compile(expression); err != nil {
+		result.AddErrorWithID(
+			issue.DiagSearchParamExpressionInvalid,
+			issue.Params{issue.String("expression", expression), issue.String("error", err.Error())},
+			fhirPath,
+		)
+		return
+	}
+
+	var bases []string
+	items, _ := data["base"].([]any)
+	for _, item := range items {
+		if base, ok := item.(string); ok {
+			bases = append(bases, base)
+		}
+	}
+	tokens, ok := tokenize(expression)
+	if !ok || len(bases) == 0 {
+		return
+	}
+	a := &analyzer{registry: v.registry, tokens: tokens, bases: bases}
+	branches := a.expression()
+	if a.failed || a.pos < len(a.tokens) {
+		return // Beyond the FHIRPath the analysis understands
+	}
+	for _, p := range a.problems {
+		result.AddErrorWithID(p.id, p.params, fhirPath)
+	}
+
+	searchType, _ := data["type"].(string)
+	allowed, checked := indexableTypes[searchType]
+	if !checked {
+		return
+	}
+	for _, s := range branches {
+		if s.types == nil || slices.ContainsFunc(s.types, v.registry.IsResourceType) {
+			continue
+		}
+		if !slices.ContainsFunc(s.types, func(t string) bool { return slices.Contains(allowed, t) }) {
+			result.AddErrorWithID(
+				issue.DiagSearchParamExpressionType,
+				issue.Params{issue.String("path", s.label), issue.String("type", strings.Join(s.types, " | ")), issue.String("searchType", searchType)},
+				fhirPath,
+			)
+		}
+	}
+}
+
+// token is a lexical token of a FHIRPath expression.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type tokenKind int
+
+const (
+	tokenIdentifier tokenKind = iota
+	tokenLiteral              // string, number, date or quantity
+	tokenVariable             // %resource, $this, ...
+	tokenPunct                // . ( ) [ ] , |
+	tokenOperator             // = != < > + - ...
+)
+
+// tokenize splits a FHIRPath expression into tokens. It reports false for
+// input it does not understand.
+func tokenize(s string) ([]token, bool) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			j := i + 1
+			for j < len(s) && s[j] != '\'' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, false
+			}
+			tokens = append(tokens, token{tokenLiteral, s[i+1 : j]})
+			i = j + 1
+		case c == '`':
+			j := strings.IndexByte(s[i+1:], '`')
+			if j < 0 {
+				return nil, false
+			}
+			tokens = append(tokens, token{tokenIdentifier, s[i+1 : i+1+j]})
+			i += j + 2
+		case isIdentifierByte(c) && (c < '0' || c > '9'):
+			j := i
+			for j < len(s) && isIdentifierByte(s[j]) {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdentifier, s[i:j]})
+			i = j
+		case c >= '0' && c <= '9', c == '@':
+			j := i + 1
+			for j < len(s) && (isIdentifierByte(s[j]) || strings.IndexByte(".:-+T", s[j]) >= 0) {
+				if s[j] == '.' && (j+1 >= len(s) || s[j+1] < '0' || s[j+1] > '9') {
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, token{tokenLiteral, s[i:j]})
+			i = j
+		case c == '%' || c == '$':
+			j := i + 1
+			for j < len(s) && isIdentifierByte(s[j]) {
+				j++
+			}
+			tokens = append(tokens, token{tokenVariable, s[i:j]})
+			i = j
+		case strings.IndexByte(".()[],|", c) >= 0:
+			tokens = append(tokens, token{tokenPunct, s[i : i+1]})
+			i++
+		case strings.IndexByte("=!<>~+-*/&", c) >= 0:
+			j := i + 1
+			if j < len(s) && (s[j] == '=' || s[j] == '~') {
+				j++
+			}
+			tokens = append(tokens, token{tokenOperator, s[i:j]})
+			i = j
+		default:
+			return nil, false
+		}
+	}
+	return tokens, true
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// keywordOperators are the operators spelled as words.
+var keywordOperators = map[string]bool{
+	"and": true, "or": true, "xor": true, "implies": true,
+	"div": true, "mod": true, "in": true, "contains": true,
+}
+
+// state is what a FHIRPath expression selects: elements at a path, of some
+// types. Path is empty and types nil once the analysis cannot follow the
+// expression (e.g., after resolve()).
+type state struct {
+	path  string   // ElementDefinition path for navigation
+	types []string // possible types of the selected elements
+	label string   // the expression so far, for messages
+}
+
+// problem is an issue found by the analysis, reported only if the whole
+// expression could be analyzed.
+type problem struct {
+	id     issue.DiagnosticID
+	params issue.Params
+}
+
+// analyzer follows the paths of a FHIRPath expression through the
+// ElementDefinitions of the base resources. It understands the subset of
+// FHIRPath used by search parameters: navigation, unions, indexers, as/is,
+// and functions such as where(), ofType() and extension(); arguments of
+// functions are not analyzed.
+type analyzer struct {
+	registry *registry.Registry
+	tokens   []token
+	pos      int
+	bases    []string
+	problems []problem
+	failed   bool
+}
+
+func (a *analyzer) peek() (token, bool) {
+	if a.pos >= len(a.tokens) {
+		return token{}, false
+	}
+	return a.tokens[a.pos], true
+}
+
+func (a *analyzer) peekPunct(text string) bool {
+	t, ok := a.peek()
+	return ok && t.kind == tokenPunct && t.text == text
+}
+
+func (a *analyzer) report(id issue.DiagnosticID, params issue.Params) {
+	for _, p := range a.problems {
+		if p.id == id && fmt.Sprint(p.params) == fmt.Sprint(params) {
+			return
+		}
+	}
+	a.problems = append(a.problems, problem{id, params})
+}
+
+// expression analyzes terms joined by operators and returns what the
+// expression selects. Only unions keep the states of their operands.
+func (a *analyzer) expression() []state {
+	states := a.term()
+	combined := false
+	for !a.failed {
+		t, ok := a.peek()
+		if !ok {
+			break
+		}
+		switch {
+		case t.kind == tokenIdentifier && (t.text == "as" || t.text == "is"):
+			a.pos++
+			typeName, ok := a.typeName()
+			if !ok {
+				return nil
+			}
+			if t.text == "is" {
+				states = []state{{types: []string{"boolean"}}}
+			} else {
+				states = narrow(states, typeName)
+			}
+		case t.kind == tokenPunct && t.text == "|":
+			a.pos++
+			states = append(states, a.term()...)
+		case t.kind == tokenOperator || (t.kind == tokenIdentifier && keywordOperators[t.text]):
+			a.pos++
+			a.term()
+			combined = true
+		default:
+			return a.result(states, combined)
+		}
+	}
+	return a.result(states, combined)
+}
+
+// result returns the states of an expression, or a single unknown state
+// when operators other than unions combine its terms.
+func (a *analyzer) result(states []state, combined bool) []state {
+	if combined {
+		return []state{{}}
+	}
+	return states
+}
+
+// typeName reads a possibly qualified type name (e.g., "FHIR.Quantity").
+func (a *analyzer) typeName() (string, bool) {
+	t, ok := a.peek()
+	if !ok || t.kind != tokenIdentifier {
+		a.failed = true
+		return "", false
+	}
+	a.pos++
+	name := t.text
+	for a.peekPunct(".") && a.pos+1 < len(a.tokens) && a.tokens[a.pos+1].kind == tokenIdentifier {
+		name = a.tokens[a.pos+1].text
+		a.pos += 2
+	}
+	return name, true
+}
+
+// term analyzes a term and the navigation, function calls and indexers
+// that follow it.
+func (a *analyzer) term() []state {
+	states := a.primary()
+	for !a.failed {
+		switch {
+		case a.peekPunct("."):
+			a.pos++
+			t, ok := a.peek()
+			if !ok || t.kind != tokenIdentifier {
+				a.failed = true
+				return nil
+			}
+			a.pos++
+			if a.peekPunct("(") {
+				states = a.function(states, t.text)
+			} else {
+				states = a.navigate(states, t.text)
+			}
+		case a.peekPunct("["):
+			a.pos++
+			a.expression()
+			if !a.peekPunct("]") {
+				a.failed = true
+				return nil
+			}
+			a.pos++
+		default:
+			return states
+		}
+	}
+	return nil
+}
+
+// primary analyzes the start of a term.
+func (a *analyzer) primary() []state {
+	t, ok := a.peek()
+	if !ok {
+		a.failed = true
+		return nil
+	}
+	a.pos++
+	switch t.kind {
+	case tokenPunct:
+		if t.text != "(" {
+			a.failed = true
+			return nil
+		}
+		states := a.expression()
+		if !a.peekPunct(")") {
+			a.failed = true
+			return nil
+		}
+		a.pos++
+		return states
+	case tokenLiteral:
+		return []state{{}}
+	case tokenVariable:
+		if t.text == "%resource" || t.text == "%rootResource" || t.text == "$this" {
+			return a.baseStates()
+		}
+		return []state{{}}
+	case tokenOperator:
+		if t.text == "-" || t.text == "+" {
+			return a.term()
+		}
+		a.failed = true
+		return nil
+	}
+
+	// Identifier: a function without input, a type name or an element of
+	// the base resources
+	if a.peekPunct("(") {
+		return a.function([]state{{}}, t.text)
+	}
+	if t.text == "true" || t.text == "false" {
+		return []state{{types: []string{"boolean"}}}
+	}
+	if isTypeName(t.text) && (slices.Contains(a.bases, t.text) || a.registry.GetByType(t.text) != nil) {
+		if !slices.Contains(a.bases, t.text) {
+			a.report(issue.DiagSearchParamExpressionBase, issue.Params{issue.String("type", t.text), issue.String("base", strings.Join(a.bases, ", "))})
+			return []state{{}}
+		}
+		return []state{{path: t.text, types: []string{t.text}, label: t.text}}
+	}
+	return a.navigate(a.baseStates(), t.text)
+}
+
+// baseStates returns the states of the base resources.
+func (a *analyzer) baseStates() []state {
+	states := make([]state, 0, len(a.bases))
+	for _, base := range a.bases {
+		states = append(states, state{path: base, types: []string{base}, label: base})
+	}
+	return states
+}
+
+// function analyzes a function call on states; the current token is the
+// opening parenthesis of its arguments.
+func (a *analyzer) function(states []state, name string) []state {
+	args := a.arguments()
+	if a.failed {
+		return nil
+	}
+	switch {
+	case filterFunctions[name]:
+		return states
+	case name == "ofType" || name == "as":
+		if len(args) == 0 || len(args[len(args)-1]) == 0 {
+			return []state{{}}
+		}
+		arg := args[len(args)-1]
+		return narrow(states, arg[len(arg)-1].text)
+	case name == "extension":
+		out := make([]state, 0, len(states))
+		for _, s := range states {
+			out = append(out, state{path: "Extension", types: []string{"Extension"}, label: s.label + ".extension()"})
+		}
+		return out
+	case booleanFunctions[name]:
+		return []state{{types: []string{"boolean"}}}
+	case name == "count" || name == "length":
+		return []state{{types: []string{"integer"}}}
+	}
+	return []state{{}}
+}
+
+// arguments skips the arguments of a function call and returns their
+// tokens, split at top-level commas.
+func (a *analyzer) arguments() [][]token {
+	a.pos++ // (
+	var args [][]token
+	var current []token
+	depth := 0
+	for ; a.pos < len(a.tokens); a.pos++ {
+		t := a.tokens[a.pos]
+		if t.kind == tokenPunct {
+			switch t.text {
+			case "(", "[":
+				depth++
+			case "]":
+				depth--
+			case ")":
+				if depth == 0 {
+					a.pos++
+					if len(current) > 0 {
+						args = append(args, current)
+					}
+					return args
+				}
+				depth--
+			case ",":
+				if depth == 0 {
+					args = append(args, current)
+					current = nil
+					continue
+				}
+			}
+		}
+		current = append(current, t)
+	}
+	a.failed = true
+	return nil
+}
+
+// narrow restricts states to a type, as ofType() and "as" do.
+func narrow(states []state, typeName string) []state {
+	out := make([]state, 0, len(states))
+	for _, s := range states {
+		if s.path == "" && s.types == nil {
+			out = append(out, s)
+			continue
+		}
+		out = append(out, state{path: s.path, types: []string{typeName}, label: s.label + ".ofType(" + typeName + ")"})
+	}
+	return out
+}
+
+// navigate follows a child element from each state, reporting children that
+// no state has.
+func (a *analyzer) navigate(states []state, name string) []state {
+	out := make([]state, 0, len(states))
+	for _, s := range states {
+		if s.path == "" {
+			out = append(out, state{})
+			continue
+		}
+		label := s.label + "." + name
+		next, ok := a.child(s, name)
+		if !ok {
+			a.report(issue.DiagSearchParamExpressionElement, issue.Params{issue.String("path", label), issue.String("type", strings.Join(s.types, " | "))})
+			out = append(out, state{})
+			continue
+		}
+		next.label = label
+		out = append(out, next)
+	}
+	return out
+}
+
+// child resolves a child element of a state: in the element's own
+// definition, then in the definitions of its types.
+func (a *analyzer) child(s state, name string) (state, bool) {
+	parents := []string{s.path}
+	for _, t := range s.types {
+		if t != s.path && !a.registry.IsPrimitiveType(t) {
+			parents = append(parents, t)
+		}
+	}
+	for _, parent := range parents {
+		path := parent + "." + name
+		if ed := a.registry.GetElementDefinition(path); ed != nil {
+			return a.elementState(path, ed, ""), true
+		}
+		if ed := a.registry.GetElementDefinition(path + "[x]"); ed != nil {
+			return a.elementState(path+"[x]", ed, ""), true
+		}
+		// Type-specific name of a choice element (e.g., valueQuantity)
+		for i := 1; i < len(name); i++ {
+			if name[i] < 'A' || name[i] > 'Z' {
+				continue
+			}
+			if ed := a.registry.GetElementDefinition(parent + "." + name[:i] + "[x]"); ed != nil {
+				for _, t := range ed.Type {
+					if strings.EqualFold(t.Code, name[i:]) {
+						return a.elementState(parent+"."+name[:i]+"[x]", ed, t.Code), true
+					}
+				}
+			}
+		}
+	}
+	return state{}, false
+}
+
+// elementState returns the state of an element, restricted to one of its
+// types if typeName is given.
+func (a *analyzer) elementState(path string, ed *registry.ElementDefinition, typeName string) state {
+	if ed.ContentReference != nil {
+		if _, ref, ok := strings.Cut(*ed.ContentReference, "#"); ok {
+			path = ref
+			if target := a.registry.GetElementDefinition(ref); target != nil {
+				ed = target
+			}
+		}
+	}
+	if typeName != "" {
+		return state{path: path, types: []string{typeName}}
+	}
+	types := make([]string, 0, len(ed.Type))
+	for _, t := range ed.Type {
+		code := t.Code
+		if system, ok := strings.CutPrefix(code, "http://hl7.org/fhirpath/System."); ok {
+			code = strings.ToLower(system) // String, Boolean, ... of element ids and primitive values
+		}
+		types = append(types, code)
+	}
+	return state{path: path, types: types}
+}
@@ -0,0 +1,180 @@
+// Package searchparam indexes the SearchParameters of loaded packages and
+// parses FHIR search queries ("Observation?code=1234-5&subject:Patient.name=x"),
+// so that queries stored in resources, such as Subscription criteria, can be
+// checked against the parameters defined for a resource type. Its Validator
+// checks the expressions of SearchParameter resources for IG authors.
+package searchparam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gofhir/validator/pkg/loader"
+)
+
+// common are the parameters that apply to every resource type, and the
+// search result parameters, which are not SearchParameter resources in every
+// package.
+var common = map[string]bool{
+	"_id": true, "_lastUpdated": true, "_tag": true, "_profile": true,
+	"_security": true, "_source": true, "_text": true, "_content": true,
+	"_list": true, "_has": true, "_type": true, "_query": true, "_filter": true,
+	"_sort": true, "_count": true, "_include": true, "_revinclude": true,
+	"_summary": true, "_total": true, "_elements": true, "_contained": true,
+	"_containedType": true, "_format": true,
+}
+
+// abstractBases are SearchParameter bases that apply to every resource type.
+var abstractBases = []string{"Resource", "DomainResource"}
+
+// Registry holds the codes of loaded SearchParameters by base resource type.
+// It is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	byBase map[string]map[string]bool // base -> code -> defined
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{byBase: make(map[string]map[string]bool)}
+}
+
+// searchParameter is the part of a SearchParameter used for the index.
+type searchParameter struct {
+	ResourceType string   `json:"resourceType"`
+	Code         string   `json:"code"`
+	Base         []string `json:"base"`
+}
+
+// LoadFromPackages loads the SearchParameters of packages.
+func (r *Registry) LoadFromPackages(packages []*loader.Package) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pkg := range packages {
+		for _, data := range pkg.ResourcesOfType("SearchParameter") {
+			var sp searchParameter
+			if err := json.Unmarshal(data, &sp); err != nil || sp.ResourceType != "SearchParameter" || sp.Code == "" {
+				continue
+			}
+			for _, base := range sp.Base {
+				if r.byBase[base] == nil {
+					r.byBase[base] = make(map[string]bool)
+				}
+				r.byBase[base][sp.Code] = true
+			}
+		}
+	}
+}
+
+// Known reports whether SearchParameters are loaded with a resource type as
+// their base. Parameter names can only be checked for known types.
+func (r *Registry) Known(resourceType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.byBase[resourceType]) > 0
+}
+
+// Defined reports whether code is a search parameter of a resource type: a
+// common parameter such as _id, or a loaded SearchParameter whose base is the
+// type or an abstract resource type.
+func (r *Registry) Defined(resourceType, code string) bool {
+	if common[code] {
+		return true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.byBase[resourceType][code] {
+		return true
+	}
+	for _, base := range abstractBases {
+		if r.byBase[base][code] {
+			return true
+		}
+	}
+	return false
+}
+
+// Query is a parsed search query.
+type Query struct {
+	ResourceType string
+	Params       []Param
+}
+
+// Param is a parameter of a search query. For "subject:Patient.name:exact=x"
+// the Name is "subject", the Modifier "Patient" and the Chain "name:exact".
+type Param struct {
+	Name     string
+	Modifier string
+	Chain    string
+	Value    string
+}
+
+// ParseQuery parses a search query of the form "Type" or "Type?params".
+func ParseQuery(query string) (*Query, error) {
+	resourceType, rawParams, _ := strings.Cut(query, "?")
+	if !isTypeName(resourceType) {
+		return nil, fmt.Errorf("expected a resource type before '?', got %q", resourceType)
+	}
+
+	q := &Query{ResourceType: resourceType}
+	if rawParams == "" {
+		return q, nil
+	}
+	for _, pair := range strings.Split(rawParams, "&") {
+		rawKey, rawValue, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("parameter %q has no value", pair)
+		}
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", rawKey, err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", key, err)
+		}
+		param, err := parseParam(key)
+		if err != nil {
+			return nil, err
+		}
+		param.Value = value
+		q.Params = append(q.Params, param)
+	}
+	return q, nil
+}
+
+// parseParam splits a parameter key into its name, modifier and chain.
+func parseParam(key string) (Param, error) {
+	if key == "" {
+		return Param{}, errors.New("empty parameter name")
+	}
+	var p Param
+	head := key
+	if !strings.HasPrefix(key, "_has:") {
+		head, p.Chain, _ = strings.Cut(key, ".")
+	}
+	p.Name, p.Modifier, _ = strings.Cut(head, ":")
+	if p.Name == "" {
+		return Param{}, fmt.Errorf("parameter %q has no name", key)
+	}
+	return p, nil
+}
+
+// isTypeName reports whether s looks like a resource type name.
+func isTypeName(s string) bool {
+	if s == "" || s[0] < 'A' || s[0] > 'Z' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+package searchparam
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    *Query
+		wantErr bool
+	}{
+		{query: "Patient", want: &Query{ResourceType: "Patient"}},
+		{
+			query: "Observation?code=http://loinc.org|1234-5&status=final",
+			want: &Query{ResourceType: "Observation", Params: []Param{
+				{Name: "code", Value: "http://loinc.org|1234-5"},
+				{Name: "status", Value: "final"},
+			}},
+		},
+		{
+			query: "Observation?subject:Patient.name:exact=Smith%20J",
+			want: &Query{ResourceType: "Observation", Params: []Param{
+				{Name: "subject", Modifier: "Patient", Chain: "name:exact", Value: "Smith J"},
+			}},
+		},
+		{
+			query: "Patient?_has:Observation:patient:code=1234-5",
+			want: &Query{ResourceType: "Patient", Params: []Param{
+				{Name: "_has", Modifier: "Observation:patient:code", Value: "1234-5"},
+			}},
+		},
+		{query: "observation?code=x", wantErr: true},
+		{query: "?code=x", wantErr: true},
+		{query: "Observation?code", wantErr: true},
+		{query: "Observation?=x", wantErr: true},
+		{query: "Observation?code=%zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := ParseQuery(tt.query)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseQuery() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			if got.ResourceType != tt.want.ResourceType || len(got.Params) != len(tt.want.Params) {
+				t.Fatalf("ParseQuery() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got.Params {
+				if got.Params[i] != tt.want.Params[i] {
+					t.Errorf("param[%d] = %+v, want %+v", i, got.Params[i], tt.want.Params[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	pkg, err := loader.NewLoader("").LoadFromResources([][]byte{
+		[]byte(`{"resourceType": "SearchParameter", "id": "Observation-code", "url": "http://example.org/SearchParameter/Observation-code", "code": "code", "base": ["Observation"]}`),
+		[]byte(`{"resourceType": "SearchParameter", "id": "DomainResource-text", "url": "http://example.org/SearchParameter/DomainResource-text", "code": "_text", "base": ["DomainResource"]}`),
+		[]byte(`{"resourceType": "SearchParameter", "id": "Resource-custom", "url": "http://example.org/SearchParameter/Resource-custom", "code": "custom", "base": ["Resource"]}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	r.LoadFromPackages([]*loader.Package{pkg})
+
+	if !r.Known("Observation") || r.Known("Patient") {
+		t.Errorf("Known(Observation) = %v, Known(Patient) = %v", r.Known("Observation"), r.Known("Patient"))
+	}
+	for _, code := range []string{"code", "_id", "_count", "custom"} {
+		if !r.Defined("Observation", code) {
+			t.Errorf("Defined(Observation, %q) = false", code)
+		}
+	}
+	if r.Defined("Observation", "patient") {
+		t.Error("Defined(Observation, patient) = true")
+	}
+}
+
+func TestValidateExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		searchType string
+		expression string
+		base       []any
+		wantIDs    []issue.DiagnosticID
+	}{
+		{name: "element", searchType: "token", expression: "Observation.code", base: []any{"Observation"}},
+		{name: "union over bases", searchType: "string", expression: "Patient.address | Practitioner.address", base: []any{"Patient", "Practitioner"}},
+		{name: "as operator", searchType: "quantity", expression: "(Observation.value as Quantity) | (Observation.value as SampledData)", base: []any{"Observation"}},
+		{name: "choice element", searchType: "date", expression: "Observation.effective", base: []any{"Observation"}},
+		{name: "ofType on backbone child", searchType: "quantity", expression: "Observation.component.value.ofType(Quantity)", base: []any{"Observation"}},
+		{name: "type-specific choice name", searchType: "token", expression: "Observation.valueCodeableConcept", base: []any{"Observation"}},
+		{name: "where and resolve", searchType: "reference", expression: "Observation.subject.where(resolve() is Patient)", base: []any{"Observation"}},
+		{name: "boolean expression", searchType: "token", expression: "Patient.deceased.exists() and Patient.deceased != false", base: []any{"Patient"}},
+		{name: "datatype child", searchType: "date", expression: "Resource.meta.lastUpdated", base: []any{"Resource"}},
+		{name: "content reference", searchType: "token", expression: "QuestionnaireResponse.item.item.linkId", base: []any{"QuestionnaireResponse"}},
+		{name: "extension value", searchType: "token", expression: "Patient.extension('http://example.org/race').value.as(Coding)", base: []any{"Patient"}},
+		{name: "relative path", searchType: "string", expression: "name.family", base: []any{"Patient"}},
+		{name: "composite not type checked", searchType: "composite", expression: "Observation.component", base: []any{"Observation"}},
+		{
+			name: "invalid FHIRPath", searchType: "token", expression: "Patient.name.(", base: []any{"Patient"},
+			wantIDs: []issue.DiagnosticID{issue.DiagSearchParamExpressionInvalid},
+		},
+		{
+			name: "unknown element", searchType: "string", expression: "Patient.nmae.family", base: []any{"Patient"},
+			wantIDs: []issue.DiagnosticID{issue.DiagSearchParamExpressionElement},
+		},
+		{
+			name: "unknown datatype element", searchType: "string", expression: "Patient.name.surname", base: []any{"Patient"},
+			wantIDs: []issue.DiagnosticID{issue.DiagSearchParamExpressionElement},
+		},
+		{
+			name: "type not a base", searchType: "string", expression: "Patient.name | Practitioner.name", base: []any{"Patient"},
+			wantIDs: []issue.DiagnosticID{issue.DiagSearchParamExpressionBase},
+		},
+		{
+			name: "type the parameter cannot index", searchType: "string", expression: "Patient.contact", base: []any{"Patient"},
+			wantIDs: []issue.DiagnosticID{issue.DiagSearchParamExpressionType},
+		},
+		{
+			name: "narrowed to a type the parameter cannot index", searchType: "date", expression: "Condition.onset.as(string)", base: []any{"Condition"},
+			wantIDs: []issue.DiagnosticID{issue.DiagSearchParamExpressionType},
+		},
+	}
+
+	v := NewValidator(testutil.Registry(t, "4.0.1"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(map[string]any{
+				"resourceType": "SearchParameter", "type": tt.searchType, "expression": tt.expression, "base": tt.base,
+			}, result)
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", result.Issues, tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+				if got := result.Issues[i].Expression[0]; got != "SearchParameter.expression" {
+					t.Errorf("issue[%d] path = %s", i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateExpressionInBundle(t *testing.T) {
+	v := NewValidator(testutil.Registry(t, "4.0.1"))
+	result := issue.NewResult()
+	v.ValidateData(map[string]any{
+		"resourceType": "Bundle", "type": "collection",
+		"entry": []any{map[string]any{"resource": map[string]any{
+			"resourceType": "SearchParameter", "type": "token", "expression": "Patient.gendr", "base": []any{"Patient"},
+		}}},
+	}, result)
+	if len(result.Issues) != 1 || result.Issues[0].Expression[0] != "Bundle.entry[0].resource.expression" {
+		t.Errorf("issues = %v", result.Issues)
+	}
+}
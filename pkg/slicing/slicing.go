@@ -5,8 +5,11 @@ package slicing
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gofhir/validator/pkg/fixedpattern"
 	"github.com/gofhir/validator/pkg/issue"
@@ -16,9 +19,16 @@ import (
 // FHIRPath special constants.
 const pathThis = "$this"
 
+// primitiveValueKey holds the value of a primitive occurrence in the map
+// slicing matches for it (see occurrences); "$" keeps it apart from the
+// element names of its shadow element.
+const primitiveValueKey = "$value"
+
 // Validator validates slicing constraints for FHIR resources.
 type Validator struct {
 	registry *registry.Registry
+	// ctxCache caches slicing contexts by SD URL
+	ctxCache sync.Map // SD URL|version -> []Context
 }
 
 // New creates a new slicing validator.
@@ -35,6 +45,7 @@ type SliceInfo struct {
 	Children   []*registry.ElementDefinition // Child ElementDefinitions of this slice
 	Min        uint32                        // Minimum cardinality for this slice
 	Max        string                        // Maximum cardinality ("*" = unbounded)
+	Contexts   []Context                     // Slicing of descendants within this slice (e.g., component:SystolicBP.code.coding)
 }
 
 // Context contains slicing information for an element path.
@@ -75,10 +86,11 @@ func (v *Validator) ValidateData(resource map[string]any, sd *registry.Structure
 	}
 
 	// Extract all slicing contexts from the StructureDefinition
-	contexts := v.extractContexts(sd)
+	contexts := v.getOrExtractContexts(sd)
 
 	// Validate each slicing context against the resource
 	for _, ctx := range contexts {
+		validateProfileConsistency(ctx, sd.URL, result)
 		v.validateContext(resource, resourceType, resourceType, ctx, result)
 	}
 
@@ -86,20 +98,86 @@ func (v *Validator) ValidateData(resource map[string]any, sd *registry.Structure
 	v.validateContained(resource, resourceType, result)
 }
 
+// CanSkip reports whether slicing validation has nothing to check for a resource:
+// it has no contained resources, no slice of the profile requires an occurrence,
+// and no sliced path is present in the data. Used as a fast-path pre-scan.
+func (v *Validator) CanSkip(resource map[string]any, sd *registry.StructureDefinition) bool {
+	if sd == nil || sd.Snapshot == nil {
+		return true
+	}
+
+	if _, ok := resource["contained"]; ok {
+		return false
+	}
+
+	resourceType, _ := resource["resourceType"].(string)
+	for _, ctx := range v.getOrExtractContexts(sd) {
+		if ctx.prohibitsElement() && ctx.EntryDef.Min > 0 {
+			return false
+		}
+		for _, slice := range ctx.Slices {
+			if slice.Min > 0 {
+				return false
+			}
+		}
+		if len(v.getElementsAtPath(resource, ctx.Path, resourceType)) > 0 ||
+			len(v.getElementsAtPath(resource, shadowPath(ctx.Path), resourceType)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Contexts returns the slicing defined by sd: its sliced elements outside
+// slices, each with its slices and the slicing nested within them.
+func (v *Validator) Contexts(sd *registry.StructureDefinition) []Context {
+	if sd == nil || sd.Snapshot == nil {
+		return nil
+	}
+	return v.getOrExtractContexts(sd)
+}
+
+// MatchSlice returns the name of the first slice of ctx whose discriminators
+// element matches, or "" when it matches none.
+func (v *Validator) MatchSlice(element map[string]any, ctx Context) string {
+	return v.matchElementToSlice(element, ctx)
+}
+
+// getOrExtractContexts returns cached slicing contexts or extracts and caches them.
+func (v *Validator) getOrExtractContexts(sd *registry.StructureDefinition) []Context {
+	if sd.URL == "" {
+		return v.extractContexts(sd)
+	}
+
+	key := sd.URL + "|" + sd.Version
+	if cached, ok := v.ctxCache.Load(key); ok {
+		if contexts, ok := cached.([]Context); ok {
+			return contexts
+		}
+	}
+
+	contexts := v.extractContexts(sd)
+	v.ctxCache.Store(key, contexts)
+	return contexts
+}
+
 // extractContexts extracts all slicing definitions from a StructureDefinition.
+// Slicing defined inside a slice (e.g., Observation.component:SystolicBP.code.coding)
+// applies only to the elements matched by that slice, so it is attached to the
+// slice's Contexts instead of being returned at the top level.
 func (v *Validator) extractContexts(sd *registry.StructureDefinition) []Context {
-	contexts := make([]Context, 0, 8)
-
-	// Map to group elements by their sliced parent path
-	slicesByPath := make(map[string][]SliceInfo)
-	entryByPath := make(map[string]*registry.ElementDefinition)
+	// Entries and slices are grouped by the element ID of the sliced element,
+	// which tells apart the same path sliced within different slices
+	slicesByID := make(map[string][]SliceInfo)
+	var entries []*registry.ElementDefinition
 
 	for i := range sd.Snapshot.Element {
 		elem := &sd.Snapshot.Element[i]
 
-		// Check if this element defines slicing
-		if elem.Slicing != nil {
-			entryByPath[elem.Path] = elem
+		// Check if this element defines slicing (re-slicing entries are slices
+		// themselves; their slices are grouped with the base entry)
+		if elem.Slicing != nil && (elem.SliceName == nil || *elem.SliceName == "") {
+			entries = append(entries, elem)
 		}
 
 		// Check if this element is a slice (has sliceName)
@@ -115,29 +193,69 @@ func (v *Validator) extractContexts(sd *registry.StructureDefinition) []Context
 				Min:        elem.Min,
 				Max:        elem.Max,
 			}
-			slicesByPath[elem.Path] = append(slicesByPath[elem.Path], sliceInfo)
+			entryID := strings.TrimSuffix(elem.ID, ":"+sliceName)
+			slicesByID[entryID] = append(slicesByID[entryID], sliceInfo)
 		}
 	}
 
 	// Build Contexts from entries and their slices
-	for path, entry := range entryByPath {
-		ctx := Context{
-			Path:     path,
+	contextsByID := make(map[string]*Context, len(entries))
+	for _, entry := range entries {
+		ctx := &Context{
+			Path:     entry.Path,
 			EntryDef: entry,
 			Rules:    entry.Slicing.Rules,
-			Slices:   slicesByPath[path],
+			Slices:   slicesByID[entry.ID],
 		}
 
 		if entry.Slicing.Discriminator != nil {
 			ctx.Discriminators = entry.Slicing.Discriminator
 		}
 
-		contexts = append(contexts, ctx)
+		contextsByID[entry.ID] = ctx
+	}
+
+	// Attach slicing within slices to the innermost enclosing slice, deepest
+	// first so nested contexts are complete before they are copied
+	slices.SortFunc(entries, func(a, b *registry.ElementDefinition) int {
+		return strings.Count(b.ID, ".") - strings.Count(a.ID, ".")
+	})
+	contexts := make([]Context, 0, 8)
+	for _, entry := range entries {
+		ctx := contextsByID[entry.ID]
+		ownerID := enclosingSliceID(entry.ID)
+		if ownerID == "" {
+			contexts = append(contexts, *ctx)
+			continue
+		}
+		ownerEntryID := ownerID[:strings.LastIndex(ownerID, ":")]
+		if owner := contextsByID[ownerEntryID]; owner != nil {
+			for i := range owner.Slices {
+				if owner.Slices[i].Definition.ID == ownerID {
+					owner.Slices[i].Contexts = append(owner.Slices[i].Contexts, *ctx)
+				}
+			}
+		}
 	}
 
 	return contexts
 }
 
+// enclosingSliceID returns the ID of the innermost slice an element ID lies
+// within (e.g., "Observation.component:SystolicBP" for
+// "Observation.component:SystolicBP.code.coding"), or "" if there is none.
+func enclosingSliceID(id string) string {
+	colon := strings.LastIndex(id, ":")
+	if colon < 0 {
+		return ""
+	}
+	dot := strings.Index(id[colon:], ".")
+	if dot < 0 {
+		return ""
+	}
+	return id[:colon+dot]
+}
+
 // findSliceChildren finds ElementDefinitions that are children of a slice.
 func (v *Validator) findSliceChildren(sd *registry.StructureDefinition, sliceID string) []*registry.ElementDefinition {
 	var children []*registry.ElementDefinition
@@ -153,19 +271,117 @@ func (v *Validator) findSliceChildren(sd *registry.StructureDefinition, sliceID
 	return children
 }
 
+// sliceMinSum returns the number of occurrences the slices require together.
+func (ctx *Context) sliceMinSum() int {
+	sum := 0
+	for _, slice := range ctx.Slices {
+		sum += int(slice.Min)
+	}
+	return sum
+}
+
+// prohibitsElement reports whether the slicing leaves no room for any
+// occurrence of the sliced element: it is closed and every slice has max = 0.
+func (ctx *Context) prohibitsElement() bool {
+	if ctx.Rules != "closed" || len(ctx.Slices) == 0 {
+		return false
+	}
+	for _, slice := range ctx.Slices {
+		if slice.Max != "0" {
+			return false
+		}
+	}
+	return true
+}
+
+// validateProfileConsistency reports slicing definitions whose slice
+// cardinalities contradict the cardinality of the sliced element itself,
+// making the profile impossible to satisfy.
+func validateProfileConsistency(ctx Context, profileURL string, result *issue.Result) {
+	if ctx.EntryDef == nil {
+		return
+	}
+
+	if ctx.EntryDef.Max != "" && ctx.EntryDef.Max != "*" {
+		maxInt, err := strconv.Atoi(ctx.EntryDef.Max)
+		if sum := ctx.sliceMinSum(); err == nil && sum > maxInt {
+			result.AddWarningWithID(issue.DiagSlicingMinExceedsMax, issue.Params{
+				issue.String("profile", profileURL),
+				issue.String("path", ctx.Path),
+				issue.Int("sum", sum),
+				issue.Int("max", maxInt),
+			}, ctx.Path)
+		}
+	}
+
+	if ctx.EntryDef.Min > 0 && ctx.prohibitsElement() {
+		result.AddWarningWithID(issue.DiagSlicingRequiredClosed, issue.Params{
+			issue.String("profile", profileURL),
+			issue.String("path", ctx.Path),
+		}, ctx.Path)
+	}
+}
+
 // validateContext validates a single slicing context against resource data.
+// sdPath is the SD path of data (e.g., "Patient") and fhirPath its location.
+// Each occurrence of the sliced element's parent is validated separately, so
+// slice cardinalities apply per parent (e.g., per Observation.component).
 func (v *Validator) validateContext(
-	resource map[string]any,
+	data map[string]any,
 	sdPath string,
 	fhirPath string,
 	ctx Context,
 	result *issue.Result,
 ) {
-	// Navigate to the sliced element in the resource
-	elements := v.getElementsAtPath(resource, ctx.Path, sdPath)
-	if elements == nil {
+	parts := strings.Split(strings.TrimPrefix(ctx.Path, sdPath+"."), ".")
+	name := parts[len(parts)-1]
+	eachParent(data, parts[:len(parts)-1], fhirPath, func(parent map[string]any, parentPath string) {
+		v.validateSlicedElement(parent, name, parentPath, ctx, result)
+	})
+}
+
+// eachParent calls fn for every object reached from data by the path parts,
+// with its FHIRPath.
+func eachParent(data map[string]any, parts []string, fhirPath string, fn func(parent map[string]any, fhirPath string)) {
+	if len(parts) == 0 {
+		fn(data, fhirPath)
+		return
+	}
+	elementPath := fhirPath + "." + parts[0]
+	switch value := data[parts[0]].(type) {
+	case map[string]any:
+		eachParent(value, parts[1:], elementPath, fn)
+	case []any:
+		for i, item := range value {
+			if m, ok := item.(map[string]any); ok {
+				eachParent(m, parts[1:], fmt.Sprintf("%s[%d]", elementPath, i), fn)
+			}
+		}
+	}
+}
+
+// validateSlicedElement matches the occurrences of a sliced element within
+// one parent to the slices and checks the slice cardinalities.
+func (v *Validator) validateSlicedElement(
+	parent map[string]any,
+	name string,
+	fhirPath string,
+	ctx Context,
+	result *issue.Result,
+) {
+	value, shadow := parent[name], parent["_"+name]
+	if value == nil && shadow == nil {
 		return // Element not present, cardinality validator handles this
 	}
+	elements, isArray := occurrences(value, shadow)
+	elementPath := fhirPath + "." + name
+
+	// Closed slicing where no slice admits an occurrence prohibits the element
+	// outright; report that once instead of a no-match or max error per item.
+	if ctx.prohibitsElement() {
+		result.AddErrorWithID(issue.DiagSlicingProhibited, issue.Params{issue.String("path", elementPath)}, elementPath)
+		return
+	}
 
 	// Track which slice each element matches
 	sliceMatches := make(map[int]string) // element index -> slice name
@@ -184,20 +400,21 @@ func (v *Validator) validateContext(
 			sliceCounts[matchedSlice]++
 		} else if ctx.Rules == "closed" {
 			// Element doesn't match any slice in closed slicing
-			elemPath := fmt.Sprintf("%s.%s[%d]", fhirPath, v.lastPathSegment(ctx.Path), i)
-			result.AddErrorWithID(issue.DiagSlicingNoMatch, nil, elemPath)
+			result.AddErrorWithID(issue.DiagSlicingNoMatch, nil, fmt.Sprintf("%s[%d]", elementPath, i))
 		}
 	}
 
 	// Validate cardinality for each slice
 	for _, slice := range ctx.Slices {
 		count := sliceCounts[slice.Name]
-		slicePath := fmt.Sprintf("%s.%s:%s", fhirPath, v.lastPathSegment(ctx.Path), slice.Name)
+		slicePath := fmt.Sprintf("%s:%s", elementPath, slice.Name)
 
 		// Check minimum (safe comparison avoiding overflow)
 		if count < 0 || count < int(slice.Min) {
-			result.AddErrorWithID(issue.DiagSlicingCardinalityMin, map[string]any{
-				"path": slicePath, "min": slice.Min, "count": count,
+			result.AddErrorWithID(issue.DiagSlicingCardinalityMin, issue.Params{
+				issue.String("path", slicePath),
+				issue.Int("min", int(slice.Min)),
+				issue.Int("count", count),
 			}, slicePath)
 		}
 
@@ -205,8 +422,10 @@ func (v *Validator) validateContext(
 		if slice.Max != "*" {
 			maxInt, err := strconv.Atoi(slice.Max)
 			if err == nil && count > maxInt {
-				result.AddErrorWithID(issue.DiagSlicingCardinalityMax, map[string]any{
-					"path": slicePath, "max": maxInt, "count": count,
+				result.AddErrorWithID(issue.DiagSlicingCardinalityMax, issue.Params{
+					issue.String("path", slicePath),
+					issue.Int("max", maxInt),
+					issue.Int("count", count),
 				}, slicePath)
 			}
 		}
@@ -214,6 +433,87 @@ func (v *Validator) validateContext(
 
 	// Validate cardinality of child elements within matched slices
 	v.validateSliceChildren(elements, sliceMatches, ctx, fhirPath, result)
+
+	// Validate slicing defined within the matched slices
+	for i := range elements {
+		slice := ctx.sliceNamed(sliceMatches[i])
+		if slice == nil || len(slice.Contexts) == 0 {
+			continue
+		}
+		elemMap, _ := elements[i].(map[string]any)
+		itemPath := elementPath
+		if isArray {
+			itemPath = fmt.Sprintf("%s[%d]", elementPath, i)
+		}
+		for _, nested := range slice.Contexts {
+			v.validateContext(elemMap, ctx.Path, itemPath, nested, result)
+		}
+	}
+}
+
+// occurrences returns the occurrences of an element, given its value and,
+// for a primitive element, its shadow element (e.g., "_given"), and whether
+// the element is an array. A primitive occurrence is returned as a map of its
+// shadow element's id and extension plus its value under primitiveValueKey,
+// so that discriminators and slice children apply to it like to a complex
+// element; an occurrence with neither value nor shadow element is nil.
+func occurrences(value, shadow any) ([]any, bool) {
+	elements, isArray := value.([]any)
+	shadows, shadowArray := shadow.([]any)
+	if !isArray {
+		isArray = shadowArray
+		if value != nil {
+			elements = []any{value}
+		}
+		if shadow != nil && !shadowArray {
+			shadows = []any{shadow}
+		}
+	}
+
+	n := max(len(elements), len(shadows))
+	result := make([]any, n)
+	for i := range n {
+		var item, ext any
+		if i < len(elements) {
+			item = elements[i]
+		}
+		if i < len(shadows) {
+			ext = shadows[i]
+		}
+		if m, ok := item.(map[string]any); ok {
+			result[i] = m
+			continue
+		}
+		if item == nil && ext == nil {
+			continue
+		}
+		primitive := make(map[string]any, 3)
+		if m, ok := ext.(map[string]any); ok {
+			maps.Copy(primitive, m)
+		}
+		if item != nil {
+			primitive[primitiveValueKey] = item
+		}
+		result[i] = primitive
+	}
+	return result, isArray
+}
+
+// shadowPath returns the path of the shadow element of a primitive element
+// (e.g., "Patient.name._given" for "Patient.name.given").
+func shadowPath(path string) string {
+	i := strings.LastIndex(path, ".")
+	return path[:i+1] + "_" + path[i+1:]
+}
+
+// sliceNamed returns the slice with a name, or nil.
+func (ctx *Context) sliceNamed(name string) *SliceInfo {
+	for i := range ctx.Slices {
+		if ctx.Slices[i].Name == name {
+			return &ctx.Slices[i]
+		}
+	}
+	return nil
 }
 
 // validateSliceChildren validates cardinality of child elements for each matched slice instance.
@@ -250,9 +550,12 @@ func (v *Validator) validateSliceChildren(
 			if child.SliceName != nil && *child.SliceName != "" {
 				continue
 			}
-
-			// Extract child element name from path (last segment)
+			// Extract child element name from path (last segment). Children
+			// lists every descendant; deeper ones are checked with their parent
 			childName := child.Path[strings.LastIndex(child.Path, ".")+1:]
+			if child.Path != ctx.Path+"."+childName {
+				continue
+			}
 
 			// Count occurrences in the resource element
 			count := countElement(elemMap, childName)
@@ -261,8 +564,10 @@ func (v *Validator) validateSliceChildren(
 			if count < int(child.Min) {
 				childFHIRPath := fmt.Sprintf("%s.%s", elemPath, childName)
 				sliceChildPath := fmt.Sprintf("%s:%s.%s", ctx.Path, sliceName, childName)
-				result.AddErrorWithID(issue.DiagSlicingCardinalityMin, map[string]any{
-					"path": sliceChildPath, "min": child.Min, "count": count,
+				result.AddErrorWithID(issue.DiagSlicingCardinalityMin, issue.Params{
+					issue.String("path", sliceChildPath),
+					issue.Int("min", int(child.Min)),
+					issue.Int("count", count),
 				}, childFHIRPath)
 			}
 
@@ -272,8 +577,10 @@ func (v *Validator) validateSliceChildren(
 				if err == nil && count > maxInt {
 					childFHIRPath := fmt.Sprintf("%s.%s", elemPath, childName)
 					sliceChildPath := fmt.Sprintf("%s:%s.%s", ctx.Path, sliceName, childName)
-					result.AddErrorWithID(issue.DiagSlicingCardinalityMax, map[string]any{
-						"path": sliceChildPath, "max": maxInt, "count": count,
+					result.AddErrorWithID(issue.DiagSlicingCardinalityMax, issue.Params{
+						issue.String("path", sliceChildPath),
+						issue.Int("max", maxInt),
+						issue.Int("count", count),
 					}, childFHIRPath)
 				}
 			}
@@ -360,14 +667,7 @@ func (v *Validator) evaluateValueDiscriminator(element map[string]any, path stri
 
 // evaluatePatternDiscriminator checks if element matches a "pattern" discriminator.
 func (v *Validator) evaluatePatternDiscriminator(element map[string]any, path string, slice SliceInfo) bool {
-	var actualValue any
-
-	if path == pathThis {
-		actualValue = element
-	} else {
-		actualValue = v.getValueAtPath(element, path)
-	}
-
+	actualValue := v.getValueAtPath(element, path)
 	if actualValue == nil {
 		return false
 	}
@@ -618,9 +918,13 @@ func (v *Validator) getResourceProfiles(resource map[string]any) []string {
 }
 
 // getValueAtPath extracts a value from an element at a given path.
-// Handles arrays by checking if any element matches.
+// Handles arrays by checking if any element matches. $this of a primitive
+// occurrence is its value.
 func (v *Validator) getValueAtPath(element map[string]any, path string) any {
 	if path == pathThis {
+		if value, ok := element[primitiveValueKey]; ok {
+			return value
+		}
 		return element
 	}
 
@@ -675,9 +979,28 @@ func (v *Validator) getFixedValueForPath(slice SliceInfo, path string) json.RawM
 		}
 	}
 
+	// Extension slices whose snapshot leaves out their url (e.g., US Core's
+	// Patient.extension:race) are identified by the profile of their type.
+	if path == "url" {
+		if url := extensionProfile(slice.Definition); url != "" {
+			val, _ := json.Marshal(url)
+			return val
+		}
+	}
+
 	return nil
 }
 
+// extensionProfile returns the URL of the extension a slice of Extension
+// type is profiled to, without its version, or "".
+func extensionProfile(def *registry.ElementDefinition) string {
+	if len(def.Type) != 1 || def.Type[0].Code != "Extension" || len(def.Type[0].Profile) != 1 {
+		return ""
+	}
+	url, _, _ := strings.Cut(def.Type[0].Profile[0], "|")
+	return url
+}
+
 // getPatternValueForPath finds the pattern[x] value for a discriminator path in a slice.
 func (v *Validator) getPatternValueForPath(slice SliceInfo, path string) json.RawMessage {
 	// First check the slice definition itself
@@ -827,7 +1150,7 @@ func (v *Validator) validateContained(resource map[string]any, baseFhirPath stri
 		containedFhirPath := fmt.Sprintf("%s.contained[%d]", baseFhirPath, i)
 
 		// Extract and validate slicing contexts for contained resource
-		contexts := v.extractContexts(containedSD)
+		contexts := v.getOrExtractContexts(containedSD)
 		for _, ctx := range contexts {
 			v.validateContext(resourceMap, resourceType, containedFhirPath, ctx, result)
 		}
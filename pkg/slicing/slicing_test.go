@@ -2,6 +2,7 @@ package slicing
 
 import (
 	"encoding/json"
+	"maps"
 	"testing"
 
 	"github.com/gofhir/validator/pkg/issue"
@@ -401,6 +402,13 @@ func TestSliceChildCardinality(t *testing.T) {
 		Min:  0,
 		Max:  "1",
 	}
+	// A grandchild is checked with its parent (period), not on the slice
+	childPeriodStart := &registry.ElementDefinition{
+		ID:   "Patient.name:NombreSocial.period.start",
+		Path: "Patient.name.period.start",
+		Min:  1,
+		Max:  "1",
+	}
 
 	ctx := Context{
 		Path: "Patient.name",
@@ -410,6 +418,7 @@ func TestSliceChildCardinality(t *testing.T) {
 				Children: []*registry.ElementDefinition{
 					childGiven,
 					childFamily,
+					childPeriodStart,
 				},
 				Min: 0,
 				Max: "*",
@@ -845,3 +854,221 @@ func TestSliceChildCardinality_PatternCodeDiscriminator(t *testing.T) {
 		}
 	})
 }
+
+func TestSliceCardinalityAgainstBase(t *testing.T) {
+	validator := &Validator{}
+	slice := func(name string, minCard uint32, maxCard string) SliceInfo {
+		return SliceInfo{Name: name, Min: minCard, Max: maxCard}
+	}
+	code := func(c string) map[string]any { return map[string]any{"code": c} }
+
+	t.Run("slice minimums exceed base max", func(t *testing.T) {
+		ctx := Context{
+			Path:     "Patient.identifier",
+			EntryDef: &registry.ElementDefinition{Path: "Patient.identifier", Min: 0, Max: "2"},
+			Rules:    "open",
+			Slices:   []SliceInfo{slice("a", 2, "*"), slice("b", 1, "1")},
+		}
+		result := issue.NewResult()
+		validateProfileConsistency(ctx, "http://example.org/p", result)
+
+		if len(result.Issues) != 1 || result.Issues[0].MessageID != string(issue.DiagSlicingMinExceedsMax) {
+			t.Fatalf("expected %s, got %v", issue.DiagSlicingMinExceedsMax, result.Issues)
+		}
+		if result.Issues[0].Severity != issue.SeverityWarning {
+			t.Errorf("expected warning severity, got %s", result.Issues[0].Severity)
+		}
+	})
+
+	t.Run("consistent slice minimums", func(t *testing.T) {
+		ctx := Context{
+			Path:     "Patient.identifier",
+			EntryDef: &registry.ElementDefinition{Path: "Patient.identifier", Min: 0, Max: "3"},
+			Slices:   []SliceInfo{slice("a", 2, "*"), slice("b", 1, "1")},
+		}
+		result := issue.NewResult()
+		validateProfileConsistency(ctx, "http://example.org/p", result)
+
+		if len(result.Issues) != 0 {
+			t.Errorf("expected no issues, got %v", result.Issues)
+		}
+	})
+
+	prohibited := Context{
+		Path:           "Patient.identifier",
+		EntryDef:       &registry.ElementDefinition{Path: "Patient.identifier", Min: 1, Max: "*"},
+		Discriminators: []registry.Discriminator{{Type: "value", Path: "code"}},
+		Rules:          "closed",
+		Slices:         []SliceInfo{slice("a", 0, "0"), slice("b", 0, "0")},
+	}
+
+	t.Run("required element with all slices prohibited", func(t *testing.T) {
+		result := issue.NewResult()
+		validateProfileConsistency(prohibited, "http://example.org/p", result)
+
+		if len(result.Issues) != 1 || result.Issues[0].MessageID != string(issue.DiagSlicingRequiredClosed) {
+			t.Fatalf("expected %s, got %v", issue.DiagSlicingRequiredClosed, result.Issues)
+		}
+	})
+
+	t.Run("closed slicing with all slices max 0 prohibits the element", func(t *testing.T) {
+		resource := map[string]any{
+			"resourceType": "Patient",
+			"identifier":   []any{code("x"), code("y")},
+		}
+		result := issue.NewResult()
+		validator.validateContext(resource, "Patient", "Patient", prohibited, result)
+
+		if len(result.Issues) != 1 || result.Issues[0].MessageID != string(issue.DiagSlicingProhibited) {
+			t.Fatalf("expected a single %s, got %v", issue.DiagSlicingProhibited, result.Issues)
+		}
+		if got := result.Issues[0].Expression[0]; got != "Patient.identifier" {
+			t.Errorf("expected expression Patient.identifier, got %s", got)
+		}
+	})
+
+	t.Run("open slicing with all slices max 0 allows other elements", func(t *testing.T) {
+		open := prohibited
+		open.Rules = "open"
+		if open.prohibitsElement() {
+			t.Error("open slicing must not prohibit the element")
+		}
+	})
+}
+
+func TestNestedSlicing(t *testing.T) {
+	// Observation.component sliced by code.text, and the interpretation of the
+	// "a" component sliced by text: the nested slicing applies to each
+	// component matched by the slice, not to the other components.
+	var sd registry.StructureDefinition
+	if err := json.Unmarshal([]byte(`{
+		"url": "http://example.org/StructureDefinition/nested",
+		"type": "Observation",
+		"snapshot": {"element": [
+			{"id": "Observation", "path": "Observation"},
+			{"id": "Observation.component", "path": "Observation.component", "min": 0, "max": "*",
+				"slicing": {"discriminator": [{"type": "value", "path": "code.text"}], "rules": "open"}},
+			{"id": "Observation.component:a", "path": "Observation.component", "sliceName": "a", "min": 1, "max": "*"},
+			{"id": "Observation.component:a.code.text", "path": "Observation.component.code.text", "fixedString": "a"},
+			{"id": "Observation.component:a.interpretation", "path": "Observation.component.interpretation", "min": 0, "max": "*",
+				"slicing": {"discriminator": [{"type": "value", "path": "text"}], "rules": "open"}},
+			{"id": "Observation.component:a.interpretation:x", "path": "Observation.component.interpretation", "sliceName": "x", "min": 1, "max": "1"},
+			{"id": "Observation.component:a.interpretation:x.text", "path": "Observation.component.interpretation.text", "fixedString": "x"},
+			{"id": "Observation.component:b", "path": "Observation.component", "sliceName": "b", "min": 0, "max": "1"},
+			{"id": "Observation.component:b.code.text", "path": "Observation.component.code.text", "fixedString": "b"}
+		]}
+	}`), &sd); err != nil {
+		t.Fatal(err)
+	}
+
+	validator := &Validator{}
+	contexts := validator.extractContexts(&sd)
+	if len(contexts) != 1 || contexts[0].Path != "Observation.component" {
+		t.Fatalf("expected only the Observation.component context at the top level, got %d", len(contexts))
+	}
+
+	component := func(code, interpretation string) map[string]any {
+		c := map[string]any{"code": map[string]any{"text": code}}
+		if interpretation != "" {
+			c["interpretation"] = []any{map[string]any{"text": interpretation}}
+		}
+		return c
+	}
+	resource := map[string]any{
+		"resourceType": "Observation",
+		"component":    []any{component("a", "x"), component("a", "y"), component("b", "y")},
+	}
+
+	result := issue.NewResult()
+	validator.ValidateData(resource, &sd, result)
+
+	if len(result.Issues) != 1 || result.Issues[0].MessageID != string(issue.DiagSlicingCardinalityMin) {
+		t.Fatalf("expected a single %s, got %v", issue.DiagSlicingCardinalityMin, result.Issues)
+	}
+	if got := result.Issues[0].Expression[0]; got != "Observation.component[1].interpretation:x" {
+		t.Errorf("expected expression Observation.component[1].interpretation:x, got %s", got)
+	}
+}
+
+func TestPrimitiveSlicing(t *testing.T) {
+	// Patient.name.given sliced by its value: "Ann" at most once, carrying an
+	// extension in its shadow element _given
+	var sd registry.StructureDefinition
+	if err := json.Unmarshal([]byte(`{
+		"url": "http://example.org/StructureDefinition/given",
+		"type": "Patient",
+		"snapshot": {"element": [
+			{"id": "Patient", "path": "Patient"},
+			{"id": "Patient.name.given", "path": "Patient.name.given", "min": 0, "max": "*",
+				"slicing": {"discriminator": [{"type": "value", "path": "$this"}], "rules": "closed"}},
+			{"id": "Patient.name.given:ann", "path": "Patient.name.given", "sliceName": "ann", "min": 1, "max": "1", "fixedString": "Ann"},
+			{"id": "Patient.name.given:ann.extension", "path": "Patient.name.given.extension", "min": 1, "max": "*"},
+			{"id": "Patient.name.given:bo", "path": "Patient.name.given", "sliceName": "bo", "min": 0, "max": "1", "patternString": "Bo"}
+		]}
+	}`), &sd); err != nil {
+		t.Fatal(err)
+	}
+	ext := map[string]any{"extension": []any{map[string]any{"url": "http://example.org/nick", "valueString": "A"}}}
+	patient := func(given, shadow []any) map[string]any {
+		name := map[string]any{}
+		if given != nil {
+			name["given"] = given
+		}
+		if shadow != nil {
+			name["_given"] = shadow
+		}
+		return map[string]any{"resourceType": "Patient", "name": []any{name}}
+	}
+
+	tests := []struct {
+		name     string
+		resource map[string]any
+		want     map[string]issue.DiagnosticID // expression -> diagnostic
+	}{
+		{
+			name:     "matching values with extension",
+			resource: patient([]any{"Bo", "Ann"}, []any{nil, ext}),
+			want:     map[string]issue.DiagnosticID{},
+		},
+		{
+			name:     "slice child in shadow element missing",
+			resource: patient([]any{"Ann"}, nil),
+			want:     map[string]issue.DiagnosticID{"Patient.name[0].given[0].extension": issue.DiagSlicingCardinalityMin},
+		},
+		{
+			name:     "value slice exceeded and unmatched value",
+			resource: patient([]any{"Ann", "Cy", "Ann"}, []any{ext, nil, ext}),
+			want: map[string]issue.DiagnosticID{
+				"Patient.name[0].given:ann": issue.DiagSlicingCardinalityMax,
+				"Patient.name[0].given[1]":  issue.DiagSlicingNoMatch,
+			},
+		},
+		{
+			name:     "extension without value",
+			resource: patient(nil, []any{ext}),
+			want: map[string]issue.DiagnosticID{
+				"Patient.name[0].given:ann": issue.DiagSlicingCardinalityMin,
+				"Patient.name[0].given[0]":  issue.DiagSlicingNoMatch,
+			},
+		},
+	}
+
+	validator := &Validator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if validator.CanSkip(tt.resource, &sd) {
+				t.Fatal("CanSkip() = true for a sliced primitive")
+			}
+			result := issue.NewResult()
+			validator.ValidateData(tt.resource, &sd, result)
+
+			got := make(map[string]issue.DiagnosticID)
+			for _, iss := range result.Issues {
+				got[iss.Expression[0]] = issue.DiagnosticID(iss.MessageID)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("issues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+//go:build bundle
+
+package specs
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// bundle holds the IG packages and manifest written by scripts/bundle-igs.sh.
+//
+//go:embed bundle
+var bundle embed.FS
+
+func init() {
+	sub, err := fs.Sub(bundle, "bundle")
+	if err != nil {
+		panic(err)
+	}
+	bundleFS = sub
+}
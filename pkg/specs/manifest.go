@@ -0,0 +1,137 @@
+package specs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// bundleManifestFile is the manifest written by scripts/bundle-igs.sh next to
+// the bundled IG packages.
+const bundleManifestFile = "manifest.json"
+
+// bundleFS holds the IG packages of a bundle build (built with -tags bundle,
+// see `make bundle`). It is nil in regular builds.
+var bundleFS fs.FS
+
+// PackageInfo describes a package embedded in the binary.
+type PackageInfo struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	FHIRVersion string `json:"fhirVersion"`
+	File        string `json:"file,omitempty"` // File within the bundle (bundled IGs only)
+	SHA256      string `json:"sha256"`
+	Size        int    `json:"size"`
+	Bundled     bool   `json:"bundled,omitempty"` // Added by a bundle build rather than part of the core specs
+}
+
+// IsBundle reports whether the binary was built with bundled IG packages.
+func IsBundle() bool {
+	return bundleFS != nil
+}
+
+// Manifest lists every embedded package: the core specification packages,
+// with checksums computed from the embedded data, followed by the bundled IGs
+// as recorded in the bundle manifest. Use Verify to check the bundled IGs
+// against their recorded checksums.
+func Manifest() ([]PackageInfo, error) {
+	infos := make([]PackageInfo, 0, len(corePackages))
+	for _, p := range corePackages {
+		infos = append(infos, PackageInfo{
+			Name:        p.name,
+			Version:     p.version,
+			FHIRVersion: p.fhirVersion,
+			SHA256:      checksum(p.data),
+			Size:        len(p.data),
+		})
+	}
+
+	if bundleFS == nil {
+		return infos, nil
+	}
+	bundled, err := readBundleManifest(bundleFS)
+	if err != nil {
+		return nil, err
+	}
+	return append(infos, bundled...), nil
+}
+
+// Verify checks every bundled IG package against the checksum and size
+// recorded in the bundle manifest. It returns nil for regular builds.
+func Verify() error {
+	if bundleFS == nil {
+		return nil
+	}
+	_, err := loadBundle(bundleFS, "")
+	return err
+}
+
+// GetBundledPackages returns the .tgz data of the bundled IG packages for a
+// FHIR version, after verifying their checksums. Returns nil for regular builds.
+func GetBundledPackages(version string) ([][]byte, error) {
+	if bundleFS == nil {
+		return nil, nil
+	}
+	return loadBundle(bundleFS, version)
+}
+
+// readBundleManifest reads and parses the bundle manifest.
+func readBundleManifest(fsys fs.FS) ([]PackageInfo, error) {
+	data, err := fs.ReadFile(fsys, bundleManifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle manifest: %w", err)
+	}
+
+	var infos []PackageInfo
+	if err := json.Unmarshal(data, &infos); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	for i := range infos {
+		infos[i].Bundled = true
+	}
+	return infos, nil
+}
+
+// loadBundle reads the bundled packages for a FHIR version (all versions when
+// empty) and verifies each one against the manifest.
+func loadBundle(fsys fs.FS, version string) ([][]byte, error) {
+	infos, err := readBundleManifest(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages [][]byte
+	var errs []error
+	for _, info := range infos {
+		if version != "" && info.FHIRVersion != version {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Clean(info.File))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s#%s: %w", info.Name, info.Version, err))
+			continue
+		}
+		if sum := checksum(data); sum != info.SHA256 || len(data) != info.Size {
+			errs = append(errs, fmt.Errorf("%s#%s: checksum mismatch (manifest %s, %d bytes; embedded %s, %d bytes)",
+				info.Name, info.Version, info.SHA256, info.Size, sum, len(data)))
+			continue
+		}
+		packages = append(packages, data)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return packages, nil
+}
+
+// checksum returns the hex-encoded SHA-256 of data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,92 @@
+package specs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestManifestCorePackages(t *testing.T) {
+	infos, err := Manifest()
+	if err != nil {
+		t.Fatalf("Manifest() error: %v", err)
+	}
+	if len(infos) != len(corePackages) {
+		t.Fatalf("Manifest() returned %d packages, want %d", len(infos), len(corePackages))
+	}
+	for _, info := range infos {
+		if len(info.SHA256) != 64 || info.Size == 0 || info.Bundled {
+			t.Errorf("unexpected core package info: %+v", info)
+		}
+	}
+	if err := Verify(); err != nil {
+		t.Errorf("Verify() on a regular build = %v, want nil", err)
+	}
+}
+
+func TestLoadBundle(t *testing.T) {
+	usCore := []byte("us core package")
+	ips := []byte("ips package")
+
+	manifest := func(infos ...PackageInfo) *fstest.MapFile {
+		data, _ := json.Marshal(infos)
+		return &fstest.MapFile{Data: data}
+	}
+	usCoreInfo := PackageInfo{
+		Name: "hl7.fhir.us.core", Version: "6.1.0", FHIRVersion: "4.0.1",
+		File: "hl7.fhir.us.core-6.1.0.tgz", SHA256: checksum(usCore), Size: len(usCore),
+	}
+	ipsInfo := PackageInfo{
+		Name: "hl7.fhir.uv.ips", Version: "1.1.0", FHIRVersion: "5.0.0",
+		File: "hl7.fhir.uv.ips-1.1.0.tgz", SHA256: checksum(ips), Size: len(ips),
+	}
+
+	fsys := fstest.MapFS{
+		bundleManifestFile:           manifest(usCoreInfo, ipsInfo),
+		"hl7.fhir.us.core-6.1.0.tgz": {Data: usCore},
+		"hl7.fhir.uv.ips-1.1.0.tgz":  {Data: ips},
+	}
+
+	t.Run("filters by FHIR version", func(t *testing.T) {
+		packages, err := loadBundle(fsys, "4.0.1")
+		if err != nil {
+			t.Fatalf("loadBundle() error: %v", err)
+		}
+		if len(packages) != 1 || string(packages[0]) != string(usCore) {
+			t.Errorf("loadBundle(4.0.1) = %q, want only US Core", packages)
+		}
+	})
+
+	t.Run("all versions", func(t *testing.T) {
+		packages, err := loadBundle(fsys, "")
+		if err != nil || len(packages) != 2 {
+			t.Errorf("loadBundle() = %d packages, %v; want 2, nil", len(packages), err)
+		}
+	})
+
+	t.Run("tampered package", func(t *testing.T) {
+		tampered := fstest.MapFS{
+			bundleManifestFile:           manifest(usCoreInfo),
+			"hl7.fhir.us.core-6.1.0.tgz": {Data: []byte("us core packagE")},
+		}
+		_, err := loadBundle(tampered, "4.0.1")
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Errorf("loadBundle() error = %v, want checksum mismatch", err)
+		}
+	})
+
+	t.Run("missing package", func(t *testing.T) {
+		missing := fstest.MapFS{bundleManifestFile: manifest(usCoreInfo)}
+		if _, err := loadBundle(missing, ""); err == nil {
+			t.Error("loadBundle() with a missing package should fail")
+		}
+	})
+
+	t.Run("bundled flag", func(t *testing.T) {
+		infos, err := readBundleManifest(fsys)
+		if err != nil || len(infos) != 2 || !infos[0].Bundled {
+			t.Errorf("readBundleManifest() = %+v, %v", infos, err)
+		}
+	})
+}
@@ -4,6 +4,9 @@
 // filtered from the full FHIR NPM packages to minimize binary size.
 //
 // R4 and R4B share the same terminology and extensions packages.
+//
+// Builds tagged "bundle" additionally embed the IG packages and checksum
+// manifest written to pkg/specs/bundle by `make bundle`; see Manifest and Verify.
 package specs
 
 import _ "embed"
@@ -35,6 +38,21 @@ var embeddedPackages = map[string][][]byte{
 	"5.0.0": {r5Core, terminologyR5, extensionsR5},
 }
 
+// corePackages describes the embedded packages for the manifest, in the same
+// order as embeddedPackages.
+var corePackages = []struct {
+	name, version, fhirVersion string
+	data                       []byte
+}{
+	{"hl7.fhir.r4.core", "4.0.1", "4.0.1", r4Core},
+	{"hl7.terminology.r4", "7.0.1", "4.0.1", terminologyR4},
+	{"hl7.fhir.uv.extensions.r4", "5.2.0", "4.0.1", extensionsR4},
+	{"hl7.fhir.r4b.core", "4.3.0", "4.3.0", r4bCore},
+	{"hl7.fhir.r5.core", "5.0.0", "5.0.0", r5Core},
+	{"hl7.terminology.r5", "7.0.1", "5.0.0", terminologyR5},
+	{"hl7.fhir.uv.extensions.r5", "5.2.0", "5.0.0", extensionsR5},
+}
+
 // GetPackages returns the embedded .tgz data for a FHIR version.
 // Returns nil if the version is not embedded.
 func GetPackages(version string) [][]byte {
@@ -0,0 +1,201 @@
+// Package strictjson checks the JSON syntax rules that encoding/json does not
+// enforce when it parses a resource:
+//
+//   - duplicate property names in an object (encoding/json keeps the last one)
+//   - data after the end of the resource
+//   - invalid UTF-8 (encoding/json replaces it with U+FFFD)
+//
+// Check scans the raw bytes once, before the resource is parsed. Like the
+// limits scan it is a tokenizer only; other malformed JSON is left for the
+// parser to report.
+package strictjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// Check scans a resource and adds a fatal issue to result for each violation.
+// It returns false if any was found, in which case the resource must not be
+// validated further.
+func Check(data []byte, result *issue.Result) bool {
+	if !utf8.Valid(data) {
+		result.AddErrorWithID(issue.DiagJSONInvalidUTF8, issue.Params{issue.Int("offset", invalidUTF8Offset(data))})
+		return false
+	}
+
+	s := scanner{data: data}
+	s.scan()
+
+	root := s.resourceType
+	if root == "" {
+		root = "$this"
+	}
+	for _, dup := range s.duplicates {
+		result.AddErrorWithID(issue.DiagJSONDuplicateKey, issue.Params{issue.String("name", dup.name)}, root+dup.path)
+	}
+	if s.trailing >= 0 {
+		result.AddErrorWithID(issue.DiagJSONTrailingData, issue.Params{issue.Int("offset", s.trailing)})
+	}
+	return len(s.duplicates) == 0 && s.trailing < 0
+}
+
+// invalidUTF8Offset returns the byte offset of the first invalid UTF-8 sequence.
+func invalidUTF8Offset(data []byte) int {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size <= 1 {
+			return i
+		}
+		i += size
+	}
+	return len(data)
+}
+
+// duplicate is a property name repeated within an object.
+type duplicate struct {
+	name string
+	path string // FHIRPath of the property relative to the resource (e.g., ".name[0].family")
+}
+
+// frame is an open object or array.
+type frame struct {
+	array bool
+	index int                 // index of the current array item
+	key   string              // current object property
+	keys  map[string]struct{} // property names seen in the object
+}
+
+// scanner walks JSON bytes tracking open containers and property names.
+type scanner struct {
+	data []byte
+	pos  int
+
+	resourceType string
+	duplicates   []duplicate
+	trailing     int // offset of data after the top-level value (-1 = none)
+}
+
+// scan runs until the end of data, recording duplicates and trailing data.
+func (s *scanner) scan() {
+	s.trailing = -1
+	var stack []*frame
+	expectKey := false
+	started := false
+
+	for ; s.pos < len(s.data); s.pos++ {
+		c := s.data[s.pos]
+		if isSpace(c) {
+			continue
+		}
+		if len(stack) == 0 {
+			if started {
+				s.trailing = s.pos
+				return
+			}
+			if c != '{' && c != '[' {
+				return // Not a JSON object or array; left for the parser
+			}
+			started = true
+		}
+
+		switch c {
+		case '{', '[':
+			stack = append(stack, &frame{array: c == '['})
+			expectKey = c == '{'
+		case '}', ']':
+			stack = stack[:len(stack)-1]
+			expectKey = false
+		case ',':
+			top := stack[len(stack)-1]
+			if top.array {
+				top.index++
+			}
+			expectKey = !top.array
+		case '"':
+			start := s.pos
+			s.skipString()
+			if !expectKey {
+				if len(stack) == 1 && stack[0].key == "resourceType" {
+					s.resourceType = unquote(s.data[start : s.pos+1])
+				}
+				continue
+			}
+			expectKey = false
+			top := stack[len(stack)-1]
+			top.key = unquote(s.data[start : s.pos+1])
+			if top.keys == nil {
+				top.keys = make(map[string]struct{})
+			}
+			if _, seen := top.keys[top.key]; seen {
+				s.duplicates = append(s.duplicates, duplicate{name: top.key, path: framePath(stack)})
+			}
+			top.keys[top.key] = struct{}{}
+		case ':':
+		default:
+			s.skipLiteral()
+		}
+	}
+}
+
+// framePath returns the FHIRPath of the current property of the innermost
+// object, relative to the resource.
+func framePath(stack []*frame) string {
+	var b strings.Builder
+	for _, f := range stack {
+		if f.array {
+			fmt.Fprintf(&b, "[%d]", f.index)
+		} else {
+			b.WriteString("." + f.key)
+		}
+	}
+	return b.String()
+}
+
+// unquote decodes a JSON string literal, falling back to its raw content.
+func unquote(literal []byte) string {
+	if len(literal) < 2 {
+		return "" // Unterminated string at the end of data
+	}
+	if bytes.IndexByte(literal, '\\') < 0 {
+		return string(literal[1 : len(literal)-1])
+	}
+	var str string
+	if err := json.Unmarshal(literal, &str); err != nil {
+		return string(literal[1 : len(literal)-1])
+	}
+	return str
+}
+
+// skipString advances pos to the closing quote of the string starting at pos.
+func (s *scanner) skipString() {
+	for s.pos++; s.pos < len(s.data); s.pos++ {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos++
+		case '"':
+			return
+		}
+	}
+	s.pos = len(s.data) - 1
+}
+
+// skipLiteral advances pos to the last byte of the number or literal at pos.
+func (s *scanner) skipLiteral() {
+	for s.pos+1 < len(s.data) {
+		switch s.data[s.pos+1] {
+		case ',', '}', ']', ':', '"', ' ', '\t', '\n', '\r':
+			return
+		}
+		s.pos++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,113 @@
+package strictjson
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "valid resource",
+			data: `{"resourceType": "Patient", "name": [{"family": "Doe", "given": ["A", "B"]}], "active": true}`,
+		},
+		{
+			name:     "duplicate top-level property",
+			data:     `{"resourceType": "Patient", "active": true, "active": false}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagJSONDuplicateKey},
+			wantPath: "Patient.active",
+		},
+		{
+			name:     "duplicate nested property",
+			data:     `{"resourceType": "Patient", "name": [{"family": "A"}, {"family": "B", "family": "C"}]}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagJSONDuplicateKey},
+			wantPath: "Patient.name[1].family",
+		},
+		{
+			name:     "duplicate written with an escape",
+			data:     `{"resourceType": "Patient", "id": "a", "\u0069d": "b"}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagJSONDuplicateKey},
+			wantPath: "Patient.id",
+		},
+		{
+			name:     "duplicate before resourceType",
+			data:     `{"id": "a", "id": "b"}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagJSONDuplicateKey},
+			wantPath: "$this.id",
+		},
+		{
+			name: "same name in sibling objects",
+			data: `{"resourceType": "Patient", "name": [{"family": "A"}, {"family": "B"}], "contact": [{"name": {"family": "C"}}]}`,
+		},
+		{
+			name: "brackets and quotes inside strings",
+			data: `{"resourceType": "Patient", "id": "a", "text": {"div": "<div>{\"id\": [1]}</div>"}}`,
+		},
+		{
+			name:    "trailing data",
+			data:    `{"resourceType": "Patient"} {"resourceType": "Patient"}`,
+			wantIDs: []issue.DiagnosticID{issue.DiagJSONTrailingData},
+		},
+		{
+			name: "trailing whitespace",
+			data: "{\"resourceType\": \"Patient\"}\n\t ",
+		},
+		{
+			name:    "invalid UTF-8",
+			data:    "{\"resourceType\": \"Patient\", \"id\": \"a\xffb\"}",
+			wantIDs: []issue.DiagnosticID{issue.DiagJSONInvalidUTF8},
+		},
+		{
+			name: "not an object",
+			data: `"Patient"`,
+		},
+		{
+			name: "unterminated string",
+			data: `{"resourceType": "Patient", "id": "`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			ok := Check([]byte(tt.data), result)
+			if ok != (len(tt.wantIDs) == 0) {
+				t.Errorf("Check = %v, issues = %d", ok, len(result.Issues))
+			}
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %d, want %v", len(result.Issues), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+				if result.Issues[i].Severity != issue.SeverityFatal {
+					t.Errorf("issue[%d] severity = %s, want fatal", i, result.Issues[i].Severity)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestCheckOffsets(t *testing.T) {
+	result := issue.NewResult()
+	Check([]byte(`{"a": 1}  x`), result)
+	if len(result.Issues) != 1 || result.Issues[0].Diagnostics != "Unexpected data after the end of the resource at byte offset 10; it was not validated" {
+		t.Errorf("trailing data issues = %+v", result.Issues)
+	}
+
+	result = issue.NewResult()
+	Check([]byte("{\"a\": \"\xc3\"}"), result)
+	if len(result.Issues) != 1 || result.Issues[0].Diagnostics != "Invalid UTF-8 byte sequence at byte offset 7; it was not validated" {
+		t.Errorf("invalid UTF-8 issues = %+v", result.Issues)
+	}
+}
@@ -4,9 +4,11 @@ package structural
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
-	"sync"
 
+	"github.com/gofhir/validator/pkg/cache"
 	"github.com/gofhir/validator/pkg/issue"
 	"github.com/gofhir/validator/pkg/registry"
 )
@@ -14,17 +16,36 @@ import (
 // Validator performs structural validation of FHIR resources.
 type Validator struct {
 	registry *registry.Registry
-	// idxCache caches element indexes by SD URL for faster repeated lookups
-	idxCache sync.Map // map[string]*elementIndex
+	// idxCache caches element indexes by SD URL|version for faster repeated lookups
+	idxCache *cache.LRU[string, *elementIndex]
 }
 
 // New creates a new structural Validator.
 func New(reg *registry.Registry) *Validator {
 	return &Validator{
 		registry: reg,
+		idxCache: cache.NewLRU(indexCost),
 	}
 }
 
+// IndexCache returns the cache of element indexes, e.g. to bound its size
+// (see cache.Budget).
+func (v *Validator) IndexCache() cache.Cache {
+	return v.idxCache
+}
+
+// indexCost estimates the memory used by a cached element index.
+func indexCost(key string, idx *elementIndex) int64 {
+	cost := int64(len(key) + 96)
+	for path := range idx.byPath {
+		cost += int64(len(path) + 48) // String header, pointer and map overhead
+	}
+	for path := range idx.choiceTypes {
+		cost += int64(len(path) + 48)
+	}
+	return cost
+}
+
 // elementIndex holds pre-processed element lookups for a StructureDefinition.
 type elementIndex struct {
 	// byPath maps exact paths to ElementDefinitions
@@ -82,16 +103,15 @@ func (v *Validator) getOrBuildIndex(sd *registry.StructureDefinition) *elementIn
 		return buildElementIndex(sd)
 	}
 
-	// Check cache
-	if cached, ok := v.idxCache.Load(sd.URL); ok {
-		if idx, ok := cached.(*elementIndex); ok {
-			return idx
-		}
+	// Check cache; versions of one canonical have their own index
+	key := sd.URL + "|" + sd.Version
+	if idx, ok := v.idxCache.Get(key); ok {
+		return idx
 	}
 
 	// Build and cache
 	idx := buildElementIndex(sd)
-	v.idxCache.Store(sd.URL, idx)
+	v.idxCache.Add(key, idx)
 	return idx
 }
 
@@ -105,7 +125,7 @@ func (v *Validator) Validate(resource []byte, sd *registry.StructureDefinition)
 	if err := json.Unmarshal(resource, &data); err != nil {
 		result.AddErrorWithID(
 			issue.DiagStructureInvalidJSON,
-			map[string]any{"error": err.Error()},
+			issue.Params{issue.String("error", err.Error())},
 		)
 		return result
 	}
@@ -140,6 +160,34 @@ func (v *Validator) ValidateData(data map[string]any, sd *registry.StructureDefi
 	return result
 }
 
+// ValidateElement validates the structure of a single element value and its
+// descendants. ParentPath is the SD path of the element's parent (e.g.,
+// "Patient" or "HumanName"), name the element name as it appears in the
+// JSON and fhirPath the path of value for issues (e.g., "Patient.name[0]").
+func (v *Validator) ValidateElement(value any, sd *registry.StructureDefinition, parentPath, name, fhirPath string) *issue.Result {
+	result := issue.GetPooledResult()
+
+	idx := v.getOrBuildIndex(sd)
+	ctx := &validationContext{
+		rootSD:  sd,
+		rootIdx: idx,
+	}
+
+	elementSDPath := parentPath + "." + name
+	resolved := v.resolveElementDefinition(elementSDPath, name, idx)
+	if resolved == nil {
+		result.AddErrorWithID(
+			issue.DiagStructureUnknownElement,
+			issue.Params{issue.String("element", name)},
+			fhirPath,
+		)
+		return result
+	}
+
+	v.validateChildren(value, resolved, elementSDPath, fhirPath, idx, ctx, result)
+	return result
+}
+
 // validateElement recursively validates an element and its children.
 func (v *Validator) validateElement(
 	data map[string]any,
@@ -149,6 +197,9 @@ func (v *Validator) validateElement(
 	ctx *validationContext,
 	result *issue.Result,
 ) {
+	// Variants and shadows present per choice element, for exclusivity checks
+	var choices map[*registry.ElementDefinition]*choiceKeys
+
 	for key, value := range data {
 		// Skip resourceType - it's handled separately
 		if key == "resourceType" {
@@ -161,13 +212,20 @@ func (v *Validator) validateElement(
 			baseKey := key[1:] // Remove the underscore prefix
 			if v.isShadowElementValid(data, baseKey, sdPath, idx) {
 				// Valid shadow element - validate its structure (should only have id and extension)
-				v.validateShadowElement(value, fhirPath+"."+key, result)
+				// and its alignment with the values, reporting at the path of the values
+				v.validateShadowElement(value, fhirPath+"."+baseKey, result)
+				validateShadowAlignment(data[baseKey], value, baseKey, fhirPath+"."+baseKey, result)
+				if idx.byPath[sdPath+"."+baseKey] == nil {
+					if choiceElemDef, _ := idx.resolveChoice(sdPath + "." + baseKey); choiceElemDef != nil {
+						choices = addChoiceKey(choices, choiceElemDef, key, true)
+					}
+				}
 				continue
 			}
 			// Invalid shadow element - the base element doesn't exist or isn't a primitive
 			result.AddErrorWithID(
 				issue.DiagStructureUnknownElement,
-				map[string]any{"element": key},
+				issue.Params{issue.String("element", key)},
 				fhirPath+"."+key,
 			)
 			continue
@@ -181,18 +239,139 @@ func (v *Validator) validateElement(
 		resolved := v.resolveElementDefinition(elementSDPath, key, idx)
 
 		if resolved == nil {
+			// A variant of a choice element with a type it does not allow
+			if choiceElemDef, _ := idx.resolveChoice(elementSDPath); choiceElemDef != nil && v.isTypeName(choiceTypeSuffix(choiceElemDef, key)) {
+				result.AddErrorWithID(
+					issue.DiagStructureInvalidChoiceType,
+					issue.Params{issue.String("element", key), issue.String("path", choicePath(choiceElemDef, fhirPath)), issue.String("allowed", allowedTypes(choiceElemDef))},
+					elementFHIRPath,
+				)
+				continue
+			}
+
 			// Unknown element - report error
 			result.AddErrorWithID(
 				issue.DiagStructureUnknownElement,
-				map[string]any{"element": key},
+				issue.Params{issue.String("element", key)},
 				elementFHIRPath,
 			)
 			continue
 		}
+		if resolved.elemDef.Path != elementSDPath {
+			choices = addChoiceKey(choices, resolved.elemDef, key, false)
+		}
 
 		// Recursively validate children based on element type
 		v.validateChildren(value, resolved, elementSDPath, elementFHIRPath, idx, ctx, result)
 	}
+
+	for _, choiceElemDef := range slices.SortedFunc(maps.Keys(choices), func(a, b *registry.ElementDefinition) int {
+		return strings.Compare(a.Path, b.Path)
+	}) {
+		validateChoiceKeys(choiceElemDef, choices[choiceElemDef], fhirPath, result)
+	}
+}
+
+// choiceKeys holds the variants (e.g., "valueString") and shadow elements
+// (e.g., "_valueString") of a choice element present in an object.
+type choiceKeys struct {
+	variants []string
+	shadows  []string
+}
+
+// addChoiceKey records a variant or shadow key of a choice element.
+func addChoiceKey(choices map[*registry.ElementDefinition]*choiceKeys, elemDef *registry.ElementDefinition, key string, shadow bool) map[*registry.ElementDefinition]*choiceKeys {
+	if choices == nil {
+		choices = make(map[*registry.ElementDefinition]*choiceKeys)
+	}
+	keys := choices[elemDef]
+	if keys == nil {
+		keys = &choiceKeys{}
+		choices[elemDef] = keys
+	}
+	if shadow {
+		keys.shadows = append(keys.shadows, key)
+	} else {
+		keys.variants = append(keys.variants, key)
+	}
+	return choices
+}
+
+// validateChoiceKeys reports a choice element with more than one variant
+// present, and shadow elements of a variant other than the one present
+// (e.g., "_valueBoolean" next to "valueString").
+func validateChoiceKeys(elemDef *registry.ElementDefinition, keys *choiceKeys, fhirPath string, result *issue.Result) {
+	slices.Sort(keys.variants)
+	slices.Sort(keys.shadows)
+	path := choicePath(elemDef, fhirPath)
+
+	if len(keys.variants) == 0 {
+		// Only shadows: a variant whose value is null, which must still be unique
+		if len(keys.shadows) > 1 {
+			result.AddErrorWithID(
+				issue.DiagStructureChoiceMultiple,
+				issue.Params{issue.String("elements", quoteKeys(keys.shadows)), issue.String("path", path)},
+				fhirPath,
+			)
+		}
+		return
+	}
+
+	if len(keys.variants) > 1 {
+		result.AddErrorWithID(
+			issue.DiagStructureChoiceMultiple,
+			issue.Params{issue.String("elements", quoteKeys(keys.variants)), issue.String("path", path)},
+			fhirPath,
+		)
+	}
+	for _, shadow := range keys.shadows {
+		if !slices.Contains(keys.variants, shadow[1:]) {
+			result.AddErrorWithID(
+				issue.DiagStructureChoiceShadowMismatch,
+				issue.Params{issue.String("shadow", shadow), issue.String("element", keys.variants[0]), issue.String("path", path)},
+				fhirPath+"."+shadow,
+			)
+		}
+	}
+}
+
+// choicePath returns the path of a choice element within an object (e.g.,
+// "Observation.component[0].value[x]").
+func choicePath(elemDef *registry.ElementDefinition, fhirPath string) string {
+	return fhirPath + "." + elemDef.Path[strings.LastIndex(elemDef.Path, ".")+1:]
+}
+
+// choiceTypeSuffix returns the type part of a choice variant name (e.g.,
+// "Quantity" for "valueQuantity" of "Observation.value[x]").
+func choiceTypeSuffix(elemDef *registry.ElementDefinition, key string) string {
+	base := strings.TrimSuffix(elemDef.Path[strings.LastIndex(elemDef.Path, ".")+1:], "[x]")
+	return strings.TrimPrefix(key, base)
+}
+
+// allowedTypes lists the types of a choice element.
+func allowedTypes(elemDef *registry.ElementDefinition) string {
+	codes := make([]string, len(elemDef.Type))
+	for i, t := range elemDef.Type {
+		codes[i] = t.Code
+	}
+	return strings.Join(codes, ", ")
+}
+
+// quoteKeys formats element names for a diagnostic (e.g., "'valueString', 'valueBoolean'").
+func quoteKeys(keys []string) string {
+	return "'" + strings.Join(keys, "', '") + "'"
+}
+
+// isTypeName reports whether a choice variant suffix names a FHIR data type
+// (e.g., "Quantity" or "DateTime" for dateTime).
+func (v *Validator) isTypeName(suffix string) bool {
+	if suffix == "" {
+		return false
+	}
+	if v.registry.GetByType(suffix) != nil {
+		return true
+	}
+	return v.registry.GetByType(strings.ToLower(suffix[:1])+suffix[1:]) != nil
 }
 
 // isShadowElementValid checks if a shadow element (_foo) is valid.
@@ -243,7 +422,7 @@ func (v *Validator) validateShadowElement(value any, fhirPath string, result *is
 			if key != "id" && key != "extension" {
 				result.AddErrorWithID(
 					issue.DiagStructureUnknownElement,
-					map[string]any{"element": key},
+					issue.Params{issue.String("element", key)},
 					fhirPath+"."+key,
 				)
 			}
@@ -259,6 +438,43 @@ func (v *Validator) validateShadowElement(value any, fhirPath string, result *is
 	}
 }
 
+// validateShadowAlignment checks that the shadow element of a repeating
+// primitive (e.g., "_given") aligns by index with its values ("given"): both
+// arrays have the same length and no item is null in both.
+func validateShadowAlignment(value, shadow any, name, fhirPath string, result *issue.Result) {
+	values, isArray := value.([]any)
+	shadows, ok := shadow.([]any)
+	if !ok {
+		if isArray {
+			result.AddErrorWithID(
+				issue.DiagStructureShadowMisaligned,
+				issue.Params{issue.String("element", name), issue.Int("shadows", 1), issue.Int("values", len(values))},
+				fhirPath,
+			)
+		}
+		return
+	}
+	if value != nil && values == nil {
+		values = []any{value}
+	}
+	if len(values) != len(shadows) && value != nil {
+		result.AddErrorWithID(
+			issue.DiagStructureShadowMisaligned,
+			issue.Params{issue.String("element", name), issue.Int("shadows", len(shadows)), issue.Int("values", len(values))},
+			fhirPath,
+		)
+	}
+	for i, item := range shadows {
+		if item == nil && (i >= len(values) || values[i] == nil) {
+			result.AddErrorWithID(
+				issue.DiagStructureShadowNull,
+				issue.Params{issue.String("element", name), issue.Int("index", i)},
+				fmt.Sprintf("%s[%d]", fhirPath, i),
+			)
+		}
+	}
+}
+
 // resolveElementDefinition finds the ElementDefinition for an element.
 // It handles both regular elements, choice types, and contentReference.
 // Returns nil if the element is not found.
@@ -289,31 +505,35 @@ func (v *Validator) resolveElementDefinition(
 		}
 	}
 
-	// 2. Try resolving as choice type
-	// Look for a choice type whose base matches the beginning of elementName
-	for choiceBasePath, choiceElemDef := range idx.choiceTypes {
-		// Get the element name from the choice path (e.g., "Patient.deceased" -> "deceased")
-		choiceBaseName := choiceBasePath[strings.LastIndex(choiceBasePath, ".")+1:]
-
-		// Check if element starts with the choice base name
-		if strings.HasPrefix(elementName, choiceBaseName) && len(elementName) > len(choiceBaseName) {
-			// Extract the type suffix (e.g., "deceasedBoolean" -> "Boolean")
-			typeSuffix := elementName[len(choiceBaseName):]
-
-			// Find the matching type from the ElementDefinition
-			matchedType := findMatchingChoiceType(choiceElemDef, typeSuffix)
-			if matchedType != "" {
-				return &resolvedElement{
-					elemDef:      choiceElemDef,
-					resolvedType: matchedType,
-				}
-			}
+	// 2. Try resolving as choice type of the same parent
+	// (e.g., "Observation.valueQuantity" against "Observation.value[x]")
+	if choiceElemDef, matchedType := idx.resolveChoice(elementPath); matchedType != "" {
+		return &resolvedElement{
+			elemDef:      choiceElemDef,
+			resolvedType: matchedType,
 		}
 	}
 
 	return nil
 }
 
+// resolveChoice finds the choice element a path is a variant of (e.g.,
+// "Observation.value[x]" for "Observation.valueQuantity") and the type of the
+// variant. The type is empty if the element does not allow it.
+func (idx *elementIndex) resolveChoice(elementPath string) (*registry.ElementDefinition, string) {
+	dot := strings.LastIndex(elementPath, ".")
+	name := elementPath[dot+1:]
+	for i := 1; i < len(name); i++ {
+		if name[i] < 'A' || name[i] > 'Z' {
+			continue
+		}
+		if choiceElemDef := idx.choiceTypes[elementPath[:dot+1+i]]; choiceElemDef != nil {
+			return choiceElemDef, findMatchingChoiceType(choiceElemDef, name[i:])
+		}
+	}
+	return nil, ""
+}
+
 // findMatchingChoiceType finds the actual type code from the ElementDefinition
 // that matches the given suffix (case-insensitive).
 // Returns the actual type code from the SD (preserving original case).
@@ -384,7 +604,7 @@ func (v *Validator) validateContainedResources(contained []any, baseFhirPath str
 		if containedSD == nil {
 			result.AddErrorWithID(
 				issue.DiagStructureUnknownResource,
-				map[string]any{"type": resourceType},
+				issue.Params{issue.String("type", resourceType)},
 				fmt.Sprintf("%s[%d]", baseFhirPath, i),
 			)
 			continue
@@ -421,7 +641,7 @@ func (v *Validator) validateResourceElement(data map[string]any, fhirPath string
 	if resourceSD == nil {
 		result.AddErrorWithID(
 			issue.DiagStructureUnknownResource,
-			map[string]any{"type": resourceType},
+			issue.Params{issue.String("type", resourceType)},
 			fhirPath,
 		)
 		return
@@ -3,6 +3,7 @@ package structural
 import (
 	"testing"
 
+	"github.com/gofhir/validator/pkg/issue"
 	"github.com/gofhir/validator/pkg/loader"
 	"github.com/gofhir/validator/pkg/registry"
 )
@@ -164,6 +165,84 @@ func TestValidateChoiceType(t *testing.T) {
 	}
 }
 
+func TestChoiceTypeConsistency(t *testing.T) {
+	reg := setupTestRegistry(t)
+	v := New(reg)
+
+	sd := reg.GetByURL("http://hl7.org/fhir/StructureDefinition/Observation")
+	if sd == nil {
+		t.Fatal("Observation StructureDefinition not found")
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name:     "single variant with its shadow",
+			resource: `{"resourceType": "Observation", "valueString": "x", "_valueString": {"id": "v"}}`,
+		},
+		{
+			name:     "type not allowed for the choice",
+			resource: `{"resourceType": "Observation", "effectiveAge": {"value": 3}}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagStructureInvalidChoiceType},
+			wantPath: "Observation.effectiveAge",
+		},
+		{
+			name:     "misspelled variant is an unknown element",
+			resource: `{"resourceType": "Observation", "effectiveWhen": "2020"}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagStructureUnknownElement},
+			wantPath: "Observation.effectiveWhen",
+		},
+		{
+			name:     "two variants",
+			resource: `{"resourceType": "Observation", "valueString": "x", "valueBoolean": true}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagStructureChoiceMultiple},
+			wantPath: "Observation",
+		},
+		{
+			name: "two variants in a backbone element",
+			resource: `{"resourceType": "Observation", "component": [
+				{"code": {"text": "a"}, "valueInteger": 1, "valueQuantity": {"value": 1}}
+			]}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagStructureChoiceMultiple},
+			wantPath: "Observation.component[0]",
+		},
+		{
+			name:     "shadow of another variant",
+			resource: `{"resourceType": "Observation", "valueString": "x", "_valueBoolean": {"id": "v"}}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagStructureChoiceShadowMismatch},
+			wantPath: "Observation._valueBoolean",
+		},
+		{
+			name:     "shadows of two null variants",
+			resource: `{"resourceType": "Observation", "_valueString": {"id": "a"}, "_valueBoolean": {"id": "b"}}`,
+			wantIDs:  []issue.DiagnosticID{issue.DiagStructureChoiceMultiple},
+			wantPath: "Observation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := v.Validate([]byte(tt.resource), sd)
+
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("got %d issues, want %v: %v", len(result.Issues), tt.wantIDs, result.Issues)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
+
 func TestValidateNestedElements(t *testing.T) {
 	reg := setupTestRegistry(t)
 	v := New(reg)
@@ -0,0 +1,331 @@
+// Package subscription validates Subscription resources beyond their
+// structure:
+//
+//   - R4 criteria must be a search query for a resource type, with search
+//     parameters defined for that type when SearchParameters for it are
+//     loaded
+//   - topic-based subscriptions (R5, and the R4 backport whose criteria is a
+//     SubscriptionTopic canonical) must reference a loaded SubscriptionTopic,
+//     and filter only by the parameters the topic allows
+//   - the channel must have an endpoint of the kind its type delivers to,
+//     and a FHIR payload MIME type when notifications carry content
+package subscription
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/searchparam"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Backport extensions carrying the topic-based parts of an R4 Subscription.
+const (
+	ExtensionFilterCriteria = "http://hl7.org/fhir/uv/subscriptions-backport/StructureDefinition/backport-filter-criteria"
+	ExtensionPayloadContent = "http://hl7.org/fhir/uv/subscriptions-backport/StructureDefinition/backport-payload-content"
+)
+
+// endpointSchemes are the endpoint URL schemes of the channel types that
+// deliver to an endpoint. Websocket channels are opened by the client and
+// need none.
+var endpointSchemes = map[string][]string{
+	"rest-hook": {"http", "https"},
+	"email":     {"mailto"},
+	"sms":       {"tel"},
+	"message":   nil, // any URL
+}
+
+// Topic is the part of a SubscriptionTopic used for validation.
+type Topic struct {
+	URL         string `json:"url"`
+	Version     string `json:"version"`
+	CanFilterBy []struct {
+		Resource        string `json:"resource"`
+		FilterParameter string `json:"filterParameter"`
+	} `json:"canFilterBy"`
+}
+
+// allowsFilter reports whether the topic allows filtering by a parameter,
+// for a resource type if one is given.
+func (t *Topic) allowsFilter(resourceType, name string) bool {
+	for _, f := range t.CanFilterBy {
+		if f.FilterParameter == name && (resourceType == "" || f.Resource == "" || f.Resource == resourceType || strings.HasSuffix(f.Resource, "/"+resourceType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// TopicRegistry holds the SubscriptionTopics of loaded packages by URL. It is
+// safe for concurrent use.
+type TopicRegistry struct {
+	mu    sync.RWMutex
+	byURL map[string]*Topic // url and url|version -> topic
+}
+
+// NewTopicRegistry creates an empty TopicRegistry.
+func NewTopicRegistry() *TopicRegistry {
+	return &TopicRegistry{byURL: make(map[string]*Topic)}
+}
+
+// LoadFromPackages loads the SubscriptionTopics of packages. When several
+// packages define a URL the first one loaded is its default definition.
+func (r *TopicRegistry) LoadFromPackages(packages []*loader.Package) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pkg := range packages {
+		for _, data := range pkg.ResourcesOfType("SubscriptionTopic") {
+			var topic Topic
+			if err := json.Unmarshal(data, &topic); err != nil || topic.URL == "" {
+				continue
+			}
+			if _, exists := r.byURL[topic.URL]; !exists {
+				r.byURL[topic.URL] = &topic
+			}
+			if topic.Version != "" {
+				r.byURL[topic.URL+"|"+topic.Version] = &topic
+			}
+		}
+	}
+}
+
+// Get returns the SubscriptionTopic with a canonical URL ("url" or
+// "url|version"), or nil if none is loaded.
+func (r *TopicRegistry) Get(url string) *Topic {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.byURL[url]
+}
+
+// Validator checks Subscription resources.
+type Validator struct {
+	registry *registry.Registry
+	params   *searchparam.Registry
+	topics   *TopicRegistry
+	walker   *walker.Walker
+}
+
+// New creates a new subscription Validator checking criteria against params
+// and topic references against topics.
+func New(reg *registry.Registry, params *searchparam.Registry, topics *TopicRegistry) *Validator {
+	return &Validator{
+		registry: reg,
+		params:   params,
+		topics:   topics,
+		walker:   walker.New(reg),
+	}
+}
+
+// ValidateData checks the Subscriptions of a pre-parsed resource: the
+// resource itself, contained resources and Bundle entries.
+func (v *Validator) ValidateData(resource map[string]any, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+	if resourceType == "Subscription" {
+		v.validateSubscription(resource, resourceType, result)
+	}
+	v.walker.Walk(resource, resourceType, resourceType, func(rc *walker.ResourceContext) bool {
+		if rc.FHIRPath != resourceType && rc.ResourceType == "Subscription" {
+			v.validateSubscription(rc.Data, rc.FHIRPath, result)
+		}
+		return true
+	})
+}
+
+// validateSubscription checks an R4 or R5 Subscription.
+func (v *Validator) validateSubscription(data map[string]any, fhirPath string, result *issue.Result) {
+	if topicURL, ok := data["topic"].(string); ok {
+		// R5: topic, filterBy and the channel at the top level
+		v.validateFilterBy(data, v.resolveTopic(topicURL, fhirPath+".topic", result), fhirPath, result)
+		channelType, _ := data["channelType"].(map[string]any)
+		code, _ := channelType["code"].(string)
+		content, _ := data["content"].(string)
+		v.validateChannel(data, code, "contentType", content, fhirPath, result)
+		return
+	}
+
+	if criteria, ok := data["criteria"].(string); ok {
+		if isCanonical(criteria) {
+			// R4 backport: the criteria is the topic
+			topic := v.resolveTopic(criteria, fhirPath+".criteria", result)
+			for _, filter := range extensionStrings(data["_criteria"], ExtensionFilterCriteria) {
+				v.validateCriteria(filter, topic, fhirPath+".criteria", result)
+			}
+		} else {
+			v.validateCriteria(criteria, nil, fhirPath+".criteria", result)
+		}
+	}
+
+	if channel, ok := data["channel"].(map[string]any); ok {
+		code, _ := channel["type"].(string)
+		content := ""
+		if values := extensionStrings(channel["_payload"], ExtensionPayloadContent); len(values) > 0 {
+			content = values[0]
+		}
+		v.validateChannel(channel, code, "payload", content, fhirPath+".channel", result)
+	}
+}
+
+// resolveTopic returns a loaded SubscriptionTopic, reporting topics that are
+// not loaded.
+func (v *Validator) resolveTopic(url, fhirPath string, result *issue.Result) *Topic {
+	topic := v.topics.Get(url)
+	if topic == nil {
+		result.AddInfoWithID(issue.DiagSubscriptionTopicUnresolved, issue.Params{issue.String("topic", url)}, fhirPath)
+	}
+	return topic
+}
+
+// validateCriteria checks a search query used as criteria or filter. With a
+// topic, parameters must be filters the topic allows; otherwise search
+// parameters of the resource type.
+func (v *Validator) validateCriteria(criteria string, topic *Topic, fhirPath string, result *issue.Result) {
+	query, err := searchparam.ParseQuery(criteria)
+	if err == nil && !v.registry.IsResourceType(query.ResourceType) {
+		err = fmt.Errorf("unknown resource type %q", query.ResourceType)
+	}
+	if err != nil {
+		result.AddErrorWithID(
+			issue.DiagSubscriptionCriteriaInvalid,
+			issue.Params{issue.String("criteria", criteria), issue.String("error", err.Error())},
+			fhirPath,
+		)
+		return
+	}
+	for _, p := range query.Params {
+		v.validateFilter(query.ResourceType, p.Name, topic, fhirPath, result)
+	}
+}
+
+// validateFilterBy checks the filterBy entries of an R5 Subscription.
+func (v *Validator) validateFilterBy(data map[string]any, topic *Topic, fhirPath string, result *issue.Result) {
+	filters, _ := data["filterBy"].([]any)
+	for i, item := range filters {
+		filter, _ := item.(map[string]any)
+		name, _ := filter["filterParameter"].(string)
+		if name == "" {
+			continue
+		}
+		resourceType, _ := filter["resourceType"].(string)
+		if idx := strings.LastIndex(resourceType, "/"); idx >= 0 {
+			resourceType = resourceType[idx+1:] // Canonical of the type's StructureDefinition
+		}
+		v.validateFilter(resourceType, name, topic, fmt.Sprintf("%s.filterBy[%d].filterParameter", fhirPath, i), result)
+	}
+}
+
+// validateFilter checks one filter parameter against the topic if it is
+// loaded, or else against the search parameters of the resource type.
+func (v *Validator) validateFilter(resourceType, name string, topic *Topic, fhirPath string, result *issue.Result) {
+	if topic != nil {
+		if !topic.allowsFilter(resourceType, name) {
+			result.AddErrorWithID(
+				issue.DiagSubscriptionFilterNotAllowed,
+				issue.Params{issue.String("name", name), issue.String("topic", topic.URL)},
+				fhirPath,
+			)
+		}
+		return
+	}
+	if resourceType != "" && v.params.Known(resourceType) && !v.params.Defined(resourceType, name) {
+		result.AddErrorWithID(
+			issue.DiagSubscriptionUnknownParameter,
+			issue.Params{issue.String("name", name), issue.String("resourceType", resourceType)},
+			fhirPath,
+		)
+	}
+}
+
+// validateChannel checks the endpoint of a channel against its type and the
+// payload MIME type (held in payloadKey) against the notification content.
+func (v *Validator) validateChannel(channel map[string]any, channelType, payloadKey, content, fhirPath string, result *issue.Result) {
+	endpoint, _ := channel["endpoint"].(string)
+	if schemes, delivers := endpointSchemes[channelType]; delivers {
+		switch {
+		case endpoint == "":
+			result.AddErrorWithID(
+				issue.DiagSubscriptionEndpointRequired,
+				issue.Params{issue.String("channel", channelType)},
+				fhirPath,
+			)
+		case len(schemes) > 0 && !hasScheme(endpoint, schemes):
+			result.AddErrorWithID(
+				issue.DiagSubscriptionEndpointInvalid,
+				issue.Params{issue.String("endpoint", endpoint), issue.String("channel", channelType), issue.String("scheme", strings.Join(schemes, " or "))},
+				fhirPath+".endpoint",
+			)
+		}
+	}
+
+	payload, _ := channel[payloadKey].(string)
+	if payload != "" && !isFHIRMediaType(payload) {
+		result.AddErrorWithID(
+			issue.DiagSubscriptionPayloadInvalid,
+			issue.Params{issue.String("payload", payload)},
+			fhirPath+"."+payloadKey,
+		)
+	}
+	if payload == "" && (content == "id-only" || content == "full-resource") {
+		result.AddErrorWithID(
+			issue.DiagSubscriptionPayloadRequired,
+			issue.Params{issue.String("content", content)},
+			fhirPath,
+		)
+	}
+}
+
+// extensionStrings returns the valueString or valueCode of the extensions
+// with a URL on a primitive element (e.g., the value of "_criteria").
+func extensionStrings(element any, url string) []string {
+	elem, _ := element.(map[string]any)
+	extensions, _ := elem["extension"].([]any)
+	var values []string
+	for _, item := range extensions {
+		ext, _ := item.(map[string]any)
+		if extURL, _ := ext["url"].(string); extURL != url {
+			continue
+		}
+		if s, ok := ext["valueString"].(string); ok {
+			values = append(values, s)
+		} else if s, ok := ext["valueCode"].(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// isCanonical reports whether criteria is a canonical URL rather than a
+// search query.
+func isCanonical(criteria string) bool {
+	return strings.HasPrefix(criteria, "http://") || strings.HasPrefix(criteria, "https://") || strings.HasPrefix(criteria, "urn:")
+}
+
+// hasScheme reports whether a URL has one of the schemes.
+func hasScheme(url string, schemes []string) bool {
+	scheme, _, ok := strings.Cut(url, ":")
+	if !ok {
+		return false
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(scheme, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFHIRMediaType reports whether a MIME type is a FHIR format, with
+// optional parameters such as fhirVersion.
+func isFHIRMediaType(s string) bool {
+	mediaType, _, err := mime.ParseMediaType(s)
+	return err == nil && (mediaType == "application/fhir+json" || mediaType == "application/fhir+xml")
+}
@@ -0,0 +1,228 @@
+package subscription
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/searchparam"
+)
+
+const topicURL = "http://example.org/SubscriptionTopic/encounter-start"
+
+// testValidator returns a Validator with search parameters for Observation
+// and a topic allowing filters on Encounter.
+func testValidator(t *testing.T) *Validator {
+	t.Helper()
+	pkg, err := loader.NewLoader("").LoadFromResources([][]byte{
+		[]byte(`{"resourceType": "SearchParameter", "id": "Observation-code", "url": "http://example.org/SearchParameter/Observation-code", "code": "code", "base": ["Observation"]}`),
+		[]byte(`{"resourceType": "SearchParameter", "id": "Observation-status", "url": "http://example.org/SearchParameter/Observation-status", "code": "status", "base": ["Observation"]}`),
+		[]byte(`{"resourceType": "SubscriptionTopic", "id": "encounter-start", "url": "` + topicURL + `", "version": "1.0.0", "status": "active",
+			"canFilterBy": [{"resource": "Encounter", "filterParameter": "patient"}]}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := searchparam.NewRegistry()
+	params.LoadFromPackages([]*loader.Package{pkg})
+	topics := NewTopicRegistry()
+	topics.LoadFromPackages([]*loader.Package{pkg})
+	return New(testutil.Registry(t, "4.0.1"), params, topics)
+}
+
+// restHook is a valid R4 rest-hook channel.
+var restHook = map[string]any{"type": "rest-hook", "endpoint": "https://example.org/hook", "payload": "application/fhir+json"}
+
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource map[string]any
+		wantIDs  []issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name: "valid R4 subscription",
+			resource: map[string]any{
+				"resourceType": "Subscription", "status": "requested", "reason": "r",
+				"criteria": "Observation?code=http://loinc.org|1234-5&_lastUpdated=gt2024",
+				"channel":  restHook,
+			},
+		},
+		{
+			name: "criteria without resource type",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "code=1234-5", "channel": restHook,
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionCriteriaInvalid},
+			wantPath: "Subscription.criteria",
+		},
+		{
+			name: "criteria for an unknown resource type",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "Observations?code=1", "channel": restHook,
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionCriteriaInvalid},
+			wantPath: "Subscription.criteria",
+		},
+		{
+			name: "unknown search parameter",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "Observation?colour=red", "channel": restHook,
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionUnknownParameter},
+			wantPath: "Subscription.criteria",
+		},
+		{
+			name: "parameters not checked without SearchParameters for the type",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "Patient?colour=red", "channel": restHook,
+			},
+		},
+		{
+			name: "rest-hook without endpoint",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "Observation",
+				"channel": map[string]any{"type": "rest-hook"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionEndpointRequired},
+			wantPath: "Subscription.channel",
+		},
+		{
+			name: "email channel with http endpoint",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "Observation",
+				"channel": map[string]any{"type": "email", "endpoint": "https://example.org"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionEndpointInvalid},
+			wantPath: "Subscription.channel.endpoint",
+		},
+		{
+			name: "websocket needs no endpoint",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "Observation",
+				"channel": map[string]any{"type": "websocket"},
+			},
+		},
+		{
+			name: "non-FHIR payload",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "Observation",
+				"channel": map[string]any{"type": "rest-hook", "endpoint": "https://example.org", "payload": "text/plain"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionPayloadInvalid},
+			wantPath: "Subscription.channel.payload",
+		},
+		{
+			name: "backport subscription with allowed filter",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": topicURL,
+				"_criteria": map[string]any{"extension": []any{
+					map[string]any{"url": ExtensionFilterCriteria, "valueString": "Encounter?patient=Patient/1"},
+				}},
+				"channel": map[string]any{
+					"type": "rest-hook", "endpoint": "https://example.org", "payload": "application/fhir+json",
+					"_payload": map[string]any{"extension": []any{
+						map[string]any{"url": ExtensionPayloadContent, "valueCode": "id-only"},
+					}},
+				},
+			},
+		},
+		{
+			name: "backport filter not allowed by the topic",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": topicURL,
+				"_criteria": map[string]any{"extension": []any{
+					map[string]any{"url": ExtensionFilterCriteria, "valueString": "Encounter?status=in-progress"},
+				}},
+				"channel": restHook,
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionFilterNotAllowed},
+			wantPath: "Subscription.criteria",
+		},
+		{
+			name: "backport content without payload",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": topicURL,
+				"channel": map[string]any{
+					"type": "rest-hook", "endpoint": "https://example.org",
+					"_payload": map[string]any{"extension": []any{
+						map[string]any{"url": ExtensionPayloadContent, "valueCode": "full-resource"},
+					}},
+				},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionPayloadRequired},
+			wantPath: "Subscription.channel",
+		},
+		{
+			name: "unresolved topic",
+			resource: map[string]any{
+				"resourceType": "Subscription", "criteria": "http://example.org/SubscriptionTopic/other", "channel": restHook,
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionTopicUnresolved},
+			wantPath: "Subscription.criteria",
+		},
+		{
+			name: "R5 subscription with versioned topic",
+			resource: map[string]any{
+				"resourceType": "Subscription", "topic": topicURL + "|1.0.0",
+				"filterBy":    []any{map[string]any{"resourceType": "Encounter", "filterParameter": "patient", "value": "Patient/1"}},
+				"channelType": map[string]any{"code": "rest-hook"}, "endpoint": "https://example.org",
+				"content": "full-resource", "contentType": "application/fhir+json; fhirVersion=5.0",
+			},
+		},
+		{
+			name: "R5 filter not allowed by the topic",
+			resource: map[string]any{
+				"resourceType": "Subscription", "topic": topicURL,
+				"filterBy":    []any{map[string]any{"filterParameter": "patient"}, map[string]any{"filterParameter": "class"}},
+				"channelType": map[string]any{"code": "websocket"},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionFilterNotAllowed},
+			wantPath: "Subscription.filterBy[1].filterParameter",
+		},
+		{
+			name: "R5 filter checked as search parameter without topic",
+			resource: map[string]any{
+				"resourceType": "Subscription", "topic": "http://example.org/SubscriptionTopic/other",
+				"filterBy":    []any{map[string]any{"resourceType": "Observation", "filterParameter": "colour"}},
+				"channelType": map[string]any{"code": "sms"}, "endpoint": "tel:+15555550100",
+			},
+			wantIDs: []issue.DiagnosticID{
+				issue.DiagSubscriptionTopicUnresolved,
+				issue.DiagSubscriptionUnknownParameter,
+			},
+			wantPath: "Subscription.topic",
+		},
+		{
+			name: "contained subscription",
+			resource: map[string]any{
+				"resourceType": "Patient",
+				"contained": []any{map[string]any{
+					"resourceType": "Subscription", "id": "s", "criteria": "Observation?colour=red", "channel": restHook,
+				}},
+			},
+			wantIDs:  []issue.DiagnosticID{issue.DiagSubscriptionUnknownParameter},
+			wantPath: "Patient.contained[0].criteria",
+		},
+	}
+
+	v := testValidator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := issue.NewResult()
+			v.ValidateData(tt.resource, result)
+			if len(result.Issues) != len(tt.wantIDs) {
+				t.Fatalf("issues = %v, want %v", testutil.IssueIDs(result), tt.wantIDs)
+			}
+			for i, want := range tt.wantIDs {
+				if result.Issues[i].MessageID != string(want) {
+					t.Errorf("issue[%d] = %s, want %s", i, result.Issues[i].MessageID, want)
+				}
+			}
+			if tt.wantPath != "" && result.Issues[0].Expression[0] != tt.wantPath {
+				t.Errorf("path = %v, want %s", result.Issues[0].Expression, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package terminology
+
+// ConceptStatus describes the status properties of a CodeSystem concept.
+// The zero value is an active, selectable concept.
+type ConceptStatus struct {
+	// Status is the concept's status property (e.g. "retired"), if any.
+	Status string
+	// Inactive reports a retired or inactive concept.
+	Inactive bool
+	// Deprecated reports a concept that is still active but should no longer
+	// be used.
+	Deprecated bool
+	// Abstract reports a concept that groups others and cannot be selected
+	// (notSelectable).
+	Abstract bool
+}
+
+// ConceptStatus returns the status of a code in a CodeSystem, read from the
+// concept's status, inactive, deprecated, notSelectable and abstract
+// properties. Unknown systems and codes report the zero value. The system may
+// name a version ("url|version"); otherwise the version pinned with
+// SetSystemVersions is used.
+func (r *Registry) ConceptStatus(system, code string) ConceptStatus {
+	cs := r.pinnedCodeSystem(system)
+	if cs == nil {
+		return ConceptStatus{}
+	}
+	return r.conceptStatuses(cs)[code]
+}
+
+// CodeConceptStatus returns the status of a code drawn from a ValueSet. With a
+// system it is ConceptStatus; without one (code elements) the code is looked
+// up in the CodeSystems the ValueSet, or a ValueSet it includes, draws from.
+func (r *Registry) CodeConceptStatus(valueSetURL, system, code string) ConceptStatus {
+	if system != "" {
+		return r.ConceptStatus(system, code)
+	}
+	return r.valueSetConceptStatus(valueSetURL, code, make(map[string]bool))
+}
+
+// ExcludesInactive reports whether a ValueSet leaves inactive codes out
+// (compose.inactive is false).
+func (r *Registry) ExcludesInactive(valueSetURL string) bool {
+	vs := r.GetValueSet(valueSetURL)
+	return vs != nil && vs.Compose.Inactive != nil && !*vs.Compose.Inactive
+}
+
+func (r *Registry) valueSetConceptStatus(valueSetURL, code string, seen map[string]bool) ConceptStatus {
+	vs := r.GetValueSet(valueSetURL)
+	if vs == nil || seen[vs.URL] {
+		return ConceptStatus{}
+	}
+	seen[vs.URL] = true
+
+	for _, inc := range vs.Compose.Include {
+		if inc.System != "" {
+			if status := r.ConceptStatus(includeCanonical(&inc, nil), code); status != (ConceptStatus{}) {
+				return status
+			}
+		}
+		for _, nested := range inc.ValueSet {
+			if status := r.valueSetConceptStatus(nested, code, seen); status != (ConceptStatus{}) {
+				return status
+			}
+		}
+	}
+	return ConceptStatus{}
+}
+
+// conceptStatuses returns the non-default concept statuses of a CodeSystem,
+// building them on first use.
+func (r *Registry) conceptStatuses(cs *CodeSystem) map[string]ConceptStatus {
+	key := cs.URL + "|" + cs.Version
+
+	r.mu.RLock()
+	statuses, ok := r.conceptCache[key]
+	r.mu.RUnlock()
+	if ok {
+		return statuses
+	}
+
+	statuses = make(map[string]ConceptStatus)
+	var walk func(concepts []CodeSystemCode)
+	walk = func(concepts []CodeSystemCode) {
+		for _, c := range concepts {
+			if status := conceptStatus(c.Property); status != (ConceptStatus{}) {
+				statuses[c.Code] = status
+			}
+			walk(c.Concept)
+		}
+	}
+	walk(cs.Concept)
+
+	r.mu.Lock()
+	r.conceptCache[key] = statuses
+	r.mu.Unlock()
+	return statuses
+}
+
+// conceptStatus reads the status of a concept from its properties.
+func conceptStatus(properties []CodeSystemProperty) ConceptStatus {
+	var status ConceptStatus
+	for _, prop := range properties {
+		isTrue := prop.ValueBoolean != nil && *prop.ValueBoolean
+		switch prop.Code {
+		case "status":
+			switch prop.ValueCode {
+			case "retired", "inactive":
+				status.Status = prop.ValueCode
+				status.Inactive = true
+			case "deprecated":
+				status.Status = prop.ValueCode
+				status.Deprecated = true
+			}
+		case "inactive":
+			status.Inactive = status.Inactive || isTrue
+		case "deprecated":
+			status.Deprecated = status.Deprecated || isTrue || prop.ValueDateTime != ""
+		case "notSelectable", "abstract":
+			status.Abstract = status.Abstract || isTrue
+		}
+	}
+	return status
+}
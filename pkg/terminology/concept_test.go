@@ -0,0 +1,76 @@
+package terminology
+
+import "testing"
+
+func TestConceptStatus(t *testing.T) {
+	yes := true
+	r := NewRegistry()
+	cs := &CodeSystem{
+		URL: "http://example.org/check",
+		Concept: []CodeSystemCode{
+			{Code: "ok", Property: []CodeSystemProperty{{Code: "status", ValueCode: "active"}}},
+			{Code: "old", Property: []CodeSystemProperty{{Code: "status", ValueCode: "retired"}}},
+			{Code: "off", Property: []CodeSystemProperty{{Code: "inactive", ValueBoolean: &yes}}},
+			{Code: "dep", Property: []CodeSystemProperty{{Code: "deprecated", ValueDateTime: "2024-01-01"}}},
+			{Code: "_group", Property: []CodeSystemProperty{{Code: "notSelectable", ValueBoolean: &yes}},
+				Concept: []CodeSystemCode{{Code: "child"}}},
+		},
+	}
+	r.codeSystems[cs.URL] = cs
+
+	tests := []struct {
+		code string
+		want ConceptStatus
+	}{
+		{"ok", ConceptStatus{}},
+		{"old", ConceptStatus{Status: "retired", Inactive: true}},
+		{"off", ConceptStatus{Inactive: true}},
+		{"dep", ConceptStatus{Deprecated: true}},
+		{"_group", ConceptStatus{Abstract: true}},
+		{"child", ConceptStatus{}},
+		{"unknown", ConceptStatus{}},
+	}
+	for _, tt := range tests {
+		if got := r.ConceptStatus(cs.URL, tt.code); got != tt.want {
+			t.Errorf("ConceptStatus(%s) = %+v, want %+v", tt.code, got, tt.want)
+		}
+	}
+
+	no := false
+	valueSets := []*ValueSet{
+		{URL: "http://example.org/vs/all", Compose: Compose{Include: []Include{{System: cs.URL}}}},
+		{URL: "http://example.org/vs/active", Compose: Compose{Inactive: &no, Include: []Include{{System: cs.URL}}}},
+		{URL: "http://example.org/vs/listed", Compose: Compose{Include: []Include{
+			{System: cs.URL, Concept: []Concept{{Code: "_group"}, {Code: "old"}}},
+		}}},
+	}
+	for _, vs := range valueSets {
+		r.valueSets[vs.URL] = vs
+	}
+
+	membership := []struct {
+		valueSet, code string
+		want           bool
+	}{
+		{"http://example.org/vs/all", "ok", true},
+		{"http://example.org/vs/all", "old", true},
+		{"http://example.org/vs/all", "_group", false}, // abstract codes are not selectable
+		{"http://example.org/vs/all", "child", true},
+		{"http://example.org/vs/active", "old", false},
+		{"http://example.org/vs/active", "off", false},
+		{"http://example.org/vs/active", "dep", true},
+		{"http://example.org/vs/listed", "_group", true}, // explicitly listed
+	}
+	for _, tt := range membership {
+		if valid, found := r.ValidateCode(tt.valueSet, cs.URL, tt.code); !found || valid != tt.want {
+			t.Errorf("ValidateCode(%s, %s) = (%v, %v), want (%v, true)", tt.valueSet, tt.code, valid, found, tt.want)
+		}
+	}
+
+	if got := r.CodeConceptStatus("http://example.org/vs/all", "", "old"); !got.Inactive {
+		t.Errorf("CodeConceptStatus(code element) = %+v, want inactive", got)
+	}
+	if !r.ExcludesInactive("http://example.org/vs/active") || r.ExcludesInactive("http://example.org/vs/all") {
+		t.Error("ExcludesInactive() does not follow compose.inactive")
+	}
+}
@@ -0,0 +1,161 @@
+package terminology
+
+import (
+	"strings"
+
+	"github.com/gofhir/validator/pkg/ucum"
+)
+
+// implicitSystems holds the code systems defined by a grammar or a published
+// table rather than a CodeSystem resource, which are validated locally. A
+// ValueSet including all codes of one of them accepts the codes its validator
+// accepts.
+var implicitSystems = map[string]func(code string) bool{
+	// IETF BCP 47 language tags
+	"urn:ietf:bcp:47": isLanguageTag,
+	// IETF BCP 13 (IANA) MIME types
+	"urn:ietf:bcp:13": isMimeType,
+	// ISO 3166-1 country codes
+	"urn:iso:std:iso:3166": func(code string) bool { return iso3166Codes[code] },
+	// UCUM unit expressions
+	ucum.System: func(code string) bool { return ucum.Validate(code) == nil },
+}
+
+// IsImplicitSystem returns true if codes of the system are validated locally
+// by grammar or a built-in table: BCP 47 language tags, BCP 13 MIME types,
+// ISO 3166 country codes and UCUM units.
+func (r *Registry) IsImplicitSystem(system string) bool {
+	return implicitSystems[system] != nil
+}
+
+// isLanguageTag reports whether code is a well-formed BCP 47 language tag
+// (RFC 5646) whose primary language and region subtags are known.
+func isLanguageTag(code string) bool {
+	subtags := strings.Split(strings.ToLower(code), "-")
+	for _, s := range subtags {
+		if len(s) == 0 || len(s) > 8 || !isAlphanumeric(s) {
+			return false
+		}
+	}
+	if subtags[0] == "x" {
+		return len(subtags) > 1 // Private use tag
+	}
+	if subtags[0] == "i" {
+		return len(subtags) > 1 // Grandfathered irregular tag, e.g. i-klingon
+	}
+
+	// language: 2-3 letters (ISO 639); no longer subtags are registered
+	language := subtags[0]
+	switch {
+	case !isAlpha(language) || len(language) < 2 || len(language) > 3:
+		return false
+	case len(language) == 2 && !iso639Codes[language]:
+		return false
+	}
+	rest := subtags[1:]
+
+	// extlang: up to three 3-letter subtags after a 2-3 letter language
+	for n := 0; n < 3 && len(rest) > 0 && len(rest[0]) == 3 && isAlpha(rest[0]); n++ {
+		rest = rest[1:]
+	}
+	// script: 4 letters
+	if len(rest) > 0 && len(rest[0]) == 4 && isAlpha(rest[0]) {
+		rest = rest[1:]
+	}
+	// region: 2 letters (ISO 3166-1) or 3 digits (UN M.49)
+	if len(rest) > 0 {
+		switch {
+		case len(rest[0]) == 2 && isAlpha(rest[0]):
+			if !iso3166Codes[strings.ToUpper(rest[0])] {
+				return false
+			}
+			rest = rest[1:]
+		case len(rest[0]) == 3 && isDigits(rest[0]):
+			rest = rest[1:]
+		}
+	}
+	// variants: 5-8 alphanumerics, or a digit followed by 3 alphanumerics
+	for len(rest) > 0 && (len(rest[0]) >= 5 || (len(rest[0]) == 4 && isDigits(rest[0][:1]))) {
+		rest = rest[1:]
+	}
+	// extensions: a singleton followed by 2-8 character subtags
+	for len(rest) > 1 && len(rest[0]) == 1 && rest[0] != "x" && len(rest[1]) >= 2 {
+		rest = rest[2:]
+		for len(rest) > 0 && len(rest[0]) >= 2 {
+			rest = rest[1:]
+		}
+	}
+	// private use: "x" followed by 1-8 character subtags
+	if len(rest) > 0 && rest[0] == "x" {
+		return len(rest) > 1
+	}
+	return len(rest) == 0
+}
+
+// mimeTopLevelTypes are the top-level media types registered with IANA.
+var mimeTopLevelTypes = map[string]bool{
+	"application": true, "audio": true, "example": true, "font": true, "image": true,
+	"message": true, "model": true, "multipart": true, "text": true, "video": true,
+}
+
+// isMimeType reports whether code is a media type (RFC 6838) with a
+// registered top-level type, optionally followed by parameters, e.g.
+// "text/plain; charset=utf-8".
+func isMimeType(code string) bool {
+	mediaType, params, _ := strings.Cut(code, ";")
+	top, sub, ok := strings.Cut(strings.TrimSpace(mediaType), "/")
+	if !ok || !mimeTopLevelTypes[strings.ToLower(top)] || !isRestrictedName(sub) {
+		return false
+	}
+	if params == "" {
+		return !strings.HasSuffix(code, ";")
+	}
+	for _, param := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !isRestrictedName(name) || value == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isRestrictedName reports whether s is an RFC 6838 restricted-name: up to 127
+// characters starting with a letter or digit.
+func isRestrictedName(s string) bool {
+	if s == "" || len(s) > 127 || !isAlphanumeric(s[:1]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isAlphanumeric(s[i:i+1]) && !strings.ContainsRune("!#$&-^_.+", rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isAlpha(s[i:i+1]) && !isDigits(s[i:i+1]) {
+			return false
+		}
+	}
+	return true
+}
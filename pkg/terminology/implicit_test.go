@@ -0,0 +1,108 @@
+package terminology
+
+import (
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+)
+
+func TestIsLanguageTag(t *testing.T) {
+	tests := map[string]bool{
+		"en":               true,
+		"en-US":            true,
+		"es-419":           true,
+		"zh-Hant-TW":       true,
+		"zh-yue-HK":        true,
+		"sl-rozaj-biske":   true,
+		"de-CH-1901":       true,
+		"en-US-u-islamcal": true,
+		"haw":              true,
+		"x-whatever":       true,
+		"en-x-private":     true,
+		"":                 false,
+		"!!":               false,
+		"english":          false, // Not a registered subtag length
+		"qq":               false,
+		"en-ZZ":            false,
+		"en-":              false,
+		"en--US":           false,
+		"en-US-u":          false,
+		"en_US":            false,
+		"x":                false,
+	}
+	for code, want := range tests {
+		if got := isLanguageTag(code); got != want {
+			t.Errorf("isLanguageTag(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestIsMimeType(t *testing.T) {
+	tests := map[string]bool{
+		"application/json":             true,
+		"application/fhir+json":        true,
+		"text/plain; charset=utf-8":    true,
+		"image/svg+xml":                true,
+		"application/vnd.ms-excel":     true,
+		"json":                         false,
+		"text/":                        false,
+		"foo/bar":                      false,
+		"text/plain;":                  false,
+		"text/plain; charset":          false,
+		"application/json extra words": false,
+	}
+	for code, want := range tests {
+		if got := isMimeType(code); got != want {
+			t.Errorf("isMimeType(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestImplicitValueSets(t *testing.T) {
+	r := NewRegistry()
+	if err := r.LoadFromPackages(testutil.Packages(t, "4.0.1")); err != nil {
+		t.Fatalf("LoadFromPackages() error: %v", err)
+	}
+
+	tests := []struct {
+		valueSet, system, code string
+		want                   bool
+	}{
+		{"http://hl7.org/fhir/ValueSet/all-languages", "urn:ietf:bcp:47", "pt-BR", true},
+		{"http://hl7.org/fhir/ValueSet/all-languages", "urn:ietf:bcp:47", "!!", false},
+		{"http://hl7.org/fhir/ValueSet/all-languages", "", "fr-CA", true},
+		{"http://hl7.org/fhir/ValueSet/mimetypes", "", "application/pdf", true},
+		{"http://hl7.org/fhir/ValueSet/mimetypes", "", "json", false},
+		{"http://hl7.org/fhir/ValueSet/iso3166-1-3", "urn:iso:std:iso:3166", "CHL", true},
+		{"http://hl7.org/fhir/ValueSet/iso3166-1-3", "urn:iso:std:iso:3166", "XYZ", false},
+		{"http://hl7.org/fhir/ValueSet/ucum-units", "http://unitsofmeasure.org", "mg", true},
+		{"http://hl7.org/fhir/ValueSet/ucum-units", "http://unitsofmeasure.org", "mgxx", false},
+		{"http://hl7.org/fhir/ValueSet/ucum-units", "http://example.org/units", "mg", false},
+	}
+	for _, tt := range tests {
+		valid, found := r.ValidateCode(tt.valueSet, tt.system, tt.code)
+		if !found || valid != tt.want {
+			t.Errorf("ValidateCode(%s, %q, %q) = %v, %v; want %v", tt.valueSet, tt.system, tt.code, valid, found, tt.want)
+		}
+	}
+
+	// The built-in tables agree with the published ValueSets
+	for _, url := range []string{"http://hl7.org/fhir/ValueSet/iso3166-1-2", "http://hl7.org/fhir/ValueSet/languages"} {
+		validate := implicitSystems[r.GetValueSet(url).Compose.Include[0].System]
+		for _, c := range r.GetValueSet(url).Compose.Include[0].Concept {
+			if !validate(c.Code) {
+				t.Errorf("%s code %q is rejected", url, c.Code)
+			}
+		}
+	}
+
+	if valid, found := r.ValidateCodeInCodeSystem("urn:ietf:bcp:47", "en-AU"); !valid || !found {
+		t.Errorf("ValidateCodeInCodeSystem(bcp:47, en-AU) = %v, %v; want true, true", valid, found)
+	}
+	if valid, _ := r.ValidateCodeInCodeSystem("urn:iso:std:iso:3166", "UK"); valid {
+		t.Error("ValidateCodeInCodeSystem(iso:3166, UK) should be invalid")
+	}
+	if !r.IsImplicitSystem("urn:ietf:bcp:13") || r.IsImplicitSystem("http://loinc.org") {
+		t.Error("IsImplicitSystem() misclassifies systems")
+	}
+}
@@ -0,0 +1,125 @@
+package terminology
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errCollecting stands in for provider answers while Prefetch collects the
+// checks a validation makes; like a provider error, it accepts the code.
+var errCollecting = errors.New("terminology: collecting provider checks")
+
+// providerCall identifies a provider check: a ValueSet $validate-code when
+// valueSet is set, a CodeSystem one otherwise.
+type providerCall struct {
+	system, code, valueSet string
+}
+
+// providerAnswer is the outcome of a provider check.
+type providerAnswer struct {
+	valid, found bool
+	err          error
+}
+
+// prefetch records the provider checks of a validation while collecting, and
+// holds their answers afterwards. It is read-only once Prefetch returns.
+type prefetch struct {
+	collecting bool
+	mu         sync.Mutex
+	calls      map[providerCall]providerAnswer
+}
+
+type prefetchKey struct{}
+
+// Prefetch runs the provider checks that walk makes, concurrently with at
+// most concurrency requests in flight, and returns a context carrying their
+// answers: checks made with it reuse them instead of calling the provider one
+// at a time. Walk is called once with a context under which provider checks
+// are recorded and answered as provider errors, e.g. to run a validation
+// whose result is discarded. Without a provider, ctx is returned unchanged.
+func (r *Registry) Prefetch(ctx context.Context, concurrency int, walk func(ctx context.Context)) context.Context {
+	if r.provider == nil {
+		return ctx
+	}
+	p := &prefetch{collecting: true, calls: make(map[providerCall]providerAnswer)}
+	walk(context.WithValue(ctx, prefetchKey{}, p))
+	if len(p.calls) == 0 || ctx.Err() != nil {
+		return ctx
+	}
+
+	answers := &prefetch{calls: make(map[providerCall]providerAnswer, len(p.calls))}
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for call := range p.calls {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if call.valueSet == "" {
+				answers.set(call, r.prefetchCode(ctx, call))
+				return
+			}
+			var a providerAnswer
+			a.valid, a.found, a.err = r.provider.ValidateCodeInValueSet(ctx, call.system, call.code, call.valueSet)
+			answers.set(call, a)
+
+			// The check falls back to the CodeSystem when the provider has
+			// no answer for the ValueSet; fetch that too unless it was
+			// recorded on its own
+			system := providerCall{system: call.system, code: call.code}
+			if _, recorded := p.calls[system]; (a.err != nil || !a.found) && !recorded {
+				answers.set(system, r.prefetchCode(ctx, system))
+			}
+		}()
+	}
+	wg.Wait()
+	return context.WithValue(ctx, prefetchKey{}, answers)
+}
+
+// prefetchCode runs a CodeSystem provider check.
+func (r *Registry) prefetchCode(ctx context.Context, call providerCall) providerAnswer {
+	var a providerAnswer
+	a.valid, a.err = r.provider.ValidateCode(ctx, call.system, call.code)
+	return a
+}
+
+// set stores the answer to a provider check.
+func (p *prefetch) set(call providerCall, a providerAnswer) {
+	p.mu.Lock()
+	p.calls[call] = a
+	p.mu.Unlock()
+}
+
+// answer returns the prefetched answer to a provider check, recording the
+// check instead while collecting.
+func (p *prefetch) answer(call providerCall) (providerAnswer, bool) {
+	if p.collecting {
+		p.set(call, providerAnswer{})
+		return providerAnswer{err: errCollecting}, true
+	}
+	a, ok := p.calls[call]
+	return a, ok
+}
+
+// providerValidateCode is provider.ValidateCode, answered from a prefetch in
+// ctx when there is one.
+func (r *Registry) providerValidateCode(ctx context.Context, system, code string) (bool, error) {
+	if p, _ := ctx.Value(prefetchKey{}).(*prefetch); p != nil {
+		if a, ok := p.answer(providerCall{system: system, code: code}); ok {
+			return a.valid, a.err
+		}
+	}
+	return r.provider.ValidateCode(ctx, system, code)
+}
+
+// providerValidateCodeInValueSet is provider.ValidateCodeInValueSet, answered
+// from a prefetch in ctx when there is one.
+func (r *Registry) providerValidateCodeInValueSet(ctx context.Context, system, code, valueSetURL string) (valid, found bool, err error) {
+	if p, _ := ctx.Value(prefetchKey{}).(*prefetch); p != nil {
+		if a, ok := p.answer(providerCall{system: system, code: code, valueSet: valueSetURL}); ok {
+			return a.valid, a.found, a.err
+		}
+	}
+	return r.provider.ValidateCodeInValueSet(ctx, system, code, valueSetURL)
+}
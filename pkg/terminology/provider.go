@@ -13,6 +13,7 @@ import "context"
 // terminology service unavailability.
 //
 // This follows the same pattern as HAPI FHIR's IValidationSupport interface.
+// A Provider is called concurrently by concurrent validations.
 type Provider interface {
 	// ValidateCode checks if a code is valid in a given code system.
 	// Returns (valid, error). If error is non-nil, the Registry falls back
@@ -25,3 +26,19 @@ type Provider interface {
 	// system-level validation via ValidateCode.
 	ValidateCodeInValueSet(ctx context.Context, system, code, valueSetURL string) (valid bool, found bool, err error)
 }
+
+// SystemSupporter is implemented by Providers that know which code systems
+// they can validate, such as a ServerProvider after Probe. The Registry does
+// not delegate codes from other systems to them, and reports such codes as
+// unsupported (see Registry.IsUnsupportedSystem).
+type SystemSupporter interface {
+	// SupportsSystem reports whether codes in system can be validated.
+	SupportsSystem(system string) bool
+}
+
+// Prober is implemented by Providers that discover what they support, e.g.,
+// from the capabilities of a terminology server. The validator probes its
+// provider once at construction.
+type Prober interface {
+	Probe(ctx context.Context) error
+}
@@ -3,7 +3,9 @@ package terminology
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // mockProvider implements TerminologyProvider for testing.
@@ -200,3 +202,72 @@ func TestProviderValidateCodeInCodeSystem_Error(t *testing.T) {
 		t.Error("expected valid=true on provider error (fail-open)")
 	}
 }
+
+func TestProviderValidateCodeContext_Deadline(t *testing.T) {
+	r := newRegistryWithSNOMEDValueSet()
+	r.SetProvider(&mockProvider{
+		validateCodeFn: func(ctx context.Context, _, _ string) (bool, error) {
+			<-ctx.Done()
+			return false, ctx.Err()
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.ValidateCodeContext(ctx, "http://example.org/ValueSet/test", "http://snomed.info/sct", "410607006")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ValidateCodeContext did not pass its context to the provider")
+	}
+}
+
+func TestProviderPrefetch(t *testing.T) {
+	const vs = "http://example.org/ValueSet/test"
+	var vsCalls, codeCalls atomic.Int32
+	r := newRegistryWithSNOMEDValueSet()
+	r.SetProvider(&mockProvider{
+		validateCodeInValueSetFn: func(_ context.Context, _, code, _ string) (bool, bool, error) {
+			vsCalls.Add(1)
+			// The provider knows the ValueSet but not this code's membership
+			if code == "123456" {
+				return false, false, nil
+			}
+			return code == "410607006", true, nil
+		},
+		validateCodeFn: func(_ context.Context, _, code string) (bool, error) {
+			codeCalls.Add(1)
+			return code == "123456", nil
+		},
+	})
+
+	codes := []string{"410607006", "999999", "410607006", "123456"}
+	ctx := r.Prefetch(context.Background(), 4, func(ctx context.Context) {
+		for _, code := range codes {
+			if valid, _ := r.ValidateCodeContext(ctx, vs, "http://snomed.info/sct", code); !valid {
+				t.Errorf("ValidateCodeContext(%s) while collecting = false, want true", code)
+			}
+		}
+	})
+	// One ValueSet check per distinct code, and a CodeSystem check only for
+	// the code whose ValueSet answer was not found
+	if n, m := vsCalls.Load(), codeCalls.Load(); n != 3 || m != 1 {
+		t.Errorf("provider calls during Prefetch = %d ValueSet, %d CodeSystem, want 3 and 1", n, m)
+	}
+
+	for _, code := range codes {
+		valid, _ := r.ValidateCodeContext(ctx, vs, "http://snomed.info/sct", code)
+		if want := code != "999999"; valid != want {
+			t.Errorf("ValidateCodeContext(%s) = %v, want %v", code, valid, want)
+		}
+	}
+	if n, m := vsCalls.Load(), codeCalls.Load(); n != 3 || m != 1 {
+		t.Errorf("provider calls after Prefetch = %d ValueSet, %d CodeSystem, want 3 and 1", n, m)
+	}
+}
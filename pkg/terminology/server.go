@@ -0,0 +1,156 @@
+package terminology
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultServerTimeout bounds each request when no http.Client is supplied.
+const defaultServerTimeout = 10 * time.Second
+
+// ServerProvider is a Provider backed by a FHIR terminology server (e.g.,
+// https://tx.fhir.org/r4), using the CodeSystem and ValueSet $validate-code
+// operations. After Probe it only claims the code systems the server lists in
+// its TerminologyCapabilities.
+type ServerProvider struct {
+	baseURL string
+	client  *http.Client
+
+	mu      sync.RWMutex
+	systems map[string]bool // Code systems the server supports; nil until probed
+}
+
+// NewServerProvider creates a Provider for the terminology server at baseURL.
+// A nil client uses one with a 10 second timeout.
+func NewServerProvider(baseURL string, client *http.Client) *ServerProvider {
+	if client == nil {
+		client = &http.Client{Timeout: defaultServerTimeout}
+	}
+	return &ServerProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// Probe reads the code systems the server supports from its
+// TerminologyCapabilities (GET [base]/metadata?mode=terminology). A server
+// that answers with a CapabilityStatement instead, or fails, is assumed to
+// support every system.
+func (p *ServerProvider) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/metadata?mode=terminology", http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/fhir+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("terminology server returned %s for metadata?mode=terminology", resp.Status)
+	}
+
+	var out struct {
+		ResourceType string `json:"resourceType"`
+		CodeSystem   []struct {
+			URI string `json:"uri"`
+		} `json:"codeSystem"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("decoding TerminologyCapabilities: %w", err)
+	}
+	if out.ResourceType != "TerminologyCapabilities" {
+		return nil // No per-system capabilities to route on
+	}
+
+	systems := make(map[string]bool, len(out.CodeSystem))
+	for _, cs := range out.CodeSystem {
+		if uri, _, _ := strings.Cut(cs.URI, "|"); uri != "" {
+			systems[uri] = true
+		}
+	}
+	p.mu.Lock()
+	p.systems = systems
+	p.mu.Unlock()
+	return nil
+}
+
+// SupportsSystem implements SystemSupporter: before a successful Probe every
+// system is supported.
+func (p *ServerProvider) SupportsSystem(system string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.systems == nil || p.systems[system]
+}
+
+// ValidateCode implements Provider using CodeSystem/$validate-code.
+func (p *ServerProvider) ValidateCode(ctx context.Context, system, code string) (bool, error) {
+	valid, found, err := p.validateCode(ctx, "CodeSystem", url.Values{
+		"url":  {system},
+		"code": {code},
+	})
+	if err == nil && !found {
+		err = fmt.Errorf("terminology server does not know code system %s", system)
+	}
+	return valid, err
+}
+
+// ValidateCodeInValueSet implements Provider using ValueSet/$validate-code.
+// A ValueSet unknown to the server is reported as not found.
+func (p *ServerProvider) ValidateCodeInValueSet(ctx context.Context, system, code, valueSetURL string) (valid, found bool, err error) {
+	return p.validateCode(ctx, "ValueSet", url.Values{
+		"url":    {valueSetURL},
+		"system": {system},
+		"code":   {code},
+	})
+}
+
+// validateCode calls $validate-code on a resource type and reads the result
+// parameter. Found is false when the server answers 404 (unknown
+// CodeSystem or ValueSet).
+func (p *ServerProvider) validateCode(ctx context.Context, resourceType string, params url.Values) (valid, found bool, err error) {
+	endpoint := p.baseURL + "/" + resourceType + "/$validate-code?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return false, false, err
+	}
+	req.Header.Set("Accept", "application/fhir+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, false, fmt.Errorf("terminology server returned %s for %s/$validate-code", resp.Status, resourceType)
+	}
+
+	var out struct {
+		ResourceType string `json:"resourceType"`
+		Parameter    []struct {
+			Name         string `json:"name"`
+			ValueBoolean *bool  `json:"valueBoolean"`
+		} `json:"parameter"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return false, false, fmt.Errorf("decoding %s/$validate-code response: %w", resourceType, err)
+	}
+	for _, param := range out.Parameter {
+		if param.Name == "result" && param.ValueBoolean != nil {
+			return *param.ValueBoolean, true, nil
+		}
+	}
+	return false, false, fmt.Errorf("%s/$validate-code response has no result parameter", resourceType)
+}
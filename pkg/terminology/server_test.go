@@ -0,0 +1,127 @@
+package terminology
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestServer serves $validate-code, accepting only code 410607006 and
+// knowing only the ValueSet http://example.org/ValueSet/test.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path == "/ValueSet/$validate-code" && q.Get("url") != "http://example.org/ValueSet/test" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path != "/ValueSet/$validate-code" && r.URL.Path != "/CodeSystem/$validate-code" {
+			http.Error(w, "unexpected path", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/fhir+json")
+		fmt.Fprintf(w, `{"resourceType":"Parameters","parameter":[{"name":"result","valueBoolean":%t}]}`,
+			q.Get("code") == "410607006")
+	}))
+}
+
+func TestServerProviderValidateCode(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	p := NewServerProvider(srv.URL+"/", nil)
+
+	valid, err := p.ValidateCode(context.Background(), "http://snomed.info/sct", "410607006")
+	if err != nil || !valid {
+		t.Errorf("ValidateCode(known) = %v, %v; want true, nil", valid, err)
+	}
+	valid, err = p.ValidateCode(context.Background(), "http://snomed.info/sct", "bogus")
+	if err != nil || valid {
+		t.Errorf("ValidateCode(unknown) = %v, %v; want false, nil", valid, err)
+	}
+}
+
+func TestServerProviderValidateCodeInValueSet(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+	p := NewServerProvider(srv.URL, nil)
+
+	valid, found, err := p.ValidateCodeInValueSet(context.Background(), "http://snomed.info/sct", "410607006", "http://example.org/ValueSet/test")
+	if err != nil || !found || !valid {
+		t.Errorf("ValidateCodeInValueSet(known) = %v, %v, %v; want true, true, nil", valid, found, err)
+	}
+	_, found, err = p.ValidateCodeInValueSet(context.Background(), "http://snomed.info/sct", "410607006", "http://example.org/ValueSet/other")
+	if err != nil || found {
+		t.Errorf("ValidateCodeInValueSet(unknown ValueSet) found = %v, err = %v; want false, nil", found, err)
+	}
+}
+
+func TestServerProviderErrorFailsOpen(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	r := newRegistryWithSNOMEDValueSet()
+	r.SetProvider(NewServerProvider(srv.URL, nil))
+
+	// Server errors fall back to accepting any code from the external system
+	valid, found := r.ValidateCode("http://example.org/ValueSet/test", "http://snomed.info/sct", "bogus")
+	if !found || !valid {
+		t.Errorf("ValidateCode() = %v, %v; want true, true on server error", valid, found)
+	}
+}
+
+func TestServerProviderProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata" || r.URL.Query().Get("mode") != "terminology" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/fhir+json")
+		fmt.Fprint(w, `{"resourceType":"TerminologyCapabilities","codeSystem":[{"uri":"http://snomed.info/sct|http://snomed.info/sct/900000000000207008"}]}`)
+	}))
+	defer srv.Close()
+	p := NewServerProvider(srv.URL, nil)
+
+	if !p.SupportsSystem("http://loinc.org") {
+		t.Error("SupportsSystem() before Probe = false, want true")
+	}
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() error: %v", err)
+	}
+	if !p.SupportsSystem("http://snomed.info/sct") {
+		t.Error("SupportsSystem(listed) = false, want true")
+	}
+	if p.SupportsSystem("http://loinc.org") {
+		t.Error("SupportsSystem(unlisted) = true, want false")
+	}
+
+	r := NewRegistry()
+	r.SetProvider(p)
+	if r.IsUnsupportedSystem("http://snomed.info/sct") || !r.IsUnsupportedSystem("http://loinc.org") {
+		t.Error("IsUnsupportedSystem() does not follow the probed capabilities")
+	}
+	// Codes from unsupported systems are not sent to the server
+	if valid, found := r.ValidateCodeInCodeSystem("http://loinc.org", "8867-4"); !valid || found {
+		t.Errorf("ValidateCodeInCodeSystem(unsupported) = %v, %v; want true, false", valid, found)
+	}
+}
+
+func TestServerProviderProbeCapabilityStatement(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		fmt.Fprint(w, `{"resourceType":"CapabilityStatement","fhirVersion":"4.0.1"}`)
+	}))
+	defer srv.Close()
+	p := NewServerProvider(srv.URL, nil)
+
+	if err := p.Probe(context.Background()); err != nil {
+		t.Fatalf("Probe() error: %v", err)
+	}
+	if !p.SupportsSystem("http://loinc.org") {
+		t.Error("SupportsSystem() without TerminologyCapabilities = false, want true")
+	}
+}
@@ -4,9 +4,13 @@ package terminology
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 
+	"github.com/gofhir/validator/pkg/cache"
 	"github.com/gofhir/validator/pkg/loader"
 )
 
@@ -23,8 +27,9 @@ type ValueSet struct {
 
 // Compose defines the content of a ValueSet.
 type Compose struct {
-	Include []Include `json:"include,omitempty"`
-	Exclude []Include `json:"exclude,omitempty"`
+	Include  []Include `json:"include,omitempty"`
+	Exclude  []Include `json:"exclude,omitempty"`
+	Inactive *bool     `json:"inactive,omitempty"` // false excludes inactive codes
 }
 
 // Include defines a set of codes to include/exclude.
@@ -51,14 +56,32 @@ type Filter struct {
 
 // CodeSystem represents a FHIR CodeSystem resource.
 type CodeSystem struct {
-	ResourceType string           `json:"resourceType"`
-	ID           string           `json:"id"`
-	URL          string           `json:"url"`
-	Version      string           `json:"version"`
-	Name         string           `json:"name"`
-	Status       string           `json:"status"`
-	Content      string           `json:"content"` // not-present | example | fragment | complete | supplement
-	Concept      []CodeSystemCode `json:"concept,omitempty"`
+	ResourceType     string                         `json:"resourceType"`
+	ID               string                         `json:"id"`
+	URL              string                         `json:"url"`
+	Version          string                         `json:"version"`
+	Name             string                         `json:"name"`
+	Status           string                         `json:"status"`
+	HierarchyMeaning string                         `json:"hierarchyMeaning,omitempty"` // grouped-by | is-a | part-of | classified-with
+	Content          string                         `json:"content"`                    // not-present | example | fragment | complete | supplement
+	Count            *int                           `json:"count,omitempty"`
+	Filter           []CodeSystemFilter             `json:"filter,omitempty"`
+	Property         []CodeSystemPropertyDefinition `json:"property,omitempty"`
+	Concept          []CodeSystemCode               `json:"concept,omitempty"`
+}
+
+// CodeSystemFilter declares a filter that ValueSets can use to select codes
+// from a CodeSystem.
+type CodeSystemFilter struct {
+	Code     string   `json:"code"`
+	Operator []string `json:"operator"`
+}
+
+// CodeSystemPropertyDefinition declares a property that concepts of a
+// CodeSystem can have.
+type CodeSystemPropertyDefinition struct {
+	Code string `json:"code"`
+	Type string `json:"type"`
 }
 
 // CodeSystemCode represents a code in a CodeSystem.
@@ -73,34 +96,60 @@ type CodeSystemCode struct {
 // CodeSystemProperty represents a property of a code in a CodeSystem.
 // Used for hierarchy relationships (subsumedBy) and other metadata.
 type CodeSystemProperty struct {
-	Code      string `json:"code"`
-	ValueCode string `json:"valueCode,omitempty"`
+	Code          string `json:"code"`
+	ValueCode     string `json:"valueCode,omitempty"`
+	ValueBoolean  *bool  `json:"valueBoolean,omitempty"`
+	ValueDateTime string `json:"valueDateTime,omitempty"`
 }
 
 // Registry holds loaded ValueSets and CodeSystems indexed by URL.
 type Registry struct {
 	mu          sync.RWMutex
-	valueSets   map[string]*ValueSet
-	codeSystems map[string]*CodeSystem
+	valueSets   map[string]*ValueSet   // Default definition of each URL
+	codeSystems map[string]*CodeSystem // Default definition of each URL
+
+	// Every loaded version, for versioned references
+	valueSetVersions   *loader.Canonicals[ValueSet]
+	codeSystemVersions *loader.Canonicals[CodeSystem]
+
+	// Mirror base URL -> published base URL, applied to unknown ValueSet URLs
+	mapping map[string]string
 
-	// Cache of expanded ValueSets (URL -> set of valid codes)
-	expansionCache map[string]map[string]bool
+	// Pinned code system versions (system URL -> version), see SetSystemVersions
+	systemVersions map[string]string
 
-	// Cache of hierarchy relationships per CodeSystem (system URL -> parent code -> child codes)
+	// Cache of expanded ValueSets (URL|version -> set of valid codes)
+	expansionCache *cache.LRU[string, map[string]bool]
+
+	// Cache of hierarchy relationships per CodeSystem (system URL|version -> parent code -> child codes)
 	// Built from subsumedBy properties in CodeSystem concepts
 	hierarchyCache map[string]map[string][]string
 
+	// Cache of concept statuses per CodeSystem (system URL|version -> code -> status),
+	// holding only concepts that are inactive, deprecated or abstract
+	conceptCache map[string]map[string]ConceptStatus
+
 	// Optional external terminology provider for systems that can't be expanded locally.
 	provider Provider
+
+	// Optional hook called when ValidateCode resolves a ValueSet.
+	resolveHook func(valueSetURL string)
+
+	// Optional hook called on each expansion cache lookup.
+	cacheHook func(hit bool)
 }
 
 // NewRegistry creates a new terminology Registry.
 func NewRegistry() *Registry {
 	return &Registry{
-		valueSets:      make(map[string]*ValueSet),
-		codeSystems:    make(map[string]*CodeSystem),
-		expansionCache: make(map[string]map[string]bool),
-		hierarchyCache: make(map[string]map[string][]string),
+		valueSets:   make(map[string]*ValueSet),
+		codeSystems: make(map[string]*CodeSystem),
+
+		valueSetVersions:   loader.NewCanonicals[ValueSet](loader.VersionLatest),
+		codeSystemVersions: loader.NewCanonicals[CodeSystem](loader.VersionLatest),
+		expansionCache:     cache.NewLRU(expansionCost),
+		hierarchyCache:     make(map[string]map[string][]string),
+		conceptCache:       make(map[string]map[string]ConceptStatus),
 	}
 }
 
@@ -112,7 +161,17 @@ func (r *Registry) SetProvider(p Provider) {
 	r.provider = p
 }
 
+// SetVersionPolicy sets how an unversioned URL resolves when several versions
+// of the same package define it. It must be called before LoadFromPackages.
+func (r *Registry) SetVersionPolicy(policy loader.VersionPolicy) {
+	r.valueSetVersions.SetPolicy(policy)
+	r.codeSystemVersions.SetPolicy(policy)
+}
+
 // LoadFromPackages loads ValueSets and CodeSystems from packages.
+// When several packages define a URL the last one loaded is its default
+// definition, except between versions of the same package, where the version
+// policy decides. Every version stays available as "url|version".
 func (r *Registry) LoadFromPackages(packages []*loader.Package) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -132,7 +191,7 @@ func (r *Registry) LoadFromPackages(packages []*loader.Package) error {
 				if err := json.Unmarshal(data, &vs); err != nil {
 					continue
 				}
-				if vs.URL != "" {
+				if vs.URL != "" && r.valueSetVersions.Add(pkg, vs.URL, vs.Version, &vs, true) {
 					r.valueSets[vs.URL] = &vs
 				}
 
@@ -141,7 +200,7 @@ func (r *Registry) LoadFromPackages(packages []*loader.Package) error {
 				if err := json.Unmarshal(data, &cs); err != nil {
 					continue
 				}
-				if cs.URL != "" {
+				if cs.URL != "" && r.codeSystemVersions.Add(pkg, cs.URL, cs.Version, &cs, true) {
 					r.codeSystems[cs.URL] = &cs
 				}
 			}
@@ -151,66 +210,214 @@ func (r *Registry) LoadFromPackages(packages []*loader.Package) error {
 	return nil
 }
 
-// GetValueSet returns a ValueSet by URL.
+// SetCanonicalMapping rewrites canonical URL prefixes during lookups, so that
+// references to an internal mirror of a package (mirror base URL -> published
+// base URL) resolve to the loaded ValueSets. Code system URIs in instances are
+// identifiers and are not rewritten. A URL that is loaded as is takes
+// precedence. It must be set before the Registry is used concurrently.
+func (r *Registry) SetCanonicalMapping(mapping map[string]string) {
+	r.mapping = mapping
+}
+
+// SetSystemVersions pins code systems to versions (system URL -> version),
+// like the system-version parameter of $expand, so that results do not change
+// with the terminology content loaded: ValueSet includes that do not name a
+// version, and codes checked against their CodeSystem, use the pinned version
+// when it is loaded. It must be set before the Registry is used concurrently.
+func (r *Registry) SetSystemVersions(versions map[string]string) {
+	r.systemVersions = versions
+}
+
+// GetValueSet returns a ValueSet by URL. A versioned reference
+// ("url|4.0.1") returns that version when it is loaded and the default
+// definition otherwise, since bindings commonly pin the FHIR release whose
+// ValueSets were superseded by the terminology package.
 func (r *Registry) GetValueSet(url string) *ValueSet {
-	// Strip version from URL if present (e.g., "http://...ValueSet/x|4.0.1")
-	url = stripVersion(url)
+	if vs := r.getValueSet(url); vs != nil || len(r.mapping) == 0 {
+		return vs
+	}
+	if mapped, ok := loader.RewriteCanonical(url, r.mapping); ok {
+		return r.getValueSet(mapped)
+	}
+	return nil
+}
+
+// getValueSet is GetValueSet without the canonical mapping.
+func (r *Registry) getValueSet(url string) *ValueSet {
+	url, version := loader.SplitCanonical(url)
 
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.valueSets[url]
+	vs := r.valueSets[url]
+	r.mu.RUnlock()
+	if version == "" || (vs != nil && vs.Version == version) {
+		return vs
+	}
+	if versioned := r.valueSetVersions.Get(url, version); versioned != nil {
+		return versioned
+	}
+	return vs
 }
 
-// GetCodeSystem returns a CodeSystem by URL.
+// GetCodeSystem returns a CodeSystem by URL, resolving a versioned reference
+// like GetValueSet does.
 func (r *Registry) GetCodeSystem(url string) *CodeSystem {
+	url, version := loader.SplitCanonical(url)
+
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.codeSystems[url]
+	cs := r.codeSystems[url]
+	r.mu.RUnlock()
+	if version == "" || (cs != nil && cs.Version == version) {
+		return cs
+	}
+	if versioned := r.codeSystemVersions.Get(url, version); versioned != nil {
+		return versioned
+	}
+	return cs
+}
+
+// IsAmbiguous reports whether several versions of the same package define
+// the ValueSet or CodeSystem url with different versions, so that an
+// unversioned reference resolves according to the version policy.
+func (r *Registry) IsAmbiguous(url string) bool {
+	return r.valueSetVersions.Ambiguous(url) || r.codeSystemVersions.Ambiguous(url)
+}
+
+// AmbiguousCount returns the number of ambiguous URLs (see IsAmbiguous).
+func (r *Registry) AmbiguousCount() int {
+	return r.valueSetVersions.AmbiguousCount() + r.codeSystemVersions.AmbiguousCount()
 }
 
 // ValidateCode checks if a code is valid for a given ValueSet URL.
 // Returns (isValid, found) where found indicates if the ValueSet was found.
 func (r *Registry) ValidateCode(valueSetURL, system, code string) (isValid, found bool) {
+	return r.ValidateCodeContext(context.Background(), valueSetURL, system, code)
+}
+
+// ValidateCodeContext is ValidateCode with a context passed to the external
+// terminology provider.
+func (r *Registry) ValidateCodeContext(ctx context.Context, valueSetURL, system, code string) (isValid, found bool) {
+	return r.ValidateCodeVersions(ctx, valueSetURL, system, code, nil)
+}
+
+// ValidateCodeVersions is ValidateCodeContext with code system versions
+// pinned for this check (system URL -> version), e.g. by a binding. They take
+// precedence over the versions set with SetSystemVersions; includes that name
+// a version keep it.
+func (r *Registry) ValidateCodeVersions(ctx context.Context, valueSetURL, system, code string, versions map[string]string) (isValid, found bool) {
+	codes, found := r.expansion(valueSetURL, r.pinnedVersions(versions))
+	if !found {
+		return false, false
+	}
 	valueSetURL = stripVersion(valueSetURL)
+	if r.resolveHook != nil {
+		r.resolveHook(valueSetURL)
+	}
 
-	// Check cache first
-	r.mu.RLock()
-	if codes, ok := r.expansionCache[valueSetURL]; ok {
-		r.mu.RUnlock()
-		return r.validateWithProvider(codes, system, code, valueSetURL), true
+	return r.validateWithProvider(ctx, codes, system, code, valueSetURL), true
+}
+
+// SetResolveHook registers a function called with the (version-less) URL of
+// every ValueSet resolved by ValidateCode, e.g. to record usage statistics.
+// It must be set before the Registry is used concurrently.
+func (r *Registry) SetResolveHook(fn func(valueSetURL string)) {
+	r.resolveHook = fn
+}
+
+// SetCacheHook registers a function called on every lookup of a ValueSet
+// expansion in the expansion cache, with whether it was cached, e.g. to
+// record cache hit rates. It must be set before the Registry is used
+// concurrently.
+func (r *Registry) SetCacheHook(fn func(hit bool)) {
+	r.cacheHook = fn
+}
+
+// ExpansionCache returns the cache of ValueSet expansions, e.g. to bound its
+// size (see cache.Budget).
+func (r *Registry) ExpansionCache() cache.Cache {
+	return r.expansionCache
+}
+
+// expansionCost estimates the memory used by a cached expansion.
+func expansionCost(key string, codes map[string]bool) int64 {
+	cost := int64(len(key) + 64)
+	for code := range codes {
+		cost += int64(len(code) + 40) // String header, value and map overhead
 	}
-	r.mu.RUnlock()
+	return cost
+}
 
-	// Expand the ValueSet
+// Warm expands a ValueSet into the expansion cache ahead of use.
+// Returns false if the ValueSet is not loaded.
+func (r *Registry) Warm(valueSetURL string) bool {
+	_, found := r.expansion(valueSetURL, r.systemVersions)
+	return found
+}
+
+// expansion returns the cached expansion of a ValueSet with pinned code
+// system versions, expanding it on first use. Expansions are cached per
+// ValueSet version and pinned versions.
+func (r *Registry) expansion(valueSetURL string, versions map[string]string) (map[string]bool, bool) {
 	vs := r.GetValueSet(valueSetURL)
 	if vs == nil {
-		return false, false
+		return nil, false
+	}
+	key := vs.URL + "|" + vs.Version
+	if len(versions) > 0 {
+		pins := make([]string, 0, len(versions))
+		for system, version := range versions {
+			pins = append(pins, system+"|"+version)
+		}
+		slices.Sort(pins)
+		key += "#" + strings.Join(pins, ",")
+	}
+
+	// Check cache first
+	codes, ok := r.expansionCache.Get(key)
+	if r.cacheHook != nil {
+		r.cacheHook(ok)
+	}
+	if ok {
+		return codes, true
 	}
 
-	codes := r.expandValueSet(vs)
+	codes = r.expandValueSet(vs, versions)
 
-	// Cache the expansion
-	r.mu.Lock()
-	r.expansionCache[valueSetURL] = codes
-	r.mu.Unlock()
+	r.expansionCache.Add(key, codes)
 
-	return r.validateWithProvider(codes, system, code, valueSetURL), true
+	return codes, true
+}
+
+// pinnedVersions returns the code system versions in effect for a check:
+// versions over those set with SetSystemVersions.
+func (r *Registry) pinnedVersions(versions map[string]string) map[string]string {
+	if len(versions) == 0 {
+		return r.systemVersions
+	}
+	if len(r.systemVersions) == 0 {
+		return versions
+	}
+	merged := maps.Clone(r.systemVersions)
+	maps.Copy(merged, versions)
+	return merged
 }
 
 // validateWithProvider checks a code against expanded codes, delegating to the
 // external provider for external systems when one is configured.
-func (r *Registry) validateWithProvider(codes map[string]bool, system, code, valueSetURL string) bool {
-	if r.provider != nil && system != "" && r.isExternalSystem(system) {
+func (r *Registry) validateWithProvider(ctx context.Context, codes map[string]bool, system, code, valueSetURL string) bool {
+	if r.provider != nil && system != "" && r.isExternalSystem(system) && r.providerSupports(system) {
 		// Try ValueSet-specific validation first (more precise)
-		valid, vsFound, err := r.provider.ValidateCodeInValueSet(
-			context.Background(), system, code, valueSetURL)
+		valid, vsFound, err := r.providerValidateCodeInValueSet(
+			ctx, system, code, valueSetURL)
 		if err == nil && vsFound {
 			return valid
 		}
-		// Fall back to system-level validation
-		valid, err = r.provider.ValidateCode(context.Background(), system, code)
-		if err == nil {
-			return valid
+		// Fall back to system-level validation; while collecting, Prefetch
+		// makes this call itself if the ValueSet answer needs it
+		if !errors.Is(err, errCollecting) {
+			valid, err = r.providerValidateCode(ctx, system, code)
+			if err == nil {
+				return valid
+			}
 		}
 		// Error from provider → fall through to wildcard (fail-open)
 	}
@@ -226,11 +433,14 @@ func (r *Registry) checkCode(codes map[string]bool, system, code string) bool {
 
 	// For code elements (no system), just check the code
 	if system == "" {
-		return codes[code]
+		return codes[code] || r.checkImplicitCode(codes, code)
 	}
 
-	// Check for system-specific wildcard
+	// Check for system-specific wildcard, validating implicit systems
 	if codes[system+"|*"] {
+		if valid := implicitSystems[system]; valid != nil {
+			return valid(code)
+		}
 		return true
 	}
 
@@ -241,21 +451,32 @@ func (r *Registry) checkCode(codes map[string]bool, system, code string) bool {
 // expandValueSet expands a ValueSet to a set of valid codes.
 // Returns a map where keys are either "code" (for code elements) or "system|code" (for Coding).
 // Special marker "*" is added when the ValueSet includes external systems that can't be expanded.
-func (r *Registry) expandValueSet(vs *ValueSet) map[string]bool {
+// Includes without a version use the version pinned in versions, if any.
+func (r *Registry) expandValueSet(vs *ValueSet, versions map[string]string) map[string]bool {
 	codes := make(map[string]bool)
+	activeOnly := vs.Compose.Inactive != nil && !*vs.Compose.Inactive
 
 	for _, inc := range vs.Compose.Include {
-		r.expandInclude(codes, &inc)
+		r.expandInclude(codes, &inc, activeOnly, versions)
 	}
 
 	return codes
 }
 
 // expandInclude expands a single Include clause into the codes map.
-func (r *Registry) expandInclude(codes map[string]bool, inc *Include) {
+// With activeOnly, inactive codes are left out.
+func (r *Registry) expandInclude(codes map[string]bool, inc *Include, activeOnly bool, versions map[string]string) {
 	// If specific concepts are listed, use them
 	if len(inc.Concept) > 0 {
-		r.addExplicitConcepts(codes, inc)
+		r.addExplicitConcepts(codes, inc, activeOnly, versions)
+		return
+	}
+
+	// Implicit systems are validated by grammar or table; filters (e.g., the
+	// regex filters of the ISO 3166 ValueSets) are not applied
+	if implicitSystems[inc.System] != nil && !r.hasConcepts(inc.System) {
+		codes[inc.System+"|*"] = true
+		r.expandNestedValueSets(codes, inc.ValueSet, versions)
 		return
 	}
 
@@ -267,15 +488,19 @@ func (r *Registry) expandInclude(codes map[string]bool, inc *Include) {
 	}
 
 	// Expand from CodeSystem
-	r.expandFromCodeSystem(codes, inc)
+	r.expandFromCodeSystem(codes, inc, activeOnly, versions)
 
 	// Handle nested ValueSets
-	r.expandNestedValueSets(codes, inc.ValueSet)
+	r.expandNestedValueSets(codes, inc.ValueSet, versions)
 }
 
 // addExplicitConcepts adds explicitly listed concepts to the codes map.
-func (r *Registry) addExplicitConcepts(codes map[string]bool, inc *Include) {
+// Listed abstract concepts are kept: the ValueSet selected them.
+func (r *Registry) addExplicitConcepts(codes map[string]bool, inc *Include, activeOnly bool, versions map[string]string) {
 	for _, c := range inc.Concept {
+		if activeOnly && inc.System != "" && r.ConceptStatus(includeCanonical(inc, versions), c.Code).Inactive {
+			continue
+		}
 		codes[c.Code] = true
 		if inc.System != "" {
 			codes[inc.System+"|"+c.Code] = true
@@ -284,31 +509,74 @@ func (r *Registry) addExplicitConcepts(codes map[string]bool, inc *Include) {
 }
 
 // expandFromCodeSystem expands codes from a CodeSystem, applying filters if present.
-func (r *Registry) expandFromCodeSystem(codes map[string]bool, inc *Include) {
+// Abstract concepts, and inactive ones with activeOnly, are not selectable and
+// are left out.
+func (r *Registry) expandFromCodeSystem(codes map[string]bool, inc *Include, activeOnly bool, versions map[string]string) {
 	if inc.System == "" {
 		return
 	}
 
-	cs := r.GetCodeSystem(inc.System)
+	cs := r.GetCodeSystem(includeCanonical(inc, versions))
 	if cs == nil {
 		return
 	}
 
+	selected := make(map[string]bool)
 	if len(inc.Filter) == 0 {
-		r.addCodesFromCodeSystem(codes, cs, inc.System)
+		r.addCodesFromCodeSystem(selected, cs, inc.System)
 	} else {
-		r.applyFilters(codes, cs, inc.System, inc.Filter)
+		r.applyFilters(selected, cs, inc.System, inc.Filter)
+	}
+
+	statuses := r.conceptStatuses(cs)
+	for key := range selected {
+		code := strings.TrimPrefix(key, inc.System+"|")
+		if status := statuses[code]; status.Abstract || (activeOnly && status.Inactive) {
+			continue
+		}
+		codes[key] = true
 	}
 }
 
+// checkImplicitCode checks a code without a system against the implicit
+// systems the expanded codes include.
+func (r *Registry) checkImplicitCode(codes map[string]bool, code string) bool {
+	for system, valid := range implicitSystems {
+		if codes[system+"|*"] && valid(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasConcepts reports whether a CodeSystem defining concepts is loaded for
+// system, which then takes precedence over its implicit validation.
+func (r *Registry) hasConcepts(system string) bool {
+	cs := r.GetCodeSystem(system)
+	return cs != nil && len(cs.Concept) > 0
+}
+
+// includeCanonical returns the versioned system of an include: the version it
+// names, else the version pinned in versions.
+func includeCanonical(inc *Include, versions map[string]string) string {
+	version := inc.Version
+	if version == "" {
+		version = versions[inc.System]
+	}
+	if version == "" {
+		return inc.System
+	}
+	return inc.System + "|" + version
+}
+
 // expandNestedValueSets recursively expands nested ValueSets.
-func (r *Registry) expandNestedValueSets(codes map[string]bool, nestedURLs []string) {
+func (r *Registry) expandNestedValueSets(codes map[string]bool, nestedURLs []string, versions map[string]string) {
 	for _, nestedVSURL := range nestedURLs {
 		nestedVS := r.GetValueSet(nestedVSURL)
 		if nestedVS == nil {
 			continue
 		}
-		for code := range r.expandValueSet(nestedVS) {
+		for code := range r.expandValueSet(nestedVS, versions) {
 			codes[code] = true
 		}
 	}
@@ -316,14 +584,8 @@ func (r *Registry) expandNestedValueSets(codes map[string]bool, nestedURLs []str
 
 // externalSystems contains systems that cannot be locally expanded and require a terminology server.
 var externalSystems = map[string]bool{
-	// IANA MIME types - includes all valid MIME types
-	"urn:ietf:bcp:13": true,
-	// IANA language tags - includes all valid language codes
-	"urn:ietf:bcp:47": true,
 	// IANA timezones
 	"urn:iana:tz": true,
-	// ISO 3166-1 country codes
-	"urn:iso:std:iso:3166": true,
 	// ISO 4217 currency codes
 	"urn:iso:std:iso:4217": true,
 	// SNOMED CT - large terminology requiring server
@@ -351,6 +613,29 @@ func (r *Registry) IsExternalSystem(system string) bool {
 	return externalSystems[system]
 }
 
+// IsUnsupportedSystem reports whether the system requires a terminology
+// server but the configured provider does not support it (see
+// SystemSupporter), so its codes are accepted without validation.
+func (r *Registry) IsUnsupportedSystem(system string) bool {
+	return r.provider != nil && r.isExternalSystem(system) && !r.providerSupports(system)
+}
+
+// IsCheckable reports whether codes of system are checked rather than
+// accepted: systems expanded locally, and external systems the configured
+// provider supports.
+func (r *Registry) IsCheckable(system string) bool {
+	return !r.isExternalSystem(system) || (r.provider != nil && r.providerSupports(system))
+}
+
+// providerSupports reports whether the provider claims system; providers that
+// do not implement SystemSupporter claim every system.
+func (r *Registry) providerSupports(system string) bool {
+	if s, ok := r.provider.(SystemSupporter); ok {
+		return s.SupportsSystem(system)
+	}
+	return true
+}
+
 // addCodesFromCodeSystem recursively adds codes from a CodeSystem.
 func (r *Registry) addCodesFromCodeSystem(codes map[string]bool, cs *CodeSystem, system string) {
 	var addConcepts func(concepts []CodeSystemCode)
@@ -427,12 +712,12 @@ func (r *Registry) applyEqualityFilter(codes map[string]bool, cs *CodeSystem, sy
 // getOrBuildHierarchy returns the hierarchy for a CodeSystem, building it if necessary.
 // The hierarchy maps parent codes to their child codes, derived from subsumedBy properties.
 func (r *Registry) getOrBuildHierarchy(cs *CodeSystem) map[string][]string {
-	if hierarchy, ok := r.hierarchyCache[cs.URL]; ok {
+	if hierarchy, ok := r.hierarchyCache[cs.URL+"|"+cs.Version]; ok {
 		return hierarchy
 	}
 
 	hierarchy := r.buildHierarchy(cs)
-	r.hierarchyCache[cs.URL] = hierarchy
+	r.hierarchyCache[cs.URL+"|"+cs.Version] = hierarchy
 	return hierarchy
 }
 
@@ -480,10 +765,93 @@ func (r *Registry) CodeSystemCount() int {
 	return len(r.codeSystems)
 }
 
+// ExampleCode returns a code from the local expansion of a ValueSet, for
+// generating example instances. The lowest system|code is chosen so results
+// are stable. Returns false if the ValueSet is unknown or has no locally
+// expandable codes.
+func (r *Registry) ExampleCode(valueSetURL string) (system, code string, ok bool) {
+	codes, found := r.expansion(valueSetURL, r.systemVersions)
+	if !found {
+		return "", "", false
+	}
+	best := ""
+	for key := range codes {
+		if !strings.Contains(key, "|") || strings.HasSuffix(key, "|*") {
+			continue
+		}
+		if best == "" || key < best {
+			best = key
+		}
+	}
+	if best == "" {
+		return "", "", false
+	}
+	system, code, _ = strings.Cut(best, "|")
+	return system, code, true
+}
+
+// EnumerateCodes returns the distinct codes of the local expansion of a
+// ValueSet, sorted, for exports that list them (e.g., JSON Schema enums).
+// Returns false if the ValueSet is unknown, has more than limit codes, or
+// includes codes that cannot be listed locally: those of external or
+// grammar-based systems, or of CodeSystems that are not loaded in full.
+func (r *Registry) EnumerateCodes(valueSetURL string, limit int) ([]string, bool) {
+	vs := r.GetValueSet(valueSetURL)
+	if vs == nil || !r.enumerable(vs, make(map[string]bool)) {
+		return nil, false
+	}
+	codes, _ := r.expansion(valueSetURL, r.systemVersions)
+	seen := make(map[string]bool)
+	var out []string
+	for key := range codes {
+		_, code, found := strings.Cut(key, "|")
+		if !found || code == "*" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		out = append(out, code)
+	}
+	if len(out) == 0 || len(out) > limit {
+		return nil, false
+	}
+	slices.Sort(out)
+	return out, true
+}
+
+// enumerable reports whether every include of a ValueSet, and of the
+// ValueSets it imports, lists its codes or draws them from a complete
+// CodeSystem that is loaded.
+func (r *Registry) enumerable(vs *ValueSet, visited map[string]bool) bool {
+	if visited[vs.URL] {
+		return true
+	}
+	visited[vs.URL] = true
+	for _, inc := range vs.Compose.Include {
+		if inc.System == "" && len(inc.ValueSet) == 0 {
+			return false
+		}
+		if inc.System != "" && len(inc.Concept) == 0 {
+			cs := r.GetCodeSystem(includeCanonical(&inc, r.systemVersions))
+			if cs == nil || cs.Content != "complete" || r.isExternalSystem(inc.System) {
+				return false
+			}
+		}
+		for _, url := range inc.ValueSet {
+			nested := r.GetValueSet(url)
+			if nested == nil || !r.enumerable(nested, visited) {
+				return false
+			}
+		}
+	}
+	return len(vs.Compose.Include) > 0
+}
+
 // GetDisplayForCode returns the display text for a code in a CodeSystem.
 // Returns (display, found) where found indicates if the code was found.
+// The system may name a version ("url|version"); otherwise the version
+// pinned with SetSystemVersions is used.
 func (r *Registry) GetDisplayForCode(system, code string) (string, bool) {
-	cs := r.GetCodeSystem(system)
+	cs := r.pinnedCodeSystem(system)
 	if cs == nil {
 		return "", false
 	}
@@ -514,8 +882,6 @@ func (r *Registry) IsSystemInValueSet(valueSetURL, system string) bool {
 		return false
 	}
 
-	valueSetURL = stripVersion(valueSetURL)
-
 	vs := r.GetValueSet(valueSetURL)
 	if vs == nil {
 		return false
@@ -545,14 +911,23 @@ func (r *Registry) IsSystemInValueSet(valueSetURL, system string) bool {
 // This is used to validate that codes exist in their declared CodeSystems,
 // regardless of any ValueSet binding.
 func (r *Registry) ValidateCodeInCodeSystem(system, code string) (isValid, codeSystemFound bool) {
+	return r.ValidateCodeInCodeSystemContext(context.Background(), system, code)
+}
+
+// ValidateCodeInCodeSystemContext is ValidateCodeInCodeSystem with a context
+// passed to the external terminology provider. The system may name a version
+// ("url|version"); otherwise the version pinned with SetSystemVersions is
+// used.
+func (r *Registry) ValidateCodeInCodeSystemContext(ctx context.Context, system, code string) (isValid, codeSystemFound bool) {
 	if system == "" || code == "" {
 		return false, false
 	}
+	url, _ := loader.SplitCanonical(system)
 
 	// Check if this is an external system we can't validate locally
-	if r.isExternalSystem(system) {
-		if r.provider != nil {
-			valid, err := r.provider.ValidateCode(context.Background(), system, code)
+	if r.isExternalSystem(url) {
+		if r.provider != nil && r.providerSupports(url) {
+			valid, err := r.providerValidateCode(ctx, url, code)
 			if err == nil {
 				return valid, true
 			}
@@ -560,7 +935,11 @@ func (r *Registry) ValidateCodeInCodeSystem(system, code string) (isValid, codeS
 		return true, false // Accept but mark as not locally validated
 	}
 
-	cs := r.GetCodeSystem(system)
+	if valid := implicitSystems[url]; valid != nil && !r.hasConcepts(url) {
+		return valid(code), true
+	}
+
+	cs := r.pinnedCodeSystem(system)
 	if cs == nil {
 		return false, false // CodeSystem not loaded
 	}
@@ -584,6 +963,75 @@ func (r *Registry) ValidateCodeInCodeSystem(system, code string) (isValid, codeS
 	return findCode(cs.Concept), true
 }
 
+// pinnedCodeSystem returns the CodeSystem for system, in the version pinned
+// with SetSystemVersions unless system names a version.
+func (r *Registry) pinnedCodeSystem(system string) *CodeSystem {
+	if version := r.systemVersions[system]; version != "" {
+		return r.GetCodeSystem(system + "|" + version)
+	}
+	return r.GetCodeSystem(system)
+}
+
+// CodeSystemVersion returns the code system, as "system|version", that codes
+// of system are checked against for a ValueSet: in the version its include
+// names, else the one pinned in versions or with SetSystemVersions, else the
+// version of the loaded CodeSystem. An empty system stands for the system of
+// a ValueSet that includes a single one. Returns "" when the version is not
+// known.
+func (r *Registry) CodeSystemVersion(valueSetURL, system string, versions map[string]string) string {
+	includes := r.systemIncludes(valueSetURL, nil, make(map[string]bool))
+	if system == "" {
+		for _, inc := range includes {
+			if system != "" && inc.System != system {
+				return "" // Several systems
+			}
+			system = inc.System
+		}
+		if system == "" {
+			return ""
+		}
+	}
+
+	version := ""
+	for _, inc := range includes {
+		if inc.System == system && inc.Version != "" {
+			version = inc.Version
+			break
+		}
+	}
+	if version == "" {
+		version = r.pinnedVersions(versions)[system]
+	}
+	if version == "" {
+		if cs := r.GetCodeSystem(system); cs != nil {
+			version = cs.Version
+		}
+	}
+	if version == "" {
+		return ""
+	}
+	return system + "|" + version
+}
+
+// systemIncludes appends the includes of a ValueSet and the ValueSets it
+// includes that select codes from a system.
+func (r *Registry) systemIncludes(valueSetURL string, includes []Include, seen map[string]bool) []Include {
+	vs := r.GetValueSet(valueSetURL)
+	if vs == nil || seen[vs.URL] {
+		return includes
+	}
+	seen[vs.URL] = true
+	for _, inc := range vs.Compose.Include {
+		if inc.System != "" {
+			includes = append(includes, inc)
+		}
+		for _, nested := range inc.ValueSet {
+			includes = r.systemIncludes(nested, includes, seen)
+		}
+	}
+	return includes
+}
+
 // stripVersion removes version from ValueSet URL (e.g., "url|4.0.1" -> "url").
 func stripVersion(url string) string {
 	if idx := strings.LastIndex(url, "|"); idx != -1 {
@@ -1,7 +1,12 @@
 package terminology
 
 import (
+	"context"
+	"encoding/json"
+	"slices"
 	"testing"
+
+	"github.com/gofhir/validator/pkg/loader"
 )
 
 func TestBuildHierarchy(t *testing.T) {
@@ -202,3 +207,175 @@ func TestNestedHierarchy(t *testing.T) {
 		t.Error("Expected CHILD to be in codes (grandchild of ROOT)")
 	}
 }
+
+func TestValueSetVersions(t *testing.T) {
+	const url = "http://example.org/ValueSet/status"
+	valueSet := func(version, code string) json.RawMessage {
+		return json.RawMessage(`{"resourceType": "ValueSet", "url": "` + url + `", "version": "` + version + `",
+			"compose": {"include": [{"system": "http://example.org/status", "concept": [{"code": "` + code + `"}]}]}}`)
+	}
+	packages := []*loader.Package{
+		{Name: "example.ig", Version: "3.1.1", Resources: map[string]json.RawMessage{url: valueSet("3.1.1", "old")}},
+		{Name: "example.ig", Version: "6.1.0", Resources: map[string]json.RawMessage{url: valueSet("6.1.0", "new")}},
+	}
+
+	r := NewRegistry()
+	if err := r.LoadFromPackages(packages); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+	tests := []struct {
+		valueSetURL string
+		code        string
+		want        bool
+	}{
+		{url, "new", true},
+		{url, "old", false},
+		{url + "|3.1.1", "old", true},
+		{url + "|3.1.1", "new", false},
+		{url + "|6.1", "new", true},
+		{url + "|4.0.1", "new", true}, // Unknown versions fall back to the default
+	}
+	for _, tt := range tests {
+		if valid, found := r.ValidateCode(tt.valueSetURL, "", tt.code); !found || valid != tt.want {
+			t.Errorf("ValidateCode(%s, %s) = (%v, %v), want (%v, true)", tt.valueSetURL, tt.code, valid, found, tt.want)
+		}
+	}
+	if !r.IsAmbiguous(url) {
+		t.Error("IsAmbiguous() = false, want true")
+	}
+
+	r = NewRegistry()
+	r.SetVersionPolicy(loader.VersionFirstLoaded)
+	if err := r.LoadFromPackages(packages); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+	if vs := r.GetValueSet(url); vs.Version != "3.1.1" {
+		t.Errorf("first loaded policy: GetValueSet() version = %q, want 3.1.1", vs.Version)
+	}
+}
+
+func TestValueSetCanonicalMapping(t *testing.T) {
+	r := NewRegistry()
+	vs := &ValueSet{
+		URL:     "http://example.org/ValueSet/status",
+		Compose: Compose{Include: []Include{{System: "http://example.org/status", Concept: []Concept{{Code: "active"}}}}},
+	}
+	r.valueSets[vs.URL] = vs
+	r.SetCanonicalMapping(map[string]string{"https://mirror.example.org/": "http://example.org/"})
+
+	valid, found := r.ValidateCode("https://mirror.example.org/ValueSet/status", "http://example.org/status", "active")
+	if !found || !valid {
+		t.Errorf("ValidateCode(mirrored) = (%v, %v), want (true, true)", valid, found)
+	}
+	if _, found := r.ValidateCode("https://other.example.org/ValueSet/status", "", "active"); found {
+		t.Error("ValidateCode(unmapped) found a ValueSet")
+	}
+}
+
+func TestSystemVersions(t *testing.T) {
+	const system = "http://example.org/lab"
+	codeSystem := func(version, code string) json.RawMessage {
+		return json.RawMessage(`{"resourceType": "CodeSystem", "url": "` + system + `", "version": "` + version + `",
+			"content": "complete", "concept": [{"code": "` + code + `"}]}`)
+	}
+	valueSet := func(url, version string) json.RawMessage {
+		return json.RawMessage(`{"resourceType": "ValueSet", "url": "` + url + `",
+			"compose": {"include": [{"system": "` + system + `", "version": "` + version + `"}]}}`)
+	}
+	const (
+		unversioned = "http://example.org/ValueSet/lab"
+		pinned      = "http://example.org/ValueSet/lab-1"
+	)
+	packages := []*loader.Package{
+		{Name: "example.lab", Version: "1.0.0", Resources: map[string]json.RawMessage{system: codeSystem("1.0", "old")}},
+		{Name: "example.lab", Version: "2.0.0", Resources: map[string]json.RawMessage{
+			system:      codeSystem("2.0", "new"),
+			unversioned: valueSet(unversioned, ""),
+			pinned:      valueSet(pinned, "1.0"),
+		}},
+	}
+
+	r := NewRegistry()
+	if err := r.LoadFromPackages(packages); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+	tests := []struct {
+		name        string
+		valueSetURL string
+		code        string
+		versions    map[string]string
+		want        bool
+	}{
+		{"latest", unversioned, "new", nil, true},
+		{"latest", unversioned, "old", nil, false},
+		{"binding pin", unversioned, "old", map[string]string{system: "1.0"}, true},
+		{"binding pin", unversioned, "new", map[string]string{system: "1.0"}, false},
+		{"include version", pinned, "old", map[string]string{system: "2.0"}, true},
+	}
+	for _, tt := range tests {
+		valid, found := r.ValidateCodeVersions(context.Background(), tt.valueSetURL, system, tt.code, tt.versions)
+		if !found || valid != tt.want {
+			t.Errorf("%s: ValidateCodeVersions(%s) = (%v, %v), want (%v, true)", tt.name, tt.code, valid, found, tt.want)
+		}
+	}
+	if got := r.CodeSystemVersion(unversioned, system, nil); got != system+"|2.0" {
+		t.Errorf("CodeSystemVersion() = %q, want %s|2.0", got, system)
+	}
+	if got := r.CodeSystemVersion(pinned, "", nil); got != system+"|1.0" {
+		t.Errorf("CodeSystemVersion(include version) = %q, want %s|1.0", got, system)
+	}
+
+	r.SetSystemVersions(map[string]string{system: "1.0"})
+	if valid, _ := r.ValidateCode(unversioned, system, "old"); !valid {
+		t.Error("ValidateCode(old) with pinned 1.0 = false, want true")
+	}
+	if valid, _ := r.ValidateCodeInCodeSystem(system, "new"); valid {
+		t.Error("ValidateCodeInCodeSystem(new) with pinned 1.0 = true, want false")
+	}
+	if got := r.CodeSystemVersion(unversioned, system, map[string]string{system: "2.0"}); got != system+"|2.0" {
+		t.Errorf("CodeSystemVersion(binding pin) = %q, want %s|2.0", got, system)
+	}
+}
+
+func TestEnumerateCodes(t *testing.T) {
+	r := NewRegistry()
+	r.codeSystems["http://example.org/color"] = &CodeSystem{
+		URL: "http://example.org/color", Content: "complete",
+		Concept: []CodeSystemCode{{Code: "red"}, {Code: "green", Concept: []CodeSystemCode{{Code: "lime"}}}},
+	}
+	r.codeSystems["http://example.org/partial"] = &CodeSystem{
+		URL: "http://example.org/partial", Content: "fragment", Concept: []CodeSystemCode{{Code: "x"}},
+	}
+	valueSets := map[string]Include{
+		"http://example.org/vs/color":    {System: "http://example.org/color"},
+		"http://example.org/vs/listed":   {System: "http://loinc.org", Concept: []Concept{{Code: "b"}, {Code: "a"}}},
+		"http://example.org/vs/partial":  {System: "http://example.org/partial"},
+		"http://example.org/vs/missing":  {System: "http://example.org/unknown"},
+		"http://example.org/vs/external": {System: "http://snomed.info/sct"},
+		"http://example.org/vs/imported": {ValueSet: []string{"http://example.org/vs/color"}},
+	}
+	for url, inc := range valueSets {
+		r.valueSets[url] = &ValueSet{URL: url, Compose: Compose{Include: []Include{inc}}}
+	}
+
+	tests := []struct {
+		url   string
+		limit int
+		want  []string
+	}{
+		{"http://example.org/vs/color", 10, []string{"green", "lime", "red"}},
+		{"http://example.org/vs/color", 2, nil},
+		{"http://example.org/vs/listed", 10, []string{"a", "b"}},
+		{"http://example.org/vs/imported", 10, []string{"green", "lime", "red"}},
+		{"http://example.org/vs/partial", 10, nil},
+		{"http://example.org/vs/missing", 10, nil},
+		{"http://example.org/vs/external", 10, nil},
+		{"http://example.org/vs/unknown", 10, nil},
+	}
+	for _, tt := range tests {
+		got, ok := r.EnumerateCodes(tt.url, tt.limit)
+		if ok != (tt.want != nil) || !slices.Equal(got, tt.want) {
+			t.Errorf("EnumerateCodes(%s, %d) = (%v, %v), want %v", tt.url, tt.limit, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Package testkit locks validation behavior with a golden corpus: a
+// directory of resources, each with a manifest of the outcome expected from
+// validating it. Downstream users run the corpus in CI to notice when a
+// validator upgrade, a package update or a configuration change alters what
+// is reported.
+//
+// The manifest of "patient.json" is "patient.expected.json" in the same
+// directory. It can expect error and warning counts and diagnostics by ID,
+// optionally with their severity and expression:
+//
+//	{
+//	  "errors": 1,
+//	  "warnings": 0,
+//	  "diagnostics": [
+//	    {"id": "CODE_NOT_IN_VALUESET", "severity": "error", "expression": "Patient.gender"}
+//	  ]
+//	}
+//
+// Omitted counts are not checked, and other diagnostics than those listed
+// may be reported. Generate writes manifests from the current output, to
+// create a corpus or accept intended changes.
+package testkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+// ManifestSuffix replaces the ".json" extension of a resource file to name
+// its manifest.
+const ManifestSuffix = ".expected.json"
+
+// Manifest is the expected outcome of validating one resource.
+type Manifest struct {
+	Errors      *int               `json:"errors,omitempty"`
+	Warnings    *int               `json:"warnings,omitempty"`
+	Diagnostics []ExpectedIssue    `json:"diagnostics,omitempty"`
+	Options     *ValidationOptions `json:"options,omitempty"`
+}
+
+// ExpectedIssue is a diagnostic that must be reported. Severity and
+// Expression are only compared when set.
+type ExpectedIssue struct {
+	ID         string `json:"id"`
+	Severity   string `json:"severity,omitempty"`
+	Expression string `json:"expression,omitempty"`
+}
+
+// ValidationOptions are per-file options of a manifest.
+type ValidationOptions struct {
+	Profiles []string `json:"profiles,omitempty"` // Profiles to validate against (ValidateWithProfile)
+}
+
+// FileResult is the outcome of one resource of the corpus.
+type FileResult struct {
+	Path     string        // Resource file
+	Manifest *Manifest     // Nil if the manifest is missing or unreadable
+	Result   *issue.Result // Nil if the resource could not be validated
+	Failures []string      // Differences from the manifest
+}
+
+// Passed reports whether the resource was validated as its manifest expects.
+func (f *FileResult) Passed() bool {
+	return len(f.Failures) == 0
+}
+
+// Report is the outcome of a corpus run.
+type Report struct {
+	Files []FileResult
+}
+
+// Passed reports whether every resource passed.
+func (r *Report) Passed() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the resources that did not pass.
+func (r *Report) Failed() []FileResult {
+	var failed []FileResult
+	for _, f := range r.Files {
+		if !f.Passed() {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+// Run validates every resource under dir and compares the outcome with its
+// manifest. Resources without a manifest fail. An error is returned only if
+// the corpus cannot be listed or ctx ends.
+func Run(ctx context.Context, v *validator.Validator, dir string) (*Report, error) {
+	files, err := resourceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Files: make([]FileResult, 0, len(files))}
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Files = append(report.Files, runFile(ctx, v, path))
+	}
+	return report, nil
+}
+
+// Check runs the corpus under dir as subtests of t, one per resource,
+// reporting the differences from each manifest as test errors.
+func Check(t *testing.T, v *validator.Validator, dir string) {
+	t.Helper()
+	files, err := resourceFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no resources in %s", dir)
+	}
+	for _, path := range files {
+		name, _ := filepath.Rel(dir, path)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			f := runFile(t.Context(), v, path)
+			for _, failure := range f.Failures {
+				t.Error(failure)
+			}
+		})
+	}
+}
+
+// Generate validates every resource under dir and writes its manifest from
+// the outcome: the error and warning counts and every diagnostic with an ID.
+// Existing manifests are replaced, keeping their options.
+func Generate(ctx context.Context, v *validator.Validator, dir string) error {
+	files, err := resourceFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		var options *ValidationOptions
+		if m, err := readManifest(manifestPath(path)); err == nil {
+			options = m.Options
+		}
+		result, err := validate(ctx, v, path, options)
+		if err != nil {
+			return err
+		}
+		m := NewManifest(result)
+		m.Options = options
+		if err := writeManifest(manifestPath(path), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewManifest returns the manifest that a result satisfies exactly.
+func NewManifest(result *issue.Result) *Manifest {
+	errs, warnings := result.ErrorCount(), result.WarningCount()
+	m := &Manifest{Errors: &errs, Warnings: &warnings}
+	for _, iss := range result.Issues {
+		if iss.MessageID == "" {
+			continue
+		}
+		m.Diagnostics = append(m.Diagnostics, ExpectedIssue{
+			ID:         iss.MessageID,
+			Severity:   string(iss.Severity),
+			Expression: strings.Join(iss.Expression, ", "),
+		})
+	}
+	return m
+}
+
+// Compare returns the differences between a result and a manifest.
+func Compare(m *Manifest, result *issue.Result) []string {
+	var failures []string
+	if m.Errors != nil && result.ErrorCount() != *m.Errors {
+		failures = append(failures, fmt.Sprintf("errors = %d, want %d%s", result.ErrorCount(), *m.Errors, listIssues(result, issue.SeverityError)))
+	}
+	if m.Warnings != nil && result.WarningCount() != *m.Warnings {
+		failures = append(failures, fmt.Sprintf("warnings = %d, want %d%s", result.WarningCount(), *m.Warnings, listIssues(result, issue.SeverityWarning)))
+	}
+
+	// Each issue satisfies one expected diagnostic, so repeated expectations
+	// need repeated issues
+	used := make([]bool, len(result.Issues))
+	for _, want := range m.Diagnostics {
+		found := false
+		for i, iss := range result.Issues {
+			if !used[i] && want.matches(&iss) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("missing diagnostic %s", want))
+		}
+	}
+	return failures
+}
+
+// matches reports whether an issue is the expected diagnostic.
+func (e ExpectedIssue) matches(iss *issue.Issue) bool {
+	return iss.MessageID == e.ID &&
+		(e.Severity == "" || string(iss.Severity) == e.Severity) &&
+		(e.Expression == "" || strings.Join(iss.Expression, ", ") == e.Expression)
+}
+
+// String formats the expected diagnostic for failure messages.
+func (e ExpectedIssue) String() string {
+	s := e.ID
+	if e.Severity != "" {
+		s += " (" + e.Severity + ")"
+	}
+	if e.Expression != "" {
+		s += " at " + e.Expression
+	}
+	return s
+}
+
+// runFile validates one resource and compares the outcome with its manifest.
+func runFile(ctx context.Context, v *validator.Validator, path string) FileResult {
+	f := FileResult{Path: path}
+	m, err := readManifest(manifestPath(path))
+	if err != nil {
+		f.Failures = append(f.Failures, err.Error())
+		return f
+	}
+	f.Manifest = m
+	if f.Result, err = validate(ctx, v, path, m.Options); err != nil {
+		f.Failures = append(f.Failures, err.Error())
+		return f
+	}
+	f.Failures = Compare(m, f.Result)
+	return f
+}
+
+// validate validates a resource file with the options of its manifest.
+func validate(ctx context.Context, v *validator.Validator, path string, options *ValidationOptions) (*issue.Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var opts []validator.ValidateOption
+	if options != nil {
+		for _, profile := range options.Profiles {
+			opts = append(opts, validator.ValidateWithProfile(profile))
+		}
+	}
+	result, err := v.Validate(ctx, data, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("validating %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// resourceFiles lists the resource files under dir in lexical order,
+// skipping manifests and hidden directories.
+func resourceFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".json") && !strings.HasSuffix(path, ManifestSuffix) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading corpus %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// manifestPath returns the manifest file of a resource file.
+func manifestPath(path string) string {
+	return strings.TrimSuffix(path, ".json") + ManifestSuffix
+}
+
+// readManifest reads a manifest file.
+func readManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no manifest %s (create it with Generate)", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// writeManifest writes a manifest file.
+func writeManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// listIssues formats the diagnostics of the issues with a severity, for
+// count failures.
+func listIssues(result *issue.Result, severity issue.Severity) string {
+	var lines []string
+	for _, iss := range result.Issues {
+		if iss.Severity == severity || (severity == issue.SeverityError && iss.Severity == issue.SeverityFatal) {
+			lines = append(lines, fmt.Sprintf("  %s: %s", strings.Join(iss.Expression, ", "), iss.Diagnostics))
+		}
+	}
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		return ""
+	}
+	return ":\n" + strings.Join(lines, "\n")
+}
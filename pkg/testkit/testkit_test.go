@@ -0,0 +1,161 @@
+package testkit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+var (
+	testValidator     *validator.Validator
+	testValidatorErr  error
+	testValidatorOnce sync.Once
+)
+
+// getTestValidator creates a validator once for all tests.
+func getTestValidator(t *testing.T) *validator.Validator {
+	t.Helper()
+	testValidatorOnce.Do(func() {
+		testValidator, testValidatorErr = validator.New()
+	})
+	if testValidatorErr != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", testValidatorErr)
+	}
+	return testValidator
+}
+
+// writeCorpus writes files (relative path -> content) to a new directory.
+func writeCorpus(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const (
+	validPatient   = `{"resourceType": "Patient", "active": true}`
+	invalidPatient = `{"resourceType": "Patient", "gender": "bogus"}`
+)
+
+func TestGenerateAndRun(t *testing.T) {
+	v := getTestValidator(t)
+	ctx := context.Background()
+	dir := writeCorpus(t, map[string]string{
+		"valid.json":             validPatient,
+		"nested/invalid.json":    invalidPatient,
+		".hidden/ignored.json":   `not json`,
+		"nested/notes.txt":       "not a resource",
+		"nested/invalid.go.json": invalidPatient,
+	})
+
+	report, err := Run(ctx, v, dir)
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(report.Files) != 3 || report.Passed() {
+		t.Fatalf("files = %d, passed = %v; want 3 files failing without manifests", len(report.Files), report.Passed())
+	}
+	if !strings.Contains(report.Files[0].Failures[0], "no manifest") {
+		t.Errorf("failure = %q", report.Files[0].Failures[0])
+	}
+
+	if err := Generate(ctx, v, dir); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	m, err := readManifest(filepath.Join(dir, "nested", "invalid"+ManifestSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Errors == nil || *m.Errors == 0 || len(m.Diagnostics) == 0 {
+		t.Errorf("manifest = %+v, want the invalid gender", m)
+	}
+
+	report, err = Run(ctx, v, dir)
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if !report.Passed() {
+		t.Errorf("failed after Generate: %+v", report.Failed())
+	}
+	Check(t, v, dir)
+}
+
+func TestRunMismatch(t *testing.T) {
+	v := getTestValidator(t)
+	dir := writeCorpus(t, map[string]string{
+		"valid.json":             invalidPatient,
+		"valid" + ManifestSuffix: `{"errors": 0, "diagnostics": [{"id": "STRUCTURE_UNKNOWN_ELEMENT"}]}`,
+	})
+
+	report, err := Run(context.Background(), v, dir)
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	failed := report.Failed()
+	if len(failed) != 1 || len(failed[0].Failures) != 2 {
+		t.Fatalf("failed = %+v, want 2 failures", failed)
+	}
+	if !strings.HasPrefix(failed[0].Failures[0], "errors = ") ||
+		failed[0].Failures[1] != "missing diagnostic STRUCTURE_UNKNOWN_ELEMENT" {
+		t.Errorf("failures = %q", failed[0].Failures)
+	}
+}
+
+func TestRunProfileOption(t *testing.T) {
+	v := getTestValidator(t)
+	dir := writeCorpus(t, map[string]string{
+		"vitals.json": `{"resourceType": "Observation", "status": "final", "code": {"text": "x"}}`,
+		"vitals" + ManifestSuffix: `{"options": {"profiles": ["http://hl7.org/fhir/StructureDefinition/vitalsigns"]},
+			"diagnostics": [{"id": "CARDINALITY_MIN", "expression": "Observation.subject"}]}`,
+	})
+	report, err := Run(context.Background(), v, dir)
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if !report.Passed() {
+		t.Errorf("failed: %+v", report.Failed())
+	}
+}
+
+func TestCompare(t *testing.T) {
+	one := 1
+	result := issue.NewResult()
+	result.AddErrorWithID(issue.DiagSDElementOrder, issue.Params{issue.String("path", "a"), issue.String("previous", "b")}, "StructureDefinition.differential.element[1]")
+
+	tests := []struct {
+		name     string
+		manifest Manifest
+		want     int
+	}{
+		{"empty", Manifest{}, 0},
+		{"count", Manifest{Errors: &one, Warnings: new(int)}, 0},
+		{"wrong count", Manifest{Warnings: &one}, 1},
+		{"id", Manifest{Diagnostics: []ExpectedIssue{{ID: "SD_ELEMENT_ORDER"}}}, 0},
+		{"id with severity and expression", Manifest{Diagnostics: []ExpectedIssue{
+			{ID: "SD_ELEMENT_ORDER", Severity: "error", Expression: "StructureDefinition.differential.element[1]"},
+		}}, 0},
+		{"wrong severity", Manifest{Diagnostics: []ExpectedIssue{{ID: "SD_ELEMENT_ORDER", Severity: "warning"}}}, 1},
+		{"repeated", Manifest{Diagnostics: []ExpectedIssue{{ID: "SD_ELEMENT_ORDER"}, {ID: "SD_ELEMENT_ORDER"}}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compare(&tt.manifest, result); len(got) != tt.want {
+				t.Errorf("failures = %q, want %d", got, tt.want)
+			}
+		})
+	}
+}
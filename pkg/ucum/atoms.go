@@ -0,0 +1,313 @@
+package ucum
+
+// atom is a UCUM unit atom. Base units have an empty definition; arbitrary
+// units ([iU], [CFU], ...) are their own dimension and are only commensurable
+// with themselves. Other atoms are defined by a UCUM expression whose
+// dimension they share; only the dimension matters here, so definitions omit
+// the numeric factors and special (non-ratio) scales such as Cel or [pH].
+type atom struct {
+	metric    bool
+	def       string
+	arbitrary bool
+}
+
+// prefixes are the UCUM metric prefixes.
+var prefixes = []string{
+	"Y", "Z", "E", "P", "T", "G", "M", "k", "h", "da", "d", "c", "m", "u", "n", "p", "f", "a", "z", "y",
+	"Ki", "Mi", "Gi", "Ti",
+}
+
+var atoms = map[string]atom{
+	// Base units
+	"m":   {metric: true},
+	"s":   {metric: true},
+	"g":   {metric: true},
+	"rad": {metric: true},
+	"K":   {metric: true},
+	"C":   {metric: true},
+	"cd":  {metric: true},
+
+	// Dimensionless
+	"10*":    {def: "1"},
+	"10^":    {def: "1"},
+	"[pi]":   {def: "1"},
+	"%":      {def: "1"},
+	"[ppth]": {def: "1"},
+	"[ppm]":  {def: "1"},
+	"[ppb]":  {def: "1"},
+	"[pptr]": {def: "1"},
+
+	// SI units
+	"mol": {metric: true, def: "10*23"},
+	"sr":  {metric: true, def: "rad2"},
+	"Hz":  {metric: true, def: "s-1"},
+	"N":   {metric: true, def: "kg.m/s2"},
+	"Pa":  {metric: true, def: "N/m2"},
+	"J":   {metric: true, def: "N.m"},
+	"W":   {metric: true, def: "J/s"},
+	"A":   {metric: true, def: "C/s"},
+	"V":   {metric: true, def: "J/C"},
+	"F":   {metric: true, def: "C/V"},
+	"Ohm": {metric: true, def: "V/A"},
+	"S":   {metric: true, def: "Ohm-1"},
+	"Wb":  {metric: true, def: "V.s"},
+	"Cel": {metric: true, def: "K"},
+	"T":   {metric: true, def: "Wb/m2"},
+	"H":   {metric: true, def: "Wb/A"},
+	"lm":  {metric: true, def: "cd.sr"},
+	"lx":  {metric: true, def: "lm/m2"},
+	"Bq":  {metric: true, def: "s-1"},
+	"Gy":  {metric: true, def: "J/kg"},
+	"Sv":  {metric: true, def: "J/kg"},
+
+	// Other units from ISO 1000 and ISO 2955
+	"gon":  {def: "deg"},
+	"deg":  {def: "rad"},
+	"'":    {def: "deg"},
+	"''":   {def: "'"},
+	"l":    {metric: true, def: "dm3"},
+	"L":    {metric: true, def: "l"},
+	"ar":   {metric: true, def: "m2"},
+	"min":  {def: "s"},
+	"h":    {def: "min"},
+	"d":    {def: "h"},
+	"a_t":  {def: "d"},
+	"a_j":  {def: "d"},
+	"a_g":  {def: "d"},
+	"a":    {def: "a_j"},
+	"wk":   {def: "d"},
+	"mo_s": {def: "d"},
+	"mo_j": {def: "a_j"},
+	"mo_g": {def: "a_g"},
+	"mo":   {def: "mo_j"},
+	"t":    {metric: true, def: "kg"},
+	"bar":  {metric: true, def: "Pa"},
+	"u":    {metric: true, def: "g"},
+	"eV":   {metric: true, def: "J"},
+	"AU":   {def: "Mm"},
+	"pc":   {metric: true, def: "m"},
+
+	// Natural units
+	"[c]":      {metric: true, def: "m/s"},
+	"[h]":      {metric: true, def: "J.s"},
+	"[k]":      {metric: true, def: "J/K"},
+	"[eps_0]":  {metric: true, def: "F/m"},
+	"[mu_0]":   {metric: true, def: "N/A2"},
+	"[e]":      {metric: true, def: "C"},
+	"[m_e]":    {metric: true, def: "g"},
+	"[m_p]":    {metric: true, def: "g"},
+	"[G]":      {metric: true, def: "m3.kg-1.s-2"},
+	"[g]":      {metric: true, def: "m/s2"},
+	"atm":      {def: "Pa"},
+	"[ly]":     {metric: true, def: "[c].a_j"},
+	"gf":       {metric: true, def: "g.[g]"},
+	"[lbf_av]": {def: "[lb_av].[g]"},
+
+	// CGS units
+	"Ky":  {metric: true, def: "cm-1"},
+	"Gal": {metric: true, def: "cm/s2"},
+	"dyn": {metric: true, def: "g.cm/s2"},
+	"erg": {metric: true, def: "dyn.cm"},
+	"P":   {metric: true, def: "dyn.s/cm2"},
+	"Bi":  {metric: true, def: "A"},
+	"St":  {metric: true, def: "cm2/s"},
+	"Mx":  {metric: true, def: "Wb"},
+	"G":   {metric: true, def: "T"},
+	"Oe":  {metric: true, def: "A/m"},
+	"Gb":  {metric: true, def: "Oe.cm"},
+	"sb":  {metric: true, def: "cd/cm2"},
+	"Lmb": {metric: true, def: "cd/cm2"},
+	"ph":  {metric: true, def: "lx"},
+	"Ci":  {metric: true, def: "Bq"},
+	"R":   {metric: true, def: "C/kg"},
+	"RAD": {metric: true, def: "erg/g"},
+	"REM": {metric: true, def: "RAD"},
+
+	// International customary units
+	"[in_i]":  {def: "cm"},
+	"[ft_i]":  {def: "[in_i]"},
+	"[yd_i]":  {def: "[ft_i]"},
+	"[mi_i]":  {def: "[ft_i]"},
+	"[fth_i]": {def: "[ft_i]"},
+	"[ch_us]": {def: "[ft_i]"},
+	"[nmi_i]": {def: "m"},
+	"[kn_i]":  {def: "[nmi_i]/h"},
+	"[sin_i]": {def: "[in_i]2"},
+	"[sft_i]": {def: "[ft_i]2"},
+	"[syd_i]": {def: "[yd_i]2"},
+	"[cin_i]": {def: "[in_i]3"},
+	"[cft_i]": {def: "[ft_i]3"},
+	"[cyd_i]": {def: "[yd_i]3"},
+	"[bf_i]":  {def: "[in_i]3"},
+	"[cr_i]":  {def: "[ft_i]3"},
+	"[mil_i]": {def: "[in_i]"},
+	"[cml_i]": {def: "[mil_i]2"},
+	"[hd_i]":  {def: "[in_i]"},
+
+	// US and British volumes
+	"[gal_us]": {def: "[in_i]3"},
+	"[bbl_us]": {def: "[gal_us]"},
+	"[qt_us]":  {def: "[gal_us]"},
+	"[pt_us]":  {def: "[qt_us]"},
+	"[gil_us]": {def: "[pt_us]"},
+	"[foz_us]": {def: "[gil_us]"},
+	"[fdr_us]": {def: "[foz_us]"},
+	"[min_us]": {def: "[fdr_us]"},
+	"[crd_us]": {def: "[ft_i]3"},
+	"[bu_us]":  {def: "[in_i]3"},
+	"[gal_wi]": {def: "[bu_us]"},
+	"[pk_us]":  {def: "[bu_us]"},
+	"[dqt_us]": {def: "[pk_us]"},
+	"[dpt_us]": {def: "[dqt_us]"},
+	"[tbs_us]": {def: "[foz_us]"},
+	"[tsp_us]": {def: "[tbs_us]"},
+	"[cup_us]": {def: "[tbs_us]"},
+	"[foz_m]":  {def: "mL"},
+	"[cup_m]":  {def: "mL"},
+	"[tsp_m]":  {def: "mL"},
+	"[tbs_m]":  {def: "mL"},
+	"[gal_br]": {def: "l"},
+	"[pk_br]":  {def: "[gal_br]"},
+	"[bu_br]":  {def: "[pk_br]"},
+	"[qt_br]":  {def: "[gal_br]"},
+	"[pt_br]":  {def: "[qt_br]"},
+	"[gil_br]": {def: "[pt_br]"},
+	"[foz_br]": {def: "[gil_br]"},
+	"[fdr_br]": {def: "[foz_br]"},
+	"[min_br]": {def: "[fdr_br]"},
+	"[drp]":    {def: "ml"},
+
+	// Avoirdupois and troy weights
+	"[gr]":       {def: "mg"},
+	"[lb_av]":    {def: "[gr]"},
+	"[oz_av]":    {def: "[lb_av]"},
+	"[dr_av]":    {def: "[oz_av]"},
+	"[scwt_av]":  {def: "[lb_av]"},
+	"[lcwt_av]":  {def: "[lb_av]"},
+	"[ston_av]":  {def: "[scwt_av]"},
+	"[lton_av]":  {def: "[lcwt_av]"},
+	"[stone_av]": {def: "[lb_av]"},
+	"[pwt_tr]":   {def: "[gr]"},
+	"[oz_tr]":    {def: "[pwt_tr]"},
+	"[lb_tr]":    {def: "[oz_tr]"},
+	"[sc_ap]":    {def: "[gr]"},
+	"[dr_ap]":    {def: "[sc_ap]"},
+	"[oz_ap]":    {def: "[dr_ap]"},
+	"[lb_ap]":    {def: "[oz_ap]"},
+	"[car_m]":    {def: "g"},
+
+	// Temperature, energy and pressure
+	"[degF]":     {def: "K"},
+	"[degR]":     {def: "K"},
+	"[degRe]":    {def: "K"},
+	"cal_[15]":   {metric: true, def: "J"},
+	"cal_[20]":   {metric: true, def: "J"},
+	"cal_m":      {metric: true, def: "J"},
+	"cal_IT":     {metric: true, def: "J"},
+	"cal_th":     {metric: true, def: "J"},
+	"cal":        {metric: true, def: "cal_th"},
+	"[Cal]":      {def: "kcal_th"},
+	"[Btu]":      {def: "[Btu_th]"},
+	"[Btu_39]":   {def: "kJ"},
+	"[Btu_59]":   {def: "kJ"},
+	"[Btu_60]":   {def: "kJ"},
+	"[Btu_m]":    {def: "kJ"},
+	"[Btu_IT]":   {def: "kJ"},
+	"[Btu_th]":   {def: "kJ"},
+	"[HP]":       {def: "W"},
+	"tex":        {metric: true, def: "g/km"},
+	"[den]":      {def: "g/km"},
+	"m[H2O]":     {metric: true, def: "kPa"},
+	"m[Hg]":      {metric: true, def: "kPa"},
+	"[in_i'H2O]": {def: "m[H2O]"},
+	"[in_i'Hg]":  {def: "m[Hg]"},
+	"[psi]":      {def: "[lbf_av]/[in_i]2"},
+
+	// Clinical units
+	"[PRU]":       {def: "mm[Hg].s/ml"},
+	"[wood'U]":    {def: "mm[Hg].min/L"},
+	"[diop]":      {def: "m-1"},
+	"[p'diop]":    {def: "1"},
+	"%[slope]":    {def: "1"},
+	"[mesh_i]":    {def: "[in_i]-1"},
+	"[hnsf'U]":    {arbitrary: true},
+	"[MET]":       {def: "mL/min/kg"},
+	"[hp'_X]":     {def: "1"},
+	"[hp'_C]":     {def: "1"},
+	"[hp'_M]":     {def: "1"},
+	"[hp'_Q]":     {def: "1"},
+	"[hp_X]":      {def: "1"},
+	"[hp_C]":      {def: "1"},
+	"[hp_M]":      {def: "1"},
+	"[hp_Q]":      {def: "1"},
+	"[kp_X]":      {def: "1"},
+	"[kp_C]":      {def: "1"},
+	"[kp_M]":      {def: "1"},
+	"[kp_Q]":      {def: "1"},
+	"eq":          {metric: true, def: "mol"},
+	"osm":         {metric: true, def: "mol"},
+	"[pH]":        {def: "mol/l"},
+	"g%":          {metric: true, def: "g/dl"},
+	"[S]":         {def: "s"},
+	"[HPF]":       {def: "1"},
+	"[LPF]":       {def: "1"},
+	"kat":         {metric: true, def: "mol/s"},
+	"U":           {metric: true, def: "umol/min"},
+	"[iU]":        {metric: true, arbitrary: true},
+	"[IU]":        {metric: true, def: "[iU]"},
+	"[arb'U]":     {arbitrary: true},
+	"[USP'U]":     {arbitrary: true},
+	"[GPL'U]":     {arbitrary: true},
+	"[MPL'U]":     {arbitrary: true},
+	"[APL'U]":     {arbitrary: true},
+	"[beth'U]":    {arbitrary: true},
+	"[anti'Xa'U]": {arbitrary: true},
+	"[todd'U]":    {arbitrary: true},
+	"[dye'U]":     {arbitrary: true},
+	"[smgy'U]":    {arbitrary: true},
+	"[bdsk'U]":    {arbitrary: true},
+	"[ka'U]":      {arbitrary: true},
+	"[knk'U]":     {arbitrary: true},
+	"[mclg'U]":    {arbitrary: true},
+	"[tb'U]":      {arbitrary: true},
+	"[CCID_50]":   {arbitrary: true},
+	"[TCID_50]":   {arbitrary: true},
+	"[EID_50]":    {arbitrary: true},
+	"[PFU]":       {arbitrary: true},
+	"[FFU]":       {arbitrary: true},
+	"[CFU]":       {arbitrary: true},
+	"[IR]":        {arbitrary: true},
+	"[BAU]":       {arbitrary: true},
+	"[AU]":        {arbitrary: true},
+	"[Amb'a'1'U]": {arbitrary: true},
+	"[PNU]":       {arbitrary: true},
+	"[Lf]":        {arbitrary: true},
+	"[D'ag'U]":    {arbitrary: true},
+	"[FEU]":       {arbitrary: true},
+	"[ELU]":       {arbitrary: true},
+	"[EU]":        {arbitrary: true},
+
+	// Levels
+	"Np":       {metric: true, def: "1"},
+	"B":        {metric: true, def: "1"},
+	"B[SPL]":   {metric: true, def: "Pa"},
+	"B[V]":     {metric: true, def: "V"},
+	"B[mV]":    {metric: true, def: "mV"},
+	"B[uV]":    {metric: true, def: "uV"},
+	"B[10.nV]": {metric: true, def: "nV"},
+	"B[W]":     {metric: true, def: "W"},
+	"B[kW]":    {metric: true, def: "kW"},
+
+	// Other legacy units and information technology
+	"st":    {metric: true, def: "m3"},
+	"Ao":    {def: "nm"},
+	"b":     {def: "fm2"},
+	"att":   {def: "kgf/cm2"},
+	"mho":   {metric: true, def: "S"},
+	"circ":  {def: "rad"},
+	"sph":   {def: "sr"},
+	"bit_s": {def: "1"},
+	"bit":   {metric: true, def: "1"},
+	"By":    {metric: true, def: "bit"},
+	"Bd":    {metric: true, def: "s-1"},
+}
@@ -0,0 +1,377 @@
+// Package ucum validates unit expressions of the Unified Code for Units of
+// Measure (UCUM), the unit system FHIR Quantity uses with
+// system = http://unitsofmeasure.org.
+//
+// The built-in engine checks the UCUM grammar (prefixes, atoms, exponents,
+// annotations, products and quotients) against the common atoms of the UCUM
+// essence and derives each unit's dimension to tell whether two units measure
+// the same kind of quantity. It does not convert values.
+package ucum
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// dimensions holds the dimension of every atom, derived from the atom table.
+var dimensions = buildDimensions()
+
+// System is the FHIR code system URI of UCUM.
+const System = "http://unitsofmeasure.org"
+
+// Service validates UCUM units. The built-in engine is returned by Engine;
+// an implementation backed by a complete UCUM library or a terminology server
+// can replace it (see validator.WithUCUMService).
+type Service interface {
+	// Validate returns an error describing why code is not a valid UCUM
+	// unit, or nil if it is.
+	Validate(code string) error
+	// Commensurable reports whether two units measure the same kind of
+	// quantity (e.g., "mm[Hg]" and "kPa"), so that values can be converted.
+	// It returns an error if either unit is invalid.
+	Commensurable(a, b string) (bool, error)
+}
+
+// Engine returns the built-in UCUM engine.
+func Engine() Service {
+	return engine{}
+}
+
+type engine struct{}
+
+func (engine) Validate(code string) error {
+	_, err := Parse(code)
+	return err
+}
+
+func (engine) Commensurable(a, b string) (bool, error) {
+	return Commensurable(a, b)
+}
+
+// Unit is a parsed UCUM unit expression.
+type Unit struct {
+	// Code is the unit expression as given.
+	Code string
+	dim  dimension
+}
+
+// Commensurable reports whether u and other measure the same kind of quantity.
+func (u *Unit) Commensurable(other *Unit) bool {
+	return u.dim.equal(other.dim)
+}
+
+// Parse parses a UCUM unit expression.
+func Parse(code string) (*Unit, error) {
+	if code == "" {
+		return nil, fmt.Errorf("empty unit")
+	}
+	if strings.ContainsAny(code, " \t\r\n") {
+		return nil, fmt.Errorf("unit %q contains white space", code)
+	}
+	p := &parser{s: code, resolve: func(name string) (dimension, error) { return dimensions[name], nil }}
+	dim, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Unit{Code: code, dim: dim}, nil
+}
+
+// Validate returns an error describing why code is not a valid UCUM unit.
+func Validate(code string) error {
+	_, err := Parse(code)
+	return err
+}
+
+// Commensurable reports whether two UCUM units measure the same kind of
+// quantity. It returns an error if either unit is invalid.
+func Commensurable(a, b string) (bool, error) {
+	ua, err := Parse(a)
+	if err != nil {
+		return false, err
+	}
+	ub, err := Parse(b)
+	if err != nil {
+		return false, err
+	}
+	return ua.Commensurable(ub), nil
+}
+
+// dimension maps base units (and arbitrary units) to their exponents.
+type dimension map[string]int
+
+func (d dimension) mul(other dimension, exp int) {
+	for base, e := range other {
+		d[base] += e * exp
+		if d[base] == 0 {
+			delete(d, base)
+		}
+	}
+}
+
+func (d dimension) equal(other dimension) bool {
+	return maps.Equal(d, other)
+}
+
+// buildDimensions derives the dimension of every atom from its definition.
+// Base and arbitrary units are their own dimension. It panics on an invalid
+// or cyclic definition, which is a bug in the atom table.
+func buildDimensions() map[string]dimension {
+	dims := make(map[string]dimension, len(atoms))
+	visiting := make(map[string]bool)
+	var resolve func(name string) (dimension, error)
+	resolve = func(name string) (dimension, error) {
+		if dim, ok := dims[name]; ok {
+			return dim, nil
+		}
+		a := atoms[name]
+		if a.arbitrary || a.def == "" {
+			dims[name] = dimension{name: 1}
+			return dims[name], nil
+		}
+		if visiting[name] {
+			return nil, fmt.Errorf("cyclic definition of %s", name)
+		}
+		visiting[name] = true
+		p := &parser{s: a.def, resolve: resolve}
+		dim, err := p.parse()
+		if err != nil {
+			return nil, fmt.Errorf("definition of %s: %w", name, err)
+		}
+		dims[name] = dim
+		return dim, nil
+	}
+	for name := range atoms {
+		if _, err := resolve(name); err != nil {
+			panic("ucum: " + err.Error())
+		}
+	}
+	return dims
+}
+
+// parser is a recursive descent parser for the UCUM grammar:
+//
+//	mainTerm    = "/" term | term
+//	term        = term "." component | term "/" component | component
+//	component   = annotatable annotation | annotatable | annotation | factor | "(" term ")"
+//	annotatable = simpleUnit exponent | simpleUnit
+//	simpleUnit  = atom | prefix atom
+type parser struct {
+	s       string
+	pos     int
+	resolve func(atom string) (dimension, error)
+}
+
+func (p *parser) parse() (dimension, error) {
+	dim := dimension{}
+	if p.peek() == '/' {
+		p.pos++
+		term, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		dim.mul(term, -1)
+	} else {
+		term, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		dim = term
+	}
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.s[p.pos], p.pos+1)
+	}
+	return dim, nil
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) term() (dimension, error) {
+	dim, err := p.component()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		exp := 0
+		switch p.peek() {
+		case '.':
+			exp = 1
+		case '/':
+			exp = -1
+		default:
+			return dim, nil
+		}
+		p.pos++
+		next, err := p.component()
+		if err != nil {
+			return nil, err
+		}
+		dim.mul(next, exp)
+	}
+}
+
+func (p *parser) component() (dimension, error) {
+	switch p.peek() {
+	case '(':
+		p.pos++
+		dim, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos+1)
+		}
+		p.pos++
+		return dim, nil
+	case '{':
+		return dimension{}, p.annotation()
+	case 0:
+		return nil, fmt.Errorf("missing unit at end of expression")
+	}
+
+	symbol, err := p.symbol()
+	if err != nil {
+		return nil, err
+	}
+	dim, err := p.simpleUnit(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() == '{' {
+		if err := p.annotation(); err != nil {
+			return nil, err
+		}
+	}
+	return dim, nil
+}
+
+// symbol reads a unit symbol with its exponent, up to the next operator,
+// parenthesis or annotation. Square brackets may contain any character.
+func (p *parser) symbol() (string, error) {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch c {
+		case '.', '/', '(', ')', '{':
+			return p.s[start:p.pos], p.checkSymbol(start)
+		case '}':
+			return "", fmt.Errorf("unexpected '}' at position %d", p.pos+1)
+		case '[':
+			end := strings.IndexByte(p.s[p.pos:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("missing ']' for '[' at position %d", p.pos+1)
+			}
+			p.pos += end + 1
+			continue
+		}
+		p.pos++
+	}
+	return p.s[start:], p.checkSymbol(start)
+}
+
+func (p *parser) checkSymbol(start int) error {
+	if p.pos == start {
+		if p.pos < len(p.s) {
+			return fmt.Errorf("unexpected %q at position %d", p.s[p.pos], p.pos+1)
+		}
+		return fmt.Errorf("missing unit at end of expression")
+	}
+	return nil
+}
+
+// annotation reads a curly-braced annotation, which has no dimension.
+func (p *parser) annotation() error {
+	start := p.pos
+	end := strings.IndexByte(p.s[p.pos:], '}')
+	if end < 0 {
+		return fmt.Errorf("missing '}' for '{' at position %d", start+1)
+	}
+	for _, c := range p.s[p.pos+1 : p.pos+end] {
+		if c < 33 || c > 126 || c == '{' {
+			return fmt.Errorf("invalid character %q in annotation at position %d", c, start+1)
+		}
+	}
+	p.pos += end + 1
+	return nil
+}
+
+// simpleUnit resolves a symbol such as "mm[Hg]", "m2", "10*3" or "s-1" to
+// its dimension. A symbol made of digits only is a dimensionless factor.
+func (p *parser) simpleUnit(symbol string) (dimension, error) {
+	if isDigits(symbol) {
+		return dimension{}, nil
+	}
+
+	name, exp := splitExponent(symbol)
+	atomName, ok := lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", name)
+	}
+	dim, err := p.resolve(atomName)
+	if err != nil {
+		return nil, err
+	}
+	result := dimension{}
+	result.mul(dim, exp)
+	return result, nil
+}
+
+// splitExponent splits the trailing signed integer exponent off a symbol.
+// Digits inside square brackets belong to the atom.
+func splitExponent(symbol string) (string, int) {
+	i := len(symbol)
+	for i > 0 && symbol[i-1] >= '0' && symbol[i-1] <= '9' {
+		i--
+	}
+	if i == len(symbol) || i == 0 {
+		return symbol, 1
+	}
+	digits := symbol[i:]
+	sign := 1
+	if symbol[i-1] == '-' || symbol[i-1] == '+' {
+		if symbol[i-1] == '-' {
+			sign = -1
+		}
+		i--
+	}
+	if i == 0 {
+		return symbol, 1
+	}
+	exp := 0
+	for _, c := range digits {
+		exp = exp*10 + int(c-'0')
+	}
+	return symbol[:i], sign * exp
+}
+
+// lookup returns the atom a symbol names, directly or as a metric atom with
+// a prefix.
+func lookup(name string) (string, bool) {
+	if _, ok := atoms[name]; ok {
+		return name, true
+	}
+	for _, prefix := range prefixes {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		if a, ok := atoms[rest]; ok && a.metric {
+			return rest, true
+		}
+	}
+	return "", false
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
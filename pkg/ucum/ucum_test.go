@@ -0,0 +1,62 @@
+package ucum
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	valid := []string{
+		"mm[Hg]", "kg/m2", "/min", "{beats}/min", "10*3/uL", "mL{total}", "[degF]",
+		"Cel", "%", "meq/L", "[iU]/L", "s-1", "(kg.m)/s2", "1", "{score}", "mg/dL", "kPa",
+	}
+	for _, code := range valid {
+		if err := Validate(code); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", code, err)
+		}
+	}
+
+	invalid := []string{"", "xyz", "kg/", "(m", "m{a", "mm Hg", "m)", "[Hg", "kCel.", "mmHg"}
+	for _, code := range invalid {
+		if err := Validate(code); err == nil {
+			t.Errorf("Validate(%q) = nil, want error", code)
+		}
+	}
+}
+
+func TestCommensurable(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"mm[Hg]", "kPa", true},
+		{"Cel", "K", true},
+		{"[lb_av]", "kg", true},
+		{"mg/dL", "g/L", true},
+		{"/min", "Hz", true},
+		{"{beats}/min", "/min", true},
+		{"kg", "m", false},
+		{"mmol/L", "mg/dL", false},
+	}
+	for _, tt := range tests {
+		got, err := Commensurable(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("Commensurable(%q, %q) error: %v", tt.a, tt.b, err)
+		}
+		if got != tt.want {
+			t.Errorf("Commensurable(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	if _, err := Commensurable("kg", "xyz"); err == nil {
+		t.Error("Commensurable() with an invalid unit returned no error")
+	}
+}
+
+func TestAtomDefinitions(t *testing.T) {
+	for name, a := range atoms {
+		if a.def == "" {
+			continue
+		}
+		if err := Validate(a.def); err != nil {
+			t.Errorf("definition of %s (%q) is invalid: %v", name, a.def, err)
+		}
+	}
+}
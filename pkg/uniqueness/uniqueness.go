@@ -0,0 +1,209 @@
+// Package uniqueness detects resources that share a business key across a
+// validation session, such as the entries of a Bundle or the lines of an
+// NDJSON stream:
+//
+//   - identifier: two resources of the same type with an identical
+//     Identifier system and value (e.g., two Patients with the same MRN)
+//   - id: two resources of the same type with the same id and
+//     meta.versionId, i.e., conflicting copies of one resource
+//
+// Further rules are plugged in as Rule values. Keys only clash between
+// resources of the same type.
+package uniqueness
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// Key is a value that must be unique among the resources of a session.
+type Key struct {
+	// Value is compared with the values of other resources of the same type.
+	Value string
+
+	// Path is the element holding the key, relative to the resource (e.g.,
+	// "identifier[0]"); issues are reported there.
+	Path string
+}
+
+// Rule is a uniqueness rule: resources of the same type may not share any of
+// the keys it extracts.
+type Rule struct {
+	// Name identifies the rule in diagnostics (e.g., "identifier").
+	Name string
+
+	// ResourceTypes limits the rule to resources of these types; nil applies
+	// it to every resource.
+	ResourceTypes []string
+
+	// Keys returns the keys of a resource; numbers are json.Number. It is
+	// called concurrently by concurrent validations.
+	Keys func(resource map[string]any) []Key
+}
+
+// applies reports whether the rule checks resources of a type.
+func (r *Rule) applies(resourceType string) bool {
+	return r.ResourceTypes == nil || slices.Contains(r.ResourceTypes, resourceType)
+}
+
+// Identifier flags resources of the same type with an identical Identifier
+// system and value. Identifiers without a system or value, and those with
+// use "old", are ignored.
+var Identifier = Rule{Name: "identifier", Keys: identifierKeys}
+
+// ID flags resources of the same type with the same id and meta.versionId.
+// Inside a Bundle the bundle phase reports these already, so it is meant for
+// streams of resources (see validator.Session).
+var ID = Rule{Name: "id", Keys: idKeys}
+
+// Defaults returns the rules applied when none are configured: Identifier.
+func Defaults() []Rule {
+	return []Rule{Identifier}
+}
+
+// Checker remembers the keys of the resources it has checked and reports
+// resources that repeat them. It is safe for concurrent use; the memory it
+// holds grows with the number of keys seen.
+type Checker struct {
+	rules []Rule
+
+	mu   sync.Mutex
+	seen map[seenKey]string // -> label of the first resource with the key
+}
+
+// seenKey is a key of a rule among resources of a type.
+type seenKey struct {
+	rule, resourceType, value string
+}
+
+// New creates a Checker applying rules, or Defaults if none are given.
+func New(rules ...Rule) *Checker {
+	if len(rules) == 0 {
+		rules = Defaults()
+	}
+	return &Checker{rules: rules, seen: make(map[seenKey]string)}
+}
+
+// Check checks a resource at path (e.g., "Patient") against the resources
+// checked before, then remembers its keys. Label names the resource in the
+// issues of later resources that repeat them (e.g., "patients.ndjson:12");
+// "" names it by path. The entries of a Bundle, other than a history Bundle,
+// are checked as well, and labelled with label and their path.
+func (c *Checker) Check(resource map[string]any, path, label string, result *issue.Result) {
+	if label == "" {
+		label = path
+	}
+	c.check(resource, path, label, result)
+
+	if resourceType, _ := resource["resourceType"].(string); resourceType != "Bundle" {
+		return
+	}
+	if bundleType, _ := resource["type"].(string); bundleType == "history" {
+		return
+	}
+	entries, _ := resource["entry"].([]any)
+	for i, e := range entries {
+		entry, _ := e.(map[string]any)
+		entryResource, ok := entry["resource"].(map[string]any)
+		if !ok {
+			continue
+		}
+		entryPath := fmt.Sprintf("%s.entry[%d].resource", path, i)
+		entryLabel := entryPath
+		if label != path {
+			entryLabel = label + " " + entryPath
+		}
+		c.check(entryResource, entryPath, entryLabel, result)
+	}
+}
+
+// check applies the rules to a single resource.
+func (c *Checker) check(resource map[string]any, path, label string, result *issue.Result) {
+	resourceType, _ := resource["resourceType"].(string)
+	if resourceType == "" {
+		return
+	}
+	for i := range c.rules {
+		rule := &c.rules[i]
+		if !rule.applies(resourceType) {
+			continue
+		}
+		for _, key := range rule.Keys(resource) {
+			if key.Value == "" {
+				continue
+			}
+			first, dup := c.remember(seenKey{rule.Name, resourceType, key.Value}, label)
+			if !dup {
+				continue
+			}
+			keyPath := path
+			if key.Path != "" {
+				keyPath += "." + key.Path
+			}
+			result.AddErrorWithID(
+				issue.DiagUniqueDuplicate,
+				issue.Params{issue.String("type", resourceType), issue.String("rule", rule.Name), issue.String("key", key.Value), issue.String("first", first)},
+				keyPath,
+			)
+		}
+	}
+}
+
+// remember records the first resource with a key, returning it and whether
+// an earlier resource had the key.
+func (c *Checker) remember(k seenKey, label string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if first, ok := c.seen[k]; ok {
+		return first, true
+	}
+	c.seen[k] = label
+	return label, false
+}
+
+// identifierKeys returns "system|value" of each identifier of a resource.
+func identifierKeys(resource map[string]any) []Key {
+	switch identifiers := resource["identifier"].(type) {
+	case map[string]any: // 0..1, e.g., Bundle.identifier
+		if value := identifierKey(identifiers); value != "" {
+			return []Key{{Value: value, Path: "identifier"}}
+		}
+	case []any:
+		var keys []Key
+		for i, id := range identifiers {
+			idMap, _ := id.(map[string]any)
+			if value := identifierKey(idMap); value != "" {
+				keys = append(keys, Key{Value: value, Path: fmt.Sprintf("identifier[%d]", i)})
+			}
+		}
+		return keys
+	}
+	return nil
+}
+
+// identifierKey returns "system|value" of an Identifier, or "" if it has no
+// system or value or is no longer in use.
+func identifierKey(id map[string]any) string {
+	system, _ := id["system"].(string)
+	value, _ := id["value"].(string)
+	if use, _ := id["use"].(string); system == "" || value == "" || use == "old" {
+		return ""
+	}
+	return system + "|" + value
+}
+
+// idKeys returns the id of a resource, with its version if any.
+func idKeys(resource map[string]any) []Key {
+	id, _ := resource["id"].(string)
+	if id == "" {
+		return nil
+	}
+	meta, _ := resource["meta"].(map[string]any)
+	if version, _ := meta["versionId"].(string); version != "" {
+		id += "/_history/" + version
+	}
+	return []Key{{Value: id, Path: "id"}}
+}
@@ -0,0 +1,91 @@
+package uniqueness
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func patient(id string, identifiers ...any) map[string]any {
+	return map[string]any{"resourceType": "Patient", "id": id, "identifier": identifiers}
+}
+
+func mrn(value string) map[string]any {
+	return map[string]any{"system": "http://hospital.example/mrn", "value": value}
+}
+
+func TestCheck(t *testing.T) {
+	c := New()
+	var paths []string
+	check := func(resource map[string]any, label string) {
+		result := issue.NewResult()
+		c.Check(resource, "Patient", label, result)
+		for _, iss := range result.Issues {
+			paths = append(paths, iss.Expression[0])
+			if iss.MessageID != string(issue.DiagUniqueDuplicate) {
+				t.Errorf("unexpected issue %s", iss.MessageID)
+			}
+		}
+	}
+
+	check(patient("a", mrn("1")), "line 1")
+	check(patient("b", mrn("2")), "line 2")
+	check(patient("c", mrn("3"), mrn("1")), "line 3")
+	check(patient("d", map[string]any{"system": "http://hospital.example/mrn", "value": "2", "use": "old"}), "line 4")
+	check(map[string]any{"resourceType": "Practitioner", "identifier": []any{mrn("1")}}, "line 5")
+
+	if want := []string{"Patient.identifier[1]"}; !slices.Equal(paths, want) {
+		t.Errorf("duplicates at %v, want %v", paths, want)
+	}
+}
+
+func TestCheckBundle(t *testing.T) {
+	entry := func(resource map[string]any) any { return map[string]any{"resource": resource} }
+	bundle := map[string]any{
+		"resourceType": "Bundle",
+		"type":         "batch",
+		"entry":        []any{entry(patient("a", mrn("1"))), entry(patient("b", mrn("2"))), entry(patient("c", mrn("1")))},
+	}
+
+	result := issue.NewResult()
+	New().Check(bundle, "Bundle", "", result)
+	if len(result.Issues) != 1 || result.Issues[0].Expression[0] != "Bundle.entry[2].resource.identifier[0]" {
+		t.Fatalf("issues = %+v, want one at entry 2", result.Issues)
+	}
+	if want := "Patient identifier 'http://hospital.example/mrn|1' is already used by Bundle.entry[0].resource"; result.Issues[0].Diagnostics != want {
+		t.Errorf("diagnostics = %q, want %q", result.Issues[0].Diagnostics, want)
+	}
+
+	bundle["type"] = "history"
+	result = issue.NewResult()
+	New().Check(bundle, "Bundle", "", result)
+	if len(result.Issues) != 0 {
+		t.Errorf("history Bundle entries should not be checked: %+v", result.Issues)
+	}
+}
+
+func TestCustomRule(t *testing.T) {
+	email := Rule{
+		Name:          "email",
+		ResourceTypes: []string{"Patient"},
+		Keys: func(resource map[string]any) []Key {
+			value, _ := resource["email"].(string)
+			return []Key{{Value: value, Path: "email"}}
+		},
+	}
+	c := New(email, ID)
+
+	result := issue.NewResult()
+	c.Check(map[string]any{"resourceType": "Patient", "id": "a", "email": "x@example.org"}, "Patient", "", result)
+	c.Check(map[string]any{"resourceType": "Patient", "id": "a", "email": "x@example.org"}, "Patient", "", result)
+	c.Check(map[string]any{"resourceType": "Patient", "id": "a", "meta": map[string]any{"versionId": "2"}}, "Patient", "", result)
+
+	var got []string
+	for _, iss := range result.Issues {
+		got = append(got, iss.Expression[0])
+	}
+	if want := []string{"Patient.email", "Patient.id"}; !slices.Equal(got, want) {
+		t.Errorf("duplicates at %v, want %v", got, want)
+	}
+}
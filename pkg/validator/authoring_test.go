@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestAuthorMode(t *testing.T) {
+	resource := `{"resourceType": "SearchParameter", "url": "http://example.org/SearchParameter/patient-nickname",
+		"name": "nickname", "status": "draft", "description": "Nickname", "code": "nickname",
+		"base": ["Patient"], "type": "string", "expression": "Patient.name.nickname"}`
+
+	for _, enabled := range []bool{false, true} {
+		v, err := New(WithAuthorMode(enabled))
+		if err != nil {
+			t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+		}
+		result, err := v.ValidateJSON(context.Background(), resource)
+		if err != nil {
+			t.Fatalf("ValidateJSON() error: %v", err)
+		}
+
+		found := false
+		for _, iss := range result.Issues {
+			if iss.MessageID == string(issue.DiagSearchParamExpressionElement) {
+				found = true
+				if iss.Expression[0] != "SearchParameter.expression" {
+					t.Errorf("path = %v", iss.Expression)
+				}
+			}
+		}
+		if found != enabled {
+			t.Errorf("WithAuthorMode(%v): %s reported = %v, issues: %v", enabled, issue.DiagSearchParamExpressionElement, found, result.Issues)
+		}
+	}
+}
@@ -84,6 +84,40 @@ func BenchmarkValidatePatientWithData(b *testing.B) {
 	}
 }
 
+// BenchmarkValidateInvalidPatient benchmarks validation of a Patient that
+// produces many issues, so that issue construction dominates allocations.
+func BenchmarkValidateInvalidPatient(b *testing.B) {
+	v, err := New()
+	if err != nil {
+		b.Skipf("Cannot create validator: %v", err)
+	}
+
+	resource := []byte(`{
+		"resourceType": "Patient",
+		"id": "invalid example",
+		"unknownElement": true,
+		"gender": "unknown-gender",
+		"birthDate": "1970-13-45",
+		"active": "yes",
+		"name": [
+			{"family": 42, "given": ["John", 7], "use": "nickname-ish"},
+			{"text": ""}
+		],
+		"telecom": [
+			{"system": "pager-x", "value": "555-1234", "use": "home-ish"},
+			{"system": "email", "rank": 0}
+		],
+		"communication": [{}],
+		"link": [{"type": "seealso"}]
+	}`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = v.Validate(context.Background(), resource)
+	}
+}
+
 // BenchmarkValidateObservation benchmarks validation of an Observation resource.
 func BenchmarkValidateObservation(b *testing.B) {
 	v, err := New()
@@ -195,3 +229,37 @@ func BenchmarkValidateBatch(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkFastPath compares minimal resources with and without fast-path pre-scans.
+func BenchmarkFastPath(b *testing.B) {
+	resources := map[string][]byte{
+		"Patient": []byte(`{"resourceType": "Patient", "id": "p1", "active": true, "gender": "female"}`),
+		"Observation": []byte(`{
+			"resourceType": "Observation",
+			"status": "final",
+			"code": {"coding": [{"system": "http://loinc.org", "code": "29463-7"}]},
+			"valueQuantity": {"value": 70.5, "unit": "kg", "system": "http://unitsofmeasure.org", "code": "kg"}
+		}`),
+	}
+
+	for _, enabled := range []bool{true, false} {
+		v, err := New(WithFastPath(enabled))
+		if err != nil {
+			b.Skipf("Cannot create validator: %v", err)
+		}
+
+		mode := "enabled"
+		if !enabled {
+			mode = "disabled"
+		}
+
+		for name, resource := range resources {
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					_, _ = v.Validate(context.Background(), resource)
+				}
+			})
+		}
+	}
+}
@@ -2,8 +2,16 @@ package validator
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/terminology"
 )
 
 func TestBindingValidation(t *testing.T) {
@@ -61,3 +69,233 @@ func TestBindingValidation(t *testing.T) {
 		})
 	}
 }
+
+// conceptStatusDefinitions are a CodeSystem with retired, deprecated and
+// abstract concepts, an active-only and an unrestricted ValueSet over it, and
+// a custom resource binding code elements to each.
+var conceptStatusDefinitions = [][]byte{
+	[]byte(`{
+		"resourceType": "CodeSystem", "url": "http://example.org/fhir/CodeSystem/check",
+		"status": "active", "content": "complete",
+		"concept": [
+			{"code": "ok"},
+			{"code": "old", "property": [{"code": "status", "valueCode": "retired"}]},
+			{"code": "dep", "property": [{"code": "status", "valueCode": "deprecated"}]},
+			{"code": "_group", "property": [{"code": "notSelectable", "valueBoolean": true}],
+				"concept": [{"code": "child"}]}
+		]
+	}`),
+	[]byte(`{
+		"resourceType": "ValueSet", "url": "http://example.org/fhir/ValueSet/check-active",
+		"status": "active",
+		"compose": {"inactive": false, "include": [{"system": "http://example.org/fhir/CodeSystem/check"}]}
+	}`),
+	[]byte(`{
+		"resourceType": "ValueSet", "url": "http://example.org/fhir/ValueSet/check-all",
+		"status": "active",
+		"compose": {"include": [{"system": "http://example.org/fhir/CodeSystem/check"}]}
+	}`),
+	[]byte(`{
+		"resourceType": "StructureDefinition",
+		"url": "http://example.org/fhir/StructureDefinition/ConceptCheck",
+		"name": "ConceptCheck", "status": "active", "kind": "resource", "abstract": false,
+		"type": "ConceptCheck",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/DomainResource",
+		"derivation": "specialization",
+		"snapshot": {"element": [
+			{"id": "ConceptCheck", "path": "ConceptCheck", "min": 0, "max": "*"},
+			{"id": "ConceptCheck.active", "path": "ConceptCheck.active", "min": 0, "max": "1", "type": [{"code": "code"}],
+				"binding": {"strength": "required", "valueSet": "http://example.org/fhir/ValueSet/check-active"}},
+			{"id": "ConceptCheck.any", "path": "ConceptCheck.any", "min": 0, "max": "1", "type": [{"code": "code"}],
+				"binding": {"strength": "required", "valueSet": "http://example.org/fhir/ValueSet/check-all"}}
+		]}
+	}`),
+}
+
+func TestBindingConceptStatus(t *testing.T) {
+	v, err := New(WithConformanceResources(conceptStatusDefinitions), WithCustomTypes())
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		resource  string
+		wantID    issue.DiagnosticID
+		wantError bool
+	}{
+		{"active code", `{"resourceType": "ConceptCheck", "active": "ok"}`, "", false},
+		{"child of abstract code", `{"resourceType": "ConceptCheck", "active": "child"}`, "", false},
+		{"retired code in active-only value set", `{"resourceType": "ConceptCheck", "active": "old"}`, issue.DiagBindingCodeInactive, true},
+		{"abstract code", `{"resourceType": "ConceptCheck", "any": "_group"}`, issue.DiagBindingCodeAbstract, true},
+		{"retired code in unrestricted value set", `{"resourceType": "ConceptCheck", "any": "old"}`, issue.DiagCodeInactive, false},
+		{"deprecated code", `{"resourceType": "ConceptCheck", "active": "dep"}`, issue.DiagCodeDeprecated, false},
+		{"unknown code", `{"resourceType": "ConceptCheck", "active": "nope"}`, issue.DiagBindingRequired, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.ValidateJSON(context.Background(), tt.resource)
+			if err != nil {
+				t.Fatalf("ValidateJSON() error: %v", err)
+			}
+			var ids []issue.DiagnosticID
+			for _, iss := range result.Issues {
+				if iss.MessageID != "" && iss.MessageID != string(issue.DiagConstraintFailed) {
+					ids = append(ids, issue.DiagnosticID(iss.MessageID))
+				}
+			}
+			switch {
+			case tt.wantID == "" && len(ids) != 0:
+				t.Errorf("issues = %v, want none", ids)
+			case tt.wantID != "" && (len(ids) != 1 || ids[0] != tt.wantID):
+				t.Errorf("issues = %v, want [%s]", ids, tt.wantID)
+			}
+			if (result.ErrorCount() > 0) != tt.wantError {
+				t.Errorf("ErrorCount() = %d, want error %v", result.ErrorCount(), tt.wantError)
+			}
+		})
+	}
+}
+
+func TestQuantityUnits(t *testing.T) {
+	v, err := New(WithUnitConsistency())
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	const bodyTemp = `{"resourceType": "Observation", "meta": {"profile": ["http://hl7.org/fhir/StructureDefinition/bodytemp"]},
+		"status": "final", "code": {"coding": [{"system": "http://loinc.org", "code": "8310-5"}]},
+		"valueQuantity": {"value": 36.6, %s}}`
+
+	tests := []struct {
+		name     string
+		quantity string
+		wantID   issue.DiagnosticID
+	}{
+		{"declared unit", `"system": "http://unitsofmeasure.org", "code": "Cel"`, ""},
+		{"convertible unit", `"system": "http://unitsofmeasure.org", "code": "K"`, issue.DiagUCUMUnitMismatch},
+		{"incompatible unit", `"system": "http://unitsofmeasure.org", "code": "mg"`, issue.DiagUCUMUnitIncompatible},
+		{"invalid unit", `"system": "http://unitsofmeasure.org", "code": "deg C"`, issue.DiagUCUMInvalidUnit},
+		{"code without system", `"code": "Cel"`, issue.DiagQuantityCodeNoSystem},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.ValidateJSON(context.Background(), fmt.Sprintf(bodyTemp, tt.quantity))
+			if err != nil {
+				t.Fatalf("ValidateJSON() error: %v", err)
+			}
+			var ids []issue.DiagnosticID
+			for _, iss := range result.Issues {
+				if strings.HasPrefix(iss.MessageID, "UCUM_") || strings.HasPrefix(iss.MessageID, "QUANTITY_") {
+					ids = append(ids, issue.DiagnosticID(iss.MessageID))
+				}
+			}
+			switch {
+			case tt.wantID == "" && len(ids) != 0:
+				t.Errorf("issues = %v, want none", ids)
+			case tt.wantID != "" && (len(ids) != 1 || ids[0] != tt.wantID):
+				t.Errorf("issues = %v, want [%s]", ids, tt.wantID)
+			}
+		})
+	}
+
+	// Nested Quantities are checked too, with the built-in grammar only when
+	// unit consistency is off.
+	shared := getSharedValidator(t)
+	result, err := shared.ValidateJSON(context.Background(), `{"resourceType": "Observation", "status": "final",
+		"code": {"text": "panel"}, "component": [{"code": {"text": "rate"},
+		"valueQuantity": {"value": 60, "system": "http://unitsofmeasure.org", "code": "beats/min"}}]}`)
+	if err != nil {
+		t.Fatalf("ValidateJSON() error: %v", err)
+	}
+	found := false
+	for _, iss := range result.Issues {
+		if iss.MessageID == string(issue.DiagUCUMInvalidUnit) && len(iss.Expression) > 0 &&
+			iss.Expression[0] == "Observation.component[0].valueQuantity.code" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("invalid component unit not reported: %v", result.Issues)
+	}
+}
+
+func TestBindingSystemUnsupportedByServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		if r.URL.Path == "/metadata" {
+			fmt.Fprint(w, `{"resourceType":"TerminologyCapabilities","codeSystem":[{"uri":"http://snomed.info/sct"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"resourceType":"Parameters","parameter":[{"name":"result","valueBoolean":true}]}`)
+	}))
+	defer srv.Close()
+
+	v, err := New(WithTerminologyProvider(terminology.NewServerProvider(srv.URL, nil)))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	result, err := v.ValidateJSON(context.Background(), `{"resourceType": "Observation", "status": "final",
+		"code": {"text": "Heart rate"},
+		"interpretation": [{"coding": [{"system": "http://loinc.org", "code": "LA6576-8"}]}]}`)
+	if err != nil {
+		t.Fatalf("ValidateJSON() error: %v", err)
+	}
+	found := false
+	for _, iss := range result.Issues {
+		if iss.MessageID == string(issue.DiagBindingSystemUnsupported) {
+			found = true
+			if iss.Severity != issue.SeverityInformation {
+				t.Errorf("severity = %s, want information", iss.Severity)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("issues = %v, want %s", result.Issues, issue.DiagBindingSystemUnsupported)
+	}
+}
+
+func TestTerminologyPrefetch(t *testing.T) {
+	var mu sync.Mutex
+	requested := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := r.URL.Query().Get("code")
+		mu.Lock()
+		requested[r.URL.Path+" "+code]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/fhir+json")
+		fmt.Fprintf(w, `{"resourceType":"Parameters","parameter":[{"name":"result","valueBoolean":%t}]}`, code != "bogus")
+	}))
+	defer srv.Close()
+
+	v, err := New(
+		WithTerminologyProvider(terminology.NewServerProvider(srv.URL, nil)),
+		WithTerminologyPrefetch(4),
+	)
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	result, err := v.ValidateJSON(context.Background(), `{"resourceType": "Observation", "status": "final",
+		"code": {"text": "Heart rate"},
+		"interpretation": [
+			{"coding": [{"system": "http://snomed.info/sct", "code": "281302008"}]},
+			{"coding": [{"system": "http://snomed.info/sct", "code": "bogus"}]}
+		]}`)
+	if err != nil {
+		t.Fatalf("ValidateJSON() error: %v", err)
+	}
+	found := false
+	for _, iss := range result.Issues {
+		found = found || iss.MessageID == string(issue.DiagCodeNotInCodeSystem)
+	}
+	if !found {
+		t.Errorf("issues = %v, want %s for the bogus code", result.Issues, issue.DiagCodeNotInCodeSystem)
+	}
+	for request, n := range requested {
+		if n != 1 && !strings.HasPrefix(request, "/metadata") {
+			t.Errorf("%s requested %d times, want once", request, n)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/phase"
+)
+
+// WithEntryProfileMap validates the resources of Bundle entries against a
+// profile by resource type (e.g., "Patient" -> a national patient profile)
+// when they declare none in meta.profile, as gateways of national IGs
+// require. Their issues are reported at the entry's path. Later calls replace
+// the profiles of the same types.
+func WithEntryProfileMap(profiles map[string]string) Option {
+	return func(c *Config) {
+		if c.EntryProfiles == nil {
+			c.EntryProfiles = make(map[string]string, len(profiles))
+		}
+		maps.Copy(c.EntryProfiles, profiles)
+	}
+}
+
+// BundleResult is the result of ValidateBundle: every issue of the Bundle,
+// as Validate reports them, and the same issues grouped by entry, so that a
+// server can answer each entry with its own OperationOutcome.
+type BundleResult struct {
+	*issue.Result
+
+	// Entries holds one EntryResult per Bundle entry, in entry order.
+	Entries []EntryResult
+
+	// BundleIssues holds the issues outside any entry, such as those of
+	// Bundle.type or duplicate fullUrls reported on the Bundle itself.
+	BundleIssues *issue.Result
+
+	// Type is Bundle.type (e.g., "transaction").
+	Type string
+}
+
+// EntryResult holds the issues of one Bundle entry.
+type EntryResult struct {
+	Index        int    // Position in Bundle.entry
+	FullURL      string // entry.fullUrl, if any
+	ResourceType string // Type of entry.resource ("" without a resource)
+	// Profile is the profile the resource was validated against by
+	// WithEntryProfileMap, "" when it declares its own or none applies.
+	Profile string
+	// Result holds the issues whose expression is within the entry.
+	Result *issue.Result
+
+	Valid    bool // No fatal or error issues within the entry
+	Errors   int  // Fatal and error issues
+	Warnings int
+	Infos    int
+}
+
+// OperationOutcome returns the issues of the entry as an OperationOutcome.
+func (e *EntryResult) OperationOutcome() *issue.OperationOutcome {
+	return e.Result.OperationOutcome()
+}
+
+// InvalidEntries returns the number of entries with errors.
+func (b *BundleResult) InvalidEntries() int {
+	n := 0
+	for i := range b.Entries {
+		if !b.Entries[i].Valid {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidateBundle validates a Bundle like Validate, and groups its issues by
+// entry (by the Bundle.entry[i] index in their first expression). Resources
+// other than a Bundle are an error.
+func (v *Validator) ValidateBundle(ctx context.Context, bundle []byte, opts ...ValidateOption) (*BundleResult, error) {
+	result, err := v.Validate(ctx, bundle, opts...)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeResource(bundle)
+	if err != nil {
+		return &BundleResult{Result: result, BundleIssues: result}, nil // Reported as invalid JSON
+	}
+	if resourceType, _ := data["resourceType"].(string); resourceType != "Bundle" {
+		return nil, fmt.Errorf("resource is a %s, not a Bundle", resourceType)
+	}
+
+	entries, _ := data["entry"].([]any)
+	br := &BundleResult{Result: result, Entries: make([]EntryResult, len(entries)), BundleIssues: issue.NewResult()}
+	br.Type, _ = data["type"].(string)
+	for i, e := range entries {
+		entry, _ := e.(map[string]any)
+		resource, _ := entry["resource"].(map[string]any)
+		er := EntryResult{Index: i, Result: issue.NewResult()}
+		er.FullURL, _ = entry["fullUrl"].(string)
+		er.ResourceType, _ = resource["resourceType"].(string)
+		if resource != nil && len(metaProfiles(resource)) == 0 {
+			er.Profile = v.config.EntryProfiles[er.ResourceType]
+		}
+		br.Entries[i] = er
+	}
+	for _, iss := range result.Issues {
+		if i, ok := entryIndex(iss); ok && i < len(br.Entries) {
+			br.Entries[i].Result.AddIssue(iss)
+		} else {
+			br.BundleIssues.AddIssue(iss)
+		}
+	}
+	for i := range br.Entries {
+		e := &br.Entries[i]
+		e.Errors, e.Warnings, e.Infos = e.Result.ErrorCount(), e.Result.WarningCount(), e.Result.InfoCount()
+		e.Valid = e.Errors == 0
+	}
+	return br, nil
+}
+
+// entryIndex returns the index of the Bundle entry an issue is within.
+func entryIndex(iss issue.Issue) (int, bool) {
+	if len(iss.Expression) == 0 {
+		return 0, false
+	}
+	rest, ok := strings.CutPrefix(iss.Expression[0], "Bundle.entry[")
+	if !ok {
+		return 0, false
+	}
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return 0, false
+	}
+	i, err := strconv.Atoi(rest[:end])
+	return i, err == nil
+}
+
+// validateEntryProfiles validates the entry resources of a Bundle that
+// declare no profile against the profile WithEntryProfileMap sets for their
+// type, adding their issues at the entry's path.
+func (v *Validator) validateEntryProfiles(ctx context.Context, vc *validateConfig, phases phase.Set, data map[string]any, result *issue.Result) {
+	entries, _ := data["entry"].([]any)
+	for i, e := range entries {
+		entry, _ := e.(map[string]any)
+		resource, _ := entry["resource"].(map[string]any)
+		resourceType, _ := resource["resourceType"].(string)
+		profile := v.config.EntryProfiles[resourceType]
+		if profile == "" || len(metaProfiles(resource)) > 0 {
+			continue
+		}
+		entryPath := fmt.Sprintf("Bundle.entry[%d].resource", i)
+
+		sd := v.registry.GetByURL(profile)
+		if sd == nil {
+			result.AddWarningWithID(issue.DiagProfileNotFound, issue.Params{issue.String("url", profile)}, entryPath)
+			continue
+		}
+		raw, err := json.Marshal(resource)
+		if err != nil {
+			continue
+		}
+		entryPhases := phases
+		if policy := v.policies[resourceType]; policy != nil {
+			entryPhases = policy.callPhases(vc, phases)
+		}
+
+		entryResult := issue.GetPooledResult()
+		entryResult.Stats = &issue.Stats{}
+		ok := v.validateAgainstProfile(ctx, entryPhases, resource, raw, sd, profile, entryResult)
+		for j := range entryResult.Issues {
+			iss := &entryResult.Issues[j]
+			if iss.Profile == "" {
+				iss.Profile = sd.URL
+			}
+			if len(iss.Expression) == 0 {
+				iss.Expression = []string{entryPath}
+			}
+			for k, expr := range iss.Expression {
+				iss.Expression[k] = rebasePath(expr, resourceType, entryPath)
+			}
+		}
+		result.Merge(entryResult)
+		issue.ReleaseResult(entryResult)
+		if !ok {
+			return
+		}
+	}
+}
+
+// rebasePath moves a path rooted at a resource type (e.g., "Patient.name")
+// under base (e.g., "Bundle.entry[0].resource.name").
+func rebasePath(path, resourceType, base string) string {
+	rest, ok := strings.CutPrefix(path, resourceType)
+	if !ok || (rest != "" && rest[0] != '.' && rest[0] != '[') {
+		return path
+	}
+	return base + rest
+}
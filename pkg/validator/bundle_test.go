@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEntryProfileMap(t *testing.T) {
+	const vitalSigns = "http://hl7.org/fhir/StructureDefinition/vitalsigns"
+	v, err := New(WithEntryProfileMap(map[string]string{"Observation": vitalSigns}))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	bundle := []byte(`{"resourceType": "Bundle", "type": "collection", "entry": [
+		{"fullUrl": "urn:uuid:1", "resource": {"resourceType": "Observation", "status": "final", "code": {"text": "x"}}},
+		{"fullUrl": "urn:uuid:2", "resource": {"resourceType": "Observation", "status": "final", "code": {"text": "x"},
+			"meta": {"profile": ["http://hl7.org/fhir/StructureDefinition/Observation"]}}},
+		{"fullUrl": "urn:uuid:3", "resource": {"resourceType": "Patient"}}
+	]}`)
+	result, err := v.ValidateBundle(context.Background(), bundle)
+	if err != nil {
+		t.Fatalf("ValidateBundle() error: %v", err)
+	}
+	if len(result.Entries) != 3 {
+		t.Fatalf("len(Entries) = %d, want 3", len(result.Entries))
+	}
+
+	routed := result.Entries[0]
+	if routed.FullURL != "urn:uuid:1" || routed.ResourceType != "Observation" || routed.Profile != vitalSigns {
+		t.Errorf("Entries[0] = %+v, want urn:uuid:1, Observation, %s", routed, vitalSigns)
+	}
+	if !routed.Result.HasErrors() {
+		t.Error("Entries[0] has no errors, want vital signs violations")
+	}
+	for _, iss := range routed.Result.Issues {
+		if !strings.HasPrefix(iss.Expression[0], "Bundle.entry[0]") {
+			t.Errorf("Entries[0] issue at %v", iss.Expression)
+		}
+	}
+
+	for _, e := range result.Entries[1:] {
+		if e.Profile != "" {
+			t.Errorf("Entries[%d].Profile = %q, want none", e.Index, e.Profile)
+		}
+		for _, iss := range e.Result.Issues {
+			if iss.Profile == vitalSigns {
+				t.Errorf("Entries[%d] validated against vital signs: %s", e.Index, iss.Diagnostics)
+			}
+		}
+	}
+
+	if _, err := v.ValidateBundle(context.Background(), []byte(`{"resourceType": "Patient"}`)); err == nil {
+		t.Error("ValidateBundle(Patient) error = nil, want an error")
+	}
+}
+
+func TestValidateBundleEntries(t *testing.T) {
+	v := getSharedValidator(t)
+
+	bundle := []byte(`{"resourceType": "Bundle", "type": "collection", "entry": [
+		{"fullUrl": "urn:uuid:a", "resource": {"resourceType": "Patient", "gender": "unknown-value"}},
+		{"fullUrl": "urn:uuid:b", "resource": {"resourceType": "Patient", "gender": "female"}}
+	]}`)
+	result, err := v.ValidateBundle(context.Background(), bundle)
+	if err != nil {
+		t.Fatalf("ValidateBundle() error: %v", err)
+	}
+	if len(result.Entries) != 2 {
+		t.Fatalf("len(Entries) = %d, want 2", len(result.Entries))
+	}
+
+	bad, good := result.Entries[0], result.Entries[1]
+	if bad.Valid || bad.Errors == 0 || bad.Errors != bad.Result.ErrorCount() {
+		t.Errorf("Entries[0]: Valid = %v, Errors = %d, want invalid with errors", bad.Valid, bad.Errors)
+	}
+	if !good.Valid || good.Errors != 0 || good.FullURL != "urn:uuid:b" {
+		t.Errorf("Entries[1] = %+v, want valid urn:uuid:b", good)
+	}
+	if n := result.InvalidEntries(); n != 1 {
+		t.Errorf("InvalidEntries() = %d, want 1", n)
+	}
+
+	grouped := len(result.BundleIssues.Issues)
+	for _, e := range result.Entries {
+		grouped += len(e.Result.Issues)
+	}
+	if grouped != len(result.Issues) {
+		t.Errorf("grouped %d issues, Result has %d", grouped, len(result.Issues))
+	}
+	if oo := bad.OperationOutcome(); oo.ResourceType != "OperationOutcome" || len(oo.Issue) != len(bad.Result.Issues) {
+		t.Errorf("Entries[0].OperationOutcome() = %+v", oo)
+	}
+}
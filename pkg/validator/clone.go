@@ -0,0 +1,131 @@
+package validator
+
+import (
+	"github.com/gofhir/validator/pkg/audit"
+	"github.com/gofhir/validator/pkg/authoring"
+	"github.com/gofhir/validator/pkg/binding"
+	"github.com/gofhir/validator/pkg/bundle"
+	"github.com/gofhir/validator/pkg/cache"
+	"github.com/gofhir/validator/pkg/canonical"
+	"github.com/gofhir/validator/pkg/cardinality"
+	"github.com/gofhir/validator/pkg/constraint"
+	"github.com/gofhir/validator/pkg/contained"
+	"github.com/gofhir/validator/pkg/crossversion"
+	"github.com/gofhir/validator/pkg/datatype"
+	"github.com/gofhir/validator/pkg/extension"
+	"github.com/gofhir/validator/pkg/fixedpattern"
+	"github.com/gofhir/validator/pkg/identifier"
+	"github.com/gofhir/validator/pkg/narrative"
+	"github.com/gofhir/validator/pkg/obligation"
+	"github.com/gofhir/validator/pkg/operation"
+	"github.com/gofhir/validator/pkg/primitive"
+	"github.com/gofhir/validator/pkg/reference"
+	"github.com/gofhir/validator/pkg/rules"
+	"github.com/gofhir/validator/pkg/sanity"
+	"github.com/gofhir/validator/pkg/slicing"
+	"github.com/gofhir/validator/pkg/structural"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// Clone returns a Validator that shares the loaded definitions, terminology
+// and configuration of v, but has its own phase validators and caches
+// (element indexes, compiled FHIRPath expressions, regular expressions and
+// custom types) and, with WithMemoryBudget, its own budget for them.
+//
+// A Validator is safe for concurrent use, so one Validator can serve all the
+// requests of a server; Clone is for callers that need to keep per-goroutine
+// or per-tenant state apart, such as cache statistics. Cloning is cheap
+// compared to New: nothing is loaded again.
+func (v *Validator) Clone() *Validator {
+	c := &Validator{
+		registry:              v.registry,
+		termRegistry:          v.termRegistry,
+		operations:            v.operations,
+		loader:                v.loader,
+		config:                v.config,
+		phases:                v.phases,
+		policies:              v.policies,
+		globalProfiles:        v.globalProfiles,
+		businessRules:         v.businessRules,
+		subscriptionValidator: v.subscriptionValidator,
+		instruments:           v.instruments,
+		usage:                 v.usage,
+	}
+	c.initPhaseValidators()
+	if c.config.MemoryBudget > 0 {
+		c.initBudget()
+	}
+	return c
+}
+
+// initPhaseValidators creates the phase validators of v from its registries
+// and configuration, except the subscription validator, which needs the
+// loaded search parameters and topics.
+func (v *Validator) initPhaseValidators() {
+	reg, termReg, config := v.registry, v.termRegistry, v.config
+
+	v.structValidator = structural.New(reg)
+	v.cardValidator = cardinality.New(reg)
+	v.primValidator = primitive.New(reg)
+	v.primValidator.SetMaxBase64Size(config.MaxBase64Size)
+	v.bindValidator = binding.New(reg, termReg)
+	if config.UCUMService != nil {
+		v.bindValidator.SetUCUMService(config.UCUMService)
+	}
+	v.bindValidator.SetUnitConsistency(config.UnitConsistency)
+	v.extValidator = extension.New(reg, termReg, v.primValidator)
+	v.extValidator.SetKnownModifierExtensions(config.KnownModifierExtensions)
+	v.refValidator = reference.New(reg)
+	v.refValidator.SetResolveMode(config.ReferenceResolution)
+	v.refValidator.SetRetiredResourceTypes(config.RetiredResourceTypes)
+	v.refValidator.SetResolver(config.ReferenceResolver)
+	v.refValidator.SetSource(config.ReferenceSource)
+	v.refValidator.SetTargetValidator(v.validateReferenceTarget)
+	v.containedValidator = contained.New()
+	v.narrativeValidator = narrative.New(reg)
+	v.formatter = canonical.NewFormatter(reg)
+	v.constraintValidator = constraint.New(reg)
+	v.constraintValidator.SkipKeys(contained.ConstraintKeys...)
+	v.constraintValidator.SkipKeys(bundle.ConstraintKeys...)
+	v.constraintValidator.SkipKeys(binding.ConstraintKeys...)
+	v.constraintValidator.SetTerminology(termReg)
+	v.fixedPatternValidator = fixedpattern.New(reg)
+	v.slicingValidator = slicing.New(reg)
+	v.bundleValidator = bundle.New()
+	identifiers := identifier.NewRegistry()
+	for system, check := range config.IdentifierValidators {
+		identifiers.Register(system, check)
+	}
+	v.identifierValidator = identifier.New(reg, identifiers)
+	v.datatypeValidator = datatype.New(reg)
+	v.datatypeValidator.SetAllowedContentTypes(config.AllowedContentTypes)
+	v.operationValidator = operation.New(reg)
+	if config.SanityChecks {
+		v.sanityValidator = sanity.New(reg, config.SanityRules, config.DisabledSanityRules)
+	}
+	if config.AuditRules {
+		v.auditValidator = audit.New(reg, v.bindValidator)
+	}
+	if config.Actor != "" {
+		v.obligationValidator = obligation.New(config.Actor)
+	}
+	if config.AuthorMode {
+		v.authoringValidator = authoring.New(reg, termReg)
+	}
+	if v.businessRules != nil {
+		v.rulesValidator = rules.New(reg, v.businessRules)
+	}
+	v.converter = crossversion.New(reg, config.FHIRVersion)
+	v.walker = walker.New(reg)
+}
+
+// initBudget creates the memory budget of v's caches, sized from the heap
+// in use now.
+func (v *Validator) initBudget() {
+	v.budget = cache.NewBudget(v.config.MemoryBudget)
+	v.budget.Add(CacheExpansions, v.termRegistry.ExpansionCache(), 0.5)
+	v.budget.Add(CacheElementIndexes, v.structValidator.IndexCache(), 0.25)
+	v.budget.Add(CacheExpressions, v.constraintValidator.ExpressionCache(), 0.2)
+	v.budget.Add(CacheMemberships, v.constraintValidator.MembershipCache(), 0.05)
+	v.budget.Apply(cache.HeapInUse())
+}
@@ -0,0 +1,163 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/phase"
+)
+
+// concurrencyCorpus returns the fixture resources, by file name.
+func concurrencyCorpus(t *testing.T) map[string][]byte {
+	t.Helper()
+	files, err := filepath.Glob("../../testdata/*/*.json")
+	if err != nil || len(files) == 0 {
+		t.Skipf("no fixtures: %v", err)
+	}
+	corpus := make(map[string][]byte, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		corpus[filepath.Base(file)] = data
+	}
+	return corpus
+}
+
+// summarize returns the issues of a result in a comparable form.
+func summarize(result *issue.Result) string {
+	var s string
+	for _, iss := range result.Issues {
+		s += fmt.Sprintf("%s %s %s %v\n", iss.Severity, iss.MessageID, iss.Diagnostics, iss.Expression)
+	}
+	return s
+}
+
+// concurrencyCalls are the ways the concurrency suite validates a resource:
+// with the default phases, with per-call options, and decoded.
+var concurrencyCalls = []struct {
+	name     string
+	validate func(ctx context.Context, v *Validator, data []byte) (*issue.Result, error)
+}{
+	{"Validate", func(ctx context.Context, v *Validator, data []byte) (*issue.Result, error) {
+		return v.Validate(ctx, data)
+	}},
+	{"WithoutTerminology", func(ctx context.Context, v *Validator, data []byte) (*issue.Result, error) {
+		return v.Validate(ctx, data, ValidateWithoutPhases(phase.Terminology))
+	}},
+	{"ValidateMap", func(ctx context.Context, v *Validator, data []byte) (*issue.Result, error) {
+		var decoded map[string]any
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return nil, err
+		}
+		return v.ValidateMap(ctx, decoded)
+	}},
+}
+
+// TestConcurrentValidation validates the fixtures from many goroutines at
+// once, with one shared Validator and with clones of it, and checks that
+// each result equals the result of validating the resource alone. Run with
+// -race to detect shared state.
+func TestConcurrentValidation(t *testing.T) {
+	v := getSharedValidator(t)
+	corpus := concurrencyCorpus(t)
+	ctx := context.Background()
+
+	names := make([]string, 0, len(corpus))
+	for name := range corpus {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	want := make(map[string]string)
+	for _, name := range names {
+		for _, call := range concurrencyCalls {
+			result, err := call.validate(ctx, v, corpus[name])
+			if err != nil {
+				t.Fatalf("%s %s: error: %v", call.name, name, err)
+			}
+			want[call.name+" "+name] = summarize(result)
+		}
+	}
+
+	const goroutines = 8
+	var wg sync.WaitGroup
+	errs := make(chan string, goroutines*len(names)*len(concurrencyCalls))
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shared := v
+			if g%2 == 1 {
+				shared = v.Clone()
+			}
+			// Each goroutine visits the corpus in a different order
+			for i := range names {
+				name := names[(i+g*7)%len(names)]
+				call := concurrencyCalls[(i+g)%len(concurrencyCalls)]
+				result, err := call.validate(ctx, shared, corpus[name])
+				if err != nil {
+					errs <- fmt.Sprintf("%s %s: error: %v", call.name, name, err)
+					continue
+				}
+				if got := summarize(result); got != want[call.name+" "+name] {
+					errs <- fmt.Sprintf("%s %s: concurrent result differs:\n%s\nwant:\n%s", call.name, name, got, want[call.name+" "+name])
+				}
+				_ = shared.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+	patient := []byte(`{"resourceType": "Patient", "gender": "bogus", "name": [{"family": "Doe"}]}`)
+
+	c := v.Clone()
+	if c.Registry() != v.Registry() || c.Terminology() != v.Terminology() || c.Config() != v.Config() {
+		t.Error("clone does not share the loaded definitions and configuration")
+	}
+	if got := c.Stats().Caches[CacheElementIndexes].Entries; got != 0 {
+		t.Errorf("clone starts with %d element indexes, want 0", got)
+	}
+
+	want, err := v.Validate(ctx, patient)
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	got, err := c.Validate(ctx, patient)
+	if err != nil {
+		t.Fatalf("clone Validate() error: %v", err)
+	}
+	if summarize(got) != summarize(want) {
+		t.Errorf("clone result:\n%s\nwant:\n%s", summarize(got), summarize(want))
+	}
+
+	before := v.Stats().Caches[CacheElementIndexes]
+	for range 3 {
+		if _, err := c.Validate(ctx, patient); err != nil {
+			t.Fatalf("clone Validate() error: %v", err)
+		}
+	}
+	after := v.Stats().Caches[CacheElementIndexes]
+	if after.Hits != before.Hits || after.Misses != before.Misses {
+		t.Errorf("validating with the clone used the original's element index cache: %+v -> %+v", before, after)
+	}
+	if c.Stats().Caches[CacheElementIndexes].Hits == 0 {
+		t.Error("clone element index cache has no hits")
+	}
+}
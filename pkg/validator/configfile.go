@@ -0,0 +1,369 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/phase"
+	"github.com/gofhir/validator/pkg/terminology"
+)
+
+// DefaultConfigFile is the configuration file name the CLI looks for in the
+// working directory.
+const DefaultConfigFile = "gofhir-validator.yaml"
+
+// FileConfig is the content of a validator configuration file, in YAML or
+// JSON, for committing reproducible validation settings:
+//
+//	fhirVersion: 4.0.1
+//	sourceVersion: R5
+//	packages:
+//	  - hl7.fhir.us.core#6.1.0
+//	igs:
+//	  - hl7.fhir.uv.ips#1.1.0
+//	versionPolicy: latest
+//	canonicalMapping:
+//	  https://internal.example.org/fhir/: http://hl7.org/fhir/us/core/
+//	profiles:
+//	  - http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient
+//	profileSelection: precedence
+//	terminology:
+//	  server: https://tx.fhir.org/r4
+//	severity:
+//	  CONSTRAINT_FAILED: warning
+//	suppress:
+//	  - id: CONSTRAINT_FAILED
+//	    contains: dom-6
+//	phases:
+//	  disable: [narrative]
+//	resourceTypes:
+//	  AuditEvent:
+//	    disable: [terminology]
+//	  Patient:
+//	    strict: true
+//	    profiles:
+//	      - http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient
+//	entryProfiles:
+//	  Patient: http://hl7.org/fhir/us/core/StructureDefinition/us-core-patient
+//	quality:
+//	  errorPenalty: 25
+//	rules:
+//	  - rules/coverage.rules
+type FileConfig struct {
+	FHIRVersion      string            `json:"fhirVersion,omitempty" yaml:"fhirVersion"`
+	SourceVersion    string            `json:"sourceVersion,omitempty" yaml:"sourceVersion"`       // FHIR version of the resources (see WithSourceVersion)
+	Packages         []string          `json:"packages,omitempty" yaml:"packages"`                 // name#version, from the package cache
+	IGs              []string          `json:"igs,omitempty" yaml:"igs"`                           // name#version; also applies their global profiles
+	VersionPolicy    string            `json:"versionPolicy,omitempty" yaml:"versionPolicy"`       // latest | first (see WithVersionPolicy)
+	CanonicalMapping map[string]string `json:"canonicalMapping,omitempty" yaml:"canonicalMapping"` // mirror base URL -> published base URL
+	PackageFiles     []string          `json:"packageFiles,omitempty" yaml:"packageFiles"`         // .tgz paths, relative to the file
+	PackageURLs      []string          `json:"packageUrls,omitempty" yaml:"packageUrls"`
+	Offline          bool              `json:"offline,omitempty" yaml:"offline"` // load packageUrls from the cache only
+	Profiles         []string          `json:"profiles,omitempty" yaml:"profiles"`
+	ProfileSelection string            `json:"profileSelection,omitempty" yaml:"profileSelection"` // union | precedence (see WithProfileSelection)
+	Terminology      TerminologyConfig `json:"terminology" yaml:"terminology"`
+	Severity         map[string]string `json:"severity,omitempty" yaml:"severity"` // Diagnostic ID -> severity
+	Suppress         []SuppressRule    `json:"suppress,omitempty" yaml:"suppress"`
+	Phases           PhaseConfig       `json:"phases" yaml:"phases"`
+	Locale           string            `json:"locale,omitempty" yaml:"locale"`
+
+	ResourceTypes map[string]ResourceTypeConfig `json:"resourceTypes,omitempty" yaml:"resourceTypes"` // resource type -> policy
+	EntryProfiles map[string]string             `json:"entryProfiles,omitempty" yaml:"entryProfiles"` // resource type -> profile of Bundle entries
+	Quality       *QualityConfig                `json:"quality,omitempty" yaml:"quality"`             // Enables quality scoring
+	Rules         []string                      `json:"rules,omitempty" yaml:"rules"`                 // Business rules files, relative to the file
+}
+
+// ResourceTypeConfig is the file form of Policy.
+type ResourceTypeConfig struct {
+	Only     []phase.Name `json:"only,omitempty" yaml:"only"`
+	Disable  []phase.Name `json:"disable,omitempty" yaml:"disable"`
+	Profiles []string     `json:"profiles,omitempty" yaml:"profiles"`
+	Strict   bool         `json:"strict,omitempty" yaml:"strict"`
+}
+
+// TerminologyConfig selects an external terminology server.
+type TerminologyConfig struct {
+	Server         string            `json:"server,omitempty" yaml:"server"`                 // FHIR terminology server base URL
+	Timeout        string            `json:"timeout,omitempty" yaml:"timeout"`               // Per-request timeout (e.g., "5s")
+	SystemVersions map[string]string `json:"systemVersions,omitempty" yaml:"systemVersions"` // Code system URL -> pinned version
+	Prefetch       int               `json:"prefetch,omitempty" yaml:"prefetch"`             // Concurrent requests made ahead of the binding phase
+}
+
+// QualityConfig is the file form of QualityScoring; omitted fields keep
+// their DefaultQualityScoring values.
+type QualityConfig struct {
+	ErrorPenalty      *configNumber `json:"errorPenalty,omitempty" yaml:"errorPenalty"`
+	WarningPenalty    *configNumber `json:"warningPenalty,omitempty" yaml:"warningPenalty"`
+	MustSupportWeight *configNumber `json:"mustSupportWeight,omitempty" yaml:"mustSupportWeight"`
+	TerminologyWeight *configNumber `json:"terminologyWeight,omitempty" yaml:"terminologyWeight"`
+}
+
+// scoring returns the QualityScoring the configuration describes.
+func (qc *QualityConfig) scoring() QualityScoring {
+	q := DefaultQualityScoring()
+	for _, f := range []struct {
+		from *configNumber
+		to   *float64
+	}{
+		{qc.ErrorPenalty, &q.ErrorPenalty},
+		{qc.WarningPenalty, &q.WarningPenalty},
+		{qc.MustSupportWeight, &q.MustSupportWeight},
+		{qc.TerminologyWeight, &q.TerminologyWeight},
+	} {
+		if f.from != nil {
+			*f.to = float64(*f.from)
+		}
+	}
+	return q
+}
+
+// configNumber is a number, also accepted as a JSON string.
+type configNumber float64
+
+func (n *configNumber) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		data = []byte(s)
+	}
+	f, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %s", data)
+	}
+	*n = configNumber(f)
+	return nil
+}
+
+// SuppressRule is the file form of issue.Suppression.
+type SuppressRule struct {
+	ID       string `json:"id,omitempty" yaml:"id"`
+	Code     string `json:"code,omitempty" yaml:"code"`
+	Path     string `json:"path,omitempty" yaml:"path"`
+	Contains string `json:"contains,omitempty" yaml:"contains"`
+}
+
+// PhaseConfig controls the validation phases.
+type PhaseConfig struct {
+	Only    []phase.Name `json:"only,omitempty" yaml:"only"`       // Run only these phases (see phase.Parse)
+	Disable []phase.Name `json:"disable,omitempty" yaml:"disable"` // Skip these phases
+	Timeout string       `json:"timeout,omitempty" yaml:"timeout"` // Per-phase budget (e.g., "2s")
+}
+
+// LoadConfigFile reads a configuration file. Files ending in .json are
+// parsed as JSON and anything else as YAML; unknown keys are errors.
+// Relative packageFiles and rules are resolved against the file's directory.
+func LoadConfigFile(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc FileConfig
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&fc)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(&fc); errors.Is(err, io.EOF) {
+			err = nil // empty file
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for _, paths := range [][]string{fc.PackageFiles, fc.Rules} {
+		for i, p := range paths {
+			if !filepath.IsAbs(p) {
+				paths[i] = filepath.Join(dir, p)
+			}
+		}
+	}
+	return &fc, nil
+}
+
+// Options converts the configuration into validator options.
+func (fc *FileConfig) Options() ([]Option, error) {
+	var opts []Option
+	if fc.FHIRVersion != "" {
+		opts = append(opts, WithVersion(fc.FHIRVersion))
+	}
+	if fc.SourceVersion != "" {
+		opts = append(opts, WithSourceVersion(fc.SourceVersion))
+	}
+	for _, pkg := range fc.Packages {
+		name, version, ok := strings.Cut(pkg, "#")
+		if !ok || name == "" || version == "" {
+			return nil, fmt.Errorf("package %q: expected name#version", pkg)
+		}
+		opts = append(opts, WithPackage(name, version))
+	}
+	for _, ig := range fc.IGs {
+		name, version, ok := strings.Cut(ig, "#")
+		if !ok || name == "" || version == "" {
+			return nil, fmt.Errorf("ig %q: expected name#version", ig)
+		}
+		opts = append(opts, WithIG(ig))
+	}
+	if fc.VersionPolicy != "" {
+		policy, ok := loader.ParseVersionPolicy(fc.VersionPolicy)
+		if !ok {
+			return nil, fmt.Errorf("versionPolicy %q: expected latest or first", fc.VersionPolicy)
+		}
+		opts = append(opts, WithVersionPolicy(policy))
+	}
+	if fc.ProfileSelection != "" {
+		selection, ok := ParseProfileSelection(fc.ProfileSelection)
+		if !ok {
+			return nil, fmt.Errorf("profileSelection %q: expected union or precedence", fc.ProfileSelection)
+		}
+		opts = append(opts, WithProfileSelection(selection))
+	}
+	if len(fc.CanonicalMapping) > 0 {
+		opts = append(opts, WithCanonicalMapping(fc.CanonicalMapping))
+	}
+	for _, path := range fc.PackageFiles {
+		opts = append(opts, WithPackageTgz(path))
+	}
+	for _, url := range fc.PackageURLs {
+		opts = append(opts, WithPackageURL(url))
+	}
+	if fc.Offline {
+		opts = append(opts, WithOffline(true))
+	}
+	for _, profile := range fc.Profiles {
+		opts = append(opts, WithProfile(profile))
+	}
+
+	if fc.Terminology.Server != "" {
+		client, err := terminologyClient(fc.Terminology.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTerminologyProvider(terminology.NewServerProvider(fc.Terminology.Server, client)))
+	}
+	if fc.Terminology.Prefetch > 0 {
+		opts = append(opts, WithTerminologyPrefetch(fc.Terminology.Prefetch))
+	}
+	for system, version := range fc.Terminology.SystemVersions {
+		opts = append(opts, WithSystemVersion(system, version))
+	}
+
+	for id, name := range fc.Severity {
+		if _, ok := issue.LocalizedTemplate(issue.DiagnosticID(id), issue.DefaultLocale); !ok {
+			return nil, fmt.Errorf("severity: unknown diagnostic ID %q", id)
+		}
+		severity, err := issue.ParseSeverity(name)
+		if err != nil {
+			return nil, fmt.Errorf("severity of %s: %w", id, err)
+		}
+		opts = append(opts, WithSeverityOverride(issue.DiagnosticID(id), severity))
+	}
+
+	for i, rule := range fc.Suppress {
+		s := issue.Suppression{
+			ID:       issue.DiagnosticID(rule.ID),
+			Code:     issue.Code(rule.Code),
+			Path:     rule.Path,
+			Contains: rule.Contains,
+		}
+		if s == (issue.Suppression{}) {
+			return nil, fmt.Errorf("suppress[%d]: set at least one of id, code, path or contains", i)
+		}
+		opts = append(opts, WithSuppressions(s))
+	}
+
+	if len(fc.Phases.Only) > 0 {
+		opts = append(opts, WithPhases(fc.Phases.Only...))
+	}
+	if len(fc.Phases.Disable) > 0 {
+		opts = append(opts, WithDisabledPhases(fc.Phases.Disable...))
+	}
+	if fc.Phases.Timeout != "" {
+		d, err := time.ParseDuration(fc.Phases.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("phases.timeout: %w", err)
+		}
+		opts = append(opts, WithPhaseTimeout(d))
+	}
+
+	if fc.Locale != "" {
+		opts = append(opts, WithLocale(fc.Locale))
+	}
+	if len(fc.ResourceTypes) > 0 {
+		policies := make(map[string]Policy, len(fc.ResourceTypes))
+		for resourceType, rc := range fc.ResourceTypes {
+			policies[resourceType] = Policy{Phases: rc.Only, DisabledPhases: rc.Disable, Profiles: rc.Profiles, Strict: rc.Strict}
+		}
+		opts = append(opts, WithResourceTypePolicy(policies))
+	}
+	if len(fc.EntryProfiles) > 0 {
+		opts = append(opts, WithEntryProfileMap(fc.EntryProfiles))
+	}
+	if fc.Quality != nil {
+		opts = append(opts, WithQualityScoring(fc.Quality.scoring()))
+	}
+	for _, path := range fc.Rules {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("rules: %w", err)
+		}
+		opts = append(opts, WithBusinessRules(rulesFile{bytes.NewReader(data), path}))
+	}
+	return opts, nil
+}
+
+// rulesFile is the content of a business rules file, named by its path in
+// errors (see rules.Parse).
+type rulesFile struct {
+	*bytes.Reader
+	path string
+}
+
+// Name returns the path of the file.
+func (f rulesFile) Name() string {
+	return f.path
+}
+
+// NewFromConfigFile creates a Validator from a configuration file (see
+// FileConfig). Opts are applied after the file's settings, so they can
+// override or extend them.
+func NewFromConfigFile(path string, opts ...Option) (*Validator, error) {
+	fc, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fileOpts, err := fc.Options()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return New(append(fileOpts, opts...)...)
+}
+
+// terminologyClient returns the HTTP client for a terminology timeout, or
+// nil for the provider default.
+func terminologyClient(timeout string) (*http.Client, error) {
+	if timeout == "" {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("terminology.timeout: %w", err)
+	}
+	return &http.Client{Timeout: d}, nil
+}
@@ -0,0 +1,178 @@
+package validator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/phase"
+)
+
+func TestLoadConfigFileYAML(t *testing.T) {
+	tests := []struct {
+		name, content string
+		want          FileConfig
+	}{
+		{"empty file", "# nothing yet\n", FileConfig{}},
+		{"document marker", "---\nlocale: es\n", FileConfig{Locale: "es"}},
+		{"numeric-looking version stays a string", "fhirVersion: 4.0\n", FileConfig{FHIRVersion: "4.0"}},
+		{"integer", "terminology:\n  prefetch: 8\n", FileConfig{Terminology: TerminologyConfig{Prefetch: 8}}},
+		{"boolean", "offline: true\n", FileConfig{Offline: true}},
+		{"double quotes", `profiles: ["http://example.org/a#b", "tab\there"]` + "\n",
+			FileConfig{Profiles: []string{"http://example.org/a#b", "tab\there"}}},
+		{"single quotes", "profiles: ['it''s quoted']\n", FileConfig{Profiles: []string{"it's quoted"}}},
+		{"comment after value", "locale: es # Spanish\n", FileConfig{Locale: "es"}},
+		{"hash inside value", "suppress:\n  - contains: dom-6#x\n", FileConfig{Suppress: []SuppressRule{{Contains: "dom-6#x"}}}},
+		{"hash inside quotes", "suppress:\n  - contains: \"dom-6 # not a comment\"\n",
+			FileConfig{Suppress: []SuppressRule{{Contains: "dom-6 # not a comment"}}}},
+		{"flow sequence", "phases:\n  disable: [narrative, 'slicing']\n",
+			FileConfig{Phases: PhaseConfig{Disable: []phase.Name{phase.Narrative, phase.Slicing}}}},
+		{"flow mapping", "severity: {CONSTRAINT_FAILED: warning}\n", FileConfig{Severity: map[string]string{"CONSTRAINT_FAILED": "warning"}}},
+		{"block sequence at key indent", "packages:\n- a#1.0.0\n- b#2.0.0\n", FileConfig{Packages: []string{"a#1.0.0", "b#2.0.0"}}},
+		{"literal block scalar", "suppress:\n  - contains: |\n      line one\n      line two\n",
+			FileConfig{Suppress: []SuppressRule{{Contains: "line one\nline two\n"}}}},
+		{"folded block scalar", "suppress:\n  - contains: >-\n      one\n      two\n",
+			FileConfig{Suppress: []SuppressRule{{Contains: "one two"}}}},
+		{"multi-line plain scalar", "suppress:\n  - contains: one\n      two\n", FileConfig{Suppress: []SuppressRule{{Contains: "one two"}}}},
+		{"anchor and alias", "profiles: [&p http://example.org/p]\nentryProfiles:\n  Patient: *p\n",
+			FileConfig{Profiles: []string{"http://example.org/p"}, EntryProfiles: map[string]string{"Patient": "http://example.org/p"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadConfigFile(writeConfigFile(t, "c.yaml", tt.content))
+			if err != nil {
+				t.Fatalf("LoadConfigFile() error: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("LoadConfigFile() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "gofhir-validator.yaml", `
+fhirVersion: R4
+packageFiles: [igs/custom.tgz]
+versionPolicy: first
+canonicalMapping:
+  https://internal.example.org/fhir/: http://hl7.org/fhir/
+severity:
+  CONSTRAINT_FAILED: information
+phases:
+  disable: [narrative]
+  timeout: 2s
+quality:
+  errorPenalty: 25
+  mustSupportWeight: 0
+`)
+	fc, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error: %v", err)
+	}
+	if fc.FHIRVersion != "R4" || fc.Phases.Timeout != "2s" || fc.Severity["CONSTRAINT_FAILED"] != "information" {
+		t.Errorf("LoadConfigFile() = %+v", fc)
+	}
+	if want := filepath.Join(filepath.Dir(path), "igs", "custom.tgz"); len(fc.PackageFiles) != 1 || fc.PackageFiles[0] != want {
+		t.Errorf("PackageFiles = %v, want [%s]", fc.PackageFiles, want)
+	}
+
+	opts, err := fc.Options()
+	if err != nil {
+		t.Fatalf("Options() error: %v", err)
+	}
+	var c Config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.FHIRVersion != "R4" || c.PhaseTimeout.String() != "2s" ||
+		c.SeverityOverrides[issue.DiagConstraintFailed] != issue.SeverityInformation ||
+		c.VersionPolicy != loader.VersionFirstLoaded ||
+		c.CanonicalMapping["https://internal.example.org/fhir/"] != "http://hl7.org/fhir/" ||
+		!reflect.DeepEqual(c.DisabledPhases, []phase.Name{phase.Narrative}) ||
+		c.QualityScoring == nil || *c.QualityScoring != (QualityScoring{ErrorPenalty: 25, WarningPenalty: 5, TerminologyWeight: 0.1}) {
+		t.Errorf("Options() produced %+v", c)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := map[string]struct{ name, content, want string }{
+		"unknown key":        {"c.yaml", "fhirVerison: 4.0.1\n", "fhirVerison"},
+		"unknown json key":   {"c.json", `{"fhirVerison": "4.0.1"}`, "unknown field"},
+		"duplicate key":      {"c.yaml", "locale: es\nlocale: en\n", "already defined"},
+		"tab indentation":    {"c.yaml", "phases:\n\tdisable: [narrative]\n", "yaml:"},
+		"wrong type":         {"c.yaml", "terminology:\n  prefetch: many\n", "cannot unmarshal"},
+		"json":               {"c.json", `{"phases": {"disable": "narrative"}}`, "cannot unmarshal"},
+		"bad severity":       {"c.yaml", "severity:\n  CONSTRAINT_FAILED: severe\n", "unknown severity"},
+		"bad diagnostic":     {"c.yaml", "severity:\n  NO_SUCH_ID: error\n", "unknown diagnostic ID"},
+		"bad package":        {"c.yaml", "packages: [hl7.fhir.us.core]\n", "name#version"},
+		"bad ig":             {"c.yaml", "igs: [hl7.fhir.uv.ips]\n", "name#version"},
+		"bad policy":         {"c.yaml", "versionPolicy: newest\n", "latest or first"},
+		"bad selection":      {"c.yaml", "profileSelection: first\n", "union or precedence"},
+		"empty suppress":     {"c.yaml", "suppress:\n  - {}\n", "at least one"},
+		"bad timeout":        {"c.yaml", "phases:\n  timeout: soon\n", "phases.timeout"},
+		"bad tx timeout":     {"c.yaml", "terminology:\n  server: http://tx\n  timeout: 5\n", "terminology.timeout"},
+		"unknown phase":      {"c.yaml", "phases:\n  disable: [spelling]\n", "unknown validation phase"},
+		"unknown type phase": {"c.yaml", "resourceTypes:\n  AuditEvent:\n    disable: [spelling]\n", "policy for AuditEvent"},
+		"unknown locale":     {"c.yaml", "locale: xx\n", "unsupported locale"},
+		"invalid content":    {"c.yaml", "profiles: [a, b\n", "yaml:"},
+		"missing rules":      {"c.yaml", "rules: [missing.rules]\n", "missing.rules"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewFromConfigFile(writeConfigFile(t, tt.name, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewFromConfigFile() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFromConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "gofhir-validator.json", `{
+  "severity": {"CONSTRAINT_FAILED": "information"},
+  "suppress": [{"path": "Patient.gender"}],
+  "phases": {"disable": ["narrative"]}
+}`)
+	v, err := NewFromConfigFile(path)
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	result, err := v.Validate(context.Background(), []byte(`{"resourceType":"Patient","gender":"bogus"}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	for _, iss := range result.Issues {
+		if iss.MessageID == string(issue.DiagConstraintFailed) && iss.Severity != issue.SeverityInformation {
+			t.Errorf("constraint issue not overridden to information: %+v", iss)
+		}
+		if len(iss.Expression) > 0 && iss.Expression[0] == "Patient.gender" {
+			t.Errorf("suppressed issue reported: %+v", iss)
+		}
+	}
+	if result.HasErrors() {
+		t.Errorf("expected no errors after overrides and suppressions, got %v", result.Issues)
+	}
+	found := false
+	for _, p := range result.Stats.SkippedPhases {
+		found = found || p == "narrative"
+	}
+	if !found {
+		t.Errorf("SkippedPhases = %v, want narrative", result.Stats.SkippedPhases)
+	}
+}
@@ -2,8 +2,12 @@ package validator
 
 import (
 	"context"
+	"encoding/json"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
 )
 
 func TestConstraintValidation(t *testing.T) {
@@ -73,3 +77,185 @@ func TestConstraintValidation(t *testing.T) {
 		})
 	}
 }
+
+// inheritedConstraintDefinitions define a Patient profile with a name
+// constraint, and a profile derived from it whose snapshot lost that
+// constraint and adds a gender constraint.
+var inheritedConstraintDefinitions = [][]byte{
+	[]byte(`{
+		"resourceType": "StructureDefinition", "url": "http://example.org/fhir/StructureDefinition/named-patient",
+		"name": "NamedPatient", "status": "active", "kind": "resource", "abstract": false, "type": "Patient",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Patient", "derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Patient", "path": "Patient", "min": 0, "max": "*", "constraint": [
+				{"key": "named-1", "severity": "error", "human": "A name is required", "expression": "name.exists()"}
+			]}
+		]}
+	}`),
+	[]byte(`{
+		"resourceType": "StructureDefinition", "url": "http://example.org/fhir/StructureDefinition/gendered-patient",
+		"name": "GenderedPatient", "status": "active", "kind": "resource", "abstract": false, "type": "Patient",
+		"baseDefinition": "http://example.org/fhir/StructureDefinition/named-patient", "derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Patient", "path": "Patient", "min": 0, "max": "*", "constraint": [
+				{"key": "gendered-1", "severity": "error", "human": "A gender is required", "expression": "gender.exists()"}
+			]}
+		]}
+	}`),
+}
+
+func TestInheritedConstraints(t *testing.T) {
+	v, err := New(WithConformanceResources(inheritedConstraintDefinitions))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	result, err := v.Validate(context.Background(), []byte(`{"resourceType": "Patient"}`),
+		ValidateWithProfile("http://example.org/fhir/StructureDefinition/gendered-patient"))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	want := map[string]string{
+		"named-1":    "http://example.org/fhir/StructureDefinition/named-patient",
+		"gendered-1": "http://example.org/fhir/StructureDefinition/gendered-patient",
+		"dom-6":      "http://hl7.org/fhir/StructureDefinition/DomainResource",
+	}
+	for _, iss := range result.Issues {
+		for key, profile := range want {
+			if strings.Contains(iss.Diagnostics, key+":") {
+				if iss.Profile != profile {
+					t.Errorf("%s: Profile = %q, want %q", key, iss.Profile, profile)
+				}
+				delete(want, key)
+			}
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("constraints not reported: %v (issues: %+v)", want, result.Issues)
+	}
+}
+
+// memberOfDefinitions define a Patient profile whose constraints check the
+// gender with memberOf(), against a loaded ValueSet and a missing one.
+var memberOfDefinitions = [][]byte{
+	[]byte(`{
+		"resourceType": "ValueSet", "url": "http://example.org/fhir/ValueSet/binary-gender", "status": "active",
+		"compose": {"include": [{"system": "http://hl7.org/fhir/administrative-gender", "concept": [{"code": "male"}, {"code": "female"}]}]}
+	}`),
+	[]byte(`{
+		"resourceType": "StructureDefinition", "url": "http://example.org/fhir/StructureDefinition/member-patient",
+		"name": "MemberPatient", "status": "active", "kind": "resource", "abstract": false, "type": "Patient",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Patient", "derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Patient", "path": "Patient", "min": 0, "max": "*", "constraint": [
+				{"key": "member-1", "severity": "error", "human": "Gender must be male or female",
+				 "expression": "gender.empty() or gender.memberOf('http://example.org/fhir/ValueSet/binary-gender')"},
+				{"key": "member-2", "severity": "error", "human": "Gender must be in the missing ValueSet",
+				 "expression": "gender.empty() or gender.memberOf('http://example.org/fhir/ValueSet/missing')"}
+			]}
+		]}
+	}`),
+}
+
+func TestConstraintMemberOf(t *testing.T) {
+	v, err := New(WithConformanceResources(memberOfDefinitions))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	profile := ValidateWithProfile("http://example.org/fhir/StructureDefinition/member-patient")
+
+	for gender, wantFailed := range map[string]bool{"male": false, "other": true} {
+		result, err := v.Validate(context.Background(), []byte(`{"resourceType": "Patient", "gender": "`+gender+`"}`), profile)
+		if err != nil {
+			t.Fatalf("Validate() error: %v", err)
+		}
+		var failed, unresolved bool
+		for _, iss := range result.Issues {
+			switch {
+			case strings.Contains(iss.Diagnostics, "member-1:"):
+				failed = true
+			case iss.MessageID == string(issue.DiagConstraintValueSetUnresolved):
+				unresolved = true
+			case strings.Contains(iss.Diagnostics, "member-2:"):
+				t.Errorf("%s: constraint on a missing ValueSet reported as failed", gender)
+			}
+		}
+		if failed != wantFailed {
+			t.Errorf("%s: member-1 failed = %v, want %v (issues: %+v)", gender, failed, wantFailed, result.Issues)
+		}
+		if !unresolved {
+			t.Errorf("%s: missing ValueSet not reported", gender)
+		}
+	}
+
+	if stats := v.Stats().Caches[CacheMemberships]; stats.Entries == 0 {
+		t.Error("memberOf() answers not cached")
+	}
+}
+
+// resolveDefinitions define a Patient profile whose constraints follow
+// managingOrganization with resolve().
+var resolveDefinitions = [][]byte{
+	[]byte(`{
+		"resourceType": "StructureDefinition", "url": "http://example.org/fhir/StructureDefinition/managed-patient",
+		"name": "ManagedPatient", "status": "active", "kind": "resource", "abstract": false, "type": "Patient",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Patient", "derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Patient", "path": "Patient", "min": 0, "max": "*", "constraint": [
+				{"key": "managed-1", "severity": "error", "human": "The managing organization must exist",
+				 "expression": "managingOrganization.exists() implies managingOrganization.resolve().exists()"},
+				{"key": "managed-2", "severity": "error", "human": "The managing organization must be an Organization",
+				 "expression": "managingOrganization.resolve().all($this is Organization)"}
+			]}
+		]}
+	}`),
+}
+
+func TestConstraintResolve(t *testing.T) {
+	v, err := New(
+		WithConformanceResources(resolveDefinitions),
+		WithReferenceSource(map[string]json.RawMessage{
+			"Organization/known": json.RawMessage(`{"resourceType": "Organization", "id": "known"}`),
+		}),
+	)
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	profile := ValidateWithProfile("http://example.org/fhir/StructureDefinition/managed-patient")
+
+	tests := []struct {
+		name       string
+		resource   string
+		failed     string // Key of the failed constraint, if any
+		unresolved bool
+	}{
+		{"contained", `{"resourceType": "Patient", "contained": [{"resourceType": "Organization", "id": "o1"}],
+			"managingOrganization": {"reference": "#o1"}}`, "", false},
+		{"contained of another type", `{"resourceType": "Patient", "contained": [{"resourceType": "Practitioner", "id": "p1"}],
+			"managingOrganization": {"reference": "#p1"}}`, "managed-2", false},
+		{"source", `{"resourceType": "Patient", "managingOrganization": {"reference": "Organization/known"}}`, "", false},
+		{"unknown", `{"resourceType": "Patient", "managingOrganization": {"reference": "Organization/unknown"}}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.Validate(context.Background(), []byte(tt.resource), profile)
+			if err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+			var failed string
+			var unresolved bool
+			for _, iss := range result.Issues {
+				switch {
+				case iss.MessageID == string(issue.DiagConstraintReferenceUnresolved):
+					unresolved = true
+				case strings.Contains(iss.Diagnostics, "managed-"):
+					failed = iss.Diagnostics[strings.Index(iss.Diagnostics, "managed-"):][:len("managed-1")]
+				}
+			}
+			if failed != tt.failed || unresolved != tt.unresolved {
+				t.Errorf("failed = %q, unresolved = %v; want %q, %v (issues: %+v)", failed, unresolved, tt.failed, tt.unresolved, result.Issues)
+			}
+		})
+	}
+}
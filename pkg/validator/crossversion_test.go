@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestSourceVersion(t *testing.T) {
+	if _, err := New(WithSourceVersion("3.0.2")); err == nil {
+		t.Error("New() with an STU3 source version succeeded, want an error")
+	}
+
+	v, err := New(WithVersion("R5"), WithSourceVersion("R4"))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	encounter := []byte(`{
+  "resourceType": "Encounter",
+  "status": "finished",
+  "class": {"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "AMB"},
+  "classHistory": [{"class": {"code": "EMER"}, "period": {"start": "2024-01-01"}}],
+  "subject": {"reference": "Patient/1"},
+  "period": {"start": "2024-13-01"},
+  "reasonCode": [{"text": "Checkup"}]
+}`)
+	ctx := context.Background()
+
+	result, err := v.Validate(ctx, encounter)
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	var converted, notMapped, period *issue.Issue
+	for i := range result.Issues {
+		iss := &result.Issues[i]
+		switch {
+		case iss.MessageID == string(issue.DiagVersionConverted):
+			converted = iss
+		case iss.MessageID == string(issue.DiagVersionElementNotMapped):
+			notMapped = iss
+		case len(iss.Expression) > 0 && iss.Expression[0] == "Encounter.actualPeriod.start":
+			period = iss
+		default:
+			if iss.Severity == issue.SeverityError {
+				t.Errorf("unexpected error: %s at %v", iss.Diagnostics, iss.Expression)
+			}
+		}
+	}
+	if converted == nil {
+		t.Error("conversion not reported")
+	}
+	if notMapped == nil || notMapped.Expression[0] != "Encounter.classHistory" || notMapped.Location == nil || notMapped.Location.Line != 5 {
+		t.Errorf("classHistory issue = %+v, want one at line 5", notMapped)
+	}
+	// Converted elements are validated at their R5 path
+	if period == nil {
+		t.Errorf("invalid actualPeriod.start not reported: %v", result.Issues)
+	}
+
+	// The validator's own version turns the conversion off
+	result, err = v.Validate(ctx, encounter, ValidateWithSourceVersion("R5"))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if hasDiagnostic(result, "Converted from FHIR") || !result.HasErrors() {
+		t.Errorf("R4 Encounter validated as R5 without conversion: %v", result.Issues)
+	}
+	if _, err := v.Validate(ctx, encounter, ValidateWithSourceVersion("3.0.2")); err == nil {
+		t.Error("Validate() with an STU3 source version succeeded, want an error")
+	}
+}
@@ -0,0 +1,132 @@
+package validator
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+var (
+	customTypeValidator     *Validator
+	customTypeValidatorOnce sync.Once
+	errCustomTypeValidator  error
+)
+
+// customTypeDefinitions are a logical model whose type is a canonical URL and
+// a custom resource derived from DomainResource.
+var customTypeDefinitions = [][]byte{
+	[]byte(`{
+		"resourceType": "StructureDefinition",
+		"url": "http://example.org/fhir/StructureDefinition/LabSummary",
+		"name": "LabSummary", "status": "active", "kind": "logical", "abstract": false,
+		"type": "http://example.org/fhir/StructureDefinition/LabSummary",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Base",
+		"derivation": "specialization",
+		"snapshot": {"element": [
+			{"id": "LabSummary", "path": "LabSummary", "min": 0, "max": "*"},
+			{"id": "LabSummary.status", "path": "LabSummary.status", "min": 1, "max": "1", "type": [{"code": "code"}]},
+			{"id": "LabSummary.result", "path": "LabSummary.result", "min": 0, "max": "*", "type": [{"code": "decimal"}]}
+		]}
+	}`),
+	[]byte(`{
+		"resourceType": "StructureDefinition",
+		"url": "http://example.org/fhir/StructureDefinition/DeviceAlert",
+		"name": "DeviceAlert", "status": "active", "kind": "resource", "abstract": false,
+		"type": "DeviceAlert",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/DomainResource",
+		"derivation": "specialization",
+		"snapshot": {"element": [
+			{"id": "DeviceAlert", "path": "DeviceAlert", "min": 0, "max": "*"},
+			{"id": "DeviceAlert.id", "path": "DeviceAlert.id", "min": 0, "max": "1", "type": [{"code": "id"}]},
+			{"id": "DeviceAlert.priority", "path": "DeviceAlert.priority", "min": 1, "max": "1", "type": [{"code": "integer"}]}
+		]}
+	}`),
+}
+
+func getCustomTypeValidator(t *testing.T) *Validator {
+	t.Helper()
+	customTypeValidatorOnce.Do(func() {
+		customTypeValidator, errCustomTypeValidator = New(
+			WithConformanceResources(customTypeDefinitions),
+			WithCustomTypes(),
+		)
+	})
+	if errCustomTypeValidator != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", errCustomTypeValidator)
+	}
+	return customTypeValidator
+}
+
+func TestValidateCustomTypes(t *testing.T) {
+	v := getCustomTypeValidator(t)
+
+	tests := []struct {
+		name       string
+		resource   string
+		wantErrors int
+		wantURL    string
+	}{
+		{
+			name:     "valid logical model instance",
+			resource: `{"resourceType": "LabSummary", "status": "final", "result": [1.5, 2.25]}`,
+			wantURL:  "http://example.org/fhir/StructureDefinition/LabSummary",
+		},
+		{
+			name:       "logical model instance missing required element",
+			resource:   `{"resourceType": "LabSummary", "result": [1.5]}`,
+			wantErrors: 1,
+		},
+		{
+			name:       "logical model instance with unknown element",
+			resource:   `{"resourceType": "LabSummary", "status": "final", "comment": "x"}`,
+			wantErrors: 1,
+		},
+		{
+			name:     "valid custom resource",
+			resource: `{"resourceType": "DeviceAlert", "id": "a1", "priority": 2}`,
+			wantURL:  "http://example.org/fhir/StructureDefinition/DeviceAlert",
+		},
+		{
+			name:       "custom resource with wrong primitive type",
+			resource:   `{"resourceType": "DeviceAlert", "priority": "high"}`,
+			wantErrors: 1,
+		},
+		{
+			name:       "unknown resourceType is still rejected",
+			resource:   `{"resourceType": "LabDetail", "status": "final"}`,
+			wantErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.ValidateJSON(context.Background(), tt.resource)
+			if err != nil {
+				t.Fatalf("ValidateJSON() error: %v", err)
+			}
+			if result.ErrorCount() != tt.wantErrors {
+				t.Errorf("got %d errors, want %d", result.ErrorCount(), tt.wantErrors)
+				for _, iss := range result.Issues {
+					t.Logf("  [%s] %s @ %v", iss.Severity, iss.Diagnostics, iss.Expression)
+				}
+			}
+			if tt.wantURL != "" && result.Stats.ProfileURL != tt.wantURL {
+				t.Errorf("ProfileURL = %q, want %q", result.Stats.ProfileURL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestValidateCustomTypesDisabled(t *testing.T) {
+	v := getSharedValidator(t)
+
+	result, err := v.ValidateJSON(context.Background(), `{"resourceType": "LabSummary", "status": "final"}`)
+	if err != nil {
+		t.Fatalf("ValidateJSON() error: %v", err)
+	}
+	if result.ErrorCount() != 1 || result.Issues[0].Severity != issue.SeverityError {
+		t.Errorf("expected the unknown resourceType to be rejected, got %v", result.Issues)
+	}
+}
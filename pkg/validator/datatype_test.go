@@ -0,0 +1,43 @@
+package validator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestAttachmentValidation(t *testing.T) {
+	v, err := New(WithAllowedContentTypes("application/pdf"))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	resource := `{"resourceType": "DocumentReference", "status": "current",
+		"content": [
+			{"attachment": {"contentType": "text/plain", "data": "SGVsbG8=", "size": 5, "hash": "9/+ei3uy4Jtwk1pdeF4MxdnQq/A="}},
+			{"attachment": {"contentType": "application/pdf", "data": "SGVsbG8=", "size": 12}},
+			{"attachment": {"contentType": "application/pdf", "data": "SGVsbG8h", "hash": "9/+ei3uy4Jtwk1pdeF4MxdnQq/A="}}
+		]}`
+
+	result, err := v.ValidateJSON(context.Background(), resource)
+	if err != nil {
+		t.Fatalf("ValidateJSON() error: %v", err)
+	}
+
+	var got []string
+	for _, iss := range result.Issues {
+		if strings.HasPrefix(iss.MessageID, "ATTACHMENT_") {
+			got = append(got, iss.MessageID+"@"+iss.Expression[0])
+		}
+	}
+	want := []string{
+		string(issue.DiagAttachmentContentTypeNotAllowed) + "@DocumentReference.content[0].attachment.contentType",
+		string(issue.DiagAttachmentSizeMismatch) + "@DocumentReference.content[1].attachment.size",
+		string(issue.DiagAttachmentHashMismatch) + "@DocumentReference.content[2].attachment.hash",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("attachment issues = %v, want %v", got, want)
+	}
+}
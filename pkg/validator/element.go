@@ -0,0 +1,370 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/location"
+	"github.com/gofhir/validator/pkg/phase"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/strictjson"
+)
+
+// ErrElementNotFound is returned by ValidateElement when the resource has no
+// element at the requested path.
+var ErrElementNotFound = errors.New("element not found")
+
+// elementTarget is the element selected by a ValidateElement path, with the
+// StructureDefinition and SD path of its parent.
+type elementTarget struct {
+	sd         *registry.StructureDefinition
+	parentPath string // SD path of the parent (e.g., "Patient.contact" or "HumanName")
+	name       string // JSON name (e.g., "valueQuantity")
+	fhirPath   string
+	value      any
+	elemDef    *registry.ElementDefinition
+}
+
+// pathSegment is one step of an element path: a name and an optional index.
+type pathSegment struct {
+	name  string
+	index int // -1 when the segment has no index
+}
+
+var pathSegmentPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(?:\[(\d+)\])?$`)
+
+// ValidateElement validates only the element at fhirPath (e.g.,
+// "Patient.name[0]" or "Observation.component[1].valueQuantity") and its
+// descendants, against the ElementDefinition and type the path resolves to in
+// the profiles Validate would use. It runs the structural, cardinality,
+// primitive, binding and constraint phases scoped to that subtree, which lets
+// editors validate a field without re-validating a large resource. The
+// element's own cardinality, which depends on its siblings, is not checked.
+//
+// Paths use JSON element names, so choice elements are written with their
+// type suffix. A path naming the resource itself validates the whole
+// resource. Phase selection options apply; ErrElementNotFound is returned
+// when the resource has no element at fhirPath.
+func (v *Validator) ValidateElement(ctx context.Context, resource []byte, fhirPath string, opts ...ValidateOption) (*issue.Result, error) {
+	startTime := time.Now()
+
+	var vc validateConfig
+	for _, opt := range opts {
+		opt(&vc)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	segments, err := splitElementPath(fhirPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 1 && segments[0].index < 0 {
+		return v.Validate(ctx, resource, opts...)
+	}
+
+	phases, err := v.callPhases(&vc)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decodeResource(resource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	resourceType, _ := data["resourceType"].(string)
+	if resourceType == "" {
+		return nil, errors.New("missing 'resourceType' property")
+	}
+	if segments[0].name != resourceType || segments[0].index >= 0 {
+		return nil, fmt.Errorf("path %q does not start with the resource type %s", fhirPath, resourceType)
+	}
+
+	result := issue.NewResult()
+	result.Stats = &issue.Stats{
+		ResourceSize: len(resource),
+		ResourceType: resourceType,
+	}
+	result.Stats.ResourceID, _ = data["id"].(string)
+
+	profiles, err := v.elementProfiles(data, resourceType, &vc, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON the parser accepted but strict syntax rejects is not validated
+	if strictjson.Check(resource, result) {
+		for _, sd := range profiles {
+			target, err := v.resolveElement(data, sd, segments)
+			if err != nil {
+				return nil, err
+			}
+			if !v.validateElementAgainstProfile(ctx, phases, target, result) {
+				break
+			}
+		}
+	}
+
+	result.Stats.Duration = time.Since(startTime).Nanoseconds()
+
+	result.EnrichLocations(func(expr string) *issue.Location {
+		if loc := location.Find(resource, expr); loc != nil {
+			return &issue.Location{Line: loc.Line, Column: loc.Column}
+		}
+		return nil
+	})
+
+	v.applyIssueRules(result)
+	if !v.config.RawIssues {
+		result.Normalize()
+	}
+	if v.config.Locale != "" {
+		result.Localize(v.config.Locale)
+	}
+	return result, nil
+}
+
+// elementProfiles returns the profiles Validate would use for data, adding
+// warnings for profiles that are not found and recording the first in the
+// result stats.
+func (v *Validator) elementProfiles(data map[string]any, resourceType string, vc *validateConfig, result *issue.Result) ([]*registry.StructureDefinition, error) {
+	coreURL := registry.GetSDForResource(resourceType)
+	coreSD := v.registry.GetByURL(coreURL)
+	if coreSD == nil && v.config.CustomTypes {
+		if sd := v.getCustomType(resourceType); sd != nil {
+			coreSD, coreURL = sd, sd.URL
+		}
+	}
+	if coreSD == nil {
+		return nil, fmt.Errorf("unknown resourceType '%s'", resourceType)
+	}
+
+	var profiles []*registry.StructureDefinition
+	var profileURLs, notFound []string
+	declared := metaProfiles(data)
+	for _, req := range v.collectProfilesToValidate(resourceType, vc, declared) {
+		if sd := v.registry.GetByURL(req.url); sd != nil {
+			profiles = append(profiles, sd)
+			profileURLs = append(profileURLs, req.url)
+			continue
+		}
+		notFound = append(notFound, req.url)
+	}
+	reportUnresolvedProfiles(resourceType, notFound, declared, profileURLs, v.config.StrictMode, result)
+	if len(profiles) == 0 {
+		profiles = []*registry.StructureDefinition{coreSD}
+		profileURLs = []string{coreURL}
+	}
+	result.Stats.IsCustomProfile = profileURLs[0] != coreURL
+	result.Stats.ProfileURL = profileURLs[0]
+	result.Stats.AppliedProfiles = profileURLs
+	return profiles, nil
+}
+
+// validateElementAgainstProfile runs the subtree-capable phases against a
+// single resolved element. It returns false if ctx ended.
+func (v *Validator) validateElementAgainstProfile(ctx context.Context, phases phase.Set, t *elementTarget, result *issue.Result) bool {
+	ok := v.runPhase(ctx, phases, phase.Structure, result, func(_ context.Context, r *issue.Result) {
+		structResult := v.structValidator.ValidateElement(t.value, t.sd, t.parentPath, t.name, t.fhirPath)
+		r.Merge(structResult)
+		issue.ReleaseResult(structResult)
+	})
+
+	ok = ok && v.runPhase(ctx, phases, phase.Cardinality, result, func(_ context.Context, r *issue.Result) {
+		cardResult := v.cardValidator.ValidateElement(t.value, t.sd, t.parentPath, t.name, t.fhirPath)
+		r.Merge(cardResult)
+		issue.ReleaseResult(cardResult)
+	})
+
+	ok = ok && v.runPhase(ctx, phases, phase.Primitives, result, func(_ context.Context, r *issue.Result) {
+		primResult := v.primValidator.ValidateElement(t.value, t.sd, t.parentPath, t.name, t.fhirPath)
+		r.Merge(primResult)
+		issue.ReleaseResult(primResult)
+	})
+
+	ok = ok && v.runPhase(ctx, phases, phase.Binding, result, func(ctx context.Context, r *issue.Result) {
+		v.bindValidator.ValidateElement(ctx, t.value, t.sd, t.parentPath, t.name, t.fhirPath, r)
+	})
+
+	// Constraints declared on the element, evaluated with each occurrence as context
+	ok = ok && v.runPhase(ctx, phases, phase.Constraints, result, func(ctx context.Context, r *issue.Result) {
+		items := map[string]any{t.fhirPath: t.value}
+		if list, isList := t.value.([]any); isList {
+			items = make(map[string]any, len(list))
+			for i, item := range list {
+				items[fmt.Sprintf("%s[%d]", t.fhirPath, i)] = item
+			}
+		}
+		for path, item := range items {
+			if _, isMap := item.(map[string]any); !isMap {
+				continue // FHIRPath needs an object as context
+			}
+			raw, err := json.Marshal(item)
+			if err != nil {
+				continue
+			}
+			v.constraintValidator.ValidateElement(ctx, raw, t.elemDef.Constraint, path, r)
+		}
+	})
+
+	return ok
+}
+
+// resolveElement walks the path segments through the resource data and the
+// StructureDefinitions, switching to a type's StructureDefinition whenever
+// the current one does not define an element's children.
+func (v *Validator) resolveElement(data map[string]any, sd *registry.StructureDefinition, segments []pathSegment) (*elementTarget, error) {
+	node := data
+	sdPath := sd.Type
+	fhirPath := segments[0].name
+
+	for i, seg := range segments[1:] {
+		value, ok := node[seg.name]
+		elementFHIRPath := fhirPath + "." + seg.name
+		if seg.index >= 0 {
+			list, isList := value.([]any)
+			if !isList || seg.index >= len(list) {
+				ok = false
+			} else {
+				value = list[seg.index]
+			}
+			elementFHIRPath += "[" + strconv.Itoa(seg.index) + "]"
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrElementNotFound, elementFHIRPath)
+		}
+
+		elemDef, typeName := findPathElement(sd, sdPath, seg.name)
+		if elemDef == nil {
+			return nil, fmt.Errorf("%s is not defined by %s", elementFHIRPath, sd.URL)
+		}
+
+		if i == len(segments)-2 {
+			return &elementTarget{
+				sd:         sd,
+				parentPath: sdPath,
+				name:       seg.name,
+				fhirPath:   elementFHIRPath,
+				value:      value,
+				elemDef:    elemDef,
+			}, nil
+		}
+
+		child, isMap := value.(map[string]any)
+		if !isMap {
+			if _, isList := value.([]any); isList {
+				return nil, fmt.Errorf("%s is a list: select an item with an index", elementFHIRPath)
+			}
+			return nil, fmt.Errorf("%w: %s has no children", ErrElementNotFound, elementFHIRPath)
+		}
+
+		elementSDPath := sdPath + "." + seg.name
+		if strings.HasSuffix(elemDef.Path, "[x]") {
+			elementSDPath = elemDef.Path
+		}
+		switch {
+		case elemDef.ContentReference != nil && *elemDef.ContentReference != "":
+			sdPath = strings.TrimPrefix(*elemDef.ContentReference, "#")
+		case hasChildElements(sd, elementSDPath):
+			sdPath = elementSDPath
+		default:
+			// Resource-typed elements (contained, Bundle.entry.resource) use
+			// the resource's own type
+			if resourceType, _ := child["resourceType"].(string); resourceType != "" {
+				typeName = resourceType
+			}
+			typeSD := v.registry.GetByType(typeName)
+			if typeSD == nil || typeSD.Snapshot == nil {
+				return nil, fmt.Errorf("%s: no StructureDefinition for type %q", elementFHIRPath, typeName)
+			}
+			sd, sdPath = typeSD, typeName
+		}
+		node, fhirPath = child, elementFHIRPath
+	}
+	return nil, fmt.Errorf("%w: %s", ErrElementNotFound, fhirPath)
+}
+
+// findPathElement returns the ElementDefinition of a child element and its
+// type, resolving choice elements (e.g., "valueQuantity" to "value[x]").
+func findPathElement(sd *registry.StructureDefinition, parentPath, name string) (*registry.ElementDefinition, string) {
+	if sd.Snapshot == nil {
+		return nil, ""
+	}
+	path := parentPath + "." + name
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		if elem.Path == path {
+			typeName := ""
+			if len(elem.Type) == 1 {
+				typeName = elem.Type[0].Code
+			}
+			return elem, typeName
+		}
+	}
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		base, isChoice := strings.CutSuffix(elem.Path, "[x]")
+		if !isChoice || !strings.HasPrefix(path, base) || len(path) == len(base) {
+			continue
+		}
+		suffix := path[len(base):]
+		for _, t := range elem.Type {
+			if strings.EqualFold(t.Code, suffix) {
+				return elem, t.Code
+			}
+		}
+	}
+	return nil, ""
+}
+
+// hasChildElements reports whether sd defines children of path.
+func hasChildElements(sd *registry.StructureDefinition, path string) bool {
+	prefix := path + "."
+	for i := range sd.Snapshot.Element {
+		if strings.HasPrefix(sd.Snapshot.Element[i].Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitElementPath splits "Patient.name[0].given" into its segments.
+func splitElementPath(path string) ([]pathSegment, error) {
+	parts := strings.Split(path, ".")
+	segments := make([]pathSegment, 0, len(parts))
+	for _, part := range parts {
+		m := pathSegmentPattern.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("invalid element path %q", path)
+		}
+		seg := pathSegment{name: m[1], index: -1}
+		if m[2] != "" {
+			seg.index, _ = strconv.Atoi(m[2])
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+// metaProfiles returns the profile URLs declared in meta.profile.
+func metaProfiles(data map[string]any) []string {
+	var profiles []string
+	if meta, ok := data["meta"].(map[string]any); ok {
+		if list, ok := meta["profile"].([]any); ok {
+			for _, p := range list {
+				if ps, ok := p.(string); ok {
+					profiles = append(profiles, ps)
+				}
+			}
+		}
+	}
+	return profiles
+}
@@ -0,0 +1,97 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const elementTestPatient = `{
+  "resourceType": "Patient",
+  "gender": "bogus",
+  "name": [
+    {"family": "Chalmers", "given": ["Peter"]},
+    {"family": ["Windsor"], "unknown": true}
+  ],
+  "contact": [
+    {"gender": "unknown", "telecom": [{"system": "telephone", "value": "555"}]}
+  ],
+  "deceasedBoolean": "no"
+}`
+
+func TestValidateElement(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		path      string
+		wantError bool
+		wantPaths []string // expressions that must be reported
+	}{
+		{"Patient.name[0]", false, nil},
+		{"Patient.name[1]", true, []string{"Patient.name[1].unknown"}},
+		{"Patient.name", true, []string{"Patient.name[1].unknown"}},
+		{"Patient.contact[0]", false, nil},
+		{"Patient.contact[0].telecom[0]", true, []string{"Patient.contact[0].telecom[0].system"}},
+		{"Patient.deceasedBoolean", true, []string{"Patient.deceasedBoolean"}},
+		{"Patient.gender", true, []string{"Patient.gender"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := v.ValidateElement(ctx, []byte(elementTestPatient), tt.path)
+			if err != nil {
+				t.Fatalf("ValidateElement() error: %v", err)
+			}
+			if result.HasErrors() != tt.wantError {
+				t.Errorf("HasErrors() = %v, want %v: %v", result.HasErrors(), tt.wantError, result.Issues)
+			}
+			for _, iss := range result.Issues {
+				for _, expr := range iss.Expression {
+					if !strings.HasPrefix(expr, tt.path) {
+						t.Errorf("issue outside %s: %s (%s)", tt.path, expr, iss.Diagnostics)
+					}
+				}
+			}
+			for _, want := range tt.wantPaths {
+				found := false
+				for _, iss := range result.Issues {
+					found = found || (len(iss.Expression) > 0 && iss.Expression[0] == want)
+				}
+				if !found {
+					t.Errorf("no issue at %s: %v", want, result.Issues)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateElementErrors(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+
+	for path, want := range map[string]string{
+		"Patient.name[5]":            "element not found",
+		"Patient.photo":              "element not found",
+		"Patient.name[0].given[0].x": "has no children",
+		"Patient.contact.telecom":    "select an item",
+		"Observation.status":         "does not start with",
+		"Patient..name":              "invalid element path",
+	} {
+		if _, err := v.ValidateElement(ctx, []byte(elementTestPatient), path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateElement(%q) error = %v, want containing %q", path, err, want)
+		}
+	}
+
+	_, err := v.ValidateElement(ctx, []byte(elementTestPatient), "Patient.name[9]")
+	if !errors.Is(err, ErrElementNotFound) {
+		t.Errorf("error = %v, want ErrElementNotFound", err)
+	}
+
+	// The resource itself is validated in full
+	result, err := v.ValidateElement(ctx, []byte(elementTestPatient), "Patient")
+	if err != nil || !result.HasErrors() {
+		t.Errorf("ValidateElement(Patient) = %v, %v; want full validation errors", result, err)
+	}
+}
@@ -0,0 +1,331 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/phase"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/slicing"
+)
+
+// Explanation describes how the validator treats one element of a resource,
+// for finding out why an issue fired: the effective definition of the
+// element in each profile, the slices its path goes through, its bindings,
+// the checks that apply to it, and the issues Validate reports for it.
+type Explanation struct {
+	// Path is the explained element path (e.g., "Patient.identifier[0].type").
+	Path string
+
+	// Value is the element in the resource; nil when the resource has none.
+	Value any
+
+	// Profiles explains the element in each profile Validate would use.
+	Profiles []ProfileExplanation
+
+	// Issues are those Validate reports at Path or below it.
+	Issues []issue.Issue
+}
+
+// ProfileExplanation is the effective definition of an element in one
+// profile.
+type ProfileExplanation struct {
+	// Profile is the canonical URL of the profile.
+	Profile string
+
+	// Element is the ElementDefinition that applies, from the slices the
+	// resource's items match; Source is the StructureDefinition it was taken
+	// from, which is the element's type for paths the profile does not
+	// constrain.
+	Element *registry.ElementDefinition
+	Source  string
+
+	// Type is the element's type when it is known.
+	Type string
+
+	// Slices are the sliced elements along the path and the slice each item
+	// matched.
+	Slices []SliceMatch
+
+	// Bindings are those of the element and of its children.
+	Bindings []BindingInfo
+
+	// Checks are the rules of the definition and the phases that check them.
+	Checks []Check
+}
+
+// SliceMatch is the slice an item of a sliced element matched.
+type SliceMatch struct {
+	Path           string                   // Path of the item (e.g., "Patient.identifier[0]")
+	ElementID      string                   // ID of the sliced element
+	Discriminators []registry.Discriminator // How items are matched to slices
+	Rules          string                   // open | closed | openAtEnd
+	Slices         []string                 // Names of the slices
+	Slice          string                   // Slice the item matched; "" when none
+}
+
+// BindingInfo is a binding of an element.
+type BindingInfo struct {
+	ElementID string
+	Strength  string
+	ValueSet  string
+	Loaded    bool // Whether the ValueSet is known; codes are accepted unchecked otherwise
+}
+
+// Check is a rule of an element definition and the phase that checks it.
+type Check struct {
+	Phase       phase.Name
+	Description string
+	Enabled     bool // Whether the phase runs with the validator and call options
+}
+
+// Explain explains the element at fhirPath in resource (e.g.,
+// "Patient.identifier[0].type"), against the profiles Validate would use.
+// Paths are written as for ValidateElement; without an index, the items of
+// a repeating element are not matched to slices. The element does not have
+// to be present in the resource. Validation options apply; an error is
+// returned when a profile does not define the path.
+func (v *Validator) Explain(ctx context.Context, resource []byte, fhirPath string, opts ...ValidateOption) (*Explanation, error) {
+	var vc validateConfig
+	for _, opt := range opts {
+		opt(&vc)
+	}
+	segments, err := splitElementPath(fhirPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeResource(resource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	resourceType, _ := data["resourceType"].(string)
+	if resourceType == "" {
+		return nil, errors.New("missing 'resourceType' property")
+	}
+	if segments[0].name != resourceType || segments[0].index >= 0 {
+		return nil, fmt.Errorf("path %q does not start with the resource type %s", fhirPath, resourceType)
+	}
+
+	phases, err := v.callPhases(&vc)
+	if err != nil {
+		return nil, err
+	}
+	if policy := v.policies[resourceType]; policy != nil {
+		phases = policy.callPhases(&vc, phases)
+	}
+
+	scratch := issue.NewResult()
+	scratch.Stats = &issue.Stats{}
+	profiles, err := v.elementProfiles(data, resourceType, &vc, scratch)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &Explanation{Path: fhirPath, Value: valueAt(data, segments)}
+	for _, sd := range profiles {
+		pe, err := v.explainProfile(sd, data, segments, phases)
+		if err != nil {
+			return nil, err
+		}
+		exp.Profiles = append(exp.Profiles, *pe)
+	}
+
+	result, err := v.Validate(ctx, resource, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, iss := range result.Issues {
+		if slices.ContainsFunc(iss.Expression, func(expr string) bool { return underPath(expr, fhirPath) }) {
+			exp.Issues = append(exp.Issues, iss)
+		}
+	}
+	return exp, nil
+}
+
+// explainProfile resolves the element of segments in sd, choosing at each
+// item of a sliced element the slice the item matches.
+func (v *Validator) explainProfile(sd *registry.StructureDefinition, data map[string]any, segments []pathSegment, phases phase.Set) (*ProfileExplanation, error) {
+	pe := &ProfileExplanation{Profile: sd.URL}
+
+	contexts := v.slicingValidator.Contexts(sd)
+	var node any = data
+	resolved := segments[0].name
+	itemPath := segments[0].name
+	for _, seg := range segments[1:] {
+		resolved += "." + seg.name
+		itemPath += "." + seg.name
+		node = member(node, seg.name)
+		if seg.index < 0 {
+			continue
+		}
+		itemPath += "[" + strconv.Itoa(seg.index) + "]"
+		node = item(node, seg.index)
+
+		elem, err := v.walker.ResolvePath(sd.URL, resolved)
+		if err != nil || elem.Source != sd.URL {
+			continue // Slicing is only defined by the profile itself
+		}
+		sctx := findContext(contexts, elem.Element.Path)
+		if sctx == nil {
+			continue
+		}
+		match := SliceMatch{
+			Path:           itemPath,
+			ElementID:      sctx.EntryDef.ID,
+			Discriminators: sctx.Discriminators,
+			Rules:          sctx.Rules,
+		}
+		for _, s := range sctx.Slices {
+			match.Slices = append(match.Slices, s.Name)
+		}
+		if m, ok := node.(map[string]any); ok {
+			match.Slice = v.slicingValidator.MatchSlice(m, *sctx)
+		}
+		pe.Slices = append(pe.Slices, match)
+		if match.Slice != "" {
+			resolved += ":" + match.Slice
+			for _, s := range sctx.Slices {
+				if s.Name == match.Slice {
+					contexts = append(contexts, s.Contexts...)
+				}
+			}
+		}
+	}
+
+	elem, err := v.walker.ResolvePath(sd.URL, resolved)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sd.URL, err)
+	}
+	pe.Element, pe.Source, pe.Type = elem.Element, elem.Source, elem.Type
+
+	for _, def := range append([]*registry.ElementDefinition{elem.Element}, elem.Children...) {
+		if def.Binding == nil || def.Binding.ValueSet == "" {
+			continue
+		}
+		vsURL, _, _ := strings.Cut(def.Binding.ValueSet, "|")
+		pe.Bindings = append(pe.Bindings, BindingInfo{
+			ElementID: def.ID,
+			Strength:  def.Binding.Strength,
+			ValueSet:  def.Binding.ValueSet,
+			Loaded:    v.termRegistry.GetValueSet(vsURL) != nil,
+		})
+	}
+	pe.Checks = elementChecks(elem.Element, elem.Type, phases)
+	return pe, nil
+}
+
+// elementChecks lists the rules of def and the phases that check them.
+func elementChecks(def *registry.ElementDefinition, typeName string, phases phase.Set) []Check {
+	var checks []Check
+	add := func(name phase.Name, format string, args ...any) {
+		checks = append(checks, Check{Phase: name, Description: fmt.Sprintf(format, args...), Enabled: phases.Enabled(name)})
+	}
+
+	add(phase.Cardinality, "cardinality %d..%s", def.Min, def.Max)
+	var types []string
+	for _, t := range def.Type {
+		types = append(types, t.Code)
+	}
+	if len(types) > 0 {
+		add(phase.Structure, "type %s", strings.Join(types, " | "))
+	}
+	if typeName != "" && isPrimitiveType(typeName) {
+		add(phase.Primitives, "%s value format", typeName)
+	}
+	if def.MaxLength > 0 {
+		add(phase.Primitives, "maxLength %d", def.MaxLength)
+	}
+	for _, t := range def.Type {
+		if len(t.TargetProfile) > 0 {
+			add(phase.Reference, "reference target %s", strings.Join(t.TargetProfile, " | "))
+		}
+		if t.Code == "Extension" && len(t.Profile) > 0 {
+			add(phase.Extensions, "extension %s", strings.Join(t.Profile, " | "))
+		}
+	}
+	if def.Binding != nil && def.Binding.ValueSet != "" {
+		add(phase.Binding, "%s binding to %s", def.Binding.Strength, def.Binding.ValueSet)
+	}
+	if value, suffix, ok := def.GetFixed(); ok {
+		add(phase.FixedPattern, "fixed%s %s", suffix, value)
+	}
+	if value, suffix, ok := def.GetPattern(); ok {
+		add(phase.FixedPattern, "pattern%s %s", suffix, value)
+	}
+	if value, _, ok := def.GetMinValue(); ok {
+		add(phase.FixedPattern, "minValue %s", value)
+	}
+	if value, _, ok := def.GetMaxValue(); ok {
+		add(phase.FixedPattern, "maxValue %s", value)
+	}
+	if def.Slicing != nil {
+		var discriminators []string
+		for _, d := range def.Slicing.Discriminator {
+			discriminators = append(discriminators, d.Type+":"+d.Path)
+		}
+		add(phase.Slicing, "sliced by %s (%s)", strings.Join(discriminators, ", "), def.Slicing.Rules)
+	}
+	if def.SliceName != nil {
+		add(phase.Slicing, "slice %s %d..%s", *def.SliceName, def.Min, def.Max)
+	}
+	for _, c := range def.Constraint {
+		add(phase.Constraints, "%s (%s): %s", c.Key, c.Severity, c.Human)
+	}
+	return checks
+}
+
+// isPrimitiveType reports whether a FHIR type name is a primitive type,
+// whose names start with a lowercase letter.
+func isPrimitiveType(name string) bool {
+	return name != "" && name[0] >= 'a' && name[0] <= 'z'
+}
+
+// findContext returns the slicing of the element at path, if any.
+func findContext(contexts []slicing.Context, path string) *slicing.Context {
+	for i := range contexts {
+		if contexts[i].Path == path {
+			return &contexts[i]
+		}
+	}
+	return nil
+}
+
+// valueAt returns the value at segments in data, or nil.
+func valueAt(data map[string]any, segments []pathSegment) any {
+	var node any = data
+	for _, seg := range segments[1:] {
+		node = member(node, seg.name)
+		if seg.index >= 0 {
+			node = item(node, seg.index)
+		}
+	}
+	return node
+}
+
+// member returns the member name of node, or nil when node is not an
+// object.
+func member(node any, name string) any {
+	if m, ok := node.(map[string]any); ok {
+		return m[name]
+	}
+	return nil
+}
+
+// item returns item i of node, or nil when node is not an array that long.
+func item(node any, i int) any {
+	if list, ok := node.([]any); ok && i < len(list) {
+		return list[i]
+	}
+	return nil
+}
+
+// underPath reports whether expr is path or an element below it.
+func underPath(expr, path string) bool {
+	rest, ok := strings.CutPrefix(expr, path)
+	return ok && (rest == "" || rest[0] == '.' || rest[0] == '[')
+}
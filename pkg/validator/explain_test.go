@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/phase"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+func TestExplain(t *testing.T) {
+	v := getSharedValidator(t)
+	resource := []byte(`{
+		"resourceType": "Observation",
+		"meta": {"profile": ["http://hl7.org/fhir/StructureDefinition/bodyweight"]},
+		"status": "final",
+		"category": [{"coding": [{"system": "http://terminology.hl7.org/CodeSystem/observation-category", "code": "vital-signs"}]}],
+		"code": {"coding": [{"system": "http://loinc.org", "code": "29463-7"}]},
+		"subject": {"reference": "Patient/example"},
+		"effectiveDateTime": "2024-01-15",
+		"valueQuantity": {"value": 70, "unit": "kg", "system": "http://unitsofmeasure.org", "code": "kgg"}
+	}`)
+
+	exp, err := v.Explain(context.Background(), resource, "Observation.category[0].coding", ValidateWithoutPhases(phase.Constraints))
+	if err != nil {
+		t.Fatalf("Explain() error: %v", err)
+	}
+	if len(exp.Profiles) != 1 || exp.Value == nil {
+		t.Fatalf("Explain() = %+v", exp)
+	}
+	pe := exp.Profiles[0]
+	if len(pe.Slices) != 1 || pe.Slices[0].Slice != "VSCat" || pe.Slices[0].Path != "Observation.category[0]" {
+		t.Errorf("Slices = %+v, want category[0] matching VSCat", pe.Slices)
+	}
+	if pe.Element.ID != "Observation.category:VSCat.coding" {
+		t.Errorf("Element.ID = %s, want the VSCat slice's coding", pe.Element.ID)
+	}
+	if !slices.ContainsFunc(pe.Checks, func(c Check) bool { return c.Phase == phase.Cardinality && c.Enabled }) {
+		t.Errorf("Checks = %+v, want cardinality", pe.Checks)
+	}
+
+	// The unit code is not UCUM, and the issue is reported under the path
+	exp, err = v.Explain(context.Background(), resource, "Observation.valueQuantity", ValidateWithoutPhases(phase.Constraints))
+	if err != nil {
+		t.Fatalf("Explain() error: %v", err)
+	}
+	if len(exp.Issues) == 0 {
+		t.Error("Issues should include the unit issue under valueQuantity")
+	}
+	pe = exp.Profiles[0]
+	if pe.Type != "Quantity" || len(pe.Bindings) == 0 {
+		t.Errorf("Type = %s, Bindings = %+v, want Quantity with the code binding", pe.Type, pe.Bindings)
+	}
+	for _, c := range pe.Checks {
+		if c.Phase == phase.Constraints && c.Enabled {
+			t.Errorf("constraint check %q should be disabled", c.Description)
+		}
+	}
+
+	if _, err := v.Explain(context.Background(), resource, "Observation.bogus"); !errors.Is(err, walker.ErrPathNotFound) {
+		t.Errorf("Explain() of an undefined path error = %v, want ErrPathNotFound", err)
+	}
+}
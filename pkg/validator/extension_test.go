@@ -3,7 +3,11 @@ package validator
 import (
 	"context"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
 )
 
 func TestExtensionValidation(t *testing.T) {
@@ -67,3 +71,110 @@ func TestExtensionValidation(t *testing.T) {
 		})
 	}
 }
+
+// extensionSliceDefinitions define a Patient profile allowing one note
+// extension, whose slice in the snapshot has no url element, as IG
+// snapshots often leave extension slices unexpanded.
+var extensionSliceDefinitions = [][]byte{
+	[]byte(`{
+		"resourceType": "StructureDefinition", "url": "http://example.org/fhir/StructureDefinition/noted-patient",
+		"name": "NotedPatient", "status": "active", "kind": "resource", "abstract": false, "type": "Patient",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Patient", "derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Patient", "path": "Patient", "min": 0, "max": "*"},
+			{"id": "Patient.extension", "path": "Patient.extension", "min": 0, "max": "*",
+			 "slicing": {"discriminator": [{"type": "value", "path": "url"}], "rules": "open"}},
+			{"id": "Patient.extension:note", "path": "Patient.extension", "sliceName": "note", "min": 0, "max": "1",
+			 "type": [{"code": "Extension", "profile": ["http://example.org/fhir/StructureDefinition/patient-note|1.0.0"]}]}
+		]}
+	}`),
+}
+
+func TestExtensionSliceCardinality(t *testing.T) {
+	v, err := New(WithConformanceResources(extensionSliceDefinitions))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	note := `{"url": "http://example.org/fhir/StructureDefinition/patient-note", "valueString": "x"}`
+
+	for n, wantMax := range map[int]bool{1: false, 2: true} {
+		notes := strings.Repeat(note+",", n)
+		resource := `{"resourceType": "Patient", "extension": [` + strings.TrimSuffix(notes, ",") + `]}`
+		result, err := v.Validate(context.Background(), []byte(resource),
+			ValidateWithProfile("http://example.org/fhir/StructureDefinition/noted-patient"))
+		if err != nil {
+			t.Fatalf("Validate() error: %v", err)
+		}
+		gotMax := slices.ContainsFunc(result.Issues, func(i issue.Issue) bool {
+			return i.MessageID == string(issue.DiagSlicingCardinalityMax)
+		})
+		if gotMax != wantMax {
+			t.Errorf("%d notes: max cardinality reported = %v, want %v (issues: %+v)", n, gotMax, wantMax, result.Issues)
+		}
+	}
+}
+
+func TestPrimitiveArrayExtensions(t *testing.T) {
+	v := getSharedValidator(t)
+	const (
+		qualifier = `{"url": "http://hl7.org/fhir/StructureDefinition/iso21090-EN-qualifier", "valueCode": "CL"}`
+		birthTime = `{"url": "http://hl7.org/fhir/StructureDefinition/patient-birthTime", "valueDateTime": "2020-01-01"}`
+	)
+
+	tests := []struct {
+		name     string
+		name0    string // Patient.name[0]
+		wantID   issue.DiagnosticID
+		wantPath string
+	}{
+		{
+			name:  "aligned extensions",
+			name0: `"given": [null, "Bo"], "_given": [{"extension": [` + qualifier + `]}, null]`,
+		},
+		{
+			name:     "shorter shadow array",
+			name0:    `"given": ["Ann", "Bo"], "_given": [null]`,
+			wantID:   issue.DiagStructureShadowMisaligned,
+			wantPath: "Patient.name[0].given",
+		},
+		{
+			name:     "null in both arrays",
+			name0:    `"given": ["Ann", null], "_given": [null, null]`,
+			wantID:   issue.DiagStructureShadowNull,
+			wantPath: "Patient.name[0].given[1]",
+		},
+		{
+			name:     "extension context at the index of its value",
+			name0:    `"given": ["Ann", "Bo"], "_given": [null, {"extension": [` + birthTime + `]}]`,
+			wantID:   issue.DiagExtensionInvalidContext,
+			wantPath: "Patient.name[0].given[1].extension[0]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := `{"resourceType": "Patient", "name": [{` + tt.name0 + `}]}`
+			result, err := v.Validate(context.Background(), []byte(resource))
+			if err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+			var errs []issue.Issue
+			for _, iss := range result.Issues {
+				if iss.Severity == issue.SeverityError {
+					errs = append(errs, iss)
+				}
+			}
+			if tt.wantID == "" {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %+v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].MessageID != string(tt.wantID) || errs[0].Expression[0] != tt.wantPath {
+				t.Fatalf("want a single %s at %s, got %+v", tt.wantID, tt.wantPath, errs)
+			}
+			if errs[0].Location == nil {
+				t.Errorf("no location for %s", tt.wantPath)
+			}
+		})
+	}
+}
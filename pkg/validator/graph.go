@@ -0,0 +1,119 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofhir/validator/pkg/graph"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/location"
+)
+
+// ValidateGraph validates a set of resources against a GraphDefinition, such
+// as the resources of an episode of care exchanged together. Resources are
+// the members of the set, or a single Bundle whose entries are the members
+// (unless the graph starts from a Bundle).
+//
+// Starting from each resource of the graph's start type, every link must lead
+// to resources in the set of the allowed types, in the number the link's
+// min/max allow, and resources reached at a node with a profile must validate
+// against it without errors. The resources themselves are not otherwise
+// validated; use Validate for that. Opts apply to the profile checks.
+func (v *Validator) ValidateGraph(ctx context.Context, definition []byte, resources [][]byte, opts ...ValidateOption) (*issue.Result, error) {
+	startTime := time.Now()
+
+	def, err := graph.Parse(definition)
+	if err != nil {
+		return nil, err
+	}
+	members, bundle, err := graphMembers(def, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	result := issue.NewResult()
+	result.Stats = &issue.Stats{ResourceType: def.Start()}
+
+	conforms := func(ctx context.Context, resource []byte, profile string) (bool, error) {
+		if v.registry.GetByURL(profile) == nil {
+			return false, errors.New("profile not found in registry")
+		}
+		profileResult, err := v.Validate(ctx, resource, append(slices.Clip(opts), ValidateWithProfile(profile))...)
+		if err != nil {
+			return false, err
+		}
+		return !profileResult.HasErrors(), nil
+	}
+	if err := graph.New(def, conforms).Validate(ctx, members, result); err != nil {
+		return nil, err
+	}
+
+	result.Stats.Duration = time.Since(startTime).Nanoseconds()
+	if bundle != nil {
+		result.EnrichLocations(func(expr string) *issue.Location {
+			if loc := location.Find(bundle, expr); loc != nil {
+				return &issue.Location{Line: loc.Line, Column: loc.Column}
+			}
+			return nil
+		})
+	}
+
+	v.applyIssueRules(result)
+	if !v.config.RawIssues {
+		result.Normalize()
+	}
+	if v.config.Locale != "" {
+		result.Localize(v.config.Locale)
+	}
+	return result, nil
+}
+
+// graphMembers returns the resources to validate against def: the entries
+// of a single Bundle, which is also returned, or each resource.
+func graphMembers(def *graph.Definition, resources [][]byte) ([]graph.Resource, []byte, error) {
+	members := make([]graph.Resource, 0, len(resources))
+	for i, raw := range resources {
+		data, err := decodeResource(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("resource %d: invalid JSON: %w", i, err)
+		}
+		resourceType, _ := data["resourceType"].(string)
+		if resourceType == "" {
+			return nil, nil, fmt.Errorf("resource %d: missing resourceType", i)
+		}
+		if resourceType == "Bundle" && len(resources) == 1 && def.Start() != "Bundle" {
+			return bundleMembers(data), raw, nil
+		}
+		members = append(members, graph.Resource{Data: data, Raw: raw, Path: resourceType})
+	}
+	return members, nil, nil
+}
+
+// bundleMembers returns the entry resources of a Bundle.
+func bundleMembers(bundle map[string]any) []graph.Resource {
+	entries, _ := bundle["entry"].([]any)
+	members := make([]graph.Resource, 0, len(entries))
+	for i, entry := range entries {
+		entryMap, _ := entry.(map[string]any)
+		data, ok := entryMap["resource"].(map[string]any)
+		if !ok {
+			continue
+		}
+		raw, err := json.Marshal(data)
+		if err != nil {
+			continue
+		}
+		fullURL, _ := entryMap["fullUrl"].(string)
+		members = append(members, graph.Resource{
+			Data:    data,
+			Raw:     raw,
+			FullURL: fullURL,
+			Path:    fmt.Sprintf("Bundle.entry[%d].resource", i),
+		})
+	}
+	return members
+}
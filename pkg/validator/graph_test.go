@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+const testGraphDefinition = `{
+  "resourceType": "GraphDefinition",
+  "name": "Episode",
+  "start": "Encounter",
+  "link": [
+    {"path": "Encounter.subject", "min": 1, "max": "1",
+     "target": [{"type": "Patient", "profile": "http://hl7.org/fhir/StructureDefinition/Patient"}]},
+    {"path": "Encounter.serviceProvider", "min": 1, "max": "1",
+     "target": [{"type": "Organization", "profile": "http://example.org/StructureDefinition/unknown"}]}
+  ]
+}`
+
+func TestValidateGraph(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+
+	bundle := []byte(`{
+  "resourceType": "Bundle",
+  "type": "collection",
+  "entry": [
+    {"fullUrl": "urn:uuid:e", "resource": {"resourceType": "Encounter", "status": "finished",
+      "class": {"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "AMB"},
+      "subject": {"reference": "urn:uuid:p"}, "serviceProvider": {"reference": "urn:uuid:o"}}},
+    {"fullUrl": "urn:uuid:p", "resource": {"resourceType": "Patient", "gender": "bogus"}},
+    {"fullUrl": "urn:uuid:o", "resource": {"resourceType": "Organization", "name": "Clinic"}}
+  ]
+}`)
+
+	result, err := v.ValidateGraph(ctx, []byte(testGraphDefinition), [][]byte{bundle})
+	if err != nil {
+		t.Fatalf("ValidateGraph() error: %v", err)
+	}
+	var got []string
+	for _, iss := range result.Issues {
+		got = append(got, iss.MessageID+"@"+iss.Expression[0])
+	}
+	slices.Sort(got)
+	want := []string{
+		string(issue.DiagGraphProfileUnchecked) + "@Bundle.entry[2].resource",
+		string(issue.DiagGraphTargetProfile) + "@Bundle.entry[1].resource",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("issues = %v, want %v", got, want)
+	}
+	if result.Issues[0].Location == nil {
+		t.Error("issues in a Bundle should have locations")
+	}
+
+	// The same resources as a set, without the Organization
+	result, err = v.ValidateGraph(ctx, []byte(testGraphDefinition), [][]byte{
+		[]byte(`{"resourceType": "Encounter", "id": "e", "subject": {"reference": "Patient/p"}}`),
+		[]byte(`{"resourceType": "Patient", "id": "p"}`),
+	})
+	if err != nil {
+		t.Fatalf("ValidateGraph() error: %v", err)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].MessageID != string(issue.DiagGraphLinkCardinality) {
+		t.Errorf("issues = %v, want a missing serviceProvider", result.Issues)
+	}
+
+	if _, err := v.ValidateGraph(ctx, []byte(`{"resourceType": "Patient"}`), nil); err == nil {
+		t.Error("ValidateGraph() with an invalid GraphDefinition should fail")
+	}
+}
@@ -0,0 +1,54 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/identifier"
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestIdentifierValidation(t *testing.T) {
+	v, err := New(WithIdentifierValidator("http://example.org/mrn", func(value string) error {
+		if !strings.HasPrefix(value, "MRN-") {
+			return errors.New("expected the MRN- prefix")
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	resource := `{"resourceType": "Patient",
+		"identifier": [
+			{"system": "` + identifier.SystemRUN + `", "value": "12345678-4"},
+			{"system": "` + identifier.SystemRUN + `", "value": "12345678-5"},
+			{"system": "http://example.org/mrn", "value": "42"}
+		],
+		"generalPractitioner": [{"identifier": {"system": "` + identifier.SystemNPI + `", "value": "1234567890"}}],
+		"contained": [{"resourceType": "Practitioner", "id": "p",
+			"identifier": [{"system": "` + identifier.SystemSSN + `", "value": "000-12-3456"}]}]}`
+
+	result, err := v.ValidateJSON(context.Background(), resource)
+	if err != nil {
+		t.Fatalf("ValidateJSON() error: %v", err)
+	}
+
+	var paths []string
+	for _, iss := range result.Issues {
+		if iss.MessageID == string(issue.DiagIdentifierInvalid) {
+			paths = append(paths, iss.Expression...)
+		}
+	}
+	want := []string{
+		"Patient.contained[0].identifier[0].value",
+		"Patient.generalPractitioner[0].identifier.value",
+		"Patient.identifier[0].value",
+		"Patient.identifier[2].value",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("invalid identifiers at %v, want %v", paths, want)
+	}
+}
@@ -0,0 +1,52 @@
+package validator
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/logger"
+)
+
+// implementationGuide is the part of an ImplementationGuide resource used
+// for global profiles.
+type implementationGuide struct {
+	URL    string `json:"url"`
+	Global []struct {
+		Type    string `json:"type"`
+		Profile string `json:"profile"`
+	} `json:"global"`
+}
+
+// implementationGuideGlobals returns the global profiles, by resource type,
+// declared by the ImplementationGuide resources of the packages selected by
+// igs. A spec without a version matches any version of the package.
+func implementationGuideGlobals(packages []*loader.Package, igs []PackageSpec) map[string][]string {
+	if len(igs) == 0 {
+		return nil
+	}
+	globals := make(map[string][]string)
+	for _, pkg := range packages {
+		selected := slices.ContainsFunc(igs, func(s PackageSpec) bool {
+			return s.Name == pkg.Name && (s.Version == "" || s.Version == pkg.Version)
+		})
+		if !selected {
+			continue
+		}
+		for _, data := range pkg.ResourcesOfType("ImplementationGuide") {
+			var ig implementationGuide
+			if err := json.Unmarshal(data, &ig); err != nil {
+				logger.Warn("Could not parse an ImplementationGuide in %s#%s: %v", pkg.Name, pkg.Version, err)
+				continue
+			}
+			for _, g := range ig.Global {
+				if g.Type == "" || g.Profile == "" || slices.Contains(globals[g.Type], g.Profile) {
+					continue
+				}
+				logger.Info("  Global profile for %s: %s (%s)", g.Type, g.Profile, ig.URL)
+				globals[g.Type] = append(globals[g.Type], g.Profile)
+			}
+		}
+	}
+	return globals
+}
@@ -0,0 +1,113 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+)
+
+const (
+	testIGProfile = "http://example.org/fhir/StructureDefinition/dated-patient"
+
+	testIG = `{
+		"resourceType": "ImplementationGuide", "id": "test-ig",
+		"url": "http://example.org/fhir/ImplementationGuide/test-ig",
+		"global": [{"type": "Patient", "profile": "` + testIGProfile + `"}]
+	}`
+
+	// testIGPatientProfile requires Patient.birthDate
+	testIGPatientProfile = `{
+		"resourceType": "StructureDefinition", "id": "dated-patient",
+		"url": "` + testIGProfile + `",
+		"name": "DatedPatient", "status": "active", "kind": "resource", "abstract": false,
+		"type": "Patient",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Patient",
+		"derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Patient", "path": "Patient", "min": 0, "max": "*"},
+			{"id": "Patient.id", "path": "Patient.id", "min": 0, "max": "1", "type": [{"code": "id"}]},
+			{"id": "Patient.birthDate", "path": "Patient.birthDate", "min": 1, "max": "1", "type": [{"code": "date"}]}
+		]}
+	}`
+)
+
+func TestImplementationGuideGlobals(t *testing.T) {
+	pkg := &loader.Package{
+		Name:    "example.ig",
+		Version: "1.0.0",
+		Resources: map[string]json.RawMessage{
+			"ImplementationGuide/test-ig":                         json.RawMessage(testIG),
+			"http://example.org/fhir/ImplementationGuide/test-ig": json.RawMessage(testIG),
+		},
+	}
+	packages := []*loader.Package{pkg}
+
+	globals := implementationGuideGlobals(packages, []PackageSpec{{Name: "example.ig", Version: "1.0.0"}})
+	if got := globals["Patient"]; !slices.Equal(got, []string{testIGProfile}) {
+		t.Errorf("globals[Patient] = %v, want [%s]", got, testIGProfile)
+	}
+	if globals := implementationGuideGlobals(packages, []PackageSpec{{Name: "example.ig", Version: "2.0.0"}}); len(globals) != 0 {
+		t.Errorf("other version: globals = %v, want none", globals)
+	}
+	if globals := implementationGuideGlobals(packages, nil); len(globals) != 0 {
+		t.Errorf("no IGs: globals = %v, want none", globals)
+	}
+}
+
+func TestWithIG(t *testing.T) {
+	cache := t.TempDir()
+	writeCachePackage(t, cache, "example.ig", "1.0.0", map[string]string{
+		"ImplementationGuide-test-ig.json":       testIG,
+		"StructureDefinition-dated-patient.json": testIGPatientProfile,
+	})
+
+	v, err := New(WithPackagePath(cache), WithIG("example.ig#1.0.0"))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	ctx := context.Background()
+
+	result, err := v.Validate(ctx, []byte(`{"resourceType": "Patient"}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if result.Stats.ProfileURL != testIGProfile {
+		t.Errorf("ProfileURL = %q, want the global profile", result.Stats.ProfileURL)
+	}
+	if !slices.ContainsFunc(result.Issues, func(i issue.Issue) bool { return i.MessageID == string(issue.DiagCardinalityMin) }) {
+		t.Errorf("missing birthDate not reported: %v", result.Issues)
+	}
+
+	// Other resource types are not affected
+	result, err = v.Validate(ctx, []byte(`{"resourceType": "Organization"}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if result.Stats.IsCustomProfile {
+		t.Errorf("Organization validated against %q", result.Stats.ProfileURL)
+	}
+}
+
+// writeCachePackage writes a package with the given files to a package cache.
+func writeCachePackage(t *testing.T, cache, name, version string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(cache, name+"#"+version, "package")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"name": "` + name + `", "version": "` + version + `", "fhirVersion": "4.0.1"}`
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package validator
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/metrics"
+	"github.com/gofhir/validator/pkg/phase"
+)
+
+// instruments records the metrics and spans of a Validator. Metrics are nil
+// without a Registerer, and the tracer is nil without a Tracer.
+type instruments struct {
+	tracer metrics.Tracer
+
+	phaseDuration      metrics.Histogram
+	validationDuration metrics.Histogram
+	active             metrics.Gauge
+	cacheRequests      metrics.Counter
+	loadDuration       metrics.Histogram
+}
+
+// newInstruments creates the metrics of a Validator with reg.
+func newInstruments(reg metrics.Registerer, tracer metrics.Tracer) *instruments {
+	in := &instruments{tracer: tracer}
+	if reg != nil {
+		in.phaseDuration = reg.Histogram(metrics.PhaseDuration,
+			"Duration of validation phases.", metrics.LabelPhase)
+		in.validationDuration = reg.Histogram(metrics.ValidationDuration,
+			"Duration of resource validations.", metrics.LabelResourceType)
+		in.active = reg.Gauge(metrics.ActiveValidations,
+			"Number of validations in progress.")
+		in.cacheRequests = reg.Counter(metrics.TerminologyCacheRequests,
+			"ValueSet expansion cache lookups.", metrics.LabelResult)
+		in.loadDuration = reg.Histogram(metrics.LoadDuration,
+			"Time taken to load the packages of a validator.")
+	}
+	return in
+}
+
+// startValidation records the start of a validation, returning the context
+// to validate with and a function to call with the result when it ends.
+func (in *instruments) startValidation(ctx context.Context) (context.Context, func(*issue.Result)) {
+	start := time.Now()
+	if in.active != nil {
+		in.active.Add(1)
+	}
+	var span metrics.Span
+	if in.tracer != nil {
+		ctx, span = in.tracer.Start(ctx, metrics.SpanValidate)
+	}
+	return ctx, func(result *issue.Result) {
+		resourceType := ""
+		if result != nil && result.Stats != nil {
+			resourceType = result.Stats.ResourceType
+		}
+		if in.active != nil {
+			in.active.Add(-1)
+			in.validationDuration.Observe(time.Since(start).Seconds(), resourceType)
+		}
+		if span != nil {
+			if result != nil {
+				attrs := []metrics.Attribute{
+					{Key: metrics.AttrResourceType, Value: resourceType},
+					{Key: metrics.AttrErrors, Value: result.ErrorCount()},
+					{Key: metrics.AttrWarnings, Value: result.WarningCount()},
+				}
+				if result.Stats != nil && result.Stats.ProfileURL != "" {
+					attrs = append(attrs, metrics.Attribute{Key: metrics.AttrProfile, Value: result.Stats.ProfileURL})
+				}
+				span.SetAttributes(attrs...)
+			}
+			span.End()
+		}
+	}
+}
+
+// startPhase records the start of a phase, returning the context to run it
+// with and a function to call when it ends.
+func (in *instruments) startPhase(ctx context.Context, name phase.Name) (context.Context, func()) {
+	start := time.Now()
+	var span metrics.Span
+	if in.tracer != nil {
+		ctx, span = in.tracer.Start(ctx, metrics.SpanPhasePrefix+string(name))
+	}
+	return ctx, func() {
+		if in.phaseDuration != nil {
+			in.phaseDuration.Observe(time.Since(start).Seconds(), string(name))
+		}
+		if span != nil {
+			span.End()
+		}
+	}
+}
+
+// observeCache records a ValueSet expansion cache lookup.
+func (in *instruments) observeCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	in.cacheRequests.Add(1, result)
+}
+
+// observeLoad records the time taken to load the packages.
+func (in *instruments) observeLoad(d time.Duration) {
+	if in.loadDuration != nil {
+		in.loadDuration.Observe(d.Seconds())
+	}
+}
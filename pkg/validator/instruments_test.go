@@ -0,0 +1,125 @@
+package validator
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/metrics"
+)
+
+// testMetrics records observations by metric name and label values.
+type testMetrics struct {
+	mu     sync.Mutex
+	values map[string]float64 // name{labels} -> sum of observations
+	counts map[string]int     // name{labels} -> number of observations
+}
+
+type testMetric struct {
+	reg  *testMetrics
+	name string
+}
+
+func (m testMetric) record(value float64, labelValues []string) {
+	m.reg.mu.Lock()
+	defer m.reg.mu.Unlock()
+	key := m.name + "{" + strings.Join(labelValues, ",") + "}"
+	m.reg.values[key] += value
+	m.reg.counts[key]++
+}
+
+func (m testMetric) Observe(value float64, labelValues ...string) { m.record(value, labelValues) }
+func (m testMetric) Add(delta float64, labelValues ...string)     { m.record(delta, labelValues) }
+
+func (r *testMetrics) Histogram(name, _ string, _ ...string) metrics.Histogram {
+	return testMetric{r, name}
+}
+func (r *testMetrics) Counter(name, _ string, _ ...string) metrics.Counter {
+	return testMetric{r, name}
+}
+func (r *testMetrics) Gauge(name, _ string, _ ...string) metrics.Gauge {
+	return testMetric{r, name}
+}
+
+// testTracer records span names, each with its parent's name.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []string // "parent>name"
+	attrs map[string]any
+	ended int
+}
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	tracer *testTracer
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, _ ...metrics.Attribute) (context.Context, metrics.Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(string)
+	t.mu.Lock()
+	t.spans = append(t.spans, parent+">"+name)
+	t.mu.Unlock()
+	return context.WithValue(ctx, testSpanKey{}, name), testSpan{t}
+}
+
+func (s testSpan) SetAttributes(attrs ...metrics.Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, a := range attrs {
+		s.tracer.attrs[a.Key] = a.Value
+	}
+}
+
+func (s testSpan) End() {
+	s.tracer.mu.Lock()
+	s.tracer.ended++
+	s.tracer.mu.Unlock()
+}
+
+func TestInstruments(t *testing.T) {
+	reg := &testMetrics{values: map[string]float64{}, counts: map[string]int{}}
+	tracer := &testTracer{attrs: map[string]any{}}
+	v, err := New(WithMetrics(reg), WithTracer(tracer))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	ctx := context.Background()
+	resource := []byte(`{"resourceType": "Patient", "gender": "bogus"}`)
+	for range 2 {
+		if _, err := v.Validate(ctx, resource); err != nil {
+			t.Fatalf("Validate() error: %v", err)
+		}
+	}
+
+	if reg.counts[metrics.LoadDuration+"{}"] != 1 {
+		t.Errorf("load duration observations = %d, want 1", reg.counts[metrics.LoadDuration+"{}"])
+	}
+	if reg.counts[metrics.ValidationDuration+"{Patient}"] != 2 {
+		t.Errorf("validation duration observations = %d, want 2", reg.counts[metrics.ValidationDuration+"{Patient}"])
+	}
+	if reg.counts[metrics.PhaseDuration+"{structural}"] != 2 {
+		t.Errorf("structural phase observations = %d, want 2", reg.counts[metrics.PhaseDuration+"{structural}"])
+	}
+	if reg.counts[metrics.ActiveValidations+"{}"] != 4 || reg.values[metrics.ActiveValidations+"{}"] != 0 {
+		t.Errorf("active validations: %d changes summing to %v, want 4 summing to 0",
+			reg.counts[metrics.ActiveValidations+"{}"], reg.values[metrics.ActiveValidations+"{}"])
+	}
+	if reg.values[metrics.TerminologyCacheRequests+"{hit}"] == 0 {
+		t.Errorf("no expansion cache hits recorded: %v", reg.values)
+	}
+
+	if !slices.Contains(tracer.spans, ">"+metrics.SpanValidate) ||
+		!slices.Contains(tracer.spans, metrics.SpanValidate+">"+metrics.SpanPhasePrefix+"binding") {
+		t.Errorf("spans = %v", tracer.spans)
+	}
+	if tracer.ended != len(tracer.spans) {
+		t.Errorf("ended %d of %d spans", tracer.ended, len(tracer.spans))
+	}
+	if tracer.attrs[metrics.AttrResourceType] != "Patient" || tracer.attrs[metrics.AttrErrors] == 0 {
+		t.Errorf("attributes = %v", tracer.attrs)
+	}
+}
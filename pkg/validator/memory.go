@@ -0,0 +1,40 @@
+package validator
+
+import (
+	"github.com/gofhir/validator/pkg/cache"
+)
+
+// Names of the caches in MemoryStats.
+const (
+	CacheExpansions     = "terminology-expansions" // ValueSet expansions
+	CacheElementIndexes = "element-indexes"        // Element lookups per StructureDefinition
+	CacheExpressions    = "fhirpath-expressions"   // Compiled FHIRPath invariants
+	CacheMemberships    = "fhirpath-memberships"   // FHIRPath memberOf() answers
+)
+
+// MemoryStats describes the memory use of a Validator.
+type MemoryStats struct {
+	Budget    int64                  // WithMemoryBudget bytes (0 = unbounded)
+	HeapInUse uint64                 // Heap bytes of the process in use by objects
+	Trims     uint64                 // Times the caches were trimmed because the heap exceeded the budget
+	Caches    map[string]cache.Stats // Contents and use of each cache, by name
+}
+
+// Stats returns the current memory use of the validator's caches, with
+// their estimated sizes, budgets and hit counts.
+func (v *Validator) Stats() MemoryStats {
+	stats := MemoryStats{
+		HeapInUse: cache.HeapInUse(),
+		Caches: map[string]cache.Stats{
+			CacheExpansions:     v.termRegistry.ExpansionCache().Stats(),
+			CacheElementIndexes: v.structValidator.IndexCache().Stats(),
+			CacheExpressions:    v.constraintValidator.ExpressionCache().Stats(),
+			CacheMemberships:    v.constraintValidator.MembershipCache().Stats(),
+		},
+	}
+	if v.budget != nil {
+		stats.Budget = v.budget.Total()
+		stats.Trims = v.budget.Trims()
+	}
+	return stats
+}
@@ -0,0 +1,56 @@
+package validator
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	const budget = 1 << 20 // Far below the loaded definitions: caches get a tenth
+	v, err := New(WithMemoryBudget(budget))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	ctx := context.Background()
+	for _, resource := range []string{
+		`{"resourceType": "Patient", "gender": "male", "maritalStatus": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/v3-MaritalStatus", "code": "M"}]}}`,
+		`{"resourceType": "Observation", "status": "final", "code": {"text": "x"}, "valueQuantity": {"value": 1}}`,
+		`{"resourceType": "Encounter", "status": "finished", "class": {"code": "AMB"}}`,
+	} {
+		if _, err := v.ValidateJSON(ctx, resource); err != nil {
+			t.Fatalf("ValidateJSON() error: %v", err)
+		}
+	}
+
+	stats := v.Stats()
+	if stats.Budget != budget || stats.HeapInUse == 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+	var total int64
+	for name, st := range stats.Caches {
+		if st.Budget == 0 || st.Cost > st.Budget {
+			t.Errorf("%s: cost %d beyond budget %d", name, st.Cost, st.Budget)
+		}
+		total += st.Budget
+	}
+	if total > budget/10+3 {
+		t.Errorf("cache budgets sum to %d, want at most a tenth of %d", total, budget)
+	}
+	if st := stats.Caches[CacheElementIndexes]; st.Entries == 0 || st.Misses == 0 {
+		t.Errorf("element index cache unused: %+v", st)
+	}
+}
+
+func TestStatsUnbounded(t *testing.T) {
+	v := getSharedValidator(t)
+	stats := v.Stats()
+	if stats.Budget != 0 || len(stats.Caches) != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+	for name, st := range stats.Caches {
+		if st.Budget != 0 {
+			t.Errorf("%s: budget %d, want unbounded", name, st.Budget)
+		}
+	}
+}
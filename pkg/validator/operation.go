@@ -0,0 +1,64 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/location"
+)
+
+// ValidateParameters validates the Parameters resource of an operation call,
+// such as the body of a POST to $validate-code, against the loaded
+// OperationDefinition with the given canonical URL ("url" or "url|version").
+//
+// The resource is validated like Validate does, and each parameter must then
+// be an input parameter of the operation, occur within its min/max and have
+// a value, resource or parts of its type. Parts are checked the same way
+// against the parts of their parameter. Opts apply to the validation of the
+// resource.
+func (v *Validator) ValidateParameters(ctx context.Context, params []byte, operationURL string, opts ...ValidateOption) (*issue.Result, error) {
+	def := v.operations.Get(operationURL)
+	if def == nil {
+		return nil, fmt.Errorf("operation definition not found: %s", operationURL)
+	}
+
+	data, err := decodeResource(params)
+	if err == nil {
+		if resourceType, _ := data["resourceType"].(string); resourceType != "Parameters" {
+			return nil, fmt.Errorf("expected a Parameters resource, got %q", resourceType)
+		}
+	}
+
+	result, err := v.Validate(ctx, params, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// Input that could not be parsed, or was rejected before validation, is
+	// already reported
+	if data == nil || slices.ContainsFunc(result.Issues, func(iss issue.Issue) bool {
+		return iss.Severity == issue.SeverityFatal
+	}) {
+		return result, nil
+	}
+
+	opResult := issue.NewResult()
+	v.operationValidator.ValidateData(def, data, opResult)
+	opResult.EnrichLocations(func(expr string) *issue.Location {
+		if loc := location.Find(params, expr); loc != nil {
+			return &issue.Location{Line: loc.Line, Column: loc.Column}
+		}
+		return nil
+	})
+	v.applyIssueRules(opResult)
+	if v.config.Locale != "" {
+		opResult.Localize(v.config.Locale)
+	}
+
+	result.Merge(opResult)
+	if !v.config.RawIssues {
+		result.Normalize()
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+const translateDefinition = `{
+	"resourceType": "OperationDefinition",
+	"id": "translate",
+	"url": "http://example.org/OperationDefinition/translate",
+	"name": "Translate",
+	"status": "active",
+	"kind": "operation",
+	"code": "translate",
+	"system": false, "type": true, "instance": false,
+	"parameter": [
+		{"name": "code", "use": "in", "min": 1, "max": "1", "type": "code"},
+		{"name": "target", "use": "in", "min": 0, "max": "1", "type": "uri"}
+	]
+}`
+
+func TestValidateParameters(t *testing.T) {
+	v, err := New(WithConformanceResources([][]byte{[]byte(translateDefinition)}))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	ctx := context.Background()
+	url := "http://example.org/OperationDefinition/translate"
+
+	result, err := v.ValidateParameters(ctx, []byte(`{"resourceType": "Parameters", "parameter": [
+		{"name": "code", "valueCode": "a"}, {"name": "target", "valueUri": "http://example.org/vs"}]}`), url)
+	if err != nil {
+		t.Fatalf("ValidateParameters() error: %v", err)
+	}
+	if result.HasErrors() {
+		t.Errorf("valid parameters: issues = %+v", result.Issues)
+	}
+
+	result, err = v.ValidateParameters(ctx, []byte(`{"resourceType": "Parameters", "parameter": [
+		{"name": "target", "valueString": "http://example.org/vs"}]}`), url)
+	if err != nil {
+		t.Fatalf("ValidateParameters() error: %v", err)
+	}
+	want := map[string]string{
+		string(issue.DiagOperationParameterMin):  "Parameters",
+		string(issue.DiagOperationParameterType): "Parameters.parameter[0].valueString",
+	}
+	for _, iss := range result.Issues {
+		if path, ok := want[iss.MessageID]; ok && iss.Expression[0] == path {
+			if iss.Location == nil && path != "Parameters" {
+				t.Errorf("%s has no location", iss.MessageID)
+			}
+			delete(want, iss.MessageID)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing issues %v in %+v", want, result.Issues)
+	}
+
+	if _, err := v.ValidateParameters(ctx, []byte(`{"resourceType": "Parameters"}`), "http://example.org/unknown"); err == nil {
+		t.Error("unknown operation: expected an error")
+	}
+	if _, err := v.ValidateParameters(ctx, []byte(`{"resourceType": "Patient"}`), url); err == nil {
+		t.Error("Patient: expected an error")
+	}
+}
@@ -0,0 +1,263 @@
+package validator
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/phase"
+)
+
+func TestRunPhase(t *testing.T) {
+	addWarning := func(_ context.Context, r *issue.Result) {
+		r.AddWarning(issue.CodeBusinessRule, "phase ran")
+	}
+	// cooperative stops as soon as its context ends, keeping a partial result
+	cooperative := func(ctx context.Context, r *issue.Result) {
+		r.AddWarning(issue.CodeBusinessRule, "partial")
+		<-ctx.Done()
+	}
+	release := make(chan struct{})
+	defer close(release)
+	// stuck ignores its context entirely
+	stuck := func(_ context.Context, r *issue.Result) {
+		<-release
+		r.AddWarning(issue.CodeBusinessRule, "too late")
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		ctx          context.Context
+		phase        func(context.Context, *issue.Result)
+		wantOK       bool
+		wantID       issue.DiagnosticID // empty = phase issues merged
+		wantMessages int
+	}{
+		{"completes without timeout", 0, context.Background(), addWarning, true, "", 1},
+		{"completes within timeout", time.Second, context.Background(), addWarning, true, "", 1},
+		{"cooperative phase times out", 10 * time.Millisecond, context.Background(), cooperative, true, issue.DiagPhaseTimeout, 1},
+		{"stuck phase is abandoned", 10 * time.Millisecond, context.Background(), stuck, true, issue.DiagPhaseTimeout, 1},
+		{"canceled context interrupts", 0, canceled, addWarning, false, issue.DiagPhaseInterrupted, 1},
+		{"canceled context interrupts with timeout", time.Second, canceled, cooperative, false, issue.DiagPhaseInterrupted, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Validator{config: &Config{PhaseTimeout: tt.timeout}}
+			result := issue.NewResult()
+			result.Stats = &issue.Stats{}
+
+			if ok := v.runPhase(tt.ctx, phase.Set{}, "test", result, tt.phase); ok != tt.wantOK {
+				t.Errorf("runPhase() = %v, want %v", ok, tt.wantOK)
+			}
+			if len(result.Issues) != tt.wantMessages {
+				t.Fatalf("got %d issues, want %d: %v", len(result.Issues), tt.wantMessages, result.Issues)
+			}
+
+			got := result.Issues[0]
+			if tt.wantID == "" {
+				if got.Diagnostics != "phase ran" || result.Stats.PhasesRun != 1 {
+					t.Errorf("issue = %q, PhasesRun = %d; want phase issue merged", got.Diagnostics, result.Stats.PhasesRun)
+				}
+				return
+			}
+			if got.MessageID != string(tt.wantID) || got.Severity != issue.SeverityWarning {
+				t.Errorf("issue = %s/%s, want %s/warning", got.MessageID, got.Severity, tt.wantID)
+			}
+			if result.Stats.PhasesRun != 0 || len(result.Stats.IncompletePhases) != 1 {
+				t.Errorf("PhasesRun = %d, IncompletePhases = %v; want 0, [test]",
+					result.Stats.PhasesRun, result.Stats.IncompletePhases)
+			}
+		})
+	}
+}
+
+func TestRunPhaseStrayLimit(t *testing.T) {
+	// Let phases abandoned by other tests finish, then take every background
+	// slot so the next timed-out phase is waited for
+	for deadline := time.Now().Add(time.Second); len(strayPhases) > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	for len(strayPhases) < cap(strayPhases) {
+		strayPhases <- struct{}{}
+	}
+	defer func() {
+		for len(strayPhases) > 0 {
+			<-strayPhases
+		}
+	}()
+
+	v := &Validator{config: &Config{PhaseTimeout: 10 * time.Millisecond}}
+	result := issue.NewResult()
+	result.Stats = &issue.Stats{}
+	var finished atomic.Bool
+	slow := func(_ context.Context, r *issue.Result) {
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	}
+
+	if ok := v.runPhase(context.Background(), phase.Set{}, "test", result, slow); !ok {
+		t.Error("runPhase() = false, want true")
+	}
+	if !finished.Load() {
+		t.Error("runPhase() returned before the timed-out phase finished")
+	}
+	if len(result.Issues) != 1 || result.Issues[0].MessageID != string(issue.DiagPhaseTimeout) {
+		t.Errorf("issues = %v, want a PHASE_TIMEOUT warning", result.Issues)
+	}
+}
+
+func TestRunPhaseDisabled(t *testing.T) {
+	v := &Validator{config: &Config{}}
+	result := issue.NewResult()
+	result.Stats = &issue.Stats{}
+
+	ran := false
+	phases := phase.NewSet(nil, []phase.Name{phase.Binding})
+	ok := v.runPhase(context.Background(), phases, phase.Binding, result, func(context.Context, *issue.Result) { ran = true })
+	if !ok || ran {
+		t.Errorf("runPhase() = %v, ran = %v; want true, false", ok, ran)
+	}
+	if len(result.Stats.SkippedPhases) != 1 || result.Stats.SkippedPhases[0] != "binding" || result.Stats.PhasesRun != 0 {
+		t.Errorf("Stats = %+v, want binding skipped", result.Stats)
+	}
+}
+
+func TestCallPhases(t *testing.T) {
+	v := &Validator{config: &Config{DisabledPhases: []phase.Name{phase.Narrative}}}
+	v.phases = phase.NewSet(v.config.Phases, v.config.DisabledPhases)
+
+	tests := []struct {
+		name    string
+		opts    []ValidateOption
+		enabled []phase.Name
+		skipped []phase.Name
+	}{
+		{"configured", nil, []phase.Name{phase.Structure, phase.Binding}, []phase.Name{phase.Narrative}},
+		{"only structure", []ValidateOption{ValidateWithPhases(phase.Structure)},
+			[]phase.Name{phase.Structure}, []phase.Name{phase.Binding, phase.Cardinality}},
+		{"only overrides configured exclusions", []ValidateOption{ValidateWithPhases(phase.Narrative)},
+			[]phase.Name{phase.Narrative}, []phase.Name{phase.Structure}},
+		{"exclusions add to configured", []ValidateOption{ValidateWithoutPhases("terminology", "references")},
+			[]phase.Name{phase.Structure}, []phase.Name{phase.Narrative, phase.Binding, phase.Reference}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var vc validateConfig
+			for _, opt := range tt.opts {
+				opt(&vc)
+			}
+			phases, err := v.callPhases(&vc)
+			if err != nil {
+				t.Fatalf("callPhases() error: %v", err)
+			}
+			for _, name := range tt.enabled {
+				if !phases.Enabled(name) {
+					t.Errorf("%s should run", name)
+				}
+			}
+			for _, name := range tt.skipped {
+				if phases.Enabled(name) {
+					t.Errorf("%s should be skipped", name)
+				}
+			}
+		})
+	}
+
+	if _, err := v.callPhases(&validateConfig{phases: []phase.Name{"spelling"}}); err == nil {
+		t.Error("callPhases() should reject an unknown phase")
+	}
+}
+
+func TestValidateWithPhases(t *testing.T) {
+	v := getSharedValidator(t)
+	resource := []byte(`{"resourceType":"Patient","gender":"bogus","foo":1}`)
+
+	result, err := v.Validate(context.Background(), resource, ValidateWithPhases(phase.Structure))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if result.Stats.PhasesRun != 1 {
+		t.Errorf("PhasesRun = %d, want 1 (skipped %v)", result.Stats.PhasesRun, result.Stats.SkippedPhases)
+	}
+	for _, iss := range result.Issues {
+		if iss.Code == issue.CodeCodeInvalid {
+			t.Errorf("binding issue reported by a structure-only check: %s", iss.Diagnostics)
+		}
+	}
+	if !result.HasErrors() {
+		t.Error("structure-only check should report the unknown element")
+	}
+
+	if _, err := v.Validate(context.Background(), resource, ValidateWithoutPhases("spelling")); err == nil {
+		t.Error("Validate() should reject an unknown phase")
+	}
+}
+
+func TestPhasePlugin(t *testing.T) {
+	var calls atomic.Int32
+	payer := phase.Plugin{
+		Name:          "payer",
+		ResourceTypes: []string{"Patient"},
+		Run: func(_ context.Context, rc *phase.Context, r *issue.Result) {
+			calls.Add(1)
+			if rc.Profile == nil || rc.Registry == nil || rc.Walker == nil {
+				t.Error("plugin context is missing definitions")
+			}
+			if _, ok := rc.Resource["identifier"]; !ok {
+				r.AddError(issue.CodeBusinessRule, "Payer requires a member identifier", rc.ResourceType)
+			}
+		},
+	}
+	v, err := New(WithPhasePlugin(payer))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	ctx := context.Background()
+
+	result, err := v.Validate(ctx, []byte(`{"resourceType":"Patient"}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if !hasDiagnostic(result, "member identifier") {
+		t.Errorf("plugin issue not reported: %v", result.Issues)
+	}
+
+	result, err = v.Validate(ctx, []byte(`{"resourceType":"Patient"}`), ValidateWithoutPhases("payer"))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if hasDiagnostic(result, "member identifier") {
+		t.Error("disabled plugin should not run")
+	}
+
+	calls.Store(0)
+	if _, err := v.Validate(ctx, []byte(`{"resourceType":"Observation","status":"final","code":{"text":"x"}}`)); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Error("plugin should only run for its resource types")
+	}
+
+	if _, err := New(WithPhasePlugin(phase.Plugin{Name: "binding", Run: payer.Run})); err == nil {
+		t.Error("New() should reject a plugin named like a built-in phase")
+	}
+}
+
+// hasDiagnostic reports whether result has an issue whose diagnostics
+// contain s.
+func hasDiagnostic(result *issue.Result, s string) bool {
+	for _, iss := range result.Issues {
+		if strings.Contains(iss.Diagnostics, s) {
+			return true
+		}
+	}
+	return false
+}
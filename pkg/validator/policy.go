@@ -0,0 +1,107 @@
+package validator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/phase"
+)
+
+// Policy sets how resources of one type are validated, so a deployment can,
+// for example, skip terminology for high-volume AuditEvents while holding
+// Patients and Observations to a stricter standard (see
+// WithResourceTypePolicy).
+type Policy struct {
+	// Phases, if set, replaces the phases configured with WithPhases for
+	// resources of the type.
+	Phases []phase.Name
+	// DisabledPhases are skipped for the type, in addition to those disabled
+	// with WithDisabledPhases.
+	DisabledPhases []phase.Name
+	// Profiles are validated when no other profile applies: none is given
+	// to the call or the Validator, declared in meta.profile or a global
+	// profile of a loaded IG.
+	Profiles []string
+	// Strict reports warnings as errors.
+	Strict bool
+}
+
+// WithResourceTypePolicy sets validation policies by resource type (e.g.,
+// "AuditEvent"), applied by the type of the resource being validated. Later
+// calls replace the policies of the same types. Phases selected for a call
+// with ValidateWithPhases override the policy's phases, and phases skipped
+// with ValidateWithoutPhases are skipped as well.
+func WithResourceTypePolicy(policies map[string]Policy) Option {
+	return func(c *Config) {
+		if c.ResourceTypePolicies == nil {
+			c.ResourceTypePolicies = make(map[string]Policy, len(policies))
+		}
+		maps.Copy(c.ResourceTypePolicies, policies)
+	}
+}
+
+// typePolicy is a Policy resolved for a Validator.
+type typePolicy struct {
+	Policy
+	phases  phase.Set
+	plugins []phase.Name // Names of the phase plugins
+}
+
+// resolvePolicies resolves the phase names of the policies in config and
+// combines them with its phases.
+func resolvePolicies(config *Config) (map[string]*typePolicy, error) {
+	if len(config.ResourceTypePolicies) == 0 {
+		return nil, nil
+	}
+	policies := make(map[string]*typePolicy, len(config.ResourceTypePolicies))
+	plugins := config.pluginNames()
+	for resourceType, p := range config.ResourceTypePolicies {
+		only, err := phase.Resolve(p.Phases, plugins...)
+		if err != nil {
+			return nil, fmt.Errorf("policy for %s: %w", resourceType, err)
+		}
+		disabled, err := phase.Resolve(p.DisabledPhases, plugins...)
+		if err != nil {
+			return nil, fmt.Errorf("policy for %s: %w", resourceType, err)
+		}
+		if len(only) == 0 {
+			only = config.Phases
+		}
+		p.Phases, p.DisabledPhases = only, append(slices.Clip(config.DisabledPhases), disabled...)
+		policies[resourceType] = &typePolicy{Policy: p, phases: phase.NewSet(p.Phases, p.DisabledPhases), plugins: plugins}
+	}
+	return policies, nil
+}
+
+// callPhases returns the phases to run for a resource under the policy,
+// given the phases selected for the call.
+func (p *typePolicy) callPhases(vc *validateConfig, phases phase.Set) phase.Set {
+	if len(vc.phases) > 0 {
+		return phases
+	}
+	if len(vc.disabledPhases) == 0 {
+		return p.phases
+	}
+	disabled, _ := phase.Resolve(vc.disabledPhases, p.plugins...) // Checked by callPhases
+	return p.phases.Without(disabled...)
+}
+
+// applyStrict reports the warnings of result as errors.
+func applyStrict(result *issue.Result) {
+	for i := range result.Issues {
+		if result.Issues[i].Severity == issue.SeverityWarning {
+			result.Issues[i].Severity = issue.SeverityError
+		}
+	}
+}
+
+// pluginNames returns the names of the phase plugins in config.
+func (c *Config) pluginNames() []phase.Name {
+	names := make([]phase.Name, len(c.PhasePlugins))
+	for i := range c.PhasePlugins {
+		names[i] = c.PhasePlugins[i].Name
+	}
+	return names
+}
@@ -0,0 +1,73 @@
+package validator
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/phase"
+)
+
+func TestResourceTypePolicy(t *testing.T) {
+	const vitalSigns = "http://hl7.org/fhir/StructureDefinition/vitalsigns"
+	v, err := New(WithResourceTypePolicy(map[string]Policy{
+		"AuditEvent":  {DisabledPhases: []phase.Name{"terminology"}},
+		"Patient":     {Strict: true},
+		"Observation": {Profiles: []string{vitalSigns}},
+	}))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	ctx := context.Background()
+
+	audit := []byte(`{"resourceType": "AuditEvent", "type": {"code": "rest"}, "recorded": "2024-01-01T00:00:00Z",
+		"agent": [{"requestor": true}], "source": {"observer": {"display": "server"}}}`)
+	result, err := v.Validate(ctx, audit)
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if !slices.Contains(result.Stats.SkippedPhases, string(phase.Binding)) {
+		t.Errorf("AuditEvent SkippedPhases = %v, want binding", result.Stats.SkippedPhases)
+	}
+	result, err = v.Validate(ctx, audit, ValidateWithPhases(phase.Binding))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if slices.Contains(result.Stats.SkippedPhases, string(phase.Binding)) {
+		t.Error("ValidateWithPhases should override the policy")
+	}
+
+	// dom-6 (no narrative) is a warning, reported as an error for Patients only
+	for _, resource := range []string{`{"resourceType": "Patient"}`, `{"resourceType": "Practitioner"}`} {
+		result, err = v.Validate(ctx, []byte(resource))
+		if err != nil {
+			t.Fatalf("Validate() error: %v", err)
+		}
+		strict := strings.Contains(resource, "Patient")
+		if result.HasErrors() != strict || (result.WarningCount() == 0) == !strict {
+			t.Errorf("%s: %d errors, %d warnings", resource, result.ErrorCount(), result.WarningCount())
+		}
+	}
+
+	result, err = v.Validate(ctx, []byte(`{"resourceType": "Observation", "status": "final", "code": {"text": "x"}}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if result.Stats.ProfileURL != vitalSigns {
+		t.Errorf("ProfileURL = %s, want the policy profile", result.Stats.ProfileURL)
+	}
+	result, err = v.Validate(ctx, []byte(`{"resourceType": "Observation", "status": "final", "code": {"text": "x"}}`),
+		ValidateWithProfile("http://hl7.org/fhir/StructureDefinition/Observation"))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if result.Stats.ProfileURL == vitalSigns {
+		t.Error("policy profiles should only apply when no other profile does")
+	}
+
+	if _, err := New(WithResourceTypePolicy(map[string]Policy{"Patient": {Phases: []phase.Name{"spelling"}}})); err == nil ||
+		!strings.Contains(err.Error(), "policy for Patient") {
+		t.Errorf("New() error = %v, want an unknown phase error", err)
+	}
+}
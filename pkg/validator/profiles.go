@@ -0,0 +1,128 @@
+package validator
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// ProfileSelection decides which of the profiles that apply to a resource it
+// is validated against. Profiles apply from these sources, in order of
+// precedence: the Validate call (ValidateWithProfile), the Validator
+// (WithProfile), meta.profile, the global profiles of loaded IGs (WithIG)
+// and the policy of the resource type (WithResourceTypePolicy). A resource no
+// resolvable profile applies to is validated against its base definition.
+type ProfileSelection int
+
+const (
+	// ProfileUnion validates against the profiles of the call, the
+	// Validator, meta.profile and IG global profiles together, as FHIR
+	// requires a resource to conform to every profile it claims; policy
+	// profiles apply when these name none. This is the default.
+	ProfileUnion ProfileSelection = iota
+	// ProfilePrecedence validates against the profiles of the first source
+	// that names any, e.g., only the profile given to the call even if the
+	// resource declares others, as conformance test harnesses expect.
+	ProfilePrecedence
+)
+
+// String returns the selection name as accepted by ParseProfileSelection.
+func (s ProfileSelection) String() string {
+	if s == ProfilePrecedence {
+		return "precedence"
+	}
+	return "union"
+}
+
+// ParseProfileSelection parses a selection name: "union" or "precedence".
+func ParseProfileSelection(s string) (ProfileSelection, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "union":
+		return ProfileUnion, true
+	case "precedence":
+		return ProfilePrecedence, true
+	}
+	return ProfileUnion, false
+}
+
+// WithProfileSelection sets which of the profiles that apply to a resource
+// it is validated against (default ProfileUnion).
+func WithProfileSelection(s ProfileSelection) Option {
+	return func(c *Config) {
+		c.ProfileSelection = s
+	}
+}
+
+// ValidateWithProfileSelection overrides the Validator's profile selection
+// for this call only.
+func ValidateWithProfileSelection(s ProfileSelection) ValidateOption {
+	return func(c *validateConfig) {
+		c.profileSelection = &s
+	}
+}
+
+// profileRequest is a profile that applies to a resource and where it was
+// asked for.
+type profileRequest struct {
+	url    string
+	origin issue.ProfileOrigin
+}
+
+// collectProfilesToValidate returns the ordered list of profiles to validate against.
+// Priority: 1) Per-call profiles, 2) Config profiles, 3) meta.profile,
+// 4) ImplementationGuide global profiles for the resource type, 5) policy
+// profiles, 6) core resource SD. The selection decides how many sources
+// apply; a profile named by several is listed once, by the first.
+func (v *Validator) collectProfilesToValidate(resourceType string, vc *validateConfig, metaProfiles []string) []profileRequest {
+	selection := v.config.ProfileSelection
+	if vc.profileSelection != nil {
+		selection = *vc.profileSelection
+	}
+	var policyProfiles []string
+	if policy := v.policies[resourceType]; policy != nil {
+		policyProfiles = policy.Profiles
+	}
+
+	sources := []struct {
+		origin issue.ProfileOrigin
+		urls   []string
+	}{
+		{issue.ProfileOriginCall, vc.profiles},
+		{issue.ProfileOriginConfig, v.config.Profiles},
+		{issue.ProfileOriginMeta, metaProfiles},
+		{issue.ProfileOriginGlobal, v.globalProfiles[resourceType]},
+		{issue.ProfileOriginPolicy, policyProfiles},
+	}
+
+	var profiles []profileRequest
+	for _, src := range sources {
+		// Policy profiles only apply when no other source names a profile
+		if len(profiles) > 0 && (selection == ProfilePrecedence || src.origin == issue.ProfileOriginPolicy) {
+			break
+		}
+		for _, url := range src.urls {
+			if !slices.ContainsFunc(profiles, func(p profileRequest) bool { return p.url == url }) {
+				profiles = append(profiles, profileRequest{url: url, origin: src.origin})
+			}
+		}
+	}
+
+	// Core resource type as fallback (added at validation time if needed)
+	// Not added here to allow detecting if all custom profiles failed
+
+	return profiles
+}
+
+// profileOutcome returns the outcome of validating against a profile from
+// the issues reported for it, counting errors as they will be reported:
+// after severity overrides, suppressions and the strict policy.
+func (v *Validator) profileOutcome(p profileRequest, issues []issue.Issue, strict bool) issue.ProfileOutcome {
+	r := &issue.Result{Issues: slices.Clone(issues)}
+	v.applyIssueRules(r)
+	if strict {
+		applyStrict(r)
+	}
+	n := r.ErrorCount()
+	return issue.ProfileOutcome{URL: p.url, Origin: p.origin, Valid: n == 0, Errors: n}
+}
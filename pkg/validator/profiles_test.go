@@ -0,0 +1,89 @@
+package validator
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestProfileSelection(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+	const (
+		core   = "http://hl7.org/fhir/StructureDefinition/Observation"
+		vitals = "http://hl7.org/fhir/StructureDefinition/vitalsigns"
+	)
+	// Valid against the core definition, but not a vital sign
+	resource := []byte(`{
+		"resourceType": "Observation",
+		"meta": {"profile": ["` + vitals + `"]},
+		"status": "final",
+		"code": {"text": "note"}
+	}`)
+
+	tests := []struct {
+		name string
+		opts []ValidateOption
+		want []issue.ProfileOutcome
+	}{
+		{
+			name: "declared profile",
+			want: []issue.ProfileOutcome{{URL: vitals, Origin: issue.ProfileOriginMeta}},
+		},
+		{
+			name: "union with call profile",
+			opts: []ValidateOption{ValidateWithProfile(core)},
+			want: []issue.ProfileOutcome{
+				{URL: core, Origin: issue.ProfileOriginCall, Valid: true},
+				{URL: vitals, Origin: issue.ProfileOriginMeta},
+			},
+		},
+		{
+			name: "call profile takes precedence",
+			opts: []ValidateOption{ValidateWithProfile(core), ValidateWithProfileSelection(ProfilePrecedence)},
+			want: []issue.ProfileOutcome{{URL: core, Origin: issue.ProfileOriginCall, Valid: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.Validate(ctx, resource, tt.opts...)
+			if err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+			got := result.Stats.ProfilesEvaluated
+			if !slices.EqualFunc(got, tt.want, func(a, b issue.ProfileOutcome) bool {
+				return a.URL == b.URL && a.Origin == b.Origin && a.Valid == b.Valid && (a.Errors == 0) == a.Valid
+			}) {
+				t.Errorf("ProfilesEvaluated = %+v, want %+v", got, tt.want)
+			}
+			if want := tt.want[len(tt.want)-1].Valid; result.HasErrors() == want {
+				t.Errorf("HasErrors() = %v, want %v: %+v", result.HasErrors(), !want, result.Issues)
+			}
+		})
+	}
+}
+
+func TestProfileSelectionBase(t *testing.T) {
+	v := getSharedValidator(t)
+	result, err := v.Validate(context.Background(), []byte(`{"resourceType":"Patient"}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	want := []issue.ProfileOutcome{{URL: "http://hl7.org/fhir/StructureDefinition/Patient", Origin: issue.ProfileOriginBase, Valid: true}}
+	if !slices.Equal(result.Stats.ProfilesEvaluated, want) {
+		t.Errorf("ProfilesEvaluated = %+v, want %+v", result.Stats.ProfilesEvaluated, want)
+	}
+}
+
+func TestParseProfileSelection(t *testing.T) {
+	for _, s := range []ProfileSelection{ProfileUnion, ProfilePrecedence} {
+		if got, ok := ParseProfileSelection(s.String()); !ok || got != s {
+			t.Errorf("ParseProfileSelection(%q) = %v, %v", s, got, ok)
+		}
+	}
+	if _, ok := ParseProfileSelection("first"); ok {
+		t.Error("ParseProfileSelection(first) succeeded")
+	}
+}
@@ -0,0 +1,83 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LoadStage identifies a step of validator construction reported to a
+// ProgressFunc.
+type LoadStage string
+
+// Load stages, in the order they occur.
+const (
+	LoadDownload         LoadStage = "download"          // A package URL is being fetched (Source is the URL)
+	LoadPackage          LoadStage = "package"           // A package source was loaded or skipped (Done counts it)
+	LoadIndexDefinitions LoadStage = "index-definitions" // StructureDefinitions are being indexed
+	LoadIndexTerminology LoadStage = "index-terminology" // ValueSets and CodeSystems are being indexed
+	LoadReady            LoadStage = "ready"             // The validator is ready
+)
+
+// LoadEvent reports validator construction progress.
+type LoadEvent struct {
+	Stage   LoadStage
+	Source  string        // Package as name#version, or the URL, file or source that failed
+	Err     error         // Why the package source was skipped (LoadPackage only)
+	Done    int           // Package sources processed so far
+	Total   int           // Package sources to process
+	Elapsed time.Duration // Time since construction started
+}
+
+// ProgressFunc receives LoadEvents during validator construction. It is
+// called synchronously from the constructing goroutine.
+type ProgressFunc func(event LoadEvent)
+
+// WithLoadProgress reports package download, parse and index progress
+// during New and NewContext, e.g. to drive a progress bar. Default packages
+// read from the package cache on disk are reported once all are loaded.
+func WithLoadProgress(fn ProgressFunc) Option {
+	return func(c *Config) {
+		c.LoadProgress = fn
+	}
+}
+
+// loadProgress tracks construction progress and cancellation.
+type loadProgress struct {
+	ctx   context.Context
+	fn    ProgressFunc
+	start time.Time
+	done  int
+	total int
+}
+
+// report sends an event for stage, if a ProgressFunc is configured.
+func (p *loadProgress) report(stage LoadStage, source string, err error) {
+	if p.fn == nil {
+		return
+	}
+	p.fn(LoadEvent{
+		Stage:   stage,
+		Source:  source,
+		Err:     err,
+		Done:    p.done,
+		Total:   p.total,
+		Elapsed: time.Since(p.start),
+	})
+}
+
+// loaded counts a package source as processed and reports it. It returns an
+// error if construction was canceled.
+func (p *loadProgress) loaded(source string, err error) error {
+	p.done++
+	p.report(LoadPackage, source, err)
+	return p.canceled()
+}
+
+// canceled returns an error if the construction context is done.
+func (p *loadProgress) canceled() error {
+	if err := p.ctx.Err(); err != nil {
+		return fmt.Errorf("validator initialization canceled: %w", err)
+	}
+	return nil
+}
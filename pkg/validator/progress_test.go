@@ -0,0 +1,73 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLoadProgress(t *testing.T) {
+	var events []LoadEvent
+	_, err := New(
+		WithPackage("example.missing", "1.0.0"),
+		WithLoadProgress(func(e LoadEvent) { events = append(events, e) }),
+	)
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	var stages []LoadStage
+	for _, e := range events {
+		if len(stages) == 0 || stages[len(stages)-1] != e.Stage {
+			stages = append(stages, e.Stage)
+		}
+	}
+	want := []LoadStage{LoadPackage, LoadIndexDefinitions, LoadIndexTerminology, LoadReady}
+	if len(stages) != len(want) {
+		t.Fatalf("stages = %v, want %v", stages, want)
+	}
+	for i := range want {
+		if stages[i] != want[i] {
+			t.Fatalf("stages = %v, want %v", stages, want)
+		}
+	}
+
+	last := events[len(events)-1]
+	if last.Done != last.Total || last.Total == 0 {
+		t.Errorf("ready event Done/Total = %d/%d", last.Done, last.Total)
+	}
+	var skipped *LoadEvent
+	for i := range events {
+		if events[i].Err != nil {
+			skipped = &events[i]
+		}
+	}
+	if skipped == nil || skipped.Source != "example.missing#1.0.0" {
+		t.Errorf("missing package not reported as skipped: %+v", skipped)
+	}
+}
+
+func TestNewContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("NewContext(canceled) error = %v, want context.Canceled", err)
+	}
+
+	// Canceling from the progress callback stops loading at the next package
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var packages int
+	_, err := NewContext(ctx, WithLoadProgress(func(e LoadEvent) {
+		if e.Stage == LoadPackage {
+			packages++
+			cancel()
+		}
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Skipf("NewContext() error = %v (packages may not be installed)", err)
+	}
+	if packages != 1 {
+		t.Errorf("loaded %d packages after cancellation, want 1", packages)
+	}
+}
@@ -0,0 +1,188 @@
+package validator
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// QualityScoring weighs the data quality score of a resource (see
+// issue.Stats.Score), from 0 to 100. Issues take ErrorPenalty points per
+// error and WarningPenalty per warning off a conformance part, which is
+// blended with must-support and terminology coverage by their weights:
+//
+//	score = 100 × ((1-MustSupportWeight-TerminologyWeight) × conformance
+//	        + MustSupportWeight × mustSupport + TerminologyWeight × terminology)
+//
+// where each part is a ratio from 0 to 1.
+type QualityScoring struct {
+	ErrorPenalty      float64 // Points per error or fatal issue
+	WarningPenalty    float64 // Points per warning
+	MustSupportWeight float64 // Weight of must-support coverage (0 to 1)
+	TerminologyWeight float64 // Weight of terminology coverage (0 to 1)
+}
+
+// DefaultQualityScoring takes 20 points per error and 5 per warning, and
+// gives must-support and terminology coverage a fifth and a tenth of the
+// score.
+func DefaultQualityScoring() QualityScoring {
+	return QualityScoring{
+		ErrorPenalty:      20,
+		WarningPenalty:    5,
+		MustSupportWeight: 0.2,
+		TerminologyWeight: 0.1,
+	}
+}
+
+// WithQualityScoring scores each result with q (see issue.Stats.Score).
+func WithQualityScoring(q QualityScoring) Option {
+	return func(c *Config) {
+		c.QualityScoring = &q
+	}
+}
+
+// score returns the quality score of result, rounded to one decimal.
+func (q QualityScoring) score(result *issue.Result) float64 {
+	wMS := clamp(q.MustSupportWeight)
+	wT := clamp(q.TerminologyWeight)
+	if wMS+wT > 1 {
+		wMS, wT = wMS/(wMS+wT), wT/(wMS+wT)
+	}
+	penalty := float64(result.ErrorCount())*q.ErrorPenalty + float64(result.WarningCount())*q.WarningPenalty
+	conformance := clamp(1 - penalty/100)
+
+	score := (1-wMS-wT)*conformance +
+		wMS*result.Stats.MustSupport.Ratio() +
+		wT*result.Stats.Terminology.Ratio()
+	return math.Round(score*1000) / 10
+}
+
+func clamp(x float64) float64 {
+	return max(0, min(1, x))
+}
+
+// phaseIssues counts the issues of result by the phase that reported them.
+func phaseIssues(result *issue.Result) map[string]int {
+	var counts map[string]int
+	for i := range result.Issues {
+		src := result.Issues[i].Source
+		if src == nil || src.Phase == "" {
+			continue
+		}
+		if counts == nil {
+			counts = make(map[string]int)
+		}
+		counts[src.Phase]++
+	}
+	return counts
+}
+
+// countElements counts the elements of a decoded resource: the value
+// itself, each object member, and each item of an array.
+func countElements(value any) int {
+	switch v := value.(type) {
+	case map[string]any:
+		n := 1
+		for _, child := range v {
+			if items, ok := child.([]any); ok {
+				for _, item := range items {
+					n += countElements(item)
+				}
+				continue
+			}
+			n += countElements(child)
+		}
+		return n
+	case []any:
+		n := 0
+		for _, item := range v {
+			n += countElements(item)
+		}
+		return n
+	}
+	return 1
+}
+
+// mustSupportCoverage counts the mustSupport elements of sd whose parent is
+// present in data, and how many of those data populates. Slices are left
+// out, since which items belong to them is up to the slicing phase.
+func mustSupportCoverage(sd *registry.StructureDefinition, data map[string]any) issue.Coverage {
+	var c issue.Coverage
+	if sd == nil || sd.Snapshot == nil {
+		return c
+	}
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		if !elem.MustSupport || strings.Contains(elem.ID, ":") {
+			continue
+		}
+		segments := strings.Split(elem.Path, ".")
+		if len(segments) < 2 {
+			continue
+		}
+		parents := []map[string]any{data}
+		for _, seg := range segments[1 : len(segments)-1] {
+			parents = children(parents, seg)
+		}
+		if len(parents) == 0 {
+			continue
+		}
+		c.Total++
+		name := segments[len(segments)-1]
+		for _, parent := range parents {
+			if hasMember(parent, name) {
+				c.Covered++
+				break
+			}
+		}
+	}
+	return c
+}
+
+// children returns the objects named seg in parents, with arrays flattened.
+func children(parents []map[string]any, seg string) []map[string]any {
+	var out []map[string]any
+	for _, parent := range parents {
+		for key, value := range parent {
+			if !memberMatches(key, seg) {
+				continue
+			}
+			switch v := value.(type) {
+			case map[string]any:
+				out = append(out, v)
+			case []any:
+				for _, item := range v {
+					if m, ok := item.(map[string]any); ok {
+						out = append(out, m)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// hasMember reports whether parent has the element name, as a value or as
+// a primitive's extension-only "_name" companion.
+func hasMember(parent map[string]any, name string) bool {
+	for key := range parent {
+		if memberMatches(strings.TrimPrefix(key, "_"), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// memberMatches reports whether the JSON member key is the element name,
+// where a choice element "value[x]" matches "valueQuantity" and the like.
+func memberMatches(key, name string) bool {
+	prefix, choice := strings.CutSuffix(name, "[x]")
+	if !choice {
+		return key == name
+	}
+	rest, ok := strings.CutPrefix(key, prefix)
+	return ok && rest != "" && unicode.IsUpper(rune(rest[0]))
+}
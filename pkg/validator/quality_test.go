@@ -0,0 +1,93 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+func TestQualityStats(t *testing.T) {
+	v := getSharedValidator(t)
+
+	result, err := v.Validate(context.Background(), []byte(`{
+		"resourceType": "Observation",
+		"meta": {"profile": ["http://hl7.org/fhir/StructureDefinition/bodyweight"]},
+		"status": "final",
+		"category": [{"coding": [{"system": "http://terminology.hl7.org/CodeSystem/observation-category", "code": "vital-signs"}]}],
+		"code": {"coding": [{"system": "http://loinc.org", "code": "29463-7"}]},
+		"subject": {"reference": "Patient/example"},
+		"effectiveDateTime": "2024-01-15",
+		"valueQuantity": {"value": 70, "unit": "kg", "system": "http://unitsofmeasure.org", "code": "kgg"}
+	}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	st := result.Stats
+
+	if st.ElementsChecked != 21 {
+		t.Errorf("ElementsChecked = %d, want 21", st.ElementsChecked)
+	}
+	if st.PhaseIssues["binding"] == 0 {
+		t.Errorf("PhaseIssues = %v, want binding issues for the unit", st.PhaseIssues)
+	}
+	// LOINC needs a terminology server, so its code is accepted unchecked
+	if st.Terminology.Total == 0 || st.Terminology.Covered >= st.Terminology.Total {
+		t.Errorf("Terminology = %+v, want some codes skipped", st.Terminology)
+	}
+	if st.MustSupport.Total == 0 || st.MustSupport.Covered == 0 || st.MustSupport.Covered > st.MustSupport.Total {
+		t.Errorf("MustSupport = %+v", st.MustSupport)
+	}
+	if st.Scored || st.Score != 0 {
+		t.Errorf("Score = %v without WithQualityScoring", st.Score)
+	}
+}
+
+func TestQualityScore(t *testing.T) {
+	result := issue.NewResult()
+	result.Stats = &issue.Stats{
+		MustSupport: issue.Coverage{Total: 4, Covered: 2},
+		Terminology: issue.Coverage{Total: 2, Covered: 2},
+	}
+
+	q := DefaultQualityScoring()
+	if got := q.score(result); got != 90 {
+		t.Errorf("score() = %v, want 90", got)
+	}
+
+	result.AddError(issue.CodeRequired, "missing", "Patient.name")
+	result.AddWarning(issue.CodeValue, "unknown", "Patient.gender")
+	if got := q.score(result); got != 72.5 {
+		t.Errorf("score() with issues = %v, want 72.5", got)
+	}
+
+	q = QualityScoring{ErrorPenalty: 200}
+	if got := q.score(result); got != 0 {
+		t.Errorf("score() with saturated penalty = %v, want 0", got)
+	}
+}
+
+func TestMustSupportCoverage(t *testing.T) {
+	sd := &registry.StructureDefinition{Snapshot: &registry.Snapshot{Element: []registry.ElementDefinition{
+		{ID: "Observation", Path: "Observation", MustSupport: true},
+		{ID: "Observation.status", Path: "Observation.status", MustSupport: true},
+		{ID: "Observation.category", Path: "Observation.category", MustSupport: true},
+		{ID: "Observation.category:VSCat", Path: "Observation.category", MustSupport: true},
+		{ID: "Observation.value[x]", Path: "Observation.value[x]", MustSupport: true},
+		{ID: "Observation.value[x].unit", Path: "Observation.value[x].unit", MustSupport: true},
+		{ID: "Observation.component.code", Path: "Observation.component.code", MustSupport: true},
+		{ID: "Observation.note", Path: "Observation.note"},
+	}}}
+	data := map[string]any{
+		"resourceType":  "Observation",
+		"_status":       map[string]any{"extension": []any{}},
+		"valueQuantity": map[string]any{"value": 1},
+	}
+
+	// component.code is left out: no component is present
+	got := mustSupportCoverage(sd, data)
+	if want := (issue.Coverage{Total: 4, Covered: 2}); got != want {
+		t.Errorf("mustSupportCoverage() = %+v, want %+v", got, want)
+	}
+}
@@ -2,8 +2,14 @@ package validator
 
 import (
 	"context"
+	"encoding/json"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/reference"
 )
 
 func TestReferenceValidation(t *testing.T) {
@@ -180,3 +186,133 @@ func TestInvalidUUIDFormats(t *testing.T) {
 		t.Errorf("Expected %d 'not in bundle' warnings, got %d", expectedNotInBundle, notInBundleCount)
 	}
 }
+
+// targetProfileDefinitions define an Observation profile whose performer must
+// be an Organization with a name.
+var targetProfileDefinitions = [][]byte{
+	[]byte(`{
+		"resourceType": "StructureDefinition", "url": "http://example.org/fhir/StructureDefinition/named-organization",
+		"name": "NamedOrganization", "status": "active", "kind": "resource", "abstract": false, "type": "Organization",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Organization", "derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Organization", "path": "Organization", "min": 0, "max": "*"},
+			{"id": "Organization.id", "path": "Organization.id", "min": 0, "max": "1", "type": [{"code": "id"}]},
+			{"id": "Organization.name", "path": "Organization.name", "min": 1, "max": "1", "type": [{"code": "string"}]}
+		]}
+	}`),
+	[]byte(`{
+		"resourceType": "StructureDefinition", "url": "http://example.org/fhir/StructureDefinition/org-observation",
+		"name": "OrgObservation", "status": "active", "kind": "resource", "abstract": false, "type": "Observation",
+		"baseDefinition": "http://hl7.org/fhir/StructureDefinition/Observation", "derivation": "constraint",
+		"snapshot": {"element": [
+			{"id": "Observation", "path": "Observation", "min": 0, "max": "*"},
+			{"id": "Observation.performer", "path": "Observation.performer", "min": 0, "max": "*",
+				"type": [{"code": "Reference", "targetProfile": [
+					"http://example.org/fhir/StructureDefinition/named-organization",
+					"http://hl7.org/fhir/StructureDefinition/Practitioner"
+				]}]}
+		]}
+	}`),
+}
+
+// mapResolver resolves references from a map.
+type mapResolver map[string]map[string]any
+
+func (m mapResolver) Resolve(_ context.Context, reference string) (map[string]any, error) {
+	return m[reference], nil
+}
+
+func TestReferenceTargetProfiles(t *testing.T) {
+	v, err := New(
+		WithConformanceResources(targetProfileDefinitions),
+		WithProfile("http://example.org/fhir/StructureDefinition/org-observation"),
+		WithReferenceResolver(mapResolver{
+			"Organization/named":   {"resourceType": "Organization", "id": "named", "name": "Lab"},
+			"Organization/unnamed": {"resourceType": "Organization", "id": "unnamed"},
+		}),
+	)
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		resource  string
+		wantError bool
+	}{
+		{"contained conforming", `{"resourceType": "Observation",
+			"contained": [{"resourceType": "Organization", "id": "o", "name": "Lab"}],
+			"performer": [{"reference": "#o"}]}`, false},
+		{"contained not conforming", `{"resourceType": "Observation",
+			"contained": [{"resourceType": "Organization", "id": "o"}],
+			"performer": [{"reference": "#o"}]}`, true},
+		{"resolved conforming", `{"resourceType": "Observation", "performer": [{"reference": "Organization/named"}]}`, false},
+		{"resolved not conforming", `{"resourceType": "Observation", "performer": [{"reference": "Organization/unnamed"}]}`, true},
+		{"unresolved", `{"resourceType": "Observation", "performer": [{"reference": "Organization/other"}]}`, false},
+		{"base target profile", `{"resourceType": "Observation", "performer": [{"reference": "Practitioner/1"}]}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.ValidateJSON(context.Background(), tt.resource)
+			if err != nil {
+				t.Fatalf("ValidateJSON() error: %v", err)
+			}
+			var failed []issue.Issue
+			for _, iss := range result.Issues {
+				if iss.MessageID == string(issue.DiagReferenceTargetProfile) {
+					failed = append(failed, iss)
+				}
+			}
+			if (len(failed) > 0) != tt.wantError {
+				t.Errorf("target profile issues = %v, want error %v", failed, tt.wantError)
+			}
+			if len(failed) > 0 && failed[0].Expression[0] != "Observation.performer[0].reference" {
+				t.Errorf("issue path = %v", failed[0].Expression)
+			}
+		})
+	}
+}
+
+func TestReferenceSource(t *testing.T) {
+	v, err := New(
+		WithConformanceResources(targetProfileDefinitions),
+		WithProfile("http://example.org/fhir/StructureDefinition/org-observation"),
+		WithReferenceResolution(reference.ResolveLocal),
+		WithReferenceSource(map[string]json.RawMessage{
+			"Organization/named":   json.RawMessage(`{"resourceType": "Organization", "id": "named", "name": "Lab"}`),
+			"Organization/unnamed": json.RawMessage(`{"resourceType": "Organization", "id": "unnamed"}`),
+		}),
+	)
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		ref    string
+		wantID issue.DiagnosticID
+	}{
+		{"conforming", "Organization/named", ""},
+		{"not conforming", "Organization/unnamed", issue.DiagReferenceTargetProfile},
+		{"missing", "Organization/other", issue.DiagReferenceNotResolved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.ValidateJSON(context.Background(),
+				`{"resourceType": "Observation", "performer": [{"reference": "`+tt.ref+`"}]}`)
+			if err != nil {
+				t.Fatalf("ValidateJSON() error: %v", err)
+			}
+			var ids []string
+			for _, iss := range result.Issues {
+				if strings.HasPrefix(iss.MessageID, "REFERENCE_") {
+					ids = append(ids, iss.MessageID)
+				}
+			}
+			if want := string(tt.wantID); (want == "" && len(ids) > 0) || (want != "" && !slices.Contains(ids, want)) {
+				t.Errorf("reference issues = %v, want %q", ids, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// ResponseBundle is a transaction-response or batch-response Bundle, ready to
+// be encoded as JSON.
+type ResponseBundle struct {
+	ResourceType string          `json:"resourceType"`
+	Type         string          `json:"type"`
+	Entry        []ResponseEntry `json:"entry,omitempty"`
+}
+
+// ResponseEntry is a Bundle.entry of a ResponseBundle.
+type ResponseEntry struct {
+	FullURL  string        `json:"fullUrl,omitempty"`
+	Response EntryResponse `json:"response"`
+}
+
+// EntryResponse is a Bundle.entry.response.
+type EntryResponse struct {
+	Status  string                  `json:"status"`
+	Outcome *issue.OperationOutcome `json:"outcome,omitempty"`
+}
+
+// Status returns the HTTP status a server answers the entry with:
+// 400 Bad Request when the resource cannot be processed (a fatal issue, or
+// an invalid structure), 422 Unprocessable Entity when it breaks other
+// rules, and 200 OK otherwise.
+func (e *EntryResult) Status() int {
+	status := http.StatusOK
+	for _, iss := range e.Result.Issues {
+		switch {
+		case iss.Severity == issue.SeverityFatal, iss.Severity == issue.SeverityError && iss.Code == issue.CodeStructure:
+			return http.StatusBadRequest
+		case iss.Severity == issue.SeverityError:
+			status = http.StatusUnprocessableEntity
+		}
+	}
+	return status
+}
+
+// Response converts the result to the response Bundle of a transaction, or
+// of a batch when the validated Bundle is a batch: one entry per validated
+// entry, with its Status and, when it has issues, an OperationOutcome.
+// Entries keep their fullUrl.
+//
+// A transaction is atomic: servers that reject a whole transaction when any
+// entry fails answer with an OperationOutcome instead, e.g., from
+// Result.OperationOutcome().
+func (b *BundleResult) Response() *ResponseBundle {
+	rb := &ResponseBundle{ResourceType: "Bundle", Type: "transaction-response", Entry: make([]ResponseEntry, len(b.Entries))}
+	if b.Type == "batch" {
+		rb.Type = "batch-response"
+	}
+	for i := range b.Entries {
+		e := &b.Entries[i]
+		status := e.Status()
+		rb.Entry[i] = ResponseEntry{
+			FullURL:  e.FullURL,
+			Response: EntryResponse{Status: strconv.Itoa(status) + " " + http.StatusText(status)},
+		}
+		if len(e.Result.Issues) > 0 {
+			rb.Entry[i].Response.Outcome = e.OperationOutcome()
+		}
+	}
+	return rb
+}
@@ -0,0 +1,43 @@
+package validator
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBundleResultResponse(t *testing.T) {
+	v := getSharedValidator(t)
+
+	bundle := []byte(`{"resourceType": "Bundle", "type": "batch", "entry": [
+		{"fullUrl": "urn:uuid:a", "resource": {"resourceType": "Patient", "gender": "female"},
+			"request": {"method": "POST", "url": "Patient"}},
+		{"fullUrl": "urn:uuid:b", "resource": {"resourceType": "Patient", "gender": "unknown-value"},
+			"request": {"method": "POST", "url": "Patient"}},
+		{"fullUrl": "urn:uuid:c", "resource": {"resourceType": "Patient", "unknownElement": true},
+			"request": {"method": "POST", "url": "Patient"}}
+	]}`)
+	result, err := v.ValidateBundle(context.Background(), bundle)
+	if err != nil {
+		t.Fatalf("ValidateBundle() error: %v", err)
+	}
+
+	response := result.Response()
+	if response.ResourceType != "Bundle" || response.Type != "batch-response" {
+		t.Errorf("Response() = %s %s, want a batch-response Bundle", response.ResourceType, response.Type)
+	}
+	want := []string{"200 OK", "422 Unprocessable Entity", "400 Bad Request"}
+	if len(response.Entry) != len(want) {
+		t.Fatalf("len(Entry) = %d, want %d", len(response.Entry), len(want))
+	}
+	for i, entry := range response.Entry {
+		if entry.Response.Status != want[i] {
+			t.Errorf("Entry[%d].Response.Status = %q, want %q", i, entry.Response.Status, want[i])
+		}
+		if entry.FullURL != result.Entries[i].FullURL {
+			t.Errorf("Entry[%d].FullURL = %q, want %q", i, entry.FullURL, result.Entries[i].FullURL)
+		}
+		if i > 0 && (entry.Response.Outcome == nil || len(entry.Response.Outcome.Issue) == 0) {
+			t.Errorf("Entry[%d] has no OperationOutcome", i)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofhir/validator/pkg/extension"
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/location"
+	"github.com/gofhir/validator/pkg/phase"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/strictjson"
+)
+
+// crossCuttingElements are top-level elements whose changes can affect
+// rules anywhere in the resource, so Revalidate validates in full.
+var crossCuttingElements = map[string]bool{
+	"resourceType": true,
+	"meta":         true, // profiles
+	"contained":    true, // dom-2 to dom-5 and local references
+	"text":         true, // narrative
+}
+
+// revalidationScope is a subtree to revalidate after a change.
+type revalidationScope struct {
+	segments  []pathSegment
+	path      string
+	topName   string   // JSON name of the top-level element containing the change
+	baseName  string   // SD name of that element, without [x]
+	rootLevel bool     // the scope is a whole top-level element
+	drop      []string // paths whose previous issues are replaced
+}
+
+// Revalidate validates a patched resource by reusing the result of
+// validating it before the patch. ChangedPaths are the element paths the
+// patch touched (see the patch package, which derives them from JSON Patch
+// and FHIRPath Patch documents). Previous issues outside the changed
+// subtrees are kept; the changed subtrees, taken as the parent element of
+// each changed path, are revalidated with the structural, cardinality,
+// primitive and binding phases, and issues inside them from the extension,
+// reference, fixed/pattern, identifier and datatype phases are recomputed.
+//
+// Changes that can affect rules elsewhere in the resource fall back to full
+// validation: changes to the resource root, meta, contained resources or
+// the narrative, to sliced elements, or to elements named by the profile's
+// resource-level invariants, as well as Bundles and Subscriptions, validators
+// with sanity checks, audit or business rules, an actor or phase plugins for
+// the resource type, resources of another FHIR version (see
+// WithSourceVersion), and previous results that are missing or incomplete.
+// Previous must come from a validator with the same configuration and
+// options.
+func (v *Validator) Revalidate(ctx context.Context, resource []byte, previous *issue.Result, changedPaths []string, opts ...ValidateOption) (*issue.Result, error) {
+	startTime := time.Now()
+
+	var vc validateConfig
+	for _, opt := range opts {
+		opt(&vc)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	phases, err := v.callPhases(&vc)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceVersion, err := v.sourceVersion(&vc)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous == nil || previous.Stats == nil || len(previous.Stats.IncompletePhases) > 0 || sourceVersion != "" ||
+		v.sanityValidator != nil || v.auditValidator != nil || v.rulesValidator != nil || v.obligationValidator != nil {
+		return v.Validate(ctx, resource, opts...)
+	}
+
+	data, err := decodeResource(resource)
+	if err != nil || !strictjson.Check(resource, issue.NewResult()) {
+		return v.Validate(ctx, resource, opts...)
+	}
+	resourceType, _ := data["resourceType"].(string)
+	if resourceType == "" || resourceType == "Bundle" || resourceType == "Subscription" || resourceType != previous.Stats.ResourceType {
+		return v.Validate(ctx, resource, opts...)
+	}
+	// Plugins may check any part of the resource
+	for i := range v.config.PhasePlugins {
+		if v.config.PhasePlugins[i].Applies(resourceType) {
+			return v.Validate(ctx, resource, opts...)
+		}
+	}
+
+	scopes, ok := revalidationScopes(resourceType, changedPaths)
+	if !ok {
+		return v.Validate(ctx, resource, opts...)
+	}
+
+	result := issue.NewResult()
+	stats := *previous.Stats
+	stats.SkippedPhases = slices.Clone(stats.SkippedPhases)
+	stats.ResourceSize = len(resource)
+	stats.ResourceID, _ = data["id"].(string)
+	result.Stats = &stats
+
+	// Profile warnings are already in the previous result
+	scratch := issue.NewResult()
+	scratch.Stats = &issue.Stats{}
+	profiles, err := v.elementProfiles(data, resourceType, &vc, scratch)
+	if err != nil {
+		return v.Validate(ctx, resource, opts...)
+	}
+	for _, sd := range profiles {
+		if !addChoicePaths(sd, scopes) || affectsCrossCuttingRules(sd, scopes) {
+			return v.Validate(ctx, resource, opts...)
+		}
+	}
+
+	// Keep previous issues outside the changed subtrees; their locations
+	// are recomputed against the patched resource
+	for _, iss := range previous.Issues {
+		if len(iss.Expression) > 0 && withinScopes(iss.Expression[0], scopes) {
+			continue
+		}
+		iss.Location = nil
+		result.AddIssue(iss)
+	}
+
+	fresh := issue.NewResult()
+	fresh.Stats = &issue.Stats{}
+	located := issue.NewResult()
+	located.Stats = &issue.Stats{}
+
+	// Resource-level invariants do not involve the changed elements
+	subtreePhases := phases.Without(phase.Constraints)
+	for _, sd := range profiles {
+		for i := range scopes {
+			scope := &scopes[i]
+			if scope.rootLevel {
+				v.runPhase(ctx, phases, phase.Cardinality, fresh, func(_ context.Context, r *issue.Result) {
+					countResult := v.cardValidator.ValidateChildCount(data, sd, sd.Type, scope.baseName, resourceType)
+					r.Merge(countResult)
+					issue.ReleaseResult(countResult)
+				})
+			}
+
+			target, err := v.resolveElement(data, sd, scope.segments)
+			if errors.Is(err, ErrElementNotFound) {
+				continue // Removed by the patch
+			}
+			if err != nil {
+				return v.Validate(ctx, resource, opts...)
+			}
+			if !v.validateElementAgainstProfile(ctx, subtreePhases, target, fresh) {
+				return nil, ctx.Err()
+			}
+		}
+
+		// Phases without a subtree entry point run on the whole resource;
+		// only their issues inside the changed subtrees are used
+		if v.config.DisableFastPath || extension.HasExtensions(resource) {
+			v.runPhase(ctx, phases, phase.Extensions, located, func(_ context.Context, r *issue.Result) {
+				v.extValidator.ValidateData(data, sd, r)
+			})
+		}
+		v.runPhase(ctx, phases, phase.Reference, located, func(_ context.Context, r *issue.Result) {
+			v.refValidator.ValidateDataWithBundle(data, sd, nil, r)
+		})
+		v.runPhase(ctx, phases, phase.FixedPattern, located, func(_ context.Context, r *issue.Result) {
+			v.fixedPatternValidator.ValidateData(data, sd, r)
+		})
+		v.runPhase(ctx, phases, phase.Identifiers, located, func(_ context.Context, r *issue.Result) {
+			v.identifierValidator.ValidateData(data, sd, r)
+		})
+		ok := v.runPhase(ctx, phases, phase.Datatypes, located, func(_ context.Context, r *issue.Result) {
+			v.datatypeValidator.ValidateData(data, sd, r)
+		})
+		if !ok {
+			return nil, ctx.Err()
+		}
+	}
+	v.primValidator.ValidateDecimalPrecision(resource, resourceType, located)
+
+	for _, iss := range located.Issues {
+		if len(iss.Expression) > 0 && withinScopes(iss.Expression[0], scopes) {
+			fresh.AddIssue(iss)
+		}
+	}
+	result.Merge(fresh)
+
+	result.Stats.Duration = time.Since(startTime).Nanoseconds()
+
+	result.EnrichLocations(func(expr string) *issue.Location {
+		if loc := location.Find(resource, expr); loc != nil {
+			return &issue.Location{Line: loc.Line, Column: loc.Column}
+		}
+		return nil
+	})
+
+	v.applyIssueRules(result)
+	if !v.config.RawIssues {
+		result.Normalize()
+	}
+	if v.config.Locale != "" {
+		result.Localize(v.config.Locale)
+	}
+	return result, nil
+}
+
+// revalidationScopes returns the subtrees to revalidate for the changed
+// paths: the parent of each path, or the whole top-level element for
+// changes directly under the root. It returns false when a path is invalid
+// or changes the root or a cross-cutting element.
+func revalidationScopes(resourceType string, changedPaths []string) ([]revalidationScope, bool) {
+	var scopes []revalidationScope
+	for _, p := range changedPaths {
+		segments, err := splitElementPath(p)
+		if err != nil || segments[0].name != resourceType || segments[0].index >= 0 || len(segments) < 2 {
+			return nil, false
+		}
+		if crossCuttingElements[segments[1].name] {
+			return nil, false
+		}
+
+		scope := revalidationScope{topName: segments[1].name}
+		if len(segments) == 2 {
+			scope.segments = []pathSegment{segments[0], {name: segments[1].name, index: -1}}
+			scope.rootLevel = true
+		} else {
+			scope.segments = segments[:len(segments)-1]
+		}
+		scope.path = joinElementPath(scope.segments)
+		scope.drop = []string{scope.path}
+		scopes = append(scopes, scope)
+	}
+
+	// Drop scopes inside other scopes
+	slices.SortFunc(scopes, func(a, b revalidationScope) int { return len(a.segments) - len(b.segments) })
+	var outer []revalidationScope
+	for _, s := range scopes {
+		if !withinScopes(s.path, outer) {
+			outer = append(outer, s)
+		}
+	}
+	return outer, true
+}
+
+// addChoicePaths resolves the SD names of the scopes' top-level elements and
+// adds the SD form of root-level choice elements (e.g., "Patient.deceased[x]"
+// for "Patient.deceasedBoolean"), which cardinality issues use, to the paths
+// whose issues are replaced. It returns false if a top-level element is not
+// defined by sd.
+func addChoicePaths(sd *registry.StructureDefinition, scopes []revalidationScope) bool {
+	for i := range scopes {
+		elemDef, _ := findPathElement(sd, sd.Type, scopes[i].topName)
+		if elemDef == nil {
+			return false
+		}
+		scopes[i].baseName = strings.TrimSuffix(elemDef.Path[len(sd.Type)+1:], "[x]")
+		if scopes[i].rootLevel {
+			scopes[i].drop = append(scopes[i].drop[:1], elemDef.Path)
+		}
+	}
+	return true
+}
+
+// affectsCrossCuttingRules reports whether a change to the scopes can affect
+// slicing or a resource-level invariant of sd.
+func affectsCrossCuttingRules(sd *registry.StructureDefinition, scopes []revalidationScope) bool {
+	for _, scope := range scopes {
+		elemDef, _ := findPathElement(sd, sd.Type, scope.topName)
+		topPath := elemDef.Path
+
+		for i := range sd.Snapshot.Element {
+			elem := &sd.Snapshot.Element[i]
+			if (elem.Slicing != nil || elem.SliceName != nil) && pathWithin(elem.Path, topPath) {
+				return true
+			}
+			if elem.Path != sd.Type {
+				continue
+			}
+			for _, c := range elem.Constraint {
+				if mentionsElement(c.Expression, scope.baseName) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// mentionsElement reports whether a resource-level FHIRPath expression
+// refers to the top-level element name: as the first step of a path, or
+// after %resource, %rootResource or $this.
+func mentionsElement(expr, name string) bool {
+	isIdent := func(c byte) bool {
+		return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	for i := 0; i+len(name) <= len(expr); {
+		j := strings.Index(expr[i:], name)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(name)
+		i = start + 1
+		if end < len(expr) && isIdent(expr[end]) {
+			continue
+		}
+		before := expr[:start]
+		if before == "" || !isIdent(before[len(before)-1]) && before[len(before)-1] != '.' {
+			return true
+		}
+		for _, root := range []string{"%resource.", "%rootResource.", "$this."} {
+			if strings.HasSuffix(before, root) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withinScopes reports whether path is inside one of the scopes' replaced
+// paths.
+func withinScopes(path string, scopes []revalidationScope) bool {
+	for _, s := range scopes {
+		for _, prefix := range s.drop {
+			if pathWithin(path, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathWithin reports whether path is prefix or one of its descendants.
+func pathWithin(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest := path[len(prefix):]
+	return rest == "" || rest[0] == '.' || rest[0] == '['
+}
+
+// joinElementPath is the inverse of splitElementPath.
+func joinElementPath(segments []pathSegment) string {
+	var b strings.Builder
+	for i, seg := range segments {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg.name)
+		if seg.index >= 0 {
+			b.WriteString("[" + strconv.Itoa(seg.index) + "]")
+		}
+	}
+	return b.String()
+}
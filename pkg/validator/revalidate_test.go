@@ -0,0 +1,215 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/patch"
+)
+
+const revalidateTestPatient = `{
+  "resourceType": "Patient",
+  "gender": "bogus",
+  "birthDate": "1974-12-25",
+  "name": [{"family": "Chalmers", "given": ["Peter", "James"]}],
+  "contact": [{"telecom": [{"system": "phone", "value": "555"}]}],
+  "photo": [{"contentType": "notamime", "title": "Portrait"}]
+}`
+
+// applyJSONPatch applies the replace, add and remove operations used by the
+// tests, with object keys and array indexes only.
+func applyJSONPatch(t *testing.T, resource, patchDoc string) []byte {
+	t.Helper()
+	var data any
+	var ops []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(resource), &data); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(patchDoc), &ops); err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range ops {
+		paths, err := patch.JSONPatchPaths("Patient", []byte(`[{"op":"remove","path":"`+op.Path+`"}]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		segments, err := splitElementPath(paths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var value any
+		if op.Op != "remove" {
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data = setPath(data, segments[1:], op.Op, value)
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func setPath(node any, segments []pathSegment, op string, value any) any {
+	m := node.(map[string]any)
+	seg := segments[0]
+	if seg.index < 0 && len(segments) == 1 {
+		if op == "remove" {
+			delete(m, seg.name)
+		} else {
+			m[seg.name] = value
+		}
+		return m
+	}
+	if seg.index < 0 {
+		m[seg.name] = setPath(m[seg.name], segments[1:], op, value)
+		return m
+	}
+	list := m[seg.name].([]any)
+	switch {
+	case len(segments) > 1:
+		list[seg.index] = setPath(list[seg.index], segments[1:], op, value)
+	case op == "remove":
+		list = slices.Delete(list, seg.index, seg.index+1)
+	case op == "add":
+		list = slices.Insert(list, seg.index, value)
+	default:
+		list[seg.index] = value
+	}
+	m[seg.name] = list
+	return m
+}
+
+func issueKeys(r *issue.Result) []string {
+	var keys []string
+	for _, iss := range r.Issues {
+		key := string(iss.Severity) + "|" + iss.Diagnostics
+		if len(iss.Expression) > 0 {
+			key += "|" + iss.Expression[0]
+		}
+		if iss.Location != nil {
+			key += "|" + strconv.Itoa(iss.Location.Line)
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestRevalidate(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+
+	previous, err := v.Validate(ctx, []byte(revalidateTestPatient))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	tests := map[string]string{
+		"fix a code":             `[{"op": "replace", "path": "/gender", "value": "male"}]`,
+		"break a nested value":   `[{"op": "replace", "path": "/name/0/given/1", "value": 7}]`,
+		"add an unknown element": `[{"op": "add", "path": "/name/0/nickname", "value": "Pete"}]`,
+		"remove an element":      `[{"op": "remove", "path": "/birthDate"}]`,
+		"insert a list item":     `[{"op": "add", "path": "/name/0", "value": {"family": ["X"]}}]`,
+		"change a backbone item": `[{"op": "add", "path": "/contact/0/gender", "value": "female"}]`,
+		"meta fallback":          `[{"op": "add", "path": "/meta", "value": {"versionId": "2"}}]`,
+		"change an attachment":   `[{"op": "replace", "path": "/photo/0/title", "value": "Profile"}]`,
+	}
+	for name, patchDoc := range tests {
+		t.Run(name, func(t *testing.T) {
+			patched := applyJSONPatch(t, revalidateTestPatient, patchDoc)
+			changed, err := patch.JSONPatchPaths("Patient", []byte(patchDoc))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := v.Revalidate(ctx, patched, previous, changed)
+			if err != nil {
+				t.Fatalf("Revalidate() error: %v", err)
+			}
+			want, err := v.Validate(ctx, patched)
+			if err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+			if g, w := issueKeys(got), issueKeys(want); !slices.Equal(g, w) {
+				t.Errorf("Revalidate() issues differ from full validation\ngot:  %q\nwant: %q", g, w)
+			}
+		})
+	}
+}
+
+func TestRevalidateReusesPreviousIssues(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+
+	previous, err := v.Validate(ctx, []byte(revalidateTestPatient))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	// A marker issue only a reused result can contain
+	previous.AddWarning(issue.CodeBusinessRule, "cached", "Patient.birthDate")
+
+	patched := applyJSONPatch(t, revalidateTestPatient, `[{"op": "replace", "path": "/name/0/family", "value": "Smith"}]`)
+	hasMarker := func(r *issue.Result) bool {
+		return slices.ContainsFunc(r.Issues, func(i issue.Issue) bool { return i.Diagnostics == "cached" })
+	}
+
+	got, err := v.Revalidate(ctx, patched, previous, []string{"Patient.name[0].family"})
+	if err != nil {
+		t.Fatalf("Revalidate() error: %v", err)
+	}
+	if !hasMarker(got) {
+		t.Error("issues outside the changed subtree were not reused")
+	}
+
+	got, err = v.Revalidate(ctx, patched, previous, []string{"Patient.birthDate"})
+	if err != nil {
+		t.Fatalf("Revalidate() error: %v", err)
+	}
+	if hasMarker(got) {
+		t.Error("issues inside the changed subtree were reused")
+	}
+
+	for _, changed := range [][]string{{"Patient"}, {"Patient.text.div"}, {"not a path"}} {
+		got, err := v.Revalidate(ctx, patched, previous, changed)
+		if err != nil {
+			t.Fatalf("Revalidate(%q) error: %v", changed, err)
+		}
+		if hasMarker(got) {
+			t.Errorf("Revalidate(%q) should fall back to full validation", changed)
+		}
+	}
+
+	if got, err := v.Revalidate(ctx, patched, nil, nil); err != nil || got == nil {
+		t.Errorf("Revalidate() without a previous result = %v, %v", got, err)
+	}
+}
+
+func TestMentionsElement(t *testing.T) {
+	tests := []struct {
+		expr, name string
+		want       bool
+	}{
+		{"contact.name.exists() or contact.telecom.exists()", "contact", true},
+		{"contact.name.exists() or contact.telecom.exists()", "name", false},
+		{"%resource.name.exists()", "name", true},
+		{"names.exists()", "name", false},
+		{"name.exists()", "name", true},
+		{"(name | other).exists()", "name", true},
+	}
+	for _, tt := range tests {
+		if got := mentionsElement(tt.expr, tt.name); got != tt.want {
+			t.Errorf("mentionsElement(%q, %q) = %v, want %v", tt.expr, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package validator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/phase"
+)
+
+func TestBusinessRules(t *testing.T) {
+	if _, err := New(WithBusinessRules(strings.NewReader("rule r1 on Coverage\n  require subscriberId.where("))); err == nil ||
+		!strings.Contains(err.Error(), "invalid business rules: rules 1: line 2") {
+		t.Errorf("New() error = %v, want the invalid rule", err)
+	}
+
+	v, err := New(WithBusinessRules(strings.NewReader(`
+rule cov-subscriber on Coverage
+  when relationship.coding.where(code = 'self').empty()
+  require subscriberId
+  message subscriberId is required unless the beneficiary is the subscriber
+`)))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	coverage := []byte(`{
+		"resourceType": "Coverage",
+		"status": "active",
+		"relationship": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/subscriber-relationship", "code": "spouse"}]},
+		"beneficiary": {"reference": "Patient/1"},
+		"payor": [{"reference": "Organization/1"}]
+	}`)
+	ctx := context.Background()
+
+	result, err := v.Validate(ctx, coverage)
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if !hasDiagnostic(result, "subscriberId is required unless the beneficiary is the subscriber (rule cov-subscriber)") {
+		t.Errorf("business rule not reported: %v", result.Issues)
+	}
+
+	// Clones share the rules; the phase can be skipped like any other
+	result, err = v.Clone().Validate(ctx, coverage, ValidateWithoutPhases(phase.BusinessRules))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if hasDiagnostic(result, "rule cov-subscriber") {
+		t.Errorf("skipped business rule reported: %v", result.Issues)
+	}
+}
@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"context"
+	"slices"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/uniqueness"
+)
+
+// Session validates related resources one by one, such as the lines of an
+// NDJSON bulk export, and checks them against each other: a resource that
+// shares a business key with one validated earlier in the session (see
+// WithUniquenessChecks for the rules) is reported in the uniqueness phase.
+// Entries of Bundles validated in a session take part as well.
+//
+// A Session is safe for concurrent use; the keys it remembers grow with the
+// number of resources validated.
+type Session struct {
+	v      *Validator
+	unique *uniqueness.Checker
+}
+
+// NewSession starts a session of validations with v.
+func (v *Validator) NewSession() *Session {
+	return &Session{v: v, unique: uniqueness.New(v.config.UniquenessRules...)}
+}
+
+// Validate validates a resource like Validator.Validate and checks it
+// against the resources validated before in the session. Label names the
+// resource in the issues of later resources that repeat its keys (e.g.,
+// "patients.ndjson:12"); "" names it by its type and id.
+func (s *Session) Validate(ctx context.Context, label string, resource []byte, opts ...ValidateOption) (*issue.Result, error) {
+	return s.v.Validate(ctx, resource, append(slices.Clip(opts), func(c *validateConfig) {
+		c.session = s
+		c.label = label
+	})...)
+}
@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestSession(t *testing.T) {
+	v, err := New()
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	ctx := context.Background()
+	s := v.NewSession()
+
+	lines := []string{
+		`{"resourceType":"Patient","id":"a","identifier":[{"system":"http://hospital.example/mrn","value":"1"}]}`,
+		`{"resourceType":"Patient","id":"b","identifier":[{"system":"http://hospital.example/mrn","value":"2"}]}`,
+		`{"resourceType":"Patient","id":"c","identifier":[{"system":"http://hospital.example/mrn","value":"1"}]}`,
+	}
+	var results []*issue.Result
+	for i, line := range lines {
+		result, err := s.Validate(ctx, fmt.Sprintf("patients.ndjson:%d", i+1), []byte(line))
+		if err != nil {
+			t.Fatalf("Validate() error: %v", err)
+		}
+		results = append(results, result)
+	}
+	if hasDiagnostic(results[1], "already used") {
+		t.Errorf("distinct identifiers reported: %v", results[1].Issues)
+	}
+	if !hasDiagnostic(results[2], "already used by patients.ndjson:1") {
+		t.Errorf("duplicate identifier not reported: %v", results[2].Issues)
+	}
+
+	// Outside a session, resources are not compared
+	result, err := v.Validate(ctx, []byte(lines[2]))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if hasDiagnostic(result, "already used") {
+		t.Errorf("duplicate reported outside a session: %v", result.Issues)
+	}
+}
+
+func TestUniquenessChecks(t *testing.T) {
+	bundle := []byte(`{"resourceType":"Bundle","type":"collection","entry":[
+		{"fullUrl":"urn:uuid:7f0b5c3e-1d2a-4c6e-9b8f-0a1b2c3d4e5f","resource":{"resourceType":"Patient","identifier":[{"system":"http://hospital.example/mrn","value":"1"}]}},
+		{"fullUrl":"urn:uuid:8a1c6d4f-2e3b-4d7f-8c9a-1b2c3d4e5f60","resource":{"resourceType":"Patient","identifier":[{"system":"http://hospital.example/mrn","value":"1"}]}}
+	]}`)
+	ctx := context.Background()
+
+	v, err := New()
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	result, err := v.Validate(ctx, bundle)
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if hasDiagnostic(result, "already used") {
+		t.Errorf("uniqueness phase should be opt-in: %v", result.Issues)
+	}
+
+	v, err = New(WithUniquenessChecks())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	br, err := v.ValidateBundle(ctx, bundle)
+	if err != nil {
+		t.Fatalf("ValidateBundle() error: %v", err)
+	}
+	if !hasDiagnostic(br.Entries[1].Result, "already used by Bundle.entry[0].resource") {
+		t.Errorf("duplicate identifier not reported on entry 1: %v", br.Result.Issues)
+	}
+}
@@ -0,0 +1,43 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// TestRootProfileSlicing checks that the slicing phase applies the slices of
+// the profile a resource is validated against to non-extension elements.
+func TestRootProfileSlicing(t *testing.T) {
+	v, err := New(WithProfile("http://hl7.org/fhir/StructureDefinition/bp"))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	// Blood pressure without the required diastolic component slice
+	resource := `{"resourceType": "Observation", "status": "final",
+		"category": [{"coding": [{"system": "http://terminology.hl7.org/CodeSystem/observation-category", "code": "vital-signs"}]}],
+		"code": {"coding": [{"system": "http://loinc.org", "code": "85354-9"}]},
+		"subject": {"reference": "Patient/example"},
+		"effectiveDateTime": "2024-03-10T09:30:00Z",
+		"component": [{
+			"code": {"coding": [{"system": "http://loinc.org", "code": "8480-6"}]},
+			"valueQuantity": {"value": 120, "unit": "mmHg", "system": "http://unitsofmeasure.org", "code": "mm[Hg]"}
+		}]}`
+
+	result, err := v.ValidateJSON(context.Background(), resource)
+	if err != nil {
+		t.Fatalf("ValidateJSON() error: %v", err)
+	}
+
+	found := false
+	for _, iss := range result.Issues {
+		if iss.MessageID == string(issue.DiagSlicingCardinalityMin) && len(iss.Expression) > 0 && iss.Expression[0] == "Observation.component:DiastolicBP" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("missing %s on Observation.component:DiastolicBP, issues: %v", issue.DiagSlicingCardinalityMin, result.Issues)
+	}
+}
@@ -0,0 +1,31 @@
+package validator
+
+import (
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/walker"
+)
+
+// attributeElements attributes the issues from index from onwards, reported
+// while validating against sd, to the ElementDefinition at their path when
+// their phase did not name one: the element of sd, or of the type definition
+// for elements sd does not constrain. Issues whose path does not resolve
+// (e.g., inside contained resources) keep the attribution their phase gave.
+func (v *Validator) attributeElements(sd *registry.StructureDefinition, result *issue.Result, from int) {
+	resolved := make(map[string]*walker.ResolvedElement)
+	for i := from; i < len(result.Issues); i++ {
+		iss := &result.Issues[i]
+		if len(iss.Expression) == 0 || (iss.Source != nil && iss.Source.ElementID != "") {
+			continue
+		}
+		path := iss.Expression[0]
+		elem, ok := resolved[path]
+		if !ok {
+			elem, _ = v.walker.ResolvePath(sd.URL, path)
+			resolved[path] = elem
+		}
+		if elem != nil {
+			iss.Attribute(issue.Source{StructureDefinition: elem.Source, ElementID: elem.Element.ID})
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestIssueSource(t *testing.T) {
+	v, err := New()
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	result, err := v.Validate(context.Background(), []byte(`{
+		"resourceType": "Patient",
+		"gender": "unknown-gender",
+		"name": [{"given": [1]}]
+	}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	const patient = "http://hl7.org/fhir/StructureDefinition/Patient"
+	want := map[string]issue.Source{
+		"Patient.gender": {
+			Phase:               "binding",
+			StructureDefinition: patient,
+			ElementID:           "Patient.gender",
+			ValueSet:            "http://hl7.org/fhir/ValueSet/administrative-gender|4.0.1",
+		},
+		"Patient.name[0].given[0]": {
+			Phase:               "primitive",
+			StructureDefinition: "http://hl7.org/fhir/StructureDefinition/HumanName",
+			ElementID:           "HumanName.given",
+		},
+		"Patient": {
+			Phase:               "constraint",
+			StructureDefinition: "http://hl7.org/fhir/StructureDefinition/DomainResource",
+			ElementID:           "DomainResource",
+			Constraint:          "dom-6",
+		},
+	}
+	for _, iss := range result.Issues {
+		if len(iss.Expression) == 0 || iss.Source == nil {
+			continue
+		}
+		if w, ok := want[iss.Expression[0]]; ok && *iss.Source == w {
+			delete(want, iss.Expression[0])
+		}
+	}
+	for path, w := range want {
+		t.Errorf("no issue at %s with source %+v: %+v", path, w, result.Issues)
+	}
+}
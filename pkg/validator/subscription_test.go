@@ -0,0 +1,43 @@
+package validator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+func TestSubscriptionValidation(t *testing.T) {
+	v, err := New(WithConformanceResources([][]byte{
+		[]byte(`{"resourceType": "SearchParameter", "id": "Observation-code", "url": "http://example.org/SearchParameter/Observation-code",
+			"name": "code", "status": "active", "description": "Code", "code": "code", "base": ["Observation"], "type": "token", "expression": "Observation.code"}`),
+	}))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+
+	resource := `{"resourceType": "Subscription", "status": "requested", "reason": "Monitor results",
+		"criteria": "Observation?code=1234-5&colour=red",
+		"channel": {"type": "email", "endpoint": "https://example.org/hook", "payload": "application/json"}}`
+
+	result, err := v.ValidateJSON(context.Background(), resource)
+	if err != nil {
+		t.Fatalf("ValidateJSON() error: %v", err)
+	}
+
+	var got []string
+	for _, iss := range result.Issues {
+		if strings.HasPrefix(iss.MessageID, "SUBSCRIPTION_") {
+			got = append(got, iss.MessageID+"@"+iss.Expression[0])
+		}
+	}
+	want := []string{
+		string(issue.DiagSubscriptionEndpointInvalid) + "@Subscription.channel.endpoint",
+		string(issue.DiagSubscriptionPayloadInvalid) + "@Subscription.channel.payload",
+		string(issue.DiagSubscriptionUnknownParameter) + "@Subscription.criteria",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("subscription issues = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/limits"
+)
+
+// Mapper is implemented by typed resources (e.g., the structs of a FHIR
+// model library) that can convert themselves to the generic JSON
+// representation directly, which ValidateResource prefers to encoding them.
+// ToMap returns the resource as JSON would decode it: objects as
+// map[string]any, arrays as []any, numbers as float64 or json.Number.
+type Mapper interface {
+	ToMap() (map[string]any, error)
+}
+
+// ValidateMap validates a resource that is already decoded, e.g., with
+// encoding/json into a map[string]any, without re-parsing it. The map must
+// hold only JSON values (map[string]any, []any, string, bool, float64,
+// json.Number and nil); other Go values are normalized by encoding and
+// decoding the resource. The map must not be modified during validation.
+// Issues have no line and column numbers, since there is no source JSON.
+func (v *Validator) ValidateMap(ctx context.Context, data map[string]any, opts ...ValidateOption) (*issue.Result, error) {
+	if v.instruments == nil {
+		return v.validateMap(ctx, data, opts...)
+	}
+	ctx, end := v.instruments.startValidation(ctx)
+	result, err := v.validateMap(ctx, data, opts...)
+	end(result)
+	return result, err
+}
+
+// validateMap implements ValidateMap.
+func (v *Validator) validateMap(ctx context.Context, data map[string]any, opts ...ValidateOption) (*issue.Result, error) {
+	startTime := time.Now()
+
+	var vc validateConfig
+	for _, opt := range opts {
+		opt(&vc)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	phases, err := v.callPhases(&vc)
+	if err != nil {
+		return nil, err
+	}
+
+	// FHIRPath constraints and decimal precision need the JSON
+	resource, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding resource: %w", err)
+	}
+	if !isJSONValue(data) {
+		if data, err = decodeResource(resource); err != nil {
+			return nil, fmt.Errorf("decoding resource: %w", err)
+		}
+	}
+
+	result := issue.NewResult()
+	result.Stats = &issue.Stats{
+		ResourceSize: len(resource),
+	}
+
+	inputLimits := limits.Limits{
+		MaxBytes:    v.config.MaxResourceBytes,
+		MaxDepth:    v.config.MaxNestingDepth,
+		MaxElements: v.config.MaxTotalElements,
+	}
+	if inputLimits.Enabled() && !limits.Check(resource, inputLimits, result) {
+		v.applyIssueRules(result)
+		if v.config.Locale != "" {
+			result.Localize(v.config.Locale)
+		}
+		result.Stats.Duration = time.Since(startTime).Nanoseconds()
+		return result, nil
+	}
+
+	return v.validateDecoded(ctx, &vc, phases, resource, data, result, startTime, nil), nil
+}
+
+// ValidateResource validates a typed resource, such as a struct of a FHIR
+// model library. Resources implementing Mapper are validated from their
+// map with ValidateMap; others are encoded with encoding/json (honoring
+// json.Marshaler) and validated with Validate. A map[string]any is passed
+// to ValidateMap, and []byte or json.RawMessage to Validate.
+func (v *Validator) ValidateResource(ctx context.Context, resource any, opts ...ValidateOption) (*issue.Result, error) {
+	switch r := resource.(type) {
+	case map[string]any:
+		return v.ValidateMap(ctx, r, opts...)
+	case []byte:
+		return v.Validate(ctx, r, opts...)
+	case json.RawMessage:
+		return v.Validate(ctx, r, opts...)
+	case Mapper:
+		data, err := r.ToMap()
+		if err != nil {
+			return nil, fmt.Errorf("converting resource: %w", err)
+		}
+		return v.ValidateMap(ctx, data, opts...)
+	}
+	raw, err := json.Marshal(resource)
+	if err != nil {
+		return nil, fmt.Errorf("encoding resource: %w", err)
+	}
+	return v.Validate(ctx, raw, opts...)
+}
+
+// isJSONValue reports whether a value holds only the types encoding/json
+// decodes into.
+func isJSONValue(value any) bool {
+	switch val := value.(type) {
+	case map[string]any:
+		for _, item := range val {
+			if !isJSONValue(item) {
+				return false
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if !isJSONValue(item) {
+				return false
+			}
+		}
+	case string, bool, float64, json.Number, nil:
+	default:
+		return false
+	}
+	return true
+}
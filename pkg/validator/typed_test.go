@@ -0,0 +1,120 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+const typedTestPatient = `{"resourceType": "Patient", "gender": "bogus", "active": true,
+	"name": [{"family": "Chalmers", "given": ["Peter"]}],
+	"birthDate": "1974-12-25"}`
+
+// testPatient is a typed resource as a FHIR model library would define it.
+type testPatient struct {
+	ResourceType string `json:"resourceType"`
+	Gender       string `json:"gender,omitempty"`
+	Active       *bool  `json:"active,omitempty"`
+	Name         []struct {
+		Family string   `json:"family,omitempty"`
+		Given  []string `json:"given,omitempty"`
+	} `json:"name,omitempty"`
+	BirthDate string `json:"birthDate,omitempty"`
+}
+
+// mappedPatient converts itself to a map without encoding.
+type mappedPatient struct {
+	data map[string]any
+	err  error
+}
+
+func (p mappedPatient) ToMap() (map[string]any, error) {
+	return p.data, p.err
+}
+
+// issueSummary lists the message IDs and expressions of a result's issues.
+func issueSummary(result *issue.Result) []string {
+	var summary []string
+	for _, iss := range result.Issues {
+		summary = append(summary, iss.MessageID+" "+iss.Diagnostics)
+		summary = append(summary, iss.Expression...)
+	}
+	return summary
+}
+
+func TestValidateTyped(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+
+	want, err := v.Validate(ctx, []byte(typedTestPatient))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if !want.HasErrors() {
+		t.Fatalf("expected the invalid gender to be reported: %v", want.Issues)
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(typedTestPatient), &data); err != nil {
+		t.Fatal(err)
+	}
+	var typed testPatient
+	if err := json.Unmarshal([]byte(typedTestPatient), &typed); err != nil {
+		t.Fatal(err)
+	}
+	nonJSON := map[string]any{
+		"resourceType": "Patient", "gender": "bogus", "active": true,
+		"name":      []map[string]any{{"family": "Chalmers", "given": []string{"Peter"}}},
+		"birthDate": "1974-12-25",
+	}
+
+	tests := []struct {
+		name     string
+		validate func() (*issue.Result, error)
+	}{
+		{"map", func() (*issue.Result, error) { return v.ValidateMap(ctx, data) }},
+		{"map with Go values", func() (*issue.Result, error) { return v.ValidateMap(ctx, nonJSON) }},
+		{"struct", func() (*issue.Result, error) { return v.ValidateResource(ctx, typed) }},
+		{"struct pointer", func() (*issue.Result, error) { return v.ValidateResource(ctx, &typed) }},
+		{"mapper", func() (*issue.Result, error) { return v.ValidateResource(ctx, mappedPatient{data: data}) }},
+		{"raw message", func() (*issue.Result, error) {
+			return v.ValidateResource(ctx, json.RawMessage(typedTestPatient))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.validate()
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if !slices.Equal(issueSummary(got), issueSummary(want)) {
+				t.Errorf("issues = %v, want %v", issueSummary(got), issueSummary(want))
+			}
+		})
+	}
+}
+
+func TestValidateResourceErrors(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx := context.Background()
+
+	errMapper := errors.New("no map")
+	if _, err := v.ValidateResource(ctx, mappedPatient{err: errMapper}); !errors.Is(err, errMapper) {
+		t.Errorf("Mapper error = %v, want %v", err, errMapper)
+	}
+	if _, err := v.ValidateResource(ctx, map[string]any{"resourceType": "Patient", "x": make(chan int)}); err == nil {
+		t.Error("expected an error for a value encoding/json cannot encode")
+	}
+
+	result, err := v.ValidateMap(ctx, map[string]any{"active": true})
+	if err != nil {
+		t.Fatalf("ValidateMap() error: %v", err)
+	}
+	if !result.HasErrors() {
+		t.Error("expected a resource without resourceType to be invalid")
+	}
+}
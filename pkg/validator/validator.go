@@ -2,30 +2,62 @@
 package validator
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofhir/fhirpath/funcs"
 
+	"github.com/gofhir/validator/pkg/audit"
+	"github.com/gofhir/validator/pkg/authoring"
 	"github.com/gofhir/validator/pkg/binding"
+	"github.com/gofhir/validator/pkg/bundle"
+	"github.com/gofhir/validator/pkg/cache"
+	"github.com/gofhir/validator/pkg/canonical"
 	"github.com/gofhir/validator/pkg/cardinality"
 	"github.com/gofhir/validator/pkg/constraint"
+	"github.com/gofhir/validator/pkg/contained"
+	"github.com/gofhir/validator/pkg/crossversion"
+	"github.com/gofhir/validator/pkg/datatype"
 	"github.com/gofhir/validator/pkg/extension"
 	"github.com/gofhir/validator/pkg/fixedpattern"
+	"github.com/gofhir/validator/pkg/identifier"
 	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/limits"
 	"github.com/gofhir/validator/pkg/loader"
 	"github.com/gofhir/validator/pkg/location"
 	"github.com/gofhir/validator/pkg/logger"
+	"github.com/gofhir/validator/pkg/metrics"
+	"github.com/gofhir/validator/pkg/narrative"
+	"github.com/gofhir/validator/pkg/obligation"
+	"github.com/gofhir/validator/pkg/operation"
+	"github.com/gofhir/validator/pkg/phase"
 	"github.com/gofhir/validator/pkg/primitive"
 	"github.com/gofhir/validator/pkg/reference"
 	"github.com/gofhir/validator/pkg/registry"
+	"github.com/gofhir/validator/pkg/rules"
+	"github.com/gofhir/validator/pkg/sanity"
+	"github.com/gofhir/validator/pkg/searchparam"
 	"github.com/gofhir/validator/pkg/slicing"
 	"github.com/gofhir/validator/pkg/specs"
+	"github.com/gofhir/validator/pkg/strictjson"
 	"github.com/gofhir/validator/pkg/structural"
+	"github.com/gofhir/validator/pkg/subscription"
 	"github.com/gofhir/validator/pkg/terminology"
+	"github.com/gofhir/validator/pkg/ucum"
+	"github.com/gofhir/validator/pkg/uniqueness"
+	"github.com/gofhir/validator/pkg/walker"
+	"github.com/gofhir/validator/pkg/warmset"
 )
 
 func init() {
@@ -36,6 +68,13 @@ func init() {
 }
 
 // Validator is the main FHIR resource validator.
+//
+// A Validator is safe for concurrent use by multiple goroutines: its
+// definitions are immutable after New, its phase validators keep no
+// per-call state, and their caches are synchronized. Per-call options only
+// affect their call. Resolvers, terminology providers and other callbacks
+// given as options are called concurrently too. See Clone for separate
+// caches.
 type Validator struct {
 	registry     *registry.Registry
 	termRegistry *terminology.Registry
@@ -49,9 +88,53 @@ type Validator struct {
 	bindValidator         *binding.Validator
 	extValidator          *extension.Validator
 	refValidator          *reference.Validator
+	containedValidator    *contained.Validator
+	narrativeValidator    *narrative.Validator
+	formatter             *canonical.Formatter
 	constraintValidator   *constraint.Validator
 	fixedPatternValidator *fixedpattern.Validator
 	slicingValidator      *slicing.Validator
+	bundleValidator       *bundle.Validator
+	identifierValidator   *identifier.Validator
+	datatypeValidator     *datatype.Validator
+	sanityValidator       *sanity.Validator     // nil unless SanityChecks is enabled
+	auditValidator        *audit.Validator      // nil unless AuditRules is enabled
+	obligationValidator   *obligation.Validator // nil unless an Actor is configured
+	authoringValidator    *authoring.Validator  // nil unless AuthorMode is enabled
+	rulesValidator        *rules.Validator      // nil unless BusinessRules are given
+	converter             *crossversion.Converter
+	operationValidator    *operation.Validator
+	subscriptionValidator *subscription.Validator
+	walker                *walker.Walker // Resolves issue paths for their Source; passed to phase plugins
+
+	// phases selects the phases run by default (see WithPhases)
+	phases phase.Set
+
+	// businessRules are the rules parsed from Config.BusinessRules (nil if none)
+	businessRules *rules.Set
+
+	// operations are the loaded OperationDefinitions (see ValidateParameters)
+	operations *operation.Registry
+
+	// policies are the resolved ResourceTypePolicies
+	policies map[string]*typePolicy
+
+	// globalProfiles are the ImplementationGuide global profiles by resource
+	// type (see WithIG)
+	globalProfiles map[string][]string
+
+	// budget sizes and trims the caches (nil unless MemoryBudget > 0)
+	budget *cache.Budget
+
+	// instruments records metrics and spans (nil unless Metrics or Tracer is set)
+	instruments *instruments
+
+	// usage records resolved profiles and ValueSets (nil unless UsageWindow > 0)
+	usage *warmset.Tracker
+
+	// customTypes caches the StructureDefinitions of logical models and custom
+	// resources by resourceType (only used when CustomTypes is enabled)
+	customTypes sync.Map // resourceType -> *registry.StructureDefinition
 }
 
 // PackageSpec represents an additional FHIR package to load.
@@ -62,28 +145,166 @@ type PackageSpec struct {
 
 // Config holds the validator configuration.
 type Config struct {
-	FHIRVersion          string               // e.g., "4.0.1", "4.3.0", "5.0.0"
-	Profiles             []string             // Additional profiles to validate against
-	StrictMode           bool                 // Treat warnings as errors
-	PackagePath          string               // Path to FHIR package cache
-	AdditionalPackages   []PackageSpec        // Additional packages to load (e.g., US Core)
-	PackageTgzPaths      []string             // Paths to local .tgz package files
-	PackageURLs          []string             // URLs to remote .tgz package files
-	PackageData          [][]byte             // In-memory .tgz package bytes (e.g., from //go:embed)
-	ConformanceResources [][]byte             // Individual conformance resource JSON bytes (e.g., from DB)
-	TerminologyProvider  terminology.Provider // Optional external terminology provider
+	FHIRVersion          string                // e.g., "4.0.1", "4.3.0", "5.0.0"
+	SourceVersion        string                // FHIR version of the resources, when not FHIRVersion (see WithSourceVersion)
+	Profiles             []string              // Additional profiles to validate against
+	ProfileSelection     ProfileSelection      // Which of the applicable profiles are validated (see WithProfileSelection)
+	StrictMode           bool                  // Treat warnings as errors
+	PackagePath          string                // Path to FHIR package cache
+	AdditionalPackages   []PackageSpec         // Additional packages to load (e.g., US Core)
+	ImplementationGuides []PackageSpec         // Packages whose ImplementationGuide global profiles apply (see WithIG)
+	VersionPolicy        loader.VersionPolicy  // Default version of canonicals defined by several package versions
+	CanonicalMapping     map[string]string     // Mirror base URL -> published base URL (see WithCanonicalMapping)
+	PackageTgzPaths      []string              // Paths to local .tgz package files
+	PackageURLs          []string              // URLs to remote .tgz package files
+	PackageURLChecksums  map[string]string     // Pinned SHA-256 of the .tgz, by URL (see WithChecksum)
+	PackageURLCache      string                // Cache of downloaded packages ("" = loader.DefaultURLCachePath)
+	Offline              bool                  // Load URL packages only from the cache (see WithOffline)
+	PackageData          [][]byte              // In-memory .tgz package bytes (e.g., from //go:embed)
+	ConformanceResources [][]byte              // Individual conformance resource JSON bytes (e.g., from DB)
+	TerminologyProvider  terminology.Provider  // Optional external terminology provider
+	SystemVersions       map[string]string     // Code system URL -> pinned version (see WithSystemVersion)
+	TerminologyPrefetch  int                   // Concurrent provider requests made ahead of the binding phase (0 = none)
+	UCUMService          ucum.Service          // Validates UCUM units (nil = built-in engine)
+	UnitConsistency      bool                  // Check Quantity units against profile-declared units
+	ReferenceResolution  reference.ResolveMode // Whether local references must resolve
+	ReferenceResolver    reference.Resolver    // Fetches reference targets for target profile validation (see WithReferenceResolver)
+	ReferenceSource      reference.Resolver    // Resources the caller already has, for reference resolution (see WithReferenceSource)
+	RetiredResourceTypes []string              // Resource types whose references emit a warning
+	DisableFastPath      bool                  // Always run every phase, even when a pre-scan shows it has nothing to check
+	RawIssues            bool                  // Keep issues in phase order, including duplicates (see WithRawIssues)
+	Locale               string                // Language of issue messages (see WithLocale)
+
+	// SeverityOverrides changes the severity of issues by diagnostic ID.
+	SeverityOverrides map[issue.DiagnosticID]issue.Severity
+
+	// Suppressions removes matching issues from every result.
+	Suppressions []issue.Suppression
+
+	// ResourceTypePolicies sets validation policies by resource type (see
+	// WithResourceTypePolicy).
+	ResourceTypePolicies map[string]Policy
+
+	// EntryProfiles sets the profile of Bundle entry resources by type (see
+	// WithEntryProfileMap).
+	EntryProfiles map[string]string
+
+	// PhasePlugins are custom phases run after the built-in ones (see
+	// WithPhasePlugin).
+	PhasePlugins []phase.Plugin
+
+	// Phases restricts validation to the listed phases (nil = all phases)
+	// and DisabledPhases skips phases; see WithPhases and WithDisabledPhases.
+	Phases         []phase.Name
+	DisabledPhases []phase.Name
+
+	// MaxBase64Size limits decoded base64Binary content in bytes (0 = unlimited).
+	MaxBase64Size int
+
+	// Input guards checked before a resource is parsed (0 = unlimited).
+	MaxResourceBytes int // Maximum resource size in bytes
+	MaxNestingDepth  int // Maximum nesting depth of JSON objects and arrays
+	MaxTotalElements int // Maximum number of JSON values (properties and array items)
+
+	// PhaseTimeout bounds the time spent in each validation phase (0 = unlimited).
+	PhaseTimeout time.Duration
+
+	// KnownModifierExtensions lists the modifierExtension URLs the receiver understands.
+	// Nil disables the check; see WithKnownModifierExtensions.
+	KnownModifierExtensions []string
+
+	// IdentifierValidators adds or replaces Identifier value checks by
+	// Identifier.system (see WithIdentifierValidator).
+	IdentifierValidators map[string]identifier.Func
+
+	// AllowedContentTypes limits the content types of Attachments; others
+	// are reported as warnings. Nil allows any. See WithAllowedContentTypes.
+	AllowedContentTypes []string
+
+	// CustomTypes accepts instances of logical models and custom resources
+	// whose resourceType is not a core resource type. See WithCustomTypes.
+	CustomTypes bool
+
+	// SanityChecks enables the cross-field temporal checks of the sanity
+	// phase: the rules in SanityRules (every rule if empty) except those in
+	// DisabledSanityRules.
+	SanityChecks        bool
+	SanityRules         []sanity.Rule
+	DisabledSanityRules []sanity.Rule
+
+	// QualityScoring scores each result (nil = no score); see
+	// WithQualityScoring.
+	QualityScoring *QualityScoring
+
+	// AuditRules enables the Provenance/AuditEvent integrity rule pack.
+	AuditRules bool
+
+	// BusinessRules are sources of business rules in the syntax of the rules
+	// package, read by New and evaluated in the business-rule phase.
+	BusinessRules []io.Reader
+
+	// Uniqueness enables the uniqueness phase for Bundles: entries of the
+	// same type may not share a key of UniquenessRules (uniqueness.Defaults
+	// if empty). Sessions apply the rules whether or not it is enabled.
+	Uniqueness      bool
+	UniquenessRules []uniqueness.Rule
+
+	// AuthorMode enables the authoring phase, which checks conformance
+	// resources (StructureDefinitions, SearchParameters, ValueSets and
+	// CodeSystems) beyond their structure.
+	AuthorMode bool
+
+	// Actor is the ActorDefinition canonical whose profile obligations are
+	// enforced (empty = obligations are not evaluated).
+	Actor string
+
+	// UsageWindow enables tracking of resolved profiles and ValueSets over the
+	// last UsageWindow resolutions (0 = disabled). See UsageStats and SaveWarmSet.
+	UsageWindow int
+
+	// WarmSetPath is a warm-set file whose profiles and ValueSets are pre-warmed
+	// at startup. A missing file is not an error.
+	WarmSetPath string
+
+	// LoadProgress receives construction progress events (see WithLoadProgress).
+	LoadProgress ProgressFunc
+
+	// MemoryBudget bounds the memory of the loaded definitions plus caches in
+	// bytes (0 = unbounded caches). See WithMemoryBudget.
+	MemoryBudget int64
+
+	// Metrics creates the validator's metrics (nil = no metrics).
+	Metrics metrics.Registerer
+
+	// Tracer starts spans around validations and phases (nil = no tracing).
+	Tracer metrics.Tracer
 }
 
 // Option is a functional option for configuring the validator.
 type Option func(*Config)
 
-// WithVersion sets the FHIR version.
+// WithVersion sets the FHIR version. Release names ("R4", "R4B", "R5") and
+// major.minor versions ("4.3") are accepted as aliases.
 func WithVersion(version string) Option {
 	return func(c *Config) {
 		c.FHIRVersion = version
 	}
 }
 
+// WithSourceVersion validates resources of another FHIR version: R4 (or
+// R4B) resources with an R5 validator, or R5 resources with an R4 one. They
+// are converted with the cross-version mappings of the crossversion package
+// before validation, so an R4 Encounter can be checked against an R5
+// profile during a migration. Elements that do not convert are reported;
+// other issues use the paths of the validator's version, with line and
+// column numbers from the original JSON where the element was not moved.
+// New fails for versions that cannot be converted.
+func WithSourceVersion(version string) Option {
+	return func(c *Config) {
+		c.SourceVersion = version
+	}
+}
+
 // WithProfile adds a profile URL to validate against.
 func WithProfile(profileURL string) Option {
 	return func(c *Config) {
@@ -112,6 +333,46 @@ func WithPackage(name, version string) Option {
 	}
 }
 
+// WithIG loads an implementation guide package, given as "name#version"
+// (e.g., "hl7.fhir.us.core#6.1.0"), and applies the global profiles its
+// ImplementationGuide resource declares: resources of a type listed in
+// ImplementationGuide.global are validated against the corresponding profile
+// without it being requested, as the HL7 Java validator does.
+func WithIG(spec string) Option {
+	return func(c *Config) {
+		name, version := loader.ParsePackageSpec(spec)
+		c.AdditionalPackages = append(c.AdditionalPackages, PackageSpec{Name: name, Version: version})
+		c.ImplementationGuides = append(c.ImplementationGuides, PackageSpec{Name: name, Version: version})
+	}
+}
+
+// WithVersionPolicy sets which definition an unversioned canonical URL
+// resolves to when several versions of the same package are loaded and
+// define it (e.g., us-core 3.1.1 and 6.1.0): the highest package version
+// (loader.VersionLatest, the default) or the package loaded first
+// (loader.VersionFirstLoaded). Versioned references ("url|3.1.1") always
+// resolve to the requested version.
+func WithVersionPolicy(policy loader.VersionPolicy) Option {
+	return func(c *Config) {
+		c.VersionPolicy = policy
+	}
+}
+
+// WithCanonicalMapping rewrites canonical URL prefixes when profiles,
+// ValueSets and extension definitions are resolved, mapping the base URL of an
+// internal mirror to the published one, e.g.
+// {"https://internal.example.org/fhir/": "http://hl7.org/fhir/us/core/"}.
+// The longest matching prefix applies, and only to URLs that are not loaded
+// as is. Repeated calls add to the mapping.
+func WithCanonicalMapping(mapping map[string]string) Option {
+	return func(c *Config) {
+		if c.CanonicalMapping == nil {
+			c.CanonicalMapping = make(map[string]string, len(mapping))
+		}
+		maps.Copy(c.CanonicalMapping, mapping)
+	}
+}
+
 // WithPackageTgz adds a local .tgz package file to load.
 func WithPackageTgz(path string) Option {
 	return func(c *Config) {
@@ -119,10 +380,56 @@ func WithPackageTgz(path string) Option {
 	}
 }
 
-// WithPackageURL adds a remote .tgz package URL to load.
-func WithPackageURL(url string) Option {
+// PackageURLOption configures a package loaded with WithPackageURL.
+type PackageURLOption func(*packageURLOptions)
+
+type packageURLOptions struct {
+	checksum string
+}
+
+// WithChecksum pins the hex-encoded SHA-256 of a package downloaded with
+// WithPackageURL. Other content, downloaded or cached, fails validator
+// creation; a cached copy with the pinned checksum is used without
+// contacting the server.
+func WithChecksum(sha256 string) PackageURLOption {
+	return func(o *packageURLOptions) {
+		o.checksum = sha256
+	}
+}
+
+// WithPackageURL adds a remote .tgz package URL to load. Downloads are kept
+// in a persistent cache (see WithPackageURLCache) and revalidated with the
+// server on later startups, falling back to the cached copy when the server
+// cannot be reached.
+func WithPackageURL(url string, opts ...PackageURLOption) Option {
 	return func(c *Config) {
 		c.PackageURLs = append(c.PackageURLs, url)
+		var o packageURLOptions
+		for _, opt := range opts {
+			opt(&o)
+		}
+		if o.checksum != "" {
+			if c.PackageURLChecksums == nil {
+				c.PackageURLChecksums = make(map[string]string)
+			}
+			c.PackageURLChecksums[url] = o.checksum
+		}
+	}
+}
+
+// WithPackageURLCache sets the directory where packages downloaded with
+// WithPackageURL are cached (default: loader.DefaultURLCachePath).
+func WithPackageURLCache(dir string) Option {
+	return func(c *Config) {
+		c.PackageURLCache = dir
+	}
+}
+
+// WithOffline loads packages added with WithPackageURL from the cache only,
+// without network calls; validator creation fails if one is not cached.
+func WithOffline(offline bool) Option {
+	return func(c *Config) {
+		c.Offline = offline
 	}
 }
 
@@ -146,16 +453,431 @@ func WithConformanceResources(resources [][]byte) Option {
 // WithTerminologyProvider sets an external terminology provider for validating
 // codes in systems that cannot be expanded locally (e.g., SNOMED CT, LOINC).
 // When configured, the validator delegates to this provider instead of silently
-// accepting any code from external systems.
+// accepting any code from external systems. A provider implementing
+// terminology.Prober, such as a terminology.ServerProvider, is probed at
+// construction; codes from systems it does not support are accepted with a
+// BINDING_SYSTEM_UNSUPPORTED information issue.
 func WithTerminologyProvider(provider terminology.Provider) Option {
 	return func(c *Config) {
 		c.TerminologyProvider = provider
 	}
 }
 
+// WithTerminologyPrefetch checks every code of a resource that needs the
+// terminology provider before the binding phase, with up to concurrency
+// requests in flight, instead of one request at a time as the phase reaches
+// each code. It cuts the latency of resources with many codings, such as
+// Claims and DiagnosticReports. It has no effect without a provider.
+func WithTerminologyPrefetch(concurrency int) Option {
+	return func(c *Config) {
+		c.TerminologyPrefetch = concurrency
+	}
+}
+
+// WithSystemVersion pins a code system to a version, e.g.
+// WithSystemVersion("http://loinc.org", "2.77"), so that results are
+// reproducible across terminology content releases: ValueSet includes that do
+// not name a version, and codes checked against their CodeSystem, use that
+// version when it is loaded. A binding can pin versions of its own with the
+// binding.SystemVersionExtension extension, which take precedence. Binding
+// issues record the version used in Issue.SystemVersion.
+func WithSystemVersion(system, version string) Option {
+	return func(c *Config) {
+		if c.SystemVersions == nil {
+			c.SystemVersions = make(map[string]string)
+		}
+		c.SystemVersions[system] = version
+	}
+}
+
+// WithUCUMService replaces the built-in UCUM engine used to validate the
+// units of Quantity values whose system is http://unitsofmeasure.org, e.g.,
+// with one backed by a complete UCUM library.
+func WithUCUMService(service ucum.Service) Option {
+	return func(c *Config) {
+		c.UCUMService = service
+	}
+}
+
+// WithUnitConsistency checks the UCUM unit of each Quantity against the units
+// the profile declares for it (a fixed or pattern code, or a required ValueSet
+// of UCUM codes), telling apart units that need conversion from units that
+// measure a different kind of quantity.
+func WithUnitConsistency() Option {
+	return func(c *Config) {
+		c.UnitConsistency = true
+	}
+}
+
+// WithIdentifierValidator registers a check for the values of Identifiers
+// with the given system, replacing any built-in check for it (see
+// identifier.Builtins). A nil check disables the built-in one.
+func WithIdentifierValidator(system string, check identifier.Func) Option {
+	return func(c *Config) {
+		if c.IdentifierValidators == nil {
+			c.IdentifierValidators = make(map[string]identifier.Func)
+		}
+		c.IdentifierValidators[system] = check
+	}
+}
+
+// WithAllowedContentTypes warns on Attachments whose contentType is not one
+// of the given MIME types. Entries may be wildcards of a top-level type
+// (e.g., "image/*").
+func WithAllowedContentTypes(types ...string) Option {
+	return func(c *Config) {
+		c.AllowedContentTypes = append(c.AllowedContentTypes, types...)
+	}
+}
+
+// WithReferenceResolution sets whether references must resolve to a resource
+// available to the validator. With reference.ResolveLocal, fragment references
+// must match a contained resource and Bundle-internal references must match an
+// entry fullUrl. The check applies to Reference(Any) elements as well.
+func WithReferenceResolution(mode reference.ResolveMode) Option {
+	return func(c *Config) {
+		c.ReferenceResolution = mode
+	}
+}
+
+// WithReferenceResolver fetches the targets of references that do not resolve
+// within the resource or its Bundle, so they can be validated against the
+// constraining profiles of the element's targetProfile like contained and
+// Bundle targets are.
+func WithReferenceResolver(r reference.Resolver) Option {
+	return func(c *Config) {
+		c.ReferenceResolver = r
+	}
+}
+
+// WithReferenceSource supplies resources the caller already has, keyed by
+// reference ("Patient/123" or an absolute URL), e.g., the entries of an
+// incoming transaction plus the server resources they refer to. Reference
+// targets found there are validated against target profiles without calling
+// the ReferenceResolver, and with reference.ResolveLocal, relative references
+// that resolve neither within the Bundle nor in the source are reported, also
+// outside a Bundle. See reference.Source for how keys match references.
+func WithReferenceSource(resources map[string]json.RawMessage) Option {
+	return func(c *Config) {
+		c.ReferenceSource = reference.NewSource(resources)
+	}
+}
+
+// WithReferenceSourceFunc is WithReferenceSource with a callback, which
+// returns nil and no error for unknown references. Unlike a
+// ReferenceResolver, it should answer from data at hand: with
+// reference.ResolveLocal it is called for every relative reference outside
+// the Bundle.
+func WithReferenceSourceFunc(fn reference.ResolverFunc) Option {
+	return func(c *Config) {
+		if fn != nil {
+			c.ReferenceSource = fn
+		}
+	}
+}
+
+// WithRetiredResourceTypes configures resource type names (e.g., "BodySite")
+// that are no longer part of the specification. References to them emit a warning.
+func WithRetiredResourceTypes(types ...string) Option {
+	return func(c *Config) {
+		c.RetiredResourceTypes = append(c.RetiredResourceTypes, types...)
+	}
+}
+
+// WithMaxBase64Size limits the decoded size of base64Binary values
+// (e.g., Attachment.data) in bytes. Larger values are reported as errors.
+func WithMaxBase64Size(size int) Option {
+	return func(c *Config) {
+		c.MaxBase64Size = size
+	}
+}
+
+// WithMaxResourceBytes rejects resources larger than size bytes with a fatal
+// issue, without parsing them.
+func WithMaxResourceBytes(size int) Option {
+	return func(c *Config) {
+		c.MaxResourceBytes = size
+	}
+}
+
+// WithMaxNestingDepth rejects resources whose JSON objects and arrays nest
+// deeper than depth (e.g., chains of contained resources) with a fatal issue,
+// without parsing them.
+func WithMaxNestingDepth(depth int) Option {
+	return func(c *Config) {
+		c.MaxNestingDepth = depth
+	}
+}
+
+// WithMaxTotalElements rejects resources with more than n JSON values
+// (object properties and array items, counted recursively) with a fatal
+// issue, without parsing them.
+func WithMaxTotalElements(n int) Option {
+	return func(c *Config) {
+		c.MaxTotalElements = n
+	}
+}
+
+// WithPhaseTimeout bounds the time each validation phase may take. A phase
+// still running when its budget runs out is abandoned and reported with a
+// PHASE_TIMEOUT warning instead of contributing partial results; the
+// remaining phases still run. Terminology lookups and FHIRPath evaluation
+// stop as soon as the deadline passes; other phases finish in the
+// background and their output is discarded. The number of such phases
+// running at once is bounded; past that, a timed-out phase is waited for.
+func WithPhaseTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.PhaseTimeout = d
+	}
+}
+
+// WithKnownModifierExtensions declares the modifierExtension URLs the receiving
+// system understands. Any other modifierExtension is reported as an error, even
+// when its StructureDefinition is loaded. Calling it with no URLs declares that
+// no modifier extensions are understood.
+func WithKnownModifierExtensions(urls ...string) Option {
+	return func(c *Config) {
+		if c.KnownModifierExtensions == nil {
+			c.KnownModifierExtensions = []string{}
+		}
+		c.KnownModifierExtensions = append(c.KnownModifierExtensions, urls...)
+	}
+}
+
+// WithCustomTypes accepts instances whose resourceType is not a core resource
+// type but matches a loaded logical model (kind = logical) or custom resource
+// StructureDefinition, e.g., one passed with WithConformanceResources. The
+// instance is validated against that definition instead of being rejected as
+// an unknown resourceType.
+func WithCustomTypes() Option {
+	return func(c *Config) {
+		c.CustomTypes = true
+	}
+}
+
+// WithSanityChecks enables the sanity phase of cross-field temporal checks
+// with the given rules, or every rule if none is given (see sanity.Rules).
+// Names are resolved with sanity.ParseRule; New fails on unknown names.
+func WithSanityChecks(rules ...sanity.Rule) Option {
+	return func(c *Config) {
+		c.SanityChecks = true
+		c.SanityRules = append(c.SanityRules, rules...)
+	}
+}
+
+// WithDisabledSanityChecks turns off sanity rules, e.g., birthdate-future
+// for test data with synthetic dates. It does not enable the sanity phase.
+func WithDisabledSanityChecks(rules ...sanity.Rule) Option {
+	return func(c *Config) {
+		c.DisabledSanityRules = append(c.DisabledSanityRules, rules...)
+	}
+}
+
+// WithAuditRules enables the Provenance/AuditEvent rule pack: Provenance
+// targets must resolve within the submitted Bundle, agents must be identified,
+// signatures must declare valid formats, and AuditEvent agent/entity codings
+// must carry system and code from the ValueSet bound to their element, even
+// where the binding is not required.
+func WithAuditRules() Option {
+	return func(c *Config) {
+		c.AuditRules = true
+	}
+}
+
+// WithBusinessRules loads business rules from r, co-occurrence constraints
+// such as "subscriberId is required when relationship is not self", written
+// in the syntax of the rules package. They are evaluated with FHIRPath in the
+// business-rule phase, for the resource, its contained resources and Bundle
+// entries. The option can be repeated; New reads r and fails on invalid
+// rules.
+func WithBusinessRules(r io.Reader) Option {
+	return func(c *Config) {
+		c.BusinessRules = append(c.BusinessRules, r)
+	}
+}
+
+// WithUniquenessChecks enables the uniqueness phase, which reports Bundle
+// entries of the same type that share a business key, with the given rules
+// or uniqueness.Defaults if none is given. The rules also apply across the
+// resources of a Session (see NewSession).
+func WithUniquenessChecks(rules ...uniqueness.Rule) Option {
+	return func(c *Config) {
+		c.Uniqueness = true
+		c.UniquenessRules = append(c.UniquenessRules, rules...)
+	}
+}
+
+// WithAuthorMode enables or disables the authoring phase for IG authors.
+// Validated StructureDefinitions must list elements in the order and
+// hierarchy of their base, declare slicing before slices, and only restrict
+// the types, target profiles and binding strengths of the base. The
+// expression of each validated SearchParameter must be valid FHIRPath,
+// navigate only elements of its base resources, and select elements of a
+// type its search parameter type can index. ValueSets and CodeSystems are
+// checked for unresolvable systems, filters their system does not define,
+// duplicate concepts, missing displays, and content and hierarchy
+// declarations that do not match their concepts.
+func WithAuthorMode(enabled bool) Option {
+	return func(c *Config) {
+		c.AuthorMode = enabled
+	}
+}
+
+// WithPhasePlugin adds custom validation phases, such as payer-specific
+// business rules, run after the built-in phases (see phase.Plugin). Their
+// names can be used with WithPhases, WithDisabledPhases, policies and the
+// per-call phase options. New fails on plugins without a name or Run
+// function and on names that repeat or clash with built-in phases.
+func WithPhasePlugin(plugins ...phase.Plugin) Option {
+	return func(c *Config) {
+		c.PhasePlugins = append(c.PhasePlugins, plugins...)
+	}
+}
+
+// WithActor enables evaluation of obligation extensions on profile elements
+// for the given actor (an ActorDefinition canonical URL). SHALL:populate
+// obligations are enforced as errors and SHOULD:populate as warnings;
+// SHALL:handle obligations are reported as information on present elements.
+// Obligations that name no actor apply to every actor.
+func WithActor(url string) Option {
+	return func(c *Config) {
+		c.Actor = url
+	}
+}
+
+// WithMemoryBudget bounds the caches so that the validator's memory stays
+// near bytes: the part of the budget the loaded definitions leave (at least
+// a tenth) is divided between the ValueSet expansion, element index and
+// compiled FHIRPath caches, which evict their least recently used entries
+// beyond their share. When the heap still grows beyond the budget, the
+// caches are trimmed to half their size. Cache sizes are estimates; Stats
+// reports them.
+func WithMemoryBudget(bytes int64) Option {
+	return func(c *Config) {
+		c.MemoryBudget = bytes
+	}
+}
+
+// WithMetrics records validator metrics through reg: phase and validation
+// durations, active validations, ValueSet expansion cache hits and misses,
+// and package load time. See the metrics package for their names.
+func WithMetrics(reg metrics.Registerer) Option {
+	return func(c *Config) {
+		c.Metrics = reg
+	}
+}
+
+// WithTracer starts a span around each Validate and ValidateMap call, and a
+// child span around each phase it runs.
+func WithTracer(tracer metrics.Tracer) Option {
+	return func(c *Config) {
+		c.Tracer = tracer
+	}
+}
+
+// WithUsageTracking records which profiles and ValueSets are resolved over a
+// sliding window of the last window resolutions (values below 1 use
+// warmset.DefaultWindow). The most frequent ones are available from UsageStats
+// and can be persisted with SaveWarmSet.
+func WithUsageTracking(window int) Option {
+	return func(c *Config) {
+		if window < 1 {
+			window = warmset.DefaultWindow
+		}
+		c.UsageWindow = window
+	}
+}
+
+// WithWarmSet pre-warms the profiles and ValueSets listed in a warm-set file
+// (written by SaveWarmSet) when the validator is created, so the first
+// requests after a restart do not pay for cache population.
+func WithWarmSet(path string) Option {
+	return func(c *Config) {
+		c.WarmSetPath = path
+	}
+}
+
+// WithFastPath enables or disables fast-path pre-scans (enabled by default).
+// When enabled, the extension phase is skipped for resources without extensions
+// and the slicing phase is skipped when no sliced path is present; skipped phases
+// are listed in Stats.SkippedPhases.
+func WithFastPath(enabled bool) Option {
+	return func(c *Config) {
+		c.DisableFastPath = !enabled
+	}
+}
+
+// WithRawIssues keeps issues in the order the phases reported them, including
+// duplicates. By default issues are sorted by path, severity and code, and
+// identical issues reported by several phases or profiles are merged (see
+// issue.Result.Normalize); raw output is meant for debugging the phases.
+func WithRawIssues() Option {
+	return func(c *Config) {
+		c.RawIssues = true
+	}
+}
+
+// WithLocale renders catalog diagnostics in the given locale (e.g., "es" or
+// "es-CL"); see issue.Locales for the available bundles. Messages without a
+// translation stay in English, and New fails for an unknown locale.
+func WithLocale(locale string) Option {
+	return func(c *Config) {
+		c.Locale = locale
+	}
+}
+
+// WithSeverityOverride reports issues with the given diagnostic ID (see
+// issue.Catalog) at another severity, e.g. downgrading CONSTRAINT_FAILED
+// warnings to information.
+func WithSeverityOverride(id issue.DiagnosticID, severity issue.Severity) Option {
+	return func(c *Config) {
+		if c.SeverityOverrides == nil {
+			c.SeverityOverrides = make(map[issue.DiagnosticID]issue.Severity)
+		}
+		c.SeverityOverrides[id] = severity
+	}
+}
+
+// WithSuppressions drops issues matching any of the rules from results, for
+// known and accepted findings. Suppressions are applied after severity
+// overrides and match the English message text.
+func WithSuppressions(rules ...issue.Suppression) Option {
+	return func(c *Config) {
+		c.Suppressions = append(c.Suppressions, rules...)
+	}
+}
+
+// WithPhases runs only the given validation phases, e.g.
+// WithPhases(phase.Structure, phase.Cardinality, phase.Primitives) for a fast
+// structural check. Phases that are not selected are listed in
+// Stats.SkippedPhases; New fails for an unknown name.
+func WithPhases(names ...phase.Name) Option {
+	return func(c *Config) {
+		c.Phases = append(c.Phases, names...)
+	}
+}
+
+// WithDisabledPhases skips the given validation phases, by constant or name
+// (e.g., WithDisabledPhases("terminology", "references"); see phase.Parse).
+// Skipped phases are listed in Stats.SkippedPhases; New fails for an unknown
+// name.
+func WithDisabledPhases(names ...phase.Name) Option {
+	return func(c *Config) {
+		c.DisabledPhases = append(c.DisabledPhases, names...)
+	}
+}
+
 // validateConfig holds per-call validation options.
 type validateConfig struct {
-	profiles []string
+	profiles       []string
+	phases         []phase.Name
+	disabledPhases []phase.Name
+
+	profileSelection *ProfileSelection // Overrides Config.ProfileSelection
+	sourceVersion    string            // Overrides Config.SourceVersion
+
+	session *Session // Set by Session.Validate
+	label   string   // Names the resource in the session's issues
 }
 
 // ValidateOption configures a single Validate call.
@@ -169,8 +891,40 @@ func ValidateWithProfile(profileURL string) ValidateOption {
 	}
 }
 
+// ValidateWithPhases runs only the given phases for this call, regardless of
+// the phases configured on the Validator. A server can, for example, answer
+// with a structural pre-check and run full validation later.
+func ValidateWithPhases(names ...phase.Name) ValidateOption {
+	return func(c *validateConfig) {
+		c.phases = append(c.phases, names...)
+	}
+}
+
+// ValidateWithoutPhases skips the given phases for this call, in addition to
+// those disabled on the Validator.
+func ValidateWithoutPhases(names ...phase.Name) ValidateOption {
+	return func(c *validateConfig) {
+		c.disabledPhases = append(c.disabledPhases, names...)
+	}
+}
+
+// ValidateWithSourceVersion sets the FHIR version of the resource for this
+// call, overriding WithSourceVersion; the validator's own version turns the
+// conversion off.
+func ValidateWithSourceVersion(version string) ValidateOption {
+	return func(c *validateConfig) {
+		c.sourceVersion = version
+	}
+}
+
 // New creates a new Validator with the given options.
 func New(opts ...Option) (*Validator, error) {
+	return NewContext(context.Background(), opts...)
+}
+
+// NewContext is New with a context: canceling it aborts loading packages and
+// building the registries, and NewContext returns an error wrapping ctx.Err().
+func NewContext(ctx context.Context, opts ...Option) (*Validator, error) {
 	startTime := time.Now()
 	startMem := getMemUsage()
 
@@ -181,6 +935,45 @@ func New(opts ...Option) (*Validator, error) {
 	for _, opt := range opts {
 		opt(config)
 	}
+	config.FHIRVersion = loader.NormalizeVersion(config.FHIRVersion)
+	if config.SourceVersion != "" {
+		config.SourceVersion = loader.NormalizeVersion(config.SourceVersion)
+		if config.SourceVersion != config.FHIRVersion &&
+			(!crossversion.Supported(config.SourceVersion) || !crossversion.Supported(config.FHIRVersion)) {
+			return nil, fmt.Errorf("unsupported source version %s for FHIR %s (conversion is between R4, R4B and R5)",
+				config.SourceVersion, config.FHIRVersion)
+		}
+	}
+	if config.Locale != "" && !issue.HasLocale(config.Locale) {
+		return nil, fmt.Errorf("unsupported locale %q (available: %s)", config.Locale, strings.Join(issue.Locales(), ", "))
+	}
+	if err := phase.CheckPlugins(config.PhasePlugins); err != nil {
+		return nil, err
+	}
+	plugins := config.pluginNames()
+	var err error
+	if config.Phases, err = phase.Resolve(config.Phases, plugins...); err != nil {
+		return nil, err
+	}
+	if config.DisabledPhases, err = phase.Resolve(config.DisabledPhases, plugins...); err != nil {
+		return nil, err
+	}
+	policies, err := resolvePolicies(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.SanityRules, err = resolveSanityRules(config.SanityRules); err != nil {
+		return nil, err
+	}
+	if config.DisabledSanityRules, err = resolveSanityRules(config.DisabledSanityRules); err != nil {
+		return nil, err
+	}
+	var businessRules *rules.Set
+	if len(config.BusinessRules) > 0 {
+		if businessRules, err = rules.Parse(config.BusinessRules...); err != nil {
+			return nil, fmt.Errorf("invalid business rules: %w", err)
+		}
+	}
 
 	logger.Info("Initializing FHIR Validator v%s", config.FHIRVersion)
 	logger.Info("  Memory at start: %s", formatBytes(startMem))
@@ -188,73 +981,159 @@ func New(opts ...Option) (*Validator, error) {
 	l := loader.NewLoader(config.PackagePath)
 	logger.Debug("Package cache: %s", l.BasePath())
 
+	// Bundled IG packages (`make bundle`) are verified up front; a checksum
+	// mismatch against the bundle manifest is fatal
+	bundled, err := specs.GetBundledPackages(config.FHIRVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify bundled packages: %w", err)
+	}
+	embeddedData := specs.GetPackages(config.FHIRVersion)
+
+	progress := &loadProgress{ctx: ctx, fn: config.LoadProgress, start: startTime}
+	progress.total = len(bundled) + len(config.AdditionalPackages) + len(config.PackageTgzPaths) +
+		len(config.PackageURLs) + len(config.PackageData)
+	if len(embeddedData) > 0 {
+		progress.total += len(embeddedData)
+	} else {
+		progress.total += len(loader.DefaultPackages[config.FHIRVersion])
+	}
+	if len(config.ConformanceResources) > 0 {
+		progress.total++
+	}
+	if err := progress.canceled(); err != nil {
+		return nil, err
+	}
+
 	// Load packages for the specified FHIR version (embedded-first, fallback to disk)
 	logger.Info("Loading FHIR packages...")
 	loadStart := time.Now()
-	var packages []*loader.Package //nolint:prealloc // assigned from branch, not built by appending
-	var err error
-	if embeddedData := specs.GetPackages(config.FHIRVersion); len(embeddedData) > 0 {
+	packages := make([]*loader.Package, 0, progress.total)
+	if len(embeddedData) > 0 {
 		logger.Info("  Using embedded specs for %s", config.FHIRVersion)
-		packages, err = l.LoadFromEmbeddedData(embeddedData)
+		for i, data := range embeddedData {
+			pkg, err := l.LoadFromTgzData(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load FHIR packages: failed to load embedded package %d: %w", i, err)
+			}
+			packages = append(packages, pkg)
+			if err := progress.loaded(packageSource(pkg), nil); err != nil {
+				return nil, err
+			}
+		}
 	} else {
 		logger.Info("  Loading specs from disk for %s", config.FHIRVersion)
-		packages, err = l.LoadVersion(config.FHIRVersion)
+		if packages, err = l.LoadVersion(config.FHIRVersion); err != nil {
+			return nil, fmt.Errorf("failed to load FHIR packages: %w", err)
+		}
+		// Optional default packages missing from the cache count as processed
+		progress.done += len(loader.DefaultPackages[config.FHIRVersion]) - len(packages)
+		for _, pkg := range packages {
+			if err := progress.loaded(packageSource(pkg), nil); err != nil {
+				return nil, err
+			}
+		}
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to load FHIR packages: %w", err)
+
+	// Load IG packages embedded by a bundle build
+	for i, data := range bundled {
+		pkg, err := l.LoadFromTgzData(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load bundled package %d: %w", i, err)
+		}
+		logger.Info("  Loaded bundled package: %s#%s", pkg.Name, pkg.Version)
+		packages = append(packages, pkg)
+		if err := progress.loaded(packageSource(pkg), nil); err != nil {
+			return nil, err
+		}
 	}
 
 	// Load additional packages (e.g., US Core, IPS)
 	for _, pkgSpec := range config.AdditionalPackages {
 		pkg, err := l.LoadPackage(pkgSpec.Name, pkgSpec.Version)
+		source := pkgSpec.Name + "#" + pkgSpec.Version
 		if err != nil {
-			logger.Warn("Could not load additional package %s#%s: %v", pkgSpec.Name, pkgSpec.Version, err)
-			continue
+			logger.Warn("Could not load additional package %s: %v", source, err)
+		} else {
+			packages = append(packages, pkg)
+		}
+		if err := progress.loaded(source, err); err != nil {
+			return nil, err
 		}
-		packages = append(packages, pkg)
 	}
 
 	// Load packages from local .tgz files
 	for _, tgzPath := range config.PackageTgzPaths {
 		pkg, err := l.LoadFromTgz(tgzPath)
+		source := tgzPath
 		if err != nil {
 			logger.Warn("Could not load package from tgz %s: %v", tgzPath, err)
-			continue
+		} else {
+			logger.Info("  Loaded package from tgz: %s#%s", pkg.Name, pkg.Version)
+			packages = append(packages, pkg)
+			source = packageSource(pkg)
+		}
+		if err := progress.loaded(source, err); err != nil {
+			return nil, err
 		}
-		logger.Info("  Loaded package from tgz: %s#%s", pkg.Name, pkg.Version)
-		packages = append(packages, pkg)
 	}
 
 	// Load packages from remote URLs
 	for _, url := range config.PackageURLs {
-		pkg, err := l.LoadFromURL(url)
-		if err != nil {
+		checksum := config.PackageURLChecksums[url]
+		progress.report(LoadDownload, url, nil)
+		pkg, status, err := l.LoadFromURLCached(ctx, url, loader.URLOptions{
+			CacheDir: config.PackageURLCache,
+			Checksum: checksum,
+			Offline:  config.Offline,
+		})
+		source := url
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return nil, progress.canceled()
+		case err != nil && (checksum != "" || config.Offline):
+			// Pinned and offline packages must not be silently missing
+			return nil, fmt.Errorf("failed to load package from URL %s: %w", url, err)
+		case err != nil:
 			logger.Warn("Could not load package from URL %s: %v", url, err)
-			continue
+		default:
+			logger.Info("  Loaded package from URL: %s#%s (%s)", pkg.Name, pkg.Version, status)
+			packages = append(packages, pkg)
+			source = packageSource(pkg)
+		}
+		if err := progress.loaded(source, err); err != nil {
+			return nil, err
 		}
-		logger.Info("  Loaded package from URL: %s#%s", pkg.Name, pkg.Version)
-		packages = append(packages, pkg)
 	}
 
 	// Load packages from in-memory .tgz data (e.g., //go:embed)
 	for i, data := range config.PackageData {
 		pkg, err := l.LoadFromTgzData(data)
+		source := fmt.Sprintf("data[%d]", i)
 		if err != nil {
 			logger.Warn("Could not load package from memory data[%d]: %v", i, err)
-			continue
+		} else {
+			logger.Info("  Loaded package from memory: %s#%s", pkg.Name, pkg.Version)
+			packages = append(packages, pkg)
+			source = packageSource(pkg)
+		}
+		if err := progress.loaded(source, err); err != nil {
+			return nil, err
 		}
-		logger.Info("  Loaded package from memory: %s#%s", pkg.Name, pkg.Version)
-		packages = append(packages, pkg)
 	}
 
 	// Load individual conformance resources from memory (e.g., from database)
 	if len(config.ConformanceResources) > 0 {
 		pkg, err := l.LoadFromResources(config.ConformanceResources)
+		source := "conformance resources"
 		if err != nil {
 			logger.Warn("Could not load conformance resources: %v", err)
 		} else {
 			logger.Info("  Loaded %d conformance resources from memory", len(pkg.Resources))
 			packages = append(packages, pkg)
+			source = packageSource(pkg)
+		}
+		if err := progress.loaded(source, err); err != nil {
+			return nil, err
 		}
 	}
 
@@ -268,12 +1147,15 @@ func New(opts ...Option) (*Validator, error) {
 	}
 	afterLoadMem := getMemUsage()
 	logger.Info("  Total: %d resources from %d packages in %v", totalResources, len(packages), loadDuration.Round(time.Millisecond))
-	logger.Info("  Memory after load: %s (+%s)", formatBytes(afterLoadMem), formatBytes(afterLoadMem-startMem))
+	logger.Info("  Memory after load: %s (%s)", formatBytes(afterLoadMem), formatDelta(startMem, afterLoadMem))
 
 	// Create and populate the registry
 	logger.Info("Building StructureDefinition registry...")
+	progress.report(LoadIndexDefinitions, "", nil)
 	registryStart := time.Now()
 	reg := registry.New()
+	reg.SetVersionPolicy(config.VersionPolicy)
+	reg.SetCanonicalMapping(config.CanonicalMapping)
 	if err := reg.LoadFromPackages(packages); err != nil {
 		return nil, fmt.Errorf("failed to load StructureDefinitions: %w", err)
 	}
@@ -281,47 +1163,98 @@ func New(opts ...Option) (*Validator, error) {
 	afterRegistryMem := getMemUsage()
 
 	logger.Info("  Indexed %d StructureDefinitions, %d types in %v", reg.Count(), reg.TypeCount(), registryDuration.Round(time.Millisecond))
-	logger.Info("  Memory after registry: %s (+%s)", formatBytes(afterRegistryMem), formatBytes(afterRegistryMem-afterLoadMem))
+	logger.Info("  Memory after registry: %s (%s)", formatBytes(afterRegistryMem), formatDelta(afterLoadMem, afterRegistryMem))
 
 	// Create and populate the terminology registry
+	if err := progress.canceled(); err != nil {
+		return nil, err
+	}
 	logger.Debug("Building terminology registry...")
+	progress.report(LoadIndexTerminology, "", nil)
 	termReg := terminology.NewRegistry()
+	termReg.SetVersionPolicy(config.VersionPolicy)
+	termReg.SetCanonicalMapping(config.CanonicalMapping)
+	termReg.SetSystemVersions(config.SystemVersions)
 	if err := termReg.LoadFromPackages(packages); err != nil {
 		return nil, fmt.Errorf("failed to load terminology: %w", err)
 	}
+	if n := reg.AmbiguousCount() + termReg.AmbiguousCount(); n > 0 {
+		logger.Warn("%d canonical URLs are defined by several versions of a package; unversioned references use the %s version", n, config.VersionPolicy)
+	}
 	logger.Debug("  Indexed %d ValueSets, %d CodeSystems", termReg.ValueSetCount(), termReg.CodeSystemCount())
 
+	operations := operation.NewRegistry()
+	operations.LoadFromPackages(packages)
+	logger.Debug("  Indexed %d OperationDefinitions", operations.Count())
+	searchParams := searchparam.NewRegistry()
+	searchParams.LoadFromPackages(packages)
+	topics := subscription.NewTopicRegistry()
+	topics.LoadFromPackages(packages)
+
 	if config.TerminologyProvider != nil {
 		termReg.SetProvider(config.TerminologyProvider)
 		logger.Debug("  External terminology provider configured")
+		if prober, ok := config.TerminologyProvider.(terminology.Prober); ok {
+			if err := prober.Probe(ctx); err != nil {
+				logger.Warn("Terminology server capabilities unavailable (%v); codes from every external system are sent to it", err)
+			}
+		}
 	}
 
 	totalDuration := time.Since(startTime)
-	totalMemUsed := getMemUsage() - startMem
-	logger.Info("Validator ready in %v (total memory: %s)", totalDuration.Round(time.Millisecond), formatBytes(totalMemUsed))
+	logger.Info("Validator ready in %v (total memory: %s)", totalDuration.Round(time.Millisecond), formatDelta(startMem, getMemUsage()))
 
 	// Create phase validators (reused across validations for caching)
 	v := &Validator{
-		registry:     reg,
-		termRegistry: termReg,
-		loader:       l,
-		config:       config,
+		registry:      reg,
+		termRegistry:  termReg,
+		operations:    operations,
+		loader:        l,
+		config:        config,
+		phases:        phase.NewSet(config.Phases, config.DisabledPhases),
+		policies:      policies,
+		businessRules: businessRules,
 	}
 
 	// Initialize phase validators
-	v.structValidator = structural.New(reg)
-	v.cardValidator = cardinality.New(reg)
-	v.primValidator = primitive.New(reg)
-	v.bindValidator = binding.New(reg, termReg)
-	v.extValidator = extension.New(reg, termReg, v.primValidator)
-	v.refValidator = reference.New(reg)
-	v.constraintValidator = constraint.New(reg)
-	v.fixedPatternValidator = fixedpattern.New(reg)
-	v.slicingValidator = slicing.New(reg)
+	v.initPhaseValidators()
+	v.subscriptionValidator = subscription.New(reg, searchParams, topics)
+	v.globalProfiles = implementationGuideGlobals(packages, config.ImplementationGuides)
+	if config.MemoryBudget > 0 {
+		runtime.GC() // Measure the definitions without loading garbage
+		v.initBudget()
+	}
+
+	// Warm before enabling tracking so warming does not count as traffic
+	if config.WarmSetPath != "" {
+		v.warm(config.WarmSetPath)
+	}
+	if config.Metrics != nil || config.Tracer != nil {
+		v.instruments = newInstruments(config.Metrics, config.Tracer)
+		v.instruments.observeLoad(totalDuration)
+		if v.instruments.cacheRequests != nil {
+			termReg.SetCacheHook(v.instruments.observeCache)
+		}
+	}
+	if config.UsageWindow > 0 {
+		v.usage = warmset.NewTracker(config.UsageWindow)
+		termReg.SetResolveHook(func(url string) {
+			v.usage.Record(warmset.KindValueSet, url)
+		})
+	}
 
+	if err := progress.canceled(); err != nil {
+		return nil, err
+	}
+	progress.report(LoadReady, "", nil)
 	return v, nil
 }
 
+// packageSource returns the name#version of a loaded package.
+func packageSource(pkg *loader.Package) string {
+	return loader.PackageRef{Name: pkg.Name, Version: pkg.Version}.String()
+}
+
 // getMemUsage returns the current memory allocation in bytes.
 func getMemUsage() uint64 {
 	var m runtime.MemStats
@@ -343,11 +1276,31 @@ func formatBytes(b uint64) string {
 	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
 }
 
+// formatDelta formats the change from one memory reading to another, which
+// is negative when the heap shrank in between (e.g., after a GC).
+func formatDelta(from, to uint64) string {
+	if to < from {
+		return "-" + formatBytes(from-to)
+	}
+	return "+" + formatBytes(to-from)
+}
+
 // Validate validates a FHIR resource and returns the validation result.
 // According to the FHIR specification, when a resource declares multiple profiles
 // in meta.profile, it MUST be valid against ALL of them.
 // Optional ValidateOption parameters allow per-call configuration (e.g., ValidateWithProfile).
 func (v *Validator) Validate(ctx context.Context, resource []byte, opts ...ValidateOption) (*issue.Result, error) {
+	if v.instruments == nil {
+		return v.validate(ctx, resource, opts...)
+	}
+	ctx, end := v.instruments.startValidation(ctx)
+	result, err := v.validate(ctx, resource, opts...)
+	end(result)
+	return result, err
+}
+
+// validate implements Validate.
+func (v *Validator) validate(ctx context.Context, resource []byte, opts ...ValidateOption) (*issue.Result, error) {
 	startTime := time.Now()
 
 	// Apply per-call options
@@ -361,78 +1314,151 @@ func (v *Validator) Validate(ctx context.Context, resource []byte, opts ...Valid
 		return nil, err
 	}
 
+	phases, err := v.callPhases(&vc)
+	if err != nil {
+		return nil, err
+	}
+	sourceVersion, err := v.sourceVersion(&vc)
+	if err != nil {
+		return nil, err
+	}
+
 	result := issue.NewResult()
 	result.Stats = &issue.Stats{
 		ResourceSize: len(resource),
 	}
 
+	// Reject oversized or pathological input before parsing it
+	inputLimits := limits.Limits{
+		MaxBytes:    v.config.MaxResourceBytes,
+		MaxDepth:    v.config.MaxNestingDepth,
+		MaxElements: v.config.MaxTotalElements,
+	}
+	if inputLimits.Enabled() && !limits.Check(resource, inputLimits, result) {
+		v.applyIssueRules(result)
+		if v.config.Locale != "" {
+			result.Localize(v.config.Locale)
+		}
+		result.Stats.Duration = time.Since(startTime).Nanoseconds()
+		return result, nil
+	}
+
+	// Reject JSON that encoding/json would silently accept (duplicate keys,
+	// trailing data, invalid UTF-8)
+	if !strictjson.Check(resource, result) {
+		v.applyIssueRules(result)
+		if v.config.Locale != "" {
+			result.Localize(v.config.Locale)
+		}
+		result.Stats.Duration = time.Since(startTime).Nanoseconds()
+		return result, nil
+	}
+
 	// Parse JSON once - this parsed data will be shared across all validation phases
-	var data map[string]any
-	if err := json.Unmarshal(resource, &data); err != nil {
+	data, err := decodeResource(resource)
+	if err != nil {
 		result.AddError(issue.CodeStructure, fmt.Sprintf("Invalid JSON: %v", err))
 		result.Stats.Duration = time.Since(startTime).Nanoseconds()
 		return result, nil
 	}
 
+	// Convert resources of another FHIR version; issues are still located
+	// in the caller's JSON
+	source := resource
+	if sourceVersion != "" {
+		if data, err = v.converter.Convert(data, sourceVersion, result); err != nil {
+			return nil, err
+		}
+		if resource, err = json.Marshal(data); err != nil {
+			return nil, fmt.Errorf("failed to encode converted resource: %w", err)
+		}
+	}
+
+	return v.validateDecoded(ctx, &vc, phases, resource, data, result, startTime, source), nil
+}
+
+// sourceVersion returns the FHIR version resources are converted from for
+// a call, or "" when they are validated as they are.
+func (v *Validator) sourceVersion(vc *validateConfig) (string, error) {
+	version := v.config.SourceVersion
+	if vc.sourceVersion != "" {
+		version = loader.NormalizeVersion(vc.sourceVersion)
+	}
+	if version == "" || version == v.config.FHIRVersion {
+		return "", nil
+	}
+	if !crossversion.Supported(version) || !crossversion.Supported(v.config.FHIRVersion) {
+		return "", fmt.Errorf("unsupported source version %s for FHIR %s (conversion is between R4, R4B and R5)",
+			version, v.config.FHIRVersion)
+	}
+	return version, nil
+}
+
+// validateDecoded validates a resource that has passed the input checks,
+// given both as JSON and decoded. Issues get line and column numbers from
+// source, the caller's JSON (nil when there is none).
+func (v *Validator) validateDecoded(ctx context.Context, vc *validateConfig, phases phase.Set, resource []byte, data map[string]any, result *issue.Result, startTime time.Time, source []byte) *issue.Result {
 	// Extract resourceType and meta from parsed data
 	resourceType, _ := data["resourceType"].(string)
 	result.Stats.ResourceType = resourceType
+	result.Stats.ResourceID, _ = data["id"].(string)
 
 	if resourceType == "" {
 		result.AddError(issue.CodeStructure, "Missing 'resourceType' property")
 		result.Stats.Duration = time.Since(startTime).Nanoseconds()
-		return result, nil
+		return result
 	}
 
-	// Extract meta.profile if present
-	var metaProfiles []string
-	if meta, ok := data["meta"].(map[string]any); ok {
-		if profiles, ok := meta["profile"].([]any); ok {
-			for _, p := range profiles {
-				if ps, ok := p.(string); ok {
-					metaProfiles = append(metaProfiles, ps)
-				}
-			}
-		}
+	policy := v.policies[resourceType]
+	if policy != nil {
+		phases = policy.callPhases(vc, phases)
 	}
 
+	// Extract meta.profile if present
+	declaredProfiles := metaProfiles(data)
+
 	// Get core resource StructureDefinition (always validate against this)
 	coreURL := registry.GetSDForResource(resourceType)
 	coreSD := v.registry.GetByURL(coreURL)
+	if coreSD == nil && v.config.CustomTypes {
+		if sd := v.getCustomType(resourceType); sd != nil {
+			coreSD, coreURL = sd, sd.URL
+		}
+	}
 
 	if coreSD == nil {
 		result.AddError(issue.CodeStructure, fmt.Sprintf("Unknown resourceType '%s'", resourceType))
 		result.Stats.Duration = time.Since(startTime).Nanoseconds()
-		return result, nil
+		return result
 	}
 
-	// Collect all profiles to validate against (metaProfiles already extracted above)
-	customProfiles := v.collectProfilesToValidate(vc.profiles, metaProfiles)
+	// Collect all profiles to validate against (declaredProfiles already extracted above)
+	requested := v.collectProfilesToValidate(resourceType, vc, declaredProfiles)
 
 	// Resolve profiles from registry
 	var resolvedProfiles []*registry.StructureDefinition
+	var resolvedRequests []profileRequest
 	var profileURLs []string
 	var profilesNotFound []string
 
-	for _, profileURL := range customProfiles {
-		sd := v.registry.GetByURL(profileURL)
+	for _, req := range requested {
+		sd := v.registry.GetByURL(req.url)
 		if sd != nil {
 			resolvedProfiles = append(resolvedProfiles, sd)
-			profileURLs = append(profileURLs, profileURL)
+			resolvedRequests = append(resolvedRequests, req)
+			profileURLs = append(profileURLs, req.url)
+			if v.registry.IsAmbiguous(req.url) {
+				result.AddWarningWithID(issue.DiagCanonicalVersionAmbiguous, issue.Params{
+					issue.String("url", req.url),
+					issue.String("versions", strings.Join(v.registry.Versions(req.url), ", ")),
+					issue.String("version", sd.Version),
+				})
+			}
 		} else {
-			profilesNotFound = append(profilesNotFound, profileURL)
+			profilesNotFound = append(profilesNotFound, req.url)
 		}
 	}
 
-	// Emit warnings for profiles not found
-	for _, notFound := range profilesNotFound {
-		result.AddIssue(issue.Issue{
-			Severity:    issue.SeverityWarning,
-			Code:        issue.CodeNotFound,
-			Diagnostics: fmt.Sprintf("Profile '%s' not found in registry", notFound),
-		})
-	}
-
 	// Determine which profiles to validate against
 	// If custom profiles found, validate against all of them
 	// If no custom profiles, validate against core only
@@ -446,11 +1472,22 @@ func (v *Validator) Validate(ctx context.Context, resource []byte, opts ...Valid
 	} else {
 		profilesToValidate = []*registry.StructureDefinition{coreSD}
 		profileURLsToValidate = []string{coreURL}
+		resolvedRequests = []profileRequest{{url: coreURL, origin: issue.ProfileOriginBase}}
 		result.Stats.IsCustomProfile = false
 	}
 
 	// Store first profile URL for stats (backward compatibility)
 	result.Stats.ProfileURL = profileURLsToValidate[0]
+	result.Stats.AppliedProfiles = profileURLsToValidate
+
+	// Report profiles not found
+	reportUnresolvedProfiles(resourceType, profilesNotFound, declaredProfiles, profileURLs, v.config.StrictMode, result)
+
+	if v.usage != nil {
+		for _, url := range profileURLsToValidate {
+			v.usage.Record(warmset.KindProfile, url)
+		}
+	}
 
 	// Log validation info
 	logger.Info("Validating %s (%s, %d bytes) against %d profile(s)",
@@ -472,23 +1509,90 @@ func (v *Validator) Validate(ctx context.Context, resource []byte, opts ...Valid
 		})
 	}
 
+	// Decimal precision needs the raw number literals, so it runs once per
+	// resource rather than per profile
+	v.primValidator.ValidateDecimalPrecision(resource, resourceType, result)
+
 	// Validate against ALL profiles
 	// According to FHIR spec, resource must be valid against all claimed profiles
 	// Pass parsed data to avoid re-parsing JSON in each phase
+	completed := true
 	for i, sd := range profilesToValidate {
 		profileURL := profileURLsToValidate[i]
-		v.validateAgainstProfile(data, resource, sd, profileURL, result)
+		before := len(result.Issues)
+		completed = v.validateAgainstProfile(ctx, phases, data, resource, sd, profileURL, result)
+		for j := before; j < len(result.Issues); j++ {
+			if result.Issues[j].Profile == "" {
+				result.Issues[j].Profile = sd.URL
+			}
+		}
+		v.attributeElements(sd, result, before)
+		if !completed {
+			break
+		}
+		result.Stats.ProfilesEvaluated = append(result.Stats.ProfilesEvaluated,
+			v.profileOutcome(resolvedRequests[i], result.Issues[before:], policy != nil && policy.Strict))
+		if result.Stats.IsCustomProfile {
+			result.Stats.MustSupport = result.Stats.MustSupport.Add(mustSupportCoverage(sd, data))
+		}
+	}
+	result.Stats.ElementsChecked = countElements(data)
+
+	// Validate Bundle entries against the profiles of their types
+	if completed && resourceType == "Bundle" && len(v.config.EntryProfiles) > 0 {
+		v.validateEntryProfiles(ctx, vc, phases, data, result)
+	}
+
+	// Cross-resource uniqueness among the resources of a session, or the
+	// entries of a Bundle (opt-in)
+	if completed {
+		switch {
+		case vc.session != nil:
+			label := vc.label
+			if id, _ := data["id"].(string); label == "" && id != "" {
+				label = resourceType + "/" + id
+			}
+			v.runPhase(ctx, phases, phase.Uniqueness, result, func(_ context.Context, r *issue.Result) {
+				vc.session.unique.Check(data, resourceType, label, r)
+			})
+		case v.config.Uniqueness && resourceType == "Bundle":
+			v.runPhase(ctx, phases, phase.Uniqueness, result, func(_ context.Context, r *issue.Result) {
+				uniqueness.New(v.config.UniquenessRules...).Check(data, resourceType, "", r)
+			})
+		}
 	}
 
 	result.Stats.Duration = time.Since(startTime).Nanoseconds()
 
 	// Enrich issues with line/column information from source JSON
-	result.EnrichLocations(func(expr string) *issue.Location {
-		if loc := location.Find(resource, expr); loc != nil {
-			return &issue.Location{Line: loc.Line, Column: loc.Column}
-		}
-		return nil
-	})
+	if source != nil {
+		result.EnrichLocations(func(expr string) *issue.Location {
+			if loc := location.Find(source, expr); loc != nil {
+				return &issue.Location{Line: loc.Line, Column: loc.Column}
+			}
+			return nil
+		})
+	}
+
+	if v.budget != nil {
+		v.budget.Check()
+	}
+
+	v.applyIssueRules(result)
+	if policy != nil && policy.Strict {
+		applyStrict(result)
+	}
+	if !v.config.RawIssues {
+		result.Normalize()
+	}
+	if v.config.Locale != "" {
+		result.Localize(v.config.Locale)
+	}
+	result.Stats.PhaseIssues = phaseIssues(result)
+	if q := v.config.QualityScoring; q != nil {
+		result.Stats.Score = q.score(result)
+		result.Stats.Scored = true
+	}
 
 	logger.Info("Validated %s in %.3fms: %d errors, %d warnings",
 		resourceType,
@@ -497,61 +1601,374 @@ func (v *Validator) Validate(ctx context.Context, resource []byte, opts ...Valid
 		result.WarningCount(),
 	)
 
-	return result, nil
+	return result
+}
+
+// decodeResource parses a resource, keeping numbers as json.Number so every
+// phase sees decimals with their exact literal (e.g., "1.50") rather than a
+// float64 that may have lost precision.
+func decodeResource(raw []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var data map[string]any
+	if err := dec.Decode(&data); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after top-level value")
+	}
+	return data, nil
+}
+
+// callPhases returns the phases to run for a Validate call: the per-call
+// selection if any, plus per-call exclusions, else the configured phases.
+func (v *Validator) callPhases(vc *validateConfig) (phase.Set, error) {
+	if len(vc.phases) == 0 && len(vc.disabledPhases) == 0 {
+		return v.phases, nil
+	}
+	plugins := v.config.pluginNames()
+	only, err := phase.Resolve(vc.phases, plugins...)
+	if err != nil {
+		return phase.Set{}, err
+	}
+	disabled, err := phase.Resolve(vc.disabledPhases, plugins...)
+	if err != nil {
+		return phase.Set{}, err
+	}
+	if len(only) == 0 {
+		only = v.config.Phases
+		disabled = append(slices.Clip(v.config.DisabledPhases), disabled...)
+	}
+	return phase.NewSet(only, disabled), nil
+}
+
+// applyIssueRules applies the configured severity overrides and suppressions.
+func (v *Validator) applyIssueRules(result *issue.Result) {
+	result.OverrideSeverities(v.config.SeverityOverrides)
+	result.Suppress(v.config.Suppressions)
 }
 
 // ValidateAgainstProfile runs all validation phases against a single profile.
 // Data is the pre-parsed JSON map, rawJSON is kept for phases that need raw bytes (constraint/fhirpath).
-func (v *Validator) validateAgainstProfile(data map[string]any, rawJSON []byte, sd *registry.StructureDefinition, _ string, result *issue.Result) {
+// It returns false if ctx ended, in which case no further profiles should be validated.
+func (v *Validator) validateAgainstProfile(ctx context.Context, phases phase.Set, data map[string]any, rawJSON []byte, sd *registry.StructureDefinition, _ string, result *issue.Result) bool {
 	// Phase 1: Structural validation (uses cached element indexes)
-	structResult := v.structValidator.ValidateData(data, sd)
-	result.Merge(structResult)
-	issue.ReleaseResult(structResult)
-	result.Stats.PhasesRun++
+	ok := v.runPhase(ctx, phases, phase.Structure, result, func(_ context.Context, r *issue.Result) {
+		structResult := v.structValidator.ValidateData(data, sd)
+		r.Merge(structResult)
+		issue.ReleaseResult(structResult)
+	})
 
 	// Phase 2: Cardinality validation
-	cardResult := v.cardValidator.ValidateData(data, sd)
-	result.Merge(cardResult)
-	issue.ReleaseResult(cardResult)
-	result.Stats.PhasesRun++
+	ok = ok && v.runPhase(ctx, phases, phase.Cardinality, result, func(_ context.Context, r *issue.Result) {
+		cardResult := v.cardValidator.ValidateData(data, sd)
+		r.Merge(cardResult)
+		issue.ReleaseResult(cardResult)
+	})
 
 	// Phase 3: Primitive type validation (uses cached regex)
-	primResult := v.primValidator.ValidateData(data, sd)
-	result.Merge(primResult)
-	issue.ReleaseResult(primResult)
-	result.Stats.PhasesRun++
+	ok = ok && v.runPhase(ctx, phases, phase.Primitives, result, func(_ context.Context, r *issue.Result) {
+		primResult := v.primValidator.ValidateData(data, sd)
+		r.Merge(primResult)
+		issue.ReleaseResult(primResult)
+	})
 
-	// Phase 4: Binding validation (terminology)
-	v.bindValidator.ValidateData(data, sd, result)
-	result.Stats.PhasesRun++
+	// Phase 4: Binding validation (terminology). The coverage is only read
+	// once the phase has run, since a timed-out phase may still be writing it.
+	var coverage binding.Coverage
+	phasesRun := result.Stats.PhasesRun
+	ok = ok && v.runPhase(ctx, phases, phase.Binding, result, func(ctx context.Context, r *issue.Result) {
+		if v.config.TerminologyPrefetch > 0 {
+			ctx = v.bindValidator.Prefetch(ctx, data, sd, v.config.TerminologyPrefetch)
+		}
+		v.bindValidator.ValidateDataContext(binding.WithCoverage(ctx, &coverage), data, sd, r)
+	})
+	if result.Stats.PhasesRun > phasesRun {
+		result.Stats.Terminology = result.Stats.Terminology.Add(issue.Coverage{
+			Total:   coverage.Validated + coverage.Skipped,
+			Covered: coverage.Validated,
+		})
+	}
 
-	// Phase 5: Extension validation
-	v.extValidator.ValidateData(data, sd, result)
-	result.Stats.PhasesRun++
+	// Phase 5: Extension validation (skipped when the pre-scan finds no extensions)
+	if v.config.DisableFastPath || extension.HasExtensions(rawJSON) {
+		ok = ok && v.runPhase(ctx, phases, phase.Extensions, result, func(_ context.Context, r *issue.Result) {
+			v.extValidator.ValidateData(data, sd, r)
+		})
+	} else {
+		skipPhase(result, phase.Extensions)
+	}
 
 	// Phase 6: Reference validation
 	// For Bundles, create a BundleContext to validate urn:uuid references
-	var bundleCtx *reference.BundleContext
+	ok = ok && v.runPhase(ctx, phases, phase.Reference, result, func(ctx context.Context, r *issue.Result) {
+		var bundleCtx *reference.BundleContext
+		if resourceType, _ := data["resourceType"].(string); resourceType == "Bundle" {
+			bundleCtx = reference.NewBundleContext(data)
+			// Validate Bundle-specific rules: fullUrl must be consistent with resource.id
+			reference.ValidateBundleFullUrls(data, r)
+			reference.ValidateTransaction(data, r)
+		}
+		v.refValidator.ValidateDataWithBundleContext(ctx, data, sd, bundleCtx, r)
+	})
+
+	// Phase 7: Contained resource rules (dom-2 to dom-5)
+	ok = ok && v.runPhase(ctx, phases, phase.Contained, result, func(_ context.Context, r *issue.Result) {
+		v.containedValidator.ValidateData(data, r)
+	})
+
+	// Phase 8: Narrative XHTML validation
+	ok = ok && v.runPhase(ctx, phases, phase.Narrative, result, func(_ context.Context, r *issue.Result) {
+		v.narrativeValidator.ValidateData(data, r)
+	})
+
+	// Phase 9: Constraint validation (FHIRPath, uses cached expressions)
+	// Note: constraint validation needs raw bytes for FHIRPath evaluation
+	ok = ok && v.runPhase(ctx, phases, phase.Constraints, result, func(ctx context.Context, r *issue.Result) {
+		v.constraintValidator.ValidateResolving(ctx, rawJSON, sd, v.refValidator.Targets(ctx, data), r)
+	})
+
+	// Phase 10: Fixed/Pattern value validation
+	ok = ok && v.runPhase(ctx, phases, phase.FixedPattern, result, func(_ context.Context, r *issue.Result) {
+		v.fixedPatternValidator.ValidateData(data, sd, r)
+	})
+
+	// Phase 11: Slicing validation (skipped when no sliced path is present)
+	if v.config.DisableFastPath || !v.slicingValidator.CanSkip(data, sd) {
+		ok = ok && v.runPhase(ctx, phases, phase.Slicing, result, func(_ context.Context, r *issue.Result) {
+			v.slicingValidator.ValidateData(data, sd, r)
+		})
+	} else {
+		skipPhase(result, phase.Slicing)
+	}
+
+	// Phase 12: Identifier values checked by system
+	ok = ok && v.runPhase(ctx, phases, phase.Identifiers, result, func(_ context.Context, r *issue.Result) {
+		v.identifierValidator.ValidateData(data, sd, r)
+	})
+
+	// Phase 13: Datatype semantics (Attachment content)
+	ok = ok && v.runPhase(ctx, phases, phase.Datatypes, result, func(_ context.Context, r *issue.Result) {
+		v.datatypeValidator.ValidateData(data, sd, r)
+	})
+
+	// Phase 14: Subscription criteria, topics and channels
+	ok = ok && v.runPhase(ctx, phases, phase.Subscription, result, func(_ context.Context, r *issue.Result) {
+		v.subscriptionValidator.ValidateData(data, r)
+	})
+
+	// Phase 15: Duplicates inside Bundles
 	if resourceType, _ := data["resourceType"].(string); resourceType == "Bundle" {
-		bundleCtx = reference.NewBundleContext(data)
-		// Validate Bundle-specific rules: fullUrl must be consistent with resource.id
-		reference.ValidateBundleFullUrls(data, result)
+		ok = ok && v.runPhase(ctx, phases, phase.Bundle, result, func(_ context.Context, r *issue.Result) {
+			v.bundleValidator.ValidateData(data, r)
+		})
 	}
-	v.refValidator.ValidateDataWithBundle(data, sd, bundleCtx, result)
-	result.Stats.PhasesRun++
 
-	// Phase 7: Constraint validation (FHIRPath, uses cached expressions)
-	// Note: constraint validation needs raw bytes for FHIRPath evaluation
-	v.constraintValidator.Validate(rawJSON, sd, result)
-	result.Stats.PhasesRun++
+	// Phase 16: Cross-field temporal checks (opt-in)
+	if v.sanityValidator != nil {
+		ok = ok && v.runPhase(ctx, phases, phase.Sanity, result, func(_ context.Context, r *issue.Result) {
+			v.sanityValidator.ValidateData(data, sd, r)
+		})
+	}
 
-	// Phase 8: Fixed/Pattern value validation
-	v.fixedPatternValidator.ValidateData(data, sd, result)
-	result.Stats.PhasesRun++
+	// Phase 17: Provenance/AuditEvent rule pack (opt-in)
+	if v.auditValidator != nil {
+		ok = ok && v.runPhase(ctx, phases, phase.Audit, result, func(ctx context.Context, r *issue.Result) {
+			v.auditValidator.ValidateData(ctx, data, r)
+		})
+	}
+
+	// Phase 18: Obligations for the configured actor (opt-in)
+	if v.obligationValidator != nil {
+		ok = ok && v.runPhase(ctx, phases, phase.Obligations, result, func(_ context.Context, r *issue.Result) {
+			v.obligationValidator.ValidateData(data, sd, r)
+		})
+	}
+
+	// Phase 19: StructureDefinition and SearchParameter authoring rules (opt-in)
+	if v.authoringValidator != nil {
+		ok = ok && v.runPhase(ctx, phases, phase.Authoring, result, func(_ context.Context, r *issue.Result) {
+			v.authoringValidator.ValidateData(data, r)
+		})
+	}
+
+	// Phase 20: Business rules loaded with WithBusinessRules (opt-in)
+	if v.rulesValidator != nil {
+		ok = ok && v.runPhase(ctx, phases, phase.BusinessRules, result, func(ctx context.Context, r *issue.Result) {
+			v.rulesValidator.ValidateData(ctx, data, r)
+		})
+	}
 
-	// Phase 9: Slicing validation
-	v.slicingValidator.ValidateData(data, sd, result)
+	// Custom phases, in registration order
+	resourceType, _ := data["resourceType"].(string)
+	for i := range v.config.PhasePlugins {
+		plugin := &v.config.PhasePlugins[i]
+		if !plugin.Applies(resourceType) {
+			continue
+		}
+		rc := &phase.Context{
+			Resource:     data,
+			Raw:          rawJSON,
+			ResourceType: resourceType,
+			Profile:      sd,
+			Registry:     v.registry,
+			Walker:       v.walker,
+		}
+		ok = ok && v.runPhase(ctx, phases, plugin.Name, result, func(ctx context.Context, r *issue.Result) {
+			plugin.Run(ctx, rc, r)
+		})
+	}
+
+	return ok
+}
+
+// resolveSanityRules parses a list of sanity rule names.
+func resolveSanityRules(rules []sanity.Rule) ([]sanity.Rule, error) {
+	resolved := make([]sanity.Rule, 0, len(rules))
+	for _, r := range rules {
+		rule, err := sanity.ParseRule(string(r))
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, rule)
+	}
+	return resolved, nil
+}
+
+// validateReferenceTarget reports whether a resolved reference target
+// conforms to a target profile. The target's own references are not checked,
+// so reference cycles do not recurse.
+func (v *Validator) validateReferenceTarget(ctx context.Context, target map[string]any, sd *registry.StructureDefinition) bool {
+	raw, err := json.Marshal(target)
+	if err != nil {
+		return false
+	}
+	result := issue.GetPooledResult()
+	defer issue.ReleaseResult(result)
+	result.Stats = &issue.Stats{}
+	v.validateAgainstProfile(ctx, v.phases.Without(phase.Reference), target, raw, sd, sd.URL, result)
+	return !result.HasErrors()
+}
+
+// skipPhase lists a skipped phase in the result stats, once however many
+// profiles skip it.
+func skipPhase(result *issue.Result, name phase.Name) {
+	if !slices.Contains(result.Stats.SkippedPhases, string(name)) {
+		result.Stats.SkippedPhases = append(result.Stats.SkippedPhases, string(name))
+	}
+}
+
+// runPhase runs one validation phase and merges its issues into result. It
+// returns false if ctx ended during the phase, after reporting the phase as
+// interrupted.
+//
+// Without a phase timeout the phase runs inline with ctx, which phases that
+// call out to terminology providers or evaluate FHIRPath honor. With a phase
+// timeout it runs in its own goroutine against a private result; if the
+// budget runs out first its partial issues are dropped and a PHASE_TIMEOUT
+// warning is reported instead. The phase context is canceled then, which
+// stops the phases that honor it; the others are left to finish in the
+// background (see abandonPhase).
+func (v *Validator) runPhase(ctx context.Context, phases phase.Set, name phase.Name, result *issue.Result, run func(context.Context, *issue.Result)) bool {
+	if !phases.Enabled(name) {
+		skipPhase(result, name)
+		return true
+	}
+	if v.instruments != nil {
+		var end func()
+		ctx, end = v.instruments.startPhase(ctx, name)
+		defer end()
+	}
+	if v.config.PhaseTimeout <= 0 {
+		phaseResult := issue.GetPooledResult()
+		run(ctx, phaseResult)
+		if ctx.Err() != nil {
+			issue.ReleaseResult(phaseResult)
+			v.reportIncomplete(ctx, name, result)
+			return false
+		}
+		phaseResult.Attribute(0, issue.Source{Phase: string(name)})
+		result.Merge(phaseResult)
+		issue.ReleaseResult(phaseResult)
+		result.Stats.PhasesRun++
+		return true
+	}
+
+	phaseCtx, cancel := context.WithTimeout(ctx, v.config.PhaseTimeout)
+	defer cancel()
+
+	phaseResult := issue.GetPooledResult()
+	done := make(chan bool, 1) // true when the phase completed within its deadline
+	go func() {
+		run(phaseCtx, phaseResult)
+		done <- phaseCtx.Err() == nil
+	}()
+
+	var completed bool
+	select {
+	case completed = <-done:
+	case <-phaseCtx.Done():
+		// The phase may have finished just as the deadline passed
+		select {
+		case completed = <-done:
+		default:
+			// Still running: the goroutine owns phaseResult until it returns
+			v.reportIncomplete(ctx, name, result)
+			abandonPhase(done, phaseResult)
+			return ctx.Err() == nil
+		}
+	}
+
+	if !completed {
+		issue.ReleaseResult(phaseResult)
+		v.reportIncomplete(ctx, name, result)
+		return ctx.Err() == nil
+	}
+	phaseResult.Attribute(0, issue.Source{Phase: string(name)})
+	result.Merge(phaseResult)
+	issue.ReleaseResult(phaseResult)
 	result.Stats.PhasesRun++
+	return true
+}
+
+// strayPhases holds a slot for each timed-out phase still running in the
+// background, across all validators.
+var strayPhases = make(chan struct{}, max(4, runtime.GOMAXPROCS(0)))
+
+// abandonPhase lets a timed-out phase that ignores its context finish in the
+// background, holding a strayPhases slot until it returns and its result
+// goes back to the pool. With every slot taken it waits for the phase
+// instead, so stuck phases slow validation down rather than pile up
+// goroutines.
+func abandonPhase(done <-chan bool, phaseResult *issue.Result) {
+	select {
+	case strayPhases <- struct{}{}:
+		go func() {
+			<-done
+			issue.ReleaseResult(phaseResult)
+			<-strayPhases
+		}()
+	default:
+		<-done
+		issue.ReleaseResult(phaseResult)
+	}
+}
+
+// reportIncomplete records a phase cut short: an interruption when the
+// validation context ended, otherwise a timeout of the phase budget.
+func (v *Validator) reportIncomplete(ctx context.Context, name phase.Name, result *issue.Result) {
+	result.Stats.IncompletePhases = append(result.Stats.IncompletePhases, string(name))
+	if err := ctx.Err(); err != nil {
+		result.AddWarningWithID(issue.DiagPhaseInterrupted, issue.Params{
+			issue.String("phase", string(name)),
+			issue.Any("reason", err),
+		})
+		return
+	}
+	result.AddWarningWithID(issue.DiagPhaseTimeout, issue.Params{
+		issue.String("phase", string(name)),
+		issue.Any("timeout", v.config.PhaseTimeout),
+	})
 }
 
 // ValidateJSON validates a FHIR resource from a JSON string.
@@ -564,7 +1981,21 @@ func (v *Validator) Registry() *registry.Registry {
 	return v.registry
 }
 
-// Config returns the validator configuration.
+// Terminology returns the underlying ValueSet and CodeSystem registry for
+// advanced use cases.
+func (v *Validator) Terminology() *terminology.Registry {
+	return v.termRegistry
+}
+
+// Canonicalize re-serializes a resource in canonical FHIR JSON property order
+// using the loaded StructureDefinitions. Values, including decimal precision,
+// are preserved; undefined properties keep their relative order at the end.
+func (v *Validator) Canonicalize(resourceData []byte) ([]byte, error) {
+	return v.formatter.Format(resourceData)
+}
+
+// Config returns the validator configuration. It is shared by concurrent
+// validations and must not be modified.
 func (v *Validator) Config() *Config {
 	return v.config
 }
@@ -574,22 +2005,107 @@ func (v *Validator) Version() string {
 	return v.config.FHIRVersion
 }
 
-// collectProfilesToValidate returns the ordered list of profiles to validate against.
-// Priority: 1) Per-call profiles, 2) Config profiles, 3) meta.profile, 4) core resource SD.
-func (v *Validator) collectProfilesToValidate(perCallProfiles, metaProfiles []string) []string {
-	var profiles []string
+// UsageStats returns up to n of the most frequently resolved profiles and
+// ValueSets within the tracking window (n <= 0 returns all). It returns nil
+// unless usage tracking is enabled (see WithUsageTracking).
+func (v *Validator) UsageStats(n int) *warmset.Stats {
+	if v.usage == nil {
+		return nil
+	}
+	return v.usage.Stats(n)
+}
 
-	// 1. Per-call profiles take highest priority
-	profiles = append(profiles, perCallProfiles...)
+// SaveWarmSet writes the n most frequently resolved profiles and ValueSets to
+// a warm-set file that WithWarmSet can restore on the next start.
+func (v *Validator) SaveWarmSet(path string, n int) error {
+	if v.usage == nil {
+		return errors.New("usage tracking is not enabled")
+	}
+	return warmset.NewFile(v.config.FHIRVersion, v.usage.Stats(n)).Save(path)
+}
 
-	// 2. Configured profiles
-	profiles = append(profiles, v.config.Profiles...)
+// warm pre-populates the per-profile caches of every phase and the ValueSet
+// expansion cache for the resources listed in a warm-set file. Profiles are
+// warmed by validating an empty resource of their type against them.
+func (v *Validator) warm(path string) {
+	f, err := warmset.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		logger.Info("No warm set at %s", path)
+		return
+	}
+	if err != nil {
+		logger.Warn("Could not load warm set: %v", err)
+		return
+	}
+	if f.FHIRVersion != v.config.FHIRVersion {
+		logger.Warn("Ignoring warm set for FHIR %s (validator is %s)", f.FHIRVersion, v.config.FHIRVersion)
+		return
+	}
 
-	// 3. Profiles from meta.profile
-	profiles = append(profiles, metaProfiles...)
+	start := time.Now()
+	profiles, valueSets := 0, 0
+	for _, url := range f.Profiles {
+		sd := v.registry.GetByURL(url)
+		if sd == nil || sd.Kind != registry.KindResource {
+			continue
+		}
+		stub := fmt.Sprintf(`{"resourceType":%q}`, sd.Type)
+		if result, err := v.Validate(context.Background(), []byte(stub), ValidateWithProfile(url)); err == nil {
+			issue.ReleaseResult(result)
+			profiles++
+		}
+	}
+	for _, url := range f.ValueSets {
+		if v.termRegistry.Warm(url) {
+			valueSets++
+		}
+	}
+	logger.Info("Warmed %d/%d profiles and %d/%d ValueSets in %v",
+		profiles, len(f.Profiles), valueSets, len(f.ValueSets), time.Since(start).Round(time.Millisecond))
+}
 
-	// 4. Core resource type as fallback (added at validation time if needed)
-	// Not added here to allow detecting if all custom profiles failed
+// getCustomType returns the StructureDefinition of a logical model or custom
+// resource for a resourceType, or nil. Phases use the definition's type as the
+// root element path, so a logical model whose type is a canonical URL is
+// returned as a copy whose type is the instance's resourceType.
+func (v *Validator) getCustomType(resourceType string) *registry.StructureDefinition {
+	if cached, ok := v.customTypes.Load(resourceType); ok {
+		sd, _ := cached.(*registry.StructureDefinition)
+		return sd
+	}
+
+	sd := v.registry.GetCustomType(resourceType)
+	if sd != nil && sd.Type != resourceType {
+		adapted := *sd
+		adapted.Type = resourceType
+		sd = &adapted
+	}
+	v.customTypes.Store(resourceType, sd)
+	return sd
+}
 
-	return profiles
+// reportUnresolvedProfiles reports the profiles that could not be resolved:
+// those declared in meta.profile, naming the profiles validated against
+// instead, and those requested by the caller or configuration. The issues are
+// warnings, or errors in strict mode.
+func reportUnresolvedProfiles(resourceType string, notFound, declared, resolved []string, strict bool, result *issue.Result) {
+	result.Stats.UnresolvedProfiles = notFound
+	for _, url := range notFound {
+		i := slices.Index(declared, url)
+		switch {
+		case i < 0:
+			result.AddWarningWithID(issue.DiagProfileNotFound, issue.Params{issue.String("url", url)})
+		case len(resolved) == 0:
+			result.AddWarningWithID(issue.DiagProfileUnresolved,
+				issue.Params{issue.String("url", url), issue.String("type", resourceType)},
+				fmt.Sprintf("%s.meta.profile[%d]", resourceType, i))
+		default:
+			result.AddWarningWithID(issue.DiagProfileUnresolvedPartial,
+				issue.Params{issue.String("url", url), issue.String("profiles", strings.Join(resolved, ", "))},
+				fmt.Sprintf("%s.meta.profile[%d]", resourceType, i))
+		}
+		if strict {
+			result.Issues[len(result.Issues)-1].Severity = issue.SeverityError
+		}
+	}
 }
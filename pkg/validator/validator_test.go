@@ -2,8 +2,16 @@ package validator
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/phase"
 )
 
 // Shared validator instance for tests to avoid repeated package loading.
@@ -58,6 +66,78 @@ func TestNewValidatorUnknownVersion(t *testing.T) {
 	}
 }
 
+func TestR4BDistinctFromR4(t *testing.T) {
+	r4 := getSharedValidator(t)
+	r4b, err := New(WithVersion("R4B"))
+	if err != nil {
+		t.Fatalf("New(R4B) failed: %v", err)
+	}
+
+	if r4b.Version() != "4.3.0" {
+		t.Errorf("Version() = %q, want %q", r4b.Version(), "4.3.0")
+	}
+
+	// Each resource uses a structure that exists in only one of the two versions.
+	tests := []struct {
+		name       string
+		resource   string
+		wantR4Err  bool
+		wantR4BErr bool
+	}{
+		{
+			name:       "resource type added in R4B",
+			resource:   `{"resourceType": "NutritionProduct", "status": "active"}`,
+			wantR4Err:  true,
+			wantR4BErr: false,
+		},
+		{
+			name:       "Evidence element added in R4B",
+			resource:   `{"resourceType": "Evidence", "status": "active", "variableDefinition": [{"variableRole": {"text": "population"}}]}`,
+			wantR4Err:  true,
+			wantR4BErr: false,
+		},
+		{
+			name:       "Evidence element removed in R4B",
+			resource:   `{"resourceType": "Evidence", "status": "active", "variableDefinition": [{"variableRole": {"text": "population"}}], "exposureBackground": {"reference": "EvidenceVariable/1"}}`,
+			wantR4Err:  true,
+			wantR4BErr: true,
+		},
+		{
+			// The required binding uses a ValueSet published in the R4B core
+			name:       "code from R4B terminology",
+			resource:   `{"resourceType": "ClinicalUseDefinition", "type": "indication"}`,
+			wantR4Err:  true,
+			wantR4BErr: false,
+		},
+		{
+			name:       "code outside R4B terminology",
+			resource:   `{"resourceType": "ClinicalUseDefinition", "type": "bogus"}`,
+			wantR4Err:  true,
+			wantR4BErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r4Result, err := r4.Validate(context.Background(), []byte(tt.resource))
+			if err != nil {
+				t.Fatalf("R4 Validate() error: %v", err)
+			}
+			r4bResult, err := r4b.Validate(context.Background(), []byte(tt.resource))
+			if err != nil {
+				t.Fatalf("R4B Validate() error: %v", err)
+			}
+
+			if r4Result.HasErrors() != tt.wantR4Err {
+				t.Errorf("R4 HasErrors() = %v, want %v: %+v", r4Result.HasErrors(), tt.wantR4Err, r4Result.Issues)
+			}
+			if r4bResult.HasErrors() != tt.wantR4BErr {
+				t.Errorf("R4B HasErrors() = %v, want %v: %+v", r4bResult.HasErrors(), tt.wantR4BErr, r4bResult.Issues)
+			}
+		})
+	}
+}
+
 func TestValidateInvalidJSON(t *testing.T) {
 	v := getSharedValidator(t)
 
@@ -77,6 +157,40 @@ func TestValidateInvalidJSON(t *testing.T) {
 	t.Logf("Error: %s", result.Issues[0].Diagnostics)
 }
 
+func TestValidateStrictJSON(t *testing.T) {
+	v := getSharedValidator(t)
+
+	tests := []struct {
+		name    string
+		data    string
+		wantID  issue.DiagnosticID
+		wantErr bool
+	}{
+		{name: "duplicate key", data: `{"resourceType": "Patient", "gender": "male", "gender": "unknown"}`, wantID: issue.DiagJSONDuplicateKey, wantErr: true},
+		{name: "trailing data", data: `{"resourceType": "Patient"}]`, wantID: issue.DiagJSONTrailingData, wantErr: true},
+		{name: "decimal keeps its literal", data: `{"resourceType": "Observation", "status": "final", "code": {"text": "x"}, "valueQuantity": {"value": 1.50}}`},
+		{name: "integer written as decimal", data: `{"resourceType": "Patient", "multipleBirthInteger": 2.0}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.Validate(context.Background(), []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Validate() returned error: %v", err)
+			}
+			if result.HasErrors() != tt.wantErr {
+				t.Fatalf("HasErrors() = %v, want %v: %+v", result.HasErrors(), tt.wantErr, result.Issues)
+			}
+			if tt.wantID == "" {
+				return
+			}
+			if len(result.Issues) != 1 || result.Issues[0].MessageID != string(tt.wantID) {
+				t.Errorf("issues = %+v, want only %s", result.Issues, tt.wantID)
+			}
+		})
+	}
+}
+
 func TestValidateMissingResourceType(t *testing.T) {
 	v := getSharedValidator(t)
 
@@ -418,3 +532,434 @@ func TestValidateWithPerCallProfile(t *testing.T) {
 		}
 	})
 }
+
+func TestUnresolvedProfiles(t *testing.T) {
+	v := getSharedValidator(t)
+	strict, err := New(WithStrictMode(true))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	ctx := context.Background()
+	const unknown = "http://example.org/fhir/StructureDefinition/unknown"
+	const core = "http://hl7.org/fhir/StructureDefinition/Patient"
+
+	tests := []struct {
+		name        string
+		resource    string
+		opts        []ValidateOption
+		strict      bool
+		wantID      issue.DiagnosticID
+		wantPath    string
+		wantApplied []string
+	}{
+		{
+			name:        "declared profile falls back to base",
+			resource:    `{"resourceType":"Patient","meta":{"profile":["` + unknown + `"]}}`,
+			wantID:      issue.DiagProfileUnresolved,
+			wantPath:    "Patient.meta.profile[0]",
+			wantApplied: []string{core},
+		},
+		{
+			name:        "declared profile next to a resolved one",
+			resource:    `{"resourceType":"Patient","meta":{"profile":["` + core + `","` + unknown + `"]}}`,
+			wantID:      issue.DiagProfileUnresolvedPartial,
+			wantPath:    "Patient.meta.profile[1]",
+			wantApplied: []string{core},
+		},
+		{
+			name:        "requested profile",
+			resource:    `{"resourceType":"Patient"}`,
+			opts:        []ValidateOption{ValidateWithProfile(unknown)},
+			wantID:      issue.DiagProfileNotFound,
+			wantApplied: []string{core},
+		},
+		{
+			name:        "declared profile in strict mode",
+			resource:    `{"resourceType":"Patient","meta":{"profile":["` + core + `","` + unknown + `"]}}`,
+			strict:      true,
+			wantID:      issue.DiagProfileUnresolvedPartial,
+			wantPath:    "Patient.meta.profile[1]",
+			wantApplied: []string{core},
+		},
+		{
+			name:        "requested profile in strict mode",
+			resource:    `{"resourceType":"Patient"}`,
+			opts:        []ValidateOption{ValidateWithProfile(unknown)},
+			strict:      true,
+			wantID:      issue.DiagProfileNotFound,
+			wantApplied: []string{core},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, wantSeverity := v, issue.SeverityWarning
+			if tt.strict {
+				val, wantSeverity = strict, issue.SeverityError
+			}
+			result, err := val.Validate(ctx, []byte(tt.resource), tt.opts...)
+			if err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+			if !slices.Equal(result.Stats.AppliedProfiles, tt.wantApplied) {
+				t.Errorf("AppliedProfiles = %v, want %v", result.Stats.AppliedProfiles, tt.wantApplied)
+			}
+			if !slices.Equal(result.Stats.UnresolvedProfiles, []string{unknown}) {
+				t.Errorf("UnresolvedProfiles = %v, want [%s]", result.Stats.UnresolvedProfiles, unknown)
+			}
+			found := false
+			for _, iss := range result.Issues {
+				if iss.MessageID == string(tt.wantID) {
+					found = true
+					if iss.Severity != wantSeverity {
+						t.Errorf("severity = %s, want %s", iss.Severity, wantSeverity)
+					}
+					if tt.wantPath != "" && (len(iss.Expression) == 0 || iss.Expression[0] != tt.wantPath) {
+						t.Errorf("expression = %v, want %s", iss.Expression, tt.wantPath)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("no %s issue: %+v", tt.wantID, result.Issues)
+			}
+		})
+	}
+}
+
+func TestFastPathSkipsPhases(t *testing.T) {
+	v := getSharedValidator(t)
+
+	result, err := v.Validate(context.Background(), []byte(`{"resourceType": "Patient", "active": true}`))
+	if err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+
+	skipped := map[string]bool{}
+	for _, p := range result.Stats.SkippedPhases {
+		skipped[p] = true
+	}
+	if !skipped["extension"] || !skipped["slicing"] {
+		t.Errorf("SkippedPhases = %v, want extension and slicing", result.Stats.SkippedPhases)
+	}
+
+	result, err = v.Validate(context.Background(), []byte(`{
+		"resourceType": "Patient",
+		"extension": [{"url": "http://example.org/unknown", "valueString": "x"}]
+	}`))
+	if err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+	for _, p := range result.Stats.SkippedPhases {
+		if p == "extension" {
+			t.Errorf("SkippedPhases = %v, extension phase must run for a resource with extensions", result.Stats.SkippedPhases)
+		}
+	}
+
+	// Phases skipped for several profiles are listed once
+	result, err = v.Validate(context.Background(), []byte(`{
+		"resourceType": "Observation",
+		"meta": {"profile": ["http://hl7.org/fhir/StructureDefinition/bodyweight"]},
+		"status": "final",
+		"code": {"text": "weight"}
+	}`), ValidateWithProfile("http://hl7.org/fhir/StructureDefinition/vitalsigns"))
+	if err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+	if len(result.Stats.AppliedProfiles) < 2 {
+		t.Fatalf("AppliedProfiles = %v, want both profiles", result.Stats.AppliedProfiles)
+	}
+	seen := map[string]bool{}
+	for _, p := range result.Stats.SkippedPhases {
+		if seen[p] {
+			t.Errorf("SkippedPhases = %v, %s listed twice", result.Stats.SkippedPhases, p)
+		}
+		seen[p] = true
+	}
+}
+
+func TestFastPathMatchesFullValidation(t *testing.T) {
+	fast := getSharedValidator(t)
+	full, err := New(WithFastPath(false))
+	if err != nil {
+		t.Skipf("Cannot create validator: %v", err)
+	}
+
+	files := []string{
+		"../../testdata/m1-structural/valid-patient-with-name.json",
+		"../../testdata/m8-extensions/valid-patient-birthplace.json",
+		"../../testdata/m8-extensions/invalid-wrong-context.json",
+		"../../testdata/m9-references/valid-fragment-reference.json",
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+
+		fastResult, _ := fast.Validate(context.Background(), data)
+		fullResult, _ := full.Validate(context.Background(), data)
+
+		if len(fastResult.Issues) != len(fullResult.Issues) {
+			t.Errorf("%s: fast path produced %d issues, full validation %d",
+				file, len(fastResult.Issues), len(fullResult.Issues))
+		}
+		if len(fullResult.Stats.SkippedPhases) != 0 {
+			t.Errorf("%s: full validation skipped %v", file, fullResult.Stats.SkippedPhases)
+		}
+	}
+}
+
+func TestUsageTrackingWarmSet(t *testing.T) {
+	v, err := New(WithUsageTracking(100))
+	if err != nil {
+		t.Skipf("Cannot create validator: %v", err)
+	}
+
+	if _, err := v.Validate(context.Background(), []byte(`{"resourceType": "Patient", "gender": "female"}`)); err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+
+	stats := v.UsageStats(0)
+	if stats == nil {
+		t.Fatal("UsageStats() = nil with tracking enabled")
+	}
+	if len(stats.Profiles) != 1 || stats.Profiles[0].URL != "http://hl7.org/fhir/StructureDefinition/Patient" {
+		t.Errorf("Profiles = %v, want core Patient", stats.Profiles)
+	}
+	foundGender := false
+	for _, e := range stats.ValueSets {
+		if e.URL == "http://hl7.org/fhir/ValueSet/administrative-gender" {
+			foundGender = true
+		}
+	}
+	if !foundGender {
+		t.Errorf("ValueSets = %v, want administrative-gender", stats.ValueSets)
+	}
+
+	path := filepath.Join(t.TempDir(), "warmset.json")
+	if err := v.SaveWarmSet(path, 10); err != nil {
+		t.Fatalf("SaveWarmSet() error: %v", err)
+	}
+
+	// Restoring must not count warming as traffic
+	warmed, err := New(WithWarmSet(path), WithUsageTracking(100))
+	if err != nil {
+		t.Fatalf("New(WithWarmSet) error: %v", err)
+	}
+	if stats := warmed.UsageStats(0); len(stats.Profiles) != 0 || len(stats.ValueSets) != 0 {
+		t.Errorf("UsageStats after warming = %+v, want empty", stats)
+	}
+
+	if getSharedValidator(t).UsageStats(0) != nil {
+		t.Error("UsageStats() should be nil without tracking")
+	}
+	if _, err := New(WithWarmSet(filepath.Join(t.TempDir(), "missing.json"))); err != nil {
+		t.Errorf("New() with missing warm set should succeed: %v", err)
+	}
+}
+
+func TestBundleDuplicates(t *testing.T) {
+	v := getSharedValidator(t)
+	bundle := []byte(`{
+		"resourceType": "Bundle",
+		"type": "collection",
+		"entry": [
+			{"fullUrl": "http://example.org/fhir/Patient/1", "resource": {"resourceType": "Patient", "id": "1"}},
+			{"fullUrl": "http://example.org/fhir/Patient/1", "resource": {"resourceType": "Patient", "id": "1"}}
+		]
+	}`)
+
+	result, err := v.Validate(context.Background(), bundle)
+	if err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+	var duplicates int
+	for _, iss := range result.Issues {
+		switch {
+		case iss.MessageID == string(issue.DiagBundleFullURLDuplicate):
+			duplicates++
+		case strings.Contains(iss.Diagnostics, "bdl-7"):
+			t.Errorf("bdl-7 reported by the constraint phase: %s", iss.Diagnostics)
+		}
+	}
+	if duplicates != 1 {
+		t.Errorf("got %d %s issues, want 1: %v", duplicates, issue.DiagBundleFullURLDuplicate, result.Issues)
+	}
+
+	result, err = v.Validate(context.Background(), bundle, ValidateWithoutPhases(phase.Bundle))
+	if err != nil {
+		t.Fatalf("Validate() returned error: %v", err)
+	}
+	if !slices.Contains(result.Stats.SkippedPhases, string(phase.Bundle)) {
+		t.Errorf("SkippedPhases = %v, want bundle", result.Stats.SkippedPhases)
+	}
+}
+
+func TestVersionedProfiles(t *testing.T) {
+	const url = "http://example.org/fhir/StructureDefinition/versioned-patient"
+	// Version 3.1.1 requires birthDate and version 6.1.0 requires gender
+	profile := func(version, required string) string {
+		return `{
+			"resourceType": "StructureDefinition", "url": "` + url + `", "version": "` + version + `",
+			"name": "VersionedPatient", "status": "active", "kind": "resource", "abstract": false,
+			"type": "Patient", "baseDefinition": "http://hl7.org/fhir/StructureDefinition/Patient",
+			"derivation": "constraint",
+			"snapshot": {"element": [
+				{"id": "Patient", "path": "Patient", "min": 0, "max": "*"},
+				{"id": "Patient.` + required + `", "path": "Patient.` + required + `", "min": 1, "max": "1", "type": [{"code": "code"}]}
+			]}
+		}`
+	}
+	cache := t.TempDir()
+	writeCachePackage(t, cache, "example.ig", "3.1.1", map[string]string{"StructureDefinition-p.json": profile("3.1.1", "birthDate")})
+	writeCachePackage(t, cache, "example.ig", "6.1.0", map[string]string{"StructureDefinition-p.json": profile("6.1.0", "gender")})
+
+	missing := func(result *issue.Result) []string {
+		var paths []string
+		for _, iss := range result.Issues {
+			if iss.MessageID == string(issue.DiagCardinalityMin) {
+				paths = append(paths, iss.Expression[0])
+			}
+		}
+		return paths
+	}
+	ambiguous := func(result *issue.Result) bool {
+		return slices.ContainsFunc(result.Issues, func(i issue.Issue) bool {
+			return i.MessageID == string(issue.DiagCanonicalVersionAmbiguous)
+		})
+	}
+
+	tests := []struct {
+		name          string
+		policy        loader.VersionPolicy
+		profile       string
+		wantMissing   string
+		wantAmbiguous bool
+	}{
+		{"latest by default", loader.VersionLatest, url, "Patient.gender", true},
+		{"first loaded", loader.VersionFirstLoaded, url, "Patient.birthDate", true},
+		{"versioned", loader.VersionLatest, url + "|3.1.1", "Patient.birthDate", false},
+		{"major.minor", loader.VersionFirstLoaded, url + "|6.1", "Patient.gender", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := New(WithPackagePath(cache),
+				WithPackage("example.ig", "3.1.1"), WithPackage("example.ig", "6.1.0"),
+				WithVersionPolicy(tt.policy))
+			if err != nil {
+				t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+			}
+			resource := `{"resourceType": "Patient", "meta": {"profile": ["` + tt.profile + `"]}}`
+			result, err := v.Validate(context.Background(), []byte(resource))
+			if err != nil {
+				t.Fatalf("Validate() error: %v", err)
+			}
+			if got := missing(result); !slices.Equal(got, []string{tt.wantMissing}) {
+				t.Errorf("missing elements = %v, want [%s]", got, tt.wantMissing)
+			}
+			if got := ambiguous(result); got != tt.wantAmbiguous {
+				t.Errorf("ambiguity warning = %v, want %v", got, tt.wantAmbiguous)
+			}
+		})
+	}
+}
+
+func TestCanonicalMapping(t *testing.T) {
+	v, err := New(WithCanonicalMapping(map[string]string{
+		"https://internal.example.org/fhir/": "http://hl7.org/fhir/",
+	}))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	ctx := context.Background()
+
+	// The mirrored vital signs profile requires Observation.category
+	result, err := v.Validate(ctx, []byte(`{
+		"resourceType": "Observation", "status": "final",
+		"meta": {"profile": ["https://internal.example.org/fhir/StructureDefinition/bp"]},
+		"code": {"text": "BP"}
+	}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if !result.Stats.IsCustomProfile {
+		t.Errorf("mirrored profile not resolved: %v", result.Issues)
+	}
+	if !slices.ContainsFunc(result.Issues, func(i issue.Issue) bool {
+		return i.MessageID == string(issue.DiagCardinalityMin) && slices.Contains(i.Expression, "Observation.category")
+	}) {
+		t.Errorf("mirrored profile not applied: %v", result.Issues)
+	}
+
+	// The mirrored birthPlace extension takes an Address
+	result, err = v.Validate(ctx, []byte(`{
+		"resourceType": "Patient",
+		"extension": [{"url": "https://internal.example.org/fhir/StructureDefinition/patient-birthPlace", "valueString": "Lima"}]
+	}`))
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if !result.HasErrors() {
+		t.Errorf("mirrored extension definition not applied: %v", result.Issues)
+	}
+}
+
+func TestVersionedProfileCaches(t *testing.T) {
+	const url = "http://example.org/fhir/StructureDefinition/fixed-gender-patient"
+	// Each version fixes another gender; indexes built for one version must
+	// not be reused for the other
+	profile := func(version, gender string) string {
+		return `{
+			"resourceType": "StructureDefinition", "url": "` + url + `", "version": "` + version + `",
+			"name": "FixedGenderPatient", "status": "active", "kind": "resource", "abstract": false,
+			"type": "Patient", "baseDefinition": "http://hl7.org/fhir/StructureDefinition/Patient",
+			"derivation": "constraint",
+			"snapshot": {"element": [
+				{"id": "Patient", "path": "Patient", "min": 0, "max": "*"},
+				{"id": "Patient.gender", "path": "Patient.gender", "min": 0, "max": "1", "type": [{"code": "code"}],
+					"fixedCode": "` + gender + `"}
+			]}
+		}`
+	}
+	cache := t.TempDir()
+	writeCachePackage(t, cache, "example.ig", "1.0.0", map[string]string{"StructureDefinition-p.json": profile("1.0.0", "male")})
+	writeCachePackage(t, cache, "example.ig", "2.0.0", map[string]string{"StructureDefinition-p.json": profile("2.0.0", "female")})
+
+	v, err := New(WithPackagePath(cache), WithPackage("example.ig", "1.0.0"), WithPackage("example.ig", "2.0.0"))
+	if err != nil {
+		t.Skipf("Cannot create validator (packages may not be installed): %v", err)
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		version string
+		want    string
+	}{
+		{"1.0.0", ""},
+		{"2.0.0", `Value must be exactly '"female"'`},
+		{"1.0.0", ""},
+	} {
+		resource := `{"resourceType": "Patient", "gender": "male"}`
+		result, err := v.Validate(ctx, []byte(resource), ValidateWithProfile(url+"|"+tt.version))
+		if err != nil {
+			t.Fatalf("Validate() error: %v", err)
+		}
+		var got string
+		for _, iss := range result.Issues {
+			if len(iss.Expression) > 0 && iss.Expression[0] == "Patient.gender" && iss.Severity == issue.SeverityError {
+				got = iss.Diagnostics
+			}
+		}
+		if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+			t.Errorf("|%s: gender issue = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	if got := formatDelta(96<<20, 112<<20); got != "+16.0 MB" {
+		t.Errorf("formatDelta() growth = %q, want +16.0 MB", got)
+	}
+	if got := formatDelta(112<<20, 96<<20); got != "-16.0 MB" {
+		t.Errorf("formatDelta() shrink = %q, want -16.0 MB", got)
+	}
+}
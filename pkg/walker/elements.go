@@ -0,0 +1,138 @@
+package walker
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// ElementContext describes a complex element value visited by WalkElements.
+type ElementContext struct {
+	// Data is the element value.
+	Data map[string]any
+
+	// Name is the JSON property name (e.g., "valueQuantity").
+	Name string
+
+	// Type is the element's type, resolved from the property name for choice
+	// elements (e.g., "Quantity" for "valueQuantity").
+	Type string
+
+	// Element is the ElementDefinition of the element.
+	Element *registry.ElementDefinition
+
+	// SD is the StructureDefinition that defines Element: the resource's
+	// StructureDefinition for its own and backbone elements, the data type's
+	// for elements of data types.
+	SD *registry.StructureDefinition
+
+	// FHIRPath is the path to the value (e.g., "Patient.identifier[0]").
+	FHIRPath string
+}
+
+// ElementVisitor is called for each complex element value found by
+// WalkElements. Return false to skip the element's descendants.
+type ElementVisitor func(ec *ElementContext) bool
+
+// WalkElements visits the complex element values of a resource or element
+// and their descendants, depth first, resolving each property against sd
+// (sdPath is the SD path of data, e.g., "Patient"). Backbone elements are
+// resolved in the same StructureDefinition and data type elements in the
+// type's StructureDefinition. Contained and Bundle entry resources are not
+// visited; use Walk to reach them.
+func (w *Walker) WalkElements(data map[string]any, sd *registry.StructureDefinition, sdPath, fhirPath string, visit ElementVisitor) {
+	if sd == nil || sd.Snapshot == nil {
+		return
+	}
+	idx := w.elementIndex(sd)
+
+	for key, value := range data {
+		if key == "resourceType" || key == "contained" {
+			continue
+		}
+		elemDef, typeName := idx.resolve(sdPath, key)
+		if elemDef == nil || typeName == "" {
+			continue
+		}
+
+		elementPath := fhirPath + "." + key
+		items, isArray := value.([]any)
+		if !isArray {
+			items = []any{value}
+		}
+		for i, item := range items {
+			itemMap, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			itemPath := elementPath
+			if isArray {
+				itemPath = fmt.Sprintf("%s[%d]", elementPath, i)
+			}
+
+			ec := &ElementContext{Data: itemMap, Name: key, Type: typeName, Element: elemDef, SD: sd, FHIRPath: itemPath}
+			if !visit(ec) {
+				continue
+			}
+			if typeName == "BackboneElement" || typeName == "Element" {
+				w.WalkElements(itemMap, sd, elemDef.Path, itemPath, visit)
+				continue
+			}
+			typeSD := w.registry.GetByType(typeName)
+			if typeSD == nil || typeSD.Kind == "primitive-type" || typeSD.Kind == "resource" {
+				continue
+			}
+			w.WalkElements(itemMap, typeSD, typeName, itemPath, visit)
+		}
+	}
+}
+
+// elementIndex indexes the snapshot elements of a StructureDefinition by
+// path (the first element of each path, i.e., not slices).
+type elementIndex struct {
+	byPath map[string]*registry.ElementDefinition
+}
+
+// elementIndex returns the cached element index of a StructureDefinition.
+func (w *Walker) elementIndex(sd *registry.StructureDefinition) *elementIndex {
+	if cached, ok := w.indexes.Load(sd); ok {
+		return cached.(*elementIndex)
+	}
+	idx := &elementIndex{byPath: make(map[string]*registry.ElementDefinition, len(sd.Snapshot.Element))}
+	for i := range sd.Snapshot.Element {
+		elem := &sd.Snapshot.Element[i]
+		if _, ok := idx.byPath[elem.Path]; !ok {
+			idx.byPath[elem.Path] = elem
+		}
+	}
+	w.indexes.Store(sd, idx)
+	return idx
+}
+
+// resolve returns the ElementDefinition of a JSON property and its type,
+// resolving choice elements (e.g., valueQuantity against value[x]).
+func (idx *elementIndex) resolve(sdPath, key string) (*registry.ElementDefinition, string) {
+	if elemDef := idx.byPath[sdPath+"."+key]; elemDef != nil {
+		if len(elemDef.Type) != 1 {
+			return elemDef, ""
+		}
+		return elemDef, elemDef.Type[0].Code
+	}
+	for i, r := range key {
+		if i == 0 || !unicode.IsUpper(r) {
+			continue
+		}
+		elemDef := idx.byPath[sdPath+"."+key[:i]+"[x]"]
+		if elemDef == nil {
+			continue
+		}
+		for _, t := range elemDef.Type {
+			if strings.EqualFold(t.Code, key[i:]) {
+				return elemDef, t.Code
+			}
+		}
+	}
+	return nil, ""
+}
@@ -0,0 +1,176 @@
+package walker
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+// ErrPathNotFound is returned by ResolvePath when a path does not name an
+// element of the profile.
+var ErrPathNotFound = errors.New("element path not found")
+
+var indexRegex = regexp.MustCompile(`\[\d+\]`)
+
+// ResolvedElement is the effective definition of an element path in a
+// profile, for editor hover and completion.
+type ResolvedElement struct {
+	// Element is the ElementDefinition from the profile snapshot, or from the
+	// element type's StructureDefinition for elements the profile does not
+	// constrain.
+	Element *registry.ElementDefinition
+
+	// Type is the element's type when it is known: its only type, or the type
+	// selected by a choice element name (e.g., "Quantity" for "valueQuantity").
+	Type string
+
+	// Source is the URL of the StructureDefinition Element was taken from.
+	Source string
+
+	// Children are the definitions of the element's direct children, without
+	// slices.
+	Children []*registry.ElementDefinition
+}
+
+// ResolvePath returns the effective definition of path in the profile, e.g.
+// "Patient.contact.relationship" or "Observation.valueQuantity.unit". Array
+// indices are ignored and "name:slice" selects a slice. Paths below the
+// elements the profile constrains resolve through the element's type (its
+// type profile, if one is declared).
+func (w *Walker) ResolvePath(profileURL, path string) (*ResolvedElement, error) {
+	sd := w.registry.GetByURL(profileURL)
+	if sd == nil {
+		return nil, fmt.Errorf("profile %s not found", profileURL)
+	}
+	if sd.Snapshot == nil || len(sd.Snapshot.Element) == 0 {
+		return nil, fmt.Errorf("profile %s has no snapshot", profileURL)
+	}
+
+	segments := strings.Split(stripIndices(path), ".")
+	root := sd.Snapshot.Element[0].Path
+	if segments[0] != root && segments[0] != sd.Type {
+		return nil, fmt.Errorf("%w: %s does not start with %s", ErrPathNotFound, path, root)
+	}
+
+	elem, typeName := &sd.Snapshot.Element[0], sd.Type
+	for i, seg := range segments[1:] {
+		// Descend: the parent's children are in the current definition, or
+		// else in the definition of its type
+		parentID := elem.ElementID()
+		switch {
+		case elem.ContentReference != nil && *elem.ContentReference != "":
+			parentID = strings.TrimPrefix(*elem.ContentReference, "#")
+		case i == 0 || sd.HasChildren(parentID):
+		default:
+			typeSD := w.typeDefinition(elem, typeName)
+			if typeSD == nil {
+				return nil, fmt.Errorf("%w: %s has no children", ErrPathNotFound, strings.Join(segments[:i+1], "."))
+			}
+			sd, parentID = typeSD, typeSD.Snapshot.Element[0].ElementID()
+		}
+
+		name, slice, _ := strings.Cut(seg, ":")
+		elem, typeName = findElement(sd, parentID, name, slice)
+		if elem == nil {
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, strings.Join(segments[:i+2], "."))
+		}
+	}
+
+	return &ResolvedElement{
+		Element:  elem,
+		Type:     typeName,
+		Source:   sd.URL,
+		Children: w.children(sd, elem, typeName),
+	}, nil
+}
+
+// typeDefinition returns the StructureDefinition of an element's type: its
+// type profile if it declares one, or else the base type.
+func (w *Walker) typeDefinition(elem *registry.ElementDefinition, typeName string) *registry.StructureDefinition {
+	if typeName == "" {
+		return nil
+	}
+	var typeSD *registry.StructureDefinition
+	for _, t := range elem.Type {
+		if t.Code == typeName && len(t.Profile) == 1 {
+			typeSD = w.registry.GetByURL(t.Profile[0])
+		}
+	}
+	if typeSD == nil {
+		typeSD = w.registry.GetByType(typeName)
+	}
+	if typeSD == nil || typeSD.Snapshot == nil || len(typeSD.Snapshot.Element) == 0 {
+		return nil
+	}
+	return typeSD
+}
+
+// children returns the direct children of elem, from sd or from its type.
+func (w *Walker) children(sd *registry.StructureDefinition, elem *registry.ElementDefinition, typeName string) []*registry.ElementDefinition {
+	parentID := elem.ElementID()
+	switch {
+	case elem.ContentReference != nil && *elem.ContentReference != "":
+		parentID = strings.TrimPrefix(*elem.ContentReference, "#")
+	case !sd.HasChildren(parentID):
+		typeSD := w.typeDefinition(elem, typeName)
+		if typeSD == nil {
+			return nil
+		}
+		sd, parentID = typeSD, typeSD.Snapshot.Element[0].ElementID()
+	}
+
+	prefix := parentID + "."
+	var children []*registry.ElementDefinition
+	for i := range sd.Snapshot.Element {
+		child := &sd.Snapshot.Element[i]
+		rest, ok := strings.CutPrefix(child.ElementID(), prefix)
+		if ok && !strings.ContainsAny(rest, ".:") {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// findElement returns the ElementDefinition of a child element (or of one of
+// its slices) and its type, resolving choice elements (e.g., "valueQuantity"
+// to "value[x]", or to its type slice if the profile defines one).
+func findElement(sd *registry.StructureDefinition, parentID, name, slice string) (*registry.ElementDefinition, string) {
+	id := parentID + "." + name
+	if slice != "" {
+		id += ":" + slice
+	}
+	if elem := sd.ElementByID(id); elem != nil {
+		typeName := ""
+		if len(elem.Type) == 1 {
+			typeName = elem.Type[0].Code
+		}
+		return elem, typeName
+	}
+
+	for i := range sd.Snapshot.Element {
+		choice := &sd.Snapshot.Element[i]
+		base, isChoice := strings.CutSuffix(choice.ElementID(), "[x]")
+		if !isChoice || !strings.HasPrefix(id, base) || len(id) == len(base) {
+			continue
+		}
+		suffix := id[len(base):]
+		for _, t := range choice.Type {
+			if !strings.EqualFold(t.Code, suffix) {
+				continue
+			}
+			if typeSlice := sd.ElementByID(choice.ElementID() + ":" + name); typeSlice != nil {
+				return typeSlice, t.Code
+			}
+			return choice, t.Code
+		}
+	}
+	return nil, ""
+}
+
+// stripIndices removes array indices: "Patient.name[0].given" -> "Patient.name.given".
+func stripIndices(path string) string {
+	return indexRegex.ReplaceAllString(path, "")
+}
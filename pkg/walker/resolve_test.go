@@ -0,0 +1,67 @@
+package walker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gofhir/validator/pkg/loader"
+	"github.com/gofhir/validator/pkg/registry"
+)
+
+func TestResolvePath(t *testing.T) {
+	l := loader.NewLoader("")
+	packages, err := l.LoadVersion("4.0.1")
+	if err != nil {
+		t.Skipf("Cannot load FHIR packages: %v", err)
+	}
+	reg := registry.New()
+	if err := reg.LoadFromPackages(packages); err != nil {
+		t.Fatalf("LoadFromPackages failed: %v", err)
+	}
+	w := New(reg)
+
+	tests := []struct {
+		profile  string
+		path     string
+		wantID   string
+		wantType string
+		child    string // a child that must be offered
+	}{
+		{"http://hl7.org/fhir/StructureDefinition/Patient", "Patient", "Patient", "Patient", "Patient.contact"},
+		{"http://hl7.org/fhir/StructureDefinition/Patient", "Patient.contact[0].relationship", "Patient.contact.relationship", "CodeableConcept", "CodeableConcept.coding"},
+		{"http://hl7.org/fhir/StructureDefinition/Patient", "Patient.name.family", "HumanName.family", "string", "string.value"},
+		{"http://hl7.org/fhir/StructureDefinition/Observation", "Observation.valueQuantity", "Observation.value[x]", "Quantity", "Quantity.unit"},
+		{"http://hl7.org/fhir/StructureDefinition/Questionnaire", "Questionnaire.item.item.linkId", "Questionnaire.item.linkId", "string", ""},
+		{"http://hl7.org/fhir/StructureDefinition/bodyweight", "Observation.code.coding:BodyWeightCode", "Observation.code.coding:BodyWeightCode", "Coding", "Observation.code.coding:BodyWeightCode.system"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resolved, err := w.ResolvePath(tt.profile, tt.path)
+			if err != nil {
+				t.Fatalf("ResolvePath() error = %v", err)
+			}
+			if resolved.Element.ID != tt.wantID || resolved.Type != tt.wantType {
+				t.Errorf("ResolvePath() = %s (%s), want %s (%s)", resolved.Element.ID, resolved.Type, tt.wantID, tt.wantType)
+			}
+			if resolved.Element.GetShort() == "" {
+				t.Error("resolved element has no short description")
+			}
+			if tt.child == "" {
+				return
+			}
+			for _, child := range resolved.Children {
+				if child.ID == tt.child {
+					return
+				}
+			}
+			t.Errorf("children do not include %s", tt.child)
+		})
+	}
+
+	if _, err := w.ResolvePath("http://hl7.org/fhir/StructureDefinition/Patient", "Patient.nope"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("ResolvePath(unknown element) error = %v, want ErrPathNotFound", err)
+	}
+	if _, err := w.ResolvePath("http://example.org/missing", "Patient"); err == nil {
+		t.Error("ResolvePath(unknown profile) should fail")
+	}
+}
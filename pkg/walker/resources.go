@@ -0,0 +1,33 @@
+package walker
+
+// ContainedResource returns the contained resource of a container with an id.
+func ContainedResource(container map[string]any, id string) map[string]any {
+	contained, _ := container["contained"].([]any)
+	for _, item := range contained {
+		if res, ok := item.(map[string]any); ok {
+			if resID, _ := res["id"].(string); resID == id && id != "" {
+				return res
+			}
+		}
+	}
+	return nil
+}
+
+// CollectReferences appends the reference strings found in data.
+func CollectReferences(data any, refs []string) []string {
+	switch node := data.(type) {
+	case map[string]any:
+		for key, value := range node {
+			if s, ok := value.(string); ok && key == "reference" {
+				refs = append(refs, s)
+				continue
+			}
+			refs = CollectReferences(value, refs)
+		}
+	case []any:
+		for _, item := range node {
+			refs = CollectReferences(item, refs)
+		}
+	}
+	return refs
+}
@@ -4,6 +4,7 @@ package walker
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/gofhir/validator/pkg/registry"
 )
@@ -42,6 +43,7 @@ type ResourceVisitor func(ctx *ResourceContext) bool
 // Walker traverses FHIR resources, including Bundle entries and contained resources.
 type Walker struct {
 	registry *registry.Registry
+	indexes  sync.Map // *registry.StructureDefinition -> *elementIndex
 }
 
 // New creates a new Walker.
@@ -0,0 +1,205 @@
+// Package warmset records which profiles and ValueSets production traffic
+// resolves, and persists the most frequent ones to a warm-set file so that a
+// restarted validator can pre-warm exactly those instead of whole IGs.
+//
+// Usage is tracked over a sliding window: a fixed-size ring buffer of the most
+// recent resolutions, with per-URL counts updated as entries enter and leave
+// the window.
+package warmset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Kind is the kind of conformance resource being tracked.
+type Kind string
+
+const (
+	// KindProfile is a StructureDefinition used as a validation profile.
+	KindProfile Kind = "profile"
+	// KindValueSet is a ValueSet resolved for a binding.
+	KindValueSet Kind = "valueset"
+)
+
+// DefaultWindow is the default number of resolutions kept by a Tracker.
+const DefaultWindow = 4096
+
+// fileVersion is the format version of warm-set files.
+const fileVersion = 1
+
+// key identifies a tracked resource.
+type key struct {
+	kind Kind
+	url  string
+}
+
+// Entry is a tracked resource and how often it was resolved within the window.
+type Entry struct {
+	Kind  Kind   `json:"kind"`
+	URL   string `json:"url"`
+	Count int    `json:"count"`
+}
+
+// Stats summarizes the resolutions within a Tracker's window.
+type Stats struct {
+	// Window is the capacity of the ring buffer.
+	Window int
+	// Recorded is the number of resolutions currently in the window.
+	Recorded int
+	// Profiles are the most frequently resolved profiles, most frequent first.
+	Profiles []Entry
+	// ValueSets are the most frequently resolved ValueSets, most frequent first.
+	ValueSets []Entry
+}
+
+// Tracker counts resolutions over a sliding window. It is safe for concurrent use.
+type Tracker struct {
+	mu     sync.Mutex
+	ring   []key
+	next   int
+	full   bool
+	counts map[key]int
+}
+
+// NewTracker creates a Tracker keeping the last window resolutions.
+// Values below 1 use DefaultWindow.
+func NewTracker(window int) *Tracker {
+	if window < 1 {
+		window = DefaultWindow
+	}
+	return &Tracker{
+		ring:   make([]key, window),
+		counts: make(map[key]int),
+	}
+}
+
+// Record records a resolution of url.
+func (t *Tracker) Record(kind Kind, url string) {
+	k := key{kind: kind, url: url}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.full {
+		evicted := t.ring[t.next]
+		if t.counts[evicted] <= 1 {
+			delete(t.counts, evicted)
+		} else {
+			t.counts[evicted]--
+		}
+	}
+	t.ring[t.next] = k
+	t.counts[k]++
+	t.next++
+	if t.next == len(t.ring) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// Stats returns up to n of the most frequently resolved profiles and
+// ValueSets each. n <= 0 returns all of them.
+func (t *Tracker) Stats(n int) *Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := &Stats{Window: len(t.ring), Recorded: t.next}
+	if t.full {
+		stats.Recorded = len(t.ring)
+	}
+	for k, count := range t.counts {
+		e := Entry{Kind: k.kind, URL: k.url, Count: count}
+		switch k.kind {
+		case KindProfile:
+			stats.Profiles = append(stats.Profiles, e)
+		case KindValueSet:
+			stats.ValueSets = append(stats.ValueSets, e)
+		}
+	}
+	stats.Profiles = top(stats.Profiles, n)
+	stats.ValueSets = top(stats.ValueSets, n)
+	return stats
+}
+
+// top sorts entries by descending count (then URL) and keeps the first n.
+func top(entries []Entry, n int) []Entry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].URL < entries[j].URL
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// File is the persisted warm set.
+type File struct {
+	Version     int      `json:"version"`
+	FHIRVersion string   `json:"fhirVersion"`
+	Profiles    []string `json:"profiles,omitempty"`
+	ValueSets   []string `json:"valueSets,omitempty"`
+}
+
+// NewFile builds a warm-set File from tracker statistics.
+func NewFile(fhirVersion string, stats *Stats) *File {
+	f := &File{Version: fileVersion, FHIRVersion: fhirVersion}
+	for _, e := range stats.Profiles {
+		f.Profiles = append(f.Profiles, e.URL)
+	}
+	for _, e := range stats.ValueSets {
+		f.ValueSets = append(f.ValueSets, e.URL)
+	}
+	return f
+}
+
+// Save writes the warm set to path. The file is replaced atomically so a
+// crash never leaves a truncated warm set behind.
+func (f *File) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode warm set: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".warmset-*")
+	if err != nil {
+		return fmt.Errorf("failed to write warm set: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write warm set: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write warm set: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write warm set: %w", err)
+	}
+	return nil
+}
+
+// Load reads a warm-set file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid warm set %s: %w", path, err)
+	}
+	if f.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported warm set version %d in %s", f.Version, path)
+	}
+	return &f, nil
+}
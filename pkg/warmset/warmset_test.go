@@ -0,0 +1,104 @@
+package warmset
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestTrackerWindow(t *testing.T) {
+	tr := NewTracker(4)
+	tr.Record(KindProfile, "a")
+	tr.Record(KindProfile, "a")
+	tr.Record(KindProfile, "b")
+	tr.Record(KindValueSet, "vs")
+
+	stats := tr.Stats(0)
+	if stats.Window != 4 || stats.Recorded != 4 {
+		t.Errorf("Window/Recorded = %d/%d, want 4/4", stats.Window, stats.Recorded)
+	}
+	want := []Entry{{KindProfile, "a", 2}, {KindProfile, "b", 1}}
+	if !reflect.DeepEqual(stats.Profiles, want) {
+		t.Errorf("Profiles = %v, want %v", stats.Profiles, want)
+	}
+
+	// Two more resolutions evict both "a" entries
+	tr.Record(KindProfile, "c")
+	tr.Record(KindProfile, "c")
+
+	stats = tr.Stats(0)
+	want = []Entry{{KindProfile, "c", 2}, {KindProfile, "b", 1}}
+	if !reflect.DeepEqual(stats.Profiles, want) {
+		t.Errorf("after eviction Profiles = %v, want %v", stats.Profiles, want)
+	}
+	if len(stats.ValueSets) != 1 || stats.ValueSets[0].URL != "vs" {
+		t.Errorf("ValueSets = %v, want [vs]", stats.ValueSets)
+	}
+
+	if top := tr.Stats(1); len(top.Profiles) != 1 || top.Profiles[0].URL != "c" {
+		t.Errorf("Stats(1).Profiles = %v, want [c]", top.Profiles)
+	}
+}
+
+func TestTrackerConcurrent(t *testing.T) {
+	tr := NewTracker(100)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				tr.Record(KindValueSet, "vs")
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := tr.Stats(0)
+	if len(stats.ValueSets) != 1 || stats.ValueSets[0].Count != 100 {
+		t.Errorf("ValueSets = %v, want one entry with count 100", stats.ValueSets)
+	}
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	tr := NewTracker(10)
+	tr.Record(KindProfile, "http://example.org/StructureDefinition/p")
+	tr.Record(KindValueSet, "http://hl7.org/fhir/ValueSet/administrative-gender")
+
+	path := filepath.Join(t.TempDir(), "warmset.json")
+	if err := NewFile("4.0.1", tr.Stats(0)).Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := &File{
+		Version:     fileVersion,
+		FHIRVersion: "4.0.1",
+		Profiles:    []string{"http://example.org/StructureDefinition/p"},
+		ValueSets:   []string{"http://hl7.org/fhir/ValueSet/administrative-gender"},
+	}
+	if !reflect.DeepEqual(f, want) {
+		t.Errorf("Load() = %+v, want %+v", f, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("Load(missing) error = %v, want not-exist", err)
+	}
+
+	badVersion := filepath.Join(dir, "v2.json")
+	if err := os.WriteFile(badVersion, []byte(`{"version":2,"fhirVersion":"4.0.1"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(badVersion); err == nil {
+		t.Error("Load() should reject unsupported versions")
+	}
+}
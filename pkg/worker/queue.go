@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errors returned by Submit, TrySubmit and in the Results of jobs dropped by
+// Close.
+var (
+	// ErrQueueFull is returned by TrySubmit when the queue is full.
+	ErrQueueFull = errors.New("worker: queue full")
+	// ErrClosed is returned for jobs submitted after Close, and is the Err of
+	// queued jobs that Close drops at its deadline.
+	ErrClosed = errors.New("worker: pool closed")
+)
+
+// queue holds the jobs submitted to a Pool, highest priority first. Its
+// size is bounded by slots: Submit takes a slot, a worker releases it when it
+// starts the job, and each queued job has a token in ready.
+type queue struct {
+	startOnce sync.Once
+	workers   sync.WaitGroup
+
+	mu     sync.Mutex
+	items  queueItems
+	seq    int
+	closed bool
+
+	slots   chan struct{}
+	ready   chan struct{}
+	closing chan struct{} // closed by Close to release blocked Submits
+
+	// ctx is canceled when Close reaches its deadline, canceling the jobs
+	// being validated; drop then makes workers drop queued jobs.
+	ctx    context.Context
+	cancel context.CancelFunc
+	drop   atomic.Bool
+}
+
+// newQueue creates a queue of size jobs.
+func newQueue(size int) *queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &queue{
+		slots:   make(chan struct{}, size),
+		ready:   make(chan struct{}, size),
+		closing: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// queueItem is a submitted job waiting for a worker.
+type queueItem struct {
+	task
+	ctx    context.Context
+	result chan Result
+}
+
+// queueItems is a heap of jobs by descending priority, in submission order
+// within a priority.
+type queueItems []*queueItem
+
+func (q queueItems) Len() int { return len(q) }
+func (q queueItems) Less(i, j int) bool {
+	if q[i].job.Priority != q[j].job.Priority {
+		return q[i].job.Priority > q[j].job.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q queueItems) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *queueItems) Push(x any)   { *q = append(*q, x.(*queueItem)) }
+func (q *queueItems) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Submit queues a job and returns a channel that receives its Result. If the
+// queue is full it blocks until a worker takes a job, ctx is done (returning
+// ctx.Err()) or the pool is closed (returning ErrClosed). The job is
+// validated with ctx, so canceling it after Submit cancels the validation.
+//
+// Queued jobs run highest Priority first. Workers start on the first Submit;
+// Close stops them.
+func (p *Pool) Submit(ctx context.Context, job Job) (<-chan Result, error) {
+	select {
+	case p.queue.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.queue.closing:
+		return nil, ErrClosed
+	}
+	return p.enqueue(ctx, job)
+}
+
+// TrySubmit is Submit without blocking: it returns ErrQueueFull when the
+// queue is full, so that a server can reject the request (e.g., with HTTP
+// 503) instead of waiting.
+func (p *Pool) TrySubmit(ctx context.Context, job Job) (<-chan Result, error) {
+	select {
+	case p.queue.slots <- struct{}{}:
+	default:
+		return nil, ErrQueueFull
+	}
+	return p.enqueue(ctx, job)
+}
+
+// enqueue queues a job for which a slot was taken.
+func (p *Pool) enqueue(ctx context.Context, job Job) (<-chan Result, error) {
+	q := p.queue
+	q.startOnce.Do(func() {
+		for range p.workers {
+			q.workers.Add(1)
+			go p.serve()
+		}
+	})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		<-q.slots
+		return nil, ErrClosed
+	}
+	item := &queueItem{
+		task:   task{job: job, seq: q.seq, enqueued: time.Now()},
+		ctx:    ctx,
+		result: make(chan Result, 1),
+	}
+	q.seq++
+	heap.Push(&q.items, item)
+	q.ready <- struct{}{}
+	return item.result, nil
+}
+
+// serve runs queued jobs until the pool is closed and its queue is empty.
+func (p *Pool) serve() {
+	q := p.queue
+	defer q.workers.Done()
+	for range q.ready {
+		q.mu.Lock()
+		item := heap.Pop(&q.items).(*queueItem)
+		q.mu.Unlock()
+		<-q.slots
+
+		if q.drop.Load() {
+			item.result <- Result{JobID: item.job.ID, Seq: item.seq, Err: ErrClosed, Enqueued: item.enqueued}
+			continue
+		}
+		ctx, cancel := context.WithCancel(item.ctx)
+		stop := context.AfterFunc(q.ctx, cancel)
+		item.result <- p.validate(ctx, item.task)
+		stop()
+		cancel()
+	}
+}
+
+// Close stops accepting jobs and waits until the queued and running jobs
+// are done. When ctx is done first, running validations are canceled,
+// queued jobs are dropped with ErrClosed, and Close returns ctx.Err() once
+// the workers have stopped. Results of jobs that completed are still
+// delivered. Close only affects Submit and TrySubmit, not Run.
+func (p *Pool) Close(ctx context.Context) error {
+	q := p.queue
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.closing)
+		close(q.ready)
+	}
+	q.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.drop.Store(true)
+		q.cancel()
+		<-stopped
+		return ctx.Err()
+	}
+}
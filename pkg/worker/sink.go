@@ -0,0 +1,159 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/reportstore"
+)
+
+// ResultSink receives validation results as jobs complete, so that large
+// batches can be written to files or a database instead of being kept in
+// memory. RunTo calls WriteResult from a single goroutine.
+type ResultSink interface {
+	WriteResult(jobID string, result *issue.Result) error
+}
+
+// RunTo validates jobs like Run and writes each result to sink as soon as it
+// is emitted. A job that fails to validate is written as a result with one
+// exception issue. A sink error stops validation and is returned; otherwise
+// RunTo returns ctx.Err() when ctx was canceled.
+func (p *Pool) RunTo(ctx context.Context, jobs <-chan Job, sink ResultSink) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var sinkErr error
+	for r := range p.Run(runCtx, jobs) {
+		if sinkErr != nil || runCtx.Err() != nil {
+			continue // Drain the remaining results
+		}
+		result := r.Result
+		if r.Err != nil {
+			result = issue.NewResult()
+			result.AddError(issue.CodeException, fmt.Sprintf("Validation failed: %v", r.Err))
+		}
+		if err := sink.WriteResult(r.JobID, result); err != nil {
+			sinkErr = fmt.Errorf("worker: write result of %s: %w", r.JobID, err)
+			cancel()
+		}
+	}
+	if sinkErr != nil {
+		return sinkErr
+	}
+	return ctx.Err()
+}
+
+// NDJSONSink writes each result as one JSON line:
+//
+//	{"job":"patient.json","resourceType":"Patient","id":"p1","valid":false,
+//	 "errors":1,"warnings":0,"info":0,"issues":[{"severity":"error",
+//	 "code":"value","diagnosticId":"...","diagnostics":"...",
+//	 "expression":["Patient.gender"],"line":3,"column":13,
+//	 "profile":"http://hl7.org/fhir/StructureDefinition/Patient"}]}
+//
+// Lines are written to w directly; wrap it in a bufio.Writer for throughput.
+type NDJSONSink struct {
+	enc *json.Encoder
+}
+
+// NewNDJSONSink creates a sink writing JSON lines to w.
+func NewNDJSONSink(w io.Writer) *NDJSONSink {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &NDJSONSink{enc: enc}
+}
+
+// ndjsonRecord is a line written by NDJSONSink.
+type ndjsonRecord struct {
+	Job          string        `json:"job"`
+	ResourceType string        `json:"resourceType,omitempty"`
+	ID           string        `json:"id,omitempty"`
+	Valid        bool          `json:"valid"`
+	Errors       int           `json:"errors"`
+	Warnings     int           `json:"warnings"`
+	Info         int           `json:"info"`
+	Issues       []ndjsonIssue `json:"issues,omitempty"`
+}
+
+// ndjsonIssue is an issue of an ndjsonRecord.
+type ndjsonIssue struct {
+	Severity     string        `json:"severity"`
+	Code         string        `json:"code"`
+	DiagnosticID string        `json:"diagnosticId,omitempty"`
+	Diagnostics  string        `json:"diagnostics"`
+	Expression   []string      `json:"expression,omitempty"`
+	Line         int           `json:"line,omitempty"`
+	Column       int           `json:"column,omitempty"`
+	Profile      string        `json:"profile,omitempty"`
+	Source       *issue.Source `json:"source,omitempty"`
+}
+
+// WriteResult implements ResultSink.
+func (s *NDJSONSink) WriteResult(jobID string, result *issue.Result) error {
+	record := ndjsonRecord{
+		Job:      jobID,
+		Valid:    !result.HasErrors(),
+		Errors:   result.ErrorCount(),
+		Warnings: result.WarningCount(),
+		Info:     result.InfoCount(),
+	}
+	if result.Stats != nil {
+		record.ResourceType, record.ID = result.Stats.ResourceType, result.Stats.ResourceID
+	}
+	for _, iss := range result.Issues {
+		out := ndjsonIssue{
+			Severity:     string(iss.Severity),
+			Code:         string(iss.Code),
+			DiagnosticID: iss.MessageID,
+			Diagnostics:  iss.Diagnostics,
+			Expression:   iss.Expression,
+			Profile:      iss.Profile,
+			Source:       iss.Source,
+		}
+		if iss.Location != nil {
+			out.Line, out.Column = iss.Location.Line, iss.Location.Column
+		}
+		record.Issues = append(record.Issues, out)
+	}
+	return s.enc.Encode(record)
+}
+
+// CSVSink writes one CSV or TSV row per issue (see issue.CSVHeader), with the
+// job ID in the file column. Rows are flushed after each result.
+type CSVSink struct {
+	w *issue.CSVWriter
+}
+
+// NewCSVSink creates a sink writing rows to w, using comma as field
+// separator (',' for CSV, '\t' for TSV).
+func NewCSVSink(w io.Writer, comma rune) *CSVSink {
+	return &CSVSink{w: issue.NewCSVWriter(w, comma)}
+}
+
+// WriteResult implements ResultSink.
+func (s *CSVSink) WriteResult(jobID string, result *issue.Result) error {
+	if err := s.w.Write(jobID, result); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// NewSQLiteSink records results in a SQLite database, as one run of the
+// reportstore package: its validation_runs, validation_results and
+// validation_issues tables are created if needed, and each result is written
+// in one transaction. Call Finish on the run to record its totals. The
+// database is opened by the caller with a SQLite driver of its choice (e.g.,
+// modernc.org/sqlite or github.com/mattn/go-sqlite3); use reportstore.Open
+// directly for PostgreSQL or to label the run.
+func NewSQLiteSink(db *sql.DB) (*reportstore.Run, error) {
+	ctx := context.Background()
+	store, err := reportstore.Open(ctx, db, reportstore.SQLite)
+	if err != nil {
+		return nil, err
+	}
+	return store.StartRun(ctx, reportstore.RunInfo{})
+}
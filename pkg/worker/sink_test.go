@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gofhir/validator/internal/testutil"
+	"github.com/gofhir/validator/pkg/issue"
+)
+
+// jobChan returns a closed channel holding jobs.
+func jobChan(jobs []Job) <-chan Job {
+	ch := make(chan Job, len(jobs))
+	for _, job := range jobs {
+		ch <- job
+	}
+	close(ch)
+	return ch
+}
+
+func TestRunToNDJSON(t *testing.T) {
+	v := getSharedValidator(t)
+	jobs := append(testJobs(5), Job{ID: "invalid", Data: []byte(`{"resourceType":"Patient","gender":1}`)})
+
+	var buf bytes.Buffer
+	if err := New(v, WithWorkers(2)).RunTo(context.Background(), jobChan(jobs), NewNDJSONSink(&buf)); err != nil {
+		t.Fatalf("RunTo() error: %v", err)
+	}
+
+	seen := map[string]ndjsonRecord{}
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var record ndjsonRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		seen[record.Job] = record
+	}
+	if len(seen) != len(jobs) {
+		t.Fatalf("got %d lines, want %d", len(seen), len(jobs))
+	}
+	invalid := seen["invalid"]
+	if invalid.Valid || invalid.Errors == 0 || len(invalid.Issues) == 0 || invalid.ResourceType != "Patient" {
+		t.Errorf("invalid job record = %+v", invalid)
+	}
+	if !seen["job-1"].Valid {
+		t.Errorf("job-1 record = %+v", seen["job-1"])
+	}
+}
+
+// failingSink fails after n results.
+type failingSink struct {
+	n       int
+	written int
+}
+
+func (s *failingSink) WriteResult(string, *issue.Result) error {
+	if s.written == s.n {
+		return errors.New("disk full")
+	}
+	s.written++
+	return nil
+}
+
+func TestRunToSinkError(t *testing.T) {
+	v := getSharedValidator(t)
+	sink := &failingSink{n: 2}
+	err := New(v, WithWorkers(2)).RunTo(context.Background(), jobChan(testJobs(10)), sink)
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("RunTo() error = %v, want the sink error", err)
+	}
+	if sink.written != 2 {
+		t.Errorf("written = %d after the error, want 2", sink.written)
+	}
+}
+
+func TestCSVSink(t *testing.T) {
+	result := issue.NewResult()
+	result.AddError(issue.CodeValue, "bad gender", "Patient.gender")
+
+	var buf bytes.Buffer
+	sink := NewCSVSink(&buf, '\t')
+	if err := sink.WriteResult("a.json", result); err != nil {
+		t.Fatalf("WriteResult() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "a.json\t") || !strings.Contains(lines[1], "bad gender") {
+		t.Errorf("rows = %q", lines)
+	}
+}
+
+func TestSQLiteSink(t *testing.T) {
+	db, recorder := testutil.OpenSQLRecorder(t)
+	sink, err := NewSQLiteSink(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteSink() error: %v", err)
+	}
+	result := issue.NewResult()
+	result.Stats = &issue.Stats{ResourceType: "Patient", ResourceID: "p1"}
+	result.AddIssue(issue.Issue{Severity: issue.SeverityError, Code: issue.CodeValue,
+		Diagnostics: "bad gender", Expression: []string{"Patient.gender"}, MessageID: "CODE_INVALID"})
+	if err := sink.WriteResult("a.json", result); err != nil {
+		t.Fatalf("WriteResult() error: %v", err)
+	}
+
+	if err := sink.Finish(context.Background()); err != nil {
+		t.Fatalf("Finish() error: %v", err)
+	}
+
+	// The tables of the reportstore package, then the run and its result
+	want := []string{
+		`CREATE TABLE IF`, `CREATE TABLE IF`, `CREATE TABLE IF`, `CREATE INDEX IF`, `CREATE INDEX IF`, `CREATE INDEX IF`,
+		`INSERT INTO validation_runs`,
+		`INSERT INTO validation_results`,
+		`INSERT INTO validation_issues`,
+		`UPDATE validation_runs SET`,
+	}
+	execs := recorder.Take()
+	if len(execs) != len(want) {
+		t.Fatalf("statements:\n%s", strings.Join(execs, "\n"))
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(execs[i], prefix) {
+			t.Errorf("statement %d = %s, want %s", i, execs[i], prefix)
+		}
+	}
+	if issueRow := execs[8]; !strings.Contains(issueRow, `"a.json","error","value","CODE_INVALID","Patient.gender"`) {
+		t.Errorf("issue row = %s", issueRow)
+	}
+}
@@ -0,0 +1,255 @@
+// Package worker validates resources concurrently with a shared Validator.
+//
+// Run and ValidateAll validate batches. By default results are emitted in
+// completion order. In ordered mode results are emitted in submission order;
+// the number of jobs in flight is bounded by the reordering buffer, so a slow
+// job holds back at most that many results.
+//
+// Submit and TrySubmit serve jobs one at a time, as a server does: jobs wait
+// in a bounded queue, highest priority first, and Submit blocks (TrySubmit
+// fails with ErrQueueFull) while the queue is full. Close drains the queue
+// with a deadline.
+package worker
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gofhir/validator/pkg/issue"
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+// Job is a single resource to validate.
+type Job struct {
+	// ID identifies the job to the caller (e.g., a file name); it is not interpreted.
+	ID string
+	// Data is the resource JSON.
+	Data []byte
+	// Options are per-call validation options (e.g., ValidateWithProfile).
+	Options []validator.ValidateOption
+	// Priority orders submitted jobs waiting for a worker: higher first, then
+	// in submission order. Run ignores it.
+	Priority int
+}
+
+// Result is the outcome of a Job with its timing metadata.
+type Result struct {
+	// JobID is the ID of the Job.
+	JobID string
+	// Seq is the zero-based submission index of the Job.
+	Seq int
+	// Result is the validation result (nil when Err is set).
+	Result *issue.Result
+	// Err is the error returned by Validate.
+	Err error
+
+	// Enqueued is when the pool received the job.
+	Enqueued time.Time
+	// Started is when a worker began validating the job.
+	Started time.Time
+	// Finished is when validation completed.
+	Finished time.Time
+}
+
+// Duration returns the validation time of the job.
+func (r *Result) Duration() time.Duration {
+	return r.Finished.Sub(r.Started)
+}
+
+// QueueTime returns how long the job waited for a worker.
+func (r *Result) QueueTime() time.Duration {
+	return r.Started.Sub(r.Enqueued)
+}
+
+// Pool validates jobs concurrently.
+type Pool struct {
+	validator  *validator.Validator
+	workers    int
+	ordered    bool
+	bufferSize int
+	queueSize  int
+	queue      *queue
+}
+
+// Option configures a Pool.
+type Option func(*Pool)
+
+// WithWorkers sets the number of concurrent workers (default: runtime.NumCPU()).
+func WithWorkers(n int) Option {
+	return func(p *Pool) {
+		if n > 0 {
+			p.workers = n
+		}
+	}
+}
+
+// WithOrdered emits results in submission order. BufferSize bounds the number
+// of jobs in flight (and therefore of results held for reordering); values
+// below 1 default to twice the number of workers.
+func WithOrdered(bufferSize int) Option {
+	return func(p *Pool) {
+		p.ordered = true
+		p.bufferSize = bufferSize
+	}
+}
+
+// WithQueueSize bounds the number of submitted jobs waiting for a worker;
+// values below 1 default to twice the number of workers.
+func WithQueueSize(n int) Option {
+	return func(p *Pool) {
+		p.queueSize = n
+	}
+}
+
+// New creates a Pool that validates with v.
+func New(v *validator.Validator, opts ...Option) *Pool {
+	p := &Pool{
+		validator: v,
+		workers:   runtime.NumCPU(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.ordered && p.bufferSize < 1 {
+		p.bufferSize = 2 * p.workers
+	}
+	if p.queueSize < 1 {
+		p.queueSize = 2 * p.workers
+	}
+	p.queue = newQueue(p.queueSize)
+	return p
+}
+
+// task is a Job tagged with its submission index.
+type task struct {
+	job      Job
+	seq      int
+	enqueued time.Time
+}
+
+// Run validates jobs until the jobs channel is closed or ctx is cancelled.
+// The returned channel is closed after the last result; callers must drain it.
+func (p *Pool) Run(ctx context.Context, jobs <-chan Job) <-chan Result {
+	tasks := make(chan task)
+	done := make(chan Result, p.workers)
+	out := make(chan Result, p.workers)
+
+	// In ordered mode each in-flight job holds a slot until its result is emitted.
+	var slots chan struct{}
+	if p.ordered {
+		slots = make(chan struct{}, p.bufferSize)
+	}
+
+	// Dispatcher
+	go func() {
+		defer close(tasks)
+		seq := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case job, ok := <-jobs:
+				if !ok {
+					return
+				}
+				t := task{job: job, seq: seq, enqueued: time.Now()}
+				seq++
+				if slots != nil {
+					select {
+					case slots <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+				}
+				select {
+				case tasks <- t:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	// Workers
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				done <- p.validate(ctx, t)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Collector
+	go func() {
+		defer close(out)
+		if !p.ordered {
+			for r := range done {
+				out <- r
+			}
+			return
+		}
+
+		pending := make(map[int]Result, p.bufferSize)
+		next := 0
+		for r := range done {
+			pending[r.Seq] = r
+			for {
+				ready, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				out <- ready
+				<-slots
+			}
+		}
+	}()
+
+	return out
+}
+
+// ValidateAll validates a slice of jobs and returns the results in the same
+// order as the jobs, regardless of the pool's ordering mode. Jobs skipped
+// because ctx was cancelled are left as zero Results.
+func (p *Pool) ValidateAll(ctx context.Context, jobs []Job) []Result {
+	in := make(chan Job)
+	go func() {
+		defer close(in)
+		for _, job := range jobs {
+			select {
+			case in <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make([]Result, len(jobs))
+	for r := range p.Run(ctx, in) {
+		results[r.Seq] = r
+	}
+	return results
+}
+
+// validate runs a single task.
+func (p *Pool) validate(ctx context.Context, t task) Result {
+	r := Result{
+		JobID:    t.job.ID,
+		Seq:      t.seq,
+		Enqueued: t.enqueued,
+		Started:  time.Now(),
+	}
+	r.Result, r.Err = p.validator.Validate(ctx, t.job.Data, t.job.Options...)
+	r.Finished = time.Now()
+	return r
+}
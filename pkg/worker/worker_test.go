@@ -0,0 +1,312 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofhir/validator/pkg/validator"
+)
+
+var (
+	sharedValidator     *validator.Validator
+	sharedValidatorErr  error
+	sharedValidatorOnce sync.Once
+)
+
+// getSharedValidator returns a shared validator instance for tests.
+func getSharedValidator(t *testing.T) *validator.Validator {
+	t.Helper()
+	sharedValidatorOnce.Do(func() {
+		sharedValidator, sharedValidatorErr = validator.New()
+	})
+	if sharedValidatorErr != nil {
+		t.Fatalf("Failed to create validator: %v", sharedValidatorErr)
+	}
+	return sharedValidator
+}
+
+// testJobs builds n jobs alternating large and small resources so that
+// completion order differs from submission order.
+func testJobs(n int) []Job {
+	jobs := make([]Job, n)
+	for i := range jobs {
+		data := fmt.Sprintf(`{"resourceType":"Patient","id":"p%d"}`, i)
+		if i%3 == 0 {
+			names := ""
+			for j := range 200 {
+				if j > 0 {
+					names += ","
+				}
+				names += fmt.Sprintf(`{"family":"F%d","given":["G%d"]}`, j, j)
+			}
+			data = fmt.Sprintf(`{"resourceType":"Patient","id":"p%d","name":[%s]}`, i, names)
+		}
+		jobs[i] = Job{ID: fmt.Sprintf("job-%d", i), Data: []byte(data)}
+	}
+	return jobs
+}
+
+func TestPoolRun(t *testing.T) {
+	v := getSharedValidator(t)
+	jobs := testJobs(30)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		ordered bool
+	}{
+		{"unordered", []Option{WithWorkers(4)}, false},
+		{"ordered", []Option{WithWorkers(4), WithOrdered(3)}, true},
+		{"ordered default buffer", []Option{WithWorkers(4), WithOrdered(0)}, true},
+		{"ordered single slot", []Option{WithWorkers(4), WithOrdered(1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := make(chan Job)
+			go func() {
+				defer close(in)
+				for _, job := range jobs {
+					in <- job
+				}
+			}()
+
+			seen := make(map[int]bool)
+			idx := 0
+			for r := range New(v, tt.opts...).Run(context.Background(), in) {
+				if r.Err != nil {
+					t.Fatalf("job %s: %v", r.JobID, r.Err)
+				}
+				if r.JobID != jobs[r.Seq].ID {
+					t.Errorf("result Seq %d has JobID %q, want %q", r.Seq, r.JobID, jobs[r.Seq].ID)
+				}
+				if tt.ordered && r.Seq != idx {
+					t.Errorf("result %d has Seq %d, want submission order", idx, r.Seq)
+				}
+				if r.Started.Before(r.Enqueued) || r.Finished.Before(r.Started) {
+					t.Errorf("job %s: inconsistent timing %v/%v/%v", r.JobID, r.Enqueued, r.Started, r.Finished)
+				}
+				seen[r.Seq] = true
+				idx++
+			}
+
+			if len(seen) != len(jobs) {
+				t.Errorf("got %d results, want %d", len(seen), len(jobs))
+			}
+		})
+	}
+}
+
+func TestPoolValidateAll(t *testing.T) {
+	v := getSharedValidator(t)
+	jobs := testJobs(10)
+	jobs = append(jobs, Job{ID: "invalid", Data: []byte(`{"resourceType":"Patient","gender":1}`)})
+
+	results := New(v, WithWorkers(3)).ValidateAll(context.Background(), jobs)
+
+	if len(results) != len(jobs) {
+		t.Fatalf("got %d results, want %d", len(results), len(jobs))
+	}
+	for i, r := range results {
+		if r.JobID != jobs[i].ID || r.Seq != i {
+			t.Errorf("results[%d] = %s (Seq %d), want %s", i, r.JobID, r.Seq, jobs[i].ID)
+		}
+		if r.Result == nil {
+			t.Fatalf("results[%d] has no validation result", i)
+		}
+	}
+	if !results[len(results)-1].Result.HasErrors() {
+		t.Error("expected errors for the invalid job")
+	}
+}
+
+func TestPoolCancelled(t *testing.T) {
+	v := getSharedValidator(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	in := make(chan Job)
+	for range New(v, WithOrdered(2)).Run(ctx, in) {
+		t.Error("no results expected after cancellation")
+	}
+}
+
+// slowJob returns a job that takes a worker long enough to queue others.
+func slowJob(id string, priority int) Job {
+	var names strings.Builder
+	for i := range 3000 {
+		if i > 0 {
+			names.WriteString(",")
+		}
+		fmt.Fprintf(&names, `{"family":"F%d","given":["G%d"]}`, i, i)
+	}
+	data := fmt.Sprintf(`{"resourceType":"Patient","name":[%s]}`, names.String())
+	return Job{ID: id, Data: []byte(data), Priority: priority}
+}
+
+func TestPoolSubmit(t *testing.T) {
+	v := getSharedValidator(t)
+	p := New(v, WithWorkers(2))
+	ctx := context.Background()
+
+	var results []<-chan Result
+	for _, job := range testJobs(6) {
+		ch, err := p.Submit(ctx, job)
+		if err != nil {
+			t.Fatalf("Submit(%s) error: %v", job.ID, err)
+		}
+		results = append(results, ch)
+	}
+	invalid, err := p.Submit(ctx, Job{
+		ID:      "profiled",
+		Data:    []byte(`{"resourceType":"Observation","status":"final","code":{"text":"x"}}`),
+		Options: []validator.ValidateOption{validator.ValidateWithProfile("http://hl7.org/fhir/StructureDefinition/vitalsigns")},
+	})
+	if err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+
+	for i, ch := range results {
+		r := <-ch
+		if r.JobID != fmt.Sprintf("job-%d", i) || r.Seq != i || r.Err != nil || r.Result == nil {
+			t.Errorf("result %d = %+v", i, r)
+		}
+	}
+	if r := <-invalid; r.Err != nil || !r.Result.HasErrors() {
+		t.Errorf("per-job profile not applied: %+v", r)
+	}
+
+	if err := p.Close(ctx); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+	if _, err := p.Submit(ctx, testJobs(1)[0]); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestPoolPriority(t *testing.T) {
+	v := getSharedValidator(t)
+	p := New(v, WithWorkers(1), WithQueueSize(4))
+	ctx := context.Background()
+
+	// The slow job keeps the only worker busy while the others queue
+	first, err := p.Submit(ctx, slowJob("slow", 10))
+	if err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	priorities := map[string]int{"low": -1, "high": 5, "normal": 0}
+	results := map[string]<-chan Result{}
+	for _, id := range []string{"low", "high", "normal"} {
+		job := testJobs(1)[0]
+		job.ID, job.Priority = id, priorities[id]
+		if results[id], err = p.Submit(ctx, job); err != nil {
+			t.Fatalf("Submit(%s) error: %v", id, err)
+		}
+	}
+
+	<-first
+	started := map[string]time.Time{}
+	for id, ch := range results {
+		started[id] = (<-ch).Started
+	}
+	if !started["high"].Before(started["normal"]) || !started["normal"].Before(started["low"]) {
+		t.Errorf("start times high %v, normal %v, low %v: want high, normal, low", started["high"], started["normal"], started["low"])
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+}
+
+func TestPoolBackpressure(t *testing.T) {
+	v := getSharedValidator(t)
+	p := New(v, WithWorkers(1), WithQueueSize(1))
+	ctx := context.Background()
+
+	slow, err := p.Submit(ctx, slowJob("slow", 0))
+	if err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	// The queue fills with one job while the worker is busy; it frees one
+	// more slot if the worker had not started the slow job yet
+	var queued []<-chan Result
+	for attempt := 0; ; attempt++ {
+		full := false
+		for range 3 {
+			ch, err := p.TrySubmit(ctx, testJobs(1)[0])
+			if errors.Is(err, ErrQueueFull) {
+				full = true
+				break
+			}
+			if err != nil {
+				t.Fatalf("TrySubmit error: %v", err)
+			}
+			queued = append(queued, ch)
+		}
+		if !full {
+			t.Fatal("TrySubmit never returned ErrQueueFull")
+		}
+
+		// Submit blocks while the queue is full, until its context is done
+		short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		_, err := p.Submit(short, testJobs(1)[0]) // validated with short: result ignored
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Submit error: %v", err)
+		}
+		if attempt > 0 {
+			t.Fatal("Submit did not block on a full queue")
+		}
+	}
+
+	<-slow
+	for _, ch := range queued {
+		if r := <-ch; r.Err != nil {
+			t.Errorf("queued job error: %v", r.Err)
+		}
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+}
+
+func TestPoolCloseDeadline(t *testing.T) {
+	v := getSharedValidator(t)
+	p := New(v, WithWorkers(1), WithQueueSize(3))
+	ctx := context.Background()
+
+	results := make([]<-chan Result, 0, 4)
+	ch, err := p.Submit(ctx, slowJob("slow", 1))
+	if err != nil {
+		t.Fatalf("Submit error: %v", err)
+	}
+	results = append(results, ch)
+	for range 3 {
+		ch, err := p.Submit(ctx, testJobs(1)[0])
+		if err != nil {
+			t.Fatalf("Submit error: %v", err)
+		}
+		results = append(results, ch)
+	}
+
+	expired, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := p.Close(expired); !errors.Is(err, context.Canceled) {
+		t.Errorf("Close() error = %v, want context.Canceled", err)
+	}
+
+	// Every job gets a result; queued jobs are dropped
+	for i, ch := range results {
+		r := <-ch
+		if i > 0 && !errors.Is(r.Err, ErrClosed) {
+			t.Errorf("queued job %d: Err = %v, want ErrClosed", i, r.Err)
+		}
+	}
+}
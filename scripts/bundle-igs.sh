@@ -0,0 +1,61 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Downloads the IG packages to embed in a bundle build into pkg/specs/bundle,
+# filtered like the core specs, and writes manifest.json with the name,
+# version, FHIR version, size and SHA-256 of each package.
+#
+# Usage: bundle-igs.sh [name#version ...]
+# Without arguments, the packages listed in scripts/bundle-igs.txt are used.
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+BUNDLE_DIR="${SCRIPT_DIR}/../pkg/specs/bundle"
+
+# shellcheck source=download-specs.sh
+DOWNLOAD_SPECS_LIB=1 source "${SCRIPT_DIR}/download-specs.sh"
+
+if [ $# -gt 0 ]; then
+    specs=("$@")
+else
+    mapfile -t specs < <(grep -v -e '^#' -e '^[[:space:]]*$' "${SCRIPT_DIR}/bundle-igs.txt")
+fi
+
+rm -rf "${BUNDLE_DIR}"
+mkdir -p "${BUNDLE_DIR}"
+
+echo "Bundling ${#specs[@]} IG package(s)..."
+echo ""
+
+for spec in "${specs[@]}"; do
+    name="${spec%%#*}"
+    version="${spec#*#}"
+    download_and_filter "${name}" "${version}" "${BUNDLE_DIR}/${name}-${version}.tgz"
+done
+
+python3 - "${BUNDLE_DIR}" <<'PY'
+import hashlib, json, os, sys, tarfile
+
+bundle_dir = sys.argv[1]
+manifest = []
+for file in sorted(f for f in os.listdir(bundle_dir) if f.endswith(".tgz")):
+    path = os.path.join(bundle_dir, file)
+    with tarfile.open(path) as tar:
+        pkg = json.load(tar.extractfile("package/package.json"))
+    fhir_versions = pkg.get("fhirVersions") or [pkg.get("fhirVersion", "")]
+    data = open(path, "rb").read()
+    manifest.append({
+        "name": pkg["name"],
+        "version": pkg["version"],
+        "fhirVersion": fhir_versions[0],
+        "file": file,
+        "sha256": hashlib.sha256(data).hexdigest(),
+        "size": len(data),
+    })
+
+with open(os.path.join(bundle_dir, "manifest.json"), "w") as out:
+    json.dump(manifest, out, indent=2)
+    out.write("\n")
+PY
+
+echo ""
+echo "Done. Bundle manifest written to pkg/specs/bundle/manifest.json"
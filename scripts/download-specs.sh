@@ -52,6 +52,11 @@ download_and_filter() {
     rm -rf "$tmp"
 }
 
+# When sourced by bundle-igs.sh only the function above is needed
+if [ "${DOWNLOAD_SPECS_LIB:-}" = "1" ]; then
+    return 0
+fi
+
 echo "Downloading and filtering FHIR packages (SD+VS+CS only)..."
 echo ""
 